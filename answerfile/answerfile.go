@@ -0,0 +1,110 @@
+package answerfile
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/greeneg/allocatord/model"
+)
+
+// Data is what answer file templates are rendered against. The provisioning
+// document is embedded, so templates can use .SerialNumber, .OSVersion,
+// .StorageVolumes, .NetworkInterfaces and so on directly
+type Data struct {
+	model.ProvisioningDocument
+	OSFamily  string
+	Vars      map[string]any
+	ServerUrl string
+}
+
+var funcs = template.FuncMap{
+	"netmask": func(bitmask int) string {
+		return net.IP(net.CIDRMask(bitmask, 32)).String()
+	},
+	// JSON strings are valid YAML scalars too, so this covers cloud-init
+	"json": func(v any) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"xml": func(v string) (string, error) {
+		var out strings.Builder
+		err := xml.EscapeText(&out, []byte(v))
+		return out.String(), err
+	},
+	"default": func(fallback any, v any) any {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// NewData builds the render data for a system from its provisioning document.
+// HostVars must hold a JSON object, which is exposed to templates as .Vars
+func NewData(document model.ProvisioningDocument, osFamily string, serverUrl string) (Data, error) {
+	vars := make(map[string]any)
+	if strings.TrimSpace(document.HostVars) != "" {
+		err := json.Unmarshal([]byte(document.HostVars), &vars)
+		if err != nil {
+			return Data{}, err
+		}
+	}
+
+	return Data{
+		ProvisioningDocument: document,
+		OSFamily:             osFamily,
+		Vars:                 vars,
+		ServerUrl:            serverUrl,
+	}, nil
+}
+
+func parse(content string) (*template.Template, error) {
+	return template.New("answerfile").Funcs(funcs).Parse(content)
+}
+
+// Validate checks that a template parses, so broken templates are refused
+// when they are stored rather than when an installer asks for them
+func Validate(content string) error {
+	_, err := parse(content)
+	return err
+}
+
+func Render(content string, data Data) ([]byte, error) {
+	tmpl, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+package boot
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/greeneg/allocatord/model"
+)
+
+// used when an architecture does not set its own kernel or initrd path
+const (
+	defaultKernelPath = "linux"
+	defaultInitrdPath = "initrd"
+)
+
+// Params holds everything a boot loader config needs, already resolved from
+// the system's records
+type Params struct {
+	SerialNumber string
+	MACAddress   string
+	Reimage      bool
+	OSName       string
+	KernelUrl    *url.URL
+	InitrdUrl    *url.URL
+	KernelArgs   string
+}
+
+// values end up on a single config line, so they must never be able to start
+// a new command
+func safe(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// kernel and initrd paths are resolved against the OS image URL, so relative
+// paths live next to the image and absolute URLs are used as is
+func resolveUrl(imageUrl string, path string) (*url.URL, error) {
+	base, err := url.Parse(imageUrl)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return base.ResolveReference(ref), nil
+}
+
+// NewParams works out how the machine behind a boot target should boot.
+// serverUrl is the allocatord API base URL handed to the imaging client
+func NewParams(target model.BootTarget, serverUrl string) (Params, error) {
+	params := Params{
+		SerialNumber: safe(target.System.SerialNumber),
+		MACAddress:   safe(target.Interface.MACAddress),
+		Reimage:      target.System.Reimage,
+		OSName:       safe(target.OperatingSystem.OSName),
+	}
+	if !params.Reimage {
+		return params, nil
+	}
+
+	kernelPath := target.Architecture.KernelPath
+	if kernelPath == "" {
+		kernelPath = defaultKernelPath
+	}
+	initrdPath := target.Architecture.InitrdPath
+	if initrdPath == "" {
+		initrdPath = defaultInitrdPath
+	}
+
+	var err error
+	params.KernelUrl, err = resolveUrl(target.OperatingSystem.OSImageUrl, kernelPath)
+	if err != nil {
+		return Params{}, err
+	}
+	params.InitrdUrl, err = resolveUrl(target.OperatingSystem.OSImageUrl, initrdPath)
+	if err != nil {
+		return Params{}, err
+	}
+
+	// the imaging client reads its settings from the kernel command line
+	args := []string{
+		"allocatord.url=" + serverUrl,
+		"allocatord.serial=" + target.System.SerialNumber,
+		"allocatord.mac=" + target.Interface.MACAddress,
+		"allocatord.image=" + target.OperatingSystem.OSImageUrl,
+		"allocatord.protocol=" + target.OperatingSystem.ImageUriProtocol,
+	}
+	if target.Architecture.KernelArgs != "" {
+		args = append([]string{target.Architecture.KernelArgs}, args...)
+	}
+	params.KernelArgs = safe(strings.Join(args, " "))
+
+	return params, nil
+}
@@ -0,0 +1,55 @@
+package boot
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"net/url"
+	"text/template"
+)
+
+var grubTemplate = template.Must(template.New("grub").Funcs(template.FuncMap{"grubPath": grubPath}).Parse(`# generated by allocatord for system {{.SerialNumber}} ({{.MACAddress}})
+set timeout=0
+{{- if .Reimage}}
+menuentry "Reimage {{.SerialNumber}} with {{.OSName}}" {
+	linux {{grubPath .KernelUrl}} {{.KernelArgs}}
+	initrd {{grubPath .InitrdUrl}}
+}
+{{- else}}
+menuentry "Boot {{.SerialNumber}} from local disk" {
+	exit
+}
+{{- end}}
+`))
+
+// GRUB addresses network files as (protocol,host)/path rather than by URL
+func grubPath(u *url.URL) string {
+	return "(" + u.Scheme + "," + u.Host + ")" + u.EscapedPath()
+}
+
+// RenderGrub produces a GRUB config with the same boot decision as RenderIPXE
+func RenderGrub(params Params) ([]byte, error) {
+	var out bytes.Buffer
+	err := grubTemplate.Execute(&out, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
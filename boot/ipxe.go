@@ -0,0 +1,49 @@
+package boot
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"text/template"
+)
+
+var ipxeTemplate = template.Must(template.New("ipxe").Parse(`#!ipxe
+# generated by allocatord for system {{.SerialNumber}} ({{.MACAddress}})
+{{- if .Reimage}}
+echo Reimaging {{.SerialNumber}} with {{.OSName}}
+kernel {{.KernelUrl}} initrd=initrd {{.KernelArgs}}
+initrd --name initrd {{.InitrdUrl}}
+boot
+{{- else}}
+echo Booting {{.SerialNumber}} from local disk
+exit
+{{- end}}
+`))
+
+// RenderIPXE produces an iPXE script that chains into the imaging client when
+// the system is flagged for reimage, or falls through to local disk otherwise
+func RenderIPXE(params Params) ([]byte, error) {
+	var out bytes.Buffer
+	err := ipxeTemplate.Execute(&out, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
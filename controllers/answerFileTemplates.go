@@ -0,0 +1,375 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/answerfile"
+	"github.com/greeneg/allocatord/model"
+)
+
+// answerFileData gathers everything a template is rendered against for a
+// system, along with the OS family its templates are looked up by
+func (a *Allocator) answerFileData(c *gin.Context, system model.System) (answerfile.Data, int, error) {
+	document, err := model.Repos.Provisioning.GetProvisioningDocument(system)
+	if err != nil {
+		return answerfile.Data{}, 0, err
+	}
+
+	operatingSystem, err := model.Repos.OperatingSystems.GetOperatingSystemById(system.OperatingSystemId)
+	if err != nil {
+		return answerfile.Data{}, 0, err
+	}
+
+	osFamily, err := model.Repos.OSFamilies.GetOSFamilyById(operatingSystem.OSFamilyId)
+	if err != nil {
+		return answerfile.Data{}, 0, err
+	}
+
+	data, err := answerfile.NewData(document, osFamily.OSFamilyName, a.serverUrl(c))
+	if err != nil {
+		return answerfile.Data{}, 0, errors.New("HostVars is not a valid JSON object: " + string(err.Error()))
+	}
+
+	return data, operatingSystem.OSFamilyId, nil
+}
+
+// CreateAnswerFileTemplate Store a new version of an answer file template
+//
+//	@Summary		Create answer file template version
+//	@Description	Store a Go text/template as the next version of the given template type for an OS family. The latest version is the one rendered for systems
+//	@Tags			answer-files
+//	@Accept			json
+//	@Produce		json
+//	@Param			template	body	model.AnswerFileTemplate	true	"Answer file template data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AnswerFileTemplate
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/answerFileTemplate [post]
+func (a *Allocator) CreateAnswerFileTemplate(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		var json model.AnswerFileTemplate
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		osFamily, err := model.Repos.OSFamilies.GetOSFamilyById(json.OSFamilyId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if osFamily.OSFamilyName == "" {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with OS family id " + strconv.Itoa(json.OSFamilyId)})
+			return
+		}
+
+		err = answerfile.Validate(json.Content)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string((&model.InvalidAnswerFileTemplate{Err: err}).Error())})
+			return
+		}
+
+		template, err := a.Repos(c).AnswerFileTemplates.CreateAnswerFileTemplate(json, userObject.Id)
+		if err != nil {
+			var invalid *model.InvalidAnswerFileTemplate
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot create answer file template: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to create answer file template: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, template)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// DeleteAnswerFileTemplate Remove a version of an answer file template
+//
+//	@Summary		Delete answer file template version
+//	@Description	Delete a single version of an answer file template by Id. Deleting the latest version makes the previous one current again
+//	@Tags			answer-files
+//	@Produce		json
+//	@Param			templateId	path	int	true	"Answer File Template Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/answerFileTemplate/{templateId} [delete]
+func (a *Allocator) DeleteAnswerFileTemplate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		templateId, _ := strconv.Atoi(c.Param("templateId"))
+		status, err := a.Repos(c).AnswerFileTemplates.DeleteAnswerFileTemplate(templateId)
+		if err != nil {
+			log.Println("ERROR: Cannot delete answer file template record: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove answer file template: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Answer file template with Id '" + strconv.Itoa(templateId) + "' has been removed from system"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove answer file template!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetAnswerFileTemplates Retrieve list of all answer file templates
+//
+//	@Summary		Retrieve list of all answer file templates
+//	@Description	Retrieve every version of every answer file template
+//	@Tags			answer-files
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AnswerFileTemplateList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/answerFileTemplates [get]
+func (a *Allocator) GetAnswerFileTemplates(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		templateList, err := model.Repos.AnswerFileTemplates.GetAnswerFileTemplates()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(templateList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": templateList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetAnswerFileTemplatesByOSFamilyId Retrieve the answer file templates of an OS family
+//
+//	@Summary		Retrieve answer file templates by OS family Id
+//	@Description	Retrieve every version of every answer file template for an OS family
+//	@Tags			answer-files
+//	@Produce		json
+//	@Param			osFamilyId	path int true "OS Family ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AnswerFileTemplateList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/answerFileTemplates/byOSFamilyId/{osFamilyId} [get]
+func (a *Allocator) GetAnswerFileTemplatesByOSFamilyId(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("osFamilyId"))
+		templateList, err := model.Repos.AnswerFileTemplates.GetAnswerFileTemplatesByOSFamilyId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(templateList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with OS family id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": templateList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetAnswerFileTemplateById Retrieve an answer file template by its Id
+//
+//	@Summary		Retrieve an answer file template by its Id
+//	@Description	Retrieve an answer file template by its Id
+//	@Tags			answer-files
+//	@Produce		json
+//	@Param			templateId	path int true "Answer File Template ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AnswerFileTemplate
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/answerFileTemplate/byId/{templateId} [get]
+func (a *Allocator) GetAnswerFileTemplateById(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("templateId"))
+		template, err := model.Repos.AnswerFileTemplates.GetAnswerFileTemplateById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if template.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with answer file template id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, template)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RenderAnswerFileTemplate Render a template for a system without storing anything
+//
+//	@Summary		Dry-run render an answer file
+//	@Description	Render either the given template content or a stored template version against a system, so template changes can be checked before they are saved
+//	@Tags			answer-files
+//	@Accept			json
+//	@Produce		plain
+//	@Param			render	body	model.AnswerFileRenderRequest	true	"Render request"
+//	@Security		BasicAuth
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/answerFileTemplate/render [post]
+func (a *Allocator) RenderAnswerFileTemplate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		var json model.AnswerFileRenderRequest
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		content := json.Content
+		if content == "" {
+			if json.TemplateId == 0 {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Either content or templateId must be given"})
+				return
+			}
+			template, err := model.Repos.AnswerFileTemplates.GetAnswerFileTemplateById(json.TemplateId)
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			if template.Id == 0 {
+				c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with answer file template id " + strconv.Itoa(json.TemplateId)})
+				return
+			}
+			content = template.Content
+		}
+
+		system, err := model.Repos.Systems.GetSystemById(json.SystemId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with system id " + strconv.Itoa(json.SystemId)})
+			return
+		}
+		if !a.CanAccessSystem(c, system.Id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		data, _, err := a.answerFileData(c, system)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		rendered, err := answerfile.Render(content, data)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string((&model.InvalidAnswerFileTemplate{Err: err}).Error())})
+			return
+		}
+
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", rendered)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemAnswerFile Render a system's answer file
+//
+//	@Summary		Retrieve a system's answer file
+//	@Description	Render the latest template of the given type for the system's OS family. This is what installers fetch
+//	@Tags			answer-files
+//	@Produce		plain
+//	@Param			systemId		path	int		true	"System Id"
+//	@Param			templateType	path	string	true	"Template type"	Enums(kickstart, autoyast, preseed, cloud-init)
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/answerFile/{templateType} [get]
+//	@Router			/machine/answerFile/{systemId}/{templateType} [get]
+func (a *Allocator) GetSystemAnswerFile(c *gin.Context) {
+	systemId := c.Param("systemId")
+	id, _ := strconv.Atoi(systemId)
+	if a.CanAccessSystem(c, id) {
+		templateType := c.Param("templateType")
+		if !model.IsValidAnswerFileTemplateType(templateType) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unknown answer file template type '" + templateType + "'"})
+			return
+		}
+
+		system, err := model.Repos.Systems.GetSystemById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with system id " + systemId})
+			return
+		}
+
+		data, osFamilyId, err := a.answerFileData(c, system)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		template, err := model.Repos.AnswerFileTemplates.GetLatestAnswerFileTemplate(osFamilyId, templateType)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if template.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No '" + templateType + "' template found for the OS family of system id " + systemId})
+			return
+		}
+
+		rendered, err := answerfile.Render(template.Content, data)
+		if err != nil {
+			log.Println("ERROR: Cannot render answer file for system '" + systemId + "': " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string((&model.InvalidAnswerFileTemplate{Err: err}).Error())})
+			return
+		}
+
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", rendered)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -0,0 +1,207 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/model"
+)
+
+// CreateApiToken Create an API token for the session user
+//
+//	@Summary		Create API token
+//	@Description	Create an API token automation can authenticate as the session user with, sent as 'Authorization: Bearer <token>'. Scopes limit the token to some of the permissions of the user's role. A scoped token also needs the 'read' scope for GET requests, and cannot manage the user's account or act as a machine. The token is only shown once
+//	@Tags			api-tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body	model.ProposedApiToken	true	"API token data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ApiTokenMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/token [post]
+func (a *Allocator) CreateApiToken(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		// a token could otherwise be used to mint itself a broader one
+		if _, isToken := c.Get(globals.ApiTokenKey); isToken {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "API tokens cannot be created with an API token. Access denied!"})
+			return
+		}
+		var json model.ProposedApiToken
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		apiToken, token, err := a.Repos(c).ApiTokens.CreateApiToken(userObject.Id, json)
+		if err != nil {
+			var invalid *model.InvalidApiToken
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot create API token: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to create API token: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "API token '" + apiToken.TokenName + "' created", "token": token, "apiToken": apiToken})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetApiTokens Retrieve the API tokens of the session user
+//
+//	@Summary		Retrieve own API tokens
+//	@Description	Retrieve the API tokens of the session user
+//	@Tags			api-tokens
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ApiTokenList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/tokens [get]
+func (a *Allocator) GetApiTokens(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		a.listApiTokens(c, userObject.Id)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RevokeApiToken Revoke one of the session user's API tokens
+//
+//	@Summary		Revoke own API token
+//	@Description	Revoke one of the session user's API tokens. It stops working immediately
+//	@Tags			api-tokens
+//	@Produce		json
+//	@Param			tokenId	path	int	true	"API token Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/token/{tokenId} [delete]
+func (a *Allocator) RevokeApiToken(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		a.revokeApiToken(c, userObject.Id)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetUserApiTokens Retrieve the API tokens of a user
+//
+//	@Summary		Retrieve a user's API tokens
+//	@Description	Retrieve the API tokens of a user
+//	@Tags			api-tokens
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ApiTokenList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/tokens [get]
+func (a *Allocator) GetUserApiTokens(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if ok {
+		a.listApiTokens(c, user.Id)
+	}
+}
+
+// RevokeUserApiToken Revoke one of a user's API tokens
+//
+//	@Summary		Revoke a user's API token
+//	@Description	Revoke one of a user's API tokens. It stops working immediately
+//	@Tags			api-tokens
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Param			tokenId	path	int	true	"API token Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/token/{tokenId} [delete]
+func (a *Allocator) RevokeUserApiToken(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if ok {
+		a.revokeApiToken(c, user.Id)
+	}
+}
+
+// scopedUser looks up the user named in the path, replying to the request
+// when they do not exist or are outside of the session user's scope
+func (a *Allocator) scopedUser(c *gin.Context) (model.User, bool) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if !authed {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return model.User{}, false
+	}
+	username := c.Param("name")
+	if !a.CanAccessUser(scope, username) {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return model.User{}, false
+	}
+
+	user, err := model.Repos.Users.GetUserByUserName(username)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return model.User{}, false
+	}
+	if user.Id == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name '" + username + "'"})
+		return model.User{}, false
+	}
+
+	return user, true
+}
+
+func (a *Allocator) listApiTokens(c *gin.Context, userId int) {
+	tokens, err := model.Repos.ApiTokens.GetApiTokensByUserId(userId)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+func (a *Allocator) revokeApiToken(c *gin.Context, userId int) {
+	tokenId, _ := strconv.Atoi(c.Param("tokenId"))
+	status, err := a.Repos(c).ApiTokens.RevokeApiToken(userId, tokenId)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke API token: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke API token: " + string(err.Error())})
+		return
+	}
+
+	if status {
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "API token Id " + strconv.Itoa(tokenId) + " has been revoked"})
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with API token id " + strconv.Itoa(tokenId)})
+	}
+}
@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/architecture [post]
 func (a *Allocator) CreateArchitecture(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateArchitecture(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateArchitecture(json, userObject.Id)
+		s, err := a.Repos(c).Architectures.CreateArchitecture(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine Role '" + json.ISEName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateArchitecture(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/architecture/{architectureId} [delete]
 func (a *Allocator) DeleteArchitecture(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		architectureId, _ := strconv.Atoi(c.Param("architectureId"))
-		status, err := model.DeleteArchitecture(architectureId)
+		status, err := a.Repos(c).Architectures.DeleteArchitecture(architectureId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete architecture record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove architecture! " + string(err.Error())})
@@ -105,7 +107,7 @@ func (a *Allocator) DeleteArchitecture(c *gin.Context) {
 func (a *Allocator) GetArchitectures(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		architectures, err := model.GetArchitectures()
+		architectures, err := model.Repos.Architectures.GetArchitectures()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -136,7 +138,7 @@ func (a *Allocator) GetArchitectureById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("architectureId"))
-		architecture, err := model.GetArchitectureById(id)
+		architecture, err := model.Repos.Architectures.GetArchitectureById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -167,7 +169,7 @@ func (a *Allocator) GetArchitectureByName(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		name := c.Param("architectureName")
-		architecture, err := model.GetArchitectureByName(name)
+		architecture, err := model.Repos.Architectures.GetArchitectureByName(name)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
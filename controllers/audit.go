@@ -0,0 +1,132 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// parseAuditTime turns an RFC 3339 timestamp into the UTC form the audit
+// trail stores its dates in
+func parseAuditTime(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.UTC().Format("2006-01-02 15:04:05"), nil
+}
+
+// GetAuditEntries Retrieve the audit trail
+//
+//	@Summary		Retrieve the audit trail
+//	@Description	Retrieve the audit trail of changes, oldest first, optionally narrowed down by table, user, entity and time range
+//	@Tags			audit
+//	@Produce		json
+//	@Param			table		query	string	false	"Table changed, such as Systems"
+//	@Param			userId		query	int		false	"Id of the user who made the change"
+//	@Param			entityId	query	int		false	"Id of the changed row"
+//	@Param			from		query	string	false	"Earliest change date, as an RFC 3339 timestamp"
+//	@Param			to			query	string	false	"Latest change date, as an RFC 3339 timestamp"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AuditEntryList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/audit [get]
+func (a *Allocator) GetAuditEntries(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		filter := model.AuditFilter{TableChanged: c.Query("table")}
+		var err error
+		if c.Query("userId") != "" {
+			filter.ChangedById, err = strconv.Atoi(c.Query("userId"))
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "userId must be a number"})
+				return
+			}
+		}
+		if c.Query("entityId") != "" {
+			filter.EntityId, err = strconv.Atoi(c.Query("entityId"))
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "entityId must be a number"})
+				return
+			}
+		}
+		filter.From, err = parseAuditTime(c.Query("from"))
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To, err = parseAuditTime(c.Query("to"))
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+
+		entries, err := model.Repos.Audit.GetAuditEntries(filter)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": entries})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetAuditEntryById Retrieve an audit trail entry by its Id
+//
+//	@Summary		Retrieve an audit trail entry by its Id
+//	@Description	Retrieve an audit trail entry by its Id
+//	@Tags			audit
+//	@Produce		json
+//	@Param			entryId	path int true "Audit Entry ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.AuditEntry
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/audit/byId/{entryId} [get]
+func (a *Allocator) GetAuditEntryById(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("entryId"))
+		entry, err := model.Repos.Audit.GetAuditEntryById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if entry.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with audit entry id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, entry)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -0,0 +1,81 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/boot"
+	"github.com/greeneg/allocatord/model"
+)
+
+func (a *Allocator) serverUrl(c *gin.Context) string {
+	if a.ConfStruct.ExternalUrl != "" {
+		return a.ConfStruct.ExternalUrl + "/api/v1"
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + c.Request.Host + "/api/v1"
+}
+
+// GetIPXEScript Retrieve the iPXE boot script for a machine by MAC address
+//
+//	@Summary		Retrieve iPXE boot script
+//	@Description	Retrieve the iPXE script for the machine owning a MAC address. Systems flagged for reimage chain into the imaging client, all others boot from local disk. Intended to be chained from iPXE with ${net0/mac}
+//	@Tags			boot
+//	@Produce		plain
+//	@Param			macAddress	path string true "Network Interface MAC Address"
+//	@Success		200	{string}	string
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		500	{object}	model.FailureMsg
+//	@Router			/boot/ipxe/{macAddress} [get]
+func (a *Allocator) GetIPXEScript(c *gin.Context) {
+	macAddress := c.Param("macAddress")
+	target, err := model.Repos.Provisioning.GetBootTargetByMACAddress(macAddress)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+	if target.System.SerialNumber == "" {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No system found with MAC address " + macAddress})
+		return
+	}
+
+	params, err := boot.NewParams(target, a.serverUrl(c))
+	if err != nil {
+		log.Println("ERROR: Cannot build iPXE script for '" + macAddress + "': " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to build boot script: " + string(err.Error())})
+		return
+	}
+
+	script, err := boot.RenderIPXE(params)
+	if err != nil {
+		log.Println("ERROR: Cannot render iPXE script for '" + macAddress + "': " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to render boot script: " + string(err.Error())})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", script)
+}
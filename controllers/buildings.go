@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/building [post]
 func (a *Allocator) CreateBuilding(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateBuilding(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateBuilding(json, userObject.Id)
+		s, err := a.Repos(c).Buildings.CreateBuilding(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Building '" + json.BuildingName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateBuilding(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/building/{buildingId} [delete]
 func (a *Allocator) DeleteBuilding(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		buildingId, _ := strconv.Atoi(c.Param("buildingId"))
-		status, err := model.DeleteBuilding(buildingId)
+		status, err := a.Repos(c).Buildings.DeleteBuilding(buildingId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete building record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove building: " + string(err.Error())})
@@ -106,7 +108,7 @@ func (a *Allocator) DeleteBuilding(c *gin.Context) {
 func (a *Allocator) GetBuildings(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		buildingList, err := model.GetBuildings()
+		buildingList, err := model.Repos.Buildings.GetBuildings()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -137,7 +139,7 @@ func (a *Allocator) GetBuildingById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("buildingId"))
-		building, err := model.GetBuildingById(id)
+		building, err := model.Repos.Buildings.GetBuildingById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -169,7 +171,7 @@ func (a *Allocator) GetBuildingByShortName(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		buildingShortName := c.Param("buildingShortName")
-		building, err := model.GetBuildingByShortName(buildingShortName)
+		building, err := model.Repos.Buildings.GetBuildingByShortName(buildingShortName)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -196,6 +198,7 @@ func (a *Allocator) GetBuildingByShortName(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/building/{buildingId} [patch]
 func (a *Allocator) UpdateBuildingById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -208,7 +211,7 @@ func (a *Allocator) UpdateBuildingById(c *gin.Context) {
 			return
 		}
 
-		status, err := model.UpdateBuildingById(id, json)
+		status, err := a.Repos(c).Buildings.UpdateBuildingById(id, json)
 		if err != nil {
 			log.Println("ERROR: Cannot update building with Id '" + buildingId + "': " + string(err.Error()))
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update building: " + string(err.Error())})
@@ -0,0 +1,77 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/dhcp"
+	"github.com/greeneg/allocatord/model"
+)
+
+// GetDhcpConfig Export DHCP host reservations
+//
+//	@Summary		Export DHCP host reservations
+//	@Description	Export a host reservation for every network interface of the systems in the session user's organizational units, with per-architecture PXE boot file names, as ISC dhcpd, Kea JSON or dnsmasq config
+//	@Tags			dhcp
+//	@Produce		plain
+//	@Produce		json
+//	@Param			format		path	string	true	"Config format"	Enums(isc, kea, dnsmasq)
+//	@Param			nextServer	query	string	false	"IPv4 address of the TFTP server handed to PXE clients"
+//	@Security		BasicAuth
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/dhcp/config/{format} [get]
+func (a *Allocator) GetDhcpConfig(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		format := c.Param("format")
+		hosts, err := model.Repos.Provisioning.GetDhcpHosts()
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+		if !scope.Global {
+			systems, err := model.Repos.Systems.GetSystems()
+			if err != nil {
+				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+				return
+			}
+			hosts = scope.FilterDhcpHosts(hosts, systems)
+		}
+
+		config, err := dhcp.Render(format, hosts, dhcp.Options{NextServer: c.Query("nextServer")})
+		if err != nil {
+			var unknown *dhcp.UnknownFormat
+			var invalid *dhcp.InvalidOptions
+			if errors.As(err, &unknown) || errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to render DHCP config: " + string(err.Error())})
+			return
+		}
+
+		c.Data(http.StatusOK, dhcp.ContentType(format), config)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
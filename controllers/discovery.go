@@ -0,0 +1,247 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// Discovery reports are small, anything bigger than this is turned away
+// before it is parsed
+const maxDiscoveryReportBytes = 64 << 10
+
+const defaultDiscoveryRequestsPerMinute = 10
+
+// DiscoveryRequestsPerMinute returns how many times a minute a single client
+// address may report unknown hardware
+func (a *Allocator) DiscoveryRequestsPerMinute() int {
+	if a.ConfStruct.DiscoveryRequestsPerMinute > 0 {
+		return a.ConfStruct.DiscoveryRequestsPerMinute
+	}
+	return defaultDiscoveryRequestsPerMinute
+}
+
+// DiscoverSystem Report hardware an imaging client booted on that the allocator does not know of
+//
+//	@Summary		Discover a system
+//	@Description	Send the serial number, MAC addresses, disks, CPU cores, RAM, architecture and vendor of unknown hardware. It is recorded as a system waiting for an operator to approve it, along with its network interfaces and storage volumes
+//	@Tags			discovery
+//	@Accept			json
+//	@Produce		json
+//	@Param			report	body	model.DiscoveryReport	true	"What the imaging client found"
+//	@Success		202	{object}	model.DiscoveryMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Failure		429	{object}	model.FailureMsg
+//	@Router			/discover [post]
+func (a *Allocator) DiscoverSystem(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDiscoveryReportBytes)
+	var json model.DiscoveryReport
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	json.ClientAddress = c.ClientIP()
+
+	system, err := a.Repos(c).Discovery.DiscoverSystem(json)
+	if err != nil {
+		var invalid *model.InvalidDiscoveryReport
+		var known *model.SystemAlreadyKnown
+		switch {
+		case errors.As(err, &invalid):
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+		case errors.As(err, &known):
+			c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+		default:
+			log.Println("ERROR: Cannot record discovered system: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to record discovered system: " + string(err.Error())})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusAccepted, model.DiscoveryMsg{
+		Message:  "System '" + system.SerialNumber + "' is waiting for approval",
+		SystemId: system.Id,
+		Status:   system.Status,
+	})
+}
+
+// GetPendingSystems Retrieve the approval queue of discovered systems
+//
+//	@Summary		Get pending systems
+//	@Description	Retrieve all systems waiting for approval, with what their imaging clients reported
+//	@Tags			discovery
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.DiscoveredSystemList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		500	{object}	model.FailureMsg
+//	@Router			/systems/pending [get]
+func (a *Allocator) GetPendingSystems(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		pending, err := model.Repos.Discovery.GetPendingSystems()
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": pending})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetDiscoveredSystem Retrieve what the imaging client of a system reported when it was discovered
+//
+//	@Summary		Get discovered system
+//	@Description	Retrieve a system along with what its imaging client reported, and the network interfaces and storage volumes it was discovered with
+//	@Tags			discovery
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.DiscoveredSystem
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		500	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/discovery [get]
+func (a *Allocator) GetDiscoveredSystem(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		discovered, err := model.Repos.Discovery.GetDiscoveredSystem(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+		if discovered.System.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with system id " + systemId})
+			return
+		}
+		// pending systems are not billed to any organizational unit yet, so
+		// everyone reviewing the approval queue may see them
+		if discovered.System.Status != model.SystemStatusPending && !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, discovered)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// ApproveSystem Approve a discovered system
+//
+//	@Summary		Approve system
+//	@Description	Assign a system waiting for approval its organizational unit, building, machine role and operating system, making it an active system. Model, vendor and architecture are only needed when what the imaging client reported was not recognized
+//	@Tags			discovery
+//	@Accept			json
+//	@Produce		json
+//	@Param			systemId	path	int						true	"System Id"
+//	@Param			approval	body	model.SystemApproval	true	"What to assign the system"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/approve [post]
+func (a *Allocator) ApproveSystem(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		scope, _ := a.GetOrgUnitScope(c)
+		systemId, _ := strconv.Atoi(c.Param("systemId"))
+		var json model.SystemApproval
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !a.InOrgUnitScope(scope, json.BilledToOrgUnitId) {
+			orgUnitScopeDenied(c, json.BilledToOrgUnitId)
+			return
+		}
+
+		status, err := a.Repos(c).Discovery.ApproveSystem(systemId, json)
+		if err != nil {
+			var notPending *model.NoPendingSystem
+			var invalid *model.InvalidSystemApproval
+			var invalidHostVars *model.InvalidHostVars
+			switch {
+			case errors.As(err, &notPending):
+				c.IndentedJSON(http.StatusNotFound, gin.H{"error": string(err.Error())})
+			case errors.As(err, &invalid), errors.As(err, &invalidHostVars):
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			default:
+				log.Println("ERROR: Cannot approve system: " + string(err.Error()))
+				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to approve system: " + string(err.Error())})
+			}
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "System Id " + strconv.Itoa(systemId) + " has been approved"})
+		} else {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to approve system"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RejectSystem Reject a discovered system
+//
+//	@Summary		Reject system
+//	@Description	Remove a system waiting for approval, along with the network interfaces and storage volumes it was discovered with. Should the hardware boot again it is discovered anew
+//	@Tags			discovery
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		500	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/reject [post]
+func (a *Allocator) RejectSystem(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		status, err := a.Repos(c).Discovery.RejectSystem(id)
+		if err != nil {
+			log.Println("ERROR: Cannot reject system: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to reject system: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "System Id " + systemId + " has been rejected"})
+		} else {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No system with Id " + systemId + " is waiting for approval"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
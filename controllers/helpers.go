@@ -1,31 +1,28 @@
 package controllers
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/helpers"
 	"github.com/greeneg/allocatord/model"
 )
 
 func (a *Allocator) GetUserId(c *gin.Context) (model.User, bool) {
 	// need to get our current user context to get the CreatorId
-	session := sessions.Default(c)
-	user := session.Get("user")
-	// if nil, we have an issue
-	if user == nil {
+	username, authed := helpers.AuthenticatedUser(c)
+	// if not, we have an issue
+	if !authed {
 		return model.User{}, false
 	}
 
-	// convert user interface to a string
-	username := fmt.Sprintf("%v", user)
 	// lets output our session user
 	log.Println("INFO: Session user: " + username)
 	// get our user id
-	userObject, err := model.GetUserByUserName(username)
+	userObject, err := model.Repos.Users.GetUserByUserName(username)
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err})
 		return model.User{}, false
@@ -35,3 +32,111 @@ func (a *Allocator) GetUserId(c *gin.Context) (model.User, bool) {
 	log.Println("INFO: Session user's ID: " + strconv.Itoa(userObject.Id))
 	return userObject, true
 }
+
+// Repos returns the repositories changes are made through on behalf of the
+// request, so the audit trail records them against the session user, or the
+// SYSTEM account for machines, along with the Id of the request
+func (a *Allocator) Repos(c *gin.Context) model.Repositories {
+	actor := model.AuditActor{
+		UserId:    model.SystemUserId,
+		UserName:  "SYSTEM",
+		RequestId: c.GetString(globals.RequestIdKey),
+	}
+
+	if systemId, isMachine := a.GetMachineSystemId(c); isMachine {
+		actor.UserName = "machine:" + strconv.Itoa(systemId)
+	} else if username, authed := helpers.AuthenticatedUser(c); authed {
+		userObject, err := model.Repos.Users.GetUserByUserName(username)
+		if err == nil && userObject.Id != 0 {
+			actor.UserId = userObject.Id
+			actor.UserName = userObject.UserName
+		}
+	}
+
+	return model.ReposFor(actor)
+}
+
+func (a *Allocator) GetMachineSystemId(c *gin.Context) (int, bool) {
+	// set by the auth middleware when a machine authenticated with its token
+	systemId, exists := c.Get(globals.MachineKey)
+	if !exists {
+		return 0, false
+	}
+
+	return systemId.(int), true
+}
+
+func (a *Allocator) CanAccessSystem(c *gin.Context, systemId int) bool {
+	// machines may only ever see their own records, while users may see the
+	// ones billed to the organizational units in their scope
+	if machineSystemId, isMachine := a.GetMachineSystemId(c); isMachine {
+		if machineSystemId != systemId {
+			log.Println("WARN: Machine for system Id " + strconv.Itoa(machineSystemId) + " attempted to access system Id " + strconv.Itoa(systemId))
+			return false
+		}
+		return true
+	}
+
+	scope, authed := a.GetOrgUnitScope(c)
+	if !authed {
+		return false
+	}
+	if scope.Global {
+		return true
+	}
+
+	system, err := model.Repos.Systems.GetSystemById(systemId)
+	if err != nil {
+		return false
+	}
+	// leave reporting systems that do not exist to the caller
+	if system.SerialNumber == "" {
+		return true
+	}
+
+	return a.InOrgUnitScope(scope, system.BilledToOrgUnitId)
+}
+
+// GetOrgUnitScope returns the organizational units the session user may see
+// and manage. When that cannot be worked out the scope is left empty, so
+// nothing is shown rather than everything
+func (a *Allocator) GetOrgUnitScope(c *gin.Context) (model.OrgUnitScope, bool) {
+	userObject, authed := a.GetUserId(c)
+	if !authed {
+		return model.OrgUnitScope{}, false
+	}
+
+	scope, err := model.Repos.OrgUnits.GetOrgUnitScope(userObject)
+	if err != nil {
+		log.Println("ERROR: Cannot work out the organizational unit scope of user '" + userObject.UserName + "': " + string(err.Error()))
+		return model.OrgUnitScope{}, true
+	}
+
+	return scope, true
+}
+
+func (a *Allocator) InOrgUnitScope(scope model.OrgUnitScope, ouId int) bool {
+	if !scope.Contains(ouId) {
+		log.Println("WARN: Organizational unit Id " + strconv.Itoa(ouId) + " is outside of the session user's scope")
+		return false
+	}
+	return true
+}
+
+// CanAccessUser reports whether the account belongs to an organizational unit
+// in the scope. Accounts that do not exist are left for the caller to report
+func (a *Allocator) CanAccessUser(scope model.OrgUnitScope, username string) bool {
+	user, err := model.Repos.Users.GetUserByUserName(username)
+	if err != nil {
+		return false
+	}
+	if user.UserName == "" {
+		return true
+	}
+
+	return a.InOrgUnitScope(scope, user.OrgUnitId)
+}
+
+func orgUnitScopeDenied(c *gin.Context, ouId int) {
+	c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access to organizational unit Id " + strconv.Itoa(ouId) + ". Access denied!"})
+}
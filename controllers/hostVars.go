@@ -0,0 +1,369 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/hostvars"
+	"github.com/greeneg/allocatord/model"
+)
+
+// SetHostVarLayer Set the variables of a host var layer
+//
+//	@Summary		Set host var layer
+//	@Description	Create or replace the variables shared by every system in a scope. Layers apply in the order global, orgUnit, building, machineRole and finally the system's own HostVars, later ones overriding earlier ones key by key. The global layer takes scope Id 0. Organizational unit layers may be set for the units in the session user's scope, every other layer needs a global scope
+//	@Tags			host-vars
+//	@Accept			json
+//	@Produce		json
+//	@Param			layer	body	model.HostVarLayer	true	"Host var layer data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/hostVarLayer [post]
+func (a *Allocator) SetHostVarLayer(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		var json model.HostVarLayer
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !a.canChangeHostVarLayer(c, json.Scope, json.ScopeId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		status, err := a.Repos(c).HostVars.SetHostVarLayer(json, userObject.Id)
+		if err != nil {
+			var invalid *model.InvalidHostVars
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot set host var layer: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to set host var layer: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Host var layer '" + json.Scope + "' with scope Id '" + strconv.Itoa(json.ScopeId) + "' has been set"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to set host var layer!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// canChangeHostVarLayer reports whether the session user may change a layer.
+// Organizational unit and system layers need the unit or system to be in
+// their scope. The global, building and machine role layers reach systems
+// billed to any organizational unit, so only a global scope may change them
+func (a *Allocator) canChangeHostVarLayer(c *gin.Context, layerScope string, scopeId int) bool {
+	scope, authed := a.GetOrgUnitScope(c)
+	if !authed {
+		return false
+	}
+
+	switch layerScope {
+	case hostvars.ScopeOrgUnit:
+		return a.InOrgUnitScope(scope, scopeId)
+	case hostvars.ScopeSystem:
+		return a.CanAccessSystem(c, scopeId)
+	}
+	if !scope.Global {
+		log.Println("WARN: Host var layer '" + layerScope + "' reaches outside of the session user's scope")
+		return false
+	}
+	return true
+}
+
+// DeleteHostVarLayer Remove a host var layer
+//
+//	@Summary		Delete host var layer
+//	@Description	Delete the variables of a scope
+//	@Tags			host-vars
+//	@Produce		json
+//	@Param			scope	path	string	true	"Scope"	Enums(global, orgUnit, building, machineRole)
+//	@Param			scopeId	path	int		true	"Scope Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/hostVarLayer/{scope}/{scopeId} [delete]
+func (a *Allocator) DeleteHostVarLayer(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		scope := c.Param("scope")
+		scopeId, _ := strconv.Atoi(c.Param("scopeId"))
+		if !a.canChangeHostVarLayer(c, scope, scopeId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		status, err := a.Repos(c).HostVars.DeleteHostVarLayer(scope, scopeId)
+		if err != nil {
+			log.Println("ERROR: Cannot delete host var layer: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove host var layer: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Host var layer '" + scope + "' with scope Id '" + strconv.Itoa(scopeId) + "' has been removed from system"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove host var layer!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetHostVarLayers Retrieve list of all host var layers
+//
+//	@Summary		Retrieve list of all host var layers
+//	@Description	Retrieve the variables of every scope that has any
+//	@Tags			host-vars
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.HostVarLayerList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/hostVarLayers [get]
+func (a *Allocator) GetHostVarLayers(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		layers, err := model.Repos.HostVars.GetHostVarLayers()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(layers) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": layers})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetHostVarLayer Retrieve the host var layer of a scope
+//
+//	@Summary		Retrieve a host var layer
+//	@Description	Retrieve the variables of a scope
+//	@Tags			host-vars
+//	@Produce		json
+//	@Param			scope	path	string	true	"Scope"	Enums(global, orgUnit, building, machineRole)
+//	@Param			scopeId	path	int		true	"Scope Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.HostVarLayer
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/hostVarLayer/{scope}/{scopeId} [get]
+func (a *Allocator) GetHostVarLayer(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		scope := c.Param("scope")
+		scopeId, _ := strconv.Atoi(c.Param("scopeId"))
+		layer, err := model.Repos.HostVars.GetHostVarLayer(scope, scopeId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if layer.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No host var layer found for " + scope + " with scope Id " + strconv.Itoa(scopeId)})
+		} else {
+			c.IndentedJSON(http.StatusOK, layer)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// SetMachineRoleHostVarsSchema Attach a host vars schema to a machine role
+//
+//	@Summary		Set machine role host vars schema
+//	@Description	Attach a JSON Schema to a machine role. The merged host vars of every system in the role are validated against it when the system is created or updated
+//	@Tags			host-vars
+//	@Accept			json
+//	@Produce		json
+//	@Param			machineRoleId	path	int								true	"Machine Role Id"
+//	@Param			schema			body	model.MachineRoleHostVarsSchema	true	"Host vars schema"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/machineRole/{machineRoleId}/hostVarsSchema [post]
+func (a *Allocator) SetMachineRoleHostVarsSchema(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		machineRoleId := c.Param("machineRoleId")
+		id, _ := strconv.Atoi(machineRoleId)
+		var json model.MachineRoleHostVarsSchema
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		machineRole, err := model.Repos.MachineRoles.GetMachineRoleById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if machineRole.MachineRoleName == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with machine role id " + machineRoleId})
+			return
+		}
+
+		status, err := a.Repos(c).HostVars.SetMachineRoleHostVarsSchema(id, json.HostVarsSchema, userObject.Id)
+		if err != nil {
+			var invalid *model.InvalidHostVarsSchema
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot set host vars schema: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to set host vars schema: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Host vars schema for machine role '" + machineRole.MachineRoleName + "' has been set"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to set host vars schema!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// DeleteMachineRoleHostVarsSchema Remove a machine role's host vars schema
+//
+//	@Summary		Delete machine role host vars schema
+//	@Description	Detach the JSON Schema from a machine role, so its systems' host vars are no longer validated
+//	@Tags			host-vars
+//	@Produce		json
+//	@Param			machineRoleId	path	int	true	"Machine Role Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/machineRole/{machineRoleId}/hostVarsSchema [delete]
+func (a *Allocator) DeleteMachineRoleHostVarsSchema(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		machineRoleId := c.Param("machineRoleId")
+		id, _ := strconv.Atoi(machineRoleId)
+		status, err := a.Repos(c).HostVars.DeleteMachineRoleHostVarsSchema(id)
+		if err != nil {
+			log.Println("ERROR: Cannot delete host vars schema: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove host vars schema: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Host vars schema for machine role with Id '" + machineRoleId + "' has been removed from system"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove host vars schema!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetMachineRoleHostVarsSchema Retrieve a machine role's host vars schema
+//
+//	@Summary		Retrieve machine role host vars schema
+//	@Description	Retrieve the JSON Schema attached to a machine role
+//	@Tags			host-vars
+//	@Produce		json
+//	@Param			machineRoleId	path	int	true	"Machine Role Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineRoleHostVarsSchema
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineRole/{machineRoleId}/hostVarsSchema [get]
+func (a *Allocator) GetMachineRoleHostVarsSchema(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		machineRoleId := c.Param("machineRoleId")
+		id, _ := strconv.Atoi(machineRoleId)
+		schema, err := model.Repos.HostVars.GetMachineRoleHostVarsSchema(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if schema.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No host vars schema found for machine role id " + machineRoleId})
+		} else {
+			c.IndentedJSON(http.StatusOK, schema)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetResolvedHostVars Retrieve a system's merged host vars
+//
+//	@Summary		Retrieve resolved host vars
+//	@Description	Merge the global, org unit, building, machine role and system host var layers of a system, reporting which layer each value came from and whether the result satisfies the machine role's schema
+//	@Tags			host-vars
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{object}	model.ResolvedHostVars
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/hostVars [get]
+//	@Router			/machine/hostVars/{systemId} [get]
+func (a *Allocator) GetResolvedHostVars(c *gin.Context) {
+	systemId := c.Param("systemId")
+	id, _ := strconv.Atoi(systemId)
+	if a.CanAccessSystem(c, id) {
+		system, err := model.Repos.Systems.GetSystemById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with system id " + systemId})
+			return
+		}
+
+		resolved, err := model.Repos.HostVars.ResolveHostVars(system)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, resolved)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
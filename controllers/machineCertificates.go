@@ -0,0 +1,486 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/machineca"
+	"github.com/greeneg/allocatord/model"
+)
+
+// machineCertificatesEnabled replies that there is nothing here when no
+// machine certificate authority has been configured
+func machineCertificatesEnabled(c *gin.Context) bool {
+	if !machineca.Enabled() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Machine certificates are not configured"})
+		return false
+	}
+	return true
+}
+
+// scopedMachineCertificate looks up the machine certificate named by the
+// :certificateId parameter, replying with why it cannot be used when the
+// session user may not see its system or it does not exist
+func (a *Allocator) scopedMachineCertificate(c *gin.Context) (model.MachineCertificate, bool) {
+	certificateId := c.Param("certificateId")
+	id, _ := strconv.Atoi(certificateId)
+	certificate, err := model.Repos.MachineCertificates.GetMachineCertificateById(id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return model.MachineCertificate{}, false
+	}
+	if certificate.Id == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with machine certificate id " + certificateId})
+		return model.MachineCertificate{}, false
+	}
+	if !a.CanAccessSystem(c, certificate.SystemId) {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return model.MachineCertificate{}, false
+	}
+
+	return certificate, true
+}
+
+func (a *Allocator) machineCertificateError(c *gin.Context, err error, action string) {
+	var invalid *model.InvalidMachineCertificateRequest
+	var pending *model.MachineCertificateRequestPending
+	var notPending *model.NoPendingMachineCertificate
+	switch {
+	case errors.As(err, &invalid):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+	case errors.As(err, &pending):
+		c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+	case errors.As(err, &notPending):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": string(err.Error())})
+	default:
+		log.Println("ERROR: Cannot " + action + " machine certificate: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to " + action + " machine certificate: " + string(err.Error())})
+	}
+}
+
+const defaultEnrollmentRequestsPerMinute = 10
+
+// EnrollmentRequestsPerMinute returns how many times a minute a single client
+// address may send or check on a certificate request
+func (a *Allocator) EnrollmentRequestsPerMinute() int {
+	if a.ConfStruct.EnrollmentRequestsPerMinute > 0 {
+		return a.ConfStruct.EnrollmentRequestsPerMinute
+	}
+	return defaultEnrollmentRequestsPerMinute
+}
+
+// enrollmentPending is the reply to every enrollment that has not been
+// issued a certificate, so the reply does not tell whether the serial number
+// belongs to a system, or what an operator made of its request
+func enrollmentPending(c *gin.Context) {
+	c.IndentedJSON(http.StatusAccepted, model.MachineEnrollmentMsg{
+		Message: "Certificate request is waiting for approval. Send it again to check on it",
+		Status:  model.MachineCertificatePending,
+	})
+}
+
+// EnrollMachine Ask for a client certificate for a system
+//
+//	@Summary		Enroll a system
+//	@Description	Send the certificate request of an imaging client, to wait for an operator to approve it. The certificate is issued to the serial number of the system, whatever subject the request asks for. Send the same request again to check on it: the reply is 202 until it has been issued, whether or not the serial number is known, and 200 along with the certificate and the certificate authority that issued it after
+//	@Tags			machine-certificates
+//	@Accept			json
+//	@Produce		json
+//	@Param			enrollment	body	model.MachineEnrollment	true	"Serial number and PEM certificate request"
+//	@Success		200	{object}	model.MachineEnrollmentMsg
+//	@Success		202	{object}	model.MachineEnrollmentMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		429	{object}	model.FailureMsg
+//	@Router			/enroll [post]
+func (a *Allocator) EnrollMachine(c *gin.Context) {
+	if !machineCertificatesEnabled(c) {
+		return
+	}
+	var json model.MachineEnrollment
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if json.SerialNumber == "" || json.CertificateRequest == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "serialNumber and certificateRequest are required"})
+		return
+	}
+	// turn malformed requests away before the serial number is looked at,
+	// so the reply to them does not depend on it either
+	if _, err := machineca.ParseRequest(json.CertificateRequest); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string((&model.InvalidMachineCertificateRequest{Err: err}).Error())})
+		return
+	}
+
+	system, err := model.Repos.Systems.GetSystemBySerialNumber(json.SerialNumber)
+	if err != nil {
+		log.Println("ERROR: Cannot look up system for enrollment: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to request machine certificate"})
+		return
+	}
+	if system.SerialNumber == "" {
+		log.Println("WARN: Enrollment for unknown serial number '" + json.SerialNumber + "' from " + c.ClientIP())
+		enrollmentPending(c)
+		return
+	}
+
+	request, err := a.Repos(c).MachineCertificates.RequestMachineCertificate(system.Id, model.ProposedMachineCertificate{
+		CertificateRequest: json.CertificateRequest,
+		ClientAddress:      c.ClientIP(),
+	})
+	if err != nil {
+		var pending *model.MachineCertificateRequestPending
+		if errors.As(err, &pending) {
+			log.Println("WARN: Enrollment for system '" + system.SerialNumber + "' while another request is pending")
+			enrollmentPending(c)
+			return
+		}
+		var invalid *model.InvalidMachineCertificateRequest
+		if errors.As(err, &invalid) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		log.Println("ERROR: Cannot request machine certificate: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to request machine certificate"})
+		return
+	}
+	if request.Status != model.MachineCertificateIssued {
+		enrollmentPending(c)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, model.MachineEnrollmentMsg{
+		Message:       "Certificate request has been issued",
+		Status:        request.Status,
+		Certificate:   request.Certificate,
+		CACertificate: machineca.CACertificate(),
+	})
+}
+
+// RenewMachineCertificate Issue a new client certificate to the calling system
+//
+//	@Summary		Renew machine certificate
+//	@Description	Issue a client certificate straight away to a system authenticated with its current certificate or machine token, for renewing it before it expires
+//	@Tags			machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			certificate	body	model.ProposedMachineCertificate	true	"PEM certificate request"
+//	@Security		MachineToken
+//	@Success		200	{object}	model.MachineCertificateMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machine/certificate [post]
+func (a *Allocator) RenewMachineCertificate(c *gin.Context) {
+	systemId, isMachine := a.GetMachineSystemId(c)
+	if !isMachine {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return
+	}
+	if !machineCertificatesEnabled(c) {
+		return
+	}
+	var json model.ProposedMachineCertificate
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	json.ClientAddress = c.ClientIP()
+
+	certificate, err := a.Repos(c).MachineCertificates.IssueMachineCertificate(systemId, json)
+	if err != nil {
+		a.machineCertificateError(c, err, "issue")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, model.MachineCertificateMsg{
+		Message:            "Machine certificate issued for system with Id '" + strconv.Itoa(systemId) + "'",
+		MachineCertificate: certificate,
+	})
+}
+
+// GetMachineCertificatesByStatus Retrieve machine certificates and requests by status
+//
+//	@Summary		Retrieve machine certificates by status
+//	@Description	Retrieve the client certificates and certificate requests of systems with a status of pending, issued, rejected or revoked. Pending requests are the ones waiting for approval
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			status	path	string	true	"Status"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineCertificateList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineCertificates/byStatus/{status} [get]
+func (a *Allocator) GetMachineCertificatesByStatus(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		status := c.Param("status")
+		if !model.IsValidMachineCertificateStatus(status) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid status '" + status + "'. Must be one of pending, issued, rejected or revoked"})
+			return
+		}
+		certificates, err := model.Repos.MachineCertificates.GetMachineCertificatesByStatus(status)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		scoped := make([]model.MachineCertificate, 0, len(certificates))
+		for _, certificate := range certificates {
+			if a.CanAccessSystem(c, certificate.SystemId) {
+				scoped = append(scoped, certificate)
+			}
+		}
+		if len(scoped) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": scoped})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetMachineCertificateById Retrieve a machine certificate or request by its Id
+//
+//	@Summary		Retrieve a machine certificate by Id
+//	@Description	Retrieve a client certificate or certificate request of a system by its Id
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			certificateId	path	int	true	"Machine certificate Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineCertificate
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineCertificate/byId/{certificateId} [get]
+func (a *Allocator) GetMachineCertificateById(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		certificate, found := a.scopedMachineCertificate(c)
+		if !found {
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, certificate)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// ApproveMachineCertificate Approve a pending certificate request
+//
+//	@Summary		Approve certificate request
+//	@Description	Issue the client certificate a system asked for while enrolling, to the serial number the system has now
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			certificateId	path	int	true	"Machine certificate Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineCertificateMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineCertificate/{certificateId}/approve [post]
+func (a *Allocator) ApproveMachineCertificate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		if !machineCertificatesEnabled(c) {
+			return
+		}
+		request, found := a.scopedMachineCertificate(c)
+		if !found {
+			return
+		}
+
+		certificate, err := a.Repos(c).MachineCertificates.ApproveMachineCertificate(request.Id)
+		if err != nil {
+			a.machineCertificateError(c, err, "approve")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, model.MachineCertificateMsg{
+			Message:            "Machine certificate issued for system with Id '" + strconv.Itoa(certificate.SystemId) + "'",
+			MachineCertificate: certificate,
+		})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RejectMachineCertificate Reject a pending certificate request
+//
+//	@Summary		Reject certificate request
+//	@Description	Turn down the client certificate a system asked for while enrolling, letting it send a new request
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			certificateId	path	int	true	"Machine certificate Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineCertificate/{certificateId}/reject [post]
+func (a *Allocator) RejectMachineCertificate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		request, found := a.scopedMachineCertificate(c)
+		if !found {
+			return
+		}
+
+		status, err := a.Repos(c).MachineCertificates.RejectMachineCertificate(request.Id)
+		if err != nil {
+			a.machineCertificateError(c, err, "reject")
+			return
+		}
+		if !status {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Certificate request with Id '" + strconv.Itoa(request.Id) + "' is not pending"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Certificate request with Id '" + strconv.Itoa(request.Id) + "' has been rejected"})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RevokeMachineCertificate Revoke a system's client certificate
+//
+//	@Summary		Revoke machine certificate
+//	@Description	Revoke a client certificate of a system. It stops being accepted immediately
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			certificateId	path	int	true	"Machine certificate Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/machineCertificate/{certificateId} [delete]
+func (a *Allocator) RevokeMachineCertificate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		certificate, found := a.scopedMachineCertificate(c)
+		if !found {
+			return
+		}
+
+		status, err := a.Repos(c).MachineCertificates.RevokeMachineCertificate(certificate.Id)
+		if err != nil {
+			a.machineCertificateError(c, err, "revoke")
+			return
+		}
+		if !status {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Machine certificate with Id '" + strconv.Itoa(certificate.Id) + "' has not been issued or is already revoked"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine certificate with Id '" + strconv.Itoa(certificate.Id) + "' has been revoked"})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemMachineCertificates Retrieve the client certificates of a system
+//
+//	@Summary		Retrieve a system's machine certificates
+//	@Description	Retrieve every client certificate and certificate request of a system
+//	@Tags			machine-certificates
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineCertificateList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/certificates [get]
+func (a *Allocator) GetSystemMachineCertificates(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		certificates, err := model.Repos.MachineCertificates.GetMachineCertificatesBySystemId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if len(certificates) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No machine certificates found for system id " + systemId})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": certificates})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// IssueMachineCertificate Issue a client certificate to a system
+//
+//	@Summary		Issue machine certificate
+//	@Description	Issue a client certificate to a system straight away for a certificate request, without it waiting for approval
+//	@Tags			machine-certificates
+//	@Accept			json
+//	@Produce		json
+//	@Param			systemId	path	int									true	"System Id"
+//	@Param			certificate	body	model.ProposedMachineCertificate	true	"PEM certificate request"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineCertificateMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/certificate [post]
+func (a *Allocator) IssueMachineCertificate(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		if !machineCertificatesEnabled(c) {
+			return
+		}
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		var json model.ProposedMachineCertificate
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		certificate, err := a.Repos(c).MachineCertificates.IssueMachineCertificate(id, json)
+		if err != nil {
+			a.machineCertificateError(c, err, "issue")
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, model.MachineCertificateMsg{
+			Message:            "Machine certificate issued for system with Id '" + systemId + "'",
+			MachineCertificate: certificate,
+		})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/machineca"
+	"github.com/greeneg/allocatord/migrations"
+	"github.com/greeneg/allocatord/model"
+)
+
+func newEnrollmentFixture(t *testing.T) (*gin.Engine, model.System) {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	dir := t.TempDir()
+	err = machineca.Configure(machineca.Config{CertFile: filepath.Join(dir, "ca.pem"), KeyFile: filepath.Join(dir, "ca.key")})
+	if err != nil {
+		t.Fatalf("machineca.Configure: %v", err)
+	}
+
+	system, err := model.Repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+		SerialNumber:      "SN-ENROLL-1",
+		NetworkInterfaces: []model.DiscoveredInterface{{DeviceId: "eth0", MACAddress: "aa:bb:cc:dd:ee:01"}},
+	})
+	if err != nil {
+		t.Fatalf("DiscoverSystem: %v", err)
+	}
+
+	a := &Allocator{}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/enroll", a.EnrollMachine)
+	return router, system
+}
+
+func certificateRequest(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "client"}}, key)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func enroll(t *testing.T, router *gin.Engine, serialNumber string, csr string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(model.MachineEnrollment{SerialNumber: serialNumber, CertificateRequest: csr})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/enroll", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+// TestEnrollUnknownSerialNumber checks that enrolling does not tell which
+// serial numbers belong to a system
+func TestEnrollUnknownSerialNumber(t *testing.T) {
+	router, system := newEnrollmentFixture(t)
+	csr := certificateRequest(t)
+
+	known := enroll(t, router, system.SerialNumber, csr)
+	unknown := enroll(t, router, "SN-NOBODY", csr)
+	if known.Code != http.StatusAccepted || unknown.Code != known.Code || unknown.Body.String() != known.Body.String() {
+		t.Errorf("enrolling a known serial number = %d %s, an unknown one = %d %s, want the same 202",
+			known.Code, known.Body, unknown.Code, unknown.Body)
+	}
+
+	// nor whether the system already has a request waiting
+	again := enroll(t, router, system.SerialNumber, certificateRequest(t))
+	if again.Code != known.Code || again.Body.String() != known.Body.String() {
+		t.Errorf("enrolling while a request is pending = %d %s, want the same reply as the first", again.Code, again.Body)
+	}
+	pending, err := model.Repos.MachineCertificates.GetMachineCertificatesBySystemId(system.Id)
+	if err != nil {
+		t.Fatalf("GetMachineCertificatesBySystemId: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Errorf("system has %d certificate requests, want 1", len(pending))
+	}
+
+	malformed := enroll(t, router, "SN-NOBODY", "not a certificate request")
+	if malformed.Code != http.StatusBadRequest {
+		t.Errorf("enrolling with a malformed request = %d, want 400", malformed.Code)
+	}
+}
+
+// TestEnrollIssued checks that sending the request again returns the
+// certificate once an operator has approved it
+func TestEnrollIssued(t *testing.T) {
+	router, system := newEnrollmentFixture(t)
+	csr := certificateRequest(t)
+
+	response := enroll(t, router, system.SerialNumber, csr)
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("enrolling = %d %s, want 202", response.Code, response.Body)
+	}
+	requests, err := model.Repos.MachineCertificates.GetMachineCertificatesBySystemId(system.Id)
+	if err != nil || len(requests) != 1 {
+		t.Fatalf("GetMachineCertificatesBySystemId = %+v, %v, want one request", requests, err)
+	}
+	_, err = model.Repos.MachineCertificates.ApproveMachineCertificate(requests[0].Id)
+	if err != nil {
+		t.Fatalf("ApproveMachineCertificate: %v", err)
+	}
+
+	response = enroll(t, router, system.SerialNumber, csr)
+	var msg model.MachineEnrollmentMsg
+	err = json.Unmarshal(response.Body.Bytes(), &msg)
+	if err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if response.Code != http.StatusOK || msg.Status != model.MachineCertificateIssued || msg.Certificate == "" || msg.CACertificate != machineca.CACertificate() {
+		t.Errorf("enrolling after approval = %d %+v, want 200 with the certificate and the certificate authority", response.Code, msg)
+	}
+
+	// only the request that was approved gets the certificate
+	response = enroll(t, router, system.SerialNumber, certificateRequest(t))
+	if response.Code != http.StatusAccepted {
+		t.Errorf("enrolling with another request after approval = %d %s, want 202", response.Code, response.Body)
+	}
+}
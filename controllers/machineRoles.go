@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/machineRole [post]
 func (a *Allocator) CreateMachineRole(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateMachineRole(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateMachineRole(json, userObject.Id)
+		s, err := a.Repos(c).MachineRoles.CreateMachineRole(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine Role '" + json.MachineRoleName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateMachineRole(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/machineRole/{machineRoleId} [delete]
 func (a *Allocator) DeleteMachineRole(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		machineRoleId, _ := strconv.Atoi(c.Param("machineRoleId"))
-		status, err := model.DeleteMachineRole(machineRoleId)
+		status, err := a.Repos(c).MachineRoles.DeleteMachineRole(machineRoleId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete machine role record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove machine role! " + string(err.Error())})
@@ -106,7 +108,7 @@ func (a *Allocator) DeleteMachineRole(c *gin.Context) {
 func (a *Allocator) GetMachineRoles(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		machineRoleList, err := model.GetMachineRoles()
+		machineRoleList, err := model.Repos.MachineRoles.GetMachineRoles()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -137,7 +139,7 @@ func (a *Allocator) GetMachineRoleById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("machineRoleId"))
-		machineRole, err := model.GetMachineRoleById(id)
+		machineRole, err := model.Repos.MachineRoles.GetMachineRoleById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -165,6 +167,7 @@ func (a *Allocator) GetMachineRoleById(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/machineRole/{machineRoleId} [patch]
 func (a *Allocator) UpdateMachineRoleById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -177,7 +180,7 @@ func (a *Allocator) UpdateMachineRoleById(c *gin.Context) {
 			return
 		}
 
-		status, err := model.UpdateMachineRoleById(id, json)
+		status, err := a.Repos(c).MachineRoles.UpdateMachineRoleById(id, json)
 		if err != nil {
 			log.Println("ERROR: Cannot update machine role with Id '" + machineRoleId + "': " + string(err.Error()))
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update machine role: " + string(err.Error())})
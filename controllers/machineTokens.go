@@ -0,0 +1,162 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// IssueMachineToken Issue the token a system uses to authenticate to the machine API
+//
+//	@Summary		Issue machine token
+//	@Description	Issue a new machine token for a system. The token is only shown once
+//	@Tags			machine-tokens
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineTokenMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId}/machineToken [post]
+func (a *Allocator) IssueMachineToken(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		system, err := model.Repos.Systems.GetSystemById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with system id " + systemId})
+			return
+		}
+
+		token, err := a.Repos(c).MachineTokens.IssueMachineToken(id, userObject.Id)
+		if err != nil {
+			var exists *model.MachineTokenExists
+			if errors.As(err, &exists) {
+				c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot issue machine token: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to issue machine token: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine token issued for system with Id '" + systemId + "'", "token": token})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RotateMachineToken Replace a system's machine token
+//
+//	@Summary		Rotate machine token
+//	@Description	Replace a system's machine token with a new one. The old token stops working immediately
+//	@Tags			machine-tokens
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MachineTokenMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId}/machineToken [patch]
+func (a *Allocator) RotateMachineToken(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		token, err := a.Repos(c).MachineTokens.RotateMachineToken(id, userObject.Id)
+		if err != nil {
+			var missing *model.NoSuchMachineToken
+			if errors.As(err, &missing) {
+				c.IndentedJSON(http.StatusNotFound, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot rotate machine token: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to rotate machine token: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine token rotated for system with Id '" + systemId + "'", "token": token})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RevokeMachineToken Revoke a system's machine token
+//
+//	@Summary		Revoke machine token
+//	@Description	Revoke a system's machine token
+//	@Tags			machine-tokens
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId}/machineToken [delete]
+func (a *Allocator) RevokeMachineToken(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		status, err := a.Repos(c).MachineTokens.RevokeMachineToken(id)
+		if err != nil {
+			var missing *model.NoSuchMachineToken
+			if errors.As(err, &missing) {
+				c.IndentedJSON(http.StatusNotFound, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot revoke machine token: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke machine token: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Machine token for system with Id '" + systemId + "' has been revoked"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke machine token!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -0,0 +1,154 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// CreateMaintenanceWindow Register a new maintenance window
+//
+//	@Summary		Register maintenance window
+//	@Description	Add a weekly maintenance window to a building. Weekday is 0 (Sunday) to 6 and startTime is HH:MM, both in UTC
+//	@Tags			maintenance-windows
+//	@Accept			json
+//	@Produce		json
+//	@Param			maintenanceWindow	body	model.MaintenanceWindow	true	"Maintenance window data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/maintenanceWindow [post]
+func (a *Allocator) CreateMaintenanceWindow(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		var json model.MaintenanceWindow
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		s, err := a.Repos(c).MaintenanceWindows.CreateMaintenanceWindow(json, userObject.Id)
+		if s {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Maintenance window for building with Id '" + strconv.Itoa(json.BuildingId) + "' has been added to system"})
+		} else {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// DeleteMaintenanceWindow Remove a maintenance window
+//
+//	@Summary		Delete maintenance window
+//	@Description	Delete a maintenance window by Id
+//	@Tags			maintenance-windows
+//	@Produce		json
+//	@Param			windowId	path	int	true	"Maintenance Window Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/maintenanceWindow/{windowId} [delete]
+func (a *Allocator) DeleteMaintenanceWindow(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		windowId, _ := strconv.Atoi(c.Param("windowId"))
+		status, err := a.Repos(c).MaintenanceWindows.DeleteMaintenanceWindow(windowId)
+		if err != nil {
+			log.Println("ERROR: Cannot delete maintenance window record: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove maintenance window: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Maintenance window with Id '" + strconv.Itoa(windowId) + "' has been removed from system"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove maintenance window!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetMaintenanceWindows Retrieve list of all maintenance windows
+//
+//	@Summary		Retrieve list of all maintenance windows
+//	@Description	Retrieve list of all maintenance windows
+//	@Tags			maintenance-windows
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MaintenanceWindowList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/maintenanceWindows [get]
+func (a *Allocator) GetMaintenanceWindows(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		windowList, err := model.Repos.MaintenanceWindows.GetMaintenanceWindows()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(windowList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": windowList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetMaintenanceWindowsByBuildingId Retrieve list of maintenance windows for a building
+//
+//	@Summary		Retrieve list of maintenance windows by building Id
+//	@Description	Retrieve list of maintenance windows by building Id
+//	@Tags			maintenance-windows
+//	@Produce		json
+//	@Param			buildingId	path int true "Building ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.MaintenanceWindowList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/maintenanceWindows/byBuildingId/{buildingId} [get]
+func (a *Allocator) GetMaintenanceWindowsByBuildingId(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("buildingId"))
+		windowList, err := model.Repos.MaintenanceWindows.GetMaintenanceWindowsByBuildingId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(windowList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with building id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": windowList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/networkInterface [post]
 func (a *Allocator) CreateNetworkInterface(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -47,8 +48,12 @@ func (a *Allocator) CreateNetworkInterface(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if !a.CanAccessSystem(c, json.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
-		s, err := model.CreateNetworkInterface(json, userObject.Id)
+		s, err := a.Repos(c).NetworkInterfaces.CreateNetworkInterface(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Network Interface '" + strconv.Itoa(json.Id) + "' has been added to system"})
 		} else {
@@ -70,12 +75,17 @@ func (a *Allocator) CreateNetworkInterface(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/networkInterface/{networkInterfaceId} [delete]
 func (a *Allocator) DeleteNetworkInterface(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		networkInterfaceId, _ := strconv.Atoi(c.Param("networkInterfaceId"))
-		status, err := model.DeleteNetworkInterface(networkInterfaceId)
+		if !a.canAccessNetworkInterface(c, networkInterfaceId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		status, err := a.Repos(c).NetworkInterfaces.DeleteNetworkInterface(networkInterfaceId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete network interface record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to network interface role! " + string(err.Error())})
@@ -101,17 +111,26 @@ func (a *Allocator) DeleteNetworkInterface(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.NetworkInterfaces
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/networkInterfaces [get]
 func (a *Allocator) GetNetworkInterfaces(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
-		networkInterfaces, err := model.GetNetworkInterfaces()
+		networkInterfaces, err := model.Repos.NetworkInterfaces.GetNetworkInterfaces()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if !scope.Global {
+			systems, err := model.Repos.Systems.GetSystems()
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			networkInterfaces = scope.FilterNetworkInterfaces(networkInterfaces, systems)
+		}
 
-		if networkInterfaces == nil {
+		if len(networkInterfaces) == 0 {
 			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
 		} else {
 			c.IndentedJSON(http.StatusOK, gin.H{"interfaces": networkInterfaces})
@@ -131,16 +150,21 @@ func (a *Allocator) GetNetworkInterfaces(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.NetworkInterface
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/networkInterface/byId/{networkInterfaceId} [get]
 func (a *Allocator) GetNetworkInterfaceById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("networkInterfaceId"))
-		networkInterface, err := model.GetNetworkInterfaceById(id)
+		networkInterface, err := model.Repos.NetworkInterfaces.GetNetworkInterfaceById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if networkInterface.DeviceModel != "" && !a.CanAccessSystem(c, networkInterface.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
 		if networkInterface.DeviceModel == "" {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with network interface id " + strconv.Itoa(id)})
@@ -162,16 +186,21 @@ func (a *Allocator) GetNetworkInterfaceById(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.NetworkInterface
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/networkInterface/byIpAddress/{networkInterfaceIpAddress} [get]
 func (a *Allocator) GetNetworkInterfaceByIpAddress(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		ipAddr := c.Param("networkInterfaceIpAddress")
-		networkInterface, err := model.GetNetworkInterfaceByIpAddress(ipAddr)
+		networkInterface, err := model.Repos.NetworkInterfaces.GetNetworkInterfaceByIpAddress(ipAddr)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if networkInterface.DeviceModel != "" && !a.CanAccessSystem(c, networkInterface.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
 		if networkInterface.DeviceModel == "" {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with IP Address " + ipAddr})
@@ -193,16 +222,21 @@ func (a *Allocator) GetNetworkInterfaceByIpAddress(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.NetworkInterface
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/networkInterface/byMACAddress/{networkInterfaceMACAddress} [get]
 func (a *Allocator) GetNetworkInterfaceByMACAddress(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		macAddress := c.Param("networkInterfaceId")
-		networkInterface, err := model.GetNetworkInterfaceByMACAddress(macAddress)
+		networkInterface, err := model.Repos.NetworkInterfaces.GetNetworkInterfaceByMACAddress(macAddress)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if networkInterface.DeviceModel != "" && !a.CanAccessSystem(c, networkInterface.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
 		if networkInterface.DeviceModel == "" {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with MAC Address " + macAddress})
@@ -224,12 +258,17 @@ func (a *Allocator) GetNetworkInterfaceByMACAddress(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.NetworkInterfaces
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/networkInterfaces/{systemId} [get]
 func (a *Allocator) GetNetworkInterfacesBySystemId(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("systemId"))
-		networkInterfaces, err := model.GetNetworkInterfacesBySystemId(id)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		networkInterfaces, err := model.Repos.NetworkInterfaces.GetNetworkInterfacesBySystemId(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -256,6 +295,7 @@ func (a *Allocator) GetNetworkInterfacesBySystemId(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/networkInterface/{networkInterfaceId} [patch]
 func (a *Allocator) UpdateNetworkInterface(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -267,8 +307,13 @@ func (a *Allocator) UpdateNetworkInterface(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		// the interface has to be in scope both before and after the change
+		if !a.canAccessNetworkInterface(c, id) || !a.CanAccessSystem(c, json.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
-		status, err := model.UpdateNetworkInterface(id, json)
+		status, err := a.Repos(c).NetworkInterfaces.UpdateNetworkInterface(id, json)
 		if err != nil {
 			log.Println("ERROR: Cannot update network interface with Id '" + networkInterfaceId + "': " + string(err.Error()))
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update network interface: " + string(err.Error())})
@@ -284,3 +329,18 @@ func (a *Allocator) UpdateNetworkInterface(c *gin.Context) {
 		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
 	}
 }
+
+// canAccessNetworkInterface reports whether the session user may see the
+// system the interface belongs to. Interfaces that do not exist are left for
+// the caller to report
+func (a *Allocator) canAccessNetworkInterface(c *gin.Context, networkInterfaceId int) bool {
+	networkInterface, err := model.Repos.NetworkInterfaces.GetNetworkInterfaceById(networkInterfaceId)
+	if err != nil {
+		return false
+	}
+	if networkInterface.DeviceModel == "" {
+		return true
+	}
+
+	return a.CanAccessSystem(c, networkInterface.SystemId)
+}
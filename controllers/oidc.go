@@ -0,0 +1,155 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/oidcauth"
+)
+
+// session keys holding what a login started with, until its callback
+const (
+	oidcStateKey    = "oidcState"
+	oidcNonceKey    = "oidcNonce"
+	oidcVerifierKey = "oidcVerifier"
+)
+
+// OidcLogin Start logging in with the OpenID provider
+//
+//	@Summary		Log in with OIDC
+//	@Description	Redirect to the OpenID provider to log in with the authorization code flow and PKCE
+//	@Tags			oidc
+//	@Produce		json
+//	@Success		302
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		502	{object}	model.FailureMsg
+//	@Router			/oidc/login [get]
+func (a *Allocator) OidcLogin(c *gin.Context) {
+	if !oidcauth.Enabled() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "OIDC login is not configured"})
+		return
+	}
+
+	state, nonce, verifier, err := oidcauth.NewChallenge()
+	if err != nil {
+		log.Println("ERROR: Cannot start OIDC login: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to start OIDC login: " + string(err.Error())})
+		return
+	}
+	authUrl, err := oidcauth.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Println("ERROR: Cannot start OIDC login: " + string(err.Error()))
+		c.IndentedJSON(http.StatusBadGateway, gin.H{"error": "Unable to start OIDC login: " + string(err.Error())})
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set(oidcStateKey, state)
+	session.Set(oidcNonceKey, nonce)
+	session.Set(oidcVerifierKey, verifier)
+	if err := session.Save(); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to save user session"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authUrl)
+}
+
+// OidcCallback Finish logging in with the OpenID provider
+//
+//	@Summary		OIDC login callback
+//	@Description	Where the OpenID provider sends the user back to. Exchanges the authorization code for tokens and starts a session for the user the ID token names, creating their account on their first login
+//	@Tags			oidc
+//	@Produce		json
+//	@Param			code	query	string	true	"Authorization code"
+//	@Param			state	query	string	true	"State the login was started with"
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		401	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Router			/oidc/callback [get]
+func (a *Allocator) OidcCallback(c *gin.Context) {
+	if !oidcauth.Enabled() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "OIDC login is not configured"})
+		return
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "OIDC login failed: " + providerError + " " + c.Query("error_description")})
+		return
+	}
+
+	// the state, nonce and verifier are good for one callback only
+	session := sessions.Default(c)
+	state, _ := session.Get(oidcStateKey).(string)
+	nonce, _ := session.Get(oidcNonceKey).(string)
+	verifier, _ := session.Get(oidcVerifierKey).(string)
+	session.Delete(oidcStateKey)
+	session.Delete(oidcNonceKey)
+	session.Delete(oidcVerifierKey)
+	if err := session.Save(); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to save user session"})
+		return
+	}
+	if state == "" || c.Query("state") != state {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "OIDC login state does not match. Please log in again"})
+		return
+	}
+
+	identity, err := oidcauth.Exchange(c.Request.Context(), c.Query("code"), nonce, verifier)
+	if err != nil {
+		if errors.Is(err, oidcauth.ErrNoMappedGroup) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. " + string(err.Error())})
+			return
+		}
+		log.Println("ERROR: OIDC login failed: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "OIDC login failed: " + string(err.Error())})
+		return
+	}
+
+	user, err := helpers.ExternalUser(oidcauth.Provider(), identity.Subject, model.ProposedUser{
+		UserName:  identity.UserName,
+		FullName:  identity.FullName,
+		OrgUnitId: identity.OrgUnitId,
+		RoleId:    identity.RoleId,
+	})
+	if err != nil {
+		log.Println("ERROR: OIDC login failed: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + string(err.Error())})
+		return
+	}
+	if !helpers.CheckIsNotLocked(user) || !helpers.CheckIsNotLockedOut(user) {
+		log.Println("WARN: User '" + user.UserName + "' is locked!")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		return
+	}
+
+	if _, ok := a.startSession(c, user, model.SessionAuthOidc); !ok {
+		return
+	}
+
+	log.Println("INFO: User '" + user.UserName + "' logged in with OIDC")
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "User '" + user.UserName + "' has logged in"})
+}
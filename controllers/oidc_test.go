@@ -0,0 +1,254 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/migrations"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/oidcauth"
+	"github.com/greeneg/allocatord/oidcauth/oidctest"
+	"github.com/greeneg/allocatord/websession"
+)
+
+const oidcRedirectUrl = "https://allocatord.example.com/api/v1/oidc/callback"
+
+// browser carries the session cookie from one request to the next, as a
+// browser would
+type browser struct {
+	t       *testing.T
+	router  *gin.Engine
+	session *http.Cookie
+}
+
+func (b *browser) get(target string) *httptest.ResponseRecorder {
+	b.t.Helper()
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	if b.session != nil {
+		request.AddCookie(b.session)
+	}
+	response := httptest.NewRecorder()
+	b.router.ServeHTTP(response, request)
+	for _, c := range response.Result().Cookies() {
+		if c.Name == "session" {
+			b.session = c
+		}
+	}
+	return response
+}
+
+// callbackPath turns the URL the provider sent the user back to into a
+// request of the test router
+func callbackPath(t *testing.T, callback string) string {
+	t.Helper()
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("callback URL: %v", err)
+	}
+	return u.Path + "?" + u.RawQuery
+}
+
+func newOidcFixture(t *testing.T) (*oidctest.Provider, *browser) {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+
+	err = websession.Configure(websession.Config{})
+	if err != nil {
+		t.Fatalf("websession.Configure: %v", err)
+	}
+
+	provider := oidctest.NewProvider()
+	t.Cleanup(provider.Close)
+	err = oidcauth.Configure(oidcauth.Config{
+		Issuer:           provider.URL,
+		ClientId:         "allocatord",
+		RedirectUrl:      oidcRedirectUrl,
+		RoleGroups:       []oidcauth.GroupMapping{{Group: "allocatord-admins", Id: 1}},
+		DefaultOrgUnitId: 1,
+	})
+	if err != nil {
+		t.Fatalf("oidcauth.Configure: %v", err)
+	}
+	t.Cleanup(func() { oidcauth.Configure(oidcauth.Config{}) })
+
+	a := &Allocator{}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore(websession.KeyPairs()...)))
+	router.GET("/api/v1/oidc/login", a.OidcLogin)
+	router.GET("/api/v1/oidc/callback", a.OidcCallback)
+
+	return provider, &browser{t: t, router: router}
+}
+
+// startLogin begins a login and returns where the provider sends the user
+// back to once they have logged in with the given claims
+func startLogin(t *testing.T, provider *oidctest.Provider, b *browser, claims map[string]any) string {
+	t.Helper()
+	response := b.get("/api/v1/oidc/login")
+	if response.Code != http.StatusFound {
+		t.Fatalf("OidcLogin = %d %s, want a redirect", response.Code, response.Body)
+	}
+	callback, err := provider.Login(response.Header().Get("Location"), claims)
+	if err != nil {
+		t.Fatalf("provider login: %v", err)
+	}
+	return callbackPath(t, callback)
+}
+
+func adminClaims(username string) map[string]any {
+	return map[string]any{
+		"sub":                "sub-" + username,
+		"preferred_username": username,
+		"name":               "Test " + username,
+		"groups":             []string{"allocatord-admins"},
+	}
+}
+
+func TestOidcLogin(t *testing.T) {
+	provider, b := newOidcFixture(t)
+
+	response := b.get(startLogin(t, provider, b, adminClaims("alice")))
+	if response.Code != http.StatusOK {
+		t.Fatalf("OidcCallback = %d %s, want 200", response.Code, response.Body)
+	}
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("alice was not provisioned: %v", err)
+	}
+	if user.TypeId != model.UserTypeExternal || user.FullName != "Test alice" {
+		t.Errorf("provisioned user = %+v, want an EXTERNAL Test alice", user)
+	}
+	userSessions, err := model.Repos.Sessions.GetSessionsByUserId(user.Id)
+	if err != nil {
+		t.Fatalf("GetSessionsByUserId: %v", err)
+	}
+	if len(userSessions) != 1 || userSessions[0].AuthMethod != model.SessionAuthOidc {
+		t.Errorf("sessions = %+v, want one OIDC session", userSessions)
+	}
+}
+
+func TestOidcLoginNameTaken(t *testing.T) {
+	provider, b := newOidcFixture(t)
+	if response := b.get(startLogin(t, provider, b, adminClaims("alice"))); response.Code != http.StatusOK {
+		t.Fatalf("OidcCallback = %d %s, want 200", response.Code, response.Body)
+	}
+
+	// a user names themselves at the provider, so another subject claiming
+	// the same name must not get alice's account
+	other := &browser{t: t, router: b.router}
+	claims := adminClaims("alice")
+	claims["sub"] = "sub-mallory"
+	if response := other.get(startLogin(t, provider, other, claims)); response.Code != http.StatusUnauthorized {
+		t.Errorf("OidcCallback for another subject named alice = %d %s, want 401", response.Code, response.Body)
+	}
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+	if user.ExternalSubject != "sub-alice" {
+		t.Errorf("alice belongs to subject %q, want sub-alice", user.ExternalSubject)
+	}
+}
+
+func TestOidcCallbackState(t *testing.T) {
+	t.Run("wrong state", func(t *testing.T) {
+		provider, b := newOidcFixture(t)
+		callback := startLogin(t, provider, b, adminClaims("alice"))
+		u, _ := url.Parse(callback)
+		q := u.Query()
+		q.Set("state", "forged")
+		u.RawQuery = q.Encode()
+
+		if response := b.get(u.String()); response.Code != http.StatusBadRequest {
+			t.Errorf("OidcCallback with a forged state = %d %s, want 400", response.Code, response.Body)
+		}
+		// the state was used up by the failed attempt
+		if response := b.get(callback); response.Code != http.StatusBadRequest {
+			t.Errorf("OidcCallback after a failed attempt = %d %s, want 400", response.Code, response.Body)
+		}
+	})
+	t.Run("no login started", func(t *testing.T) {
+		provider, b := newOidcFixture(t)
+		other := &browser{t: t, router: b.router}
+		callback := startLogin(t, provider, b, adminClaims("alice"))
+
+		// a callback arriving in another browser has no state to match
+		if response := other.get(callback); response.Code != http.StatusBadRequest {
+			t.Errorf("OidcCallback without a login = %d %s, want 400", response.Code, response.Body)
+		}
+	})
+	t.Run("replayed callback", func(t *testing.T) {
+		provider, b := newOidcFixture(t)
+		callback := startLogin(t, provider, b, adminClaims("alice"))
+		if response := b.get(callback); response.Code != http.StatusOK {
+			t.Fatalf("OidcCallback = %d %s, want 200", response.Code, response.Body)
+		}
+		if response := b.get(callback); response.Code != http.StatusBadRequest {
+			t.Errorf("replayed OidcCallback = %d %s, want 400", response.Code, response.Body)
+		}
+	})
+}
+
+func TestOidcCallbackRejected(t *testing.T) {
+	t.Run("wrong nonce", func(t *testing.T) {
+		provider, b := newOidcFixture(t)
+		claims := adminClaims("alice")
+		claims["nonce"] = "nonce-of-another-login"
+		if response := b.get(startLogin(t, provider, b, claims)); response.Code != http.StatusUnauthorized {
+			t.Errorf("OidcCallback with another login's nonce = %d %s, want 401", response.Code, response.Body)
+		}
+	})
+	t.Run("no mapped group", func(t *testing.T) {
+		provider, b := newOidcFixture(t)
+		claims := adminClaims("alice")
+		claims["groups"] = []string{"other"}
+		if response := b.get(startLogin(t, provider, b, claims)); response.Code != http.StatusForbidden {
+			t.Errorf("OidcCallback for a user in no mapped group = %d %s, want 403", response.Code, response.Body)
+		}
+	})
+	t.Run("provider error", func(t *testing.T) {
+		_, b := newOidcFixture(t)
+		b.get("/api/v1/oidc/login")
+		if response := b.get("/api/v1/oidc/callback?error=access_denied"); response.Code != http.StatusUnauthorized {
+			t.Errorf("OidcCallback with a provider error = %d %s, want 401", response.Code, response.Body)
+		}
+	})
+
+	_, err := model.Repos.Users.GetUserByUserName("alice")
+	if err == nil {
+		t.Errorf("a user was provisioned from a rejected login")
+	}
+}
@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/operatingSystem [post]
 func (a *Allocator) CreateOperatingSystem(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateOperatingSystem(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateOperatingSystem(json, userObject.Id)
+		s, err := a.Repos(c).OperatingSystems.CreateOperatingSystem(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Operating System '" + json.OSName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateOperatingSystem(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/operatingSystem/{osId} [delete]
 func (a *Allocator) DeleteOperatingSystem(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		osId, _ := strconv.Atoi(c.Param("osId"))
-		status, err := model.DeleteOperatingSystem(osId)
+		status, err := a.Repos(c).OperatingSystems.DeleteOperatingSystem(osId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete Operating System record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove Operating System! " + string(err.Error())})
@@ -105,7 +107,7 @@ func (a *Allocator) DeleteOperatingSystem(c *gin.Context) {
 func (a *Allocator) GetOperatingSystems(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		operatingSystems, err := model.GetOperatingSystems()
+		operatingSystems, err := model.Repos.OperatingSystems.GetOperatingSystems()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -136,7 +138,7 @@ func (a *Allocator) GetOperatingSystemById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osId"))
-		operatingSystem, err := model.GetOperatingSystemById(id)
+		operatingSystem, err := model.Repos.OperatingSystems.GetOperatingSystemById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -167,7 +169,7 @@ func (a *Allocator) GetOperatingSystemsByFamilyId(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osFamilyId"))
-		operatingSystem, err := model.GetOperatingSystemsByFamilyId(id)
+		operatingSystem, err := model.Repos.OperatingSystems.GetOperatingSystemsByFamilyId(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -198,7 +200,7 @@ func (a *Allocator) GetOperatingSystemsByVendorId(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osVendorId"))
-		operatingSystem, err := model.GetOperatingSystemsByVendorId(id)
+		operatingSystem, err := model.Repos.OperatingSystems.GetOperatingSystemsByVendorId(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -225,6 +227,7 @@ func (a *Allocator) GetOperatingSystemsByVendorId(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/operatingSystem/{osId} [patch]
 func (a *Allocator) UpdateOperatingSystemById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -237,7 +240,7 @@ func (a *Allocator) UpdateOperatingSystemById(c *gin.Context) {
 		}
 
 		osIdStr := strconv.Itoa(osId)
-		status, err := model.UpdateOperatingSystemById(osId, json)
+		status, err := a.Repos(c).OperatingSystems.UpdateOperatingSystemById(osId, json)
 		if err != nil {
 			log.Println("ERROR: Cannot update Operating System with Id '" + osIdStr + "': " + string(err.Error()))
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update Operating System record: " + string(err.Error())})
@@ -19,6 +19,7 @@ package controllers
 */
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -38,6 +39,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/organizationalUnit [post]
 func (a *Allocator) CreateOU(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +50,7 @@ func (a *Allocator) CreateOU(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateOU(json, userObject.Id)
+		s, err := a.Repos(c).OrgUnits.CreateOU(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Orgaizational Unit '" + json.OUName + "' has been added to system"})
 		} else {
@@ -70,13 +72,19 @@ func (a *Allocator) CreateOU(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/organizationalUnit/{ouId} [delete]
 func (a *Allocator) DeleteOU(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		ouId, _ := strconv.Atoi(c.Param("ouId"))
-		status, err := model.DeleteOU(ouId)
+		status, err := a.Repos(c).OrgUnits.DeleteOU(ouId)
 		if err != nil {
+			var invalid *model.InvalidOrgUnitHierarchy
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
 			log.Println("ERROR: Cannot delete organizational unit: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove organizational unit! " + string(err.Error())})
 			return
@@ -106,7 +114,7 @@ func (a *Allocator) DeleteOU(c *gin.Context) {
 func (a *Allocator) GetOUs(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		ouList, err := model.GetOUs()
+		ouList, err := model.Repos.OrgUnits.GetOUs()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -137,7 +145,7 @@ func (a *Allocator) GetOUById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("ouId"))
-		ou, err := model.GetOUById(id)
+		ou, err := model.Repos.OrgUnits.GetOUById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -153,3 +161,216 @@ func (a *Allocator) GetOUById(c *gin.Context) {
 		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
 	}
 }
+
+// GetOUChildren Retrieve the organizational units directly below an organizational unit
+//
+//	@Summary		Retrieve children of an organizational unit
+//	@Description	Retrieve the organizational units directly below an organizational unit
+//	@Tags			orgs
+//	@Produce		json
+//	@Param			ouId	path	int	true	"Organizational Unit Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.OrgUnitList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/organizationalUnit/{ouId}/children [get]
+func (a *Allocator) GetOUChildren(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		ouId, _ := strconv.Atoi(c.Param("ouId"))
+		ouList, err := model.Repos.OrgUnits.GetOUChildren(ouId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": ouList})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetOUSubtree Retrieve an organizational unit and everything below it
+//
+//	@Summary		Retrieve subtree of an organizational unit
+//	@Description	Retrieve an organizational unit along with every organizational unit below it
+//	@Tags			orgs
+//	@Produce		json
+//	@Param			ouId	path	int	true	"Organizational Unit Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.OrgUnitList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/organizationalUnit/{ouId}/subtree [get]
+func (a *Allocator) GetOUSubtree(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		ouId, _ := strconv.Atoi(c.Param("ouId"))
+		ouList, err := model.Repos.OrgUnits.GetOUSubtree(ouId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if len(ouList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with organizational unit id " + strconv.Itoa(ouId)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": ouList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// MoveOU Put an organizational unit under a new parent
+//
+//	@Summary		Move organizational unit
+//	@Description	Put an organizational unit, along with everything below it, under a new parent. A parent Id of 0 makes it a top level organizational unit
+//	@Tags			orgs
+//	@Accept			json
+//	@Produce		json
+//	@Param			ouId	path	int					true	"Organizational Unit Id"
+//	@Param			parent	body	model.OrgUnitParent	true	"New parent"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/organizationalUnit/{ouId}/parent [patch]
+func (a *Allocator) MoveOU(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		ouId, _ := strconv.Atoi(c.Param("ouId"))
+		var json model.OrgUnitParent
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := a.Repos(c).OrgUnits.MoveOU(ouId, json.ParentId)
+		if err != nil {
+			var invalid *model.InvalidOrgUnitHierarchy
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot move organizational unit: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to move organizational unit! " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Organizational Unit Id " + strconv.Itoa(ouId) + " has been moved under Organizational Unit Id " + strconv.Itoa(json.ParentId)})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to move organizational unit!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetOrgUnitDelegations Retrieve the organizational units delegated to an organizational unit
+//
+//	@Summary		Retrieve delegations of an organizational unit
+//	@Description	Retrieve the organizational units whose systems and users the members of an organizational unit may also manage
+//	@Tags			orgs
+//	@Produce		json
+//	@Param			ouId	path	int	true	"Organizational Unit Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.OrgUnitDelegationList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/organizationalUnit/{ouId}/delegations [get]
+func (a *Allocator) GetOrgUnitDelegations(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		ouId, _ := strconv.Atoi(c.Param("ouId"))
+		delegations, err := model.Repos.OrgUnits.GetOrgUnitDelegations(ouId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": delegations})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// CreateOrgUnitDelegation Delegate an organizational unit to another
+//
+//	@Summary		Delegate organizational unit
+//	@Description	Let the members of an organizational unit see and manage the systems and users of another
+//	@Tags			orgs
+//	@Accept			json
+//	@Produce		json
+//	@Param			ouId		path	int							true	"Organizational Unit Id"
+//	@Param			delegation	body	model.OrgUnitDelegation	true	"Delegated organizational unit"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/organizationalUnit/{ouId}/delegations [post]
+func (a *Allocator) CreateOrgUnitDelegation(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		var json model.OrgUnitDelegation
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		json.OrgUnitId, _ = strconv.Atoi(c.Param("ouId"))
+
+		s, err := a.Repos(c).OrgUnits.CreateOrgUnitDelegation(json, userObject.Id)
+		if err != nil {
+			var invalid *model.InvalidOrgUnitDelegation
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot delegate organizational unit: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to delegate organizational unit: " + string(err.Error())})
+			return
+		}
+
+		if s {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Organizational Unit Id " + strconv.Itoa(json.DelegatedOrgUnitId) + " has been delegated to Organizational Unit Id " + strconv.Itoa(json.OrgUnitId)})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to delegate organizational unit!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// DeleteOrgUnitDelegation Remove a delegation between organizational units
+//
+//	@Summary		Remove delegation
+//	@Description	Stop the members of an organizational unit from managing the systems and users of another
+//	@Tags			orgs
+//	@Produce		json
+//	@Param			ouId			path	int	true	"Organizational Unit Id"
+//	@Param			delegatedOuId	path	int	true	"Delegated Organizational Unit Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/organizationalUnit/{ouId}/delegation/{delegatedOuId} [delete]
+func (a *Allocator) DeleteOrgUnitDelegation(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		ouId, _ := strconv.Atoi(c.Param("ouId"))
+		delegatedOuId, _ := strconv.Atoi(c.Param("delegatedOuId"))
+		status, err := a.Repos(c).OrgUnits.DeleteOrgUnitDelegation(ouId, delegatedOuId)
+		if err != nil {
+			log.Println("ERROR: Cannot remove organizational unit delegation: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove delegation! " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Organizational Unit Id " + strconv.Itoa(delegatedOuId) + " is no longer delegated to Organizational Unit Id " + strconv.Itoa(ouId)})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove delegation!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/osFamily [post]
 func (a *Allocator) CreateOSFamily(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateOSFamily(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateOSFamily(json, userObject.Id)
+		s, err := a.Repos(c).OSFamilies.CreateOSFamily(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Operating System Family with ID '" + strconv.Itoa(json.Id) + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateOSFamily(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/osFamily/{storageVolumeId} [delete]
 func (a *Allocator) DeleteOSFamily(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		osFamilyId, _ := strconv.Atoi(c.Param("osFamilyId"))
-		status, err := model.DeleteOSFamily(osFamilyId)
+		status, err := a.Repos(c).OSFamilies.DeleteOSFamily(osFamilyId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete operating system family: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove operating system family! " + string(err.Error())})
@@ -105,7 +107,7 @@ func (a *Allocator) DeleteOSFamily(c *gin.Context) {
 func (a *Allocator) GetOSFamilies(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		osFamilies, err := model.GetOSFamilies()
+		osFamilies, err := model.Repos.OSFamilies.GetOSFamilies()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -136,7 +138,7 @@ func (a *Allocator) GetOSFamilyById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osFamilyId"))
-		osFamily, err := model.GetOSFamilyById(id)
+		osFamily, err := model.Repos.OSFamilies.GetOSFamilyById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -167,7 +169,7 @@ func (a *Allocator) GetOSFamilyByName(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		osFamilyName := c.Param("osFamilyName")
-		osFamily, err := model.GetOSFamilyByName(osFamilyName)
+		osFamily, err := model.Repos.OSFamilies.GetOSFamilyByName(osFamilyName)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
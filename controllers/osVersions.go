@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/osVersion [post]
 func (a *Allocator) CreateOSVersion(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateOSVersion(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateOSVersion(json, userObject.Id)
+		s, err := a.Repos(c).OSVersions.CreateOSVersion(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Operating System Version '" + json.VersionNumber + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateOSVersion(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/osVersion/{osVersionId} [delete]
 func (a *Allocator) DeleteOSVersion(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		osVersionId, _ := strconv.Atoi(c.Param("osVersionId"))
-		status, err := model.DeleteOSVersion(osVersionId)
+		status, err := a.Repos(c).OSVersions.DeleteOSVersion(osVersionId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete Operating System Version record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove Operating System Version! " + string(err.Error())})
@@ -105,7 +107,7 @@ func (a *Allocator) DeleteOSVersion(c *gin.Context) {
 func (a *Allocator) GetOSVersions(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		versions, err := model.GetOSVersions()
+		versions, err := model.Repos.OSVersions.GetOSVersions()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -136,7 +138,7 @@ func (a *Allocator) GetOSVersionById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osVersionId"))
-		osVersion, err := model.GetOSVersionById(id)
+		osVersion, err := model.Repos.OSVersions.GetOSVersionById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -167,7 +169,7 @@ func (a *Allocator) GetOSVersionsByOSId(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("osId"))
-		osVersions, err := model.GetOSVersionsByOSId(id)
+		osVersions, err := model.Repos.OSVersions.GetOSVersionsByOSId(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
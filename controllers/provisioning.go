@@ -0,0 +1,123 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+func (a *Allocator) sendProvisioningDocument(c *gin.Context, system model.System) {
+	document, err := model.Repos.Provisioning.GetProvisioningDocument(system)
+	if err != nil {
+		var pending *model.SystemNotApproved
+		if errors.As(err, &pending) {
+			c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to assemble provisioning document: " + string(err.Error())})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, document)
+}
+
+// CheckInBySerialNumber Retrieve the provisioning document for a system by its serial number
+//
+//	@Summary		Machine check-in by serial number
+//	@Description	Retrieve whether a system needs to be reimaged, and with what image and settings, by its serial number
+//	@Tags			machine
+//	@Produce		json
+//	@Param			serialNumber	path string true "System serial number"
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{object}	model.ProvisioningDocument
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Router			/machine/checkIn/bySerialNumber/{serialNumber} [get]
+func (a *Allocator) CheckInBySerialNumber(c *gin.Context) {
+	serialNumber := c.Param("serialNumber")
+	system, err := model.Repos.Systems.GetSystemBySerialNumber(serialNumber)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	if system.SerialNumber == "" {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with serial number " + serialNumber})
+		return
+	}
+
+	if a.CanAccessSystem(c, system.Id) {
+		a.sendProvisioningDocument(c, system)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// CheckInByMACAddress Retrieve the provisioning document for a system by the MAC address of one of its interfaces
+//
+//	@Summary		Machine check-in by MAC address
+//	@Description	Retrieve whether a system needs to be reimaged, and with what image and settings, by a MAC address
+//	@Tags			machine
+//	@Produce		json
+//	@Param			macAddress	path string true "Network Interface MAC Address"
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{object}	model.ProvisioningDocument
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Router			/machine/checkIn/byMACAddress/{macAddress} [get]
+func (a *Allocator) CheckInByMACAddress(c *gin.Context) {
+	macAddress := c.Param("macAddress")
+	networkInterface, err := model.Repos.NetworkInterfaces.GetNetworkInterfaceByMACAddress(macAddress)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	if networkInterface.MACAddress == "" {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with MAC Address " + macAddress})
+		return
+	}
+
+	system, err := model.Repos.Systems.GetSystemById(networkInterface.SystemId)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	if system.SerialNumber == "" {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No system found owning MAC Address " + macAddress})
+		return
+	}
+
+	if a.CanAccessSystem(c, system.Id) {
+		a.sendProvisioningDocument(c, system)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
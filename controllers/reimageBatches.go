@@ -0,0 +1,231 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// CreateReimageBatch Schedule a reimage of a group of systems
+//
+//	@Summary		Schedule reimage batch
+//	@Description	Schedule a reimage of every system matching the given machine role, organizational unit, building or system Ids. Reimages start once scheduledDate (RFC 3339) has passed and each building's maintenance window is open, with at most maxPerBuilding systems reimaging per building at once (0 for no limit)
+//	@Tags			reimage-batches
+//	@Accept			json
+//	@Produce		json
+//	@Param			batch	body	model.ProposedReimageBatch	true	"Reimage batch data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageBatch
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/reimageBatch [post]
+func (a *Allocator) CreateReimageBatch(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		var json model.ProposedReimageBatch
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !a.CanReimageBatch(c, json) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Batches may only select systems of your organizational units!"})
+			return
+		}
+
+		batch, err := a.Repos(c).ReimageBatches.CreateReimageBatch(json, userObject.Id)
+		if err != nil {
+			var invalid *model.InvalidReimageBatch
+			if errors.As(err, &invalid) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot create reimage batch: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to create reimage batch: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, batch)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// CancelReimageBatch Stop a reimage batch from starting more reimages
+//
+//	@Summary		Cancel reimage batch
+//	@Description	Cancel a scheduled or running reimage batch. Every system of the batch has to be in one of your organizational units. Reimages the batch already started are left to finish
+//	@Tags			reimage-batches
+//	@Produce		json
+//	@Param			batchId	path	int	true	"Reimage Batch Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/reimageBatch/{batchId}/cancel [post]
+func (a *Allocator) CancelReimageBatch(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		batchId, _ := strconv.Atoi(c.Param("batchId"))
+		batch, err := model.Repos.ReimageBatches.GetReimageBatchById(batchId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if batch.BatchName == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with reimage batch id " + strconv.Itoa(batchId)})
+			return
+		}
+		if !a.CanAccessReimageBatch(c, batch) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Batches may only be cancelled by users with access to all of their systems!"})
+			return
+		}
+
+		_, err = a.Repos(c).ReimageBatches.CancelReimageBatch(batchId)
+		if err != nil {
+			var notCancellable *model.ReimageBatchNotCancellable
+			if errors.As(err, &notCancellable) {
+				c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot cancel reimage batch: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to cancel reimage batch: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Reimage batch with Id '" + strconv.Itoa(batchId) + "' has been cancelled"})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetReimageBatches Retrieve list of all reimage batches
+//
+//	@Summary		Retrieve list of all reimage batches
+//	@Description	Retrieve list of all reimage batches whose systems are all in your organizational units, newest first, along with the state of each of their systems
+//	@Tags			reimage-batches
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageBatchList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/reimageBatches [get]
+func (a *Allocator) GetReimageBatches(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		batchList, err := model.Repos.ReimageBatches.GetReimageBatches()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		visible := make([]model.ReimageBatch, 0, len(batchList))
+		for _, batch := range batchList {
+			if a.CanAccessReimageBatch(c, batch) {
+				visible = append(visible, batch)
+			}
+		}
+		batchList = visible
+
+		if len(batchList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": batchList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetReimageBatchById Retrieve a reimage batch by its Id
+//
+//	@Summary		Retrieve a reimage batch by its Id
+//	@Description	Retrieve a reimage batch by its Id
+//	@Tags			reimage-batches
+//	@Produce		json
+//	@Param			batchId	path int true "Reimage Batch ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageBatch
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/reimageBatch/byId/{batchId} [get]
+func (a *Allocator) GetReimageBatchById(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("batchId"))
+		batch, err := model.Repos.ReimageBatches.GetReimageBatchById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if batch.BatchName == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with reimage batch id " + strconv.Itoa(id)})
+		} else if !a.CanAccessReimageBatch(c, batch) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, batch)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// CanReimageBatch reports whether every system the batch could select is in
+// the session user's scope. As the selectors narrow each other down, that
+// holds when the batch is limited to an organizational unit in scope or to a
+// list of systems that all are
+func (a *Allocator) CanReimageBatch(c *gin.Context, p model.ProposedReimageBatch) bool {
+	scope, authed := a.GetOrgUnitScope(c)
+	if !authed {
+		return false
+	}
+	if scope.Global {
+		return true
+	}
+	if p.OrgUnitId != 0 && scope.Contains(p.OrgUnitId) {
+		return true
+	}
+	if len(p.SystemIds) == 0 {
+		return false
+	}
+	for _, systemId := range p.SystemIds {
+		if !a.CanAccessSystem(c, systemId) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanAccessReimageBatch reports whether every system of the batch is in the
+// session user's scope. A batch that reaches into any organizational unit
+// outside it is neither shown to nor cancellable by the user
+func (a *Allocator) CanAccessReimageBatch(c *gin.Context, batch model.ReimageBatch) bool {
+	for _, system := range batch.Systems {
+		if !a.CanAccessSystem(c, system.SystemId) {
+			return false
+		}
+	}
+	return true
+}
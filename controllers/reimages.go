@@ -0,0 +1,238 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// RequestReimage Start a reimage of a system
+//
+//	@Summary		Request a reimage
+//	@Description	Start a new reimage of a system and flag it for reimaging on its next check-in
+//	@Tags			reimages
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageRequest
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId}/reimage [post]
+func (a *Allocator) RequestReimage(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		system, err := model.Repos.Systems.GetSystemById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with system id " + systemId})
+			return
+		}
+
+		request, err := a.Repos(c).Reimages.CreateReimageRequest(id, userObject.Id)
+		if err != nil {
+			var inProgress *model.ReimageInProgress
+			if errors.As(err, &inProgress) {
+				c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot request reimage: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to request reimage: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, request)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// CancelReimage Fail a system's active reimage
+//
+//	@Summary		Cancel a reimage
+//	@Description	Fail a system's active reimage, for reimages stuck in a state the machine will not report its way out of, and clear the system's reimage flag
+//	@Tags			reimages
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageRequest
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/reimage/cancel [post]
+func (a *Allocator) CancelReimage(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		request, err := a.Repos(c).Reimages.CancelReimageRequest(id, userObject.UserName)
+		if err != nil {
+			var noActive *model.NoActiveReimage
+			if errors.As(err, &noActive) {
+				c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot cancel reimage: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to cancel reimage: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, request)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetCurrentReimage Retrieve the latest reimage of a system
+//
+//	@Summary		Retrieve current reimage state
+//	@Description	Retrieve the state and event history of a system's most recent reimage
+//	@Tags			reimages
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{object}	model.ReimageRequest
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/reimage [get]
+//	@Router			/machine/reimage/{systemId} [get]
+func (a *Allocator) GetCurrentReimage(c *gin.Context) {
+	systemId := c.Param("systemId")
+	id, _ := strconv.Atoi(systemId)
+	if a.CanAccessSystem(c, id) {
+		request, err := model.Repos.Reimages.GetCurrentReimageRequest(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if request.Id == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No reimages found for system id " + systemId})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, request)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetReimageHistory Retrieve all reimages of a system
+//
+//	@Summary		Retrieve reimage history
+//	@Description	Retrieve every reimage of a system, newest first, along with their events
+//	@Tags			reimages
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.ReimageRequestList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/system/{systemId}/reimages [get]
+func (a *Allocator) GetReimageHistory(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		requests, err := model.Repos.Reimages.GetReimageRequestsBySystemId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if len(requests) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No reimages found for system id " + systemId})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": requests})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// ReportReimageStatus Record progress of a system's reimage
+//
+//	@Summary		Report reimage progress
+//	@Description	Record a progress event for a system's active reimage, moving it to the reported state
+//	@Tags			machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			systemId	path	int							true	"System Id"
+//	@Param			status		body	model.ReimageStatusReport	true	"Reimage status"
+//	@Security		BasicAuth
+//	@Security		MachineToken
+//	@Success		200	{object}	model.ReimageRequest
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Failure		409	{object}	model.FailureMsg
+//	@Router			/machine/reimage/{systemId}/status [post]
+func (a *Allocator) ReportReimageStatus(c *gin.Context) {
+	systemId := c.Param("systemId")
+	id, _ := strconv.Atoi(systemId)
+	if a.CanAccessSystem(c, id) {
+		var json model.ReimageStatusReport
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		request, err := a.Repos(c).Reimages.ReportReimageStatus(id, json)
+		if err != nil {
+			var noActive *model.NoActiveReimage
+			var badTransition *model.InvalidReimageTransition
+			if errors.As(err, &noActive) || errors.As(err, &badTransition) {
+				c.IndentedJSON(http.StatusConflict, gin.H{"error": string(err.Error())})
+				return
+			}
+			log.Println("ERROR: Cannot record reimage status: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to record reimage status: " + string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, request)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
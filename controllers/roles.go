@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/role [post]
 func (a *Allocator) CreateRole(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateRole(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateRole(json)
+		s, err := a.Repos(c).Roles.CreateRole(json)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Role '" + json.RoleName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateRole(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/role/{roleId} [delete]
 func (a *Allocator) DeleteRole(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		roleId, _ := strconv.Atoi(c.Param("roleId"))
-		status, err := model.DeleteRole(roleId)
+		status, err := a.Repos(c).Roles.DeleteRole(roleId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete role: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove role! " + string(err.Error())})
@@ -93,6 +95,50 @@ func (a *Allocator) DeleteRole(c *gin.Context) {
 	}
 }
 
+// SetRoleRequireTwoFactor Set whether users of a role must use two-factor authentication
+//
+//	@Summary		Require two-factor authentication for a role
+//	@Description	Set whether the LOCAL users of a role must log in with a second factor. Users who have not enrolled can only enroll until they have
+//	@Tags			role
+//	@Accept			json
+//	@Produce		json
+//	@Param			roleId	path	int	true	"Role Id"
+//	@Param			role	body	model.RoleRequireTwoFactor	true	"Two-factor requirement"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/role/{roleId}/requireTwoFactor [patch]
+func (a *Allocator) SetRoleRequireTwoFactor(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		roleId, _ := strconv.Atoi(c.Param("roleId"))
+		var json model.RoleRequireTwoFactor
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := a.Repos(c).Roles.SetRoleRequireTwoFactor(roleId, json)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		roleIdStr := strconv.Itoa(roleId)
+		if !status {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with role id " + roleIdStr})
+		} else if json.RequireTwoFactor {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Users of role Id " + roleIdStr + " must use two-factor authentication"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Users of role Id " + roleIdStr + " no longer have to use two-factor authentication"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
 // GetRoles Retrieve list of all roles
 //
 //	@Summary		Retrieve list of all roles
@@ -106,7 +152,7 @@ func (a *Allocator) DeleteRole(c *gin.Context) {
 func (a *Allocator) GetRoles(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		roles, err := model.GetRoles()
+		roles, err := model.Repos.Roles.GetRoles()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -137,7 +183,7 @@ func (a *Allocator) GetRoleById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("roleId"))
-		role, err := model.GetRoleById(id)
+		role, err := model.Repos.Roles.GetRoleById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -169,7 +215,7 @@ func (a *Allocator) GetRoleByName(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		roleName := c.Param("roleName")
-		role, err := model.GetRoleByName(roleName)
+		role, err := model.Repos.Roles.GetRoleByName(roleName)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -184,3 +230,138 @@ func (a *Allocator) GetRoleByName(c *gin.Context) {
 		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
 	}
 }
+
+// GetPermissions Retrieve the permissions that can be granted to roles
+//
+//	@Summary		Retrieve list of all permissions
+//	@Description	Retrieve the permissions that can be granted to roles
+//	@Tags			role
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.PermissionList
+//	@Router			/permissions [get]
+func (a *Allocator) GetPermissions(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		c.IndentedJSON(http.StatusOK, gin.H{"data": model.GetPermissions()})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetRolePermissions Retrieve the permissions granted to a role
+//
+//	@Summary		Retrieve the permissions of a role
+//	@Description	Retrieve the permissions granted to a role
+//	@Tags			role
+//	@Produce		json
+//	@Param			roleId	path	int	true	"Role Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.RolePermissionList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/role/{roleId}/permissions [get]
+func (a *Allocator) GetRolePermissions(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		roleId, _ := strconv.Atoi(c.Param("roleId"))
+		role, err := model.Repos.Roles.GetRoleById(roleId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if role.RoleName == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with role id " + strconv.Itoa(roleId)})
+			return
+		}
+
+		permissions, err := model.Repos.RolePermissions.GetRolePermissions(roleId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"data": permissions})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GrantRolePermission Grant a permission to a role
+//
+//	@Summary		Grant permission
+//	@Description	Grant a permission to a role
+//	@Tags			role
+//	@Accept			json
+//	@Produce		json
+//	@Param			roleId		path	int							true	"Role Id"
+//	@Param			permission	body	model.RolePermissionGrant	true	"Permission to grant"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/role/{roleId}/permissions [post]
+func (a *Allocator) GrantRolePermission(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		roleId, _ := strconv.Atoi(c.Param("roleId"))
+		var json model.RolePermissionGrant
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		role, err := model.Repos.Roles.GetRoleById(roleId)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		if role.RoleName == "" {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with role id " + strconv.Itoa(roleId)})
+			return
+		}
+
+		s, err := a.Repos(c).RolePermissions.GrantRolePermission(roleId, json.Permission, userObject.Id)
+		if s {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Permission '" + json.Permission + "' has been granted to role '" + role.RoleName + "'"})
+		} else {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RevokeRolePermission Revoke a permission from a role
+//
+//	@Summary		Revoke permission
+//	@Description	Revoke a permission from a role
+//	@Tags			role
+//	@Produce		json
+//	@Param			roleId		path	int		true	"Role Id"
+//	@Param			permission	path	string	true	"Permission"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/role/{roleId}/permission/{permission} [delete]
+func (a *Allocator) RevokeRolePermission(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		roleId, _ := strconv.Atoi(c.Param("roleId"))
+		permission := c.Param("permission")
+		status, err := a.Repos(c).RolePermissions.RevokeRolePermission(roleId, permission)
+		if err != nil {
+			log.Println("ERROR: Cannot revoke permission: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke permission! " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "Permission '" + permission + "' has been revoked from role Id " + strconv.Itoa(roleId)})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke permission!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
@@ -0,0 +1,342 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/model"
+)
+
+// startSession starts a server-side session for a user who has just logged
+// in and hands its token to them in the session cookie
+func (a *Allocator) startSession(c *gin.Context, user model.User, authMethod string) (model.Session, bool) {
+	userSession, token, err := model.Repos.Sessions.CreateSession(user.Id, model.ProposedSession{
+		AuthMethod:    authMethod,
+		ClientAddress: c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+	})
+	if err != nil {
+		log.Println("ERROR: Cannot start session: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to start session: " + string(err.Error())})
+		return model.Session{}, false
+	}
+
+	session := sessions.Default(c)
+	session.Set(globals.SessionTokenKey, token)
+	if err := session.Save(); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to save user session"})
+		return model.Session{}, false
+	}
+
+	userSession.Current = true
+	return userSession, true
+}
+
+// Login Log in and start a session
+//
+//	@Summary		Log in
+//	@Description	Check a user's password, and the TOTP or recovery code in otp if they use two-factor authentication, and start a session. The session cookie set authenticates later requests until the session is logged out, revoked, or times out
+//	@Tags			sessions
+//	@Accept			json
+//	@Produce		json
+//	@Param			credentials	body	model.LoginCredentials	true	"Login credentials"
+//	@Success		200	{object}	model.LoginMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		401	{object}	model.FailureMsg
+//	@Router			/login [post]
+func (a *Allocator) Login(c *gin.Context) {
+	var json model.LoginCredentials
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if helpers.EmptyUserPass(json.UserName, json.Password) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "userName and password are required"})
+		return
+	}
+
+	if !helpers.CheckUserPass(json.UserName, json.Password) {
+		log.Println("ERROR: Login failed for user '" + json.UserName + "'")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		return
+	}
+	user, err := model.Repos.Users.GetUserByUserName(json.UserName)
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		return
+	}
+	if helpers.SecondFactorRequired(user) {
+		if json.Otp == "" {
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "Second factor required"})
+			return
+		}
+		if !helpers.CheckSecondFactor(user, json.Otp) {
+			log.Println("ERROR: Second factor check failed for user '" + user.UserName + "'")
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+			return
+		}
+	}
+
+	userSession, ok := a.startSession(c, user, model.SessionAuthPassword)
+	if !ok {
+		return
+	}
+
+	log.Println("INFO: User '" + user.UserName + "' logged in")
+	c.IndentedJSON(http.StatusOK, model.LoginMsg{
+		Message:                     "User '" + user.UserName + "' has logged in",
+		Session:                     userSession,
+		MustChangePassword:          helpers.PasswordChangeRequired(user),
+		TwoFactorEnrollmentRequired: helpers.TwoFactorEnrollmentRequired(user),
+	})
+}
+
+// Logout End the current session
+//
+//	@Summary		Log out
+//	@Description	End the session the request was made with and clear the session cookie
+//	@Tags			sessions
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/logout [post]
+func (a *Allocator) Logout(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		sessionId := c.GetInt(globals.SessionIdKey)
+		if sessionId == 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Not logged in with a session"})
+			return
+		}
+
+		_, err := a.Repos(c).Sessions.RevokeSession(userObject.Id, sessionId)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to end session: " + string(err.Error())})
+			return
+		}
+
+		session := sessions.Default(c)
+		session.Clear()
+		session.Options(sessions.Options{Path: "/", MaxAge: -1})
+		if err := session.Save(); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to save user session"})
+			return
+		}
+
+		log.Println("INFO: User '" + userObject.UserName + "' logged out")
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "User '" + userObject.UserName + "' has logged out"})
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// WhoAmI Retrieve who the request is authenticated as
+//
+//	@Summary		Retrieve the authenticated user
+//	@Description	Retrieve the user a request is authenticated as, how it was authenticated and, for sessions, the session
+//	@Tags			sessions
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.WhoAmI
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/whoami [get]
+func (a *Allocator) WhoAmI(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		whoAmI := model.WhoAmI{
+			Id:                          userObject.Id,
+			UserName:                    userObject.UserName,
+			FullName:                    userObject.FullName,
+			OrgUnitId:                   userObject.OrgUnitId,
+			RoleId:                      userObject.RoleId,
+			TypeId:                      userObject.TypeId,
+			AuthMethod:                  c.GetString(globals.AuthMethodKey),
+			TwoFactorEnabled:            helpers.SecondFactorRequired(userObject),
+			MustChangePassword:          helpers.PasswordChangeRequired(userObject),
+			TwoFactorEnrollmentRequired: helpers.TwoFactorEnrollmentRequired(userObject),
+		}
+
+		if sessionId := c.GetInt(globals.SessionIdKey); sessionId != 0 {
+			userSessions, err := model.Repos.Sessions.GetSessionsByUserId(userObject.Id)
+			if err != nil {
+				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+				return
+			}
+			for _, userSession := range userSessions {
+				if userSession.Id == sessionId {
+					userSession.Current = true
+					whoAmI.Session = &userSession
+					break
+				}
+			}
+		}
+
+		c.IndentedJSON(http.StatusOK, whoAmI)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSessions Retrieve the active sessions of the session user
+//
+//	@Summary		Retrieve own sessions
+//	@Description	Retrieve the sessions of the authenticated user that have not ended, marking the one the request was made with as current
+//	@Tags			sessions
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SessionList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/sessions [get]
+func (a *Allocator) GetSessions(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		a.listSessions(c, userObject.Id)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// RevokeSession End one of the session user's sessions
+//
+//	@Summary		Revoke own session
+//	@Description	End one of the authenticated user's sessions, such as one left open on another machine. It stops working immediately
+//	@Tags			sessions
+//	@Produce		json
+//	@Param			sessionId	path	int	true	"Session Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/session/{sessionId} [delete]
+func (a *Allocator) RevokeSession(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		a.revokeSession(c, userObject.Id)
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetUserSessions Retrieve the active sessions of a user
+//
+//	@Summary		Retrieve a user's sessions
+//	@Description	Retrieve the sessions of a user that have not ended
+//	@Tags			sessions
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SessionList
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/sessions [get]
+func (a *Allocator) GetUserSessions(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if ok {
+		a.listSessions(c, user.Id)
+	}
+}
+
+// RevokeUserSession End one of a user's sessions
+//
+//	@Summary		Revoke a user's session
+//	@Description	End one of a user's sessions. It stops working immediately
+//	@Tags			sessions
+//	@Produce		json
+//	@Param			name		path	string	true	"User name"
+//	@Param			sessionId	path	int		true	"Session Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/session/{sessionId} [delete]
+func (a *Allocator) RevokeUserSession(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if ok {
+		a.revokeSession(c, user.Id)
+	}
+}
+
+// RevokeUserSessions End every session of a user
+//
+//	@Summary		Revoke all of a user's sessions
+//	@Description	End every session of a user, logging them out everywhere
+//	@Tags			sessions
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/sessions [delete]
+func (a *Allocator) RevokeUserSessions(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if !ok {
+		return
+	}
+
+	count, err := a.Repos(c).Sessions.RevokeUserSessions(user.Id)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke sessions: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke sessions: " + string(err.Error())})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": strconv.Itoa(count) + " sessions of user '" + user.UserName + "' have been revoked"})
+}
+
+func (a *Allocator) listSessions(c *gin.Context, userId int) {
+	userSessions, err := model.Repos.Sessions.GetSessionsByUserId(userId)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	currentId := c.GetInt(globals.SessionIdKey)
+	for i := range userSessions {
+		userSessions[i].Current = userSessions[i].Id == currentId
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"data": userSessions})
+}
+
+func (a *Allocator) revokeSession(c *gin.Context, userId int) {
+	sessionId, _ := strconv.Atoi(c.Param("sessionId"))
+	status, err := a.Repos(c).Sessions.RevokeSession(userId, sessionId)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke session: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to revoke session: " + string(err.Error())})
+		return
+	}
+
+	if status {
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Session Id " + strconv.Itoa(sessionId) + " has been revoked"})
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with session id " + strconv.Itoa(sessionId)})
+	}
+}
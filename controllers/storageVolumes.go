@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/storageVolume [post]
 func (a *Allocator) CreateStorageVolume(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -47,8 +48,12 @@ func (a *Allocator) CreateStorageVolume(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if !a.CanAccessSystem(c, json.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
-		s, err := model.CreateStorageVolume(json, userObject.Id)
+		s, err := a.Repos(c).StorageVolumes.CreateStorageVolume(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Storage Volume with ID '" + strconv.Itoa(json.Id) + "' has been added to system"})
 		} else {
@@ -70,12 +75,17 @@ func (a *Allocator) CreateStorageVolume(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/storageVolume/{storageVolumeId} [delete]
 func (a *Allocator) DeleteStorageVolume(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		storageVolumeId, _ := strconv.Atoi(c.Param("storageVolumeId"))
-		status, err := model.DeleteStorageVolume(storageVolumeId)
+		if !a.canAccessStorageVolume(c, storageVolumeId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		status, err := a.Repos(c).StorageVolumes.DeleteStorageVolume(storageVolumeId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete storage volume: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove storage volume! " + string(err.Error())})
@@ -101,17 +111,26 @@ func (a *Allocator) DeleteStorageVolume(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.StorageVolumes
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/storageVolumes [get]
 func (a *Allocator) GetStorageVolumes(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
-		volumes, err := model.GetStorageVolumes()
+		volumes, err := model.Repos.StorageVolumes.GetStorageVolumes()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if !scope.Global {
+			systems, err := model.Repos.Systems.GetSystems()
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+				return
+			}
+			volumes = scope.FilterStorageVolumes(volumes, systems)
+		}
 
-		if volumes == nil {
+		if len(volumes) == 0 {
 			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
 		} else {
 			c.IndentedJSON(http.StatusOK, gin.H{"data": volumes})
@@ -131,16 +150,21 @@ func (a *Allocator) GetStorageVolumes(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.StorageVolume
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/storageVolume/byId/{storageVolumeId} [get]
 func (a *Allocator) GetStorageVolumeById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("storageVolumeId"))
-		volume, err := model.GetStorageVolumeById(id)
+		volume, err := model.Repos.StorageVolumes.GetStorageVolumeById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
 		}
+		if volume.VolumeName != "" && !a.CanAccessSystem(c, volume.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
 		if volume.VolumeName == "" {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with storage volume ID " + strconv.Itoa(id)})
@@ -163,13 +187,18 @@ func (a *Allocator) GetStorageVolumeById(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.StorageVolume
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/storageVolume/{systemId}/byLabel/{storageVolumeLabel} [get]
 func (a *Allocator) GetStorageVolumeByLabel(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		systemId, _ := strconv.Atoi(c.Param("systemId"))
 		label := c.Param("storageVolumeLabel")
-		volume, err := model.GetStorageVolumeByLabel(label, systemId)
+		if !a.CanAccessSystem(c, systemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		volume, err := model.Repos.StorageVolumes.GetStorageVolumeByLabel(label, systemId)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -195,12 +224,17 @@ func (a *Allocator) GetStorageVolumeByLabel(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.StorageVolumes
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/storageVolumes/{systemId} [get]
 func (a *Allocator) GetStorageVolumesBySystemId(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		systemId, _ := strconv.Atoi(c.Param("systemId"))
-		volumes, err := model.GetStorageVolumesBySystemId(systemId)
+		if !a.CanAccessSystem(c, systemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		volumes, err := model.Repos.StorageVolumes.GetStorageVolumesBySystemId(systemId)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -227,6 +261,7 @@ func (a *Allocator) GetStorageVolumesBySystemId(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/storageVolume/{storageVolumeId} [patch]
 func (a *Allocator) UpdateStorageVolume(c *gin.Context) {
 	_, authed := a.GetUserId(c)
@@ -238,8 +273,13 @@ func (a *Allocator) UpdateStorageVolume(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		// the volume has to be in scope both before and after the change
+		if !a.canAccessStorageVolume(c, id) || !a.CanAccessSystem(c, json.SystemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 
-		status, err := model.UpdateStorageVolume(id, json)
+		status, err := a.Repos(c).StorageVolumes.UpdateStorageVolume(id, json)
 		if err != nil {
 			log.Println("ERROR: Cannot update storage volume with Id '" + storageVolumeId + "': " + string(err.Error()))
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update storage volume: " + string(err.Error())})
@@ -255,3 +295,18 @@ func (a *Allocator) UpdateStorageVolume(c *gin.Context) {
 		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
 	}
 }
+
+// canAccessStorageVolume reports whether the session user may see the system
+// the volume belongs to. Volumes that do not exist are left for the caller
+// to report
+func (a *Allocator) canAccessStorageVolume(c *gin.Context, storageVolumeId int) bool {
+	volume, err := model.Repos.StorageVolumes.GetStorageVolumeById(storageVolumeId)
+	if err != nil {
+		return false
+	}
+	if volume.VolumeName == "" {
+		return true
+	}
+
+	return a.CanAccessSystem(c, volume.SystemId)
+}
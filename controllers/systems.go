@@ -1 +1,422 @@
 package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// CreateSystem Register a new system
+//
+//	@Summary		Register system
+//	@Description	Add a new system
+//	@Tags			systems
+//	@Accept			json
+//	@Produce		json
+//	@Param			system	body	model.System	true	"System data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system [post]
+func (a *Allocator) CreateSystem(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if authed {
+		scope, _ := a.GetOrgUnitScope(c)
+		var json model.System
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !a.InOrgUnitScope(scope, json.BilledToOrgUnitId) {
+			orgUnitScopeDenied(c, json.BilledToOrgUnitId)
+			return
+		}
+
+		s, err := a.Repos(c).Systems.CreateSystem(json, userObject.Id)
+		if s {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "System '" + json.SerialNumber + "' has been added to system"})
+		} else {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// DeleteSystem Remove a system
+//
+//	@Summary		Delete system
+//	@Description	Delete a system by Id
+//	@Tags			systems
+//	@Accept			json
+//	@Produce		json
+//	@Param			systemId	path	int	true	"System Id"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId} [delete]
+func (a *Allocator) DeleteSystem(c *gin.Context) {
+	_, authed := a.GetUserId(c)
+	if authed {
+		systemId, _ := strconv.Atoi(c.Param("systemId"))
+		if !a.CanAccessSystem(c, systemId) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		status, err := a.Repos(c).Systems.DeleteSystem(systemId)
+		if err != nil {
+			log.Println("ERROR: Cannot delete system record: " + string(err.Error()))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove system: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "System with Id '" + strconv.Itoa(systemId) + "' has been removed from system"})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove system!"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystems Retrieve list of all system objects
+//
+//	@Summary		Retrieve list of all system objects
+//	@Description	Retrieve list of all system objects
+//	@Tags			systems
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems [get]
+func (a *Allocator) GetSystems(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		systemList, err := model.Repos.Systems.GetSystems()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found!"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemById Retrieve a system by its Id
+//
+//	@Summary		Retrieve a system by its Id
+//	@Description	Retrieve a system by its Id
+//	@Tags			systems
+//	@Produce		json
+//	@Param			id	path int true "System ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.System
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Router			/system/byId/{id} [get]
+func (a *Allocator) GetSystemById(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("id"))
+		system, err := model.Repos.Systems.GetSystemById(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if system.SerialNumber == "" {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with system id " + strconv.Itoa(id)})
+		} else if !a.InOrgUnitScope(scope, system.BilledToOrgUnitId) {
+			orgUnitScopeDenied(c, system.BilledToOrgUnitId)
+		} else {
+			c.IndentedJSON(http.StatusOK, system)
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByVendorId Retrieve list of systems by their vendor Id
+//
+//	@Summary		Retrieve list of systems by their vendor Id
+//	@Description	Retrieve list of systems by their vendor Id
+//	@Tags			systems
+//	@Produce		json
+//	@Param			vendorId	path int true "Vendor ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems/byVendorId/{vendorId} [get]
+func (a *Allocator) GetSystemsByVendorId(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("vendorId"))
+		systemList, err := model.Repos.Systems.GetSystemsByVendorId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with vendor id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByCpuCores Retrieve list of systems by their number of CPU cores
+//
+//	@Summary		Retrieve list of systems by their number of CPU cores
+//	@Description	Retrieve list of systems by their number of CPU cores
+//	@Tags			systems
+//	@Produce		json
+//	@Param			coreCount	path int true "CPU core count"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems/byCpuCores/{coreCount} [get]
+func (a *Allocator) GetSystemsByCpuCores(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("coreCount"))
+		systemList, err := model.Repos.Systems.GetSystemsByCpuCores(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with CPU core count " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByRAM Retrieve list of systems by their amount of installed RAM
+//
+//	@Summary		Retrieve list of systems by their amount of installed RAM
+//	@Description	Retrieve list of systems by their amount of installed RAM
+//	@Tags			systems
+//	@Produce		json
+//	@Param			memoryCount	path int true "Installed RAM"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems/byRAM/{memoryCount} [get]
+func (a *Allocator) GetSystemsByRAM(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("memoryCount"))
+		systemList, err := model.Repos.Systems.GetSystemsByRAM(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with installed RAM " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByMachineRoleId Retrieve list of systems by their machine role Id
+//
+//	@Summary		Retrieve list of systems by their machine role Id
+//	@Description	Retrieve list of systems by their machine role Id
+//	@Tags			systems
+//	@Produce		json
+//	@Param			machineRoleId	path int true "Machine Role ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems/byMachineRoleId/{machineRoleId} [get]
+func (a *Allocator) GetSystemsByMachineRoleId(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("machineRoleId"))
+		systemList, err := model.Repos.Systems.GetSystemsByMachineRoleId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with machine role id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByOuId Retrieve list of systems by their organizational unit Id
+//
+//	@Summary		Retrieve list of systems by their organizational unit Id
+//	@Description	Retrieve list of systems by their organizational unit Id
+//	@Tags			systems
+//	@Produce		json
+//	@Param			ouId	path int true "Organizational Unit ID"
+//	@Param			includeDescendants	query	bool	false	"Also include systems of the organizational units below"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
+//	@Router			/systems/byOuId/{ouId} [get]
+func (a *Allocator) GetSystemsByOuId(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("ouId"))
+		if !a.InOrgUnitScope(scope, id) {
+			orgUnitScopeDenied(c, id)
+			return
+		}
+		var systemList []model.System
+		var err error
+		if c.Query("includeDescendants") == "true" {
+			systemList, err = model.Repos.Systems.GetSystemsByOuSubtree(id)
+		} else {
+			systemList, err = model.Repos.Systems.GetSystemsByOuId(id)
+		}
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with organizational unit id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// GetSystemsByBuildingId Retrieve list of systems by their building Id
+//
+//	@Summary		Retrieve list of systems by their building Id
+//	@Description	Retrieve list of systems by their building Id
+//	@Tags			systems
+//	@Produce		json
+//	@Param			buildingId	path int true "Building ID"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SystemList
+//	@Failure		400	{object}	model.FailureMsg
+//	@Router			/systems/byBuildingId/{buildingId} [get]
+func (a *Allocator) GetSystemsByBuildingId(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		id, _ := strconv.Atoi(c.Param("buildingId"))
+		systemList, err := model.Repos.Systems.GetSystemsByBuildingId(id)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			return
+		}
+		systemList = scope.FilterSystems(systemList)
+
+		if len(systemList) == 0 {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with building id " + strconv.Itoa(id)})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"data": systemList})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// UpdateSystemById Update a system by its Id
+//
+//	@Summary		Update a system by its Id
+//	@Description	Update a system by its Id. The reimage flag is ignored, request a reimage of the system instead
+//	@Tags			systems
+//	@Produce		json
+//	@Param			systemId	path int true "System ID"
+//	@Param			systemData	body model.System	true	"System data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/system/{systemId} [patch]
+func (a *Allocator) UpdateSystemById(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		systemId := c.Param("systemId")
+		id, _ := strconv.Atoi(systemId)
+		var json model.System
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// the system has to be in scope both before and after the change
+		if !a.CanAccessSystem(c, id) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		if !a.InOrgUnitScope(scope, json.BilledToOrgUnitId) {
+			orgUnitScopeDenied(c, json.BilledToOrgUnitId)
+			return
+		}
+
+		status, err := a.Repos(c).Systems.UpdateSystemById(id, json)
+		if err != nil {
+			log.Println("ERROR: Cannot update system with Id '" + systemId + "': " + string(err.Error()))
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update system: " + string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "System with Id '" + systemId + "' has been updated"})
+		} else {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Unable to update system with Id '" + systemId + "'"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
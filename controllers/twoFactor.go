@@ -0,0 +1,277 @@
+package controllers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/twofactor"
+)
+
+// twoFactorUser returns the session user for managing their own two-factor
+// authentication, replying to the request when they cannot
+func (a *Allocator) twoFactorUser(c *gin.Context) (model.User, bool) {
+	userObject, authed := a.GetUserId(c)
+	if !authed {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return model.User{}, false
+	}
+	// a leaked token must not be able to swap the second factor out
+	if _, isToken := c.Get(globals.ApiTokenKey); isToken {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication cannot be managed with an API token. Access denied!"})
+		return model.User{}, false
+	}
+	if userObject.TypeId == model.UserTypeExternal {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication of EXTERNAL users is handled by their identity provider"})
+		return model.User{}, false
+	}
+
+	return userObject, true
+}
+
+// GetTwoFactorStatus Retrieve the session user's two-factor authentication status
+//
+//	@Summary		Retrieve own two-factor status
+//	@Description	Retrieve whether the session user has two-factor authentication enabled and how many unused recovery codes they have left
+//	@Tags			two-factor
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.TwoFactor
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/twoFactor [get]
+func (a *Allocator) GetTwoFactorStatus(c *gin.Context) {
+	userObject, ok := a.twoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	tf, err := model.Repos.TwoFactor.GetTwoFactorByUserId(userObject.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+	tf.UserId = userObject.Id
+
+	c.IndentedJSON(http.StatusOK, tf)
+}
+
+// EnrollTwoFactor Start two-factor enrollment for the session user
+//
+//	@Summary		Enroll in two-factor authentication
+//	@Description	Generate a TOTP secret for the session user, returned with its otpauth:// provisioning URI and a QR code of it as a PNG data URI. It takes effect once confirmed with a code from the authenticator app. Starting over replaces an unconfirmed secret
+//	@Tags			two-factor
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.TwoFactorEnrollment
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/twoFactor/enroll [post]
+func (a *Allocator) EnrollTwoFactor(c *gin.Context) {
+	userObject, ok := a.twoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := twofactor.Generate(userObject.UserName)
+	if err != nil {
+		log.Println("ERROR: Cannot generate TOTP secret: " + string(err.Error()))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to generate TOTP secret: " + string(err.Error())})
+		return
+	}
+
+	status, err := a.Repos(c).TwoFactor.StartTwoFactorEnrollment(userObject.Id, enrollment.Secret)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+	if !status {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, model.TwoFactorEnrollment{
+		Secret:          enrollment.Secret,
+		ProvisioningUri: enrollment.ProvisioningUri,
+		QrCode:          enrollment.QrCode,
+	})
+}
+
+// ConfirmTwoFactor Confirm the session user's two-factor enrollment
+//
+//	@Summary		Confirm two-factor enrollment
+//	@Description	Enable two-factor authentication for the session user with a code from their authenticator app. The recovery codes returned can each stand in for a code once, and are only shown this once
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body	model.TwoFactorCode	true	"TOTP code"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.RecoveryCodesMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/twoFactor/confirm [post]
+func (a *Allocator) ConfirmTwoFactor(c *gin.Context) {
+	userObject, ok := a.twoFactorUser(c)
+	if !ok {
+		return
+	}
+	var json model.TwoFactorCode
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tf, err := model.Repos.TwoFactor.GetTwoFactorByUserId(userObject.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+	if tf.Id == 0 || tf.Enabled {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No two-factor enrollment is waiting to be confirmed"})
+		return
+	}
+	step, valid := twofactor.Validate(tf.Secret, json.Code, time.Now())
+	if !valid {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid TOTP code"})
+		return
+	}
+
+	codes, err := a.Repos(c).TwoFactor.EnableTwoFactor(userObject.Id, step)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+	if codes == nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No two-factor enrollment is waiting to be confirmed"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled for user '" + userObject.UserName + "'", "recoveryCodes": codes})
+}
+
+// DisableTwoFactor Turn off two-factor authentication for the session user
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Turn off two-factor authentication for the session user, which takes a current TOTP code or a recovery code. Users of roles requiring it have to enroll again
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body	model.TwoFactorCode	true	"TOTP or recovery code"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/twoFactor/disable [post]
+func (a *Allocator) DisableTwoFactor(c *gin.Context) {
+	userObject, ok := a.twoFactorUser(c)
+	if !ok {
+		return
+	}
+	var json model.TwoFactorCode
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !helpers.CheckSecondFactor(userObject, json.Code) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid or already used code"})
+		return
+	}
+
+	_, err := a.Repos(c).TwoFactor.DisableTwoFactor(userObject.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled for user '" + userObject.UserName + "'"})
+}
+
+// RegenerateRecoveryCodes Replace the session user's recovery codes
+//
+//	@Summary		Replace recovery codes
+//	@Description	Hand the session user a new set of recovery codes, voiding the old ones. Takes a current TOTP code or a recovery code
+//	@Tags			two-factor
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body	model.TwoFactorCode	true	"TOTP or recovery code"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.RecoveryCodesMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Router			/twoFactor/recoveryCodes [post]
+func (a *Allocator) RegenerateRecoveryCodes(c *gin.Context) {
+	userObject, ok := a.twoFactorUser(c)
+	if !ok {
+		return
+	}
+	var json model.TwoFactorCode
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !helpers.CheckSecondFactor(userObject, json.Code) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid or already used code"})
+		return
+	}
+
+	codes, err := a.Repos(c).TwoFactor.RegenerateRecoveryCodes(userObject.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "New recovery codes issued for user '" + userObject.UserName + "'", "recoveryCodes": codes})
+}
+
+// ResetUserTwoFactor Turn off two-factor authentication for a user
+//
+//	@Summary		Reset a user's two-factor authentication
+//	@Description	Remove the TOTP secret and recovery codes of a user who lost them, so they can log in with their password and enroll again
+//	@Tags			two-factor
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/twoFactor [delete]
+func (a *Allocator) ResetUserTwoFactor(c *gin.Context) {
+	user, ok := a.scopedUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := a.Repos(c).TwoFactor.DisableTwoFactor(user.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		return
+	}
+
+	if status {
+		c.IndentedJSON(http.StatusOK, gin.H{"message": "Two-factor authentication of user '" + user.UserName + "' has been reset"})
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "User '" + user.UserName + "' has not enrolled in two-factor authentication"})
+	}
+}
@@ -19,6 +19,8 @@ package controllers
 */
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -39,17 +41,22 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user [post]
 func (a *Allocator) CreateUser(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
 		var json model.ProposedUser
 		if err := c.ShouldBindJSON(&json); err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if !a.InOrgUnitScope(scope, json.OrgUnitId) {
+			orgUnitScopeDenied(c, json.OrgUnitId)
+			return
+		}
 
-		s, err := model.CreateUser(json)
+		s, err := a.Repos(c).Users.CreateUser(json)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "User has been added to system"})
 		} else {
@@ -63,26 +70,52 @@ func (a *Allocator) CreateUser(c *gin.Context) {
 // ChangeAccountPassowrd Change an account's password
 //
 //	@Summary		Change password
-//	@Description	Change password
+//	@Description	Change the password of the session user. A wrong old password counts as a failed login. Users with the users:admin permission reset other users' passwords instead
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
 //	@Param			name	path	string	true	"User name"
 //	@Param			changePassword	body	model.PasswordChange	true	"Password data"
+//	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		401	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user/{name} [patch]
 func (a *Allocator) ChangeAccountPassword(c *gin.Context) {
+	userObject, authed := a.GetUserId(c)
+	if !authed {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return
+	}
 	username := c.Param("name")
+	if userObject.UserName != username {
+		log.Println("WARN: User '" + userObject.UserName + "' attempted to change the password of user '" + username + "'")
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+		return
+	}
+
 	var json model.PasswordChange
 	if err := c.ShouldBindJSON(&json); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	status, err := model.ChangeAccountPassword(username, json.OldPassword, json.NewPassword)
+	status, err := a.Repos(c).Users.ChangeAccountPassword(username, json.OldPassword, json.NewPassword)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		var violation *model.PasswordPolicyViolation
+		var mismatch *model.PasswordHashMismatch
+		var lockedOut *model.AccountLockedOut
+		switch {
+		case errors.As(err, &violation):
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+		case errors.As(err, &mismatch), errors.As(err, &lockedOut):
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": string(err.Error())})
+		case errors.Is(err, sql.ErrNoRows):
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name " + username})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+		}
 		return
 	}
 
@@ -93,6 +126,61 @@ func (a *Allocator) ChangeAccountPassword(c *gin.Context) {
 	}
 }
 
+// ResetUserPassword Set a new password for a user who cannot give their old one
+//
+//	@Summary		Reset a user's password
+//	@Description	Set a new password for a LOCAL user in the session user's organizational units without their old password. The user has to change it on their next login. Their failed logins are left alone
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Param			resetPassword	body	model.PasswordReset	true	"Password data"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/resetPassword [post]
+func (a *Allocator) ResetUserPassword(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		username := c.Param("name")
+		if !a.CanAccessUser(scope, username) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		var json model.PasswordReset
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := a.Repos(c).Users.ResetAccountPassword(username, json.NewPassword)
+		if err != nil {
+			var violation *model.PasswordPolicyViolation
+			var notResettable *model.PasswordNotResettable
+			switch {
+			case errors.As(err, &violation), errors.As(err, &notResettable):
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
+			case errors.Is(err, sql.ErrNoRows):
+				c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name '" + username + "'"})
+			default:
+				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			}
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "The password of user '" + username + "' has been reset"})
+		} else {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name '" + username + "'"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
 // DeleteUser Remove a user for authentication and authorization
 //
 //	@Summary		Delete user
@@ -104,12 +192,17 @@ func (a *Allocator) ChangeAccountPassword(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user/{name} [delete]
 func (a *Allocator) DeleteUser(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 		username := c.Param("name")
-		canDelete, err := model.CanDeleteUser(username)
+		canDelete, err := model.Repos.Users.CanDeleteUser(username)
 		if err != nil {
 			log.Println("ERROR: Cannot check if user can be deleted: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to check if user can be deleted: " + string(err.Error())})
@@ -119,7 +212,7 @@ func (a *Allocator) DeleteUser(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "User '" + username + "' cannot be deleted. Please check the user type and role."})
 			return
 		}
-		status, err := model.DeleteUser(username)
+		status, err := a.Repos(c).Users.DeleteUser(username)
 		if err != nil {
 			log.Println("ERROR: Cannot delete user: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove user! " + string(err.Error())})
@@ -147,12 +240,17 @@ func (a *Allocator) DeleteUser(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.UserStatusMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/user/{name}/status [get]
 func (a *Allocator) GetUserStatus(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 		username := c.Param("name")
-		status, err := model.GetUserStatus(username)
+		status, err := model.Repos.Users.GetUserStatus(username)
 		if err != nil {
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to get the user " + username + " status: " + string(err.Error())})
 			return
@@ -183,11 +281,16 @@ func (a *Allocator) GetUserStatus(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.UserStatusMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user/{name}/status [patch]
 func (a *Allocator) SetUserStatus(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
-		canChangeStatus, err := model.CanChangeUserStatus(c.Param("name"))
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		canChangeStatus, err := model.Repos.Users.CanChangeUserStatus(c.Param("name"))
 		if err != nil {
 			log.Println("ERROR: Cannot check if user status can be changed: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to check if user status can be changed: " + string(err.Error())})
@@ -204,7 +307,7 @@ func (a *Allocator) SetUserStatus(c *gin.Context) {
 			return
 		}
 
-		status, err := model.SetUserStatus(username, json)
+		status, err := a.Repos(c).Users.SetUserStatus(username, json)
 		if err != nil {
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
 			return
@@ -223,6 +326,91 @@ func (a *Allocator) SetUserStatus(c *gin.Context) {
 	}
 }
 
+// UnlockUser Lift a user's lockout after failed logins
+//
+//	@Summary		Unlock a user locked out after failed logins
+//	@Description	Clear a user's failed logins and any lockout they caused
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/unlock [post]
+func (a *Allocator) UnlockUser(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		username := c.Param("name")
+		if !a.CanAccessUser(scope, username) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+
+		status, err := a.Repos(c).Users.UnlockUser(username)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if status {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "User '" + username + "' has been unlocked"})
+		} else {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name '" + username + "'"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
+// SetUserMustChangePassword Set whether a user has to change their password on their next login
+//
+//	@Summary		Force a user to change their password
+//	@Description	Set whether a user has to change their password before doing anything else
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body	model.UserMustChangePassword	true	"User Data"
+//	@Param			name	path	string	true	"User name"
+//	@Security		BasicAuth
+//	@Success		200	{object}	model.SuccessMsg
+//	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
+//	@Failure		404	{object}	model.FailureMsg
+//	@Router			/user/{name}/mustChangePassword [patch]
+func (a *Allocator) SetUserMustChangePassword(c *gin.Context) {
+	scope, authed := a.GetOrgUnitScope(c)
+	if authed {
+		username := c.Param("name")
+		if !a.CanAccessUser(scope, username) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
+		var json model.UserMustChangePassword
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := a.Repos(c).Users.SetUserMustChangePassword(username, json)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
+			return
+		}
+
+		if !status {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "No records found with user name '" + username + "'"})
+		} else if json.MustChangePassword {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "User '" + username + "' must change their password on their next login"})
+		} else {
+			c.IndentedJSON(http.StatusOK, gin.H{"message": "User '" + username + "' no longer has to change their password"})
+		}
+	} else {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+	}
+}
+
 // SetUserOuId Set the Organizational Unit Id of a user
 //
 //	@Summary		Set a user's organizational unit Id
@@ -235,17 +423,26 @@ func (a *Allocator) SetUserStatus(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.UserOrgUnitIdMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user/{name}/ouId [patch]
 func (a *Allocator) SetUserOuId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 		username := c.Param("name")
 		var json model.UserOrgUnitId
 		if err := c.ShouldBindJSON(&json); err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		}
+		if !a.InOrgUnitScope(scope, json.OrgUnitId) {
+			orgUnitScopeDenied(c, json.OrgUnitId)
+			return
+		}
 
-		status, err := model.SetUserOuId(username, json)
+		status, err := a.Repos(c).Users.SetUserOuId(username, json)
 		if err != nil {
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
 		}
@@ -276,17 +473,22 @@ func (a *Allocator) SetUserOuId(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200 {object}	model.UserRoleIdMsg
 //	@Failure		400 {object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/user/{name}/roleId [patch]
 func (a *Allocator) SetUserRoleId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 		username := c.Param("name")
 		var json model.UserRoleId
 		if err := c.ShouldBindJSON(&json); err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		}
 
-		status, err := model.SetUserRoleId(username, json)
+		status, err := a.Repos(c).Users.SetUserRoleId(username, json)
 		if err != nil {
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
 		}
@@ -317,10 +519,15 @@ func (a *Allocator) SetUserRoleId(c *gin.Context) {
 //		@Security		BasicAuth
 //		@Success		200 {object} model.UserType
 //		@Failure		400 {object} model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	 @Router			/user/{name}/typeId [patch]
 func (a *Allocator) SetUserTypeId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
+		if !a.CanAccessUser(scope, c.Param("name")) {
+			c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Insufficient access. Access denied!"})
+			return
+		}
 		username := c.Param("name")
 		var json model.UserType
 		if err := c.ShouldBindJSON(&json); err != nil {
@@ -328,7 +535,7 @@ func (a *Allocator) SetUserTypeId(c *gin.Context) {
 			return
 		}
 
-		status, err := model.SetUserTypeId(username, json)
+		status, err := a.Repos(c).Users.SetUserTypeId(username, json)
 		if err != nil {
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": string(err.Error())})
 			return
@@ -358,10 +565,11 @@ func (a *Allocator) SetUserTypeId(c *gin.Context) {
 //	@Failure		400	{object}	model.FailureMsg
 //	@Router			/users [get]
 func (a *Allocator) GetUsers(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
-		users, err := model.GetUsers()
+		users, err := model.Repos.Users.GetUsers()
 		helpers.FatalCheckError(err)
+		users = scope.FilterUsers(users)
 
 		safeUsers := make([]SafeUser, 0)
 		for _, user := range users {
@@ -393,16 +601,29 @@ func (a *Allocator) GetUsers(c *gin.Context) {
 //	@Tags           user
 //	@Produce        json
 //	@Param          ouId	path int true "Organizational Unit Id"
+//	@Param          includeDescendants	query	bool	false	"Also include users of the organizational units below"
 //	@Security		BasicAuth
 //	@Success        200 {object}	model.UsersList
 //	@Failure		400 {object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/users/ouid/{ouId} [get]
 func (a *Allocator) GetUsersByOuId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
 		ouId, _ := strconv.Atoi(c.Param("ouId"))
-		users, err := model.GetUsersByOuId(ouId)
+		if !a.InOrgUnitScope(scope, ouId) {
+			orgUnitScopeDenied(c, ouId)
+			return
+		}
+		var users []model.User
+		var err error
+		if c.Query("includeDescendants") == "true" {
+			users, err = model.Repos.Users.GetUsersByOuSubtree(ouId)
+		} else {
+			users, err = model.Repos.Users.GetUsersByOuId(ouId)
+		}
 		helpers.FatalCheckError(err)
+		users = scope.FilterUsers(users)
 
 		safeUsers := make([]SafeUser, 0)
 		for _, user := range users {
@@ -440,11 +661,12 @@ func (a *Allocator) GetUsersByOuId(c *gin.Context) {
 //	@Failure		400 {object}	model.FailureMsg
 //	@Router			/users/roleid/{roleId} [get]
 func (a *Allocator) GetUsersByRoleId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
 		roleId, _ := strconv.Atoi(c.Param("roleId"))
-		users, err := model.GetUsersByRoleId(roleId)
+		users, err := model.Repos.Users.GetUsersByRoleId(roleId)
 		helpers.FatalCheckError(err)
+		users = scope.FilterUsers(users)
 
 		safeUsers := make([]SafeUser, 0)
 		for _, user := range users {
@@ -482,11 +704,12 @@ func (a *Allocator) GetUsersByRoleId(c *gin.Context) {
 //	@Failure		400 {object}	model.FailureMsg
 //	@Router			/users/typeid/{typeId} [get]
 func (a *Allocator) GetUsersByTypeId(c *gin.Context) {
-	_, authed := a.GetUserId(c)
+	scope, authed := a.GetOrgUnitScope(c)
 	if authed {
 		typeId, _ := strconv.Atoi(c.Param("typeId"))
-		users, err := model.GetUsersByTypeId(typeId)
+		users, err := model.Repos.Users.GetUsersByTypeId(typeId)
 		helpers.FatalCheckError(err)
+		users = scope.FilterUsers(users)
 
 		safeUsers := make([]SafeUser, 0)
 		for _, user := range users {
@@ -521,10 +744,12 @@ func (a *Allocator) GetUsersByTypeId(c *gin.Context) {
 //	@Param			id	path int true "User ID"
 //	@Success		200	{object}	SafeUser
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/user/id/{id} [get]
 func (a *Allocator) GetUserById(c *gin.Context) {
+	scope, _ := a.GetOrgUnitScope(c)
 	id, _ := strconv.Atoi(c.Param("id"))
-	ent, err := model.GetUserById(id)
+	ent, err := model.Repos.Users.GetUserById(id)
 	helpers.FatalCheckError(err)
 
 	// don't return the password hash
@@ -539,6 +764,8 @@ func (a *Allocator) GetUserById(c *gin.Context) {
 	if ent.UserName == "" {
 		strId := strconv.Itoa(id)
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with user id " + strId})
+	} else if !a.InOrgUnitScope(scope, ent.OrgUnitId) {
+		orgUnitScopeDenied(c, ent.OrgUnitId)
 	} else {
 		c.IndentedJSON(http.StatusOK, safeUser)
 	}
@@ -553,10 +780,12 @@ func (a *Allocator) GetUserById(c *gin.Context) {
 //	@Param			name	path	string	true	"User name"
 //	@Success		200	{object}	SafeUser
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.FailureMsg
 //	@Router			/user/name/{name} [get]
 func (a *Allocator) GetUserByUserName(c *gin.Context) {
+	scope, _ := a.GetOrgUnitScope(c)
 	username := c.Param("name")
-	ent, err := model.GetUserByUserName(username)
+	ent, err := model.Repos.Users.GetUserByUserName(username)
 	helpers.FatalCheckError(err)
 
 	// don't return the password hash
@@ -570,6 +799,8 @@ func (a *Allocator) GetUserByUserName(c *gin.Context) {
 
 	if ent.UserName == "" {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "No records found with user name " + username})
+	} else if !a.InOrgUnitScope(scope, ent.OrgUnitId) {
+		orgUnitScopeDenied(c, ent.OrgUnitId)
 	} else {
 		c.IndentedJSON(http.StatusOK, safeUser)
 	}
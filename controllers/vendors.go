@@ -38,6 +38,7 @@ import (
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/vendor [post]
 func (a *Allocator) CreateVendor(c *gin.Context) {
 	userObject, authed := a.GetUserId(c)
@@ -48,7 +49,7 @@ func (a *Allocator) CreateVendor(c *gin.Context) {
 			return
 		}
 
-		s, err := model.CreateVendor(json, userObject.Id)
+		s, err := a.Repos(c).Vendors.CreateVendor(json, userObject.Id)
 		if s {
 			c.IndentedJSON(http.StatusOK, gin.H{"message": "Vendor '" + json.VendorName + "' has been added to system"})
 		} else {
@@ -70,12 +71,13 @@ func (a *Allocator) CreateVendor(c *gin.Context) {
 //	@Security		BasicAuth
 //	@Success		200	{object}	model.SuccessMsg
 //	@Failure		400	{object}	model.FailureMsg
+//	@Failure		403	{object}	model.PermissionDeniedMsg
 //	@Router			/vendor/{vendorId} [delete]
 func (a *Allocator) DeleteVendor(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		ouId, _ := strconv.Atoi(c.Param("ouId"))
-		status, err := model.DeleteVendor(ouId)
+		status, err := a.Repos(c).Vendors.DeleteVendor(ouId)
 		if err != nil {
 			log.Println("ERROR: Cannot delete vendor record: " + string(err.Error()))
 			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove vendor! " + string(err.Error())})
@@ -106,7 +108,7 @@ func (a *Allocator) DeleteVendor(c *gin.Context) {
 func (a *Allocator) GetVendors(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
-		vendorList, err := model.GetVendors()
+		vendorList, err := model.Repos.Vendors.GetVendors()
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
@@ -137,7 +139,7 @@ func (a *Allocator) GetVendorById(c *gin.Context) {
 	_, authed := a.GetUserId(c)
 	if authed {
 		id, _ := strconv.Atoi(c.Param("vendorId"))
-		vendor, err := model.GetVendorById(id)
+		vendor, err := model.Repos.Vendors.GetVendorById(id)
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": string(err.Error())})
 			return
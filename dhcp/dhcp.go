@@ -0,0 +1,226 @@
+package dhcp
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	FormatISC     = "isc"
+	FormatKea     = "kea"
+	FormatDnsmasq = "dnsmasq"
+)
+
+var Formats = []string{FormatISC, FormatKea, FormatDnsmasq}
+
+// Host is a single reservation, taken from a network interface and the
+// system and architecture it belongs to
+type Host struct {
+	SerialNumber string
+	DeviceId     string
+	MACAddress   string
+	IpAddress    string
+	Bitmask      int
+	Gateway      string
+	BootFilename string
+}
+
+// Options apply to every reservation in the export
+type Options struct {
+	// IPv4 address of the TFTP server handing out boot files, left out if
+	// empty
+	NextServer string
+}
+
+type UnknownFormat struct {
+	Format string
+}
+
+func (u *UnknownFormat) Error() string {
+	return "Unknown DHCP config format '" + u.Format + "'! Use one of: " + strings.Join(Formats, ", ")
+}
+
+type InvalidOptions struct {
+	Err error
+}
+
+func (i *InvalidOptions) Error() string {
+	return "Invalid DHCP config options: " + i.Err.Error()
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// Name is the host name used in the reservation. Serial numbers are not
+// unique per interface, so the device Id is appended
+func (h Host) Name() string {
+	name := h.SerialNumber
+	if h.DeviceId != "" {
+		name += "-" + h.DeviceId
+	}
+
+	return strings.Trim(unsafeNameChars.ReplaceAllString(name, "-"), "-")
+}
+
+// Netmask returns the dotted quad form of the host's bitmask
+func (h Host) Netmask() string {
+	return net.IP(net.CIDRMask(h.Bitmask, 32)).String()
+}
+
+// Subnet returns the CIDR of the network the host's address is on
+func (h Host) Subnet() string {
+	network := net.IPNet{
+		IP:   net.ParseIP(h.IpAddress).To4().Mask(net.CIDRMask(h.Bitmask, 32)),
+		Mask: net.CIDRMask(h.Bitmask, 32),
+	}
+
+	return network.String()
+}
+
+// parseIPv4 returns the dotted quad form of an IPv4 address, or nil
+func parseIPv4(address string) net.IP {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+	return ip.To4()
+}
+
+// normalize checks a host holds nothing a DHCP server would reject or read
+// as more than one value, and returns it with its MAC and IP addresses in the
+// form every format writes them in. The boot file name ends up quoted in ISC
+// config and comma separated in dnsmasq config, so it may contain neither
+// quotes, backslashes, commas nor control characters
+func (h Host) normalize() (Host, error) {
+	mac, err := net.ParseMAC(h.MACAddress)
+	if err != nil || len(mac) != 6 {
+		return Host{}, errors.New("invalid MAC address '" + h.MACAddress + "'")
+	}
+	h.MACAddress = mac.String()
+
+	ip := parseIPv4(h.IpAddress)
+	if ip == nil {
+		return Host{}, errors.New("invalid IPv4 address '" + h.IpAddress + "'")
+	}
+	h.IpAddress = ip.String()
+	if h.Bitmask < 0 || h.Bitmask > 32 {
+		return Host{}, errors.New("invalid bitmask for '" + h.IpAddress + "'")
+	}
+
+	if h.Gateway != "" {
+		gateway := parseIPv4(h.Gateway)
+		if gateway == nil {
+			return Host{}, errors.New("invalid IPv4 gateway address '" + h.Gateway + "'")
+		}
+		h.Gateway = gateway.String()
+	}
+
+	if strings.ContainsAny(h.BootFilename, "\"\\,") || strings.IndexFunc(h.BootFilename, unicode.IsControl) >= 0 {
+		return Host{}, errors.New("invalid boot file name '" + h.BootFilename + "'")
+	}
+
+	return h, nil
+}
+
+// printable drops control characters, so what a skipped host was stored with
+// cannot start a new line of the config it is noted in
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// usableHosts drops records a DHCP server would reject, returning why each
+// was skipped so the export can note it, and orders the rest by name so
+// repeated exports diff cleanly. A MAC address can only be reserved once, and
+// each host needs a name of its own, so later hosts repeating either are
+// skipped too
+func usableHosts(hosts []Host) ([]Host, []string) {
+	usable := make([]Host, 0, len(hosts))
+	skipped := make([]string, 0)
+	for _, h := range hosts {
+		normalized, err := h.normalize()
+		if err != nil {
+			skipped = append(skipped, printable(h.Name()+": "+err.Error()))
+			continue
+		}
+		usable = append(usable, normalized)
+	}
+	sort.SliceStable(usable, func(i, j int) bool { return usable[i].Name() < usable[j].Name() })
+
+	names := make(map[string]bool, len(usable))
+	macAddresses := make(map[string]bool, len(usable))
+	unique := make([]Host, 0, len(usable))
+	for _, h := range usable {
+		switch {
+		case names[h.Name()]:
+			skipped = append(skipped, h.Name()+": duplicate host name for MAC address "+h.MACAddress)
+		case macAddresses[h.MACAddress]:
+			skipped = append(skipped, h.Name()+": duplicate MAC address "+h.MACAddress)
+		default:
+			names[h.Name()] = true
+			macAddresses[h.MACAddress] = true
+			unique = append(unique, h)
+		}
+	}
+
+	return unique, skipped
+}
+
+// Render produces the reservations for hosts in the given format. It only
+// works on the records it is handed, so the daemon and setuptool can both use
+// it with their own DB access
+func Render(format string, hosts []Host, options Options) ([]byte, error) {
+	if options.NextServer != "" {
+		nextServer := parseIPv4(options.NextServer)
+		if nextServer == nil {
+			return nil, &InvalidOptions{Err: errors.New("next server '" + printable(options.NextServer) + "' is not an IPv4 address")}
+		}
+		options.NextServer = nextServer.String()
+	}
+
+	usable, skipped := usableHosts(hosts)
+	switch format {
+	case FormatISC:
+		return renderISC(usable, skipped, options), nil
+	case FormatKea:
+		return renderKea(usable, options)
+	case FormatDnsmasq:
+		return renderDnsmasq(usable, skipped, options), nil
+	default:
+		return nil, &UnknownFormat{Format: format}
+	}
+}
+
+// ContentType is the MIME type matching a format's output
+func ContentType(format string) string {
+	if format == FormatKea {
+		return "application/json; charset=utf-8"
+	}
+
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,170 @@
+package dhcp
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// hosts mixes ordinary reservations with records an operator with
+// network:write could have stored to break out of the generated config
+var hosts = []Host{
+	{SerialNumber: "SN-200", DeviceId: "eth0", MACAddress: "52-54-00-AA-BB-02", IpAddress: "10.0.1.20", Bitmask: 24, Gateway: "10.0.1.1", BootFilename: "grubx64.efi"},
+	{SerialNumber: "SN-100", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, Gateway: "10.0.0.1", BootFilename: "pxelinux.0"},
+	{SerialNumber: "SN-100", DeviceId: "eth1", MACAddress: "5254.00aa.bb03", IpAddress: "192.168.5.10", Bitmask: 16},
+	// sanitizes to the same name as SN-100 eth0
+	{SerialNumber: "SN 100", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:04", IpAddress: "10.0.0.11", Bitmask: 24},
+	// the same interface stored twice
+	{SerialNumber: "SN-300", DeviceId: "eth0", MACAddress: "52:54:00:AA:BB:01", IpAddress: "10.0.0.30", Bitmask: 24},
+	{SerialNumber: "EVIL-1", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:10", IpAddress: "10.0.0.50", Bitmask: 24, Gateway: "10.0.0.1;\n}\nhost pwned {\n\thardware ethernet 00:00:00:00:00:01"},
+	{SerialNumber: "EVIL-2", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:11", IpAddress: "10.0.0.51", Bitmask: 24, BootFilename: "x\";\n\toption routers 6.6.6.6;#"},
+	{SerialNumber: "EVIL-3", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:12", IpAddress: "10.0.0.52", Bitmask: 24, BootFilename: "pxelinux.0,,6.6.6.6"},
+	{SerialNumber: "EVIL-4", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:13\ndhcp-range=0.0.0.0,255.255.255.255", IpAddress: "10.0.0.53", Bitmask: 24},
+	{SerialNumber: "EVIL-5", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:14", IpAddress: "10.0.0.54\nconf-file=/etc/shadow", Bitmask: 24},
+	{SerialNumber: "EVIL-6", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:15", IpAddress: "10.0.0.55", Bitmask: 24, BootFilename: "a\\b"},
+	{SerialNumber: "BAD", DeviceId: "eth0", MACAddress: "52:54:00:aa:bb:16", IpAddress: "fe80::1", Bitmask: 64},
+	{SerialNumber: "BAD", DeviceId: "eth1", MACAddress: "02:00:5e:10:00:00:00:01", IpAddress: "10.0.0.56", Bitmask: 24},
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		err := os.WriteFile(path, got, 0644)
+		if err != nil {
+			t.Fatalf("cannot update %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read %s: %v", path, err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s output differs from %s:\n%s", name, path, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			out, err := Render(format, hosts, Options{NextServer: "10.0.0.2"})
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			checkGolden(t, format+".golden", out)
+
+			// every line has to be one the generator wrote, not one smuggled
+			// in through a stored value
+			for _, line := range strings.Split(string(out), "\n") {
+				for _, smuggled := range []string{"pwned", "6.6.6.6", "dhcp-range", "conf-file"} {
+					if strings.Contains(line, smuggled) && !strings.HasPrefix(line, "# skipped ") {
+						t.Errorf("%s output carries %q outside a comment: %q", format, smuggled, line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	for _, format := range Formats {
+		out, err := Render(format, nil, Options{})
+		if err != nil {
+			t.Fatalf("Render(%s): %v", format, err)
+		}
+		checkGolden(t, format+"-empty.golden", out)
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	_, err := Render("bind", hosts, Options{})
+	var unknown *UnknownFormat
+	if !errors.As(err, &unknown) {
+		t.Errorf("Render with an unknown format = %v, want UnknownFormat", err)
+	}
+
+	for _, nextServer := range []string{"tftp.example.com", "10.0.0.2;\n\tfilename \"evil\"", "fe80::1", "10.0.0.256"} {
+		_, err = Render(FormatISC, hosts, Options{NextServer: nextServer})
+		var invalid *InvalidOptions
+		if !errors.As(err, &invalid) {
+			t.Errorf("Render with next server %q = %v, want InvalidOptions", nextServer, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		host Host
+		want Host
+		ok   bool
+	}{
+		{
+			name: "canonical forms",
+			host: Host{MACAddress: "52-54-00-AA-BB-01", IpAddress: "::ffff:10.0.0.10", Bitmask: 24, Gateway: "10.0.0.1", BootFilename: "efi/grubx64.efi"},
+			want: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, Gateway: "10.0.0.1", BootFilename: "efi/grubx64.efi"},
+			ok:   true,
+		},
+		{name: "IPoIB MAC", host: Host{MACAddress: "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01", IpAddress: "10.0.0.10", Bitmask: 24}},
+		{name: "IPv6 address", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "fe80::1", Bitmask: 24}},
+		{name: "bitmask", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 33}},
+		{name: "gateway host name", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, Gateway: "router"}},
+		{name: "gateway list", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, Gateway: "10.0.0.1, 10.0.0.2"}},
+		{name: "boot file quote", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, BootFilename: "a\"b"}},
+		{name: "boot file backslash", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, BootFilename: "a\\b"}},
+		{name: "boot file comma", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, BootFilename: "a,b"}},
+		{name: "boot file newline", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, BootFilename: "a\nb"}},
+		{name: "boot file NUL", host: Host{MACAddress: "52:54:00:aa:bb:01", IpAddress: "10.0.0.10", Bitmask: 24, BootFilename: "a\x00b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.host.normalize()
+			if (err == nil) != tt.ok {
+				t.Fatalf("normalize() error = %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && got != tt.want {
+				t.Errorf("normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		host Host
+		want string
+	}{
+		{Host{SerialNumber: "SN-100", DeviceId: "eth0"}, "SN-100-eth0"},
+		{Host{SerialNumber: "SN-100"}, "SN-100"},
+		{Host{SerialNumber: " SN 100", DeviceId: "eno1.20"}, "SN-100-eno1-20"},
+		{Host{SerialNumber: "x;}\nhost y {", DeviceId: ""}, "x-host-y"},
+	}
+	for _, tt := range tests {
+		if got := tt.host.Name(); got != tt.want {
+			t.Errorf("Name() of %q %q = %q, want %q", tt.host.SerialNumber, tt.host.DeviceId, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package dhcp
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"strings"
+)
+
+// every host gets a tag named after it so its router and boot file options
+// only apply to that host
+func renderDnsmasq(hosts []Host, skipped []string, options Options) []byte {
+	var out strings.Builder
+	out.WriteString("# generated by allocatord, do not edit by hand\n")
+	for _, reason := range skipped {
+		out.WriteString("# skipped " + reason + "\n")
+	}
+
+	for _, h := range hosts {
+		name := h.Name()
+		out.WriteString("\ndhcp-host=" + h.MACAddress + ",set:" + name + "," + h.IpAddress + "," + name + "\n")
+		out.WriteString("dhcp-option=tag:" + name + ",option:netmask," + h.Netmask() + "\n")
+		if h.Gateway != "" {
+			out.WriteString("dhcp-option=tag:" + name + ",option:router," + h.Gateway + "\n")
+		}
+		if h.BootFilename != "" {
+			boot := "dhcp-boot=tag:" + name + "," + h.BootFilename
+			if options.NextServer != "" {
+				boot += ",," + options.NextServer
+			}
+			out.WriteString(boot + "\n")
+		}
+	}
+
+	return []byte(out.String())
+}
@@ -0,0 +1,50 @@
+package dhcp
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"strings"
+)
+
+func renderISC(hosts []Host, skipped []string, options Options) []byte {
+	var out strings.Builder
+	out.WriteString("# generated by allocatord, do not edit by hand\n")
+	for _, reason := range skipped {
+		out.WriteString("# skipped " + reason + "\n")
+	}
+
+	for _, h := range hosts {
+		out.WriteString("\nhost " + h.Name() + " {\n")
+		out.WriteString("\thardware ethernet " + h.MACAddress + ";\n")
+		out.WriteString("\tfixed-address " + h.IpAddress + ";\n")
+		out.WriteString("\toption subnet-mask " + h.Netmask() + ";\n")
+		if h.Gateway != "" {
+			out.WriteString("\toption routers " + h.Gateway + ";\n")
+		}
+		if options.NextServer != "" {
+			out.WriteString("\tnext-server " + options.NextServer + ";\n")
+		}
+		if h.BootFilename != "" {
+			out.WriteString("\tfilename \"" + h.BootFilename + "\";\n")
+		}
+		out.WriteString("}\n")
+	}
+
+	return []byte(out.String())
+}
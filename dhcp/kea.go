@@ -0,0 +1,87 @@
+package dhcp
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"encoding/json"
+)
+
+type keaOption struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+type keaReservation struct {
+	HwAddress    string      `json:"hw-address"`
+	IpAddress    string      `json:"ip-address"`
+	Hostname     string      `json:"hostname"`
+	NextServer   string      `json:"next-server,omitempty"`
+	BootFileName string      `json:"boot-file-name,omitempty"`
+	OptionData   []keaOption `json:"option-data,omitempty"`
+}
+
+type keaSubnet struct {
+	Id           int              `json:"id"`
+	Subnet       string           `json:"subnet"`
+	Reservations []keaReservation `json:"reservations"`
+}
+
+type keaConfig struct {
+	Dhcp4 struct {
+		Subnet4 []keaSubnet `json:"subnet4"`
+	} `json:"Dhcp4"`
+}
+
+// Kea keeps reservations inside their subnet, so hosts are grouped by the
+// network their address is on. Subnet Ids are numbered in order of first
+// appearance, which is stable because hosts arrive sorted. JSON has no
+// comments, so unlike the other formats skipped hosts are not noted
+func renderKea(hosts []Host, options Options) ([]byte, error) {
+	config := keaConfig{}
+	config.Dhcp4.Subnet4 = make([]keaSubnet, 0)
+	subnets := make(map[string]int)
+	for _, h := range hosts {
+		reservation := keaReservation{
+			HwAddress:    h.MACAddress,
+			IpAddress:    h.IpAddress,
+			Hostname:     h.Name(),
+			NextServer:   options.NextServer,
+			BootFileName: h.BootFilename,
+		}
+		if h.Gateway != "" {
+			reservation.OptionData = []keaOption{{Name: "routers", Data: h.Gateway}}
+		}
+
+		subnet := h.Subnet()
+		index, known := subnets[subnet]
+		if !known {
+			index = len(config.Dhcp4.Subnet4)
+			subnets[subnet] = index
+			config.Dhcp4.Subnet4 = append(config.Dhcp4.Subnet4, keaSubnet{Id: index + 1, Subnet: subnet})
+		}
+		config.Dhcp4.Subnet4[index].Reservations = append(config.Dhcp4.Subnet4[index].Reservations, reservation)
+	}
+
+	out, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
@@ -22,14 +22,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/architecture": {
+        "/answerFileTemplate": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new architecture",
+                "description": "Store a Go text/template as the next version of the given template type for an OS family. The latest version is the one rendered for systems",
                 "consumes": [
                     "application/json"
                 ],
@@ -37,17 +37,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "architectures"
+                    "answer-files"
                 ],
-                "summary": "Register architecture",
+                "summary": "Create answer file template version",
                 "parameters": [
                     {
-                        "description": "Architecture data",
-                        "name": "architecture",
+                        "description": "Answer file template data",
+                        "name": "template",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Architecture"
+                            "$ref": "#/definitions/model.AnswerFileTemplate"
                         }
                     }
                 ],
@@ -55,7 +55,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.AnswerFileTemplate"
                         }
                     },
                     "400": {
@@ -63,30 +63,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/architecture/byId/{architectureId}": {
+        "/answerFileTemplate/byId/{templateId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve an architecture by its Id",
+                "description": "Retrieve an answer file template by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "architectures"
+                    "answer-files"
                 ],
-                "summary": "Retrieve an architecture by its Id",
+                "summary": "Retrieve an answer file template by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Architecture ID",
-                        "name": "architectureId",
+                        "description": "Answer File Template ID",
+                        "name": "templateId",
                         "in": "path",
                         "required": true
                     }
@@ -95,7 +101,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Architecture"
+                            "$ref": "#/definitions/model.AnswerFileTemplate"
                         }
                     },
                     "400": {
@@ -103,39 +109,50 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/architecture/byName/{architectureName}": {
-            "get": {
+        "/answerFileTemplate/render": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve an architecture by its name",
-                "produces": [
+                "description": "Render either the given template content or a stored template version against a system, so template changes can be checked before they are saved",
+                "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "text/plain"
+                ],
                 "tags": [
-                    "architectures"
+                    "answer-files"
                 ],
-                "summary": "Retrieve an architecture by its name",
+                "summary": "Dry-run render an answer file",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Architecture Name",
-                        "name": "architectureName",
-                        "in": "path",
-                        "required": true
+                        "description": "Render request",
+                        "name": "render",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.AnswerFileRenderRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Architecture"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -143,33 +160,42 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/architecture/{architectureId}": {
+        "/answerFileTemplate/{templateId}": {
             "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete an architecture by Id",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete a single version of an answer file template by Id. Deleting the latest version makes the previous one current again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "architectures"
+                    "answer-files"
                 ],
-                "summary": "Delete architecture",
+                "summary": "Delete answer file template version",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Architecture Id",
-                        "name": "architectureId",
+                        "description": "Answer File Template Id",
+                        "name": "templateId",
                         "in": "path",
                         "required": true
                     }
@@ -186,30 +212,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/architectures": {
+        "/answerFileTemplates": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all architectures",
+                "description": "Retrieve every version of every answer file template",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "architectures"
+                    "answer-files"
                 ],
-                "summary": "Retrieve list of all architectures",
+                "summary": "Retrieve list of all answer file templates",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.ArchitectureList"
+                            "$ref": "#/definitions/model.AnswerFileTemplateList"
                         }
                     },
                     "400": {
@@ -221,40 +253,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/building": {
-            "post": {
+        "/answerFileTemplates/byOSFamilyId/{osFamilyId}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new building",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve every version of every answer file template for an OS family",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "answer-files"
                 ],
-                "summary": "Register building",
+                "summary": "Retrieve answer file templates by OS family Id",
                 "parameters": [
                     {
-                        "description": "Building data",
-                        "name": "building",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Building"
-                        }
+                        "type": "integer",
+                        "description": "OS Family ID",
+                        "name": "osFamilyId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.AnswerFileTemplateList"
                         }
                     },
                     "400": {
@@ -266,35 +293,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/building/byId/{buildingId}": {
-            "get": {
+        "/architecture": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a building by its Id",
+                "description": "Add a new architecture",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "architectures"
                 ],
-                "summary": "Retrieve a building by its Id",
+                "summary": "Register architecture",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Building ID",
-                        "name": "buildingId",
-                        "in": "path",
-                        "required": true
+                        "description": "Architecture data",
+                        "name": "architecture",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Architecture"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Building"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -302,30 +334,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/building/byShortName/{buildingShortName}": {
+        "/architecture/byId/{architectureId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a building by its abbreviated name",
+                "description": "Retrieve an architecture by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "architectures"
                 ],
-                "summary": "Retrieve a building by its abbreviated name",
+                "summary": "Retrieve an architecture by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Building abbreviation",
-                        "name": "buildingShortName",
+                        "description": "Architecture ID",
+                        "name": "architectureId",
                         "in": "path",
                         "required": true
                     }
@@ -334,7 +372,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Building"
+                            "$ref": "#/definitions/model.Architecture"
                         }
                     },
                     "400": {
@@ -346,29 +384,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/building/{buildingId}": {
-            "delete": {
+        "/architecture/byName/{architectureName}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a building by Id",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve an architecture by its name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "architectures"
                 ],
-                "summary": "Delete building",
+                "summary": "Retrieve an architecture by its name",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Building Id",
-                        "name": "buildingId",
+                        "description": "Architecture Name",
+                        "name": "architectureName",
                         "in": "path",
                         "required": true
                     }
@@ -377,7 +412,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.Architecture"
                         }
                     },
                     "400": {
@@ -387,37 +422,33 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/architecture/{architectureId}": {
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Update a building by its Id",
+                "description": "Delete an architecture by Id",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "architectures"
                 ],
-                "summary": "Update a building by its Id",
+                "summary": "Delete architecture",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Building ID",
-                        "name": "buildingId",
+                        "description": "Architecture Id",
+                        "name": "architectureId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Building data",
-                        "name": "buildingData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Building"
-                        }
                     }
                 ],
                 "responses": {
@@ -432,30 +463,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/buildings": {
+        "/architectures": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all building objects",
+                "description": "Retrieve list of all architectures",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "architectures"
                 ],
-                "summary": "Retrieve list of all building objects",
+                "summary": "Retrieve list of all architectures",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.BuildingList"
+                            "$ref": "#/definitions/model.ArchitectureList"
                         }
                     },
                     "400": {
@@ -467,40 +504,58 @@ const docTemplate = `{
                 }
             }
         },
-        "/machineRole": {
-            "post": {
+        "/audit": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new machine role",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve the audit trail of changes, oldest first, optionally narrowed down by table, user, entity and time range",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machine-roles"
+                    "audit"
                 ],
-                "summary": "Register machine role",
+                "summary": "Retrieve the audit trail",
                 "parameters": [
                     {
-                        "description": "Machine Role data",
-                        "name": "machineRole",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.MachineRole"
-                        }
+                        "type": "string",
+                        "description": "Table changed, such as Systems",
+                        "name": "table",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the user who made the change",
+                        "name": "userId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the changed row",
+                        "name": "entityId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest change date, as an RFC 3339 timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest change date, as an RFC 3339 timestamp",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.AuditEntryList"
                         }
                     },
                     "400": {
@@ -508,30 +563,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/machineRole/byId/{machineRoleId}": {
+        "/audit/byId/{entryId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a machine role by its Id",
+                "description": "Retrieve an audit trail entry by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machine-roles"
+                    "audit"
                 ],
-                "summary": "Retrieve a machine role by its Id",
+                "summary": "Retrieve an audit trail entry by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Machine Role ID",
-                        "name": "machineRoleId",
+                        "description": "Audit Entry ID",
+                        "name": "entryId",
                         "in": "path",
                         "required": true
                     }
@@ -540,7 +601,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.MachineRole"
+                            "$ref": "#/definitions/model.AuditEntry"
                         }
                     },
                     "400": {
@@ -548,124 +609,56 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/machineRole/{machineRoleId}": {
-            "delete": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Delete a machine role by Id",
-                "consumes": [
-                    "application/json"
-                ],
+        "/boot/ipxe/{macAddress}": {
+            "get": {
+                "description": "Retrieve the iPXE script for the machine owning a MAC address. Systems flagged for reimage chain into the imaging client, all others boot from local disk. Intended to be chained from iPXE with ${net0/mac}",
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "machine-roles"
+                    "boot"
                 ],
-                "summary": "Delete machine role",
+                "summary": "Retrieve iPXE boot script",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Machine Role Id",
-                        "name": "machineRoleId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.FailureMsg"
-                        }
-                    }
-                }
-            },
-            "patch": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Update a machine role by its Id",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "machine-roles"
-                ],
-                "summary": "Update a machine role by its Id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Machine Role ID",
-                        "name": "machineRoleId",
+                        "type": "string",
+                        "description": "Network Interface MAC Address",
+                        "name": "macAddress",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Machine Role data",
-                        "name": "machineRoleData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.MachineRole"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
-                    }
-                }
-            }
-        },
-        "/machineRoles": {
-            "get": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Retrieve list of all machine roles",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "machine-roles"
-                ],
-                "summary": "Retrieve list of all machine roles",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.MachineRoleList"
-                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
@@ -673,14 +666,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/networkInterface": {
+        "/building": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new network interface",
+                "description": "Add a new building",
                 "consumes": [
                     "application/json"
                 ],
@@ -688,17 +681,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Register network interface",
+                "summary": "Register building",
                 "parameters": [
                     {
-                        "description": "Network Interface data",
-                        "name": "networkInterface",
+                        "description": "Building data",
+                        "name": "building",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.NetworkInterface"
+                            "$ref": "#/definitions/model.Building"
                         }
                     }
                 ],
@@ -714,70 +707,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
-                    }
-                }
-            }
-        },
-        "/networkInterface/byId/{networkInterfaceId}": {
-            "get": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Retrieve a network interface by its Id",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "network-interfaces"
-                ],
-                "summary": "Retrieve a network interface by its Id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Network Interface ID",
-                        "name": "networkInterfaceId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.NetworkInterface"
-                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/model.FailureMsg"
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
                         }
                     }
                 }
             }
         },
-        "/networkInterface/byIpAddress/{networkInterfaceIpAddress}": {
+        "/building/byId/{buildingId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a network interface by its IP Address",
+                "description": "Retrieve a building by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Retrieve a network interface by its IP Address",
+                "summary": "Retrieve a building by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Network Interface IP Address",
-                        "name": "networkInterfaceIpAddress",
+                        "description": "Building ID",
+                        "name": "buildingId",
                         "in": "path",
                         "required": true
                     }
@@ -786,7 +745,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.NetworkInterface"
+                            "$ref": "#/definitions/model.Building"
                         }
                     },
                     "400": {
@@ -798,26 +757,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/networkInterface/byMACAddress/{networkInterfaceMACAddress}": {
+        "/building/byShortName/{buildingShortName}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a network interface by its MAC Address",
+                "description": "Retrieve a building by its abbreviated name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Retrieve a network interface by its MAC Address",
+                "summary": "Retrieve a building by its abbreviated name",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Network Interface MAC Address",
-                        "name": "networkInterfaceMACAddress",
+                        "description": "Building abbreviation",
+                        "name": "buildingShortName",
                         "in": "path",
                         "required": true
                     }
@@ -826,7 +785,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.NetworkInterface"
+                            "$ref": "#/definitions/model.Building"
                         }
                     },
                     "400": {
@@ -838,14 +797,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/networkInterface/{networkInterfaceId}": {
+        "/building/{buildingId}": {
             "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a network interface by Id",
+                "description": "Delete a building by Id",
                 "consumes": [
                     "application/json"
                 ],
@@ -853,14 +812,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Delete network interface",
+                "summary": "Delete building",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Network Interface Id",
-                        "name": "networkInterfaceId",
+                        "description": "Building Id",
+                        "name": "buildingId",
                         "in": "path",
                         "required": true
                     }
@@ -877,6 +836,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             },
@@ -886,29 +851,29 @@ const docTemplate = `{
                         "BasicAuth": []
                     }
                 ],
-                "description": "Update a network interface by its Id",
+                "description": "Update a building by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Update a network interface by its Id",
+                "summary": "Update a building by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Network Interface ID",
-                        "name": "networkInterfaceId",
+                        "description": "Building ID",
+                        "name": "buildingId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Network Interface data",
-                        "name": "networkInterfaceData",
+                        "description": "Building data",
+                        "name": "buildingData",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.MachineRole"
+                            "$ref": "#/definitions/model.Building"
                         }
                     }
                 ],
@@ -924,30 +889,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/networkInterfaces": {
+        "/buildings": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all network interfaces",
+                "description": "Retrieve list of all building objects",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "buildings"
                 ],
-                "summary": "Retrieve list of all network interfaces",
+                "summary": "Retrieve list of all building objects",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.NetworkInterfaces"
+                            "$ref": "#/definitions/model.BuildingList"
                         }
                     },
                     "400": {
@@ -959,35 +930,47 @@ const docTemplate = `{
                 }
             }
         },
-        "/networkInterfaces/{systemId}": {
+        "/dhcp/config/{format}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve the list of network interfaces for a system's Id",
+                "description": "Export a host reservation for every network interface of the systems in the session user's organizational units, with per-architecture PXE boot file names, as ISC dhcpd, Kea JSON or dnsmasq config",
                 "produces": [
+                    "text/plain",
                     "application/json"
                 ],
                 "tags": [
-                    "network-interfaces"
+                    "dhcp"
                 ],
-                "summary": "Retrieve the list of network interfaces for a system's Id",
+                "summary": "Export DHCP host reservations",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "System ID",
-                        "name": "systemId",
+                        "enum": [
+                            "isc",
+                            "kea",
+                            "dnsmasq"
+                        ],
+                        "type": "string",
+                        "description": "Config format",
+                        "name": "format",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IPv4 address of the TFTP server handed to PXE clients",
+                        "name": "nextServer",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.NetworkInterfaces"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -999,14 +982,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/operatingSystem": {
+        "/discover": {
             "post": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Add a new operating system",
+                "description": "Send the serial number, MAC addresses, disks, CPU cores, RAM, architecture and vendor of unknown hardware. It is recorded as a system waiting for an operator to approve it, along with its network interfaces and storage volumes",
                 "consumes": [
                     "application/json"
                 ],
@@ -1014,25 +992,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "discovery"
                 ],
-                "summary": "Register operating system",
+                "summary": "Discover a system",
                 "parameters": [
                     {
-                        "description": "Operating System data",
-                        "name": "operatingSystem",
+                        "description": "What the imaging client found",
+                        "name": "report",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystem"
+                            "$ref": "#/definitions/model.DiscoveryReport"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.DiscoveryMsg"
                         }
                     },
                     "400": {
@@ -1040,39 +1018,57 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/operatingSystem/byId/{osId}": {
-            "get": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
+        "/enroll": {
+            "post": {
+                "description": "Send the certificate request of an imaging client, to wait for an operator to approve it. The certificate is issued to the serial number of the system, whatever subject the request asks for. Send the same request again to check on it: the reply is 202 until it has been issued, whether or not the serial number is known, and 200 along with the certificate and the certificate authority that issued it after",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Retrieve an operating system by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "machine-certificates"
                 ],
-                "summary": "Retrieve an operating system by its Id",
+                "summary": "Enroll a system",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Operating System ID",
-                        "name": "osId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                        "description": "Serial number and PEM certificate request",
+                        "name": "enrollment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineEnrollment"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystem"
+                            "$ref": "#/definitions/model.MachineEnrollmentMsg"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineEnrollmentMsg"
                         }
                     },
                     "400": {
@@ -1080,18 +1076,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/operatingSystem/{osId}": {
-            "delete": {
+        "/hostVarLayer": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete an operating system by Id",
+                "description": "Create or replace the variables shared by every system in a scope. Layers apply in the order global, orgUnit, building, machineRole and finally the system's own HostVars, later ones overriding earlier ones key by key. The global layer takes scope Id 0. Organizational unit layers may be set for the units in the session user's scope, every other layer needs a global scope",
                 "consumes": [
                     "application/json"
                 ],
@@ -1099,16 +1101,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "host-vars"
                 ],
-                "summary": "Delete operating system",
+                "summary": "Set host var layer",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Operating System Id",
-                        "name": "osId",
-                        "in": "path",
-                        "required": true
+                        "description": "Host var layer data",
+                        "name": "layer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.HostVarLayer"
+                        }
                     }
                 ],
                 "responses": {
@@ -1123,46 +1127,58 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/hostVarLayer/{scope}/{scopeId}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Update an operating system by its Id",
+                "description": "Retrieve the variables of a scope",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "host-vars"
                 ],
-                "summary": "Update an operating system by its Id",
+                "summary": "Retrieve a host var layer",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Operating System ID",
-                        "name": "operatingSystemId",
+                        "enum": [
+                            "global",
+                            "orgUnit",
+                            "building",
+                            "machineRole"
+                        ],
+                        "type": "string",
+                        "description": "Scope",
+                        "name": "scope",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Operating System data",
-                        "name": "operatingSystemData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OperatingSystem"
-                        }
+                        "type": "integer",
+                        "description": "Scope Id",
+                        "name": "scopeId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.HostVarLayer"
                         }
                     },
                     "400": {
@@ -1170,30 +1186,56 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
-            }
-        },
-        "/operatingSystems": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all operating systems",
+                "description": "Delete the variables of a scope",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "host-vars"
+                ],
+                "summary": "Delete host var layer",
+                "parameters": [
+                    {
+                        "enum": [
+                            "global",
+                            "orgUnit",
+                            "building",
+                            "machineRole"
+                        ],
+                        "type": "string",
+                        "description": "Scope",
+                        "name": "scope",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Scope Id",
+                        "name": "scopeId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all operating systems",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemList"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -1201,39 +1243,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/operatingSystems/byFamilyId/{osFamilyId}": {
+        "/hostVarLayers": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve operating systems by family Id",
+                "description": "Retrieve the variables of every scope that has any",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
-                ],
-                "summary": "Retrieve operating systems by family Id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Operating System Family ID",
-                        "name": "osFamilyId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "host-vars"
                 ],
+                "summary": "Retrieve list of all host var layers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemList"
+                            "$ref": "#/definitions/model.HostVarLayerList"
                         }
                     },
                     "400": {
@@ -1245,35 +1284,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/operatingSystems/byVendorId/{osVendorId}": {
-            "get": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
+        "/login": {
+            "post": {
+                "description": "Check a user's password, and the TOTP or recovery code in otp if they use two-factor authentication, and start a session. The session cookie set authenticates later requests until the session is logged out, revoked, or times out",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Retrieve an operating system by its vendor Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-systems"
+                    "sessions"
                 ],
-                "summary": "Retrieve an operating system by its vendor Id",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Operating System Vendor ID",
-                        "name": "osVendorId",
-                        "in": "path",
-                        "required": true
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LoginCredentials"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemList"
+                            "$ref": "#/definitions/model.LoginMsg"
                         }
                     },
                     "400": {
@@ -1281,39 +1320,31 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/organizationalUnit": {
+        "/logout": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new organizational unit",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "End the session the request was made with and clear the session cookie",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orgs"
-                ],
-                "summary": "Register organizational unit",
-                "parameters": [
-                    {
-                        "description": "Organizational Unit data",
-                        "name": "orgUnit",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OrgUnit"
-                        }
-                    }
+                    "sessions"
                 ],
+                "summary": "Log out",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1326,30 +1357,52 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/organizationalUnit/byId/{ouId}": {
+        "/machine/answerFile/{systemId}/{templateType}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Retrieve an organizational unit by its Id",
+                "description": "Render the latest template of the given type for the system's OS family. This is what installers fetch",
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "orgs"
+                    "answer-files"
                 ],
-                "summary": "Retrieve an organizational unit by its Id",
+                "summary": "Retrieve a system's answer file",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Organizationl Unit ID",
-                        "name": "ouId",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "kickstart",
+                            "autoyast",
+                            "preseed",
+                            "cloud-init"
+                        ],
+                        "type": "string",
+                        "description": "Template type",
+                        "name": "templateType",
                         "in": "path",
                         "required": true
                     }
@@ -1358,7 +1411,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OrgUnit"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1366,18 +1419,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/organizationalUnit/{ouId}": {
-            "delete": {
+        "/machine/certificate": {
+            "post": {
                 "security": [
                     {
-                        "BasicAuth": []
+                        "MachineToken": []
                     }
                 ],
-                "description": "Delete an organizational unit",
+                "description": "Issue a client certificate straight away to a system authenticated with its current certificate or machine token, for renewing it before it expires",
                 "consumes": [
                     "application/json"
                 ],
@@ -1385,23 +1450,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orgs"
+                    "machine"
                 ],
-                "summary": "Delete OU",
+                "summary": "Renew machine certificate",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Organizational Unit Id",
-                        "name": "ouId",
-                        "in": "path",
-                        "required": true
+                        "description": "PEM certificate request",
+                        "name": "certificate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProposedMachineCertificate"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.MachineCertificateMsg"
                         }
                     },
                     "400": {
@@ -1409,30 +1476,54 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/organizationalUnits": {
+        "/machine/checkIn/byMACAddress/{macAddress}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Retrieve list of all organizational units",
+                "description": "Retrieve whether a system needs to be reimaged, and with what image and settings, by a MAC address",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orgs"
+                    "machine"
+                ],
+                "summary": "Machine check-in by MAC address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Network Interface MAC Address",
+                        "name": "macAddress",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all organizational units",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OrgUnitList"
+                            "$ref": "#/definitions/model.ProvisioningDocument"
                         }
                     },
                     "400": {
@@ -1440,30 +1531,60 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osFamilies": {
+        "/machine/checkIn/bySerialNumber/{serialNumber}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Retrieve list of all storage volumes",
+                "description": "Retrieve whether a system needs to be reimaged, and with what image and settings, by its serial number",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-families"
+                    "machine"
+                ],
+                "summary": "Machine check-in by serial number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "System serial number",
+                        "name": "serialNumber",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all storage volumes",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemFamilyList"
+                            "$ref": "#/definitions/model.ProvisioningDocument"
                         }
                     },
                     "400": {
@@ -1471,44 +1592,60 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osFamily": {
-            "post": {
+        "/machine/hostVars/{systemId}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Add a new operating system family",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
+                "description": "Merge the global, org unit, building, machine role and system host var layers of a system, reporting which layer each value came from and whether the result satisfies the machine role's schema",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-families"
+                    "host-vars"
                 ],
-                "summary": "Register operating system family",
+                "summary": "Retrieve resolved host vars",
                 "parameters": [
                     {
-                        "description": "Operating System Family data",
-                        "name": "osFamily",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemFamily"
-                        }
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.ResolvedHostVars"
                         }
                     },
                     "400": {
@@ -1516,30 +1653,45 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osFamily/byId/{osFamilyId}": {
+        "/machine/reimage/{systemId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Retrieve an operating system family by its Id",
+                "description": "Retrieve the state and event history of a system's most recent reimage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-families"
+                    "reimages"
                 ],
-                "summary": "Retrieve an operating system family by its Id",
+                "summary": "Retrieve current reimage state",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Operating System Family ID",
-                        "name": "osFamilyId",
+                        "description": "System Id",
+                        "name": "systemId",
                         "in": "path",
                         "required": true
                     }
@@ -1548,7 +1700,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemFamily"
+                            "$ref": "#/definitions/model.ReimageRequest"
                         }
                     },
                     "400": {
@@ -1556,39 +1708,66 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osFamily/byName/{osFamilyName}": {
-            "get": {
+        "/machine/reimage/{systemId}/status": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
                     }
                 ],
-                "description": "Retrieve an operating system family by its name",
+                "description": "Record a progress event for a system's active reimage, moving it to the reported state",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-families"
+                    "machine"
                 ],
-                "summary": "Retrieve an operating system family by its name",
+                "summary": "Report reimage progress",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Operating System Family Name",
-                        "name": "osFamilyName",
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Reimage status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageStatusReport"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemFamily"
+                            "$ref": "#/definitions/model.ReimageRequest"
                         }
                     },
                     "400": {
@@ -1596,33 +1775,42 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osFamily/{storageVolumeId}": {
-            "delete": {
+        "/machineCertificate/byId/{certificateId}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete an operating system family",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve a client certificate or certificate request of a system by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-families"
+                    "machine-certificates"
                 ],
-                "summary": "Delete operating system family",
+                "summary": "Retrieve a machine certificate by Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Operating System Family Id",
-                        "name": "osFamilyId",
+                        "description": "Machine certificate Id",
+                        "name": "certificateId",
                         "in": "path",
                         "required": true
                     }
@@ -1631,11 +1819,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.MachineCertificate"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
@@ -1643,33 +1837,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/osVersion": {
-            "post": {
+        "/machineCertificate/{certificateId}": {
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new operating system version",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revoke a client certificate of a system. It stops being accepted immediately",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-versions"
+                    "machine-certificates"
                 ],
-                "summary": "Register operating system version",
+                "summary": "Revoke machine certificate",
                 "parameters": [
                     {
-                        "description": "Operating System Version data",
-                        "name": "osVersion",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemVersion"
-                        }
+                        "type": "integer",
+                        "description": "Machine certificate Id",
+                        "name": "certificateId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1679,8 +1868,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
@@ -1688,26 +1883,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/osVersion/byId/{osVersionId}": {
-            "get": {
+        "/machineCertificate/{certificateId}/approve": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve an operating system version by its Id",
+                "description": "Issue the client certificate a system asked for while enrolling, to the serial number the system has now",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-versions"
+                    "machine-certificates"
                 ],
-                "summary": "Retrieve an operating system version by its Id",
+                "summary": "Approve certificate request",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Operating System Version ID",
-                        "name": "osVersionId",
+                        "description": "Machine certificate Id",
+                        "name": "certificateId",
                         "in": "path",
                         "required": true
                     }
@@ -1716,7 +1911,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemVersion"
+                            "$ref": "#/definitions/model.MachineCertificateMsg"
                         }
                     },
                     "400": {
@@ -1724,33 +1919,42 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/osVersion/{osVersionId}": {
-            "delete": {
+        "/machineCertificate/{certificateId}/reject": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete an operating system version by Id",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Turn down the client certificate a system asked for while enrolling, letting it send a new request",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-versions"
+                    "machine-certificates"
                 ],
-                "summary": "Delete operating system version",
+                "summary": "Reject certificate request",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Operating System Version Id",
-                        "name": "osVersionId",
+                        "description": "Machine certificate Id",
+                        "name": "certificateId",
                         "in": "path",
                         "required": true
                     }
@@ -1762,39 +1966,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.FailureMsg"
-                        }
-                    }
-                }
-            }
-        },
-        "/osVersions": {
-            "get": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Retrieve list of all operating systems versions",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "operating-system-versions"
-                ],
-                "summary": "Retrieve list of all operating systems versions",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystemVersionList"
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
@@ -1802,26 +1981,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/osVersions/byOSId/{osId}": {
+        "/machineCertificates/byStatus/{status}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve operating system versions by operating system Id",
+                "description": "Retrieve the client certificates and certificate requests of systems with a status of pending, issued, rejected or revoked. Pending requests are the ones waiting for approval",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "operating-system-versions"
+                    "machine-certificates"
                 ],
-                "summary": "Retrieve operating system versions by operating system Id",
+                "summary": "Retrieve machine certificates by status",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Operating System ID",
-                        "name": "osId",
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
                         "in": "path",
                         "required": true
                     }
@@ -1830,7 +2009,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OperatingSystem"
+                            "$ref": "#/definitions/model.MachineCertificateList"
                         }
                     },
                     "400": {
@@ -1838,18 +2017,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/role": {
+        "/machineRole": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new role",
+                "description": "Add a new machine role",
                 "consumes": [
                     "application/json"
                 ],
@@ -1857,17 +2048,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "role"
+                    "machine-roles"
                 ],
-                "summary": "Register role",
+                "summary": "Register machine role",
                 "parameters": [
                     {
-                        "description": "Role data",
-                        "name": "role",
+                        "description": "Machine Role data",
+                        "name": "machineRole",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Role"
+                            "$ref": "#/definitions/model.MachineRole"
                         }
                     }
                 ],
@@ -1883,30 +2074,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/role/byId/{roleId}": {
+        "/machineRole/byId/{machineRoleId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a role by its Id",
+                "description": "Retrieve a machine role by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "role"
+                    "machine-roles"
                 ],
-                "summary": "Retrieve a role by its Id",
+                "summary": "Retrieve a machine role by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Role ID",
-                        "name": "roleId",
+                        "description": "Machine Role ID",
+                        "name": "machineRoleId",
                         "in": "path",
                         "required": true
                     }
@@ -1915,7 +2112,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Role"
+                            "$ref": "#/definitions/model.MachineRole"
                         }
                     },
                     "400": {
@@ -1927,26 +2124,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/role/byName/{roleName}": {
-            "get": {
+        "/machineRole/{machineRoleId}": {
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a role by its role name",
+                "description": "Delete a machine role by Id",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "role"
+                    "machine-roles"
                 ],
-                "summary": "Retrieve a role by its role name",
+                "summary": "Delete machine role",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Role Name",
-                        "name": "roleName",
+                        "description": "Machine Role Id",
+                        "name": "machineRoleId",
                         "in": "path",
                         "required": true
                     }
@@ -1955,7 +2155,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Role"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -1963,35 +2163,45 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
-            }
-        },
-        "/role/{roleId}": {
-            "delete": {
+            },
+            "patch": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a role",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Update a machine role by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "role"
+                    "machine-roles"
                 ],
-                "summary": "Delete role",
+                "summary": "Update a machine role by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Role Id",
-                        "name": "roleId",
+                        "description": "Machine Role ID",
+                        "name": "machineRoleId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Machine Role data",
+                        "name": "machineRoleData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineRole"
+                        }
                     }
                 ],
                 "responses": {
@@ -2006,30 +2216,45 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/roles": {
+        "/machineRole/{machineRoleId}/hostVarsSchema": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all roles",
+                "description": "Retrieve the JSON Schema attached to a machine role",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "role"
+                    "host-vars"
+                ],
+                "summary": "Retrieve machine role host vars schema",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Machine Role Id",
+                        "name": "machineRoleId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all roles",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.RolesList"
+                            "$ref": "#/definitions/model.MachineRoleHostVarsSchema"
                         }
                     },
                     "400": {
@@ -2037,18 +2262,22 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
-            }
-        },
-        "/storageVolume": {
+            },
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new storage volume",
+                "description": "Attach a JSON Schema to a machine role. The merged host vars of every system in the role are validated against it when the system is created or updated",
                 "consumes": [
                     "application/json"
                 ],
@@ -2056,17 +2285,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "host-vars"
                 ],
-                "summary": "Register storage volume",
+                "summary": "Set machine role host vars schema",
                 "parameters": [
                     {
-                        "description": "Storage Volume data",
-                        "name": "storageVolume",
+                        "type": "integer",
+                        "description": "Machine Role Id",
+                        "name": "machineRoleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Host vars schema",
+                        "name": "schema",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolume"
+                            "$ref": "#/definitions/model.MachineRoleHostVarsSchema"
                         }
                     }
                 ],
@@ -2082,30 +2318,40 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
-            }
-        },
-        "/storageVolume/byId/{storageVolumeId}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a storage volume by its Id",
+                "description": "Detach the JSON Schema from a machine role, so its systems' host vars are no longer validated",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "host-vars"
                 ],
-                "summary": "Retrieve a storage volume by its Id",
+                "summary": "Delete machine role host vars schema",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Storage Volume ID",
-                        "name": "storageVolumeId",
+                        "description": "Machine Role Id",
+                        "name": "machineRoleId",
                         "in": "path",
                         "required": true
                     }
@@ -2114,7 +2360,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolume"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2122,42 +2368,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/storageVolume/{storageVolumeId}": {
-            "delete": {
+        "/machineRoles": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a storage volume",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve list of all machine roles",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
-                ],
-                "summary": "Delete storage volume",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Storage Volume Id",
-                        "name": "storageVolumeId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "machine-roles"
                 ],
+                "summary": "Retrieve list of all machine roles",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.MachineRoleList"
                         }
                     },
                     "400": {
@@ -2167,36 +2407,34 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/maintenanceWindow": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Update a storage volume by its Id",
+                "description": "Add a weekly maintenance window to a building. Weekday is 0 (Sunday) to 6 and startTime is HH:MM, both in UTC",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "maintenance-windows"
                 ],
-                "summary": "Update a storage volume by its Id",
+                "summary": "Register maintenance window",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Storage Volume ID",
-                        "name": "storageVolumeId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Storage Volume data",
-                        "name": "storageVolumeData",
+                        "description": "Maintenance window data",
+                        "name": "maintenanceWindow",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolume"
+                            "$ref": "#/definitions/model.MaintenanceWindow"
                         }
                     }
                 ],
@@ -2212,37 +2450,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/storageVolume/{systemId}/byLabel/{storageVolumeLabel}": {
-            "get": {
+        "/maintenanceWindow/{windowId}": {
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a storage volume by its volume label and system Id",
+                "description": "Delete a maintenance window by Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "maintenance-windows"
                 ],
-                "summary": "Retrieve a storage volume by its volume label and system Id",
+                "summary": "Delete maintenance window",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "System ID",
-                        "name": "systemId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Storage Volume Label",
-                        "name": "storageVolumeLabel",
+                        "description": "Maintenance Window Id",
+                        "name": "windowId",
                         "in": "path",
                         "required": true
                     }
@@ -2251,7 +2488,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolume"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2259,30 +2496,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/storageVolumes": {
+        "/maintenanceWindows": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all storage volumes",
+                "description": "Retrieve list of all maintenance windows",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "maintenance-windows"
                 ],
-                "summary": "Retrieve list of all storage volumes",
+                "summary": "Retrieve list of all maintenance windows",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolumes"
+                            "$ref": "#/definitions/model.MaintenanceWindowList"
                         }
                     },
                     "400": {
@@ -2294,26 +2537,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/storageVolumes/{systemId}": {
+        "/maintenanceWindows/byBuildingId/{buildingId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve storage volumes by system Id",
+                "description": "Retrieve list of maintenance windows by building Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "storage-volumes"
+                    "maintenance-windows"
                 ],
-                "summary": "Retrieve storage volumes by system Id",
+                "summary": "Retrieve list of maintenance windows by building Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "System ID",
-                        "name": "systemId",
+                        "description": "Building ID",
+                        "name": "buildingId",
                         "in": "path",
                         "required": true
                     }
@@ -2322,7 +2565,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.StorageVolumes"
+                            "$ref": "#/definitions/model.MaintenanceWindowList"
                         }
                     },
                     "400": {
@@ -2334,14 +2577,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user": {
+        "/networkInterface": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new user",
+                "description": "Add a new network interface",
                 "consumes": [
                     "application/json"
                 ],
@@ -2349,17 +2592,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Register user",
+                "summary": "Register network interface",
                 "parameters": [
                     {
-                        "description": "User Data",
-                        "name": "user",
+                        "description": "Network Interface data",
+                        "name": "networkInterface",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.ProposedUser"
+                            "$ref": "#/definitions/model.NetworkInterface"
                         }
                     }
                 ],
@@ -2375,25 +2618,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/id/{id}": {
+        "/networkInterface/byId/{networkInterfaceId}": {
             "get": {
-                "description": "Retrieve a user by their Id",
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a network interface by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Retrieve a user by their Id",
+                "summary": "Retrieve a network interface by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
+                        "description": "Network Interface ID",
+                        "name": "networkInterfaceId",
                         "in": "path",
                         "required": true
                     }
@@ -2402,7 +2656,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.SafeUser"
+                            "$ref": "#/definitions/model.NetworkInterface"
                         }
                     },
                     "400": {
@@ -2410,25 +2664,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/name/{name}": {
+        "/networkInterface/byIpAddress/{networkInterfaceIpAddress}": {
             "get": {
-                "description": "Retrieve a user by their UserName",
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a network interface by its IP Address",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Retrieve a user by their UserName",
+                "summary": "Retrieve a network interface by its IP Address",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
+                        "type": "integer",
+                        "description": "Network Interface IP Address",
+                        "name": "networkInterfaceIpAddress",
                         "in": "path",
                         "required": true
                     }
@@ -2437,7 +2702,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.SafeUser"
+                            "$ref": "#/definitions/model.NetworkInterface"
                         }
                     },
                     "400": {
@@ -2445,33 +2710,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/{name}": {
-            "delete": {
+        "/networkInterface/byMACAddress/{networkInterfaceMACAddress}": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve a network interface by its MAC Address",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Delete user",
+                "summary": "Retrieve a network interface by its MAC Address",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
+                        "type": "integer",
+                        "description": "Network Interface MAC Address",
+                        "name": "networkInterfaceMACAddress",
                         "in": "path",
                         "required": true
                     }
@@ -2480,7 +2748,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.SuccessMsg"
+                            "$ref": "#/definitions/model.NetworkInterface"
                         }
                     },
                     "400": {
@@ -2488,37 +2756,41 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
-            },
-            "patch": {
-                "description": "Change password",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            }
+        },
+        "/networkInterface/{networkInterfaceId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a network interface by Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Change password",
+                "summary": "Delete network interface",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
+                        "type": "integer",
+                        "description": "Network Interface Id",
+                        "name": "networkInterfaceId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Password data",
-                        "name": "changePassword",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.PasswordChange"
-                        }
                     }
                 ],
                 "responses": {
@@ -2533,51 +2805,52 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
-            }
-        },
-        "/user/{name}/ouId": {
+            },
             "patch": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Set a user's organizational unit Id",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Update a network interface by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Set a user's organizational unit Id",
+                "summary": "Update a network interface by its Id",
                 "parameters": [
                     {
-                        "description": "Organizational Unit Id",
-                        "name": "ouId",
+                        "type": "integer",
+                        "description": "Network Interface ID",
+                        "name": "networkInterfaceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Network Interface data",
+                        "name": "networkInterfaceData",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.UserOrgUnitId"
+                            "$ref": "#/definitions/model.MachineRole"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserOrgUnitIdMsg"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2585,51 +2858,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/{name}/roleId": {
-            "patch": {
+        "/networkInterfaces": {
+            "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Set a user's role Id",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve list of all network interfaces",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
-                ],
-                "summary": "Set a user's role Id",
-                "parameters": [
-                    {
-                        "description": "Role Id",
-                        "name": "roleId",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.UserRoleId"
-                        }
-                    },
-                    {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    }
+                    "network-interfaces"
                 ],
+                "summary": "Retrieve list of all network interfaces",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserRoleIdMsg"
+                            "$ref": "#/definitions/model.NetworkInterfaces"
                         }
                     },
                     "400": {
@@ -2637,33 +2895,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/{name}/status": {
+        "/networkInterfaces/{systemId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a user's active status",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve the list of network interfaces for a system's Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "network-interfaces"
                 ],
-                "summary": "Retrieve a user's active status. Can be either 'enabled' or 'locked'",
+                "summary": "Retrieve the list of network interfaces for a system's Id",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
+                        "type": "integer",
+                        "description": "System ID",
+                        "name": "systemId",
                         "in": "path",
                         "required": true
                     }
@@ -2672,7 +2933,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserStatusMsg"
+                            "$ref": "#/definitions/model.NetworkInterfaces"
                         }
                     },
                     "400": {
@@ -2680,41 +2941,39 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
-            },
-            "patch": {
-                "security": [
-                    {
-                        "BasicAuth": []
-                    }
-                ],
-                "description": "Set a user's active status",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/oidc/callback": {
+            "get": {
+                "description": "Where the OpenID provider sends the user back to. Exchanges the authorization code for tokens and starts a session for the user the ID token names, creating their account on their first login",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "oidc"
                 ],
-                "summary": "Set a user's active status. Can be either 'enabled' or 'locked'",
+                "summary": "OIDC login callback",
                 "parameters": [
                     {
-                        "description": "User Data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.UserStatus"
-                        }
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "User name",
-                        "name": "name",
-                        "in": "path",
+                        "description": "State the login was started with",
+                        "name": "state",
+                        "in": "query",
                         "required": true
                     }
                 ],
@@ -2722,7 +2981,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserStatusMsg"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2730,18 +2989,59 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
                     }
                 }
             }
         },
-        "/user/{name}/typeId": {
-            "patch": {
+        "/oidc/login": {
+            "get": {
+                "description": "Redirect to the OpenID provider to log in with the authorization code flow and PKCE",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Log in with OIDC",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/operatingSystem": {
+            "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Set a user's type Id",
+                "description": "Add a new operating system",
                 "consumes": [
                     "application/json"
                 ],
@@ -2749,32 +3049,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "operating-systems"
                 ],
-                "summary": "Set a user's type Id",
+                "summary": "Register operating system",
                 "parameters": [
                     {
-                        "description": "User Type Id",
-                        "name": "typeId",
+                        "description": "Operating System data",
+                        "name": "operatingSystem",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.UserType"
+                            "$ref": "#/definitions/model.OperatingSystem"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "User name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserType"
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2782,30 +3075,45 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/users": {
+        "/operatingSystem/byId/{osId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all users",
+                "description": "Retrieve an operating system by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "operating-systems"
+                ],
+                "summary": "Retrieve an operating system by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System ID",
+                        "name": "osId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UsersList"
+                            "$ref": "#/definitions/model.OperatingSystem"
                         }
                     },
                     "400": {
@@ -2817,27 +3125,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/ouid/{ouId}": {
-            "get": {
+        "/operatingSystem/{osId}": {
+            "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of users by Organizational Unit Id",
+                "description": "Delete an operating system by Id",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "operating-systems"
                 ],
-                "summary": "Retrieve list of users by Organizational Unit Id",
+                "summary": "Delete operating system",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Organizational Unit Id",
-                        "name": "ouId",
-                        "in": "path",
+                        "description": "Operating System Id",
+                        "name": "osId",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -2845,7 +3156,60 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UsersList"
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Update an operating system by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-systems"
+                ],
+                "summary": "Update an operating system by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System ID",
+                        "name": "operatingSystemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Operating System data",
+                        "name": "operatingSystemData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystem"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
                         }
                     },
                     "400": {
@@ -2853,30 +3217,67 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/users/roleid/{roleId}": {
+        "/operatingSystems": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of users by role Id",
+                "description": "Retrieve list of all operating systems",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "operating-systems"
                 ],
-                "summary": "Retrieve list of users by role Id",
+                "summary": "Retrieve list of all operating systems",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/operatingSystems/byFamilyId/{osFamilyId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve operating systems by family Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-systems"
+                ],
+                "summary": "Retrieve operating systems by family Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Role Id",
-                        "name": "roleId",
+                        "description": "Operating System Family ID",
+                        "name": "osFamilyId",
                         "in": "path",
                         "required": true
                     }
@@ -2885,7 +3286,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UsersList"
+                            "$ref": "#/definitions/model.OperatingSystemList"
                         }
                     },
                     "400": {
@@ -2897,26 +3298,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/typeid/{typeId}": {
+        "/operatingSystems/byVendorId/{osVendorId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of users by type Id",
+                "description": "Retrieve an operating system by its vendor Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "operating-systems"
                 ],
-                "summary": "Retrieve list of users by type Id",
+                "summary": "Retrieve an operating system by its vendor Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Type Id",
-                        "name": "typeId",
+                        "description": "Operating System Vendor ID",
+                        "name": "osVendorId",
                         "in": "path",
                         "required": true
                     }
@@ -2925,7 +3326,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UsersList"
+                            "$ref": "#/definitions/model.OperatingSystemList"
                         }
                     },
                     "400": {
@@ -2937,14 +3338,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/vendor": {
+        "/organizationalUnit": {
             "post": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Add a new vendor",
+                "description": "Add a new organizational unit",
                 "consumes": [
                     "application/json"
                 ],
@@ -2952,17 +3353,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "vendors"
+                    "orgs"
                 ],
-                "summary": "Register vendor",
+                "summary": "Register organizational unit",
                 "parameters": [
                     {
-                        "description": "Vendor data",
-                        "name": "vendor",
+                        "description": "Organizational Unit data",
+                        "name": "orgUnit",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Vendor"
+                            "$ref": "#/definitions/model.OrgUnit"
                         }
                     }
                 ],
@@ -2978,30 +3379,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/vendor/byId/{ouId}": {
+        "/organizationalUnit/byId/{ouId}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve a vendor by its Id",
+                "description": "Retrieve an organizational unit by its Id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "vendors"
+                    "orgs"
                 ],
-                "summary": "Retrieve a vendor by its Id",
+                "summary": "Retrieve an organizational unit by its Id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Vendor ID",
-                        "name": "vendorId",
+                        "description": "Organizationl Unit ID",
+                        "name": "ouId",
                         "in": "path",
                         "required": true
                     }
@@ -3010,7 +3417,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Vendor"
+                            "$ref": "#/definitions/model.OrgUnit"
                         }
                     },
                     "400": {
@@ -3022,14 +3429,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/vendor/{vendorId}": {
+        "/organizationalUnit/{ouId}": {
             "delete": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Delete a vendor",
+                "description": "Delete an organizational unit",
                 "consumes": [
                     "application/json"
                 ],
@@ -3037,14 +3444,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "vendors"
+                    "orgs"
                 ],
-                "summary": "Delete Vendor",
+                "summary": "Delete OU",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Vendor Id",
-                        "name": "vendorId",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
                         "in": "path",
                         "required": true
                     }
@@ -3061,30 +3468,45 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.FailureMsg"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
                     }
                 }
             }
         },
-        "/vendorss": {
+        "/organizationalUnit/{ouId}/children": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "Retrieve list of all vendors",
+                "description": "Retrieve the organizational units directly below an organizational unit",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "vendors"
+                    "orgs"
+                ],
+                "summary": "Retrieve children of an organizational unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Retrieve list of all vendors",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.VendorList"
+                            "$ref": "#/definitions/model.OrgUnitList"
                         }
                     },
                     "400": {
@@ -3095,186 +3517,5975 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.SafeUser": {
-            "type": "object",
-            "properties": {
-                "creationDate": {
-                    "type": "string"
-                },
-                "fullName": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "orgUnitId": {
-                    "type": "integer"
-                },
-                "roleId": {
-                    "type": "integer"
-                },
-                "userName": {
-                    "type": "string"
+        },
+        "/organizationalUnit/{ouId}/delegation/{delegatedOuId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Stop the members of an organizational unit from managing the systems and users of another",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Remove delegation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delegated Organizational Unit Id",
+                        "name": "delegatedOuId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
                 }
             }
         },
-        "model.Architecture": {
-            "type": "object",
-            "properties": {
-                "Id": {
-                    "type": "integer"
-                },
-                "creationDate": {
-                    "type": "string"
-                },
-                "creatorId": {
-                    "type": "integer"
+        "/organizationalUnit/{ouId}/delegations": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the organizational units whose systems and users the members of an organizational unit may also manage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Retrieve delegations of an organizational unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OrgUnitDelegationList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Let the members of an organizational unit see and manage the systems and users of another",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Delegate organizational unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Delegated organizational unit",
+                        "name": "delegation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OrgUnitDelegation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizationalUnit/{ouId}/parent": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Put an organizational unit, along with everything below it, under a new parent. A parent Id of 0 makes it a top level organizational unit",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Move organizational unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New parent",
+                        "name": "parent",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OrgUnitParent"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizationalUnit/{ouId}/subtree": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve an organizational unit along with every organizational unit below it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Retrieve subtree of an organizational unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OrgUnitList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizationalUnits": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all organizational units",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Retrieve list of all organizational units",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OrgUnitList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osFamilies": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all storage volumes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-families"
+                ],
+                "summary": "Retrieve list of all storage volumes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemFamilyList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osFamily": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new operating system family",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-families"
+                ],
+                "summary": "Register operating system family",
+                "parameters": [
+                    {
+                        "description": "Operating System Family data",
+                        "name": "osFamily",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemFamily"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osFamily/byId/{osFamilyId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve an operating system family by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-families"
+                ],
+                "summary": "Retrieve an operating system family by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System Family ID",
+                        "name": "osFamilyId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemFamily"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osFamily/byName/{osFamilyName}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve an operating system family by its name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-families"
+                ],
+                "summary": "Retrieve an operating system family by its name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operating System Family Name",
+                        "name": "osFamilyName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemFamily"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osFamily/{storageVolumeId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete an operating system family",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-families"
+                ],
+                "summary": "Delete operating system family",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System Family Id",
+                        "name": "osFamilyId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osVersion": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new operating system version",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-versions"
+                ],
+                "summary": "Register operating system version",
+                "parameters": [
+                    {
+                        "description": "Operating System Version data",
+                        "name": "osVersion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemVersion"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osVersion/byId/{osVersionId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve an operating system version by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-versions"
+                ],
+                "summary": "Retrieve an operating system version by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System Version ID",
+                        "name": "osVersionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osVersion/{osVersionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete an operating system version by Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-versions"
+                ],
+                "summary": "Delete operating system version",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System Version Id",
+                        "name": "osVersionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osVersions": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all operating systems versions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-versions"
+                ],
+                "summary": "Retrieve list of all operating systems versions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystemVersionList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/osVersions/byOSId/{osId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve operating system versions by operating system Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operating-system-versions"
+                ],
+                "summary": "Retrieve operating system versions by operating system Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Operating System ID",
+                        "name": "osId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OperatingSystem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/permissions": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the permissions that can be granted to roles",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Retrieve list of all permissions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionList"
+                        }
+                    }
+                }
+            }
+        },
+        "/reimageBatch": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Schedule a reimage of every system matching the given machine role, organizational unit, building or system Ids. Reimages start once scheduledDate (RFC 3339) has passed and each building's maintenance window is open, with at most maxPerBuilding systems reimaging per building at once (0 for no limit)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimage-batches"
+                ],
+                "summary": "Schedule reimage batch",
+                "parameters": [
+                    {
+                        "description": "Reimage batch data",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProposedReimageBatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/reimageBatch/byId/{batchId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a reimage batch by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimage-batches"
+                ],
+                "summary": "Retrieve a reimage batch by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reimage Batch ID",
+                        "name": "batchId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/reimageBatch/{batchId}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Cancel a scheduled or running reimage batch. Every system of the batch has to be in one of your organizational units. Reimages the batch already started are left to finish",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimage-batches"
+                ],
+                "summary": "Cancel reimage batch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reimage Batch Id",
+                        "name": "batchId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/reimageBatches": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all reimage batches whose systems are all in your organizational units, newest first, along with the state of each of their systems",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimage-batches"
+                ],
+                "summary": "Retrieve list of all reimage batches",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageBatchList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Register role",
+                "parameters": [
+                    {
+                        "description": "Role data",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/byId/{roleId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a role by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Retrieve a role by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/byName/{roleName}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a role by its role name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Retrieve a role by its role name",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Name",
+                        "name": "roleName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/{roleId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Delete role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/{roleId}/permission/{permission}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Revoke a permission from a role",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Revoke permission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Permission",
+                        "name": "permission",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/{roleId}/permissions": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the permissions granted to a role",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Retrieve the permissions of a role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RolePermissionList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Grant a permission to a role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Grant permission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Permission to grant",
+                        "name": "permission",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RolePermissionGrant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/role/{roleId}/requireTwoFactor": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set whether the LOCAL users of a role must log in with a second factor. Users who have not enrolled can only enroll until they have",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Require two-factor authentication for a role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Two-factor requirement",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RoleRequireTwoFactor"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all roles",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Retrieve list of all roles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RolesList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/session/{sessionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "End one of the authenticated user's sessions, such as one left open on another machine. It stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Revoke own session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session Id",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the sessions of the authenticated user that have not ended, marking the one the request was made with as current",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Retrieve own sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SessionList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolume": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new storage volume",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Register storage volume",
+                "parameters": [
+                    {
+                        "description": "Storage Volume data",
+                        "name": "storageVolume",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolume"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolume/byId/{storageVolumeId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a storage volume by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Retrieve a storage volume by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Storage Volume ID",
+                        "name": "storageVolumeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolume"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolume/{storageVolumeId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a storage volume",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Delete storage volume",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Storage Volume Id",
+                        "name": "storageVolumeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Update a storage volume by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Update a storage volume by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Storage Volume ID",
+                        "name": "storageVolumeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Storage Volume data",
+                        "name": "storageVolumeData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolume"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolume/{systemId}/byLabel/{storageVolumeLabel}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a storage volume by its volume label and system Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Retrieve a storage volume by its volume label and system Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System ID",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Storage Volume Label",
+                        "name": "storageVolumeLabel",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolume"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolumes": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all storage volumes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Retrieve list of all storage volumes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolumes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/storageVolumes/{systemId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve storage volumes by system Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage-volumes"
+                ],
+                "summary": "Retrieve storage volumes by system Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System ID",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.StorageVolumes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Register system",
+                "parameters": [
+                    {
+                        "description": "System data",
+                        "name": "system",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.System"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/byId/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a system by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve a system by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.System"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a system by Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Delete system",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Update a system by its Id. The reimage flag is ignored, request a reimage of the system instead",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Update a system by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System ID",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "System data",
+                        "name": "systemData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.System"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/answerFile/{templateType}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
+                    }
+                ],
+                "description": "Render the latest template of the given type for the system's OS family. This is what installers fetch",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "answer-files"
+                ],
+                "summary": "Retrieve a system's answer file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "kickstart",
+                            "autoyast",
+                            "preseed",
+                            "cloud-init"
+                        ],
+                        "type": "string",
+                        "description": "Template type",
+                        "name": "templateType",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Assign a system waiting for approval its organizational unit, building, machine role and operating system, making it an active system. Model, vendor and architecture are only needed when what the imaging client reported was not recognized",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discovery"
+                ],
+                "summary": "Approve system",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "What to assign the system",
+                        "name": "approval",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemApproval"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/certificate": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Issue a client certificate to a system straight away for a certificate request, without it waiting for approval",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machine-certificates"
+                ],
+                "summary": "Issue machine certificate",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PEM certificate request",
+                        "name": "certificate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProposedMachineCertificate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineCertificateMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/certificates": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve every client certificate and certificate request of a system",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machine-certificates"
+                ],
+                "summary": "Retrieve a system's machine certificates",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineCertificateList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/discovery": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a system along with what its imaging client reported, and the network interfaces and storage volumes it was discovered with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discovery"
+                ],
+                "summary": "Get discovered system",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DiscoveredSystem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/hostVars": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
+                    }
+                ],
+                "description": "Merge the global, org unit, building, machine role and system host var layers of a system, reporting which layer each value came from and whether the result satisfies the machine role's schema",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "host-vars"
+                ],
+                "summary": "Retrieve resolved host vars",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ResolvedHostVars"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/machineToken": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Issue a new machine token for a system. The token is only shown once",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machine-tokens"
+                ],
+                "summary": "Issue machine token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineTokenMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Revoke a system's machine token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machine-tokens"
+                ],
+                "summary": "Revoke machine token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Replace a system's machine token with a new one. The old token stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machine-tokens"
+                ],
+                "summary": "Rotate machine token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MachineTokenMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/reimage": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "MachineToken": []
+                    }
+                ],
+                "description": "Retrieve the state and event history of a system's most recent reimage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimages"
+                ],
+                "summary": "Retrieve current reimage state",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Start a new reimage of a system and flag it for reimaging on its next check-in",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimages"
+                ],
+                "summary": "Request a reimage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/reimage/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Fail a system's active reimage, for reimages stuck in a state the machine will not report its way out of, and clear the system's reimage flag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimages"
+                ],
+                "summary": "Cancel a reimage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageRequest"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/reimages": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve every reimage of a system, newest first, along with their events",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reimages"
+                ],
+                "summary": "Retrieve reimage history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReimageRequestList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/{systemId}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Remove a system waiting for approval, along with the network interfaces and storage volumes it was discovered with. Should the hardware boot again it is discovered anew",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discovery"
+                ],
+                "summary": "Reject system",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "System Id",
+                        "name": "systemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all system objects",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of all system objects",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byBuildingId/{buildingId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their building Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their building Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Building ID",
+                        "name": "buildingId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byCpuCores/{coreCount}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their number of CPU cores",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their number of CPU cores",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "CPU core count",
+                        "name": "coreCount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byMachineRoleId/{machineRoleId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their machine role Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their machine role Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Machine Role ID",
+                        "name": "machineRoleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byOuId/{ouId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their organizational unit Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their organizational unit Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit ID",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also include systems of the organizational units below",
+                        "name": "includeDescendants",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byRAM/{memoryCount}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their amount of installed RAM",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their amount of installed RAM",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Installed RAM",
+                        "name": "memoryCount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/byVendorId/{vendorId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of systems by their vendor Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "systems"
+                ],
+                "summary": "Retrieve list of systems by their vendor Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vendor ID",
+                        "name": "vendorId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SystemList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/systems/pending": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve all systems waiting for approval, with what their imaging clients reported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discovery"
+                ],
+                "summary": "Get pending systems",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DiscoveredSystemList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Create an API token automation can authenticate as the session user with, sent as 'Authorization: Bearer \u003ctoken\u003e'. Scopes limit the token to some of the permissions of the user's role. A scoped token also needs the 'read' scope for GET requests, and cannot manage the user's account or act as a machine. The token is only shown once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-tokens"
+                ],
+                "summary": "Create API token",
+                "parameters": [
+                    {
+                        "description": "API token data",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProposedApiToken"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ApiTokenMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/{tokenId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Revoke one of the session user's API tokens. It stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-tokens"
+                ],
+                "summary": "Revoke own API token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API token Id",
+                        "name": "tokenId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the API tokens of the session user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-tokens"
+                ],
+                "summary": "Retrieve own API tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ApiTokenList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/twoFactor": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve whether the session user has two-factor authentication enabled and how many unused recovery codes they have left",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Retrieve own two-factor status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/twoFactor/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Enable two-factor authentication for the session user with a code from their authenticator app. The recovery codes returned can each stand in for a code once, and are only shown this once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Confirm two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": "TOTP code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecoveryCodesMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/twoFactor/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication for the session user, which takes a current TOTP code or a recovery code. Users of roles requiring it have to enroll again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "TOTP or recovery code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/twoFactor/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Generate a TOTP secret for the session user, returned with its otpauth:// provisioning URI and a QR code of it as a PNG data URI. It takes effect once confirmed with a code from the authenticator app. Starting over replaces an unconfirmed secret",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Enroll in two-factor authentication",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/twoFactor/recoveryCodes": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Hand the session user a new set of recovery codes, voiding the old ones. Takes a current TOTP code or a recovery code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Replace recovery codes",
+                "parameters": [
+                    {
+                        "description": "TOTP or recovery code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecoveryCodesMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "User Data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProposedUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/id/{id}": {
+            "get": {
+                "description": "Retrieve a user by their Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve a user by their Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SafeUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/name/{name}": {
+            "get": {
+                "description": "Retrieve a user by their UserName",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve a user by their UserName",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SafeUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Change the password of the session user. A wrong old password counts as a failed login. Users with the users:admin permission reset other users' passwords instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Password data",
+                        "name": "changePassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/mustChangePassword": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set whether a user has to change their password before doing anything else",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Force a user to change their password",
+                "parameters": [
+                    {
+                        "description": "User Data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserMustChangePassword"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/ouId": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set a user's organizational unit Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Set a user's organizational unit Id",
+                "parameters": [
+                    {
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserOrgUnitId"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserOrgUnitIdMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/resetPassword": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set a new password for a LOCAL user in the session user's organizational units without their old password. The user has to change it on their next login. Their failed logins are left alone",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reset a user's password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Password data",
+                        "name": "resetPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordReset"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/roleId": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set a user's role Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Set a user's role Id",
+                "parameters": [
+                    {
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserRoleId"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserRoleIdMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/session/{sessionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "End one of a user's sessions. It stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Revoke a user's session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Session Id",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the sessions of a user that have not ended",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Retrieve a user's sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SessionList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "End every session of a user, logging them out everywhere",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Revoke all of a user's sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/status": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a user's active status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve a user's active status. Can be either 'enabled' or 'locked'",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserStatusMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set a user's active status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Set a user's active status. Can be either 'enabled' or 'locked'",
+                "parameters": [
+                    {
+                        "description": "User Data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserStatus"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserStatusMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/token/{tokenId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Revoke one of a user's API tokens. It stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-tokens"
+                ],
+                "summary": "Revoke a user's API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "API token Id",
+                        "name": "tokenId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the API tokens of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-tokens"
+                ],
+                "summary": "Retrieve a user's API tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ApiTokenList"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/twoFactor": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Remove the TOTP secret and recovery codes of a user who lost them, so they can log in with their password and enroll again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Reset a user's two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/typeId": {
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Set a user's type Id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Set a user's type Id",
+                "parameters": [
+                    {
+                        "description": "User Type Id",
+                        "name": "typeId",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserType"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserType"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{name}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Clear a user's failed logins and any lockout they caused",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Unlock a user locked out after failed logins",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all users",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve list of all users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsersList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/ouid/{ouId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of users by Organizational Unit Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve list of users by Organizational Unit Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organizational Unit Id",
+                        "name": "ouId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also include users of the organizational units below",
+                        "name": "includeDescendants",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsersList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/roleid/{roleId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of users by role Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve list of users by role Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role Id",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsersList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/typeid/{typeId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of users by type Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve list of users by type Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Type Id",
+                        "name": "typeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsersList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/vendor": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Add a new vendor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vendors"
+                ],
+                "summary": "Register vendor",
+                "parameters": [
+                    {
+                        "description": "Vendor data",
+                        "name": "vendor",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Vendor"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/vendor/byId/{ouId}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve a vendor by its Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vendors"
+                ],
+                "summary": "Retrieve a vendor by its Id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vendor ID",
+                        "name": "vendorId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Vendor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/vendor/{vendorId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Delete a vendor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vendors"
+                ],
+                "summary": "Delete Vendor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vendor Id",
+                        "name": "vendorId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SuccessMsg"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/vendorss": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve list of all vendors",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vendors"
+                ],
+                "summary": "Retrieve list of all vendors",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.VendorList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.FailureMsg"
+                        }
+                    }
+                }
+            }
+        },
+        "/whoami": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Retrieve the user a request is authenticated as, how it was authenticated and, for sessions, the session",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Retrieve the authenticated user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WhoAmI"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionDeniedMsg"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.SafeUser": {
+            "type": "object",
+            "properties": {
+                "creationDate": {
+                    "type": "string"
+                },
+                "fullName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "orgUnitId": {
+                    "type": "integer"
+                },
+                "roleId": {
+                    "type": "integer"
+                },
+                "userName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AnswerFileRenderRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                },
+                "templateId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AnswerFileTemplate": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "osFamilyId": {
+                    "type": "integer"
+                },
+                "templateType": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AnswerFileTemplateList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AnswerFileTemplate"
+                    }
+                }
+            }
+        },
+        "model.ApiToken": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "expiryDate": {
+                    "type": "string"
+                },
+                "lastUsedDate": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tokenName": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ApiTokenList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ApiToken"
+                    }
+                }
+            }
+        },
+        "model.ApiTokenMsg": {
+            "type": "object",
+            "properties": {
+                "apiToken": {
+                    "$ref": "#/definitions/model.ApiToken"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Architecture": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "bootFilename": {
+                    "type": "string"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "initrdPath": {
+                    "type": "string"
+                },
+                "iseName": {
+                    "type": "string"
+                },
+                "kernelArgs": {
+                    "type": "string"
+                },
+                "kernelPath": {
+                    "type": "string"
+                },
+                "registerSize": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ArchitectureList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Architecture"
+                    }
+                }
+            }
+        },
+        "model.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "changeClass": {
+                    "type": "string"
+                },
+                "changeDate": {
+                    "type": "string"
+                },
+                "changedById": {
+                    "type": "integer"
+                },
+                "changedByName": {
+                    "type": "string"
+                },
+                "entityId": {
+                    "type": "integer"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "rowAfter": {
+                    "type": "object"
+                },
+                "rowBefore": {
+                    "type": "object"
+                },
+                "tableChanged": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AuditEntryList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditEntry"
+                    }
+                }
+            }
+        },
+        "model.Building": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "buildingName": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "shortName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BuildingList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Building"
+                    }
+                }
+            }
+        },
+        "model.DiscoveredDisk": {
+            "type": "object",
+            "properties": {
+                "deviceId": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "storageType": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DiscoveredInterface": {
+            "type": "object",
+            "properties": {
+                "deviceId": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "macAddress": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DiscoveredSystem": {
+            "type": "object",
+            "properties": {
+                "clientAddress": {
+                    "type": "string"
+                },
+                "discoveryDate": {
+                    "type": "string"
+                },
+                "networkInterfaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NetworkInterface"
+                    }
+                },
+                "reportedArchitecture": {
+                    "type": "string"
+                },
+                "reportedModel": {
+                    "type": "string"
+                },
+                "reportedVendor": {
+                    "type": "string"
+                },
+                "storageVolumes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.StorageVolume"
+                    }
+                },
+                "system": {
+                    "$ref": "#/definitions/model.System"
+                }
+            }
+        },
+        "model.DiscoveredSystemList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiscoveredSystem"
+                    }
+                }
+            }
+        },
+        "model.DiscoveryMsg": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DiscoveryReport": {
+            "type": "object",
+            "properties": {
+                "architecture": {
+                    "type": "string"
+                },
+                "cpuCores": {
+                    "type": "integer"
+                },
+                "disks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiscoveredDisk"
+                    }
+                },
+                "model": {
+                    "type": "string"
+                },
+                "networkInterfaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiscoveredInterface"
+                    }
+                },
+                "ram": {
+                    "type": "integer"
+                },
+                "serialNumber": {
+                    "type": "string"
+                },
+                "vendor": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.FailureMsg": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.HostVarLayer": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "hostVars": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string",
+                    "enum": [
+                        "global",
+                        "orgUnit",
+                        "building",
+                        "machineRole"
+                    ]
+                },
+                "scopeId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.HostVarLayerList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.HostVarLayer"
+                    }
+                }
+            }
+        },
+        "model.LoginCredentials": {
+            "type": "object",
+            "properties": {
+                "otp": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "userName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.LoginMsg": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "type": "boolean"
+                },
+                "session": {
+                    "$ref": "#/definitions/model.Session"
+                },
+                "twoFactorEnrollmentRequired": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.MachineCertificate": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "certificate": {
+                    "type": "string"
+                },
+                "certificateRequest": {
+                    "type": "string"
+                },
+                "clientAddress": {
+                    "type": "string"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "notAfter": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                },
+                "updatedDate": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MachineCertificateList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MachineCertificate"
+                    }
+                }
+            }
+        },
+        "model.MachineCertificateMsg": {
+            "type": "object",
+            "properties": {
+                "machineCertificate": {
+                    "$ref": "#/definitions/model.MachineCertificate"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MachineEnrollment": {
+            "type": "object",
+            "properties": {
+                "certificateRequest": {
+                    "type": "string"
+                },
+                "serialNumber": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MachineEnrollmentMsg": {
+            "type": "object",
+            "properties": {
+                "caCertificate": {
+                    "type": "string"
+                },
+                "certificate": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MachineRole": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "machineRoleName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MachineRoleHostVarsSchema": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "hostVarsSchema": {
+                    "type": "string"
+                },
+                "machineRoleId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.MachineRoleList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MachineRole"
+                    }
+                }
+            }
+        },
+        "model.MachineTokenMsg": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MaintenanceWindow": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "buildingId": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "durationMinutes": {
+                    "type": "integer"
+                },
+                "startTime": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.MaintenanceWindowList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MaintenanceWindow"
+                    }
+                }
+            }
+        },
+        "model.NetworkInterface": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "bitmask": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "deviceId": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "gateway": {
+                    "type": "string"
+                },
+                "ipAddress": {
+                    "type": "string"
+                },
+                "macAddress": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.NetworkInterfaces": {
+            "type": "object",
+            "properties": {
+                "interfaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NetworkInterface"
+                    }
+                }
+            }
+        },
+        "model.OperatingSystem": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "imageUriProtocol": {
+                    "type": "string"
+                },
+                "osFamilyId": {
+                    "type": "integer"
+                },
+                "osImageUrl": {
+                    "type": "string"
+                },
+                "osName": {
+                    "type": "string"
+                },
+                "vendorId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.OperatingSystemFamily": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "osFamilyName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.OperatingSystemFamilyList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OperatingSystemFamily"
+                    }
+                }
+            }
+        },
+        "model.OperatingSystemList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OperatingSystem"
+                    }
+                }
+            }
+        },
+        "model.OperatingSystemVersion": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "osId": {
+                    "type": "integer"
+                },
+                "versionNumber": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.OperatingSystemVersionList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OperatingSystemVersion"
+                    }
+                }
+            }
+        },
+        "model.OrgUnit": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "creatorId": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "ouName": {
+                    "type": "string"
                 },
-                "iseName": {
+                "parentId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.OrgUnitDelegation": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "creationDate": {
                     "type": "string"
                 },
-                "registerSize": {
+                "creatorId": {
+                    "type": "integer"
+                },
+                "delegatedOrgUnitId": {
+                    "type": "integer"
+                },
+                "orgUnitId": {
                     "type": "integer"
                 }
             }
         },
-        "model.ArchitectureList": {
+        "model.OrgUnitDelegationList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.Architecture"
+                        "$ref": "#/definitions/model.OrgUnitDelegation"
                     }
                 }
             }
         },
-        "model.Building": {
+        "model.OrgUnitList": {
             "type": "object",
             "properties": {
-                "Id": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OrgUnit"
+                    }
+                }
+            }
+        },
+        "model.OrgUnitParent": {
+            "type": "object",
+            "properties": {
+                "parentId": {
                     "type": "integer"
+                }
+            }
+        },
+        "model.PasswordChange": {
+            "type": "object",
+            "properties": {
+                "newPassword": {
+                    "type": "string"
                 },
-                "buildingName": {
+                "oldPassword": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PasswordReset": {
+            "type": "object",
+            "properties": {
+                "newPassword": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Permission": {
+            "type": "object",
+            "properties": {
+                "description": {
                     "type": "string"
                 },
-                "city": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PermissionDeniedMsg": {
+            "type": "object",
+            "properties": {
+                "error": {
                     "type": "string"
                 },
-                "creationDate": {
+                "permission": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PermissionList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Permission"
+                    }
+                }
+            }
+        },
+        "model.ProposedApiToken": {
+            "type": "object",
+            "properties": {
+                "expiryDate": {
                     "type": "string"
                 },
-                "creatorId": {
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tokenName": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ProposedMachineCertificate": {
+            "type": "object",
+            "properties": {
+                "certificateRequest": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ProposedReimageBatch": {
+            "type": "object",
+            "properties": {
+                "batchName": {
+                    "type": "string"
+                },
+                "buildingId": {
                     "type": "integer"
                 },
-                "region": {
+                "machineRoleId": {
+                    "type": "integer"
+                },
+                "maxPerBuilding": {
+                    "type": "integer"
+                },
+                "orgUnitId": {
+                    "type": "integer"
+                },
+                "scheduledDate": {
                     "type": "string"
                 },
-                "shortName": {
+                "systemIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "model.ProposedUser": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "fullName": {
+                    "type": "string"
+                },
+                "orgUnitId": {
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "roleId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "userName": {
                     "type": "string"
+                },
+                "userTypeId": {
+                    "type": "integer"
                 }
             }
         },
-        "model.BuildingList": {
+        "model.ProvisioningDocument": {
             "type": "object",
             "properties": {
-                "data": {
+                "hostVars": {
+                    "type": "string"
+                },
+                "imageUriProtocol": {
+                    "type": "string"
+                },
+                "networkInterfaces": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.Building"
+                        "$ref": "#/definitions/model.NetworkInterface"
+                    }
+                },
+                "osImageUrl": {
+                    "type": "string"
+                },
+                "osName": {
+                    "type": "string"
+                },
+                "osVersion": {
+                    "type": "string"
+                },
+                "reimage": {
+                    "type": "boolean"
+                },
+                "serialNumber": {
+                    "type": "string"
+                },
+                "storageVolumes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.StorageVolume"
                     }
+                },
+                "systemId": {
+                    "type": "integer"
                 }
             }
         },
-        "model.FailureMsg": {
+        "model.RecoveryCodesMsg": {
             "type": "object",
             "properties": {
-                "error": {
+                "message": {
                     "type": "string"
+                },
+                "recoveryCodes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "model.MachineRole": {
+        "model.ReimageBatch": {
             "type": "object",
             "properties": {
                 "Id": {
                     "type": "integer"
                 },
+                "batchName": {
+                    "type": "string"
+                },
                 "creationDate": {
                     "type": "string"
                 },
                 "creatorId": {
                     "type": "integer"
                 },
-                "description": {
+                "maxPerBuilding": {
+                    "type": "integer"
+                },
+                "scheduledDate": {
                     "type": "string"
                 },
-                "machineRoleName": {
+                "state": {
                     "type": "string"
+                },
+                "systems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReimageBatchSystem"
+                    }
                 }
             }
         },
-        "model.MachineRoleList": {
+        "model.ReimageBatchList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.MachineRole"
+                        "$ref": "#/definitions/model.ReimageBatch"
                     }
                 }
             }
         },
-        "model.NetworkInterface": {
+        "model.ReimageBatchSystem": {
             "type": "object",
             "properties": {
                 "Id": {
                     "type": "integer"
                 },
-                "bitmask": {
+                "batchId": {
                     "type": "integer"
                 },
-                "creationDate": {
+                "reimageRequestId": {
+                    "type": "integer"
+                },
+                "startedDate": {
                     "type": "string"
                 },
-                "creatorId": {
+                "state": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ReimageEvent": {
+            "type": "object",
+            "properties": {
+                "Id": {
                     "type": "integer"
                 },
-                "deviceId": {
+                "eventDate": {
                     "type": "string"
                 },
-                "deviceModel": {
+                "message": {
                     "type": "string"
                 },
-                "gateway": {
+                "reimageRequestId": {
+                    "type": "integer"
+                },
+                "state": {
                     "type": "string"
+                }
+            }
+        },
+        "model.ReimageRequest": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
                 },
-                "ipAddress": {
+                "completedDate": {
                     "type": "string"
                 },
-                "macAddress": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReimageEvent"
+                    }
+                },
+                "requestedById": {
+                    "type": "integer"
+                },
+                "requestedDate": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                },
+                "updatedDate": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReimageRequestList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReimageRequest"
+                    }
+                }
+            }
+        },
+        "model.ReimageStatusReport": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ResolvedHostVar": {
+            "type": "object",
+            "properties": {
+                "scope": {
                     "type": "string"
                 },
-                "systemId": {
+                "scopeId": {
                     "type": "integer"
-                }
+                },
+                "value": {}
             }
         },
-        "model.NetworkInterfaces": {
+        "model.ResolvedHostVars": {
             "type": "object",
             "properties": {
-                "interfaces": {
+                "machineRoleId": {
+                    "type": "integer"
+                },
+                "systemId": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "boolean"
+                },
+                "vars": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.ResolvedHostVar"
+                    }
+                },
+                "violations": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.NetworkInterface"
+                        "type": "string"
                     }
                 }
             }
         },
-        "model.OperatingSystem": {
+        "model.Role": {
             "type": "object",
             "properties": {
                 "Id": {
@@ -3283,27 +9494,18 @@ const docTemplate = `{
                 "creationDate": {
                     "type": "string"
                 },
-                "creatorId": {
-                    "type": "integer"
-                },
-                "imageUriProtocol": {
+                "description": {
                     "type": "string"
                 },
-                "osFamilyId": {
-                    "type": "integer"
-                },
-                "osImageUrl": {
-                    "type": "string"
+                "requireTwoFactor": {
+                    "type": "boolean"
                 },
-                "osName": {
+                "roleName": {
                     "type": "string"
-                },
-                "vendorId": {
-                    "type": "integer"
                 }
             }
         },
-        "model.OperatingSystemFamily": {
+        "model.RolePermission": {
             "type": "object",
             "properties": {
                 "Id": {
@@ -3315,65 +9517,96 @@ const docTemplate = `{
                 "creatorId": {
                     "type": "integer"
                 },
-                "osFamilyName": {
+                "permission": {
+                    "type": "string"
+                },
+                "roleId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.RolePermissionGrant": {
+            "type": "object",
+            "properties": {
+                "permission": {
                     "type": "string"
                 }
             }
         },
-        "model.OperatingSystemFamilyList": {
+        "model.RolePermissionList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.OperatingSystemFamily"
+                        "$ref": "#/definitions/model.RolePermission"
                     }
                 }
             }
         },
-        "model.OperatingSystemList": {
+        "model.RoleRequireTwoFactor": {
+            "type": "object",
+            "properties": {
+                "requireTwoFactor": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.RolesList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.OperatingSystem"
+                        "$ref": "#/definitions/model.Role"
                     }
                 }
             }
         },
-        "model.OperatingSystemVersion": {
+        "model.Session": {
             "type": "object",
             "properties": {
                 "Id": {
                     "type": "integer"
                 },
+                "authMethod": {
+                    "type": "string"
+                },
+                "clientAddress": {
+                    "type": "string"
+                },
                 "creationDate": {
                     "type": "string"
                 },
-                "creatorId": {
-                    "type": "integer"
+                "current": {
+                    "type": "boolean"
                 },
-                "osId": {
-                    "type": "integer"
+                "expiryDate": {
+                    "type": "string"
                 },
-                "versionNumber": {
+                "lastSeenDate": {
+                    "type": "string"
+                },
+                "userAgent": {
                     "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
                 }
             }
         },
-        "model.OperatingSystemVersionList": {
+        "model.SessionList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.OperatingSystemVersion"
+                        "$ref": "#/definitions/model.Session"
                     }
                 }
             }
         },
-        "model.OrgUnit": {
+        "model.StorageVolume": {
             "type": "object",
             "properties": {
                 "Id": {
@@ -3385,94 +9618,148 @@ const docTemplate = `{
                 "creatorId": {
                     "type": "integer"
                 },
-                "description": {
+                "deviceId": {
                     "type": "string"
                 },
-                "ouName": {
+                "deviceModel": {
+                    "type": "string"
+                },
+                "mountPoint": {
+                    "type": "string"
+                },
+                "storageType": {
+                    "type": "string"
+                },
+                "systemId": {
+                    "type": "integer"
+                },
+                "volumeFormat": {
+                    "type": "string"
+                },
+                "volumeLabel": {
+                    "type": "string"
+                },
+                "volumeName": {
                     "type": "string"
+                },
+                "volumeSize": {
+                    "type": "integer"
                 }
             }
         },
-        "model.OrgUnitList": {
+        "model.StorageVolumes": {
             "type": "object",
             "properties": {
-                "data": {
+                "volumes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.OrgUnit"
+                        "$ref": "#/definitions/model.StorageVolume"
                     }
                 }
             }
         },
-        "model.PasswordChange": {
+        "model.SuccessMsg": {
             "type": "object",
             "properties": {
-                "newPassword": {
-                    "type": "string"
-                },
-                "oldPassword": {
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "model.ProposedUser": {
+        "model.System": {
             "type": "object",
             "properties": {
+                "HostVars": {
+                    "type": "string"
+                },
                 "Id": {
                     "type": "integer"
                 },
-                "fullName": {
-                    "type": "string"
+                "architectureId": {
+                    "type": "integer"
                 },
-                "orgUnitId": {
+                "billedToOrgUnitId": {
                     "type": "integer"
                 },
-                "password": {
+                "buildingId": {
+                    "type": "integer"
+                },
+                "cpuCores": {
+                    "type": "integer"
+                },
+                "creationDate": {
                     "type": "string"
                 },
-                "roleId": {
+                "creatorId": {
                     "type": "integer"
                 },
-                "status": {
+                "machineRoleId": {
+                    "type": "integer"
+                },
+                "modelId": {
+                    "type": "integer"
+                },
+                "osId": {
+                    "type": "integer"
+                },
+                "ram": {
+                    "type": "integer"
+                },
+                "reimage": {
+                    "type": "boolean"
+                },
+                "serialNumber": {
                     "type": "string"
                 },
-                "userName": {
+                "status": {
                     "type": "string"
                 },
-                "userTypeId": {
+                "vendorId": {
                     "type": "integer"
                 }
             }
         },
-        "model.Role": {
+        "model.SystemApproval": {
             "type": "object",
             "properties": {
-                "Id": {
+                "architectureId": {
                     "type": "integer"
                 },
-                "creationDate": {
-                    "type": "string"
+                "billedToOrgUnitId": {
+                    "type": "integer"
                 },
-                "description": {
-                    "type": "string"
+                "buildingId": {
+                    "type": "integer"
                 },
-                "roleName": {
+                "hostVars": {
                     "type": "string"
+                },
+                "machineRoleId": {
+                    "type": "integer"
+                },
+                "modelId": {
+                    "type": "integer"
+                },
+                "osId": {
+                    "type": "integer"
+                },
+                "vendorId": {
+                    "type": "integer"
                 }
             }
         },
-        "model.RolesList": {
+        "model.SystemList": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.Role"
+                        "$ref": "#/definitions/model.System"
                     }
                 }
             }
         },
-        "model.StorageVolume": {
+        "model.TwoFactor": {
             "type": "object",
             "properties": {
                 "Id": {
@@ -3481,53 +9768,35 @@ const docTemplate = `{
                 "creationDate": {
                     "type": "string"
                 },
-                "creatorId": {
-                    "type": "integer"
-                },
-                "deviceId": {
-                    "type": "string"
-                },
-                "deviceModel": {
-                    "type": "string"
-                },
-                "mountPoint": {
-                    "type": "string"
-                },
-                "storageType": {
-                    "type": "string"
+                "enabled": {
+                    "type": "boolean"
                 },
-                "systemId": {
+                "recoveryCodesLeft": {
                     "type": "integer"
                 },
-                "volumeFormat": {
-                    "type": "string"
-                },
-                "volumeLabel": {
-                    "type": "string"
-                },
-                "volumeName": {
-                    "type": "string"
-                },
-                "volumeSize": {
+                "userId": {
                     "type": "integer"
                 }
             }
         },
-        "model.StorageVolumes": {
+        "model.TwoFactorCode": {
             "type": "object",
             "properties": {
-                "volumes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.StorageVolume"
-                    }
+                "code": {
+                    "type": "string"
                 }
             }
         },
-        "model.SuccessMsg": {
+        "model.TwoFactorEnrollment": {
             "type": "object",
             "properties": {
-                "message": {
+                "provisioningUri": {
+                    "type": "string"
+                },
+                "qrCode": {
+                    "type": "string"
+                },
+                "secret": {
                     "type": "string"
                 }
             }
@@ -3541,12 +9810,27 @@ const docTemplate = `{
                 "creationDate": {
                     "type": "string"
                 },
+                "externalProvider": {
+                    "type": "string"
+                },
+                "externalSubject": {
+                    "type": "string"
+                },
+                "failedLoginCount": {
+                    "type": "integer"
+                },
                 "fullName": {
                     "type": "string"
                 },
                 "lastPasswordChangedDate": {
                     "type": "string"
                 },
+                "lockedUntil": {
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "type": "boolean"
+                },
                 "orgUnitId": {
                     "type": "integer"
                 },
@@ -3567,6 +9851,14 @@ const docTemplate = `{
                 }
             }
         },
+        "model.UserMustChangePassword": {
+            "type": "object",
+            "properties": {
+                "mustChangePassword": {
+                    "type": "boolean"
+                }
+            }
+        },
         "model.UserOrgUnitId": {
             "type": "object",
             "properties": {
@@ -3685,11 +9977,61 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "model.WhoAmI": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "integer"
+                },
+                "authMethod": {
+                    "type": "string"
+                },
+                "fullName": {
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "type": "boolean"
+                },
+                "orgUnitId": {
+                    "type": "integer"
+                },
+                "roleId": {
+                    "type": "integer"
+                },
+                "session": {
+                    "$ref": "#/definitions/model.Session"
+                },
+                "twoFactorEnabled": {
+                    "type": "boolean"
+                },
+                "twoFactorEnrollmentRequired": {
+                    "type": "boolean"
+                },
+                "typeId": {
+                    "type": "integer"
+                },
+                "userName": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
         "BasicAuth": {
             "type": "basic"
+        },
+        "BearerAuth": {
+            "description": "API token of a user, sent as 'Bearer \u003ctoken\u003e'",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "MachineToken": {
+            "description": "Per-system machine token. Requests must also send 'X-ASSIMILATOR-TYPE: MACHINE'",
+            "type": "apiKey",
+            "name": "X-Auth-Token",
+            "in": "header"
         }
     }
 }`
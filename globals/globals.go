@@ -18,6 +18,33 @@ package globals
 
 */
 
-var Secret = []byte("secret")
-
+// context key holding the name of the user a request was made by
 const UserKey = "user"
+
+// session cookie key holding the token of the user's server-side session,
+// and the context key holding the Id of that session
+const SessionTokenKey = "sessionToken"
+const SessionIdKey = "sessionId"
+
+// context key holding how a request was authenticated, one of the
+// AuthMethod values
+const AuthMethodKey = "authMethod"
+
+const (
+	AuthMethodSession   = "session"
+	AuthMethodBasic     = "basic"
+	AuthMethodApiToken  = "apiToken"
+	AuthMethodOidcToken = "oidcToken"
+)
+
+// context key holding the Id of the system the machine token or client
+// certificate of a request belongs to
+const MachineKey = "machineSystemId"
+
+// context key holding the API token a request authenticated with. The name of
+// its owner is kept under UserKey
+const ApiTokenKey = "apiToken"
+
+// header carrying the Id of a request, and the context key holding it
+const RequestIdHeader = "X-Request-ID"
+const RequestIdKey = "requestId"
@@ -19,10 +19,103 @@ package globals
 */
 
 type Config struct {
-	TcpPort    int    `json:"tcpPort"`
-	TLSTcpPort int    `json:"tlsTcpPort"`
-	TLSPemFile string `json:"tlsPemFile"`
-	TLSKeyFile string `json:"tlsKeyFile"`
-	DbPath     string `json:"dbPath"`
-	UseTLS     bool   `json:"useTls"`
+	TcpPort           int    `json:"tcpPort"`
+	TLSTcpPort        int    `json:"tlsTcpPort"`
+	TLSPemFile        string `json:"tlsPemFile"`
+	TLSKeyFile        string `json:"tlsKeyFile"`
+	DbDriver          string `json:"dbDriver"`
+	DbPath            string `json:"dbPath"`
+	DbConnection      string `json:"dbConnection"`
+	SkipMigrations    bool   `json:"skipMigrations"`
+	UseTLS            bool   `json:"useTls"`
+	SchedulerInterval int    `json:"schedulerIntervalSeconds"`
+	ExternalUrl       string `json:"externalUrl"`
+	TFTPEnabled       bool   `json:"tftpEnabled"`
+	TFTPAddress       string `json:"tftpAddress"`
+	TFTPRoot          string `json:"tftpRoot"`
+	// password hashing, "argon2id" (the default) or "bcrypt". Costs left at
+	// zero use the defaults of the passwords package
+	PasswordHashAlgorithm string `json:"passwordHashAlgorithm"`
+	Argon2Time            uint32 `json:"argon2Time"`
+	Argon2MemoryKB        uint32 `json:"argon2MemoryKB"`
+	Argon2Threads         uint8  `json:"argon2Threads"`
+	BcryptCost            int    `json:"bcryptCost"`
+	// password policy, rules left at zero or false are not enforced. Users
+	// are locked out for lockoutMinutes after maxFailedLogins failures in a
+	// row
+	PasswordMinLength     int  `json:"passwordMinLength"`
+	PasswordRequireUpper  bool `json:"passwordRequireUpper"`
+	PasswordRequireLower  bool `json:"passwordRequireLower"`
+	PasswordRequireDigit  bool `json:"passwordRequireDigit"`
+	PasswordRequireSymbol bool `json:"passwordRequireSymbol"`
+	PasswordMaxAgeDays    int  `json:"passwordMaxAgeDays"`
+	MaxFailedLogins       int  `json:"maxFailedLogins"`
+	LockoutMinutes        int  `json:"lockoutMinutes"`
+	// LDAP authentication of EXTERNAL users, off unless ldapUrl is set.
+	// Users logging in for the first time are created with the role and
+	// organizational unit of the first mapping their groups match
+	LdapUrl                string         `json:"ldapUrl"`
+	LdapStartTLS           bool           `json:"ldapStartTls"`
+	LdapInsecureSkipVerify bool           `json:"ldapInsecureSkipVerify"`
+	LdapBindDN             string         `json:"ldapBindDn"`
+	LdapBindPassword       string         `json:"ldapBindPassword"`
+	LdapBaseDN             string         `json:"ldapBaseDn"`
+	LdapUserFilter         string         `json:"ldapUserFilter"`
+	LdapFullNameAttribute  string         `json:"ldapFullNameAttribute"`
+	LdapGroupAttribute     string         `json:"ldapGroupAttribute"`
+	LdapTimeoutSeconds     int            `json:"ldapTimeoutSeconds"`
+	LdapGroupRoles         []GroupMapping `json:"ldapGroupRoles"`
+	LdapGroupOrgUnits      []GroupMapping `json:"ldapGroupOrgUnits"`
+	LdapDefaultRoleId      int            `json:"ldapDefaultRoleId"`
+	LdapDefaultOrgUnitId   int            `json:"ldapDefaultOrgUnitId"`
+	// OpenID Connect login, off unless oidcIssuer is set. Users are named
+	// after oidcUserNameClaim and mapped like LDAP users through the groups
+	// in oidcGroupsClaim. Bearer access tokens must be issued for
+	// oidcAudience, which defaults to the client Id
+	OidcIssuer           string         `json:"oidcIssuer"`
+	OidcClientId         string         `json:"oidcClientId"`
+	OidcClientSecret     string         `json:"oidcClientSecret"`
+	OidcRedirectUrl      string         `json:"oidcRedirectUrl"`
+	OidcScopes           []string       `json:"oidcScopes"`
+	OidcAudience         string         `json:"oidcAudience"`
+	OidcUserNameClaim    string         `json:"oidcUserNameClaim"`
+	OidcFullNameClaim    string         `json:"oidcFullNameClaim"`
+	OidcGroupsClaim      string         `json:"oidcGroupsClaim"`
+	OidcGroupRoles       []GroupMapping `json:"oidcGroupRoles"`
+	OidcGroupOrgUnits    []GroupMapping `json:"oidcGroupOrgUnits"`
+	OidcDefaultRoleId    int            `json:"oidcDefaultRoleId"`
+	OidcDefaultOrgUnitId int            `json:"oidcDefaultOrgUnitId"`
+	// the issuer authenticator apps list TOTP accounts under, defaults to
+	// allocatord
+	TwoFactorIssuer string `json:"twoFactorIssuer"`
+	// session cookies are signed with the first of sessionKeys, or of the
+	// lines of sessionKeyFile, and accepted with any of them. Sessions end
+	// after sessionIdleMinutes without a request and sessionMaxAgeMinutes
+	// after logging in
+	SessionKeys          []string `json:"sessionKeys"`
+	SessionKeyFile       string   `json:"sessionKeyFile"`
+	SessionIdleMinutes   int      `json:"sessionIdleMinutes"`
+	SessionMaxAgeMinutes int      `json:"sessionMaxAgeMinutes"`
+	// internal certificate authority issuing client certificates to systems,
+	// off unless machineCaCertFile is set. Its certificate and key are
+	// generated on first start when neither file exists. Client certificates
+	// are only checked over TLS, and with requireMachineCertificates set
+	// systems can no longer use machine tokens
+	MachineCaCertFile          string `json:"machineCaCertFile"`
+	MachineCaKeyFile           string `json:"machineCaKeyFile"`
+	MachineCertValidityDays    int    `json:"machineCertValidityDays"`
+	RequireMachineCertificates bool   `json:"requireMachineCertificates"`
+	// how many times a minute a single client address may report unknown
+	// hardware, defaults to 10
+	DiscoveryRequestsPerMinute int `json:"discoveryRequestsPerMinute"`
+	// how many times a minute a single client address may send or check on a
+	// certificate request while enrolling, defaults to 10
+	EnrollmentRequestsPerMinute int `json:"enrollmentRequestsPerMinute"`
+}
+
+// GroupMapping maps the members of a group of an external identity provider
+// to a role or an organizational unit
+type GroupMapping struct {
+	Group string `json:"group"`
+	Id    int    `json:"id"`
 }
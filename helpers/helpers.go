@@ -19,20 +19,167 @@ package helpers
 */
 
 import (
-	"crypto/sha512"
-	"encoding/hex"
+	"database/sql"
+	"errors"
 	"log"
 	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/ldapauth"
 	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/passwords"
+	"github.com/greeneg/allocatord/twofactor"
 )
 
+// AuthenticatedUser returns the name of the user a request was made by,
+// however they authenticated
+func AuthenticatedUser(c *gin.Context) (string, bool) {
+	username := c.GetString(globals.UserKey)
+	return username, username != ""
+}
+
 func CheckIsNotLocked(u model.User) bool {
 	return u.Status != "locked"
 }
 
+// CheckIsNotLockedOut reports whether a user is free of any lockout after
+// failed logins
+func CheckIsNotLockedOut(u model.User) bool {
+	if u.LockedUntil == "" {
+		return true
+	}
+	lockedUntil, err := model.ParseTimestamp(u.LockedUntil)
+	if err != nil {
+		return true
+	}
+	return !time.Now().UTC().Before(lockedUntil)
+}
+
+// PasswordChangeRequired reports whether a user has been told to change their
+// password or has kept it longer than the password policy allows
+func PasswordChangeRequired(u model.User) bool {
+	// the passwords of EXTERNAL users are the directory's business
+	if u.TypeId == model.UserTypeExternal {
+		return false
+	}
+	if u.MustChangePassword {
+		return true
+	}
+	lastChanged, err := model.ParseTimestamp(u.LastPasswordChangedDate)
+	if err != nil {
+		return false
+	}
+	return passwords.Expired(lastChanged, time.Now().UTC())
+}
+
+// authenticateExternalUser checks the password of a user with the directory.
+// Errors other than a wrong password are not the user's doing, so they are
+// reported rather than counted as a failed login
+func authenticateExternalUser(username, password string) (ldapauth.Identity, bool, error) {
+	if !ldapauth.Enabled() {
+		return ldapauth.Identity{}, false, errors.New("LDAP authentication is not configured")
+	}
+	identity, err := ldapauth.Authenticate(username, password)
+	if err != nil {
+		if errors.Is(err, ldapauth.ErrInvalidCredentials) {
+			return ldapauth.Identity{}, false, nil
+		}
+		return ldapauth.Identity{}, false, err
+	}
+	return identity, true, nil
+}
+
+// ExternalUser returns the account of a user an external identity provider
+// vouched for, which is the one the provider created for the subject it
+// knows them by. It is created the first time they log in, and its name,
+// role and organizational unit follow the provider on later logins. User
+// names are only a claim the provider makes, so an account of that name
+// belonging to anybody else is never taken over. Only the directory, which
+// has always known users by their name, may claim an EXTERNAL account no
+// provider owns yet
+func ExternalUser(provider string, subject string, p model.ProposedUser) (model.User, error) {
+	user, err := model.Repos.Users.GetUserByExternalIdentity(provider, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = claimExternalUser(provider, subject, p)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if p.FullName != user.FullName || p.RoleId != user.RoleId || p.OrgUnitId != user.OrgUnitId {
+		p.UserName = user.UserName
+		_, err = model.Repos.Users.SyncExternalUser(provider, subject, p)
+		if err != nil {
+			return model.User{}, err
+		}
+		return model.Repos.Users.GetUserByExternalIdentity(provider, subject)
+	}
+
+	return user, nil
+}
+
+// claimExternalUser creates the account of a user an identity provider has
+// no account for yet, or hands the directory an unowned account of theirs
+func claimExternalUser(provider string, subject string, p model.ProposedUser) (model.User, error) {
+	existing, err := model.Repos.Users.GetUserByUserName(p.UserName)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = model.Repos.Users.ProvisionExternalUser(provider, subject, p)
+		if err != nil {
+			return model.User{}, err
+		}
+		return model.Repos.Users.GetUserByExternalIdentity(provider, subject)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if existing.TypeId != model.UserTypeExternal {
+		return model.User{}, &model.ExternalAccountConflict{Err: errors.New("user '" + p.UserName + "' is not an EXTERNAL user")}
+	}
+	if existing.ExternalProvider != "" || provider != ldapauth.Provider {
+		return model.User{}, &model.ExternalAccountConflict{Err: errors.New("user '" + p.UserName + "' was not created by identity provider '" + provider + "' for this subject")}
+	}
+
+	_, err = model.Repos.Users.ClaimExternalUser(p.UserName, provider, subject)
+	if err != nil {
+		return model.User{}, err
+	}
+	return model.Repos.Users.GetUserByExternalIdentity(provider, subject)
+}
+
+// provisionExternalUser creates the account of a directory user logging in
+// for the first time
+func provisionExternalUser(username, password string) bool {
+	identity, match, err := authenticateExternalUser(username, password)
+	if err != nil {
+		log.Println("WARN: Cannot authenticate user '" + username + "' with LDAP: " + string(err.Error()))
+		return false
+	}
+	if !match {
+		return false
+	}
+
+	_, err = ExternalUser(ldapauth.Provider, username, model.ProposedUser{
+		UserName:  username,
+		FullName:  identity.FullName,
+		OrgUnitId: identity.OrgUnitId,
+		RoleId:    identity.RoleId,
+	})
+	if err != nil {
+		log.Println("ERROR: Could not provision external user '" + username + "': " + string(err.Error()))
+		return false
+	}
+	return true
+}
+
 func CheckUserPass(username, password string) bool {
-	user, err := model.GetUserByUserName(username)
+	user, err := model.Repos.Users.GetUserByUserName(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		// directory users get an account the first time they log in
+		return ldapauth.Enabled() && provisionExternalUser(username, password)
+	}
 	if err != nil {
 		return false
 	}
@@ -42,14 +189,152 @@ func CheckUserPass(username, password string) bool {
 		return false
 	}
 
-	// get the password hash from the user so we can compare it
-	pwHash := user.PasswordHash
+	// attempts made while locked out are not counted, or the lockout would
+	// never end for as long as somebody kept guessing
+	if !CheckIsNotLockedOut(user) {
+		log.Println("WARN: User '" + username + "' is locked out until " + user.LockedUntil + " UTC")
+		return false
+	}
+
+	// EXTERNAL users are checked with the directory, everybody else against
+	// the stored hash, whichever format it is in
+	var match, needsRehash bool
+	if user.TypeId == model.UserTypeExternal {
+		// accounts another identity provider created are not the directory's
+		// to vouch for, even when it knows a user of the same name
+		if user.ExternalProvider != "" && user.ExternalProvider != ldapauth.Provider {
+			log.Println("WARN: User '" + username + "' belongs to identity provider '" + user.ExternalProvider + "' and cannot log in with a password")
+			return false
+		}
+		var identity ldapauth.Identity
+		identity, match, err = authenticateExternalUser(username, password)
+		if err != nil {
+			log.Println("WARN: Cannot authenticate user '" + username + "' with LDAP: " + string(err.Error()))
+			return false
+		}
+		if match {
+			_, err = ExternalUser(ldapauth.Provider, username, model.ProposedUser{
+				UserName:  username,
+				FullName:  identity.FullName,
+				OrgUnitId: identity.OrgUnitId,
+				RoleId:    identity.RoleId,
+			})
+			if err != nil {
+				log.Println("WARN: Could not sync external user '" + username + "' with LDAP: " + string(err.Error()))
+				return false
+			}
+		}
+	} else {
+		match, needsRehash = passwords.Verify(password, user.PasswordHash)
+	}
+	if !match {
+		_, err = model.Repos.Users.RecordFailedLogin(username)
+		if err != nil {
+			log.Println("WARN: Could not record the failed login of user '" + username + "': " + string(err.Error()))
+		}
+		return false
+	}
+
+	// users with a second factor have their failed logins cleared once that
+	// has been checked too, or guessing codes would never lock them out
+	if !SecondFactorRequired(user) {
+		clearFailedLogins(user)
+	}
+
+	// hashes made with an older algorithm or weaker costs are replaced now
+	// that the password is known. Failing to do so is not fatal, the next
+	// login tries again
+	if needsRehash {
+		upgraded, err := passwords.Hash(password)
+		if err == nil {
+			_, err = model.Repos.Users.UpgradePasswordHash(username, upgraded)
+		}
+		if err != nil {
+			log.Println("WARN: Could not upgrade the password hash of user '" + username + "': " + string(err.Error()))
+		}
+	}
+
+	return true
+}
+
+func clearFailedLogins(u model.User) {
+	if u.FailedLoginCount == 0 && u.LockedUntil == "" {
+		return
+	}
+	_, err := model.Repos.Users.RecordSuccessfulLogin(u.UserName)
+	if err != nil {
+		log.Println("WARN: Could not clear the failed logins of user '" + u.UserName + "': " + string(err.Error()))
+	}
+}
 
-	// now calculate the sha512 of the password and see if it matches
-	sha := sha512.Sum512([]byte(password))
-	newPwHash := hex.EncodeToString(sha[:])
+// SecondFactorRequired reports whether a user has to give a second factor to
+// log in. When that cannot be told the answer is yes, so a failing database
+// does not let anybody skip it
+func SecondFactorRequired(u model.User) bool {
+	// EXTERNAL users are left to the directory or the OpenID provider
+	if u.TypeId == model.UserTypeExternal {
+		return false
+	}
+	tf, err := model.Repos.TwoFactor.GetTwoFactorByUserId(u.Id)
+	if err != nil {
+		log.Println("ERROR: Cannot tell whether user '" + u.UserName + "' uses two-factor authentication: " + string(err.Error()))
+		return true
+	}
+	return tf.Enabled
+}
+
+// TwoFactorEnrollmentRequired reports whether a user holds a role requiring
+// two-factor authentication without having enrolled
+func TwoFactorEnrollmentRequired(u model.User) bool {
+	if u.TypeId == model.UserTypeExternal {
+		return false
+	}
+	role, err := model.Repos.Roles.GetRoleById(u.RoleId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve the role of user '" + u.UserName + "': " + string(err.Error()))
+		return true
+	}
+	if !role.RequireTwoFactor {
+		return false
+	}
+	return !SecondFactorRequired(u)
+}
+
+// CheckSecondFactor checks the second factor of a user with two-factor
+// authentication, which is either a TOTP code or one of their recovery
+// codes. A code can only be used once, and wrong ones count as failed
+// logins the same as wrong passwords do
+func CheckSecondFactor(u model.User, code string) bool {
+	tf, err := model.Repos.TwoFactor.GetTwoFactorByUserId(u.Id)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve the TOTP secret of user '" + u.UserName + "': " + string(err.Error()))
+		return false
+	}
+	if !tf.Enabled {
+		return false
+	}
+
+	var match bool
+	if step, valid := twofactor.Validate(tf.Secret, code, time.Now()); valid {
+		match, err = model.Repos.TwoFactor.AcceptTwoFactorStep(u.Id, step)
+	} else if strings.TrimSpace(code) != "" {
+		match, err = model.Repos.TwoFactor.UseRecoveryCode(u.Id, code)
+	}
+	if err != nil {
+		log.Println("ERROR: Cannot check the second factor of user '" + u.UserName + "': " + string(err.Error()))
+		return false
+	}
+	if !match {
+		log.Println("WARN: Wrong or reused second factor given by user '" + u.UserName + "'")
+		_, err = model.Repos.Users.RecordFailedLogin(u.UserName)
+		if err != nil {
+			log.Println("WARN: Could not record the failed login of user '" + u.UserName + "': " + string(err.Error()))
+		}
+		return false
+	}
 
-	return pwHash == newPwHash // returns boolean based on equality
+	clearFailedLogins(u)
+	return true
 }
 
 func EmptyUserPass(username, password string) bool {
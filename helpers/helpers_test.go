@@ -0,0 +1,377 @@
+package helpers
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/ldapauth"
+	"github.com/greeneg/allocatord/ldapauth/ldaptest"
+	"github.com/greeneg/allocatord/migrations"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/passwords"
+)
+
+const (
+	adminsGroup    = "cn=admins,ou=groups,dc=example,dc=com"
+	operatorsGroup = "cn=operators,ou=groups,dc=example,dc=com"
+	salesGroup     = "cn=sales,ou=groups,dc=example,dc=com"
+	aliceDN        = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+// ldapFixture is a migrated database with roles and an organizational unit
+// for directory groups to map to
+type ldapFixture struct {
+	server         *ldaptest.Server
+	adminRoleId    int
+	operatorRoleId int
+	salesOrgUnitId int
+}
+
+func alice(cn string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       aliceDN,
+		Password: "alice-secret",
+		Attributes: map[string][]string{
+			"uid":      {"alice"},
+			"cn":       {cn},
+			"memberOf": groups,
+		},
+	}
+}
+
+func roleId(t *testing.T, name string) int {
+	t.Helper()
+	_, err := model.Repos.Roles.CreateRole(model.Role{RoleName: name, Description: name})
+	if err != nil {
+		t.Fatalf("CreateRole(%s): %v", name, err)
+	}
+	roles, err := model.Repos.Roles.GetRoles()
+	if err != nil {
+		t.Fatalf("GetRoles: %v", err)
+	}
+	for _, r := range roles {
+		if r.RoleName == name {
+			return r.Id
+		}
+	}
+	t.Fatalf("role %s not found after creating it", name)
+	return 0
+}
+
+func orgUnitId(t *testing.T, name string) int {
+	t.Helper()
+	_, err := model.Repos.OrgUnits.CreateOU(model.OrgUnit{OUName: name, Description: name}, model.SystemUserId)
+	if err != nil {
+		t.Fatalf("CreateOU(%s): %v", name, err)
+	}
+	orgUnits, err := model.Repos.OrgUnits.GetOUs()
+	if err != nil {
+		t.Fatalf("GetOUs: %v", err)
+	}
+	for _, o := range orgUnits {
+		if o.OUName == name {
+			return o.Id
+		}
+	}
+	t.Fatalf("organizational unit %s not found after creating it", name)
+	return 0
+}
+
+func newLdapFixture(t *testing.T) ldapFixture {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+
+	f := ldapFixture{
+		server:         ldaptest.NewServer(alice("Alice Liddell", "CN=Admins,OU=Groups,DC=example,DC=com", salesGroup)),
+		adminRoleId:    roleId(t, "Administrators"),
+		operatorRoleId: roleId(t, "Operators"),
+		salesOrgUnitId: orgUnitId(t, "Sales"),
+	}
+	t.Cleanup(f.server.Close)
+
+	err = ldapauth.Configure(ldapauth.Config{
+		Url:     f.server.URL,
+		BaseDN:  "dc=example,dc=com",
+		Timeout: 5 * time.Second,
+		RoleGroups: []ldapauth.GroupMapping{
+			{Group: adminsGroup, Id: f.adminRoleId},
+			{Group: operatorsGroup, Id: f.operatorRoleId},
+		},
+		OrgUnitGroups:    []ldapauth.GroupMapping{{Group: salesGroup, Id: f.salesOrgUnitId}},
+		DefaultOrgUnitId: 1,
+	})
+	if err != nil {
+		t.Fatalf("ldapauth.Configure: %v", err)
+	}
+	t.Cleanup(func() { ldapauth.Configure(ldapauth.Config{}) })
+
+	return f
+}
+
+func checkUser(t *testing.T, fullName string, roleId int, orgUnitId int) model.User {
+	t.Helper()
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+	if user.TypeId != model.UserTypeExternal {
+		t.Errorf("alice has user type %d, want EXTERNAL", user.TypeId)
+	}
+	if user.FullName != fullName || user.RoleId != roleId || user.OrgUnitId != orgUnitId {
+		t.Errorf("alice is %q with role %d in OU %d, want %q with role %d in OU %d",
+			user.FullName, user.RoleId, user.OrgUnitId, fullName, roleId, orgUnitId)
+	}
+	return user
+}
+
+func TestLdapProvisioning(t *testing.T) {
+	f := newLdapFixture(t)
+
+	// nobody gets an account for a password the directory turned down
+	if CheckUserPass("alice", "wrong") {
+		t.Fatalf("CheckUserPass accepted a wrong password")
+	}
+	_, err := model.Repos.Users.GetUserByUserName("alice")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("alice was provisioned after a failed login: %v", err)
+	}
+
+	if !CheckUserPass("alice", "alice-secret") {
+		t.Fatalf("CheckUserPass turned down the directory password on first login")
+	}
+	checkUser(t, "Alice Liddell", f.adminRoleId, f.salesOrgUnitId)
+
+	// later logins bring the account in line with the directory
+	f.server.SetEntry(alice("Alice Hargreaves", operatorsGroup))
+	if !CheckUserPass("alice", "alice-secret") {
+		t.Fatalf("CheckUserPass turned down the directory password on a later login")
+	}
+	checkUser(t, "Alice Hargreaves", f.operatorRoleId, 1)
+
+	if CheckUserPass("alice", "wrong") {
+		t.Fatalf("CheckUserPass accepted a wrong password for a provisioned user")
+	}
+	user := checkUser(t, "Alice Hargreaves", f.operatorRoleId, 1)
+	if user.FailedLoginCount != 1 {
+		t.Errorf("alice has %d failed logins, want 1", user.FailedLoginCount)
+	}
+
+	// once the directory maps her to no role at all she cannot log in, and
+	// keeps the role she had
+	f.server.SetEntry(alice("Alice Hargreaves", "cn=nobody,ou=groups,dc=example,dc=com"))
+	if CheckUserPass("alice", "alice-secret") {
+		t.Errorf("CheckUserPass let in a user in no mapped group")
+	}
+	checkUser(t, "Alice Hargreaves", f.operatorRoleId, 1)
+}
+
+func TestExternalUserTakeover(t *testing.T) {
+	f := newLdapFixture(t)
+
+	// the built-in SYSTEM account is not EXTERNAL, so a directory user of
+	// the same name must not take it over
+	_, err := ExternalUser(ldapauth.Provider, "SYSTEM", model.ProposedUser{UserName: "SYSTEM", FullName: "Mallory", RoleId: 1, OrgUnitId: 1})
+	var conflict *model.ExternalAccountConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("ExternalUser on the SYSTEM account = %v, want a conflict", err)
+	}
+	user, err := model.Repos.Users.GetUserByUserName("SYSTEM")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+	if user.FullName == "Mallory" {
+		t.Errorf("SYSTEM account was renamed by ExternalUser")
+	}
+
+	// an OIDC user claiming the name of an account the directory created
+	// gets neither the account nor the chance to change it
+	if !CheckUserPass("alice", "alice-secret") {
+		t.Fatalf("CheckUserPass turned down the directory password")
+	}
+	_, err = ExternalUser("oidc:https://idp.example.com", "mallory-subject", model.ProposedUser{UserName: "alice", FullName: "Mallory", RoleId: f.adminRoleId, OrgUnitId: 1})
+	if !errors.As(err, &conflict) {
+		t.Errorf("ExternalUser for another provider's account = %v, want a conflict", err)
+	}
+	user = checkUser(t, "Alice Liddell", f.adminRoleId, f.salesOrgUnitId)
+	if user.ExternalProvider != ldapauth.Provider || user.ExternalSubject != "alice" {
+		t.Errorf("alice belongs to %q subject %q, want the directory's alice", user.ExternalProvider, user.ExternalSubject)
+	}
+}
+
+func TestExternalUserOwnership(t *testing.T) {
+	f := newLdapFixture(t)
+	const issuer = "oidc:https://idp.example.com"
+
+	// the provider's own account is found by subject, whatever name the
+	// provider claims for it later
+	created, err := ExternalUser(issuer, "subject-1", model.ProposedUser{UserName: "alice", FullName: "Alice", RoleId: f.adminRoleId, OrgUnitId: 1})
+	if err != nil {
+		t.Fatalf("ExternalUser: %v", err)
+	}
+	if created.ExternalProvider != issuer || created.ExternalSubject != "subject-1" {
+		t.Errorf("account belongs to %q subject %q, want %q subject-1", created.ExternalProvider, created.ExternalSubject, issuer)
+	}
+	renamed, err := ExternalUser(issuer, "subject-1", model.ProposedUser{UserName: "alice2", FullName: "Alice L", RoleId: f.operatorRoleId, OrgUnitId: 1})
+	if err != nil {
+		t.Fatalf("ExternalUser on a later login: %v", err)
+	}
+	if renamed.Id != created.Id || renamed.UserName != "alice" || renamed.FullName != "Alice L" || renamed.RoleId != f.operatorRoleId {
+		t.Errorf("later login returned %+v, want account %d synced with the provider", renamed, created.Id)
+	}
+
+	// another subject of the same provider claiming the name is turned away
+	_, err = ExternalUser(issuer, "subject-2", model.ProposedUser{UserName: "alice", FullName: "Mallory", RoleId: f.adminRoleId, OrgUnitId: 1})
+	var conflict *model.ExternalAccountConflict
+	if !errors.As(err, &conflict) {
+		t.Errorf("ExternalUser for another subject = %v, want a conflict", err)
+	}
+
+	// and so is the directory, even with a good password for its own alice
+	if CheckUserPass("alice", "alice-secret") {
+		t.Errorf("CheckUserPass let the directory log in to an OIDC account")
+	}
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+	if user.FullName != "Alice L" || user.RoleId != f.operatorRoleId || user.ExternalProvider != issuer {
+		t.Errorf("OIDC account was changed by the directory: %+v", user)
+	}
+}
+
+func TestUnownedExternalUser(t *testing.T) {
+	f := newLdapFixture(t)
+
+	// accounts made by hand belong to no provider until the directory
+	// claims them, and no OIDC provider ever can
+	_, err := model.Repos.Users.CreateUser(model.ProposedUser{UserName: "alice", FullName: "Alice", Status: "enabled", OrgUnitId: 1, RoleId: f.operatorRoleId, TypeId: model.UserTypeExternal, Password: "Unused-password-1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	_, err = ExternalUser("oidc:https://idp.example.com", "subject-1", model.ProposedUser{UserName: "alice", FullName: "Mallory", RoleId: f.adminRoleId, OrgUnitId: 1})
+	var conflict *model.ExternalAccountConflict
+	if !errors.As(err, &conflict) {
+		t.Errorf("ExternalUser claimed an unowned account for OIDC: %v", err)
+	}
+
+	if !CheckUserPass("alice", "alice-secret") {
+		t.Fatalf("CheckUserPass turned down the directory password for an unowned account")
+	}
+	user := checkUser(t, "Alice Liddell", f.adminRoleId, f.salesOrgUnitId)
+	if user.ExternalProvider != ldapauth.Provider || user.ExternalSubject != "alice" {
+		t.Errorf("alice belongs to %q subject %q after logging in, want the directory's alice", user.ExternalProvider, user.ExternalSubject)
+	}
+}
+
+// TestRehashOnLogin checks that logging in replaces a password hash made the
+// old way, or with other parameters than the current ones, and leaves it
+// alone when the password is wrong
+func TestRehashOnLogin(t *testing.T) {
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	t.Cleanup(func() { passwords.Configure(passwords.DefaultParams()) })
+	err = passwords.Configure(passwords.Params{Algorithm: passwords.Argon2id, Argon2Time: 1, Argon2MemoryKB: 1024, Argon2Threads: 1})
+	if err != nil {
+		t.Fatalf("passwords.Configure: %v", err)
+	}
+	_, err = model.Repos.Users.CreateUser(model.ProposedUser{UserName: "carol", FullName: "Carol", Status: "enabled", OrgUnitId: 1, RoleId: 1, TypeId: model.UserTypeLocal, Password: "Carol-password-1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	storedHash := func() string {
+		t.Helper()
+		user, err := model.Repos.Users.GetUserByUserName("carol")
+		if err != nil {
+			t.Fatalf("GetUserByUserName: %v", err)
+		}
+		return user.PasswordHash
+	}
+
+	// as accounts created before the move to a proper KDF are stored
+	sum := sha512.Sum512([]byte("Carol-password-1"))
+	legacy := hex.EncodeToString(sum[:])
+	_, err = model.DB.Exec("UPDATE Users SET PasswordHash = ? WHERE UserName = ?", legacy, "carol")
+	if err != nil {
+		t.Fatalf("storing a legacy hash: %v", err)
+	}
+
+	if CheckUserPass("carol", "Carol-password-2") {
+		t.Fatalf("CheckUserPass with the wrong password succeeded")
+	}
+	if hash := storedHash(); hash != legacy {
+		t.Errorf("after a failed login the hash is %s, want the legacy one left alone", hash)
+	}
+
+	tests := []struct {
+		name   string
+		params passwords.Params
+		prefix string
+	}{
+		{"legacy to argon2id", passwords.Params{Algorithm: passwords.Argon2id, Argon2Time: 1, Argon2MemoryKB: 1024, Argon2Threads: 1}, "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{"argon2id parameters raised", passwords.Params{Algorithm: passwords.Argon2id, Argon2Time: 2, Argon2MemoryKB: 2048, Argon2Threads: 1}, "$argon2id$v=19$m=2048,t=2,p=1$"},
+		{"argon2id to bcrypt", passwords.Params{Algorithm: passwords.Bcrypt, BcryptCost: 4}, "$2a$04$"},
+	}
+	for _, test := range tests {
+		err = passwords.Configure(test.params)
+		if err != nil {
+			t.Fatalf("passwords.Configure: %v", err)
+		}
+		if !CheckUserPass("carol", "Carol-password-1") {
+			t.Fatalf("%s: CheckUserPass with the right password failed", test.name)
+		}
+		hash := storedHash()
+		if !strings.HasPrefix(hash, test.prefix) {
+			t.Errorf("%s: hash after logging in is %s, want it to start with %s", test.name, hash, test.prefix)
+		}
+		if match, needsRehash := passwords.Verify("Carol-password-1", hash); !match || needsRehash {
+			t.Errorf("%s: Verify of the new hash = %v, %v, want a match that needs no rehash", test.name, match, needsRehash)
+		}
+
+		// a login that needs no rehash leaves the hash as it is
+		if !CheckUserPass("carol", "Carol-password-1") {
+			t.Fatalf("%s: second CheckUserPass failed", test.name)
+		}
+		if again := storedHash(); again != hash {
+			t.Errorf("%s: hash changed to %s on a login that needed no rehash", test.name, again)
+		}
+	}
+}
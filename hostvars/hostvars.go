@@ -0,0 +1,181 @@
+package hostvars
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// layers are applied in this order, later ones overriding earlier ones
+const (
+	ScopeGlobal      = "global"
+	ScopeOrgUnit     = "orgUnit"
+	ScopeBuilding    = "building"
+	ScopeMachineRole = "machineRole"
+	ScopeSystem      = "system"
+)
+
+var Scopes = []string{ScopeGlobal, ScopeOrgUnit, ScopeBuilding, ScopeMachineRole, ScopeSystem}
+
+// Layer is one set of variables along with where it is defined. ScopeId is
+// the Id of the org unit, building, machine role or system, and 0 for the
+// global layer
+type Layer struct {
+	Scope   string
+	ScopeId int
+	Vars    string
+}
+
+// Value is a resolved variable and the layer that supplied it
+type Value struct {
+	Value   any    `json:"value"`
+	Scope   string `json:"scope"`
+	ScopeId int    `json:"scopeId"`
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse decodes a set of variables, which must be a JSON object. An empty
+// string is treated as an empty object
+func Parse(vars string) (map[string]any, error) {
+	parsed := make(map[string]any)
+	if strings.TrimSpace(vars) == "" {
+		return parsed, nil
+	}
+	err := json.Unmarshal([]byte(vars), &parsed)
+	if err != nil {
+		return nil, err
+	}
+	if parsed == nil {
+		return nil, errors.New("host vars must be a JSON object")
+	}
+
+	return parsed, nil
+}
+
+// Resolve merges the layers, which must be given in precedence order. Only
+// top-level keys are merged: a nested object in a later layer replaces the
+// earlier one as a whole, so every value has exactly one source
+func Resolve(layers []Layer) (map[string]Value, error) {
+	resolved := make(map[string]Value)
+	for _, layer := range layers {
+		vars, err := Parse(layer.Vars)
+		if err != nil {
+			return nil, errors.New(layer.Scope + " host vars: " + err.Error())
+		}
+		for key, value := range vars {
+			resolved[key] = Value{Value: value, Scope: layer.Scope, ScopeId: layer.ScopeId}
+		}
+	}
+
+	return resolved, nil
+}
+
+// Flatten drops the sources from resolved variables
+func Flatten(resolved map[string]Value) map[string]any {
+	vars := make(map[string]any, len(resolved))
+	for key, value := range resolved {
+		vars[key] = value.Value
+	}
+	return vars
+}
+
+const schemaUrl = "allocatord:///hostvars.schema.json"
+
+// schemas are self-contained: references to anything but the meta-schemas
+// are refused rather than fetched, so a schema cannot read local files
+func compile(schema string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, errors.New("external references are not supported: " + url)
+	}
+	err := compiler.AddResource(schemaUrl, strings.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile(schemaUrl)
+}
+
+// ValidateSchema checks that a schema compiles, so broken schemas are refused
+// when they are attached to a machine role
+func ValidateSchema(schema string) error {
+	_, err := compile(schema)
+	return err
+}
+
+// Validate checks variables against a schema. The returned list holds one
+// entry per violation, each prefixed by the JSON pointer of the offending
+// value; it is empty when the variables are valid
+func Validate(schema string, vars map[string]any) ([]string, error) {
+	compiled, err := compile(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// round trip through JSON so the validator sees plain JSON types
+	raw, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	err = json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]string, 0)
+	err = compiled.Validate(doc)
+	if err == nil {
+		return violations, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+	collect(validationErr, &violations)
+
+	return violations, nil
+}
+
+func collect(ve *jsonschema.ValidationError, violations *[]string) {
+	if len(ve.Causes) == 0 {
+		location := ve.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		*violations = append(*violations, location+": "+ve.Message)
+		return
+	}
+	for _, cause := range ve.Causes {
+		collect(cause, violations)
+	}
+}
@@ -0,0 +1,212 @@
+package ldapauth
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Provider is the identity provider the accounts of directory users are
+// recorded as belonging to. The directory knows users by their name, which
+// is the subject their accounts are recorded with
+const Provider = "ldap"
+
+// ErrInvalidCredentials is returned when the directory turns down the user
+// name and password
+var ErrInvalidCredentials = errors.New("invalid LDAP credentials")
+
+// ErrNoMappedGroup is returned when a user is in none of the groups mapped to
+// a role or an organizational unit, and there is no default to fall back to
+var ErrNoMappedGroup = errors.New("user is not a member of any mapped LDAP group")
+
+// GroupMapping maps the members of an LDAP group, given by its DN, to the Id
+// of a role or an organizational unit
+type GroupMapping struct {
+	Group string
+	Id    int
+}
+
+// Config is how to reach the directory and find users in it. Users are
+// looked up with UserFilter, in which %s is replaced by the escaped user
+// name, after binding as BindDN, or anonymously when it is empty
+type Config struct {
+	Url                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	FullNameAttribute  string
+	GroupAttribute     string
+	Timeout            time.Duration
+	RoleGroups         []GroupMapping
+	OrgUnitGroups      []GroupMapping
+	DefaultRoleId      int
+	DefaultOrgUnitId   int
+}
+
+// Identity is what the directory knows about a user who logged in, with the
+// role and organizational unit their groups map to
+type Identity struct {
+	DN        string
+	UserName  string
+	FullName  string
+	Groups    []string
+	RoleId    int
+	OrgUnitId int
+}
+
+var config Config
+
+// Configure sets the directory EXTERNAL users are authenticated against.
+// Leaving the URL empty turns LDAP authentication off
+func Configure(c Config) error {
+	if c.Url == "" {
+		config = Config{}
+		return nil
+	}
+	if c.BaseDN == "" {
+		return errors.New("an LDAP base DN is needed to look up users")
+	}
+	if c.UserFilter == "" {
+		c.UserFilter = "(uid=%s)"
+	}
+	if !strings.Contains(c.UserFilter, "%s") {
+		return errors.New("the LDAP user filter must contain %s for the user name")
+	}
+	if c.FullNameAttribute == "" {
+		c.FullNameAttribute = "cn"
+	}
+	if c.GroupAttribute == "" {
+		c.GroupAttribute = "memberOf"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+
+	config = c
+	return nil
+}
+
+// Enabled reports whether LDAP authentication has been configured
+func Enabled() bool {
+	return config.Url != ""
+}
+
+func connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(config.Url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(config.Timeout)
+
+	if config.StartTLS {
+		err = conn.StartTLS(tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// Authenticate binds to the directory as the user to check their password,
+// and returns who they are along with the role and organizational unit their
+// groups map to
+func Authenticate(username string, password string) (Identity, error) {
+	// an empty password would make an unauthenticated bind, which most
+	// directories accept for any DN
+	if username == "" || password == "" {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	conn, err := connect()
+	if err != nil {
+		return Identity{}, fmt.Errorf("cannot connect to the LDAP server: %w", err)
+	}
+	defer conn.Close()
+
+	if config.BindDN != "" {
+		err = conn.Bind(config.BindDN, config.BindPassword)
+		if err != nil {
+			return Identity{}, fmt.Errorf("cannot bind as the LDAP service account: %w", err)
+		}
+	}
+
+	search := ldap.NewSearchRequest(config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(config.Timeout.Seconds()), false,
+		fmt.Sprintf(config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{config.FullNameAttribute, config.GroupAttribute}, nil)
+	result, err := conn.Search(search)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return Identity{}, fmt.Errorf("cannot look up the user in LDAP: %w", err)
+	}
+	if result == nil || len(result.Entries) != 1 {
+		log.Println("WARN: LDAP user filter did not match exactly one entry for user '" + username + "'")
+		return Identity{}, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return Identity{}, ErrInvalidCredentials
+		}
+		return Identity{}, fmt.Errorf("cannot bind as the user: %w", err)
+	}
+
+	identity := Identity{
+		DN:       entry.DN,
+		UserName: username,
+		FullName: entry.GetAttributeValue(config.FullNameAttribute),
+		Groups:   entry.GetAttributeValues(config.GroupAttribute),
+	}
+	if identity.FullName == "" {
+		identity.FullName = username
+	}
+	identity.RoleId = mapGroups(identity.Groups, config.RoleGroups, config.DefaultRoleId)
+	identity.OrgUnitId = mapGroups(identity.Groups, config.OrgUnitGroups, config.DefaultOrgUnitId)
+	if identity.RoleId == 0 || identity.OrgUnitId == 0 {
+		return identity, ErrNoMappedGroup
+	}
+
+	return identity, nil
+}
+
+// mapGroups returns the Id of the first mapping the user's groups match. DNs
+// are compared without regard to case, as directories do
+func mapGroups(groups []string, mappings []GroupMapping, fallback int) int {
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if strings.EqualFold(group, mapping.Group) {
+				return mapping.Id
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,230 @@
+package ldapauth
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/ldapauth/ldaptest"
+)
+
+const (
+	serviceDN       = "cn=allocatord,ou=services,dc=example,dc=com"
+	servicePassword = "service-secret"
+	peopleDN        = "ou=people,dc=example,dc=com"
+	adminsGroup     = "cn=admins,ou=groups,dc=example,dc=com"
+	operatorsGroup  = "cn=operators,ou=groups,dc=example,dc=com"
+	salesGroup      = "cn=sales,ou=groups,dc=example,dc=com"
+	adminRoleId     = 2
+	operatorRoleId  = 3
+	salesOrgUnitId  = 4
+)
+
+func person(uid, password, cn string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=" + uid + "," + peopleDN,
+		Password: password,
+		Attributes: map[string][]string{
+			"objectClass": {"inetOrgPerson"},
+			"uid":         {uid},
+			"cn":          {cn},
+			"memberOf":    groups,
+		},
+	}
+}
+
+// directory starts a directory and points the package at it, binding as
+// the service account to look users up
+func directory(t *testing.T, c Config) *ldaptest.Server {
+	t.Helper()
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: serviceDN, Password: servicePassword},
+		person("alice", "alice-secret", "Alice Liddell", "CN=Admins,OU=Groups,DC=example,DC=com", salesGroup),
+		person("bob", "bob-secret", "Bob", operatorsGroup),
+		person("carol", "carol-secret", "", "cn=nobody,ou=groups,dc=example,dc=com"),
+	)
+	t.Cleanup(server.Close)
+
+	c.Url = server.URL
+	c.BaseDN = "dc=example,dc=com"
+	c.Timeout = 5 * time.Second
+	if c.BindDN == "" {
+		c.BindDN = serviceDN
+		c.BindPassword = servicePassword
+	}
+	err := Configure(c)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { Configure(Config{}) })
+	return server
+}
+
+func mappedConfig() Config {
+	return Config{
+		RoleGroups: []GroupMapping{
+			{Group: adminsGroup, Id: adminRoleId},
+			{Group: operatorsGroup, Id: operatorRoleId},
+		},
+		OrgUnitGroups: []GroupMapping{
+			{Group: salesGroup, Id: salesOrgUnitId},
+		},
+		DefaultOrgUnitId: 1,
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Config{})
+
+	err := Configure(Config{})
+	if err != nil || Enabled() {
+		t.Fatalf("Configure without a URL = %v, enabled %v; want LDAP off", err, Enabled())
+	}
+	err = Configure(Config{Url: "ldap://localhost"})
+	if err == nil {
+		t.Errorf("Configure without a base DN succeeded")
+	}
+	err = Configure(Config{Url: "ldap://localhost", BaseDN: "dc=example,dc=com", UserFilter: "(uid=*)"})
+	if err == nil {
+		t.Errorf("Configure with a user filter lacking %%s succeeded")
+	}
+	err = Configure(Config{Url: "ldap://localhost", BaseDN: "dc=example,dc=com"})
+	if err != nil || !Enabled() {
+		t.Errorf("Configure = %v, enabled %v; want LDAP on", err, Enabled())
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	directory(t, mappedConfig())
+
+	identity, err := Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if identity.DN != "uid=alice,"+peopleDN || identity.UserName != "alice" || identity.FullName != "Alice Liddell" {
+		t.Errorf("identity = %+v, want alice's DN, name and full name", identity)
+	}
+	if len(identity.Groups) != 2 {
+		t.Errorf("groups = %v, want alice's two groups", identity.Groups)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"wrong password", "alice", "wrong"},
+		{"empty password", "alice", ""},
+		{"unknown user", "mallory", "alice-secret"},
+		{"filter injection", "*", "alice-secret"},
+		{"empty user name", "", "alice-secret"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Authenticate(tt.username, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Authenticate(%q, %q) = %v, want ErrInvalidCredentials", tt.username, tt.password, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateServiceAccount(t *testing.T) {
+	c := mappedConfig()
+	c.BindDN = serviceDN
+	c.BindPassword = "wrong"
+	directory(t, c)
+
+	// a misconfigured service account is not the user's fault, so it must
+	// not be reported as bad credentials
+	_, err := Authenticate("alice", "alice-secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate with a bad service account = %v, want another error", err)
+	}
+}
+
+func TestAuthenticateUnreachable(t *testing.T) {
+	server := directory(t, mappedConfig())
+	server.Close()
+
+	_, err := Authenticate("alice", "alice-secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate against a stopped server = %v, want a connection error", err)
+	}
+}
+
+func TestGroupMapping(t *testing.T) {
+	directory(t, mappedConfig())
+
+	tests := []struct {
+		username  string
+		password  string
+		roleId    int
+		orgUnitId int
+		fullName  string
+		err       error
+	}{
+		// group DNs match without regard to case
+		{"alice", "alice-secret", adminRoleId, salesOrgUnitId, "Alice Liddell", nil},
+		// no OU group, so the default OU
+		{"bob", "bob-secret", operatorRoleId, 1, "Bob", nil},
+		// no role group and no default role
+		{"carol", "carol-secret", 0, 1, "carol", ErrNoMappedGroup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			identity, err := Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Authenticate = %v, want %v", err, tt.err)
+			}
+			if identity.RoleId != tt.roleId || identity.OrgUnitId != tt.orgUnitId {
+				t.Errorf("role %d and OU %d, want role %d and OU %d", identity.RoleId, identity.OrgUnitId, tt.roleId, tt.orgUnitId)
+			}
+			if identity.FullName != tt.fullName {
+				t.Errorf("full name %q, want %q", identity.FullName, tt.fullName)
+			}
+		})
+	}
+}
+
+func TestMapGroups(t *testing.T) {
+	mappings := []GroupMapping{
+		{Group: adminsGroup, Id: adminRoleId},
+		{Group: operatorsGroup, Id: operatorRoleId},
+	}
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   int
+	}{
+		{"first mapping wins", []string{operatorsGroup, adminsGroup}, adminRoleId},
+		{"case insensitive", []string{"CN=Operators,OU=Groups,DC=Example,DC=Com"}, operatorRoleId},
+		{"fallback", []string{salesGroup}, 9},
+		{"no groups", nil, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapGroups(tt.groups, mappings, 9); got != tt.want {
+				t.Errorf("mapGroups(%v) = %d, want %d", tt.groups, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package ldaptest
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// Package ldaptest provides a directory server for tests, in the manner of
+// net/http/httptest. It understands just enough LDAP to answer simple binds
+// and searches with equality, presence, and, or and not filters
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP operations and result codes the server deals in
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchEntry      = 4
+	opSearchDone       = 5
+	opExtendedRequest  = 23
+	opExtendedResponse = 24
+
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultUnwillingToPerform = 53
+	filterAnd                = 0
+	filterOr                 = 1
+	filterNot                = 2
+	filterEqualityMatch      = 3
+	filterPresent            = 7
+)
+
+// Entry is an object in the directory. Entries without a password cannot be
+// bound as
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a directory listening on a port of the loopback interface
+type Server struct {
+	// URL is the ldap:// URL of the server
+	URL string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	entries  []Entry
+	conns    map[net.Conn]bool
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a directory holding the given entries. It is stopped
+// with Close
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: cannot listen on a port: " + err.Error())
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+		conns:    make(map[net.Conn]bool),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// SetEntry adds an entry to the directory, or replaces the one with the same
+// DN
+func (s *Server) SetEntry(e Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, existing := range s.entries {
+		if strings.EqualFold(existing.DN, e.DN) {
+			s.entries[i] = e
+			return
+		}
+	}
+	s.entries = append(s.entries, e)
+}
+
+// Close stops the server and drops every connection to it
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns[conn] = true
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			err = s.bind(conn, messageId, op)
+		case opSearchRequest:
+			err = s.search(conn, messageId, op)
+		case opUnbindRequest:
+			return
+		case opExtendedRequest:
+			// StartTLS and the like are not offered
+			err = writeResult(conn, messageId, opExtendedResponse, resultProtocolError)
+		default:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) bind(conn net.Conn, messageId int64, op *ber.Packet) error {
+	if len(op.Children) < 3 {
+		return writeResult(conn, messageId, opBindResponse, resultProtocolError)
+	}
+	dn := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+
+	// anonymous binds are let through, unauthenticated ones, naming a DN
+	// without a password, are not
+	if dn == "" && password == "" {
+		return writeResult(conn, messageId, opBindResponse, resultSuccess)
+	}
+	if password == "" {
+		return writeResult(conn, messageId, opBindResponse, resultUnwillingToPerform)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) && e.Password != "" && e.Password == password {
+			return writeResult(conn, messageId, opBindResponse, resultSuccess)
+		}
+	}
+	return writeResult(conn, messageId, opBindResponse, resultInvalidCredentials)
+}
+
+func (s *Server) search(conn net.Conn, messageId int64, op *ber.Packet) error {
+	if len(op.Children) < 8 {
+		return writeResult(conn, messageId, opSearchDone, resultProtocolError)
+	}
+	baseDN := strings.ToLower(op.Children[0].Data.String())
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	wanted := make([]string, 0)
+	for _, attribute := range op.Children[7].Children {
+		wanted = append(wanted, attribute.Data.String())
+	}
+
+	s.mutex.Lock()
+	matches := make([]Entry, 0)
+	for _, e := range s.entries {
+		dn := strings.ToLower(e.DN)
+		if (dn == baseDN || strings.HasSuffix(dn, ","+baseDN)) && matchFilter(e, filter) {
+			matches = append(matches, e)
+		}
+	}
+	s.mutex.Unlock()
+
+	code := int64(resultSuccess)
+	if sizeLimit > 0 && int64(len(matches)) > sizeLimit {
+		matches = matches[:sizeLimit]
+		code = resultSizeLimitExceeded
+	}
+	for _, e := range matches {
+		_, err := conn.Write(searchEntry(messageId, e, wanted).Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(conn, messageId, opSearchDone, code)
+}
+
+// attributeValues returns the values of an attribute, whose name is matched
+// without regard to case
+func attributeValues(e Entry, name string) ([]string, bool) {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+func matchFilter(e Entry, filter *ber.Packet) bool {
+	if filter.ClassType != ber.ClassContext {
+		return false
+	}
+
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(e, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matchFilter(e, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matchFilter(e, filter.Children[0])
+	case filterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		values, _ := attributeValues(e, filter.Children[0].Data.String())
+		for _, value := range values {
+			if strings.EqualFold(value, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		if strings.EqualFold(filter.Data.String(), "objectClass") {
+			return true
+		}
+		_, present := attributeValues(e, filter.Data.String())
+		return present
+	}
+
+	return false
+}
+
+func envelope(messageId int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func writeResult(conn net.Conn, messageId int64, opTag ber.Tag, code int64) error {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opTag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	_, err := conn.Write(envelope(messageId, op).Bytes())
+	return err
+}
+
+// searchEntry returns an entry with the attributes asked for, or all of them
+// when none were
+func searchEntry(messageId int64, e Entry, wanted []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range e.Attributes {
+		if len(wanted) > 0 {
+			found := false
+			for _, w := range wanted {
+				if strings.EqualFold(w, name) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+
+	return envelope(messageId, op)
+}
@@ -0,0 +1,311 @@
+package machineca
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"time"
+)
+
+// Config is where the internal certificate authority keeps its certificate
+// and key, and how long the client certificates it issues to systems stay
+// valid. Leaving CertFile empty turns it off. Systems authenticate to the
+// machine facing API with their certificate instead of a machine token, and
+// with RequireClientCertificates set machine tokens are turned away
+type Config struct {
+	CertFile                  string
+	KeyFile                   string
+	Validity                  time.Duration
+	RequireClientCertificates bool
+}
+
+// Certificate is a client certificate issued to a system
+type Certificate struct {
+	PEM         string
+	Fingerprint string
+	NotAfter    time.Time
+}
+
+// client certificates are valid this long unless configured otherwise, and
+// a certificate authority generated on first start for this long
+const (
+	DefaultValidity = 365 * 24 * time.Hour
+	caValidity      = 10 * 365 * 24 * time.Hour
+)
+
+// certificates are backdated a little, so clients whose clocks run slow do
+// not reject them as not yet valid
+const clockSkew = 5 * time.Minute
+
+var config Config
+var caCert *x509.Certificate
+var caKey crypto.Signer
+var caPEM string
+var certPool *x509.CertPool
+
+// Configure loads the certificate authority. When neither of its files
+// exist yet a new one is generated and written to them, so the first start
+// is all it takes to turn it on
+func Configure(c Config) error {
+	if c.CertFile == "" {
+		if c.RequireClientCertificates {
+			return errors.New("client certificates cannot be required without a machine certificate authority")
+		}
+		return nil
+	}
+	if c.KeyFile == "" {
+		return errors.New("the machine certificate authority needs a key file")
+	}
+	if c.Validity < 0 {
+		return errors.New("machine certificate validity cannot be negative")
+	}
+	if c.Validity == 0 {
+		c.Validity = DefaultValidity
+	}
+
+	_, certErr := os.Stat(c.CertFile)
+	_, keyErr := os.Stat(c.KeyFile)
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		err := generate(c.CertFile, c.KeyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	cert, key, err := load(c.CertFile, c.KeyFile)
+	if err != nil {
+		return err
+	}
+	if !cert.IsCA {
+		return errors.New(c.CertFile + " is not a certificate authority")
+	}
+
+	config = c
+	caCert = cert
+	caKey = key
+	caPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	certPool = x509.NewCertPool()
+	certPool.AddCert(cert)
+	log.Println("INFO: Machine certificate authority '" + cert.Subject.CommonName + "' loaded, valid until " + cert.NotAfter.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// Enabled reports whether client certificates are issued to systems
+func Enabled() bool {
+	return caCert != nil
+}
+
+// Required reports whether systems must authenticate with a client
+// certificate rather than a machine token
+func Required() bool {
+	return Enabled() && config.RequireClientCertificates
+}
+
+// CertPool holds the certificate authority, for verifying the client
+// certificates systems present
+func CertPool() *x509.CertPool {
+	return certPool
+}
+
+// CACertificate returns the certificate of the certificate authority as PEM
+func CACertificate() string {
+	return caPEM
+}
+
+// Fingerprint is the SHA-256 of a certificate, which identifies it
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseRequest decodes a PEM certificate signing request and checks that it
+// was signed with the key it asks a certificate for
+func ParseRequest(csrPEM string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("certificate request must be a PEM encoded CERTIFICATE REQUEST")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	err = csr.CheckSignature()
+	if err != nil {
+		return nil, errors.New("certificate request signature is invalid: " + err.Error())
+	}
+
+	return csr, nil
+}
+
+// Issue signs a client certificate for the key of a certificate request. The
+// subject the request asks for is ignored, the certificate is always issued
+// to the serial number of the system
+func Issue(csrPEM string, systemSerialNumber string) (Certificate, error) {
+	if !Enabled() {
+		return Certificate{}, errors.New("the machine certificate authority is not configured")
+	}
+	if systemSerialNumber == "" {
+		return Certificate{}, errors.New("client certificates need the serial number of a system")
+	}
+	csr, err := ParseRequest(csrPEM)
+	if err != nil {
+		return Certificate{}, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	now := time.Now().UTC()
+	notAfter := now.Add(config.Validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, isRSA := csr.PublicKey.(*rsa.PublicKey); isRSA {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: systemSerialNumber},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return Certificate{}, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	return Certificate{
+		PEM:         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Fingerprint: Fingerprint(cert),
+		NotAfter:    cert.NotAfter,
+	}, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// generate creates a self-signed certificate authority with an ECDSA P-256
+// key, writing the key so only the daemon's user can read it
+func generate(certFile string, keyFile string) error {
+	log.Println("WARN: No machine certificate authority found, generating one in " + certFile)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "allocatord machine CA " + hostname},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+func load(certFile string, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, errors.New("no PEM encoded CERTIFICATE found in " + certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, errors.New("no PEM encoded key found in " + keyFile)
+	}
+	var parsed any
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	key, isSigner := parsed.(crypto.Signer)
+	if !isSigner {
+		return nil, nil, errors.New("the key in " + keyFile + " cannot sign certificates")
+	}
+	public, comparable := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !comparable || !public.Equal(cert.PublicKey) {
+		return nil, nil, errors.New("the key in " + keyFile + " does not belong to the certificate in " + certFile)
+	}
+
+	return cert, key, nil
+}
@@ -0,0 +1,206 @@
+package machineca
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configure sets up a freshly generated certificate authority in a temporary
+// directory
+func configure(t *testing.T, validity time.Duration) {
+	t.Helper()
+	dir := t.TempDir()
+	err := Configure(Config{
+		CertFile: filepath.Join(dir, "ca.pem"),
+		KeyFile:  filepath.Join(dir, "ca.key"),
+		Validity: validity,
+	})
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+}
+
+// certificateRequest returns a PEM certificate signing request asking for a
+// certificate for commonName, and the key it was made with
+func certificateRequest(t *testing.T, commonName string) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), key
+}
+
+func parseCertificate(t *testing.T, certPEM string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		t.Fatal("issued certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestConfigure(t *testing.T) {
+	dir := t.TempDir()
+	c := Config{CertFile: filepath.Join(dir, "ca.pem"), KeyFile: filepath.Join(dir, "ca.key")}
+	err := Configure(c)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if !Enabled() || Required() {
+		t.Errorf("got Enabled() = %v and Required() = %v, want true and false", Enabled(), Required())
+	}
+	generated := CACertificate()
+
+	// a second start loads the certificate authority the first one made
+	err = Configure(c)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if CACertificate() != generated {
+		t.Error("the certificate authority was generated again instead of loaded")
+	}
+
+	other := t.TempDir()
+	err = Configure(Config{CertFile: filepath.Join(other, "ca.pem"), KeyFile: filepath.Join(other, "ca.key")})
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	err = Configure(Config{CertFile: c.CertFile, KeyFile: filepath.Join(other, "ca.key")})
+	if err == nil || !strings.Contains(err.Error(), "does not belong") {
+		t.Errorf("a key of another certificate authority was accepted, got %v", err)
+	}
+
+	if err := Configure(Config{RequireClientCertificates: true}); err == nil {
+		t.Error("client certificates were required without a certificate authority")
+	}
+	if err := Configure(Config{CertFile: c.CertFile}); err == nil {
+		t.Error("a certificate authority without a key file was accepted")
+	}
+}
+
+func TestIssue(t *testing.T) {
+	configure(t, 0)
+	csrPEM, key := certificateRequest(t, "someone-else")
+
+	issued, err := Issue(csrPEM, "SN-0001")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	cert := parseCertificate(t, issued.PEM)
+
+	if cert.Subject.CommonName != "SN-0001" {
+		t.Errorf("certificate issued to %q, want the serial number SN-0001", cert.Subject.CommonName)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		t.Error("certificate is not for the key of the request")
+	}
+	if issued.Fingerprint != Fingerprint(cert) || !issued.NotAfter.Equal(cert.NotAfter) {
+		t.Error("fingerprint or expiry returned do not match the certificate")
+	}
+	if got := cert.NotAfter.Sub(cert.NotBefore); got != DefaultValidity+clockSkew {
+		t.Errorf("certificate valid for %v, want %v", got, DefaultValidity+clockSkew)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Errorf("certificate does not verify as a client certificate: %v", err)
+	}
+
+	if _, err := Issue(csrPEM, ""); err == nil {
+		t.Error("a certificate was issued without a serial number")
+	}
+}
+
+func TestIssueValidity(t *testing.T) {
+	configure(t, 20*365*24*time.Hour)
+	csrPEM, _ := certificateRequest(t, "")
+
+	issued, err := Issue(csrPEM, "SN-0002")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !issued.NotAfter.Equal(caCert.NotAfter) {
+		t.Errorf("certificate valid until %v, want it cut off at the certificate authority's %v", issued.NotAfter, caCert.NotAfter)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	csrPEM, _ := certificateRequest(t, "SN-0003")
+	block, _ := pem.Decode([]byte(csrPEM))
+
+	// flipping a bit of the signature leaves a request that no longer
+	// matches the key it claims to be for
+	tampered := append([]byte{}, block.Bytes...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := x509.ParseCertificateRequest(tampered); err != nil {
+		t.Fatalf("tampered request does not parse: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		csr  string
+		ok   bool
+	}{
+		{"valid", csrPEM, true},
+		{"empty", "", false},
+		{"not PEM", "not a certificate request", false},
+		{"wrong type", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: block.Bytes})), false},
+		{"garbage", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte("garbage")})), false},
+		{"signature mismatch", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered})), false},
+	}
+	configure(t, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRequest(tt.csr)
+			if tt.ok && err != nil {
+				t.Errorf("ParseRequest: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("ParseRequest accepted the request")
+			}
+			_, err = Issue(tt.csr, "SN-0003")
+			if !tt.ok && err == nil {
+				t.Error("Issue signed the request")
+			}
+		})
+	}
+}
@@ -24,13 +24,14 @@ package main
 */
 
 import (
-	"database/sql"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
-	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -43,9 +44,18 @@ import (
 	_ "github.com/greeneg/allocatord/docs"
 	"github.com/greeneg/allocatord/globals"
 	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/ldapauth"
+	"github.com/greeneg/allocatord/machineca"
 	"github.com/greeneg/allocatord/middleware"
+	"github.com/greeneg/allocatord/migrations"
 	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/oidcauth"
+	"github.com/greeneg/allocatord/passwords"
 	"github.com/greeneg/allocatord/routes"
+	"github.com/greeneg/allocatord/scheduler"
+	"github.com/greeneg/allocatord/tftpserver"
+	"github.com/greeneg/allocatord/twofactor"
+	"github.com/greeneg/allocatord/websession"
 )
 
 //	@title			Allocator Daemon
@@ -57,6 +67,16 @@ import (
 
 //	@securityDefinitions.basic	BasicAuth
 
+//	@securityDefinitions.apikey	MachineToken
+//	@in							header
+//	@name						X-Auth-Token
+//	@description				Per-system machine token. Requests must also send 'X-ASSIMILATOR-TYPE: MACHINE'
+
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				API token of a user, sent as 'Bearer <token>'
+
 //	@license.name	Apache 2.0
 //	@license.url	http://www.apache.org/licenses/LICENSE-2.0.html
 
@@ -65,258 +85,6 @@ import (
 
 // @schemas	http https
 
-func createDB(dbName string) (bool, error) {
-	log.Println("INFO: DB doesn't exist. Attempt to create it")
-	const schema string = `CREATE TABLE IF NOT EXISTS Architectures (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  NOT NULL
-							  UNIQUE,
-		ISEName      STRING   UNIQUE
-							  NOT NULL,
-		CreatorId    INTEGER  NOT NULL
-							  REFERENCES Users (Id),
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS Audit (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  NOT NULL
-							  UNIQUE,
-		ChangedById  INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		TableChanged STRING   NOT NULL,
-		ChangeClass  STRING   NOT NULL,
-		ChangeDate   DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS Buildings (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  UNIQUE
-							  NOT NULL,
-		BuildingName STRING   NOT NULL
-							  UNIQUE,
-		ShortName    STRING   NOT NULL
-							  UNIQUE,
-		City         STRING   NOT NULL,
-		Region       STRING   NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS MachineRoles (
-		Id              INTEGER  PRIMARY KEY AUTOINCREMENT
-								 UNIQUE
-								 NOT NULL,
-		MachineRoleName STRING   UNIQUE
-								 NOT NULL,
-		Description     STRING   NOT NULL,
-		CreatorId       INTEGER  REFERENCES Users (Id)
-								 NOT NULL,
-		CreationDate    DATETIME NOT NULL
-								 DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS NetworkInterfaces (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  NOT NULL
-							  UNIQUE,
-		DeviceModel  STRING   NOT NULL,
-		DeviceId     STRING   NOT NULL,
-		MACAddress   STRING   NOT NULL
-							  UNIQUE,
-		SystemId     INTEGER  REFERENCES Systems (Id)
-							  NOT NULL,
-		IpAddress    STRING   NOT NULL,
-		Bitmask      INTEGER  NOT NULL,
-		Gateway      STRING   NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS OperatingSystemFamilies (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  UNIQUE
-							  NOT NULL,
-		OSFamilyName STRING   UNIQUE
-							  NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS OperatingSystems (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  UNIQUE
-							  NOT NULL,
-		OSName       STRING   UNIQUE
-							  NOT NULL,
-		OSFamilyId   INTEGER  REFERENCES OperatingSystemFamilies (Id)
-							  NOT NULL,
-		OSImageUrl   STRING   UNIQUE
-							  NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS OrganizationalUnits (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  NOT NULL
-							  UNIQUE,
-		OUName       STRING   UNIQUE
-							  NOT NULL,
-		Description  STRING   NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-
-	INSERT INTO OrganizationalUnits (Id, OUName, Description, CreatorId, CreationDate)
-		VALUES ( 1, 'Unassigned', 'The OU used as a place holder when a system changes hands', 1, '2024-06-01 15:38:42' );
-
-	CREATE TABLE IF NOT EXISTS Roles (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT,
-		RoleName     STRING   UNIQUE
-							  NOT NULL,
-		Description  STRING   NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-
-	INSERT INTO Roles (Id, RoleName, Description, CreationDate)
-		VALUES ( 1, 'SYSTEM', 'Built-in system role', '2024-06-01 14:57:41' );
-
-	CREATE TABLE IF NOT EXISTS StorageVolumes (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  UNIQUE
-							  NOT NULL,
-		VolumeName   STRING   NOT NULL,
-		StorageType  STRING   NOT NULL,
-		DeviceModel  STRING   NOT NULL,
-		DeviceId     STRING   NOT NULL,
-		MountPoint   STRING   NOT NULL,
-		VolumeSize   INTEGER  NOT NULL,
-		VolumeFormat STRING   NOT NULL,
-		VolumeLabel  STRING   NOT NULL,
-		SystemId     INTEGER  REFERENCES Systems (Id)
-							  NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS SystemModels (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  UNIQUE
-							  NOT NULL,
-		ModelName    STRING   NOT NULL
-							  UNIQUE,
-		CreatorId    INTEGER  REFERENCES Users (Id)
-							  NOT NULL,
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS Systems (
-		Id                INTEGER  PRIMARY KEY AUTOINCREMENT
-								   UNIQUE
-								   NOT NULL,
-		SerialNumber      STRING   NOT NULL
-								   UNIQUE,
-		ModelId           INTEGER  REFERENCES SystemModels (Id)
-								   NOT NULL,
-		OperatingSystemId INTEGER  NOT NULL
-								   REFERENCES OperatingSystems (Id),
-		Reimage           BOOL     NOT NULL
-								   DEFAULT (FALSE),
-		HostVars          STRING   NOT NULL,
-		BilledToOrgUnitId INTEGER  REFERENCES OrganizationalUnits (Id)
-								   NOT NULL,
-		MachineRoleId     INTEGER  NOT NULL
-								   REFERENCES MachineRoles (Id),
-		BuildingId        INTEGER  REFERENCES Buildings (Id)
-								   NOT NULL,
-		VendorId          INTEGER  NOT NULL
-								   REFERENCES Vendors (Id),
-		ArchitectureId    INTEGER  REFERENCES Architectures (Id)
-								   NOT NULL,
-		RAM               INTEGER  NOT NULL,
-		CPUCores          INTEGER  NOT NULL,
-		CreatorId         INTEGER  REFERENCES Users (Id)
-								   NOT NULL,
-		CreationDate      DATETIME NOT NULL
-								   DEFAULT (CURRENT_TIMESTAMP)
-	);
-	CREATE TABLE IF NOT EXISTS Users (
-		Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
-										 UNIQUE
-										 NOT NULL,
-		UserName                STRING   NOT NULL
-										 UNIQUE,
-		FullName                STRING   NOT NULL,
-		Status                  STRING   NOT NULL
-										 DEFAULT enabled,
-		OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
-										 NOT NULL,
-		RoleId                  INTEGER  REFERENCES Roles (Id)
-										 NOT NULL,
-		PasswordHash            STRING   NOT NULL,
-		CreationDate            DATETIME NOT NULL
-										 DEFAULT (CURRENT_TIMESTAMP),
-		LastPasswordChangedDate DATETIME NOT NULL
-										 DEFAULT (CURRENT_TIMESTAMP)
-	);
-
-	INSERT INTO Users (Id, UserName, FullName, Status, OrgUnitId, RoleId, PasswordHash, CreationDate, LastPasswordChangedDate)
-		VALUES ( 1, 'SYSTEM', 'Allocator System', 'enabled', 1, 1, '!', '2024-06-01 14:58:36', '2024-06-01 14:58:36' );
-
-	CREATE TABLE IF NOT EXISTS UserTypes (
-		Id              		INTEGER PRIMARY KEY AUTOINCREMENT
-							  			UNIQUE
-							  			NOT NULL,
-		TypeName        		STRING	NOT NULL
-							  			UNIQUE,
-		Description 			STRING	NOT NULL,
-		AllowRoleChange 		BOOL	NOT NULL
-							  			DEFAULT (FALSE),
-		AllowDeletion   		BOOL	NOT NULL
-							  			DEFAULT (FALSE),
-		AllowDisable			BOOL	NOT NULL
-										DEFAULT (FALSE)
-	);
-
-	INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
-		VALUES ( 1, 'BUILTIN', 'Built-in system user type', FALSE, FALSE, FALSE );
-
-	INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
-		VALUES ( 2, 'LOCAL', 'Local user type', TRUE, TRUE, TRUE );
-
-	INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
-		VALUES ( 3, 'EXTERNAL', 'External user type', TRUE, TRUE, TRUE );
-
-	CREATE TABLE IF NOT EXISTS Vendors (
-		Id           INTEGER  PRIMARY KEY AUTOINCREMENT
-							  NOT NULL
-							  UNIQUE,
-		VendorName   STRING   UNIQUE
-							  NOT NULL,
-		CreatorId    INTEGER  REFERENCES Users (Id),
-		CreationDate DATETIME NOT NULL
-							  DEFAULT (CURRENT_TIMESTAMP)
-	);
-	`
-
-	db, err := sql.Open("sqlite3", dbName)
-	if err != nil {
-		helpers.FatalCheckError(err)
-	}
-	if _, err := db.Exec(schema); err != nil {
-		helpers.FatalCheckError(err)
-	}
-	return true, err
-}
-
 func main() {
 	r := gin.Default()
 	r.SetTrustedProxies(nil)
@@ -342,22 +110,139 @@ func main() {
 	Allocator.ConfigPath = configDir
 	Allocator.ConfStruct = config
 
-	if _, err := os.Stat(Allocator.ConfStruct.DbPath); errors.Is(err, os.ErrNotExist) {
-		_, err := createDB(Allocator.ConfStruct.DbPath)
-		if err != nil {
-			helpers.FatalCheckError(err)
-		}
+	// new password hashes use the configured algorithm and costs, older ones
+	// are upgraded as their owners log in
+	err = passwords.Configure(passwords.Params{
+		Algorithm:      Allocator.ConfStruct.PasswordHashAlgorithm,
+		Argon2Time:     Allocator.ConfStruct.Argon2Time,
+		Argon2MemoryKB: Allocator.ConfStruct.Argon2MemoryKB,
+		Argon2Threads:  Allocator.ConfStruct.Argon2Threads,
+		BcryptCost:     Allocator.ConfStruct.BcryptCost,
+	})
+	helpers.FatalCheckError(err)
+	err = passwords.ConfigurePolicy(passwords.Policy{
+		MinLength:       Allocator.ConfStruct.PasswordMinLength,
+		RequireUpper:    Allocator.ConfStruct.PasswordRequireUpper,
+		RequireLower:    Allocator.ConfStruct.PasswordRequireLower,
+		RequireDigit:    Allocator.ConfStruct.PasswordRequireDigit,
+		RequireSymbol:   Allocator.ConfStruct.PasswordRequireSymbol,
+		MaxAgeDays:      Allocator.ConfStruct.PasswordMaxAgeDays,
+		MaxFailedLogins: Allocator.ConfStruct.MaxFailedLogins,
+		LockoutMinutes:  Allocator.ConfStruct.LockoutMinutes,
+	})
+	helpers.FatalCheckError(err)
+
+	// EXTERNAL users can be authenticated against LDAP, when it is configured
+	err = ldapauth.Configure(ldapauth.Config{
+		Url:                Allocator.ConfStruct.LdapUrl,
+		StartTLS:           Allocator.ConfStruct.LdapStartTLS,
+		InsecureSkipVerify: Allocator.ConfStruct.LdapInsecureSkipVerify,
+		BindDN:             Allocator.ConfStruct.LdapBindDN,
+		BindPassword:       Allocator.ConfStruct.LdapBindPassword,
+		BaseDN:             Allocator.ConfStruct.LdapBaseDN,
+		UserFilter:         Allocator.ConfStruct.LdapUserFilter,
+		FullNameAttribute:  Allocator.ConfStruct.LdapFullNameAttribute,
+		GroupAttribute:     Allocator.ConfStruct.LdapGroupAttribute,
+		Timeout:            time.Duration(Allocator.ConfStruct.LdapTimeoutSeconds) * time.Second,
+		RoleGroups:         ldapGroupMappings(Allocator.ConfStruct.LdapGroupRoles),
+		OrgUnitGroups:      ldapGroupMappings(Allocator.ConfStruct.LdapGroupOrgUnits),
+		DefaultRoleId:      Allocator.ConfStruct.LdapDefaultRoleId,
+		DefaultOrgUnitId:   Allocator.ConfStruct.LdapDefaultOrgUnitId,
+	})
+	helpers.FatalCheckError(err)
+
+	// as can users of an OpenID provider
+	err = oidcauth.Configure(oidcauth.Config{
+		Issuer:           Allocator.ConfStruct.OidcIssuer,
+		ClientId:         Allocator.ConfStruct.OidcClientId,
+		ClientSecret:     Allocator.ConfStruct.OidcClientSecret,
+		RedirectUrl:      Allocator.ConfStruct.OidcRedirectUrl,
+		Scopes:           Allocator.ConfStruct.OidcScopes,
+		Audience:         Allocator.ConfStruct.OidcAudience,
+		UserNameClaim:    Allocator.ConfStruct.OidcUserNameClaim,
+		FullNameClaim:    Allocator.ConfStruct.OidcFullNameClaim,
+		GroupsClaim:      Allocator.ConfStruct.OidcGroupsClaim,
+		RoleGroups:       oidcGroupMappings(Allocator.ConfStruct.OidcGroupRoles),
+		OrgUnitGroups:    oidcGroupMappings(Allocator.ConfStruct.OidcGroupOrgUnits),
+		DefaultRoleId:    Allocator.ConfStruct.OidcDefaultRoleId,
+		DefaultOrgUnitId: Allocator.ConfStruct.OidcDefaultOrgUnitId,
+	})
+	helpers.FatalCheckError(err)
+
+	// LOCAL users can add a TOTP second factor
+	twofactor.Configure(Allocator.ConfStruct.TwoFactorIssuer)
+
+	// session cookies only carry the token of a session kept in the database
+	err = websession.Configure(websession.Config{
+		Keys:        Allocator.ConfStruct.SessionKeys,
+		KeyFile:     Allocator.ConfStruct.SessionKeyFile,
+		IdleTimeout: time.Duration(Allocator.ConfStruct.SessionIdleMinutes) * time.Minute,
+		MaxAge:      time.Duration(Allocator.ConfStruct.SessionMaxAgeMinutes) * time.Minute,
+	})
+	helpers.FatalCheckError(err)
+
+	// systems can be issued client certificates to authenticate with instead
+	// of machine tokens. They are presented during the TLS handshake, so
+	// cannot be required of them without it
+	err = machineca.Configure(machineca.Config{
+		CertFile:                  Allocator.ConfStruct.MachineCaCertFile,
+		KeyFile:                   Allocator.ConfStruct.MachineCaKeyFile,
+		Validity:                  time.Duration(Allocator.ConfStruct.MachineCertValidityDays) * 24 * time.Hour,
+		RequireClientCertificates: Allocator.ConfStruct.RequireMachineCertificates,
+	})
+	helpers.FatalCheckError(err)
+	if machineca.Required() && !Allocator.ConfStruct.UseTLS {
+		helpers.FatalCheckError(errors.New("requireMachineCertificates needs useTls to be set"))
 	}
 
-	err = model.ConnectDatabase(Allocator.ConfStruct.DbPath)
+	// SQLite is the default and reads a local file. PostgreSQL lets several
+	// daemons share one database
+	dataSource := Allocator.ConfStruct.DbPath
+	if Allocator.ConfStruct.DbDriver == model.PostgreSQL {
+		dataSource = Allocator.ConfStruct.DbConnection
+	}
+	err = model.ConnectDatabase(Allocator.ConfStruct.DbDriver, dataSource)
 	helpers.FatalCheckError(err)
 
+	// bring the schema up to date, unless the operator would rather do so
+	// explicitly with setuptool
+	if Allocator.ConfStruct.SkipMigrations {
+		version, err := migrations.CurrentVersion(model.DB)
+		helpers.FatalCheckError(err)
+		if version != migrations.Latest() {
+			helpers.FatalCheckError(errors.New("database schema is at version " + strconv.Itoa(version) +
+				" but version " + strconv.Itoa(migrations.Latest()) + " is required. Run 'setuptool migrate' to upgrade it"))
+		}
+	} else {
+		err = migrations.Up(model.DB)
+		helpers.FatalCheckError(err)
+	}
+
+	// start reimaging batches as their maintenance windows open
+	scheduler.Start(Allocator.ConfStruct.SchedulerInterval)
+
+	// optional TFTP listener for PXE bootstrapping
+	if Allocator.ConfStruct.TFTPEnabled {
+		_, err = tftpserver.Start(Allocator.ConfStruct)
+		helpers.FatalCheckError(err)
+	}
+
 	// set up our static assets
 	// r.Static("/assets", "./assets")
 	// r.LoadHTMLGlob("templates/*.html")
 
 	// some defaults for using session support
-	r.Use(sessions.Sessions("session", cookie.NewStore(globals.Secret)))
+	sessionStore := cookie.NewStore(websession.KeyPairs()...)
+	sessionStore.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   int(websession.MaxAge().Seconds()),
+		Secure:   Allocator.ConfStruct.UseTLS,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	r.Use(sessions.Sessions("session", sessionStore))
+	// tag every request so the changes it makes can be traced back to it
+	r.Use(middleware.RequestId)
 	// frontend
 	// fePublic := r.Group("/")
 	// routes.FePublicRoutes(fePublic, AllocatorD)
@@ -371,9 +256,14 @@ func main() {
 	routes.PublicRoutes(public, Allocator)
 
 	private := r.Group("/api/v1")
-	private.Use(middleware.AuthCheck)
+	private.Use(middleware.AuthCheck, middleware.TokenScopes)
 	routes.PrivateRoutes(private, Allocator)
 
+	// machine facing API used by imaging clients
+	machine := r.Group("/api/v1/machine")
+	machine.Use(middleware.AuthCheck, middleware.RejectScopedTokens)
+	routes.MachineRoutes(machine, Allocator)
+
 	// swagger doc
 	r.GET("/api/v1/swagger/*any", func(c *gin.Context) {
 		if c.Request.URL.Path == "/api/v1/swagger/" {
@@ -388,9 +278,39 @@ func main() {
 	tlsTcpPort := strconv.Itoa(Allocator.ConfStruct.TLSTcpPort)
 	tlsPemFile := Allocator.ConfStruct.TLSPemFile
 	tlsKeyFile := Allocator.ConfStruct.TLSKeyFile
-	if Allocator.ConfStruct.UseTLS {
+	if Allocator.ConfStruct.UseTLS && machineca.Enabled() {
+		// ask for a client certificate without insisting on one, as only
+		// systems have them. Those that are sent must have been issued by
+		// the machine certificate authority
+		server := &http.Server{
+			Addr:    ":" + tlsTcpPort,
+			Handler: r,
+			TLSConfig: &tls.Config{
+				ClientAuth: tls.VerifyClientCertIfGiven,
+				ClientCAs:  machineca.CertPool(),
+			},
+		}
+		err = server.ListenAndServeTLS(tlsPemFile, tlsKeyFile)
+		helpers.FatalCheckError(err)
+	} else if Allocator.ConfStruct.UseTLS {
 		r.RunTLS(":"+tlsTcpPort, tlsPemFile, tlsKeyFile)
 	} else {
 		r.Run(":" + tcpPort)
 	}
 }
+
+func ldapGroupMappings(mappings []globals.GroupMapping) []ldapauth.GroupMapping {
+	converted := make([]ldapauth.GroupMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		converted = append(converted, ldapauth.GroupMapping{Group: mapping.Group, Id: mapping.Id})
+	}
+	return converted
+}
+
+func oidcGroupMappings(mappings []globals.GroupMapping) []oidcauth.GroupMapping {
+	converted := make([]oidcauth.GroupMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		converted = append(converted, oidcauth.GroupMapping{Group: mapping.Group, Id: mapping.Id})
+	}
+	return converted
+}
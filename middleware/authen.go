@@ -19,84 +19,353 @@ package middleware
 */
 
 import (
-	"encoding/base64"
-	"fmt"
+	"crypto/x509"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/greeneg/allocatord/globals"
 	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/machineca"
 	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/oidcauth"
 )
 
-func processAuthorizationHeader(authHeader string) (string, string) {
-	// split the header value at the space
-	encodedString := strings.Split(authHeader, " ")
+// bearerToken returns the token of an Authorization header using the Bearer
+// scheme
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")), true
+}
 
-	// remove base64 encoding
-	decodedString, _ := base64.StdEncoding.DecodeString(encodedString[1])
+// apiTokenAuth authenticates a request as the owner of the API token it
+// presented, aborting it when the token or its owner may not be used
+func apiTokenAuth(c *gin.Context, token string) bool {
+	apiToken, err := model.Repos.ApiTokens.GetApiTokenByToken(token)
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		c.Abort()
+		return false
+	}
+	if apiToken.Id == 0 {
+		log.Println("ERROR: API token authentication failed. Aborting")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+	if apiToken.Expired(time.Now().UTC()) {
+		log.Println("WARN: Expired API token Id " + strconv.Itoa(apiToken.Id) + " presented")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "API token has expired!"})
+		c.Abort()
+		return false
+	}
 
-	// now lets return both the
-	authValues := strings.Split(string(decodedString), ":")
+	user, err := model.Repos.Users.GetUserById(apiToken.UserId)
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		c.Abort()
+		return false
+	}
+	if !helpers.CheckIsNotLocked(user) || !helpers.CheckIsNotLockedOut(user) {
+		log.Println("WARN: User '" + user.UserName + "' is locked!")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+
+	// failing to record the use is not a reason to turn the request away
+	_ = model.Repos.ApiTokens.TouchApiToken(apiToken.Id)
 
-	return authValues[0], authValues[1]
+	log.Println("INFO: Authenticated with API token '" + apiToken.TokenName + "' of user '" + user.UserName + "'")
+	c.Set(globals.UserKey, user.UserName)
+	c.Set(globals.AuthMethodKey, globals.AuthMethodApiToken)
+	c.Set(globals.ApiTokenKey, apiToken)
+	return true
 }
 
-func AuthCheck(c *gin.Context) {
-	var clientFingerprintHeader string = c.GetHeader("X-ASSIMILATOR-TYPE")
-	// check if this is a machine logging in for DB access
-	if clientFingerprintHeader == "MACHINE" {
-		// now grab the token from the headers
-		/*		authToken := c.GetHeader("X-Auth-Token")
-				if authToken != "" {
-					result, err := verifyMachineToken(authToken)
-					if err != nil {
-					}
-					if result {
+// oidcTokenAuth authenticates a request as the user an access token of the
+// OpenID provider was issued to, creating their account on first use
+func oidcTokenAuth(c *gin.Context, token string) bool {
+	identity, err := oidcauth.VerifyAccessToken(c.Request.Context(), token)
+	if err != nil {
+		log.Println("ERROR: OIDC access token authentication failed: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+
+	user, err := helpers.ExternalUser(oidcauth.Provider(), identity.Subject, model.ProposedUser{
+		UserName:  identity.UserName,
+		FullName:  identity.FullName,
+		OrgUnitId: identity.OrgUnitId,
+		RoleId:    identity.RoleId,
+	})
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		c.Abort()
+		return false
+	}
+	if !helpers.CheckIsNotLocked(user) || !helpers.CheckIsNotLockedOut(user) {
+		log.Println("WARN: User '" + user.UserName + "' is locked!")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+
+	log.Println("INFO: Authenticated with OIDC access token of user '" + user.UserName + "'")
+	c.Set(globals.UserKey, user.UserName)
+	c.Set(globals.AuthMethodKey, globals.AuthMethodOidcToken)
+	return true
+}
+
+func verifyMachineToken(authToken string) (int, error) {
+	return model.Repos.MachineTokens.GetSystemIdByMachineToken(authToken)
+}
+
+// clientCertificate returns the client certificate sent during the TLS
+// handshake. The server only accepts ones the machine certificate authority
+// issued, which have been verified by the time a request arrives
+func clientCertificate(c *gin.Context) (*x509.Certificate, bool) {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		return nil, false
+	}
+	return c.Request.TLS.VerifiedChains[0][0], true
+}
 
-					} else {
+// machineCertificateAuth authenticates a request as the system a client
+// certificate was issued to. Certificates are only accepted while they are
+// on record as issued, and while the system still has the serial number
+// their subject names
+func machineCertificateAuth(c *gin.Context, cert *x509.Certificate) bool {
+	certificate, err := model.Repos.MachineCertificates.GetMachineCertificateByFingerprint(machineca.Fingerprint(cert))
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		c.Abort()
+		return false
+	}
+	if certificate.Id == 0 || certificate.Status != model.MachineCertificateIssued {
+		log.Println("WARN: Client certificate for '" + cert.Subject.CommonName + "' is unknown or has been revoked")
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+
+	system, err := model.Repos.Systems.GetSystemById(certificate.SystemId)
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+		c.Abort()
+		return false
+	}
+	if system.SerialNumber == "" || system.SerialNumber != cert.Subject.CommonName {
+		log.Println("WARN: Client certificate for '" + cert.Subject.CommonName + "' does not match the serial number of system Id " + strconv.Itoa(certificate.SystemId))
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+		c.Abort()
+		return false
+	}
+
+	log.Println("INFO: Machine authenticated with client certificate: System Id: " + strconv.Itoa(system.Id))
+	c.Set(globals.MachineKey, system.Id)
+	return true
+}
+
+// currentSession returns the server-side session the session cookie of a
+// request carries the token of. The cookie is cleared when that session has
+// ended or been revoked
+func currentSession(c *gin.Context) (model.Session, bool) {
+	session := sessions.Default(c)
+	token, _ := session.Get(globals.SessionTokenKey).(string)
+	if token == "" {
+		return model.Session{}, false
+	}
+
+	userSession, err := model.Repos.Sessions.GetSessionByToken(token)
+	if err != nil {
+		log.Println("ERROR: " + string(err.Error()))
+		return model.Session{}, false
+	}
+	if userSession.Id == 0 || userSession.Expired(time.Now().UTC()) {
+		log.Println("INFO: Session has ended or was revoked")
+		session.Delete(globals.SessionTokenKey)
+		_ = session.Save()
+		return model.Session{}, false
+	}
+
+	return userSession, true
+}
 
-					}
-				} else {
+// touchSession keeps a session from going idle. Recording every request
+// would mean a write for each of them, so it is done at most once a minute
+func touchSession(userSession model.Session) {
+	lastSeen, err := model.ParseTimestamp(userSession.LastSeenDate)
+	if err == nil && time.Since(lastSeen) < time.Minute {
+		return
+	}
+	// failing to record the use is not a reason to turn the request away
+	_ = model.Repos.Sessions.TouchSession(userSession.Id)
+}
 
-				} */
+// alwaysAllowed is whether a request is for something users who still have
+// to change their password or enroll in two-factor authentication can do
+func alwaysAllowed(c *gin.Context) bool {
+	return c.FullPath() == "/api/v1/whoami" || c.FullPath() == "/api/v1/logout"
+}
+
+func isOwnPasswordChange(c *gin.Context, user model.User) bool {
+	return c.Request.Method == http.MethodPatch && c.FullPath() == "/api/v1/user/:name" && c.Param("name") == user.UserName
+}
+
+// passwordChangeAllowed lets users who must change their password through to
+// changing it, and nowhere else
+func passwordChangeAllowed(c *gin.Context, user model.User) bool {
+	if !helpers.PasswordChangeRequired(user) {
+		return true
+	}
+	if isOwnPasswordChange(c, user) || alwaysAllowed(c) {
+		return true
+	}
+
+	log.Println("WARN: User '" + user.UserName + "' must change their password")
+	c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Password change required"})
+	c.Abort()
+	return false
+}
+
+// twoFactorEnrollmentAllowed lets users whose role requires two-factor
+// authentication through to enrolling, and nowhere else until they have.
+// Changing their password stays open, it may be demanded of them first
+func twoFactorEnrollmentAllowed(c *gin.Context, user model.User) bool {
+	if !helpers.TwoFactorEnrollmentRequired(user) {
+		return true
+	}
+	if strings.HasPrefix(c.FullPath(), "/api/v1/twoFactor") || isOwnPasswordChange(c, user) || alwaysAllowed(c) {
+		return true
+	}
+
+	log.Println("WARN: User '" + user.UserName + "' must enroll in two-factor authentication")
+	c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Two-factor enrollment required"})
+	c.Abort()
+	return false
+}
+
+// basicAuthAllowed turns away users with two-factor authentication. Basic
+// authentication is checked on every request while each TOTP code only
+// counts once, so they log in for a session or use an API token instead
+func basicAuthAllowed(c *gin.Context, user model.User) bool {
+	if !helpers.SecondFactorRequired(user) {
+		return true
+	}
+	log.Println("WARN: User '" + user.UserName + "' uses two-factor authentication and cannot use basic authentication")
+	c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "Users with two-factor authentication log in at /login or use an API token"})
+	c.Abort()
+	return false
+}
+
+func AuthCheck(c *gin.Context) {
+	var clientFingerprintHeader string = c.GetHeader("X-ASSIMILATOR-TYPE")
+	// systems with a client certificate authenticate with it alone
+	if cert, presented := clientCertificate(c); presented {
+		if !machineCertificateAuth(c, cert) {
+			return
+		}
+		c.Next()
+	} else if clientFingerprintHeader == "MACHINE" {
+		// check if this is a machine logging in for DB access
+		if machineca.Required() {
+			log.Println("ERROR: Machine token presented where a client certificate is required. Aborting")
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "client certificate required!"})
+			c.Abort()
+			return
+		}
+		// now grab the token from the headers
+		authToken := c.GetHeader("X-Auth-Token")
+		if authToken == "" {
+			log.Println("ERROR: No machine token found. Aborting")
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+			c.Abort()
+			return
+		}
+		systemId, err := verifyMachineToken(authToken)
+		if err != nil {
+			log.Println("ERROR: " + string(err.Error()))
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+			c.Abort()
+			return
+		}
+		if systemId == 0 {
+			log.Println("ERROR: Machine authentication failed. Aborting")
+			c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+			c.Abort()
+			return
+		}
+		log.Println("INFO: Machine authenticated: System Id: " + strconv.Itoa(systemId))
+		c.Set(globals.MachineKey, systemId)
+		c.Next()
+	} else if token, isBearer := bearerToken(c); isBearer {
+		// automation authenticates with an API token, and clients of the
+		// OpenID provider with one of its access tokens, without a session
+		var authed bool
+		if strings.HasPrefix(token, model.ApiTokenPrefix) || !oidcauth.Enabled() {
+			authed = apiTokenAuth(c, token)
+		} else {
+			authed = oidcTokenAuth(c, token)
+		}
+		if !authed {
+			return
+		}
+		c.Next()
 	} else {
-		session := sessions.Default(c)
-		user := session.Get("user")
-		if user == nil {
+		userSession, found := currentSession(c)
+		if !found {
 			log.Println("INFO: No session found. Attempting to check for authentication headers")
-			baHeader := c.GetHeader("Authorization")
-			if baHeader == "" {
-				log.Println("ERROR: No authentication header found. Aborting")
+			// Basic authentication is checked on every request, sessions are
+			// only started by logging in. Passwords may contain colons, only
+			// the first one separates the username
+			username, password, found := c.Request.BasicAuth()
+			if !found {
+				log.Println("ERROR: No usable authentication header found. Aborting")
 				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
 				c.Abort()
 				return
 			}
-			// otherwise, lets process that header
-			username, password := processAuthorizationHeader(baHeader)
 			authStatus := helpers.CheckUserPass(username, password)
-			if authStatus {
-				session.Set(globals.UserKey, username)
-				if err := session.Save(); err != nil {
-					c.IndentedJSON(http.StatusInternalServerError,
-						gin.H{"error": "failed to save user session"})
-					// session saving is not fatal, so allow them to proceed
-				}
-				log.Println("INFO: Authenticated")
-			} else {
+			if !authStatus {
 				log.Println("ERROR: Authentication failed. Aborting")
 				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
 				c.Abort()
 				return
 			}
+			user, err := model.Repos.Users.GetUserByUserName(username)
+			if err != nil {
+				log.Println("ERROR: " + string(err.Error()))
+				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
+				c.Abort()
+				return
+			}
+			if !basicAuthAllowed(c, user) {
+				return
+			}
+			log.Println("INFO: Authenticated")
+			c.Set(globals.UserKey, user.UserName)
+			c.Set(globals.AuthMethodKey, globals.AuthMethodBasic)
+			if !passwordChangeAllowed(c, user) || !twoFactorEnrollmentAllowed(c, user) {
+				return
+			}
 		} else {
-			userString := fmt.Sprintf("%v", user)
-			log.Println("INFO: Session found: User: " + userString)
+			log.Println("INFO: Session found: Session Id: " + strconv.Itoa(userSession.Id))
 			log.Println("INFO: Checking if user is locked or not...")
-			user, err := model.GetUserByUserName(userString)
+			user, err := model.Repos.Users.GetUserById(userSession.UserId)
 			if err != nil {
 				log.Println("ERROR: " + string(err.Error()))
 				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "unable to authenticate: " + err.Error()})
@@ -107,11 +376,24 @@ func AuthCheck(c *gin.Context) {
 			if status {
 				log.Println("INFO: Authenticated")
 			} else {
-				log.Println("WARN: User '" + userString + "' is locked!")
+				log.Println("WARN: User '" + user.UserName + "' is locked!")
+				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
+				c.Abort()
+				return
+			}
+			if !helpers.CheckIsNotLockedOut(user) {
+				log.Println("WARN: User '" + user.UserName + "' is locked out until " + user.LockedUntil + " UTC")
 				c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": "not authorized!"})
 				c.Abort()
 				return
 			}
+			touchSession(userSession)
+			c.Set(globals.UserKey, user.UserName)
+			c.Set(globals.SessionIdKey, userSession.Id)
+			c.Set(globals.AuthMethodKey, globals.AuthMethodSession)
+			if !passwordChangeAllowed(c, user) || !twoFactorEnrollmentAllowed(c, user) {
+				return
+			}
 		}
 		c.Next()
 	}
@@ -0,0 +1,302 @@
+package middleware
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/migrations"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/oidcauth"
+	"github.com/greeneg/allocatord/oidcauth/oidctest"
+	"github.com/greeneg/allocatord/twofactor"
+	"github.com/pquerna/otp/totp"
+)
+
+const audience = "allocatord-api"
+
+// bearerFixture is a migrated database, an OpenID provider whose admins
+// group maps to a role of its own, and a router answering with who AuthCheck
+// let through
+type bearerFixture struct {
+	provider    *oidctest.Provider
+	router      *gin.Engine
+	adminRoleId int
+}
+
+func newBearerFixture(t *testing.T) bearerFixture {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+
+	f := bearerFixture{provider: oidctest.NewProvider()}
+	t.Cleanup(f.provider.Close)
+	_, err = model.Repos.Roles.CreateRole(model.Role{RoleName: "Administrators", Description: "Administrators"})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	roles, err := model.Repos.Roles.GetRoles()
+	if err != nil {
+		t.Fatalf("GetRoles: %v", err)
+	}
+	for _, r := range roles {
+		if r.RoleName == "Administrators" {
+			f.adminRoleId = r.Id
+		}
+	}
+
+	err = oidcauth.Configure(oidcauth.Config{
+		Issuer:           f.provider.URL,
+		ClientId:         "allocatord",
+		RedirectUrl:      "https://allocatord.example.com/api/v1/oidc/callback",
+		Audience:         audience,
+		RoleGroups:       []oidcauth.GroupMapping{{Group: "allocatord-admins", Id: f.adminRoleId}},
+		DefaultOrgUnitId: 1,
+	})
+	if err != nil {
+		t.Fatalf("oidcauth.Configure: %v", err)
+	}
+	t.Cleanup(func() { oidcauth.Configure(oidcauth.Config{}) })
+
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	f.router.Use(sessions.Sessions("session", cookie.NewStore([]byte("test-session-key-0123456789abcdef"))))
+	f.router.GET("/whoami", AuthCheck, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(globals.UserKey)+" "+c.GetString(globals.AuthMethodKey))
+	})
+	return f
+}
+
+func (f bearerFixture) get(token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response := httptest.NewRecorder()
+	f.router.ServeHTTP(response, request)
+	return response
+}
+
+func TestOidcBearerToken(t *testing.T) {
+	f := newBearerFixture(t)
+
+	token := f.provider.Token(audience, map[string]any{
+		"sub":                "sub-alice",
+		"preferred_username": "alice",
+		"name":               "Alice Liddell",
+		"groups":             []string{"allocatord-admins"},
+	})
+	response := f.get(token)
+	if response.Code != http.StatusOK {
+		t.Fatalf("AuthCheck with a valid access token = %d %s, want 200", response.Code, response.Body)
+	}
+	if want := "alice " + globals.AuthMethodOidcToken; response.Body.String() != want {
+		t.Errorf("authenticated as %q, want %q", response.Body, want)
+	}
+
+	// the account is created on first use, with the mapped role
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+	if user.TypeId != model.UserTypeExternal || user.RoleId != f.adminRoleId || user.OrgUnitId != 1 || user.FullName != "Alice Liddell" {
+		t.Errorf("provisioned user = %+v, want an EXTERNAL Alice Liddell with role %d in OU 1", user, f.adminRoleId)
+	}
+}
+
+func TestOidcBearerTokenRejected(t *testing.T) {
+	f := newBearerFixture(t)
+
+	claims := map[string]any{"sub": "sub-bob", "preferred_username": "bob", "groups": []string{"allocatord-admins"}}
+	expired := map[string]any{"sub": "sub-bob", "preferred_username": "bob", "groups": []string{"allocatord-admins"}, "exp": 1}
+	unmapped := map[string]any{"sub": "sub-bob", "preferred_username": "bob", "groups": []string{"other"}}
+
+	for name, token := range map[string]string{
+		"ID token audience": f.provider.Token("allocatord", claims),
+		"expired":           f.provider.Token(audience, expired),
+		"no mapped group":   f.provider.Token(audience, unmapped),
+		"garbage":           "not-a-token",
+		"unknown API token": model.ApiTokenPrefix + "0123456789",
+	} {
+		t.Run(name, func(t *testing.T) {
+			response := f.get(token)
+			if response.Code != http.StatusUnauthorized {
+				t.Errorf("AuthCheck = %d %s, want 401", response.Code, response.Body)
+			}
+		})
+	}
+
+	_, err := model.Repos.Users.GetUserByUserName("bob")
+	if err == nil {
+		t.Errorf("a user was provisioned from a rejected token")
+	}
+}
+
+func TestOidcBearerTokenLockedUser(t *testing.T) {
+	f := newBearerFixture(t)
+
+	token := f.provider.Token(audience, map[string]any{"sub": "sub-carol", "preferred_username": "carol", "groups": []string{"allocatord-admins"}})
+	if response := f.get(token); response.Code != http.StatusOK {
+		t.Fatalf("AuthCheck = %d %s, want 200", response.Code, response.Body)
+	}
+
+	_, err := model.DB.Exec("UPDATE Users SET Status = 'locked' WHERE UserName = ?", "carol")
+	if err != nil {
+		t.Fatalf("cannot lock carol: %v", err)
+	}
+	if response := f.get(token); response.Code != http.StatusUnauthorized {
+		t.Errorf("AuthCheck for a locked user = %d %s, want 401", response.Code, response.Body)
+	}
+}
+
+// newBasicFixture is a migrated database holding the LOCAL user alice and a
+// router answering with who AuthCheck let through
+func newBasicFixture(t *testing.T) (*gin.Engine, model.User) {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	_, err = model.Repos.Users.CreateUser(model.ProposedUser{UserName: "alice", FullName: "Alice", Status: "enabled", OrgUnitId: 1, RoleId: 1, TypeId: model.UserTypeLocal, Password: "Alice-password-1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("test-session-key-0123456789abcdef"))))
+	router.GET("/whoami", AuthCheck, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(globals.UserKey)+" "+c.GetString(globals.AuthMethodKey))
+	})
+	return router, user
+}
+
+func basicGet(router *gin.Engine, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func basicAuth(username string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestBasicAuthTwoFactor(t *testing.T) {
+	router, alice := newBasicFixture(t)
+
+	response := basicGet(router, map[string]string{"Authorization": basicAuth("alice", "Alice-password-1")})
+	if want := "alice " + globals.AuthMethodBasic; response.Code != http.StatusOK || response.Body.String() != want {
+		t.Fatalf("basic authentication without two-factor = %d %s, want 200 %s", response.Code, response.Body, want)
+	}
+
+	enrollment, err := twofactor.Generate("alice")
+	if err != nil {
+		t.Fatalf("twofactor.Generate: %v", err)
+	}
+	_, err = model.Repos.TwoFactor.StartTwoFactorEnrollment(alice.Id, enrollment.Secret)
+	if err != nil {
+		t.Fatalf("StartTwoFactorEnrollment: %v", err)
+	}
+	_, err = model.Repos.TwoFactor.EnableTwoFactor(alice.Id, 0)
+	if err != nil {
+		t.Fatalf("EnableTwoFactor: %v", err)
+	}
+
+	// each code only counts once, so basic authentication, which is checked
+	// on every request, is refused outright rather than working once per
+	// time step
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("totp.GenerateCode: %v", err)
+	}
+	for _, header := range []map[string]string{
+		{"Authorization": basicAuth("alice", "Alice-password-1")},
+		{"Authorization": basicAuth("alice", "Alice-password-1"), "X-OTP": code},
+	} {
+		response = basicGet(router, header)
+		if response.Code != http.StatusUnauthorized || !strings.Contains(response.Body.String(), "/login") {
+			t.Errorf("basic authentication with two-factor = %d %s, want 401 pointing to /login", response.Code, response.Body)
+		}
+	}
+}
+
+func TestBasicAuthMalformed(t *testing.T) {
+	router, _ := newBasicFixture(t)
+
+	for _, header := range []string{
+		"Basic",
+		"Basic ",
+		"Basic !!!not-base64!!!",
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("alice")),
+		"Digest username=\"alice\"",
+		"alice:Alice-password-1",
+	} {
+		response := basicGet(router, map[string]string{"Authorization": header})
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q = %d %s, want 401", header, response.Code, response.Body)
+		}
+	}
+}
+
+func TestBasicAuthColonInPassword(t *testing.T) {
+	router, _ := newBasicFixture(t)
+	_, err := model.Repos.Users.CreateUser(model.ProposedUser{UserName: "bob", FullName: "Bob", Status: "enabled", OrgUnitId: 1, RoleId: 1, TypeId: model.UserTypeLocal, Password: "Bob:pass:word-1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	response := basicGet(router, map[string]string{"Authorization": basicAuth("bob", "Bob:pass:word-1")})
+	if want := "bob " + globals.AuthMethodBasic; response.Code != http.StatusOK || response.Body.String() != want {
+		t.Errorf("basic authentication with colons in the password = %d %s, want 200 %s", response.Code, response.Body, want)
+	}
+	response = basicGet(router, map[string]string{"Authorization": basicAuth("bob", "Bob")})
+	if response.Code != http.StatusUnauthorized {
+		t.Errorf("basic authentication with the password cut at its first colon = %d, want 401", response.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/model"
+)
+
+func permissionDenied(c *gin.Context, permission string) {
+	c.IndentedJSON(http.StatusForbidden, gin.H{
+		"error":      "Insufficient access. Missing permission '" + permission + "'",
+		"permission": permission,
+	})
+	c.Abort()
+}
+
+// RequirePermission only lets the request through when the role of the
+// session user holds the permission, and the API token it presented, if any,
+// is scoped to it. It runs after AuthCheck, so machines, which have no role,
+// are always turned away
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, authed := helpers.AuthenticatedUser(c)
+		if !authed {
+			log.Println("WARN: Request without a user session needs permission '" + permission + "'")
+			permissionDenied(c, permission)
+			return
+		}
+		if apiToken, exists := c.Get(globals.ApiTokenKey); exists && !apiToken.(model.ApiToken).Allows(permission) {
+			log.Println("WARN: API token of user '" + username + "' is not scoped to permission '" + permission + "'")
+			permissionDenied(c, permission)
+			return
+		}
+
+		granted, err := model.Repos.RolePermissions.UserHasPermission(username, permission)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Unable to check permissions! " + string(err.Error())})
+			c.Abort()
+			return
+		}
+		if !granted {
+			log.Println("WARN: User '" + username + "' lacks permission '" + permission + "'")
+			permissionDenied(c, permission)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TokenScopes holds scoped API tokens to their scopes on the routes that no
+// permission guards. Requests that only read need the read scope. Everything
+// else either passes RequirePermission or, for the few routes of the user's
+// own account, RejectScopedTokens
+func TokenScopes(c *gin.Context) {
+	apiToken, exists := c.Get(globals.ApiTokenKey)
+	if !exists || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+		c.Next()
+		return
+	}
+	if !apiToken.(model.ApiToken).AllowsReading() {
+		log.Println("WARN: API token '" + apiToken.(model.ApiToken).TokenName + "' is not scoped to reading")
+		permissionDenied(c, model.ApiTokenScopeRead)
+		return
+	}
+
+	c.Next()
+}
+
+// RejectScopedTokens turns away scoped API tokens from routes that manage the
+// user's own account or act as a machine, which no scope covers
+func RejectScopedTokens(c *gin.Context) {
+	if apiToken, exists := c.Get(globals.ApiTokenKey); exists && apiToken.(model.ApiToken).Scoped() {
+		log.Println("WARN: Scoped API token '" + apiToken.(model.ApiToken).TokenName + "' used on a route no scope covers")
+		c.IndentedJSON(http.StatusForbidden, gin.H{"error": "Scoped API tokens cannot be used here. Access denied!"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
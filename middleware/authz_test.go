@@ -0,0 +1,101 @@
+package middleware
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+)
+
+// scopedRouter answers the routes of a private group the way the daemon sets
+// them up: a read, a write guarded by a permission and an account route
+func scopedRouter(router *gin.Engine) {
+	g := router.Group("/api", AuthCheck, TokenScopes)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	g.GET("/systems", ok)
+	g.POST("/system", RequirePermission(model.PermissionSystemsWrite), ok)
+	g.PATCH("/user/:name", RejectScopedTokens, ok)
+}
+
+func TestTokenScopes(t *testing.T) {
+	router, alice := newBasicFixture(t)
+	scopedRouter(router)
+
+	tokens := map[string][]string{
+		"unscoped": nil,
+		"read":     {model.ApiTokenScopeRead},
+		"write":    {model.PermissionSystemsWrite},
+		"all":      {model.PermissionAll},
+	}
+	secrets := make(map[string]string)
+	for name, scopes := range tokens {
+		_, secret, err := model.Repos.ApiTokens.CreateApiToken(alice.Id, model.ProposedApiToken{TokenName: name, Scopes: scopes})
+		if err != nil {
+			t.Fatalf("CreateApiToken(%s): %v", name, err)
+		}
+		secrets[name] = secret
+	}
+
+	tests := []struct {
+		token  string
+		method string
+		target string
+		want   int
+	}{
+		{"unscoped", http.MethodGet, "/api/systems", http.StatusOK},
+		{"unscoped", http.MethodPost, "/api/system", http.StatusOK},
+		{"unscoped", http.MethodPatch, "/api/user/alice", http.StatusOK},
+		{"read", http.MethodGet, "/api/systems", http.StatusOK},
+		{"read", http.MethodPost, "/api/system", http.StatusForbidden},
+		{"read", http.MethodPatch, "/api/user/alice", http.StatusForbidden},
+		{"write", http.MethodGet, "/api/systems", http.StatusForbidden},
+		{"write", http.MethodPost, "/api/system", http.StatusOK},
+		{"write", http.MethodPatch, "/api/user/alice", http.StatusForbidden},
+		{"all", http.MethodGet, "/api/systems", http.StatusOK},
+		{"all", http.MethodPost, "/api/system", http.StatusOK},
+		{"all", http.MethodPatch, "/api/user/alice", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.target, nil)
+		request.Header.Set("Authorization", "Bearer "+secrets[test.token])
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		if response.Code != test.want {
+			t.Errorf("%s %s with the %s token = %d %s, want %d", test.method, test.target, test.token, response.Code, response.Body, test.want)
+		}
+	}
+}
+
+func TestReadScopeIsNotAPermission(t *testing.T) {
+	_, alice := newBasicFixture(t)
+
+	_, _, err := model.Repos.ApiTokens.CreateApiToken(alice.Id, model.ProposedApiToken{TokenName: "bogus", Scopes: []string{"systems:read"}})
+	var invalid *model.InvalidApiToken
+	if !errors.As(err, &invalid) {
+		t.Errorf("creating a token with an unknown scope = %v, want an InvalidApiToken error", err)
+	}
+	if model.IsValidPermission(model.ApiTokenScopeRead) {
+		t.Errorf("%q is a permission roles can be granted, want it to be a token scope only", model.ApiTokenScopeRead)
+	}
+}
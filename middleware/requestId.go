@@ -0,0 +1,42 @@
+package middleware
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/greeneg/allocatord/globals"
+)
+
+// Ids passed in by clients longer than this are replaced rather than stored
+const maxRequestIdLength = 128
+
+// RequestId tags every request with an Id, taken from the X-Request-ID header
+// when the client or a proxy in front of the daemon sent one. It is echoed
+// back in the response and recorded with any change the request makes
+func RequestId(c *gin.Context) {
+	requestId := c.GetHeader(globals.RequestIdHeader)
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		requestId = uuid.NewString()
+	}
+
+	c.Set(globals.RequestIdKey, requestId)
+	c.Header(globals.RequestIdHeader, requestId)
+	c.Next()
+}
@@ -0,0 +1,90 @@
+package middleware
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Clients not heard from for this long are forgotten, keeping the table of
+// addresses from growing without bound
+const throttleForgetAfter = 10 * time.Minute
+
+type throttleBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Throttle limits each client address to perMinute requests a minute, with
+// bursts of up to that many. Requests over the limit are turned away with
+// 429 Too Many Requests. It is meant for routes that take requests from
+// anyone, so the counts are kept in memory for each daemon
+func Throttle(perMinute int) gin.HandlerFunc {
+	var mutex sync.Mutex
+	buckets := make(map[string]*throttleBucket)
+	rate := float64(perMinute) / time.Minute.Seconds()
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		client := c.ClientIP()
+
+		mutex.Lock()
+		if now.Sub(lastSweep) > throttleForgetAfter {
+			for address, b := range buckets {
+				if now.Sub(b.lastSeen) > throttleForgetAfter {
+					delete(buckets, address)
+				}
+			}
+			lastSweep = now
+		}
+		b, exists := buckets[client]
+		if !exists {
+			b = &throttleBucket{tokens: float64(perMinute), lastSeen: now}
+			buckets[client] = b
+		}
+		b.tokens += now.Sub(b.lastSeen).Seconds() * rate
+		if b.tokens > float64(perMinute) {
+			b.tokens = float64(perMinute)
+		}
+		b.lastSeen = now
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		wait := (1 - b.tokens) / rate
+		mutex.Unlock()
+
+		if !allowed {
+			log.Println("WARN: Throttling client " + client + " on " + c.FullPath())
+			c.Header("Retry-After", strconv.Itoa(int(wait)+1))
+			c.IndentedJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
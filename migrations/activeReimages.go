@@ -0,0 +1,44 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// at most one reimage may be open per system. Checking for an open one before
+// opening another is not enough when two requests race, so let the database
+// refuse the second. Systems that already have several open reimages keep the
+// newest, and the older ones are failed so the index can be built
+const activeReimagesUp = `UPDATE ReimageRequests
+	SET State = 'failed', UpdatedDate = CURRENT_TIMESTAMP, CompletedDate = CURRENT_TIMESTAMP
+	WHERE State NOT IN ('complete', 'failed')
+	  AND Id < (SELECT MAX(r.Id) FROM ReimageRequests r
+	            WHERE r.SystemId = ReimageRequests.SystemId AND r.State NOT IN ('complete', 'failed'));
+CREATE UNIQUE INDEX ReimageRequestsActive ON ReimageRequests (SystemId)
+	WHERE State NOT IN ('complete', 'failed');
+`
+
+const activeReimagesDown = `DROP INDEX IF EXISTS ReimageRequestsActive;
+`
+
+var activeReimages = Migration{
+	Version:      21,
+	Name:         "active_reimages",
+	Up:           execSQL(activeReimagesUp),
+	Down:         execSQL(activeReimagesDown),
+	PostgresUp:   execSQL(activeReimagesUp),
+	PostgresDown: execSQL(activeReimagesDown),
+}
@@ -0,0 +1,47 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// versioned unattended install templates per OS family
+const answerFileTemplatesUp = `CREATE TABLE IF NOT EXISTS AnswerFileTemplates (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	OSFamilyId   INTEGER  REFERENCES OperatingSystemFamilies (Id) ON DELETE CASCADE
+						  NOT NULL,
+	TemplateType STRING   NOT NULL,
+	Version      INTEGER  NOT NULL,
+	Content      STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP),
+	UNIQUE (OSFamilyId, TemplateType, Version)
+);
+`
+
+const answerFileTemplatesDown = `DROP TABLE IF EXISTS AnswerFileTemplates;
+`
+
+var answerFileTemplates = Migration{
+	Version: 7,
+	Name:    "answer_file_templates",
+	Up:      execSQL(answerFileTemplatesUp),
+	Down:    execSQL(answerFileTemplatesDown),
+}
@@ -0,0 +1,66 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// tokens users create for automation to authenticate as them. Scopes holds a
+// comma separated list of permissions, an empty one leaves the token with
+// everything the role of its owner holds
+const apiTokensUp = `CREATE TABLE IF NOT EXISTS ApiTokens (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	UserId       INTEGER  REFERENCES Users (Id) ON DELETE CASCADE
+						  NOT NULL,
+	TokenName    STRING   NOT NULL,
+	TokenHash    STRING   NOT NULL
+						  UNIQUE,
+	Scopes       STRING   NOT NULL
+						  DEFAULT '',
+	ExpiryDate   DATETIME,
+	LastUsedDate DATETIME,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP),
+	UNIQUE (UserId, TokenName)
+);
+`
+
+const apiTokensDown = `DROP TABLE IF EXISTS ApiTokens;
+`
+
+const postgresApiTokensUp = `CREATE TABLE ApiTokens (
+	Id           SERIAL       PRIMARY KEY,
+	UserId       INTEGER      NOT NULL REFERENCES Users (Id) ON DELETE CASCADE,
+	TokenName    TEXT         NOT NULL,
+	TokenHash    TEXT         NOT NULL UNIQUE,
+	Scopes       TEXT         NOT NULL DEFAULT '',
+	ExpiryDate   TIMESTAMP(0),
+	LastUsedDate TIMESTAMP(0),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (UserId, TokenName)
+);
+`
+
+var apiTokens = Migration{
+	Version:      14,
+	Name:         "api_tokens",
+	Up:           execSQL(apiTokensUp),
+	Down:         execSQL(apiTokensDown),
+	PostgresUp:   execSQL(postgresApiTokensUp),
+	PostgresDown: execSQL(apiTokensDown),
+}
@@ -0,0 +1,90 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// the audit trail keeps the name of whoever made a change alongside their Id,
+// so entries outlive the accounts they point at, and the row as it was before
+// and after the change
+const auditWithRows = `CREATE TABLE %s (
+	Id            INTEGER  PRIMARY KEY AUTOINCREMENT
+						   NOT NULL
+						   UNIQUE,
+	ChangedById   INTEGER  NOT NULL,
+	TableChanged  STRING   NOT NULL,
+	ChangeClass   STRING   NOT NULL,
+	ChangeDate    DATETIME NOT NULL
+						   DEFAULT (CURRENT_TIMESTAMP),
+	ChangedByName STRING   NOT NULL
+						   DEFAULT '',
+	EntityId      INTEGER  NOT NULL
+						   DEFAULT 0,
+	RequestId     STRING   NOT NULL
+						   DEFAULT '',
+	RowBefore     STRING,
+	RowAfter      STRING
+)`
+
+const auditWithoutRows = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ChangedById  INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	TableChanged STRING   NOT NULL,
+	ChangeClass  STRING   NOT NULL,
+	ChangeDate   DATETIME NOT NULL
+)`
+
+const auditTrailIndexes = `CREATE INDEX IF NOT EXISTS AuditTableEntity ON Audit (TableChanged, EntityId);
+CREATE INDEX IF NOT EXISTS AuditChangeDate ON Audit (ChangeDate);
+`
+
+const postgresAuditTrailUp = `ALTER TABLE Audit DROP CONSTRAINT audit_changedbyid_fkey;
+ALTER TABLE Audit ADD COLUMN ChangedByName TEXT NOT NULL DEFAULT '';
+ALTER TABLE Audit ADD COLUMN EntityId INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Audit ADD COLUMN RequestId TEXT NOT NULL DEFAULT '';
+ALTER TABLE Audit ADD COLUMN RowBefore TEXT;
+ALTER TABLE Audit ADD COLUMN RowAfter TEXT;
+CREATE INDEX AuditTableEntity ON Audit (TableChanged, EntityId);
+CREATE INDEX AuditChangeDate ON Audit (ChangeDate);
+`
+
+// entries made by accounts that have since been removed would break the
+// restored constraint, so it is added without checking existing rows
+const postgresAuditTrailDown = `DROP INDEX IF EXISTS AuditChangeDate;
+DROP INDEX IF EXISTS AuditTableEntity;
+ALTER TABLE Audit DROP COLUMN RowAfter;
+ALTER TABLE Audit DROP COLUMN RowBefore;
+ALTER TABLE Audit DROP COLUMN RequestId;
+ALTER TABLE Audit DROP COLUMN EntityId;
+ALTER TABLE Audit DROP COLUMN ChangedByName;
+ALTER TABLE Audit ADD CONSTRAINT audit_changedbyid_fkey FOREIGN KEY (ChangedById) REFERENCES Users (Id) NOT VALID;
+`
+
+var auditTrail = Migration{
+	Version: 12,
+	Name:    "audit_trail",
+	Up: steps(
+		upgradeTable("Audit", auditWithRows),
+		execSQL(auditTrailIndexes),
+	),
+	Down:         downgradeTable("Audit", auditWithoutRows),
+	PostgresUp:   execSQL(postgresAuditTrailUp),
+	PostgresDown: execSQL(postgresAuditTrailDown),
+}
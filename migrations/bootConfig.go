@@ -0,0 +1,49 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// kernel, initrd and bootloader settings per architecture for network boot
+const architecturesWithBootConfig = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ISEName      STRING   UNIQUE
+						  NOT NULL,
+	RegisterSize INTEGER  NOT NULL
+						  DEFAULT (64),
+	KernelPath   STRING   NOT NULL
+						  DEFAULT '',
+	InitrdPath   STRING   NOT NULL
+						  DEFAULT '',
+	KernelArgs   STRING   NOT NULL
+						  DEFAULT '',
+	BootFilename STRING   NOT NULL
+						  DEFAULT '',
+	CreatorId    INTEGER  NOT NULL
+						  REFERENCES Users (Id),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+var bootConfig = Migration{
+	Version: 6,
+	Name:    "boot_config",
+	Up:      upgradeTable("Architectures", architecturesWithBootConfig),
+	Down:    downgradeTable("Architectures", architecturesWithRegisterSize),
+}
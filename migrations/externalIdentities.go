@@ -0,0 +1,75 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// external users record the identity provider that created their account and
+// the subject it knows them by, so no other provider can log in to it.
+// Accounts from before this version have neither and can only be claimed by
+// the directory, which has always known users by their name
+const usersWithExternalIdentity = `CREATE TABLE %s (
+	Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
+									 UNIQUE
+									 NOT NULL,
+	UserName                STRING   NOT NULL
+									 UNIQUE,
+	FullName                STRING   NOT NULL,
+	Status                  STRING   NOT NULL
+									 DEFAULT enabled,
+	OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
+									 NOT NULL,
+	RoleId                  INTEGER  REFERENCES Roles (Id)
+									 NOT NULL,
+	TypeId                  INTEGER  REFERENCES UserTypes (Id)
+									 NOT NULL
+									 DEFAULT (2),
+	PasswordHash            STRING   NOT NULL,
+	CreationDate            DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	LastPasswordChangedDate DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	FailedLoginCount        INTEGER  NOT NULL
+									 DEFAULT (0),
+	LockedUntil             DATETIME,
+	MustChangePassword      BOOL     NOT NULL
+									 DEFAULT (FALSE),
+	ExternalProvider        STRING,
+	ExternalSubject         STRING
+)`
+
+const externalIdentitiesIndex = `CREATE UNIQUE INDEX IF NOT EXISTS UsersExternalIdentity ON Users (ExternalProvider, ExternalSubject);
+`
+
+const postgresExternalIdentitiesUp = `ALTER TABLE Users ADD COLUMN ExternalProvider TEXT;
+ALTER TABLE Users ADD COLUMN ExternalSubject TEXT;
+CREATE UNIQUE INDEX UsersExternalIdentity ON Users (ExternalProvider, ExternalSubject);
+`
+
+const postgresExternalIdentitiesDown = `DROP INDEX IF EXISTS UsersExternalIdentity;
+ALTER TABLE Users DROP COLUMN ExternalSubject;
+ALTER TABLE Users DROP COLUMN ExternalProvider;
+`
+
+var externalIdentities = Migration{
+	Version:      19,
+	Name:         "external_identities",
+	Up:           steps(upgradeTable("Users", usersWithExternalIdentity), execSQL(externalIdentitiesIndex)),
+	Down:         downgradeTable("Users", usersWithLockout),
+	PostgresUp:   execSQL(postgresExternalIdentitiesUp),
+	PostgresDown: execSQL(postgresExternalIdentitiesDown),
+}
@@ -0,0 +1,60 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// inherited host var layers and per machine role host vars schemas
+const hostVarsUp = `CREATE TABLE IF NOT EXISTS HostVarLayers (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	Scope        STRING   NOT NULL,
+	ScopeId      INTEGER  NOT NULL
+						  DEFAULT (0),
+	HostVars     STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP),
+	UNIQUE (Scope, ScopeId)
+);
+CREATE TABLE IF NOT EXISTS MachineRoleHostVarsSchemas (
+	Id             INTEGER  PRIMARY KEY AUTOINCREMENT
+							NOT NULL
+							UNIQUE,
+	MachineRoleId  INTEGER  REFERENCES MachineRoles (Id) ON DELETE CASCADE
+							UNIQUE
+							NOT NULL,
+	HostVarsSchema STRING   NOT NULL,
+	CreatorId      INTEGER  REFERENCES Users (Id)
+							NOT NULL,
+	CreationDate   DATETIME NOT NULL
+							DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const hostVarsDown = `DROP TABLE IF EXISTS MachineRoleHostVarsSchemas;
+DROP TABLE IF EXISTS HostVarLayers;
+`
+
+var hostVars = Migration{
+	Version: 8,
+	Name:    "host_vars",
+	Up:      execSQL(hostVarsUp),
+	Down:    execSQL(hostVarsDown),
+}
@@ -0,0 +1,284 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// the schema as createDB laid it down before versioned migrations existed
+const initialUp = `CREATE TABLE IF NOT EXISTS Architectures (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ISEName      STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  NOT NULL
+						  REFERENCES Users (Id),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS Audit (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ChangedById  INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	TableChanged STRING   NOT NULL,
+	ChangeClass  STRING   NOT NULL,
+	ChangeDate   DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS Buildings (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	BuildingName STRING   NOT NULL
+						  UNIQUE,
+	ShortName    STRING   NOT NULL
+						  UNIQUE,
+	City         STRING   NOT NULL,
+	Region       STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS MachineRoles (
+	Id              INTEGER  PRIMARY KEY AUTOINCREMENT
+							 UNIQUE
+							 NOT NULL,
+	MachineRoleName STRING   UNIQUE
+							 NOT NULL,
+	Description     STRING   NOT NULL,
+	CreatorId       INTEGER  REFERENCES Users (Id)
+							 NOT NULL,
+	CreationDate    DATETIME NOT NULL
+							 DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS NetworkInterfaces (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	DeviceModel  STRING   NOT NULL,
+	DeviceId     STRING   NOT NULL,
+	MACAddress   STRING   NOT NULL
+						  UNIQUE,
+	SystemId     INTEGER  REFERENCES Systems (Id)
+						  NOT NULL,
+	IpAddress    STRING   NOT NULL,
+	Bitmask      INTEGER  NOT NULL,
+	Gateway      STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS OperatingSystemFamilies (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	OSFamilyName STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS OperatingSystems (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	OSName       STRING   UNIQUE
+						  NOT NULL,
+	OSFamilyId   INTEGER  REFERENCES OperatingSystemFamilies (Id)
+						  NOT NULL,
+	OSImageUrl   STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS OrganizationalUnits (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	OUName       STRING   UNIQUE
+						  NOT NULL,
+	Description  STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+
+INSERT INTO OrganizationalUnits (Id, OUName, Description, CreatorId, CreationDate)
+	VALUES ( 1, 'Unassigned', 'The OU used as a place holder when a system changes hands', 1, '2024-06-01 15:38:42' );
+
+CREATE TABLE IF NOT EXISTS Roles (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+	RoleName     STRING   UNIQUE
+						  NOT NULL,
+	Description  STRING   NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+
+INSERT INTO Roles (Id, RoleName, Description, CreationDate)
+	VALUES ( 1, 'SYSTEM', 'Built-in system role', '2024-06-01 14:57:41' );
+
+CREATE TABLE IF NOT EXISTS StorageVolumes (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	VolumeName   STRING   NOT NULL,
+	StorageType  STRING   NOT NULL,
+	DeviceModel  STRING   NOT NULL,
+	DeviceId     STRING   NOT NULL,
+	MountPoint   STRING   NOT NULL,
+	VolumeSize   INTEGER  NOT NULL,
+	VolumeFormat STRING   NOT NULL,
+	VolumeLabel  STRING   NOT NULL,
+	SystemId     INTEGER  REFERENCES Systems (Id)
+						  NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS SystemModels (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	ModelName    STRING   NOT NULL
+						  UNIQUE,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS Systems (
+	Id                INTEGER  PRIMARY KEY AUTOINCREMENT
+							   UNIQUE
+							   NOT NULL,
+	SerialNumber      STRING   NOT NULL
+							   UNIQUE,
+	ModelId           INTEGER  REFERENCES SystemModels (Id)
+							   NOT NULL,
+	OperatingSystemId INTEGER  NOT NULL
+							   REFERENCES OperatingSystems (Id),
+	Reimage           BOOL     NOT NULL
+							   DEFAULT (FALSE),
+	HostVars          STRING   NOT NULL,
+	BilledToOrgUnitId INTEGER  REFERENCES OrganizationalUnits (Id)
+							   NOT NULL,
+	MachineRoleId     INTEGER  NOT NULL
+							   REFERENCES MachineRoles (Id),
+	BuildingId        INTEGER  REFERENCES Buildings (Id)
+							   NOT NULL,
+	VendorId          INTEGER  NOT NULL
+							   REFERENCES Vendors (Id),
+	ArchitectureId    INTEGER  REFERENCES Architectures (Id)
+							   NOT NULL,
+	RAM               INTEGER  NOT NULL,
+	CPUCores          INTEGER  NOT NULL,
+	CreatorId         INTEGER  REFERENCES Users (Id)
+							   NOT NULL,
+	CreationDate      DATETIME NOT NULL
+							   DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS Users (
+	Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
+									 UNIQUE
+									 NOT NULL,
+	UserName                STRING   NOT NULL
+									 UNIQUE,
+	FullName                STRING   NOT NULL,
+	Status                  STRING   NOT NULL
+									 DEFAULT enabled,
+	OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
+									 NOT NULL,
+	RoleId                  INTEGER  REFERENCES Roles (Id)
+									 NOT NULL,
+	PasswordHash            STRING   NOT NULL,
+	CreationDate            DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	LastPasswordChangedDate DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP)
+);
+
+INSERT INTO Users (Id, UserName, FullName, Status, OrgUnitId, RoleId, PasswordHash, CreationDate, LastPasswordChangedDate)
+	VALUES ( 1, 'SYSTEM', 'Allocator System', 'enabled', 1, 1, '!', '2024-06-01 14:58:36', '2024-06-01 14:58:36' );
+
+CREATE TABLE IF NOT EXISTS UserTypes (
+	Id              		INTEGER PRIMARY KEY AUTOINCREMENT
+						  			UNIQUE
+						  			NOT NULL,
+	TypeName        		STRING	NOT NULL
+						  			UNIQUE,
+	Description 			STRING	NOT NULL,
+	AllowRoleChange 		BOOL	NOT NULL
+						  			DEFAULT (FALSE),
+	AllowDeletion   		BOOL	NOT NULL
+						  			DEFAULT (FALSE),
+	AllowDisable			BOOL	NOT NULL
+									DEFAULT (FALSE)
+);
+
+INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
+	VALUES ( 1, 'BUILTIN', 'Built-in system user type', FALSE, FALSE, FALSE );
+
+INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
+	VALUES ( 2, 'LOCAL', 'Local user type', TRUE, TRUE, TRUE );
+
+INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
+	VALUES ( 3, 'EXTERNAL', 'External user type', TRUE, TRUE, TRUE );
+
+CREATE TABLE IF NOT EXISTS Vendors (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	VendorName   STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const initialDown = `DROP TABLE IF EXISTS Vendors;
+DROP TABLE IF EXISTS UserTypes;
+DROP TABLE IF EXISTS Users;
+DROP TABLE IF EXISTS Systems;
+DROP TABLE IF EXISTS SystemModels;
+DROP TABLE IF EXISTS StorageVolumes;
+DROP TABLE IF EXISTS Roles;
+DROP TABLE IF EXISTS OrganizationalUnits;
+DROP TABLE IF EXISTS OperatingSystems;
+DROP TABLE IF EXISTS OperatingSystemFamilies;
+DROP TABLE IF EXISTS NetworkInterfaces;
+DROP TABLE IF EXISTS MachineRoles;
+DROP TABLE IF EXISTS Buildings;
+DROP TABLE IF EXISTS Audit;
+DROP TABLE IF EXISTS Architectures;
+`
+
+var initial = Migration{
+	Version: 1,
+	Name:    "initial",
+	Up:      execSQL(initialUp),
+	Down:    execSQL(initialDown),
+}
@@ -0,0 +1,69 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// client certificates of systems and the requests for them. Requests systems
+// send while enrolling wait as pending until an operator approves or rejects
+// them, issued certificates are found by their fingerprint when presented
+const machineCertificatesUp = `CREATE TABLE IF NOT EXISTS MachineCertificates (
+	Id                 INTEGER  PRIMARY KEY AUTOINCREMENT
+								NOT NULL
+								UNIQUE,
+	SystemId           INTEGER  REFERENCES Systems (Id) ON DELETE CASCADE
+								NOT NULL,
+	Status             STRING   NOT NULL
+								DEFAULT 'pending',
+	CertificateRequest STRING   NOT NULL,
+	Certificate        STRING,
+	Fingerprint        STRING   UNIQUE,
+	NotAfter           DATETIME,
+	ClientAddress      STRING   NOT NULL
+								DEFAULT '',
+	CreationDate       DATETIME NOT NULL
+								DEFAULT (CURRENT_TIMESTAMP),
+	UpdatedDate        DATETIME NOT NULL
+								DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const machineCertificatesDown = `DROP TABLE IF EXISTS MachineCertificates;
+`
+
+const postgresMachineCertificatesUp = `CREATE TABLE MachineCertificates (
+	Id                 SERIAL       PRIMARY KEY,
+	SystemId           INTEGER      NOT NULL REFERENCES Systems (Id) ON DELETE CASCADE,
+	Status             TEXT         NOT NULL DEFAULT 'pending',
+	CertificateRequest TEXT         NOT NULL,
+	Certificate        TEXT,
+	Fingerprint        TEXT         UNIQUE,
+	NotAfter           TIMESTAMP(0),
+	ClientAddress      TEXT         NOT NULL DEFAULT '',
+	CreationDate       TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UpdatedDate        TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+var machineCertificates = Migration{
+	Version:      17,
+	Name:         "machine_certificates",
+	Up:           execSQL(machineCertificatesUp),
+	Down:         execSQL(machineCertificatesDown),
+	PostgresUp:   execSQL(postgresMachineCertificatesUp),
+	PostgresDown: execSQL(machineCertificatesDown),
+}
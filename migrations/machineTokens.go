@@ -0,0 +1,46 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// per-system tokens for the machine API
+const machineTokensUp = `CREATE TABLE IF NOT EXISTS MachineTokens (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	SystemId     INTEGER  REFERENCES Systems (Id) ON DELETE CASCADE
+						  NOT NULL
+						  UNIQUE,
+	TokenHash    STRING   NOT NULL
+						  UNIQUE,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const machineTokensDown = `DROP TABLE IF EXISTS MachineTokens;
+`
+
+var machineTokens = Migration{
+	Version: 3,
+	Name:    "machine_tokens",
+	Up:      execSQL(machineTokensUp),
+	Down:    execSQL(machineTokensDown),
+}
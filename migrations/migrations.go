@@ -0,0 +1,457 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/greeneg/allocatord/model"
+)
+
+// Migration is one numbered step of the schema. Up moves a database from the
+// previous version to this one and Down reverses it. Both run inside a single
+// transaction, on SQLite with foreign key enforcement off so tables can be
+// rebuilt. PostgresUp and PostgresDown do the same on PostgreSQL, and are only
+// needed for versions past the PostgreSQL baseline
+type Migration struct {
+	Version      int
+	Name         string
+	Up           func(tx *sql.Tx) error
+	Down         func(tx *sql.Tx) error
+	PostgresUp   func(tx *sql.Tx) error
+	PostgresDown func(tx *sql.Tx) error
+}
+
+// AppliedMigration is a row of the schema_version table
+type AppliedMigration struct {
+	Version     int
+	Name        string
+	AppliedDate string
+}
+
+type UnknownVersion struct {
+	Version int
+}
+
+func (u *UnknownVersion) Error() string {
+	return "Unknown schema version " + strconv.Itoa(u.Version) + ", the latest known version is " + strconv.Itoa(Latest())
+}
+
+const schemaVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	Version     INTEGER  PRIMARY KEY
+						 NOT NULL,
+	Name        STRING   NOT NULL,
+	AppliedDate DATETIME NOT NULL
+						 DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const postgresSchemaVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	Version     INTEGER      PRIMARY KEY,
+	Name        TEXT         NOT NULL,
+	AppliedDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// the bookkeeping statements use numbered placeholders, which both backends
+// accept
+const (
+	recordVersion = "INSERT INTO schema_version (Version, Name) VALUES ($1, $2)"
+	removeVersion = "DELETE FROM schema_version WHERE Version = $1"
+)
+
+// Migrations returns every known migration in version order
+func Migrations() []Migration {
+	return migrations
+}
+
+// Latest is the version a fully migrated database is at
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func execSQL(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+func steps(funcs ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, f := range funcs {
+			err := f(tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		var column string
+		err = rows.Scan(&column)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// isSubsequence reports whether every column of want appears in have, in the
+// same relative order
+func isSubsequence(want []string, have []string) bool {
+	i := 0
+	for _, column := range have {
+		if i < len(want) && want[i] == column {
+			i++
+		}
+	}
+	return i == len(want)
+}
+
+// upgradeTable brings a table to the given definition, which takes the table
+// name as its only format verb. SQLite can neither add constrained columns
+// nor reorder them, so the table is recreated and every column the old and
+// new layouts share is copied across. Tables already holding the wanted
+// columns in order are left alone, which lets this run against databases
+// whose schema was patched by hand or created with a later layout
+func upgradeTable(table string, definition string) func(tx *sql.Tx) error {
+	return rebuildTable(table, definition, isSubsequence)
+}
+
+// downgradeTable recreates a table with the given definition, dropping any
+// column it does not list
+func downgradeTable(table string, definition string) func(tx *sql.Tx) error {
+	return rebuildTable(table, definition, func(want []string, have []string) bool {
+		return strings.Join(want, ",") == strings.Join(have, ",")
+	})
+}
+
+func rebuildTable(table string, definition string, upToDate func(want []string, have []string) bool) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		staging := table + "_migrating"
+		_, err := tx.Exec(fmt.Sprintf(definition, staging))
+		if err != nil {
+			return err
+		}
+
+		oldColumns, err := tableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		newColumns, err := tableColumns(tx, staging)
+		if err != nil {
+			return err
+		}
+
+		if upToDate(newColumns, oldColumns) {
+			_, err = tx.Exec("DROP TABLE " + staging)
+			return err
+		}
+
+		existing := make(map[string]bool, len(oldColumns))
+		for _, column := range oldColumns {
+			existing[column] = true
+		}
+		shared := make([]string, 0, len(newColumns))
+		for _, column := range newColumns {
+			if existing[column] {
+				shared = append(shared, column)
+			}
+		}
+
+		log.Println("NOTICE: Rebuilding table " + table)
+		columnList := strings.Join(shared, ", ")
+		_, err = tx.Exec("INSERT INTO " + staging + " (" + columnList + ") SELECT " + columnList + " FROM " + table)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DROP TABLE " + table)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("ALTER TABLE " + staging + " RENAME TO " + table)
+		return err
+	}
+}
+
+func tableExists(db *model.Database, table string) (bool, error) {
+	query := "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = $1"
+	if db.Driver() == model.PostgreSQL {
+		// unquoted identifiers are folded to lower case by PostgreSQL
+		query = "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = lower($1)"
+	}
+
+	var count int
+	err := db.SQL().QueryRow(query, table).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// prepare makes sure the schema_version table exists. SQLite databases
+// created before migrations were introduced already hold the initial schema,
+// so they are recorded as being at version 1 and upgraded from there
+func prepare(db *model.Database) error {
+	tracked, err := tableExists(db, "schema_version")
+	if err != nil {
+		return err
+	}
+	if tracked {
+		return nil
+	}
+
+	if db.Driver() == model.PostgreSQL {
+		_, err = db.SQL().Exec(postgresSchemaVersionTable)
+		return err
+	}
+
+	legacy, err := tableExists(db, "Users")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.SQL().Exec(schemaVersionTable)
+	if err != nil {
+		return err
+	}
+
+	if legacy {
+		log.Println("NOTICE: Found a database without schema version tracking, recording it as version 1")
+		_, err = db.SQL().Exec(recordVersion, migrations[0].Version, migrations[0].Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CurrentVersion reports the version the database is at, 0 meaning empty
+func CurrentVersion(db *model.Database) (int, error) {
+	err := prepare(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = db.SQL().QueryRow("SELECT COALESCE(MAX(Version), 0) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Applied lists the migrations recorded in the schema_version table
+func Applied(db *model.Database) ([]AppliedMigration, error) {
+	err := prepare(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.SQL().Query("SELECT Version, Name, AppliedDate FROM schema_version ORDER BY Version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make([]AppliedMigration, 0)
+	for rows.Next() {
+		migration := AppliedMigration{}
+		err = rows.Scan(&migration.Version, &migration.Name, &migration.AppliedDate)
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration
+func Up(db *model.Database) error {
+	return MigrateTo(db, Latest())
+}
+
+// MigrateTo moves the database up or down to the given version, one
+// migration per transaction, so a failure leaves it at the last version that
+// applied cleanly
+func MigrateTo(db *model.Database, target int) error {
+	if target < 0 || target > Latest() {
+		return &UnknownVersion{Version: target}
+	}
+
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+	if current > Latest() {
+		return &UnknownVersion{Version: current}
+	}
+
+	postgres := db.Driver() == model.PostgreSQL
+	if postgres {
+		if target < postgresBaselineVersion {
+			return errors.New("PostgreSQL databases start at schema version " + strconv.Itoa(postgresBaselineVersion) +
+				" and cannot be migrated to version " + strconv.Itoa(target))
+		}
+		if current == 0 {
+			log.Println("NOTICE: Creating the PostgreSQL schema at version " + strconv.Itoa(postgresBaselineVersion))
+			err = run(db, execSQL(postgresBaselineUp), func(tx *sql.Tx) error {
+				for _, m := range migrations {
+					if m.Version > postgresBaselineVersion {
+						break
+					}
+					_, err := tx.Exec(recordVersion, m.Version, m.Name)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return errors.New("creating the PostgreSQL schema failed: " + err.Error())
+			}
+			current = postgresBaselineVersion
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version > current && m.Version <= target {
+			up := m.Up
+			if postgres {
+				up = m.PostgresUp
+			}
+			if up == nil {
+				return errors.New("migration " + strconv.Itoa(m.Version) + " (" + m.Name + ") has no steps for " + db.Driver())
+			}
+
+			log.Println("NOTICE: Applying schema migration " + strconv.Itoa(m.Version) + " (" + m.Name + ")")
+			err = run(db, up, func(tx *sql.Tx) error {
+				_, err := tx.Exec(recordVersion, m.Version, m.Name)
+				return err
+			})
+			if err != nil {
+				return errors.New("migration " + strconv.Itoa(m.Version) + " (" + m.Name + ") failed: " + err.Error())
+			}
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= current && m.Version > target {
+			down := m.Down
+			if postgres {
+				down = m.PostgresDown
+			}
+			if down == nil {
+				return errors.New("migration " + strconv.Itoa(m.Version) + " (" + m.Name + ") cannot be reverted on " + db.Driver())
+			}
+
+			log.Println("NOTICE: Reverting schema migration " + strconv.Itoa(m.Version) + " (" + m.Name + ")")
+			err = run(db, down, func(tx *sql.Tx) error {
+				_, err := tx.Exec(removeVersion, m.Version)
+				return err
+			})
+			if err != nil {
+				return errors.New("reverting migration " + strconv.Itoa(m.Version) + " (" + m.Name + ") failed: " + err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// run executes a migration step and its bookkeeping in one transaction. On
+// SQLite the foreign_keys pragma is a no-op inside a transaction, so it is
+// switched on a dedicated connection beforehand and the constraints are
+// checked by hand before committing. PostgreSQL checks them as it goes
+func run(db *model.Database, step func(tx *sql.Tx) error, record func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	conn, err := db.SQL().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	sqlite := db.Driver() != model.PostgreSQL
+	if sqlite {
+		_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = step(tx)
+	if err != nil {
+		return err
+	}
+
+	if sqlite {
+		var table string
+		var rowId sql.NullInt64
+		var parent string
+		var constraint int
+		err = tx.QueryRow("PRAGMA foreign_key_check").Scan(&table, &rowId, &parent, &constraint)
+		if err == nil {
+			err = errors.New("foreign key violation in table " + table + " referencing " + parent)
+			return err
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	err = record(tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	return err
+}
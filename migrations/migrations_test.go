@@ -0,0 +1,501 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/passwords"
+	"github.com/lib/pq"
+)
+
+// PostgreSQL is only tested against a server named by this variable, in
+// key/value or URL form. Each test works in a schema of its own, dropped
+// once it is done
+const postgresDSNVariable = "ALLOCATORD_TEST_POSTGRES_DSN"
+
+func openSQLite(t *testing.T) *model.Database {
+	t.Helper()
+	db, err := model.OpenDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("cannot open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func openPostgres(t *testing.T) *model.Database {
+	t.Helper()
+	dsn := os.Getenv(postgresDSNVariable)
+	if dsn == "" {
+		t.Skip(postgresDSNVariable + " is not set")
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		dsn, err = pq.ParseURL(dsn)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %v", postgresDSNVariable, err)
+		}
+	}
+
+	admin, err := sql.Open(model.PostgreSQL, dsn)
+	if err != nil {
+		t.Fatalf("cannot connect to PostgreSQL: %v", err)
+	}
+	schema := "allocatord_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	if err != nil {
+		admin.Close()
+		t.Fatalf("cannot create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	db, err := model.OpenDatabase(model.PostgreSQL, dsn+" search_path="+schema)
+	if err != nil {
+		t.Fatalf("cannot open PostgreSQL database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// backends runs a test against a fresh SQLite database, and a fresh
+// PostgreSQL one when a server is available
+func backends(t *testing.T, test func(t *testing.T, db *model.Database)) {
+	t.Run("sqlite", func(t *testing.T) { test(t, openSQLite(t)) })
+	t.Run("postgres", func(t *testing.T) { test(t, openPostgres(t)) })
+}
+
+func checkVersion(t *testing.T, db *model.Database, want int) {
+	t.Helper()
+	version, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatalf("CurrentVersion: %v", err)
+	}
+	if version != want {
+		t.Fatalf("database is at version %d, want %d", version, want)
+	}
+}
+
+func TestPostgresBaseline(t *testing.T) {
+	db := openPostgres(t)
+
+	err := MigrateTo(db, postgresBaselineVersion)
+	if err != nil {
+		t.Fatalf("MigrateTo(%d): %v", postgresBaselineVersion, err)
+	}
+	checkVersion(t, db, postgresBaselineVersion)
+
+	// the baseline stands in for every version up to it
+	applied, err := Applied(db)
+	if err != nil {
+		t.Fatalf("Applied: %v", err)
+	}
+	if len(applied) != postgresBaselineVersion {
+		t.Fatalf("%d versions recorded, want %d", len(applied), postgresBaselineVersion)
+	}
+	for i, m := range applied {
+		if m.Version != i+1 || m.Name != migrations[i].Name {
+			t.Errorf("version %d recorded as %d (%s), want %d (%s)", i+1, m.Version, m.Name, migrations[i].Version, migrations[i].Name)
+		}
+	}
+
+	for _, table := range []string{"Users", "Systems", "NetworkInterfaces", "StorageVolumes", "HostVarLayers"} {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			t.Fatalf("tableExists(%s): %v", table, err)
+		}
+		if !exists {
+			t.Errorf("table %s missing from the baseline", table)
+		}
+	}
+
+	// the seeds set their Ids by hand, so the sequences must have moved on
+	var roleId int
+	err = db.SQL().QueryRow("INSERT INTO Roles (RoleName, Description) VALUES ('test', 'test') RETURNING Id").Scan(&roleId)
+	if err != nil {
+		t.Fatalf("cannot insert a role: %v", err)
+	}
+	if roleId <= 1 {
+		t.Errorf("new role got Id %d, which the seeds already use", roleId)
+	}
+
+	err = MigrateTo(db, postgresBaselineVersion-1)
+	if err == nil {
+		t.Errorf("migrating below the baseline succeeded")
+	}
+	checkVersion(t, db, postgresBaselineVersion)
+}
+
+// TestMigrations applies every migration, reverts them one at a time down to
+// the oldest version the backend supports and applies them all again, so
+// that each Up and Down, or PostgresUp and PostgresDown, is run
+func TestMigrations(t *testing.T) {
+	backends(t, func(t *testing.T, db *model.Database) {
+		err := Up(db)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		checkVersion(t, db, Latest())
+
+		oldest := 0
+		if db.Driver() == model.PostgreSQL {
+			oldest = postgresBaselineVersion
+		}
+		for version := Latest() - 1; version >= oldest; version-- {
+			err = MigrateTo(db, version)
+			if err != nil {
+				t.Fatalf("MigrateTo(%d): %v", version, err)
+			}
+			checkVersion(t, db, version)
+		}
+
+		err = Up(db)
+		if err != nil {
+			t.Fatalf("Up after reverting: %v", err)
+		}
+		checkVersion(t, db, Latest())
+	})
+}
+
+func TestMigrateToUnknownVersion(t *testing.T) {
+	db := openSQLite(t)
+
+	err := MigrateTo(db, Latest()+1)
+	var unknown *UnknownVersion
+	if !errors.As(err, &unknown) {
+		t.Fatalf("MigrateTo(%d) = %v, want an UnknownVersion error", Latest()+1, err)
+	}
+}
+
+// TestRepositories runs queries of the model, written with ? placeholders
+// and RETURNING Id, against a fully migrated schema
+func TestRepositories(t *testing.T) {
+	backends(t, func(t *testing.T, db *model.Database) {
+		err := Up(db)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		repos := model.NewRepositories(db)
+
+		_, err = repos.Vendors.CreateVendor(model.Vendor{VendorName: "Acme"}, model.SystemUserId)
+		if err != nil {
+			t.Fatalf("CreateVendor: %v", err)
+		}
+		vendors, err := repos.Vendors.GetVendors()
+		if err != nil {
+			t.Fatalf("GetVendors: %v", err)
+		}
+		if len(vendors) != 1 || vendors[0].Id == 0 || vendors[0].VendorName != "Acme" {
+			t.Fatalf("vendors = %+v, want Acme with an Id", vendors)
+		}
+		vendor := vendors[0]
+
+		system, err := repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+			SerialNumber:      "SN-TEST-1",
+			Vendor:            "Acme",
+			CpuCores:          8,
+			RAM:               16384,
+			NetworkInterfaces: []model.DiscoveredInterface{{DeviceId: "eth0", MACAddress: "AA-BB-CC-DD-EE-01"}},
+			Disks:             []model.DiscoveredDisk{{DeviceId: "sda", StorageType: "ssd", Size: 512}},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverSystem: %v", err)
+		}
+		if system.Id == 0 {
+			t.Fatalf("discovered system has no Id")
+		}
+		if system.VendorId != vendor.Id {
+			t.Errorf("discovered system has vendor %d, want %d", system.VendorId, vendor.Id)
+		}
+		if system.Status != model.SystemStatusPending {
+			t.Errorf("discovered system is %s, want %s", system.Status, model.SystemStatusPending)
+		}
+
+		discovered, err := repos.Discovery.GetDiscoveredSystem(system.Id)
+		if err != nil {
+			t.Fatalf("GetDiscoveredSystem: %v", err)
+		}
+		if len(discovered.NetworkInterfaces) != 1 || discovered.NetworkInterfaces[0].Id == 0 {
+			t.Fatalf("discovered network interfaces = %+v, want one with an Id", discovered.NetworkInterfaces)
+		}
+		if mac := discovered.NetworkInterfaces[0].MACAddress; mac != "aa:bb:cc:dd:ee:01" {
+			t.Errorf("MAC address stored as %s, want aa:bb:cc:dd:ee:01", mac)
+		}
+		if len(discovered.StorageVolumes) != 1 || discovered.StorageVolumes[0].Id == 0 {
+			t.Fatalf("discovered storage volumes = %+v, want one with an Id", discovered.StorageVolumes)
+		}
+
+		_, err = repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+			SerialNumber:      "SN-TEST-2",
+			NetworkInterfaces: []model.DiscoveredInterface{{MACAddress: "aa:bb:cc:dd:ee:01"}},
+		})
+		var known *model.SystemAlreadyKnown
+		if !errors.As(err, &known) {
+			t.Errorf("rediscovering a known MAC address = %v, want a SystemAlreadyKnown error", err)
+		}
+
+		request, err := repos.Reimages.CreateReimageRequest(system.Id, model.SystemUserId)
+		if err != nil {
+			t.Fatalf("CreateReimageRequest: %v", err)
+		}
+		if request.Id == 0 || request.SystemId != system.Id {
+			t.Errorf("reimage request = %+v, want one with an Id for system %d", request, system.Id)
+		}
+		_, err = repos.Reimages.CreateReimageRequest(system.Id, model.SystemUserId)
+		var inProgress *model.ReimageInProgress
+		if !errors.As(err, &inProgress) {
+			t.Errorf("second reimage request = %v, want a ReimageInProgress error", err)
+		}
+	})
+}
+
+func TestFailedLoginsInParallel(t *testing.T) {
+	backends(t, func(t *testing.T, db *model.Database) {
+		err := Up(db)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		repos := model.NewRepositories(db)
+		_, err = repos.Users.CreateUser(model.ProposedUser{UserName: "alice", FullName: "Alice", Status: "enabled", OrgUnitId: 1, RoleId: 1, TypeId: model.UserTypeLocal, Password: "Alice-password-1"})
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		t.Cleanup(func() { passwords.ConfigurePolicy(passwords.Policy{}) })
+
+		// every guess made at the same time counts, so guessing in parallel
+		// locks the account out exactly as often as guessing one at a time
+		const guesses = 20
+		for _, limit := range []int{0, 5} {
+			err = passwords.ConfigurePolicy(passwords.Policy{MaxFailedLogins: limit, LockoutMinutes: 5})
+			if err != nil {
+				t.Fatalf("ConfigurePolicy: %v", err)
+			}
+			_, err = repos.Users.UnlockUser("alice")
+			if err != nil {
+				t.Fatalf("UnlockUser: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			lockouts := 0
+			for i := 0; i < guesses; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					locked, err := repos.Users.RecordFailedLogin("alice")
+					mu.Lock()
+					defer mu.Unlock()
+					if err != nil {
+						t.Errorf("RecordFailedLogin: %v", err)
+					}
+					if locked {
+						lockouts++
+					}
+				}()
+			}
+			wg.Wait()
+
+			user, err := repos.Users.GetUserByUserName("alice")
+			if err != nil {
+				t.Fatalf("GetUserByUserName: %v", err)
+			}
+			switch limit {
+			case 0:
+				if user.FailedLoginCount != guesses || lockouts != 0 {
+					t.Errorf("without a limit: %d failed logins and %d lockouts, want %d and none", user.FailedLoginCount, lockouts, guesses)
+				}
+			default:
+				if lockouts != guesses/limit || user.FailedLoginCount != 0 || user.LockedUntil == "" {
+					t.Errorf("with a limit of %d: %d lockouts, %d failed logins left and locked until %q, want %d lockouts, none left and a lockout",
+						limit, lockouts, user.FailedLoginCount, user.LockedUntil, guesses/limit)
+				}
+			}
+		}
+	})
+}
+
+// TestMACAddresses checks that interfaces are found by their MAC address
+// however it was written when they were stored or when they are looked up
+func TestMACAddresses(t *testing.T) {
+	backends(t, func(t *testing.T, db *model.Database) {
+		err := MigrateTo(db, normalizedMACAddresses.Version-1)
+		if err != nil {
+			t.Fatalf("MigrateTo(%d): %v", normalizedMACAddresses.Version-1, err)
+		}
+		repos := model.NewRepositories(db)
+
+		system, err := repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+			SerialNumber:      "SN-TEST-1",
+			NetworkInterfaces: []model.DiscoveredInterface{{DeviceId: "eth0", MACAddress: "aa:bb:cc:dd:ee:01"}},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverSystem: %v", err)
+		}
+		// as the previous version stored addresses added by hand
+		_, err = db.Exec("INSERT INTO NetworkInterfaces (DeviceModel, DeviceId, MACAddress, SystemId, IpAddress, Bitmask, Gateway, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			"e1000", "eth1", "AA-BB-CC-DD-EE-02", system.Id, "", 0, "", model.SystemUserId)
+		if err != nil {
+			t.Fatalf("inserting an unnormalized MAC address: %v", err)
+		}
+
+		err = Up(db)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+
+		_, err = repos.NetworkInterfaces.CreateNetworkInterface(model.NetworkInterface{
+			DeviceModel: "e1000",
+			DeviceId:    "eth2",
+			MACAddress:  " AA:BB:CC:DD:EE:03",
+			SystemId:    system.Id,
+		}, model.SystemUserId)
+		if err != nil {
+			t.Fatalf("CreateNetworkInterface: %v", err)
+		}
+		_, err = repos.NetworkInterfaces.CreateNetworkInterface(model.NetworkInterface{
+			DeviceModel: "e1000",
+			DeviceId:    "eth3",
+			MACAddress:  "not a MAC address",
+			SystemId:    system.Id,
+		}, model.SystemUserId)
+		var invalid *model.InvalidMACAddress
+		if !errors.As(err, &invalid) {
+			t.Errorf("creating an interface with an invalid MAC address = %v, want an InvalidMACAddress error", err)
+		}
+
+		for _, lookup := range []string{"aa:bb:cc:dd:ee:02", "AA-BB-CC-DD-EE-02", "aa-bb-cc-dd-ee-03", "AA:BB:CC:DD:EE:03"} {
+			n, err := repos.NetworkInterfaces.GetNetworkInterfaceByMACAddress(lookup)
+			if err != nil {
+				t.Fatalf("GetNetworkInterfaceByMACAddress(%q): %v", lookup, err)
+			}
+			if n.Id == 0 || n.SystemId != system.Id {
+				t.Errorf("GetNetworkInterfaceByMACAddress(%q) = %+v, want an interface of system %d", lookup, n, system.Id)
+			}
+			normalized, _ := model.NormalizeMACAddress(lookup)
+			if n.MACAddress != normalized {
+				t.Errorf("MAC address stored as %s, want %s", n.MACAddress, normalized)
+			}
+
+			_, err = repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+				SerialNumber:      "SN-TEST-2",
+				NetworkInterfaces: []model.DiscoveredInterface{{MACAddress: lookup}},
+			})
+			var known *model.SystemAlreadyKnown
+			if !errors.As(err, &known) {
+				t.Errorf("discovering %s = %v, want a SystemAlreadyKnown error", lookup, err)
+			}
+		}
+
+		n, err := repos.NetworkInterfaces.GetNetworkInterfaceByMACAddress("not a MAC address")
+		if err != nil || n.Id != 0 {
+			t.Errorf("GetNetworkInterfaceByMACAddress of an invalid address = %+v, %v, want no interface", n, err)
+		}
+	})
+}
+
+// TestActiveReimages checks that a system never has more than one reimage
+// open, however many are requested at once, and that an operator can fail
+// one that is stuck
+func TestActiveReimages(t *testing.T) {
+	backends(t, func(t *testing.T, db *model.Database) {
+		err := MigrateTo(db, activeReimages.Version-1)
+		if err != nil {
+			t.Fatalf("MigrateTo(%d): %v", activeReimages.Version-1, err)
+		}
+		repos := model.NewRepositories(db)
+
+		system, err := repos.Discovery.DiscoverSystem(model.DiscoveryReport{
+			SerialNumber:      "SN-TEST-1",
+			NetworkInterfaces: []model.DiscoveredInterface{{DeviceId: "eth0", MACAddress: "aa:bb:cc:dd:ee:01"}},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverSystem: %v", err)
+		}
+		// as racing requests could leave them before this version
+		for i := 0; i < 2; i++ {
+			_, err = db.Exec("INSERT INTO ReimageRequests (SystemId, State, RequestedById) VALUES (?, ?, ?)",
+				system.Id, model.ReimageStateImaging, model.SystemUserId)
+			if err != nil {
+				t.Fatalf("inserting an open reimage: %v", err)
+			}
+		}
+
+		err = Up(db)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		requests, err := repos.Reimages.GetReimageRequestsBySystemId(system.Id)
+		if err != nil {
+			t.Fatalf("GetReimageRequestsBySystemId: %v", err)
+		}
+		if len(requests) != 2 || requests[0].State != model.ReimageStateImaging || requests[1].State != model.ReimageStateFailed {
+			t.Fatalf("reimages after upgrading = %+v, want the newest left imaging and the older one failed", requests)
+		}
+
+		cancelled, err := repos.Reimages.CancelReimageRequest(system.Id, "admin")
+		if err != nil {
+			t.Fatalf("CancelReimageRequest: %v", err)
+		}
+		if cancelled.State != model.ReimageStateFailed {
+			t.Errorf("cancelled reimage is %s, want %s", cancelled.State, model.ReimageStateFailed)
+		}
+		_, err = repos.Reimages.CancelReimageRequest(system.Id, "admin")
+		var noActive *model.NoActiveReimage
+		if !errors.As(err, &noActive) {
+			t.Errorf("cancelling without an open reimage = %v, want a NoActiveReimage error", err)
+		}
+
+		const requesters = 10
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		opened := 0
+		for i := 0; i < requesters; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repos.Reimages.CreateReimageRequest(system.Id, model.SystemUserId)
+				mu.Lock()
+				defer mu.Unlock()
+				var inProgress *model.ReimageInProgress
+				switch {
+				case err == nil:
+					opened++
+				case !errors.As(err, &inProgress):
+					t.Errorf("CreateReimageRequest: %v, want success or a ReimageInProgress error", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if opened != 1 {
+			t.Errorf("%d of %d parallel reimage requests opened a reimage, want 1", opened, requesters)
+		}
+	})
+}
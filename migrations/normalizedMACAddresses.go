@@ -0,0 +1,95 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// interfaces used to store their MAC address as it was typed, so the same
+// card could be "AA-BB-CC-DD-EE-FF" in one row and be looked up as
+// "aa:bb:cc:dd:ee:ff" elsewhere. Rewrite every address in the lower case,
+// colon separated form the daemon now stores and compares. Addresses that do
+// not parse are left alone for an operator to correct
+func normalizeMACAddresses(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT Id, MACAddress FROM NetworkInterfaces")
+	if err != nil {
+		return err
+	}
+	stored := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var macAddress string
+		err = rows.Scan(&id, &macAddress)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		stored[id] = macAddress
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	owners := make(map[string]int)
+	for id, macAddress := range stored {
+		hw, err := net.ParseMAC(strings.TrimSpace(macAddress))
+		if err != nil {
+			log.Println("WARNING: Network interface " + strconv.Itoa(id) + " has an unparsable MAC address '" + macAddress + "', leaving it as is")
+			continue
+		}
+		normalized := hw.String()
+		other, seen := owners[normalized]
+		if seen {
+			return errors.New("network interfaces " + strconv.Itoa(other) + " and " + strconv.Itoa(id) +
+				" have the same MAC address " + normalized + "; remove one before upgrading")
+		}
+		owners[normalized] = id
+		if normalized == macAddress {
+			continue
+		}
+		_, err = tx.Exec("UPDATE NetworkInterfaces SET MACAddress = $1 WHERE Id = $2", normalized, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the old spellings are gone, and the normalized ones are as valid to the
+// previous version as they are to this one
+func keepMACAddresses(tx *sql.Tx) error {
+	return nil
+}
+
+var normalizedMACAddresses = Migration{
+	Version:      20,
+	Name:         "normalized_mac_addresses",
+	Up:           normalizeMACAddresses,
+	Down:         keepMACAddresses,
+	PostgresUp:   normalizeMACAddresses,
+	PostgresDown: keepMACAddresses,
+}
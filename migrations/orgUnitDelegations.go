@@ -0,0 +1,59 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// organizational units whose systems and users the members of another
+// organizational unit may also see and manage
+const orgUnitDelegationsUp = `CREATE TABLE IF NOT EXISTS OrgUnitDelegations (
+	Id                 INTEGER  PRIMARY KEY AUTOINCREMENT
+								NOT NULL
+								UNIQUE,
+	OrgUnitId          INTEGER  REFERENCES OrganizationalUnits (Id) ON DELETE CASCADE
+								NOT NULL,
+	DelegatedOrgUnitId INTEGER  REFERENCES OrganizationalUnits (Id) ON DELETE CASCADE
+								NOT NULL,
+	CreatorId          INTEGER  REFERENCES Users (Id)
+								NOT NULL,
+	CreationDate       DATETIME NOT NULL
+								DEFAULT (CURRENT_TIMESTAMP),
+	UNIQUE (OrgUnitId, DelegatedOrgUnitId)
+);
+`
+
+const orgUnitDelegationsDown = `DROP TABLE IF EXISTS OrgUnitDelegations;
+`
+
+const postgresOrgUnitDelegationsUp = `CREATE TABLE OrgUnitDelegations (
+	Id                 SERIAL       PRIMARY KEY,
+	OrgUnitId          INTEGER      NOT NULL REFERENCES OrganizationalUnits (Id) ON DELETE CASCADE,
+	DelegatedOrgUnitId INTEGER      NOT NULL REFERENCES OrganizationalUnits (Id) ON DELETE CASCADE,
+	CreatorId          INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate       TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (OrgUnitId, DelegatedOrgUnitId)
+);
+`
+
+var orgUnitDelegations = Migration{
+	Version:      10,
+	Name:         "org_unit_delegations",
+	Up:           execSQL(orgUnitDelegationsUp),
+	Down:         execSQL(orgUnitDelegationsDown),
+	PostgresUp:   execSQL(postgresOrgUnitDelegationsUp),
+	PostgresDown: execSQL(orgUnitDelegationsDown),
+}
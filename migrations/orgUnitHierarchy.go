@@ -0,0 +1,62 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// organizational units nest under a parent, top level ones have none
+const orgUnitsWithParent = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	OUName       STRING   UNIQUE
+						  NOT NULL,
+	Description  STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP),
+	ParentId     INTEGER  REFERENCES OrganizationalUnits (Id)
+)`
+
+const orgUnitsWithoutParent = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	OUName       STRING   UNIQUE
+						  NOT NULL,
+	Description  STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const postgresOrgUnitHierarchyUp = `ALTER TABLE OrganizationalUnits ADD COLUMN ParentId INTEGER REFERENCES OrganizationalUnits (Id);
+`
+
+const postgresOrgUnitHierarchyDown = `ALTER TABLE OrganizationalUnits DROP COLUMN ParentId;
+`
+
+var orgUnitHierarchy = Migration{
+	Version:      11,
+	Name:         "org_unit_hierarchy",
+	Up:           upgradeTable("OrganizationalUnits", orgUnitsWithParent),
+	Down:         downgradeTable("OrganizationalUnits", orgUnitsWithoutParent),
+	PostgresUp:   execSQL(postgresOrgUnitHierarchyUp),
+	PostgresDown: execSQL(postgresOrgUnitHierarchyDown),
+}
@@ -0,0 +1,68 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// users carry a failed login counter, the time any lockout ends and whether
+// they have to change their password before doing anything else
+const usersWithLockout = `CREATE TABLE %s (
+	Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
+									 UNIQUE
+									 NOT NULL,
+	UserName                STRING   NOT NULL
+									 UNIQUE,
+	FullName                STRING   NOT NULL,
+	Status                  STRING   NOT NULL
+									 DEFAULT enabled,
+	OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
+									 NOT NULL,
+	RoleId                  INTEGER  REFERENCES Roles (Id)
+									 NOT NULL,
+	TypeId                  INTEGER  REFERENCES UserTypes (Id)
+									 NOT NULL
+									 DEFAULT (2),
+	PasswordHash            STRING   NOT NULL,
+	CreationDate            DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	LastPasswordChangedDate DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	FailedLoginCount        INTEGER  NOT NULL
+									 DEFAULT (0),
+	LockedUntil             DATETIME,
+	MustChangePassword      BOOL     NOT NULL
+									 DEFAULT (FALSE)
+)`
+
+const postgresPasswordPolicyUp = `ALTER TABLE Users ADD COLUMN FailedLoginCount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Users ADD COLUMN LockedUntil TIMESTAMP(0);
+ALTER TABLE Users ADD COLUMN MustChangePassword BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+const postgresPasswordPolicyDown = `ALTER TABLE Users DROP COLUMN MustChangePassword;
+ALTER TABLE Users DROP COLUMN LockedUntil;
+ALTER TABLE Users DROP COLUMN FailedLoginCount;
+`
+
+var passwordPolicy = Migration{
+	Version:      13,
+	Name:         "password_policy",
+	Up:           upgradeTable("Users", usersWithLockout),
+	Down:         downgradeTable("Users", usersWithType),
+	PostgresUp:   execSQL(postgresPasswordPolicyUp),
+	PostgresDown: execSQL(postgresPasswordPolicyDown),
+}
@@ -0,0 +1,272 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// PostgreSQL support arrived with schema version 8, so there is no older
+// PostgreSQL database to upgrade. An empty one is created straight at that
+// version and later migrations carry a PostgreSQL form of their steps.
+//
+// Columns are laid out in the same order as in SQLite, since the model reads
+// rows with SELECT *. Timestamps are stored without a zone and in UTC, which
+// the model pins for every session
+const postgresBaselineVersion = 8
+
+const postgresBaselineUp = `CREATE TABLE Roles (
+	Id           SERIAL       PRIMARY KEY,
+	RoleName     TEXT         NOT NULL UNIQUE,
+	Description  TEXT         NOT NULL,
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE UserTypes (
+	Id              SERIAL  PRIMARY KEY,
+	TypeName        TEXT    NOT NULL UNIQUE,
+	Description     TEXT    NOT NULL,
+	AllowRoleChange BOOLEAN NOT NULL DEFAULT FALSE,
+	AllowDeletion   BOOLEAN NOT NULL DEFAULT FALSE,
+	AllowDisable    BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE OrganizationalUnits (
+	Id           SERIAL       PRIMARY KEY,
+	OUName       TEXT         NOT NULL UNIQUE,
+	Description  TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL,
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE Users (
+	Id                      SERIAL       PRIMARY KEY,
+	UserName                TEXT         NOT NULL UNIQUE,
+	FullName                TEXT         NOT NULL,
+	Status                  TEXT         NOT NULL DEFAULT 'enabled',
+	OrgUnitId               INTEGER      NOT NULL REFERENCES OrganizationalUnits (Id),
+	RoleId                  INTEGER      NOT NULL REFERENCES Roles (Id),
+	TypeId                  INTEGER      NOT NULL DEFAULT 2 REFERENCES UserTypes (Id),
+	PasswordHash            TEXT         NOT NULL,
+	CreationDate            TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	LastPasswordChangedDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO Roles (Id, RoleName, Description, CreationDate)
+	VALUES ( 1, 'SYSTEM', 'Built-in system role', '2024-06-01 14:57:41' );
+INSERT INTO UserTypes (Id, TypeName, Description, AllowRoleChange, AllowDeletion, AllowDisable)
+	VALUES ( 1, 'BUILTIN', 'Built-in system user type', FALSE, FALSE, FALSE ),
+	       ( 2, 'LOCAL', 'Local user type', TRUE, TRUE, TRUE ),
+	       ( 3, 'EXTERNAL', 'External user type', TRUE, TRUE, TRUE );
+INSERT INTO OrganizationalUnits (Id, OUName, Description, CreatorId, CreationDate)
+	VALUES ( 1, 'Unassigned', 'The OU used as a place holder when a system changes hands', 1, '2024-06-01 15:38:42' );
+INSERT INTO Users (Id, UserName, FullName, Status, OrgUnitId, RoleId, TypeId, PasswordHash, CreationDate, LastPasswordChangedDate)
+	VALUES ( 1, 'SYSTEM', 'Allocator System', 'enabled', 1, 1, 1, '!', '2024-06-01 14:58:36', '2024-06-01 14:58:36' );
+
+-- the seeds above set their Ids by hand, so move the sequences past them
+SELECT setval(pg_get_serial_sequence('roles', 'id'), (SELECT MAX(Id) FROM Roles));
+SELECT setval(pg_get_serial_sequence('usertypes', 'id'), (SELECT MAX(Id) FROM UserTypes));
+SELECT setval(pg_get_serial_sequence('organizationalunits', 'id'), (SELECT MAX(Id) FROM OrganizationalUnits));
+SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(Id) FROM Users));
+
+-- organizational units and users refer to each other, so this constraint
+-- can only be added once both tables exist
+ALTER TABLE OrganizationalUnits ADD FOREIGN KEY (CreatorId) REFERENCES Users (Id);
+
+CREATE TABLE Audit (
+	Id           SERIAL       PRIMARY KEY,
+	ChangedById  INTEGER      NOT NULL REFERENCES Users (Id),
+	TableChanged TEXT         NOT NULL,
+	ChangeClass  TEXT         NOT NULL,
+	ChangeDate   TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE Buildings (
+	Id           SERIAL       PRIMARY KEY,
+	BuildingName TEXT         NOT NULL UNIQUE,
+	ShortName    TEXT         NOT NULL UNIQUE,
+	City         TEXT         NOT NULL,
+	Region       TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE MachineRoles (
+	Id              SERIAL       PRIMARY KEY,
+	MachineRoleName TEXT         NOT NULL UNIQUE,
+	Description     TEXT         NOT NULL,
+	CreatorId       INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate    TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE Vendors (
+	Id           SERIAL       PRIMARY KEY,
+	VendorName   TEXT         NOT NULL UNIQUE,
+	CreatorId    INTEGER      REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE Architectures (
+	Id           SERIAL       PRIMARY KEY,
+	ISEName      TEXT         NOT NULL UNIQUE,
+	RegisterSize INTEGER      NOT NULL DEFAULT 64,
+	KernelPath   TEXT         NOT NULL DEFAULT '',
+	InitrdPath   TEXT         NOT NULL DEFAULT '',
+	KernelArgs   TEXT         NOT NULL DEFAULT '',
+	BootFilename TEXT         NOT NULL DEFAULT '',
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE OperatingSystemFamilies (
+	Id           SERIAL       PRIMARY KEY,
+	OSFamilyName TEXT         NOT NULL UNIQUE,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE OperatingSystems (
+	Id               SERIAL       PRIMARY KEY,
+	OSName           TEXT         NOT NULL UNIQUE,
+	OSFamilyId       INTEGER      NOT NULL REFERENCES OperatingSystemFamilies (Id),
+	VendorId         INTEGER      REFERENCES Vendors (Id),
+	OSImageUrl       TEXT         NOT NULL UNIQUE,
+	ImageUriProtocol TEXT         NOT NULL DEFAULT '',
+	CreatorId        INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate     TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE OperatingSystemVersions (
+	Id                SERIAL       PRIMARY KEY,
+	OperatingSystemId INTEGER      NOT NULL REFERENCES OperatingSystems (Id) ON DELETE CASCADE,
+	VersionNumber     TEXT         NOT NULL,
+	CreatorId         INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate      TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE SystemModels (
+	Id           SERIAL       PRIMARY KEY,
+	ModelName    TEXT         NOT NULL UNIQUE,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE Systems (
+	Id                SERIAL       PRIMARY KEY,
+	SerialNumber      TEXT         NOT NULL UNIQUE,
+	ModelId           INTEGER      NOT NULL REFERENCES SystemModels (Id),
+	OperatingSystemId INTEGER      NOT NULL REFERENCES OperatingSystems (Id),
+	Reimage           BOOLEAN      NOT NULL DEFAULT FALSE,
+	HostVars          TEXT         NOT NULL,
+	BilledToOrgUnitId INTEGER      NOT NULL REFERENCES OrganizationalUnits (Id),
+	MachineRoleId     INTEGER      NOT NULL REFERENCES MachineRoles (Id),
+	BuildingId        INTEGER      NOT NULL REFERENCES Buildings (Id),
+	VendorId          INTEGER      NOT NULL REFERENCES Vendors (Id),
+	ArchitectureId    INTEGER      NOT NULL REFERENCES Architectures (Id),
+	RAM               INTEGER      NOT NULL,
+	CPUCores          INTEGER      NOT NULL,
+	CreatorId         INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate      TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE NetworkInterfaces (
+	Id           SERIAL       PRIMARY KEY,
+	DeviceModel  TEXT         NOT NULL,
+	DeviceId     TEXT         NOT NULL,
+	MACAddress   TEXT         NOT NULL UNIQUE,
+	SystemId     INTEGER      NOT NULL REFERENCES Systems (Id),
+	IpAddress    TEXT         NOT NULL,
+	Bitmask      INTEGER      NOT NULL,
+	Gateway      TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE StorageVolumes (
+	Id           SERIAL       PRIMARY KEY,
+	VolumeName   TEXT         NOT NULL,
+	StorageType  TEXT         NOT NULL,
+	DeviceModel  TEXT         NOT NULL,
+	DeviceId     TEXT         NOT NULL,
+	MountPoint   TEXT         NOT NULL,
+	VolumeSize   INTEGER      NOT NULL,
+	VolumeFormat TEXT         NOT NULL,
+	VolumeLabel  TEXT         NOT NULL,
+	SystemId     INTEGER      NOT NULL REFERENCES Systems (Id),
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE MachineTokens (
+	Id           SERIAL       PRIMARY KEY,
+	SystemId     INTEGER      NOT NULL UNIQUE REFERENCES Systems (Id) ON DELETE CASCADE,
+	TokenHash    TEXT         NOT NULL UNIQUE,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE ReimageRequests (
+	Id            SERIAL       PRIMARY KEY,
+	SystemId      INTEGER      NOT NULL REFERENCES Systems (Id) ON DELETE CASCADE,
+	State         TEXT         NOT NULL DEFAULT 'requested',
+	RequestedById INTEGER      NOT NULL REFERENCES Users (Id),
+	RequestedDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UpdatedDate   TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CompletedDate TIMESTAMP(0)
+);
+CREATE TABLE ReimageEvents (
+	Id               SERIAL       PRIMARY KEY,
+	ReimageRequestId INTEGER      NOT NULL REFERENCES ReimageRequests (Id) ON DELETE CASCADE,
+	State            TEXT         NOT NULL,
+	Message          TEXT         NOT NULL DEFAULT '',
+	EventDate        TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE MaintenanceWindows (
+	Id              SERIAL       PRIMARY KEY,
+	BuildingId      INTEGER      NOT NULL REFERENCES Buildings (Id) ON DELETE CASCADE,
+	Weekday         INTEGER      NOT NULL,
+	StartTime       TEXT         NOT NULL,
+	DurationMinutes INTEGER      NOT NULL,
+	CreatorId       INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate    TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE ReimageBatches (
+	Id             SERIAL       PRIMARY KEY,
+	BatchName      TEXT         NOT NULL,
+	ScheduledDate  TIMESTAMP(0) NOT NULL,
+	MaxPerBuilding INTEGER      NOT NULL DEFAULT 0,
+	State          TEXT         NOT NULL DEFAULT 'scheduled',
+	CreatorId      INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate   TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE ReimageBatchSystems (
+	Id               SERIAL       PRIMARY KEY,
+	BatchId          INTEGER      NOT NULL REFERENCES ReimageBatches (Id) ON DELETE CASCADE,
+	SystemId         INTEGER      NOT NULL REFERENCES Systems (Id) ON DELETE CASCADE,
+	State            TEXT         NOT NULL DEFAULT 'pending',
+	ReimageRequestId INTEGER      REFERENCES ReimageRequests (Id),
+	StartedDate      TIMESTAMP(0)
+);
+CREATE TABLE AnswerFileTemplates (
+	Id           SERIAL       PRIMARY KEY,
+	OSFamilyId   INTEGER      NOT NULL REFERENCES OperatingSystemFamilies (Id) ON DELETE CASCADE,
+	TemplateType TEXT         NOT NULL,
+	Version      INTEGER      NOT NULL,
+	Content      TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (OSFamilyId, TemplateType, Version)
+);
+CREATE TABLE HostVarLayers (
+	Id           SERIAL       PRIMARY KEY,
+	Scope        TEXT         NOT NULL,
+	ScopeId      INTEGER      NOT NULL DEFAULT 0,
+	HostVars     TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (Scope, ScopeId)
+);
+CREATE TABLE MachineRoleHostVarsSchemas (
+	Id             SERIAL       PRIMARY KEY,
+	MachineRoleId  INTEGER      NOT NULL UNIQUE REFERENCES MachineRoles (Id) ON DELETE CASCADE,
+	HostVarsSchema TEXT         NOT NULL,
+	CreatorId      INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate   TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
@@ -0,0 +1,76 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// scheduled reimage batches and the building maintenance windows they run in
+const reimageBatchesUp = `CREATE TABLE IF NOT EXISTS MaintenanceWindows (
+	Id              INTEGER  PRIMARY KEY AUTOINCREMENT
+							 NOT NULL
+							 UNIQUE,
+	BuildingId      INTEGER  REFERENCES Buildings (Id) ON DELETE CASCADE
+							 NOT NULL,
+	Weekday         INTEGER  NOT NULL,
+	StartTime       STRING   NOT NULL,
+	DurationMinutes INTEGER  NOT NULL,
+	CreatorId       INTEGER  REFERENCES Users (Id)
+							 NOT NULL,
+	CreationDate    DATETIME NOT NULL
+							 DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS ReimageBatches (
+	Id             INTEGER  PRIMARY KEY AUTOINCREMENT
+							NOT NULL
+							UNIQUE,
+	BatchName      STRING   NOT NULL,
+	ScheduledDate  DATETIME NOT NULL,
+	MaxPerBuilding INTEGER  NOT NULL
+							DEFAULT (0),
+	State          STRING   NOT NULL
+							DEFAULT scheduled,
+	CreatorId      INTEGER  REFERENCES Users (Id)
+							NOT NULL,
+	CreationDate   DATETIME NOT NULL
+							DEFAULT (CURRENT_TIMESTAMP)
+);
+CREATE TABLE IF NOT EXISTS ReimageBatchSystems (
+	Id               INTEGER  PRIMARY KEY AUTOINCREMENT
+							  NOT NULL
+							  UNIQUE,
+	BatchId          INTEGER  REFERENCES ReimageBatches (Id) ON DELETE CASCADE
+							  NOT NULL,
+	SystemId         INTEGER  REFERENCES Systems (Id) ON DELETE CASCADE
+							  NOT NULL,
+	State            STRING   NOT NULL
+							  DEFAULT pending,
+	ReimageRequestId INTEGER  REFERENCES ReimageRequests (Id),
+	StartedDate      DATETIME
+);
+`
+
+const reimageBatchesDown = `DROP TABLE IF EXISTS ReimageBatchSystems;
+DROP TABLE IF EXISTS ReimageBatches;
+DROP TABLE IF EXISTS MaintenanceWindows;
+`
+
+var reimageBatches = Migration{
+	Version: 5,
+	Name:    "reimage_batches",
+	Up:      execSQL(reimageBatchesUp),
+	Down:    execSQL(reimageBatchesDown),
+}
@@ -0,0 +1,61 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// reimage lifecycle state and its progress events
+const reimagesUp = `CREATE TABLE IF NOT EXISTS ReimageRequests (
+	Id            INTEGER  PRIMARY KEY AUTOINCREMENT
+						   NOT NULL
+						   UNIQUE,
+	SystemId      INTEGER  REFERENCES Systems (Id) ON DELETE CASCADE
+						   NOT NULL,
+	State         STRING   NOT NULL
+						   DEFAULT requested,
+	RequestedById INTEGER  REFERENCES Users (Id)
+						   NOT NULL,
+	RequestedDate DATETIME NOT NULL
+						   DEFAULT (CURRENT_TIMESTAMP),
+	UpdatedDate   DATETIME NOT NULL
+						   DEFAULT (CURRENT_TIMESTAMP),
+	CompletedDate DATETIME
+);
+CREATE TABLE IF NOT EXISTS ReimageEvents (
+	Id               INTEGER  PRIMARY KEY AUTOINCREMENT
+							  NOT NULL
+							  UNIQUE,
+	ReimageRequestId INTEGER  REFERENCES ReimageRequests (Id) ON DELETE CASCADE
+							  NOT NULL,
+	State            STRING   NOT NULL,
+	Message          STRING   NOT NULL
+							  DEFAULT '',
+	EventDate        DATETIME NOT NULL
+							  DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const reimagesDown = `DROP TABLE IF EXISTS ReimageEvents;
+DROP TABLE IF EXISTS ReimageRequests;
+`
+
+var reimages = Migration{
+	Version: 4,
+	Name:    "reimages",
+	Up:      execSQL(reimagesUp),
+	Down:    execSQL(reimagesDown),
+}
@@ -0,0 +1,61 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// permissions granted to roles. Every role that exists when this is applied
+// is granted the wildcard, so upgrading does not take away access anyone
+// already had
+const rolePermissionsUp = `CREATE TABLE IF NOT EXISTS RolePermissions (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	RoleId       INTEGER  REFERENCES Roles (Id) ON DELETE CASCADE
+						  NOT NULL,
+	Permission   STRING   NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP),
+	UNIQUE (RoleId, Permission)
+);
+INSERT INTO RolePermissions (RoleId, Permission, CreatorId) SELECT Id, '*', 1 FROM Roles;
+`
+
+const rolePermissionsDown = `DROP TABLE IF EXISTS RolePermissions;
+`
+
+const postgresRolePermissionsUp = `CREATE TABLE RolePermissions (
+	Id           SERIAL       PRIMARY KEY,
+	RoleId       INTEGER      NOT NULL REFERENCES Roles (Id) ON DELETE CASCADE,
+	Permission   TEXT         NOT NULL,
+	CreatorId    INTEGER      NOT NULL REFERENCES Users (Id),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (RoleId, Permission)
+);
+INSERT INTO RolePermissions (RoleId, Permission, CreatorId) SELECT Id, '*', 1 FROM Roles;
+`
+
+var rolePermissions = Migration{
+	Version:      9,
+	Name:         "role_permissions",
+	Up:           execSQL(rolePermissionsUp),
+	Down:         execSQL(rolePermissionsDown),
+	PostgresUp:   execSQL(postgresRolePermissionsUp),
+	PostgresDown: execSQL(rolePermissionsDown),
+}
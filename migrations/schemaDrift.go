@@ -0,0 +1,166 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// the model has long expected user types, OS vendors and URI protocols,
+// architecture register sizes and OS versions, none of which the initial
+// schema had
+
+const usersWithType = `CREATE TABLE %s (
+	Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
+									 UNIQUE
+									 NOT NULL,
+	UserName                STRING   NOT NULL
+									 UNIQUE,
+	FullName                STRING   NOT NULL,
+	Status                  STRING   NOT NULL
+									 DEFAULT enabled,
+	OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
+									 NOT NULL,
+	RoleId                  INTEGER  REFERENCES Roles (Id)
+									 NOT NULL,
+	TypeId                  INTEGER  REFERENCES UserTypes (Id)
+									 NOT NULL
+									 DEFAULT (2),
+	PasswordHash            STRING   NOT NULL,
+	CreationDate            DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	LastPasswordChangedDate DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const usersWithoutType = `CREATE TABLE %s (
+	Id                      INTEGER  PRIMARY KEY AUTOINCREMENT
+									 UNIQUE
+									 NOT NULL,
+	UserName                STRING   NOT NULL
+									 UNIQUE,
+	FullName                STRING   NOT NULL,
+	Status                  STRING   NOT NULL
+									 DEFAULT enabled,
+	OrgUnitId               INTEGER  REFERENCES OrganizationalUnits (Id)
+									 NOT NULL,
+	RoleId                  INTEGER  REFERENCES Roles (Id)
+									 NOT NULL,
+	PasswordHash            STRING   NOT NULL,
+	CreationDate            DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP),
+	LastPasswordChangedDate DATETIME NOT NULL
+									 DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+// operating systems registered before vendors were tracked keep a NULL
+// VendorId
+const operatingSystemsWithVendor = `CREATE TABLE %s (
+	Id               INTEGER  PRIMARY KEY AUTOINCREMENT
+							  UNIQUE
+							  NOT NULL,
+	OSName           STRING   UNIQUE
+							  NOT NULL,
+	OSFamilyId       INTEGER  REFERENCES OperatingSystemFamilies (Id)
+							  NOT NULL,
+	VendorId         INTEGER  REFERENCES Vendors (Id),
+	OSImageUrl       STRING   UNIQUE
+							  NOT NULL,
+	ImageUriProtocol STRING   NOT NULL
+							  DEFAULT '',
+	CreatorId        INTEGER  REFERENCES Users (Id)
+							  NOT NULL,
+	CreationDate     DATETIME NOT NULL
+							  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const operatingSystemsWithoutVendor = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  UNIQUE
+						  NOT NULL,
+	OSName       STRING   UNIQUE
+						  NOT NULL,
+	OSFamilyId   INTEGER  REFERENCES OperatingSystemFamilies (Id)
+						  NOT NULL,
+	OSImageUrl   STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  REFERENCES Users (Id)
+						  NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const architecturesWithRegisterSize = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ISEName      STRING   UNIQUE
+						  NOT NULL,
+	RegisterSize INTEGER  NOT NULL
+						  DEFAULT (64),
+	CreatorId    INTEGER  NOT NULL
+						  REFERENCES Users (Id),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const architecturesWithoutRegisterSize = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	ISEName      STRING   UNIQUE
+						  NOT NULL,
+	CreatorId    INTEGER  NOT NULL
+						  REFERENCES Users (Id),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+const operatingSystemVersions = `CREATE TABLE IF NOT EXISTS OperatingSystemVersions (
+	Id                INTEGER  PRIMARY KEY AUTOINCREMENT
+							   UNIQUE
+							   NOT NULL,
+	OperatingSystemId INTEGER  REFERENCES OperatingSystems (Id) ON DELETE CASCADE
+							   NOT NULL,
+	VersionNumber     STRING   NOT NULL,
+	CreatorId         INTEGER  REFERENCES Users (Id)
+							   NOT NULL,
+	CreationDate      DATETIME NOT NULL
+							   DEFAULT (CURRENT_TIMESTAMP)
+);`
+
+// the built-in account is the only one of the built-in type, and the URI
+// protocol of existing images is taken from their URLs
+const schemaDriftBackfill = `UPDATE Users SET TypeId = 1 WHERE UserName = 'SYSTEM';
+UPDATE OperatingSystems SET ImageUriProtocol = substr(OSImageUrl, 1, instr(OSImageUrl, '://') - 1)
+	WHERE ImageUriProtocol = '' AND instr(OSImageUrl, '://') > 1;`
+
+var schemaDrift = Migration{
+	Version: 2,
+	Name:    "schema_drift",
+	Up: steps(
+		upgradeTable("Users", usersWithType),
+		upgradeTable("OperatingSystems", operatingSystemsWithVendor),
+		upgradeTable("Architectures", architecturesWithRegisterSize),
+		execSQL(operatingSystemVersions),
+		execSQL(schemaDriftBackfill),
+	),
+	Down: steps(
+		execSQL("DROP TABLE IF EXISTS OperatingSystemVersions;"),
+		downgradeTable("Architectures", architecturesWithoutRegisterSize),
+		downgradeTable("OperatingSystems", operatingSystemsWithoutVendor),
+		downgradeTable("Users", usersWithoutType),
+	),
+}
@@ -0,0 +1,68 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// sessions users started by logging in, found by the hash of the token their
+// session cookie carries. A session ends at ExpiryDate, or earlier when it
+// goes unused for longer than the idle timeout
+const sessionsUp = `CREATE TABLE IF NOT EXISTS Sessions (
+	Id            INTEGER  PRIMARY KEY AUTOINCREMENT
+						   NOT NULL
+						   UNIQUE,
+	UserId        INTEGER  REFERENCES Users (Id) ON DELETE CASCADE
+						   NOT NULL,
+	TokenHash     STRING   NOT NULL
+						   UNIQUE,
+	AuthMethod    STRING   NOT NULL,
+	ClientAddress STRING   NOT NULL
+						   DEFAULT '',
+	UserAgent     STRING   NOT NULL
+						   DEFAULT '',
+	CreationDate  DATETIME NOT NULL
+						   DEFAULT (CURRENT_TIMESTAMP),
+	LastSeenDate  DATETIME NOT NULL
+						   DEFAULT (CURRENT_TIMESTAMP),
+	ExpiryDate    DATETIME NOT NULL
+);
+`
+
+const sessionsDown = `DROP TABLE IF EXISTS Sessions;
+`
+
+const postgresSessionsUp = `CREATE TABLE Sessions (
+	Id            SERIAL       PRIMARY KEY,
+	UserId        INTEGER      NOT NULL REFERENCES Users (Id) ON DELETE CASCADE,
+	TokenHash     TEXT         NOT NULL UNIQUE,
+	AuthMethod    TEXT         NOT NULL,
+	ClientAddress TEXT         NOT NULL DEFAULT '',
+	UserAgent     TEXT         NOT NULL DEFAULT '',
+	CreationDate  TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	LastSeenDate  TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ExpiryDate    TIMESTAMP(0) NOT NULL
+);
+`
+
+var sessions = Migration{
+	Version:      16,
+	Name:         "sessions",
+	Up:           execSQL(sessionsUp),
+	Down:         execSQL(sessionsDown),
+	PostgresUp:   execSQL(postgresSessionsUp),
+	PostgresDown: execSQL(sessionsDown),
+}
@@ -0,0 +1,152 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// systems reported by imaging clients that booted on unknown hardware wait as
+// pending until an operator approves them. Until then they have no
+// organizational unit, building, machine role or operating system, and may
+// lack a model, vendor or architecture the allocator knows of
+const systemsWithStatus = `CREATE TABLE %s (
+	Id                INTEGER  PRIMARY KEY AUTOINCREMENT
+							   UNIQUE
+							   NOT NULL,
+	SerialNumber      STRING   NOT NULL
+							   UNIQUE,
+	ModelId           INTEGER  REFERENCES SystemModels (Id),
+	OperatingSystemId INTEGER  REFERENCES OperatingSystems (Id),
+	Reimage           BOOL     NOT NULL
+							   DEFAULT (FALSE),
+	HostVars          STRING   NOT NULL,
+	BilledToOrgUnitId INTEGER  REFERENCES OrganizationalUnits (Id),
+	MachineRoleId     INTEGER  REFERENCES MachineRoles (Id),
+	BuildingId        INTEGER  REFERENCES Buildings (Id),
+	VendorId          INTEGER  REFERENCES Vendors (Id),
+	ArchitectureId    INTEGER  REFERENCES Architectures (Id),
+	RAM               INTEGER  NOT NULL,
+	CPUCores          INTEGER  NOT NULL,
+	CreatorId         INTEGER  REFERENCES Users (Id)
+							   NOT NULL,
+	CreationDate      DATETIME NOT NULL
+							   DEFAULT (CURRENT_TIMESTAMP),
+	Status            STRING   NOT NULL
+							   DEFAULT 'active'
+)`
+
+const systemsWithoutStatus = `CREATE TABLE %s (
+	Id                INTEGER  PRIMARY KEY AUTOINCREMENT
+							   UNIQUE
+							   NOT NULL,
+	SerialNumber      STRING   NOT NULL
+							   UNIQUE,
+	ModelId           INTEGER  REFERENCES SystemModels (Id)
+							   NOT NULL,
+	OperatingSystemId INTEGER  NOT NULL
+							   REFERENCES OperatingSystems (Id),
+	Reimage           BOOL     NOT NULL
+							   DEFAULT (FALSE),
+	HostVars          STRING   NOT NULL,
+	BilledToOrgUnitId INTEGER  REFERENCES OrganizationalUnits (Id)
+							   NOT NULL,
+	MachineRoleId     INTEGER  NOT NULL
+							   REFERENCES MachineRoles (Id),
+	BuildingId        INTEGER  REFERENCES Buildings (Id)
+							   NOT NULL,
+	VendorId          INTEGER  NOT NULL
+							   REFERENCES Vendors (Id),
+	ArchitectureId    INTEGER  REFERENCES Architectures (Id)
+							   NOT NULL,
+	RAM               INTEGER  NOT NULL,
+	CPUCores          INTEGER  NOT NULL,
+	CreatorId         INTEGER  REFERENCES Users (Id)
+							   NOT NULL,
+	CreationDate      DATETIME NOT NULL
+							   DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+// what the imaging client of a discovered system reported that could not be
+// stored against the system itself, kept for the operator reviewing it
+const systemDiscoveryUp = `CREATE TABLE IF NOT EXISTS SystemDiscoveries (
+	Id                   INTEGER  PRIMARY KEY AUTOINCREMENT
+								  NOT NULL
+								  UNIQUE,
+	SystemId             INTEGER  REFERENCES Systems (Id) ON DELETE CASCADE
+								  NOT NULL
+								  UNIQUE,
+	ReportedVendor       STRING   NOT NULL
+								  DEFAULT '',
+	ReportedModel        STRING   NOT NULL
+								  DEFAULT '',
+	ReportedArchitecture STRING   NOT NULL
+								  DEFAULT '',
+	ClientAddress        STRING   NOT NULL
+								  DEFAULT '',
+	CreationDate         DATETIME NOT NULL
+								  DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+// systems still waiting for approval cannot be kept once the columns they
+// leave empty are required again, and neither can what was recorded about
+// them. Foreign keys are off while migrating, so nothing cascades
+const pendingSystemsDown = `DELETE FROM NetworkInterfaces WHERE SystemId IN (SELECT Id FROM Systems WHERE Status = 'pending');
+DELETE FROM StorageVolumes WHERE SystemId IN (SELECT Id FROM Systems WHERE Status = 'pending');
+DELETE FROM MachineTokens WHERE SystemId IN (SELECT Id FROM Systems WHERE Status = 'pending');
+DELETE FROM MachineCertificates WHERE SystemId IN (SELECT Id FROM Systems WHERE Status = 'pending');
+DELETE FROM Systems WHERE Status = 'pending';
+DROP TABLE IF EXISTS SystemDiscoveries;
+`
+
+const postgresSystemDiscoveryUp = `ALTER TABLE Systems ALTER COLUMN ModelId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN OperatingSystemId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN BilledToOrgUnitId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN MachineRoleId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN BuildingId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN VendorId DROP NOT NULL;
+ALTER TABLE Systems ALTER COLUMN ArchitectureId DROP NOT NULL;
+ALTER TABLE Systems ADD COLUMN Status TEXT NOT NULL DEFAULT 'active';
+
+CREATE TABLE SystemDiscoveries (
+	Id                   SERIAL       PRIMARY KEY,
+	SystemId             INTEGER      NOT NULL UNIQUE REFERENCES Systems (Id) ON DELETE CASCADE,
+	ReportedVendor       TEXT         NOT NULL DEFAULT '',
+	ReportedModel        TEXT         NOT NULL DEFAULT '',
+	ReportedArchitecture TEXT         NOT NULL DEFAULT '',
+	ClientAddress        TEXT         NOT NULL DEFAULT '',
+	CreationDate         TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const postgresSystemDiscoveryDown = pendingSystemsDown + `ALTER TABLE Systems DROP COLUMN Status;
+ALTER TABLE Systems ALTER COLUMN ModelId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN OperatingSystemId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN BilledToOrgUnitId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN MachineRoleId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN BuildingId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN VendorId SET NOT NULL;
+ALTER TABLE Systems ALTER COLUMN ArchitectureId SET NOT NULL;
+`
+
+var systemDiscovery = Migration{
+	Version:      18,
+	Name:         "system_discovery",
+	Up:           steps(upgradeTable("Systems", systemsWithStatus), execSQL(systemDiscoveryUp)),
+	Down:         steps(execSQL(pendingSystemsDown), downgradeTable("Systems", systemsWithoutStatus)),
+	PostgresUp:   execSQL(postgresSystemDiscoveryUp),
+	PostgresDown: execSQL(postgresSystemDiscoveryDown),
+}
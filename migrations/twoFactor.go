@@ -0,0 +1,111 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// roles can require their users to log in with a second factor
+const rolesWithTwoFactor = `CREATE TABLE %s (
+	Id               INTEGER  PRIMARY KEY AUTOINCREMENT,
+	RoleName         STRING   UNIQUE
+							  NOT NULL,
+	Description      STRING   NOT NULL,
+	CreationDate     DATETIME NOT NULL
+							  DEFAULT (CURRENT_TIMESTAMP),
+	RequireTwoFactor BOOL     NOT NULL
+							  DEFAULT (FALSE)
+)`
+
+const rolesWithoutTwoFactor = `CREATE TABLE %s (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+	RoleName     STRING   UNIQUE
+						  NOT NULL,
+	Description  STRING   NOT NULL,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+)`
+
+// the TOTP secret of each enrolled user, which only counts once the user has
+// confirmed it with a code. LastUsedStep is the time step of the last code
+// accepted, so a code cannot be replayed. Recovery codes are stored hashed
+// and can be used once each
+const twoFactorUp = `CREATE TABLE IF NOT EXISTS TwoFactorSecrets (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	UserId       INTEGER  REFERENCES Users (Id) ON DELETE CASCADE
+						  NOT NULL
+						  UNIQUE,
+	Secret       STRING   NOT NULL,
+	Enabled      BOOL     NOT NULL
+						  DEFAULT (FALSE),
+	LastUsedStep INTEGER  NOT NULL
+						  DEFAULT (0),
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+
+CREATE TABLE IF NOT EXISTS RecoveryCodes (
+	Id           INTEGER  PRIMARY KEY AUTOINCREMENT
+						  NOT NULL
+						  UNIQUE,
+	UserId       INTEGER  REFERENCES Users (Id) ON DELETE CASCADE
+						  NOT NULL,
+	CodeHash     STRING   NOT NULL,
+	UsedDate     DATETIME,
+	CreationDate DATETIME NOT NULL
+						  DEFAULT (CURRENT_TIMESTAMP)
+);
+`
+
+const twoFactorDown = `DROP TABLE IF EXISTS RecoveryCodes;
+DROP TABLE IF EXISTS TwoFactorSecrets;
+`
+
+const postgresTwoFactorUp = `ALTER TABLE Roles ADD COLUMN RequireTwoFactor BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE TwoFactorSecrets (
+	Id           SERIAL       PRIMARY KEY,
+	UserId       INTEGER      NOT NULL UNIQUE REFERENCES Users (Id) ON DELETE CASCADE,
+	Secret       TEXT         NOT NULL,
+	Enabled      BOOLEAN      NOT NULL DEFAULT FALSE,
+	LastUsedStep BIGINT       NOT NULL DEFAULT 0,
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE RecoveryCodes (
+	Id           SERIAL       PRIMARY KEY,
+	UserId       INTEGER      NOT NULL REFERENCES Users (Id) ON DELETE CASCADE,
+	CodeHash     TEXT         NOT NULL,
+	UsedDate     TIMESTAMP(0),
+	CreationDate TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const postgresTwoFactorDown = `DROP TABLE IF EXISTS RecoveryCodes;
+DROP TABLE IF EXISTS TwoFactorSecrets;
+ALTER TABLE Roles DROP COLUMN RequireTwoFactor;
+`
+
+var twoFactor = Migration{
+	Version:      15,
+	Name:         "two_factor",
+	Up:           steps(upgradeTable("Roles", rolesWithTwoFactor), execSQL(twoFactorUp)),
+	Down:         steps(execSQL(twoFactorDown), downgradeTable("Roles", rolesWithoutTwoFactor)),
+	PostgresUp:   execSQL(postgresTwoFactorUp),
+	PostgresDown: execSQL(postgresTwoFactorDown),
+}
@@ -0,0 +1,45 @@
+package migrations
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// every migration, in the order they apply. Versions must be consecutive and
+// a released migration must never change; fix mistakes with a new one
+var migrations = []Migration{
+	initial,
+	schemaDrift,
+	machineTokens,
+	reimages,
+	reimageBatches,
+	bootConfig,
+	answerFileTemplates,
+	hostVars,
+	rolePermissions,
+	orgUnitDelegations,
+	orgUnitHierarchy,
+	auditTrail,
+	passwordPolicy,
+	apiTokens,
+	twoFactor,
+	sessions,
+	machineCertificates,
+	systemDiscovery,
+	externalIdentities,
+	normalizedMACAddresses,
+	activeReimages,
+}
@@ -20,52 +20,114 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"log"
+	"strings"
 
+	"github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var DB *sql.DB
+// the database/sql driver names of the supported backends
+const (
+	SQLite     = "sqlite3"
+	PostgreSQL = "postgres"
+)
+
+var DB *Database
 
-func ConnectDatabase(dbPath string) error {
-	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on&cache=shared&mode=rwc&_journal_mode=WAL&_tx_locking_mode=EXCLUSIVE&_busy_timeout=5000&_temp_store=MEMORY&_auto_vacuum=FULL&_synchronous=NORMAL")
+// ConnectDatabase opens the configured backend and points the package
+// repositories at it. For SQLite the data source is the path to the database
+// file, for PostgreSQL it is a connection string or URL
+func ConnectDatabase(driver string, dataSource string) error {
+	db, err := OpenDatabase(driver, dataSource)
 	if err != nil {
 		return err
 	}
 
+	DB = db
+	Repos = NewRepositories(db)
+	return nil
+}
+
+// OpenDatabase opens a connection pool without touching the package state
+func OpenDatabase(driver string, dataSource string) (*Database, error) {
+	switch driver {
+	case "", SQLite:
+		return openSQLite(dataSource)
+	case PostgreSQL:
+		return openPostgres(dataSource)
+	}
+
+	return nil, errors.New("unsupported database driver '" + driver + "', expected " + SQLite + " or " + PostgreSQL)
+}
+
+func openSQLite(dbPath string) (*Database, error) {
+	db, err := sql.Open(SQLite, "file:"+dbPath+"?_foreign_keys=on&cache=shared&mode=rwc&_journal_mode=WAL&_tx_locking_mode=EXCLUSIVE&_busy_timeout=5000&_temp_store=MEMORY&_auto_vacuum=FULL&_synchronous=NORMAL")
+	if err != nil {
+		return nil, err
+	}
+
 	// Set the appropriate pragmas for SQLite
 	log.Println("NOTICE: Setting foreign keys to ON")
 	_, err = db.Exec("PRAGMA foreign_keys = ON")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("NOTICE: Setting journal mode to WAL")
 	_, err = db.Exec("PRAGMA journal_mode = WAL")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("NOTICE: Settting tx_locking mode to EXCLUSIVE")
 	log.Println("NOTICE: Setting busy timeout to 5000ms")
 	_, err = db.Exec("PRAGMA busy_timeout = 5000")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("NOTICE: Setting temp store to MEMORY")
 	_, err = db.Exec("PRAGMA temp_store = MEMORY")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("NOTICE: Setting auto_vacuum to FULL")
 	_, err = db.Exec("PRAGMA auto_vacuum = FULL")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("NOTICE: Setting synchronous to NORMAL")
 	_, err = db.Exec("PRAGMA synchronous = NORMAL")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	DB = db
-	return nil
+	return &Database{conn: db, driver: SQLite}, nil
+}
+
+func openPostgres(dataSource string) (*Database, error) {
+	// timestamps are kept without a zone and in UTC, as SQLite does, so the
+	// session zone is pinned. URLs are turned into key/value form first so
+	// the setting can be appended
+	var err error
+	if strings.HasPrefix(dataSource, "postgres://") || strings.HasPrefix(dataSource, "postgresql://") {
+		dataSource, err = pq.ParseURL(dataSource)
+		if err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open(PostgreSQL, dataSource+" timezone=UTC")
+	if err != nil {
+		return nil, err
+	}
+
+	// sql.Open is lazy, so check the server is reachable before the daemon
+	// starts serving
+	log.Println("NOTICE: Connecting to PostgreSQL")
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Database{conn: db, driver: PostgreSQL}, nil
 }
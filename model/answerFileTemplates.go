@@ -0,0 +1,233 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+)
+
+var AnswerFileTemplateTypes = []string{"kickstart", "autoyast", "preseed", "cloud-init"}
+
+func IsValidAnswerFileTemplateType(templateType string) bool {
+	for _, t := range AnswerFileTemplateTypes {
+		if t == templateType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateAnswerFileTemplate stores a template as the next version for its OS
+// family and type. Earlier versions are kept so changes can be rolled back
+func (repo *sqlRepository) CreateAnswerFileTemplate(a AnswerFileTemplate, id int) (AnswerFileTemplate, error) {
+	log.Println("INFO: Answer file template creation requested: " + a.TemplateType + " for OS family " + strconv.Itoa(a.OSFamilyId))
+	if !IsValidAnswerFileTemplateType(a.TemplateType) {
+		return AnswerFileTemplate{}, &InvalidAnswerFileTemplate{Err: errors.New("unknown template type '" + a.TemplateType + "'")}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return AnswerFileTemplate{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var version int
+	err = t.QueryRow("SELECT COALESCE(MAX(Version), 0) + 1 FROM AnswerFileTemplates WHERE OSFamilyId = ? AND TemplateType = ?",
+		a.OSFamilyId, a.TemplateType).Scan(&version)
+	if err != nil {
+		log.Println("ERROR: Cannot determine next template version: " + string(err.Error()))
+		return AnswerFileTemplate{}, err
+	}
+
+	var templateId int
+	err = t.QueryRow("INSERT INTO AnswerFileTemplates (OSFamilyId, TemplateType, Version, Content, CreatorId) VALUES (?, ?, ?, ?, ?) RETURNING Id",
+		a.OSFamilyId, a.TemplateType, version, a.Content, id).Scan(&templateId)
+	if err != nil {
+		log.Println("ERROR: Cannot create answer file template '" + a.TemplateType + "': " + string(err.Error()))
+		return AnswerFileTemplate{}, err
+	}
+
+	err = repo.auditById(t, "AnswerFileTemplates", templateId, rowSnapshot{})
+	if err != nil {
+		return AnswerFileTemplate{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return AnswerFileTemplate{}, err
+	}
+
+	log.Println("INFO: Answer file template '" + a.TemplateType + "' version " + strconv.Itoa(version) + " created")
+	return repo.GetAnswerFileTemplateById(templateId)
+}
+
+func (repo *sqlRepository) DeleteAnswerFileTemplate(templateId int) (bool, error) {
+	log.Println("INFO: Answer file template deletion requested: " + strconv.Itoa(templateId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "AnswerFileTemplates", templateId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM AnswerFileTemplates WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(templateId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete answer file template with Id '" + strconv.Itoa(templateId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "AnswerFileTemplates", templateId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Answer file template with Id '" + strconv.Itoa(templateId) + "' has been deleted")
+	return true, nil
+}
+
+func scanAnswerFileTemplate(row interface{ Scan(...any) error }) (AnswerFileTemplate, error) {
+	template := AnswerFileTemplate{}
+	err := row.Scan(
+		&template.Id,
+		&template.OSFamilyId,
+		&template.TemplateType,
+		&template.Version,
+		&template.Content,
+		&template.CreatorId,
+		&template.CreationDate,
+	)
+	if err != nil {
+		return AnswerFileTemplate{}, err
+	}
+	template.CreationDate = ConvertSqliteTimestamp(template.CreationDate)
+
+	return template, nil
+}
+
+func (repo *sqlRepository) queryAnswerFileTemplates(query string, args ...any) ([]AnswerFileTemplate, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]AnswerFileTemplate, 0)
+	for rows.Next() {
+		template, err := scanAnswerFileTemplate(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the answer file template objects!" + string(err.Error()))
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
+
+func (repo *sqlRepository) queryAnswerFileTemplate(query string, args ...any) (AnswerFileTemplate, error) {
+	template, err := scanAnswerFileTemplate(repo.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AnswerFileTemplate{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve answer file template from DB: " + string(err.Error()))
+		return AnswerFileTemplate{}, err
+	}
+
+	return template, nil
+}
+
+func (repo *sqlRepository) GetAnswerFileTemplates() ([]AnswerFileTemplate, error) {
+	log.Println("INFO: List of answer file template objects requested")
+	templates, err := repo.queryAnswerFileTemplates("SELECT * FROM AnswerFileTemplates ORDER BY OSFamilyId, TemplateType, Version")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of all answer file templates retrieved")
+	return templates, nil
+}
+
+// GetAnswerFileTemplatesByOSFamilyId returns every version of every template
+// for an OS family
+func (repo *sqlRepository) GetAnswerFileTemplatesByOSFamilyId(osFamilyId int) ([]AnswerFileTemplate, error) {
+	log.Println("INFO: Answer file templates by OS family Id requested: " + strconv.Itoa(osFamilyId))
+	templates, err := repo.queryAnswerFileTemplates("SELECT * FROM AnswerFileTemplates WHERE OSFamilyId = ? ORDER BY TemplateType, Version", osFamilyId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of answer file templates with OS family Id '" + strconv.Itoa(osFamilyId) + "' retrieved")
+	return templates, nil
+}
+
+func (repo *sqlRepository) GetAnswerFileTemplateById(templateId int) (AnswerFileTemplate, error) {
+	log.Println("INFO: Answer file template by Id requested: " + strconv.Itoa(templateId))
+	return repo.queryAnswerFileTemplate("SELECT * FROM AnswerFileTemplates WHERE Id = ?", templateId)
+}
+
+// GetLatestAnswerFileTemplate returns the current version of a template, which
+// is the one rendered for systems
+func (repo *sqlRepository) GetLatestAnswerFileTemplate(osFamilyId int, templateType string) (AnswerFileTemplate, error) {
+	log.Println("INFO: Latest '" + templateType + "' answer file template requested for OS family: " + strconv.Itoa(osFamilyId))
+	return repo.queryAnswerFileTemplate("SELECT * FROM AnswerFileTemplates WHERE OSFamilyId = ? AND TemplateType = ? ORDER BY Version DESC LIMIT 1",
+		osFamilyId, templateType)
+}
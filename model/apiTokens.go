@@ -0,0 +1,293 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApiTokenPrefix starts every API token, so they can be told apart from other
+// bearer credentials at a glance
+const ApiTokenPrefix = "adt_"
+
+// ApiTokenScopeRead is the scope a scoped token needs for requests that only
+// read. It is not a permission of roles, as every user may read what is in
+// their scope
+const ApiTokenScopeRead = "read"
+
+// Expired reports whether the token is past its expiry date. Tokens without
+// one never expire
+func (t ApiToken) Expired(now time.Time) bool {
+	if t.ExpiryDate == "" {
+		return false
+	}
+	expiry, err := ParseTimestamp(t.ExpiryDate)
+	if err != nil {
+		return true
+	}
+	return !now.Before(expiry)
+}
+
+// Allows reports whether the token's scopes cover the permission. Tokens
+// without scopes are limited only by the role of their owner
+func (t ApiToken) Allows(permission string) bool {
+	if len(t.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range t.Scopes {
+		if scope == permission || scope == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsReading reports whether the token may be used for requests that only
+// read. Tokens without scopes may, scoped ones need the read scope
+func (t ApiToken) AllowsReading() bool {
+	return t.Allows(ApiTokenScopeRead)
+}
+
+// Scoped reports whether the token is limited to some of the permissions of
+// its owner
+func (t ApiToken) Scoped() bool {
+	return len(t.Scopes) > 0
+}
+
+func scanApiToken(row interface{ Scan(...any) error }) (ApiToken, error) {
+	token := ApiToken{}
+	var scopes string
+	var expiryDate, lastUsedDate sql.NullString
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.TokenName,
+		&token.TokenHash,
+		&scopes,
+		&expiryDate,
+		&lastUsedDate,
+		&token.CreationDate,
+	)
+	if err != nil {
+		return ApiToken{}, err
+	}
+	token.Scopes = []string{}
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, ",")
+	}
+	if expiryDate.Valid {
+		token.ExpiryDate = ConvertSqliteTimestamp(expiryDate.String)
+	}
+	if lastUsedDate.Valid {
+		token.LastUsedDate = ConvertSqliteTimestamp(lastUsedDate.String)
+	}
+	token.CreationDate = ConvertSqliteTimestamp(token.CreationDate)
+
+	return token, nil
+}
+
+// CreateApiToken creates a token the user can authenticate to the API with.
+// Only the hash is stored, so the returned token cannot be retrieved again
+// later
+func (repo *sqlRepository) CreateApiToken(userId int, p ProposedApiToken) (ApiToken, string, error) {
+	log.Println("INFO: API token '" + p.TokenName + "' requested for user Id: " + strconv.Itoa(userId))
+	if strings.TrimSpace(p.TokenName) == "" {
+		return ApiToken{}, "", &InvalidApiToken{Err: errors.New("tokenName is required")}
+	}
+	for _, scope := range p.Scopes {
+		if scope != ApiTokenScopeRead && !IsValidPermission(scope) {
+			return ApiToken{}, "", &InvalidApiToken{Err: &UnknownPermission{Permission: scope}}
+		}
+	}
+	var expiryDate any
+	if p.ExpiryDate != "" {
+		parsed, err := time.Parse(time.RFC3339, p.ExpiryDate)
+		if err != nil {
+			return ApiToken{}, "", &InvalidApiToken{Err: errors.New("expiryDate must be an RFC 3339 timestamp")}
+		}
+		if !parsed.After(time.Now()) {
+			return ApiToken{}, "", &InvalidApiToken{Err: errors.New("expiryDate must be in the future")}
+		}
+		expiryDate = parsed.UTC().Format(sqliteTimeLayout)
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		log.Println("ERROR: Could not generate API token!" + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+	token := ApiTokenPrefix + secret
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var exists bool
+	err = t.QueryRow("SELECT EXISTS(SELECT 1 FROM ApiTokens WHERE UserId = ? AND TokenName = ?)", userId, p.TokenName).Scan(&exists)
+	if err != nil {
+		log.Println("ERROR: Cannot check for existing API token: " + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+	if exists {
+		err = &InvalidApiToken{Err: errors.New("a token named '" + p.TokenName + "' already exists")}
+		return ApiToken{}, "", err
+	}
+
+	var tokenId int
+	err = t.QueryRow("INSERT INTO ApiTokens (UserId, TokenName, TokenHash, Scopes, ExpiryDate) VALUES (?, ?, ?, ?, ?) RETURNING Id",
+		userId, p.TokenName, hashToken(token), strings.Join(p.Scopes, ","), expiryDate).Scan(&tokenId)
+	if err != nil {
+		log.Println("ERROR: Cannot store API token '" + p.TokenName + "': " + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+
+	err = repo.auditById(t, "ApiTokens", tokenId, rowSnapshot{})
+	if err != nil {
+		return ApiToken{}, "", err
+	}
+
+	apiToken, err := scanApiToken(t.QueryRow("SELECT * FROM ApiTokens WHERE Id = ?", tokenId))
+	if err != nil {
+		log.Println("ERROR: Cannot read back API token '" + p.TokenName + "': " + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return ApiToken{}, "", err
+	}
+
+	log.Println("INFO: API token '" + p.TokenName + "' created for user Id: " + strconv.Itoa(userId))
+	return apiToken, token, nil
+}
+
+// RevokeApiToken deletes one of a user's tokens, reporting whether they had
+// a token with that Id
+func (repo *sqlRepository) RevokeApiToken(userId int, tokenId int) (bool, error) {
+	log.Println("INFO: Revocation of API token Id " + strconv.Itoa(tokenId) + " requested for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "ApiTokens", "Id = ? AND UserId = ?", tokenId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("DELETE FROM ApiTokens WHERE Id = ? AND UserId = ?", tokenId, userId)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke API token Id " + strconv.Itoa(tokenId) + ": " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "ApiTokens", before, "Id = ? AND UserId = ?", tokenId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+func (repo *sqlRepository) GetApiTokensByUserId(userId int) ([]ApiToken, error) {
+	rows, err := repo.db.Query("SELECT * FROM ApiTokens WHERE UserId = ? ORDER BY Id", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve API tokens from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]ApiToken, 0)
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot scan the API token object!" + string(err.Error()))
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetApiTokenByToken returns the stored token matching the one presented, or
+// an empty token if it is unknown or has been revoked
+func (repo *sqlRepository) GetApiTokenByToken(token string) (ApiToken, error) {
+	if !strings.HasPrefix(token, ApiTokenPrefix) {
+		return ApiToken{}, nil
+	}
+
+	apiToken, err := scanApiToken(repo.db.QueryRow("SELECT * FROM ApiTokens WHERE TokenHash = ?", hashToken(token)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("WARN: Unknown API token presented")
+			return ApiToken{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve API token from DB: " + string(err.Error()))
+		return ApiToken{}, err
+	}
+
+	return apiToken, nil
+}
+
+// TouchApiToken records that a token has just been used. This is bookkeeping
+// rather than a change anybody made, so it is left out of the audit trail
+func (repo *sqlRepository) TouchApiToken(tokenId int) error {
+	_, err := repo.db.Exec("UPDATE ApiTokens SET LastUsedDate = ? WHERE Id = ?", time.Now().UTC().Format(sqliteTimeLayout), tokenId)
+	if err != nil {
+		log.Println("ERROR: Cannot record use of API token Id " + strconv.Itoa(tokenId) + ": " + string(err.Error()))
+	}
+	return err
+}
@@ -24,9 +24,9 @@ import (
 	"strconv"
 )
 
-func CreateArchitecture(a Architecture, id int) (bool, error) {
+func (repo *sqlRepository) CreateArchitecture(a Architecture, id int) (bool, error) {
 	log.Println("INFO: Architecture creation requested: " + a.ISEName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +42,24 @@ func CreateArchitecture(a Architecture, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Architectures (ISEName, RegisterSize, CreatorId) VALUES (?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO Architectures (ISEName, RegisterSize, KernelPath, InitrdPath, KernelArgs, BootFilename, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(a.ISEName, a.RegisterSize, id)
+	var newId int
+	err = q.QueryRow(a.ISEName, a.RegisterSize, a.KernelPath, a.InitrdPath, a.KernelArgs, a.BootFilename, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create architecture '" + a.ISEName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "Architectures", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +70,9 @@ func CreateArchitecture(a Architecture, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteArchitecture(architectureId int) (bool, error) {
+func (repo *sqlRepository) DeleteArchitecture(architectureId int) (bool, error) {
 	log.Println("INFO: Architecture deletion requested: " + strconv.Itoa(architectureId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +88,12 @@ func DeleteArchitecture(architectureId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM Architectures WHERE Id IS ?")
+	before, err := snapshotById(t, "Architectures", architectureId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Architectures WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +105,11 @@ func DeleteArchitecture(architectureId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "Architectures", architectureId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +120,9 @@ func DeleteArchitecture(architectureId int) (bool, error) {
 	return true, nil
 }
 
-func GetArchitectures() ([]Architecture, error) {
+func (repo *sqlRepository) GetArchitectures() ([]Architecture, error) {
 	log.Println("INFO: List of architecture objects requested")
-	rows, err := DB.Query("SELECT * FROM Architectures")
+	rows, err := repo.db.Query("SELECT * FROM Architectures")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -120,6 +136,10 @@ func GetArchitectures() ([]Architecture, error) {
 			&architecture.Id,
 			&architecture.ISEName,
 			&architecture.RegisterSize,
+			&architecture.KernelPath,
+			&architecture.InitrdPath,
+			&architecture.KernelArgs,
+			&architecture.BootFilename,
 			&architecture.CreatorId,
 			&architecture.CreationDate,
 		)
@@ -137,9 +157,9 @@ func GetArchitectures() ([]Architecture, error) {
 	return architectures, nil
 }
 
-func GetArchitectureById(id int) (Architecture, error) {
+func (repo *sqlRepository) GetArchitectureById(id int) (Architecture, error) {
 	log.Println("INFO: Architecture by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM Architectures WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Architectures WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Architecture{}, err
@@ -148,26 +168,23 @@ func GetArchitectureById(id int) (Architecture, error) {
 
 	architecture := Architecture{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such architecture found in DB: " + string(err.Error()))
-			return Architecture{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve architecture from DB: " + string(err.Error()))
-		return Architecture{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&architecture.Id,
 		&architecture.ISEName,
 		&architecture.RegisterSize,
+		&architecture.KernelPath,
+		&architecture.InitrdPath,
+		&architecture.KernelArgs,
+		&architecture.BootFilename,
 		&architecture.CreatorId,
 		&architecture.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the architecture object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such architecture found in DB: " + string(err.Error()))
+			return Architecture{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve architecture from DB: " + string(err.Error()))
 		return Architecture{}, err
 	}
 
@@ -177,9 +194,9 @@ func GetArchitectureById(id int) (Architecture, error) {
 	return architecture, nil
 }
 
-func GetArchitectureByName(architectureName string) (Architecture, error) {
+func (repo *sqlRepository) GetArchitectureByName(architectureName string) (Architecture, error) {
 	log.Println("INFO: Architecture by Name requested: " + architectureName)
-	rec, err := DB.Prepare("SELECT * FROM Architectures WHERE ISEName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Architectures WHERE ISEName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Architecture{}, err
@@ -203,6 +220,10 @@ func GetArchitectureByName(architectureName string) (Architecture, error) {
 		&architecture.Id,
 		&architecture.ISEName,
 		&architecture.RegisterSize,
+		&architecture.KernelPath,
+		&architecture.InitrdPath,
+		&architecture.KernelArgs,
+		&architecture.BootFilename,
 		&architecture.CreatorId,
 		&architecture.CreationDate,
 	)
@@ -0,0 +1,326 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the kinds of change recorded in the audit trail
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// the Id of the built-in SYSTEM account
+const SystemUserId = 1
+
+// systemActor is who changes are recorded against when no user is behind
+// them, such as those the scheduler makes
+var systemActor = AuditActor{UserId: SystemUserId, UserName: "SYSTEM"}
+
+// columns never copied into the audit trail. Machine token hashes are kept,
+// the tokens are random enough that their hashes give nothing away. TOTP
+// secrets are stored as they are and recovery codes are short enough to be
+// guessed from their hashes, so both are left out
+var redactedColumns = map[string]bool{
+	"passwordhash": true,
+	"secret":       true,
+	"codehash":     true,
+}
+
+// ReposFor returns repositories that record the changes made through them
+// against the given actor
+func ReposFor(actor AuditActor) Repositories {
+	return newRepositories(DB, actor)
+}
+
+// rowSnapshot is a row as it is written to the audit trail, with no row
+// meaning it does not exist
+type rowSnapshot struct {
+	id  int
+	row []byte
+}
+
+// snapshotRow reads the single row of a table matching the condition, inside
+// the transaction making the change, as a JSON object keyed by column
+func snapshotRow(t *Tx, table string, where string, args ...any) (rowSnapshot, error) {
+	snapshot, err := readRow(t, table, where, args...)
+	if err != nil {
+		log.Println("ERROR: Cannot read the changed row of " + table + " for the audit trail: " + string(err.Error()))
+		return rowSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+func readRow(t *Tx, table string, where string, args ...any) (rowSnapshot, error) {
+	rows, err := t.Query("SELECT * FROM "+table+" WHERE "+where, args...)
+	if err != nil {
+		return rowSnapshot{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return rowSnapshot{}, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return rowSnapshot{}, err
+	}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	err = rows.Scan(pointers...)
+	if err != nil {
+		return rowSnapshot{}, err
+	}
+
+	snapshot := rowSnapshot{}
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		// PostgreSQL hands back column names folded to lower case
+		if strings.EqualFold(column, "Id") {
+			if id, ok := values[i].(int64); ok {
+				snapshot.id = int(id)
+			}
+		}
+		if redactedColumns[strings.ToLower(column)] {
+			continue
+		}
+		switch value := values[i].(type) {
+		case []byte:
+			row[column] = string(value)
+		case time.Time:
+			row[column] = value.UTC().Format("2006-01-02 15:04:05")
+		default:
+			row[column] = value
+		}
+	}
+
+	snapshot.row, err = json.Marshal(row)
+	if err != nil {
+		return rowSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+func snapshotById(t *Tx, table string, id int) (rowSnapshot, error) {
+	return snapshotRow(t, table, "Id = ?", id)
+}
+
+// auditById records the change to the row of a table with the given Id, as
+// it stands now against the snapshot taken before
+func (repo *sqlRepository) auditById(t *Tx, table string, id int, before rowSnapshot) error {
+	return repo.auditRow(t, table, before, "Id = ?", id)
+}
+
+// auditRow is auditById for rows found by another key
+func (repo *sqlRepository) auditRow(t *Tx, table string, before rowSnapshot, where string, args ...any) error {
+	after, err := snapshotRow(t, table, where, args...)
+	if err != nil {
+		return err
+	}
+	return repo.audit(t, table, before, after)
+}
+
+// execAudited runs a statement changing the row of a table with the given Id
+// in a transaction of its own, recording the change
+func (repo *sqlRepository) execAudited(table string, id int, query string, args ...any) error {
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, table, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	err = repo.auditById(t, table, id, before)
+	if err != nil {
+		return err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+	}
+	return err
+}
+
+func nullableRow(row []byte) any {
+	if row == nil {
+		return nil
+	}
+	return string(row)
+}
+
+// audit records a change to a row in the same transaction that made it. What
+// kind of change it was follows from which snapshots hold a row, and changes
+// that left the row as it was are not recorded
+func (repo *sqlRepository) audit(t *Tx, table string, before rowSnapshot, after rowSnapshot) error {
+	changeClass := AuditUpdate
+	entityId := after.id
+	switch {
+	case before.row == nil && after.row == nil:
+		return nil
+	case before.row == nil:
+		changeClass = AuditCreate
+	case after.row == nil:
+		changeClass = AuditDelete
+		entityId = before.id
+	case bytes.Equal(before.row, after.row):
+		return nil
+	}
+
+	_, err := t.Exec("INSERT INTO Audit (ChangedById, ChangedByName, TableChanged, ChangeClass, ChangeDate, EntityId, RequestId, RowBefore, RowAfter) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?)",
+		repo.actor.UserId, repo.actor.UserName, table, changeClass, entityId, repo.actor.RequestId, nullableRow(before.row), nullableRow(after.row))
+	if err != nil {
+		log.Println("ERROR: Cannot record the change to " + table + " in the audit trail: " + string(err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+func scanAuditEntry(scan func(dest ...any) error) (AuditEntry, error) {
+	entry := AuditEntry{}
+	var rowBefore, rowAfter sql.NullString
+	err := scan(
+		&entry.Id,
+		&entry.ChangedById,
+		&entry.ChangedByName,
+		&entry.TableChanged,
+		&entry.ChangeClass,
+		&entry.ChangeDate,
+		&entry.EntityId,
+		&entry.RequestId,
+		&rowBefore,
+		&rowAfter,
+	)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+
+	entry.ChangeDate = ConvertSqliteTimestamp(entry.ChangeDate)
+	if rowBefore.Valid {
+		entry.RowBefore = json.RawMessage(rowBefore.String)
+	}
+	if rowAfter.Valid {
+		entry.RowAfter = json.RawMessage(rowAfter.String)
+	}
+
+	return entry, nil
+}
+
+const auditColumns = "Id, ChangedById, ChangedByName, TableChanged, ChangeClass, ChangeDate, EntityId, RequestId, RowBefore, RowAfter"
+
+// GetAuditEntries returns the audit trail, oldest first, narrowed down by
+// every field of the filter that is set. The time range is inclusive and
+// given in UTC as 'YYYY-MM-DD HH:MM:SS'
+func (repo *sqlRepository) GetAuditEntries(f AuditFilter) ([]AuditEntry, error) {
+	log.Println("INFO: Audit trail requested")
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	if f.TableChanged != "" {
+		conditions = append(conditions, "TableChanged = ?")
+		args = append(args, f.TableChanged)
+	}
+	if f.ChangedById != 0 {
+		conditions = append(conditions, "ChangedById = ?")
+		args = append(args, f.ChangedById)
+	}
+	if f.EntityId != 0 {
+		conditions = append(conditions, "EntityId = ?")
+		args = append(args, f.EntityId)
+	}
+	if f.From != "" {
+		conditions = append(conditions, "ChangeDate >= ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		conditions = append(conditions, "ChangeDate <= ?")
+		args = append(args, f.To)
+	}
+
+	query := "SELECT " + auditColumns + " FROM Audit"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY Id"
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows.Scan)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the audit entry objects!" + string(err.Error()))
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	log.Println("INFO: " + strconv.Itoa(len(entries)) + " audit entries retrieved")
+	return entries, rows.Err()
+}
+
+func (repo *sqlRepository) GetAuditEntryById(entryId int) (AuditEntry, error) {
+	log.Println("INFO: Audit entry by Id requested: " + strconv.Itoa(entryId))
+	entry, err := scanAuditEntry(repo.db.QueryRow("SELECT "+auditColumns+" FROM Audit WHERE Id = ?", entryId).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such audit entry found in DB: " + string(err.Error()))
+			return AuditEntry{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve audit entry from DB: " + string(err.Error()))
+		return AuditEntry{}, err
+	}
+
+	log.Println("INFO: Audit entry with Id '" + strconv.Itoa(entryId) + "' has been retrieved")
+	return entry, nil
+}
@@ -0,0 +1,67 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"strconv"
+)
+
+// GetBootTargetByMACAddress looks up everything needed to build a network boot
+// script for the machine owning the given MAC address. An empty BootTarget is
+// returned if no interface or system is registered for the address, or the
+// system is still waiting for approval
+func (repo *sqlRepository) GetBootTargetByMACAddress(macAddress string) (BootTarget, error) {
+	log.Println("INFO: Boot target requested for MAC address: " + macAddress)
+	networkInterface, err := repo.GetNetworkInterfaceByMACAddress(macAddress)
+	if err != nil {
+		return BootTarget{}, err
+	}
+	if networkInterface.MACAddress == "" {
+		return BootTarget{}, nil
+	}
+
+	system, err := repo.GetSystemById(networkInterface.SystemId)
+	if err != nil {
+		return BootTarget{}, err
+	}
+	if system.SerialNumber == "" || system.Status == SystemStatusPending {
+		return BootTarget{}, nil
+	}
+
+	operatingSystem, err := repo.GetOperatingSystemById(system.OperatingSystemId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve operating system for system '" + system.SerialNumber + "': " + string(err.Error()))
+		return BootTarget{}, err
+	}
+
+	architecture, err := repo.GetArchitectureById(system.ArchitectureId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve architecture for system '" + system.SerialNumber + "': " + string(err.Error()))
+		return BootTarget{}, err
+	}
+
+	log.Println("INFO: Boot target for MAC address '" + macAddress + "' is system " + strconv.Itoa(system.Id))
+	return BootTarget{
+		System:          system,
+		Interface:       networkInterface,
+		OperatingSystem: operatingSystem,
+		Architecture:    architecture,
+	}, nil
+}
@@ -25,9 +25,9 @@ import (
 	"strconv"
 )
 
-func CreateBuilding(b Building, id int) (bool, error) {
+func (repo *sqlRepository) CreateBuilding(b Building, id int) (bool, error) {
 	log.Println("INFO: Building creation requested: " + b.BuildingName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -43,18 +43,24 @@ func CreateBuilding(b Building, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Buildings (BuildingName, ShortName, City, Region, CreatorId) VALUES (?, ?, ?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO Buildings (BuildingName, ShortName, City, Region, CreatorId) VALUES (?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(b.BuildingName, b.ShortName, b.City, b.Region, id)
+	var newId int
+	err = q.QueryRow(b.BuildingName, b.ShortName, b.City, b.Region, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create building '" + b.BuildingName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "Buildings", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -65,9 +71,9 @@ func CreateBuilding(b Building, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteBuilding(buildingId int) (bool, error) {
+func (repo *sqlRepository) DeleteBuilding(buildingId int) (bool, error) {
 	log.Println("INFO: Building deletion requested: " + strconv.Itoa(buildingId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -83,7 +89,12 @@ func DeleteBuilding(buildingId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM Buildings WHERE Id IS ?")
+	before, err := snapshotById(t, "Buildings", buildingId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Buildings WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -95,6 +106,11 @@ func DeleteBuilding(buildingId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "Buildings", buildingId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -105,9 +121,9 @@ func DeleteBuilding(buildingId int) (bool, error) {
 	return true, nil
 }
 
-func GetBuildings() ([]Building, error) {
+func (repo *sqlRepository) GetBuildings() ([]Building, error) {
 	log.Println("INFO: List of building object requested")
-	rows, err := DB.Query("SELECT * FROM Buildings")
+	rows, err := repo.db.Query("SELECT * FROM Buildings")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -140,9 +156,9 @@ func GetBuildings() ([]Building, error) {
 	return buildings, nil
 }
 
-func GetBuildingById(id int) (Building, error) {
+func (repo *sqlRepository) GetBuildingById(id int) (Building, error) {
 	log.Println("INFO: Building by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM Buildings WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Buildings WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Building{}, err
@@ -150,18 +166,7 @@ func GetBuildingById(id int) (Building, error) {
 	defer rec.Close()
 
 	building := Building{}
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such building found in DB: " + string(err.Error()))
-			return Building{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve building from DB: " + string(err.Error()))
-		return Building{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&building.Id,
 		&building.BuildingName,
 		&building.ShortName,
@@ -171,7 +176,11 @@ func GetBuildingById(id int) (Building, error) {
 		&building.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot marshal the building objects!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such building found in DB: " + string(err.Error()))
+			return Building{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve building from DB: " + string(err.Error()))
 		return Building{}, err
 	}
 
@@ -181,9 +190,9 @@ func GetBuildingById(id int) (Building, error) {
 	return building, nil
 }
 
-func GetBuildingByShortName(buildingShortName string) (Building, error) {
+func (repo *sqlRepository) GetBuildingByShortName(buildingShortName string) (Building, error) {
 	log.Println("INFO: Building by Short Name requested: " + buildingShortName)
-	rec, err := DB.Prepare("SELECT * FROM Buildings WHERE ShortName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Buildings WHERE ShortName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Building{}, err
@@ -223,9 +232,9 @@ func GetBuildingByShortName(buildingShortName string) (Building, error) {
 	return building, nil
 }
 
-func UpdateBuildingById(buildingId int, b Building) (bool, error) {
+func (repo *sqlRepository) UpdateBuildingById(buildingId int, b Building) (bool, error) {
 	log.Println("INFO: Update building by Id requested: " + strconv.Itoa(buildingId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		return false, err
 	}
@@ -240,6 +249,11 @@ func UpdateBuildingById(buildingId int, b Building) (bool, error) {
 		}
 	}()
 
+	before, err := snapshotById(t, "Buildings", buildingId)
+	if err != nil {
+		return false, err
+	}
+
 	q, err := t.Prepare("UPDATE Buildings SET BuildingName = ?, ShortName = ?, City = ?, Region = ? WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
@@ -258,6 +272,11 @@ func UpdateBuildingById(buildingId int, b Building) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "Buildings", buildingId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -0,0 +1,150 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Database is a connection pool along with the backend it talks to. Queries
+// are written once with ? placeholders in the subset of SQL that SQLite and
+// PostgreSQL share, and are rewritten for the backend as they are run
+type Database struct {
+	conn   *sql.DB
+	driver string
+}
+
+// Tx is a transaction started from a Database
+type Tx struct {
+	tx *sql.Tx
+	db *Database
+}
+
+// Driver returns the database/sql driver name of the backend
+func (d *Database) Driver() string {
+	return d.driver
+}
+
+// SQL exposes the underlying pool for callers, such as the schema
+// migrations, that write backend specific statements themselves
+func (d *Database) SQL() *sql.DB {
+	return d.conn
+}
+
+func (d *Database) Close() error {
+	return d.conn.Close()
+}
+
+// rebind turns ? placeholders into the numbered $N ones PostgreSQL expects,
+// leaving quoted literals and identifiers alone
+func (d *Database) rebind(query string) string {
+	if d.driver != PostgreSQL || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+func (d *Database) Begin() (*Tx, error) {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, db: d}, nil
+}
+
+func (d *Database) Prepare(query string) (*sql.Stmt, error) {
+	return d.conn.Prepare(d.rebind(query))
+}
+
+func (d *Database) Exec(query string, args ...any) (sql.Result, error) {
+	return d.conn.Exec(d.rebind(query), args...)
+}
+
+func (d *Database) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.conn.Query(d.rebind(query), args...)
+}
+
+func (d *Database) QueryRow(query string, args ...any) *sql.Row {
+	return d.conn.QueryRow(d.rebind(query), args...)
+}
+
+func (t *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return t.tx.Prepare(t.db.rebind(query))
+}
+
+func (t *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.Exec(t.db.rebind(query), args...)
+}
+
+func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.tx.Query(t.db.rebind(query), args...)
+}
+
+func (t *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRow(t.db.rebind(query), args...)
+}
+
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// isUniqueViolation reports whether the backend refused a statement because
+// it would have broken a unique index
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return false
+}
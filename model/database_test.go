@@ -0,0 +1,52 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"no placeholders", "SELECT * FROM Users", "SELECT * FROM Users"},
+		{"numbered in order", "UPDATE Users SET FullName = ?, Status = ? WHERE Id = ?",
+			"UPDATE Users SET FullName = $1, Status = $2 WHERE Id = $3"},
+		{"string literal left alone", "SELECT Id FROM Users WHERE FullName = 'who?' AND Id = ?",
+			"SELECT Id FROM Users WHERE FullName = 'who?' AND Id = $1"},
+		{"quoted identifier left alone", `SELECT "odd?" FROM Users WHERE Id = ?`,
+			`SELECT "odd?" FROM Users WHERE Id = $1`},
+		{"returning", "INSERT INTO Vendors (VendorName, CreatorId) VALUES (?, ?) RETURNING Id",
+			"INSERT INTO Vendors (VendorName, CreatorId) VALUES ($1, $2) RETURNING Id"},
+	}
+
+	postgres := &Database{driver: PostgreSQL}
+	sqlite := &Database{driver: SQLite}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postgres.rebind(tt.query); got != tt.want {
+				t.Errorf("PostgreSQL rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+			if got := sqlite.rebind(tt.query); got != tt.query {
+				t.Errorf("SQLite rebind(%q) = %q, want it unchanged", tt.query, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+
+	"github.com/greeneg/allocatord/dhcp"
+)
+
+// GetDhcpHosts returns a reservation for every network interface, with the
+// boot file name taken from the architecture of the system it belongs to.
+// Interfaces of systems waiting for approval get no reservation
+func (repo *sqlRepository) GetDhcpHosts() ([]dhcp.Host, error) {
+	log.Println("INFO: DHCP host reservations requested")
+	rows, err := repo.db.Query(`SELECT s.SerialNumber, n.DeviceId, n.MACAddress, n.IpAddress, n.Bitmask, n.Gateway, COALESCE(a.BootFilename, '')
+		FROM NetworkInterfaces n
+		JOIN Systems s ON s.Id = n.SystemId
+		LEFT JOIN Architectures a ON a.Id = s.ArchitectureId
+		WHERE s.Status = 'active'`)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	hosts := make([]dhcp.Host, 0)
+	for rows.Next() {
+		host := dhcp.Host{}
+		err = rows.Scan(
+			&host.SerialNumber,
+			&host.DeviceId,
+			&host.MACAddress,
+			&host.IpAddress,
+			&host.Bitmask,
+			&host.Gateway,
+			&host.BootFilename,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the DHCP host objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	log.Println("INFO: List of all DHCP host reservations retrieved")
+	return hosts, nil
+}
@@ -0,0 +1,546 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Anybody can report hardware, so what they report is kept to sizes real
+// hardware fits in
+const (
+	maxDiscoveredInterfaces = 64
+	maxDiscoveredDisks      = 128
+	maxDiscoveryFieldLength = 255
+)
+
+// checkDiscoveryFieldLengths turns away a report with any of the given fields
+// longer than maxDiscoveryFieldLength
+func checkDiscoveryFieldLengths(fields map[string]string) error {
+	for name, value := range fields {
+		if len(value) > maxDiscoveryFieldLength {
+			return &InvalidDiscoveryReport{Err: errors.New(name + " cannot be longer than " + strconv.Itoa(maxDiscoveryFieldLength) + " characters")}
+		}
+	}
+	return nil
+}
+
+// validateDiscoveryReport checks what an imaging client reported, and
+// normalizes the MAC addresses so they compare the way the boot lookup does
+func validateDiscoveryReport(r *DiscoveryReport) error {
+	r.SerialNumber = strings.TrimSpace(r.SerialNumber)
+	if r.SerialNumber == "" {
+		return &InvalidDiscoveryReport{Err: errors.New("serialNumber is required")}
+	}
+	err := checkDiscoveryFieldLengths(map[string]string{
+		"serialNumber": r.SerialNumber,
+		"vendor":       r.Vendor,
+		"model":        r.Model,
+		"architecture": r.Architecture,
+	})
+	if err != nil {
+		return err
+	}
+	if r.CpuCores < 0 || r.RAM < 0 {
+		return &InvalidDiscoveryReport{Err: errors.New("cpuCores and ram cannot be negative")}
+	}
+	if len(r.NetworkInterfaces) == 0 {
+		return &InvalidDiscoveryReport{Err: errors.New("at least one network interface is required")}
+	}
+	if len(r.NetworkInterfaces) > maxDiscoveredInterfaces {
+		return &InvalidDiscoveryReport{Err: errors.New("no more than " + strconv.Itoa(maxDiscoveredInterfaces) + " network interfaces can be reported")}
+	}
+	if len(r.Disks) > maxDiscoveredDisks {
+		return &InvalidDiscoveryReport{Err: errors.New("no more than " + strconv.Itoa(maxDiscoveredDisks) + " disks can be reported")}
+	}
+
+	seen := make(map[string]bool)
+	for i, n := range r.NetworkInterfaces {
+		err = checkDiscoveryFieldLengths(map[string]string{
+			"deviceModel": n.DeviceModel,
+			"deviceId":    n.DeviceId,
+		})
+		if err != nil {
+			return err
+		}
+		mac, err := NormalizeMACAddress(n.MACAddress)
+		if err != nil {
+			return &InvalidDiscoveryReport{Err: err}
+		}
+		if seen[mac] {
+			return &InvalidDiscoveryReport{Err: errors.New("MAC address " + mac + " was reported twice")}
+		}
+		seen[mac] = true
+		r.NetworkInterfaces[i].MACAddress = mac
+	}
+	for _, d := range r.Disks {
+		if strings.TrimSpace(d.DeviceId) == "" {
+			return &InvalidDiscoveryReport{Err: errors.New("every disk needs a deviceId")}
+		}
+		err = checkDiscoveryFieldLengths(map[string]string{
+			"deviceModel": d.DeviceModel,
+			"deviceId":    d.DeviceId,
+			"storageType": d.StorageType,
+		})
+		if err != nil {
+			return err
+		}
+		if d.Size < 0 {
+			return &InvalidDiscoveryReport{Err: errors.New("disk '" + d.DeviceId + "' cannot have a negative size")}
+		}
+	}
+
+	return nil
+}
+
+// lookupIdByName resolves a name the imaging client reported to the Id of a
+// record the allocator knows of. Names it does not know are left NULL for
+// the operator approving the system to fill in
+func lookupIdByName(t *Tx, query string, name string) (sql.NullInt64, error) {
+	var id sql.NullInt64
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return id, nil
+	}
+	err := t.QueryRow(query, name).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot look up '" + name + "': " + string(err.Error()))
+		return id, err
+	}
+	return id, nil
+}
+
+// DiscoverSystem records hardware an imaging client booted on that the
+// allocator did not know of, as a system waiting for an operator to approve
+// it, along with its network interfaces and disks
+func (repo *sqlRepository) DiscoverSystem(r DiscoveryReport) (System, error) {
+	log.Println("INFO: Discovery reported for serial number: " + r.SerialNumber)
+	err := validateDiscoveryReport(&r)
+	if err != nil {
+		return System{}, err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return System{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var status string
+	err = t.QueryRow("SELECT Status FROM Systems WHERE SerialNumber = ?", r.SerialNumber).Scan(&status)
+	if err == nil {
+		if status == SystemStatusPending {
+			err = &SystemAlreadyKnown{Err: errors.New("serial number " + r.SerialNumber + " is already waiting for approval")}
+		} else {
+			err = &SystemAlreadyKnown{Err: errors.New("serial number " + r.SerialNumber + " belongs to a system")}
+		}
+		return System{}, err
+	}
+	if err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot check for a system with serial number '" + r.SerialNumber + "': " + string(err.Error()))
+		return System{}, err
+	}
+	for _, n := range r.NetworkInterfaces {
+		// MAC addresses are stored normalized, the same as the boot lookup
+		// looks them up
+		var owner int
+		err = t.QueryRow("SELECT SystemId FROM NetworkInterfaces WHERE MACAddress = ? LIMIT 1", n.MACAddress).Scan(&owner)
+		if err == nil {
+			err = &SystemAlreadyKnown{Err: errors.New("MAC address " + n.MACAddress + " belongs to system " + strconv.Itoa(owner))}
+			return System{}, err
+		}
+		if err != sql.ErrNoRows {
+			log.Println("ERROR: Cannot check for a network interface with MAC address '" + n.MACAddress + "': " + string(err.Error()))
+			return System{}, err
+		}
+	}
+
+	vendorId, err := lookupIdByName(t, "SELECT Id FROM Vendors WHERE VendorName = ?", r.Vendor)
+	if err != nil {
+		return System{}, err
+	}
+	architectureId, err := lookupIdByName(t, "SELECT Id FROM Architectures WHERE ISEName = ?", r.Architecture)
+	if err != nil {
+		return System{}, err
+	}
+	modelId, err := lookupIdByName(t, "SELECT Id FROM SystemModels WHERE ModelName = ?", r.Model)
+	if err != nil {
+		return System{}, err
+	}
+
+	var systemId int
+	err = t.QueryRow("INSERT INTO Systems (SerialNumber, ModelId, HostVars, VendorId, ArchitectureId, RAM, CPUCores, CreatorId, Status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+		r.SerialNumber, modelId, "{}", vendorId, architectureId, r.RAM, r.CpuCores, SystemUserId, SystemStatusPending).Scan(&systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot create system '" + r.SerialNumber + "': " + string(err.Error()))
+		return System{}, err
+	}
+	err = repo.auditById(t, "Systems", systemId, rowSnapshot{})
+	if err != nil {
+		return System{}, err
+	}
+
+	for _, n := range r.NetworkInterfaces {
+		var interfaceId int
+		err = t.QueryRow("INSERT INTO NetworkInterfaces (DeviceModel, DeviceId, MACAddress, SystemId, IpAddress, Bitmask, Gateway, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+			n.DeviceModel, n.DeviceId, n.MACAddress, systemId, "", 0, "", SystemUserId).Scan(&interfaceId)
+		if err != nil {
+			log.Println("ERROR: Cannot create network interface '" + n.MACAddress + "': " + string(err.Error()))
+			return System{}, err
+		}
+		err = repo.auditById(t, "NetworkInterfaces", interfaceId, rowSnapshot{})
+		if err != nil {
+			return System{}, err
+		}
+	}
+
+	for _, d := range r.Disks {
+		var volumeId int
+		err = t.QueryRow("INSERT INTO StorageVolumes (VolumeName, StorageType, DeviceModel, DeviceId, MountPoint, VolumeSize, VolumeFormat, VolumeLabel, SystemId, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+			d.DeviceId, d.StorageType, d.DeviceModel, d.DeviceId, "", d.Size, "", "", systemId, SystemUserId).Scan(&volumeId)
+		if err != nil {
+			log.Println("ERROR: Cannot create storage volume '" + d.DeviceId + "': " + string(err.Error()))
+			return System{}, err
+		}
+		err = repo.auditById(t, "StorageVolumes", volumeId, rowSnapshot{})
+		if err != nil {
+			return System{}, err
+		}
+	}
+
+	var discoveryId int
+	err = t.QueryRow("INSERT INTO SystemDiscoveries (SystemId, ReportedVendor, ReportedModel, ReportedArchitecture, ClientAddress) VALUES (?, ?, ?, ?, ?) RETURNING Id",
+		systemId, r.Vendor, r.Model, r.Architecture, r.ClientAddress).Scan(&discoveryId)
+	if err != nil {
+		log.Println("ERROR: Cannot record the discovery of system '" + r.SerialNumber + "': " + string(err.Error()))
+		return System{}, err
+	}
+	err = repo.auditById(t, "SystemDiscoveries", discoveryId, rowSnapshot{})
+	if err != nil {
+		return System{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return System{}, err
+	}
+
+	log.Println("INFO: System '" + r.SerialNumber + "' discovered, Id " + strconv.Itoa(systemId) + " waiting for approval")
+	return repo.GetSystemById(systemId)
+}
+
+// recordExists reports whether a table has a row with the given Id
+func (repo *sqlRepository) recordExists(table string, id int) (bool, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE Id = ?)", id).Scan(&exists)
+	if err != nil {
+		log.Println("ERROR: Cannot look up Id " + strconv.Itoa(id) + " in " + table + ": " + string(err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+// ApproveSystem assigns a pending system the organizational unit, building,
+// machine role and operating system the operator picked, and makes it an
+// active system. Model, vendor and architecture only need to be given when
+// what the imaging client reported did not match any known to the allocator
+func (repo *sqlRepository) ApproveSystem(systemId int, a SystemApproval) (bool, error) {
+	log.Println("INFO: Approval requested for discovered system: " + strconv.Itoa(systemId))
+	s, err := repo.GetSystemById(systemId)
+	if err != nil {
+		return false, err
+	}
+	if s.Id == 0 || s.Status != SystemStatusPending {
+		return false, &NoPendingSystem{Err: errors.New("system " + strconv.Itoa(systemId) + " is not waiting for approval")}
+	}
+
+	s.BilledToOrgUnitId = a.BilledToOrgUnitId
+	s.BuildingId = a.BuildingId
+	s.MachineRoleId = a.MachineRoleId
+	s.OperatingSystemId = a.OperatingSystemId
+	if a.ModelId != 0 {
+		s.ModelId = a.ModelId
+	}
+	if a.VendorId != 0 {
+		s.VendorId = a.VendorId
+	}
+	if a.ArchitectureId != 0 {
+		s.ArchitectureId = a.ArchitectureId
+	}
+	if strings.TrimSpace(a.HostVars) != "" {
+		s.HostVars = a.HostVars
+	}
+
+	required := []struct {
+		field string
+		table string
+		id    int
+	}{
+		{"billedToOrgUnitId", "OrganizationalUnits", s.BilledToOrgUnitId},
+		{"buildingId", "Buildings", s.BuildingId},
+		{"machineRoleId", "MachineRoles", s.MachineRoleId},
+		{"osId", "OperatingSystems", s.OperatingSystemId},
+		{"modelId", "SystemModels", s.ModelId},
+		{"vendorId", "Vendors", s.VendorId},
+		{"architectureId", "Architectures", s.ArchitectureId},
+	}
+	for _, r := range required {
+		if r.id == 0 {
+			return false, &InvalidSystemApproval{Err: errors.New(r.field + " is required")}
+		}
+		exists, err := repo.recordExists(r.table, r.id)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, &InvalidSystemApproval{Err: errors.New(r.field + " " + strconv.Itoa(r.id) + " does not exist")}
+		}
+	}
+	err = repo.validateSystemHostVars(s)
+	if err != nil {
+		return false, err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("UPDATE Systems SET ModelId = ?, OperatingSystemId = ?, HostVars = ?, BilledToOrgUnitId = ?, MachineRoleId = ?, BuildingId = ?, VendorId = ?, ArchitectureId = ?, Status = ? WHERE Id = ? AND Status = ?",
+		s.ModelId, s.OperatingSystemId, s.HostVars, s.BilledToOrgUnitId, s.MachineRoleId, s.BuildingId,
+		s.VendorId, s.ArchitectureId, SystemStatusActive, systemId, SystemStatusPending)
+	if err != nil {
+		log.Println("ERROR: Cannot approve system with Id '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return false, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if changed == 0 {
+		err = &NoPendingSystem{Err: errors.New("system " + strconv.Itoa(systemId) + " is not waiting for approval")}
+		return false, err
+	}
+
+	err = repo.auditById(t, "Systems", systemId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: System '" + s.SerialNumber + "' approved")
+	return true, nil
+}
+
+// deleteSystemRows deletes the rows of a table that belong to a system,
+// auditing each of them
+func (repo *sqlRepository) deleteSystemRows(t *Tx, table string, systemId int) error {
+	rows, err := t.Query("SELECT Id FROM "+table+" WHERE SystemId = ?", systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot list the " + table + " of system " + strconv.Itoa(systemId) + ": " + string(err.Error()))
+		return err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		before, err := snapshotById(t, table, id)
+		if err != nil {
+			return err
+		}
+		_, err = t.Exec("DELETE FROM "+table+" WHERE Id = ?", id)
+		if err != nil {
+			log.Println("ERROR: Cannot delete Id " + strconv.Itoa(id) + " from " + table + ": " + string(err.Error()))
+			return err
+		}
+		err = repo.auditById(t, table, id, before)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RejectSystem throws away a system waiting for approval, along with the
+// network interfaces and disks its imaging client reported. Should the
+// hardware boot again it is discovered anew
+func (repo *sqlRepository) RejectSystem(systemId int) (bool, error) {
+	log.Println("INFO: Rejection requested for discovered system: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var pending bool
+	err = t.QueryRow("SELECT EXISTS(SELECT 1 FROM Systems WHERE Id = ? AND Status = ?)",
+		systemId, SystemStatusPending).Scan(&pending)
+	if err != nil {
+		log.Println("ERROR: Cannot check for a pending system: " + string(err.Error()))
+		return false, err
+	}
+	if !pending {
+		t.Rollback()
+		return false, nil
+	}
+
+	for _, table := range []string{"NetworkInterfaces", "StorageVolumes", "SystemDiscoveries"} {
+		err = repo.deleteSystemRows(t, table, systemId)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	before, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return false, err
+	}
+	_, err = t.Exec("DELETE FROM Systems WHERE Id = ?", systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete system with Id '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return false, err
+	}
+	err = repo.auditById(t, "Systems", systemId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Discovered system with Id '" + strconv.Itoa(systemId) + "' has been rejected")
+	return true, nil
+}
+
+// discoveredSystem adds what the imaging client reported to a system
+func (repo *sqlRepository) discoveredSystem(s System) (DiscoveredSystem, error) {
+	d := DiscoveredSystem{System: s}
+	err := repo.db.QueryRow("SELECT ReportedVendor, ReportedModel, ReportedArchitecture, ClientAddress, CreationDate FROM SystemDiscoveries WHERE SystemId = ?",
+		s.Id).Scan(&d.ReportedVendor, &d.ReportedModel, &d.ReportedArchitecture, &d.ClientAddress, &d.DiscoveryDate)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot retrieve the discovery of system " + strconv.Itoa(s.Id) + ": " + string(err.Error()))
+		return DiscoveredSystem{}, err
+	}
+	if d.DiscoveryDate != "" {
+		d.DiscoveryDate = ConvertSqliteTimestamp(d.DiscoveryDate)
+	}
+
+	d.NetworkInterfaces, err = repo.GetNetworkInterfacesBySystemId(s.Id)
+	if err != nil {
+		return DiscoveredSystem{}, err
+	}
+	d.StorageVolumes, err = repo.GetStorageVolumesBySystemId(s.Id)
+	if err != nil {
+		return DiscoveredSystem{}, err
+	}
+
+	return d, nil
+}
+
+func (repo *sqlRepository) GetPendingSystems() ([]DiscoveredSystem, error) {
+	log.Println("INFO: List of systems waiting for approval requested")
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE Status = ?", SystemStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]DiscoveredSystem, 0)
+	for _, s := range systems {
+		d, err := repo.discoveredSystem(s)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, d)
+	}
+
+	log.Println("INFO: List of systems waiting for approval retrieved")
+	return pending, nil
+}
+
+// GetDiscoveredSystem returns a system along with what its imaging client
+// reported when it was discovered. An empty DiscoveredSystem is returned if
+// there is no such system
+func (repo *sqlRepository) GetDiscoveredSystem(systemId int) (DiscoveredSystem, error) {
+	log.Println("INFO: Discovered system requested: " + strconv.Itoa(systemId))
+	s, err := repo.GetSystemById(systemId)
+	if err != nil || s.Id == 0 {
+		return DiscoveredSystem{}, err
+	}
+
+	return repo.discoveredSystem(s)
+}
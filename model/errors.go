@@ -33,3 +33,228 @@ type PasswordHashMismatch struct {
 func (p *PasswordHashMismatch) Error() string {
 	return "Password hashes do not match!"
 }
+
+type PasswordNotResettable struct {
+	Err error
+}
+
+func (p *PasswordNotResettable) Error() string {
+	return "Password cannot be reset: " + p.Err.Error()
+}
+
+type AccountLockedOut struct {
+	Err error
+}
+
+func (a *AccountLockedOut) Error() string {
+	return "Account is locked out after too many failed logins: " + a.Err.Error()
+}
+
+type ExternalAccountConflict struct {
+	Err error
+}
+
+func (e *ExternalAccountConflict) Error() string {
+	return "Account belongs to another identity: " + e.Err.Error()
+}
+
+type MachineTokenExists struct {
+	Err error
+}
+
+func (m *MachineTokenExists) Error() string {
+	return "A machine token has already been issued for this system!"
+}
+
+type NoSuchMachineToken struct {
+	Err error
+}
+
+func (n *NoSuchMachineToken) Error() string {
+	return "No machine token has been issued for this system!"
+}
+
+type InvalidMACAddress struct {
+	Err error
+}
+
+func (i *InvalidMACAddress) Error() string {
+	return "Invalid MAC address: " + i.Err.Error()
+}
+
+type InvalidDiscoveryReport struct {
+	Err error
+}
+
+func (i *InvalidDiscoveryReport) Error() string {
+	return "Invalid discovery report: " + i.Err.Error()
+}
+
+type SystemAlreadyKnown struct {
+	Err error
+}
+
+func (s *SystemAlreadyKnown) Error() string {
+	return "Hardware is already known: " + s.Err.Error()
+}
+
+type NoPendingSystem struct {
+	Err error
+}
+
+func (n *NoPendingSystem) Error() string {
+	return "No system with this Id is waiting for approval!"
+}
+
+type SystemNotApproved struct {
+	Err error
+}
+
+func (s *SystemNotApproved) Error() string {
+	return "System is waiting for approval!"
+}
+
+type InvalidSystemApproval struct {
+	Err error
+}
+
+func (i *InvalidSystemApproval) Error() string {
+	return "Invalid system approval: " + i.Err.Error()
+}
+
+type InvalidMachineCertificateRequest struct {
+	Err error
+}
+
+func (i *InvalidMachineCertificateRequest) Error() string {
+	return "Invalid certificate request: " + i.Err.Error()
+}
+
+type MachineCertificateRequestPending struct {
+	Err error
+}
+
+func (m *MachineCertificateRequestPending) Error() string {
+	return "A certificate request is already pending for this system!"
+}
+
+type NoPendingMachineCertificate struct {
+	Err error
+}
+
+func (n *NoPendingMachineCertificate) Error() string {
+	return "No certificate request with this Id is pending!"
+}
+
+type InvalidReimageTransition struct {
+	From string
+	To   string
+}
+
+func (i *InvalidReimageTransition) Error() string {
+	return "Invalid reimage state transition from '" + i.From + "' to '" + i.To + "'!"
+}
+
+type ReimageInProgress struct {
+	Err error
+}
+
+func (r *ReimageInProgress) Error() string {
+	return "A reimage is already in progress for this system!"
+}
+
+type NoActiveReimage struct {
+	Err error
+}
+
+func (n *NoActiveReimage) Error() string {
+	return "No reimage is in progress for this system!"
+}
+
+type InvalidMaintenanceWindow struct {
+	Err error
+}
+
+func (i *InvalidMaintenanceWindow) Error() string {
+	return "Maintenance windows need a weekday from 0 to 6, a start time as HH:MM and a duration of up to one week in minutes!"
+}
+
+type InvalidReimageBatch struct {
+	Err error
+}
+
+func (i *InvalidReimageBatch) Error() string {
+	return "Invalid reimage batch: " + i.Err.Error()
+}
+
+type ReimageBatchNotCancellable struct {
+	Err error
+}
+
+func (r *ReimageBatchNotCancellable) Error() string {
+	return "Reimage batch does not exist or has already finished!"
+}
+
+type InvalidAnswerFileTemplate struct {
+	Err error
+}
+
+func (i *InvalidAnswerFileTemplate) Error() string {
+	return "Invalid answer file template: " + i.Err.Error()
+}
+
+type InvalidHostVars struct {
+	Err error
+}
+
+func (i *InvalidHostVars) Error() string {
+	return "Invalid host vars: " + i.Err.Error()
+}
+
+type InvalidHostVarsSchema struct {
+	Err error
+}
+
+func (i *InvalidHostVarsSchema) Error() string {
+	return "Invalid host vars schema: " + i.Err.Error()
+}
+
+type UnknownPermission struct {
+	Permission string
+}
+
+func (u *UnknownPermission) Error() string {
+	return "Unknown permission '" + u.Permission + "'!"
+}
+
+type InvalidOrgUnitDelegation struct {
+	Err error
+}
+
+func (i *InvalidOrgUnitDelegation) Error() string {
+	return "Invalid organizational unit delegation: " + i.Err.Error()
+}
+
+type InvalidOrgUnitHierarchy struct {
+	Err error
+}
+
+func (i *InvalidOrgUnitHierarchy) Error() string {
+	return "Invalid organizational unit hierarchy: " + i.Err.Error()
+}
+
+type PasswordPolicyViolation struct {
+	Err error
+}
+
+func (p *PasswordPolicyViolation) Error() string {
+	return "Password does not meet the password policy: " + p.Err.Error()
+}
+
+type InvalidApiToken struct {
+	Err error
+}
+
+func (i *InvalidApiToken) Error() string {
+	return "Invalid API token: " + i.Err.Error()
+}
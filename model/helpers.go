@@ -26,3 +26,17 @@ func ConvertSqliteTimestamp(t string) string {
 	createTime, _ := time.Parse(sqlTimestampFormat, t)
 	return createTime.Format(timeFormat)
 }
+
+// ParseTimestamp reads a timestamp as ConvertSqliteTimestamp formats it
+func ParseTimestamp(t string) (time.Time, error) {
+	return time.Parse(sqliteTimeLayout, t)
+}
+
+// nullableId stores unset (zero) foreign keys as NULL, which satisfies
+// REFERENCES constraints on optional columns
+func nullableId(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,492 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/greeneg/allocatord/hostvars"
+)
+
+// the system layer lives in Systems.HostVars, every other layer is stored
+// here keyed by scope and the Id of the record it applies to
+func (repo *sqlRepository) validateHostVarLayer(l HostVarLayer) error {
+	if !hostvars.IsValidScope(l.Scope) || l.Scope == hostvars.ScopeSystem {
+		return &InvalidHostVars{Err: errors.New("unknown scope '" + l.Scope + "'")}
+	}
+	if l.Scope == hostvars.ScopeGlobal {
+		if l.ScopeId != 0 {
+			return &InvalidHostVars{Err: errors.New("the global layer takes scope Id 0")}
+		}
+	} else {
+		var exists bool
+		switch l.Scope {
+		case hostvars.ScopeOrgUnit:
+			ou, err := repo.GetOUById(l.ScopeId)
+			if err != nil {
+				return err
+			}
+			exists = ou.OUName != ""
+		case hostvars.ScopeBuilding:
+			building, err := repo.GetBuildingById(l.ScopeId)
+			if err != nil {
+				return err
+			}
+			exists = building.BuildingName != ""
+		case hostvars.ScopeMachineRole:
+			machineRole, err := repo.GetMachineRoleById(l.ScopeId)
+			if err != nil {
+				return err
+			}
+			exists = machineRole.MachineRoleName != ""
+		}
+		if !exists {
+			return &InvalidHostVars{Err: errors.New("no " + l.Scope + " found with Id " + strconv.Itoa(l.ScopeId))}
+		}
+	}
+
+	_, err := hostvars.Parse(l.HostVars)
+	if err != nil {
+		return &InvalidHostVars{Err: err}
+	}
+
+	return nil
+}
+
+// SetHostVarLayer creates the layer for a scope or replaces its variables
+func (repo *sqlRepository) SetHostVarLayer(l HostVarLayer, id int) (bool, error) {
+	log.Println("INFO: Host var layer update requested: " + l.Scope + " " + strconv.Itoa(l.ScopeId))
+	err := repo.validateHostVarLayer(l)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(l.HostVars) == "" {
+		l.HostVars = "{}"
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "HostVarLayers", "Scope = ? AND ScopeId = ?", l.Scope, l.ScopeId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("INSERT INTO HostVarLayers (Scope, ScopeId, HostVars, CreatorId) VALUES (?, ?, ?, ?) ON CONFLICT (Scope, ScopeId) DO UPDATE SET HostVars = excluded.HostVars")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(l.Scope, l.ScopeId, l.HostVars, id)
+	if err != nil {
+		log.Println("ERROR: Cannot set host var layer '" + l.Scope + " " + strconv.Itoa(l.ScopeId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "HostVarLayers", before, "Scope = ? AND ScopeId = ?", l.Scope, l.ScopeId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Host var layer '" + l.Scope + " " + strconv.Itoa(l.ScopeId) + "' has been set")
+	return true, nil
+}
+
+func (repo *sqlRepository) DeleteHostVarLayer(scope string, scopeId int) (bool, error) {
+	log.Println("INFO: Host var layer deletion requested: " + scope + " " + strconv.Itoa(scopeId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "HostVarLayers", "Scope = ? AND ScopeId = ?", scope, scopeId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM HostVarLayers WHERE Scope = ? AND ScopeId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(scope, scopeId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete host var layer '" + scope + " " + strconv.Itoa(scopeId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "HostVarLayers", before, "Scope = ? AND ScopeId = ?", scope, scopeId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Host var layer '" + scope + " " + strconv.Itoa(scopeId) + "' has been deleted")
+	return true, nil
+}
+
+func (repo *sqlRepository) GetHostVarLayers() ([]HostVarLayer, error) {
+	log.Println("INFO: List of host var layer objects requested")
+	rows, err := repo.db.Query("SELECT * FROM HostVarLayers")
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	layers := make([]HostVarLayer, 0)
+	for rows.Next() {
+		layer := HostVarLayer{}
+		err = rows.Scan(
+			&layer.Id,
+			&layer.Scope,
+			&layer.ScopeId,
+			&layer.HostVars,
+			&layer.CreatorId,
+			&layer.CreationDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the host var layer objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		layer.CreationDate = ConvertSqliteTimestamp(layer.CreationDate)
+
+		layers = append(layers, layer)
+	}
+
+	log.Println("INFO: List of all host var layers retrieved")
+	return layers, nil
+}
+
+func (repo *sqlRepository) GetHostVarLayer(scope string, scopeId int) (HostVarLayer, error) {
+	log.Println("INFO: Host var layer requested: " + scope + " " + strconv.Itoa(scopeId))
+	rec, err := repo.db.Prepare("SELECT * FROM HostVarLayers WHERE Scope = ? AND ScopeId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return HostVarLayer{}, err
+	}
+	defer rec.Close()
+
+	layer := HostVarLayer{}
+	err = rec.QueryRow(scope, scopeId).Scan(
+		&layer.Id,
+		&layer.Scope,
+		&layer.ScopeId,
+		&layer.HostVars,
+		&layer.CreatorId,
+		&layer.CreationDate,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return HostVarLayer{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve host var layer from DB: " + string(err.Error()))
+		return HostVarLayer{}, err
+	}
+
+	layer.CreationDate = ConvertSqliteTimestamp(layer.CreationDate)
+
+	log.Println("INFO: Host var layer '" + scope + " " + strconv.Itoa(scopeId) + "' retrieved")
+	return layer, nil
+}
+
+// SetMachineRoleHostVarsSchema attaches a JSON Schema to a machine role,
+// replacing any previous one
+func (repo *sqlRepository) SetMachineRoleHostVarsSchema(machineRoleId int, schema string, id int) (bool, error) {
+	log.Println("INFO: Host vars schema update requested for machine role: " + strconv.Itoa(machineRoleId))
+	err := hostvars.ValidateSchema(schema)
+	if err != nil {
+		return false, &InvalidHostVarsSchema{Err: err}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "MachineRoleHostVarsSchemas", "MachineRoleId = ?", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("INSERT INTO MachineRoleHostVarsSchemas (MachineRoleId, HostVarsSchema, CreatorId) VALUES (?, ?, ?) ON CONFLICT (MachineRoleId) DO UPDATE SET HostVarsSchema = excluded.HostVarsSchema")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(machineRoleId, schema, id)
+	if err != nil {
+		log.Println("ERROR: Cannot set host vars schema for machine role '" + strconv.Itoa(machineRoleId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "MachineRoleHostVarsSchemas", before, "MachineRoleId = ?", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Host vars schema for machine role '" + strconv.Itoa(machineRoleId) + "' has been set")
+	return true, nil
+}
+
+func (repo *sqlRepository) DeleteMachineRoleHostVarsSchema(machineRoleId int) (bool, error) {
+	log.Println("INFO: Host vars schema deletion requested for machine role: " + strconv.Itoa(machineRoleId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "MachineRoleHostVarsSchemas", "MachineRoleId = ?", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM MachineRoleHostVarsSchemas WHERE MachineRoleId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(machineRoleId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete host vars schema for machine role '" + strconv.Itoa(machineRoleId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "MachineRoleHostVarsSchemas", before, "MachineRoleId = ?", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Host vars schema for machine role '" + strconv.Itoa(machineRoleId) + "' has been deleted")
+	return true, nil
+}
+
+func (repo *sqlRepository) GetMachineRoleHostVarsSchema(machineRoleId int) (MachineRoleHostVarsSchema, error) {
+	log.Println("INFO: Host vars schema requested for machine role: " + strconv.Itoa(machineRoleId))
+	rec, err := repo.db.Prepare("SELECT * FROM MachineRoleHostVarsSchemas WHERE MachineRoleId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return MachineRoleHostVarsSchema{}, err
+	}
+	defer rec.Close()
+
+	schema := MachineRoleHostVarsSchema{}
+	err = rec.QueryRow(machineRoleId).Scan(
+		&schema.Id,
+		&schema.MachineRoleId,
+		&schema.HostVarsSchema,
+		&schema.CreatorId,
+		&schema.CreationDate,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return MachineRoleHostVarsSchema{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve host vars schema from DB: " + string(err.Error()))
+		return MachineRoleHostVarsSchema{}, err
+	}
+
+	schema.CreationDate = ConvertSqliteTimestamp(schema.CreationDate)
+
+	log.Println("INFO: Host vars schema for machine role '" + strconv.Itoa(machineRoleId) + "' retrieved")
+	return schema, nil
+}
+
+// hostVarLayersForSystem collects the layers that apply to a system, from
+// least to most specific. Org unit variables come from the unit the system
+// is billed to
+func (repo *sqlRepository) hostVarLayersForSystem(s System) ([]hostvars.Layer, error) {
+	scopes := []HostVarLayer{
+		{Scope: hostvars.ScopeGlobal, ScopeId: 0},
+		{Scope: hostvars.ScopeOrgUnit, ScopeId: s.BilledToOrgUnitId},
+		{Scope: hostvars.ScopeBuilding, ScopeId: s.BuildingId},
+		{Scope: hostvars.ScopeMachineRole, ScopeId: s.MachineRoleId},
+	}
+
+	layers := make([]hostvars.Layer, 0, len(scopes)+1)
+	for _, scope := range scopes {
+		layer, err := repo.GetHostVarLayer(scope.Scope, scope.ScopeId)
+		if err != nil {
+			return nil, err
+		}
+		if layer.Id == 0 {
+			continue
+		}
+		layers = append(layers, hostvars.Layer{Scope: layer.Scope, ScopeId: layer.ScopeId, Vars: layer.HostVars})
+	}
+	layers = append(layers, hostvars.Layer{Scope: hostvars.ScopeSystem, ScopeId: s.Id, Vars: s.HostVars})
+
+	return layers, nil
+}
+
+// ResolveHostVars merges the variable layers of a system and checks the
+// result against its machine role's schema. A system whose own HostVars are
+// not a JSON object is reported as an InvalidHostVars error
+func (repo *sqlRepository) ResolveHostVars(s System) (ResolvedHostVars, error) {
+	log.Println("INFO: Resolved host vars requested for system: " + strconv.Itoa(s.Id))
+	layers, err := repo.hostVarLayersForSystem(s)
+	if err != nil {
+		return ResolvedHostVars{}, err
+	}
+
+	resolved, err := hostvars.Resolve(layers)
+	if err != nil {
+		return ResolvedHostVars{}, &InvalidHostVars{Err: err}
+	}
+
+	result := ResolvedHostVars{
+		SystemId:      s.Id,
+		MachineRoleId: s.MachineRoleId,
+		Vars:          make(map[string]ResolvedHostVar, len(resolved)),
+		Valid:         true,
+		Violations:    make([]string, 0),
+	}
+	for key, value := range resolved {
+		result.Vars[key] = ResolvedHostVar{Value: value.Value, Scope: value.Scope, ScopeId: value.ScopeId}
+	}
+
+	schema, err := repo.GetMachineRoleHostVarsSchema(s.MachineRoleId)
+	if err != nil {
+		return ResolvedHostVars{}, err
+	}
+	if schema.Id != 0 {
+		violations, err := hostvars.Validate(schema.HostVarsSchema, hostvars.Flatten(resolved))
+		if err != nil {
+			return ResolvedHostVars{}, &InvalidHostVarsSchema{Err: err}
+		}
+		result.Violations = violations
+		result.Valid = len(violations) == 0
+	}
+
+	log.Println("INFO: Host vars for system '" + strconv.Itoa(s.Id) + "' resolved")
+	return result, nil
+}
+
+// resolvedHostVarsJSON is the merged variables of a system without their
+// sources, as handed to imaging clients and answer file templates
+func (repo *sqlRepository) resolvedHostVarsJSON(s System) (string, error) {
+	resolved, err := repo.ResolveHostVars(s)
+	if err != nil {
+		return "", err
+	}
+
+	vars := make(map[string]any, len(resolved.Vars))
+	for key, value := range resolved.Vars {
+		vars[key] = value.Value
+	}
+	out, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// validateSystemHostVars refuses systems whose HostVars are not a JSON
+// object or whose merged variables break their machine role's schema
+func (repo *sqlRepository) validateSystemHostVars(s System) error {
+	resolved, err := repo.ResolveHostVars(s)
+	if err != nil {
+		return err
+	}
+	if !resolved.Valid {
+		return &InvalidHostVars{Err: errors.New(strings.Join(resolved.Violations, "; "))}
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/greeneg/allocatord/machineca"
+)
+
+const (
+	MachineCertificatePending  = "pending"
+	MachineCertificateIssued   = "issued"
+	MachineCertificateRejected = "rejected"
+	MachineCertificateRevoked  = "revoked"
+)
+
+func IsValidMachineCertificateStatus(status string) bool {
+	switch status {
+	case MachineCertificatePending, MachineCertificateIssued, MachineCertificateRejected, MachineCertificateRevoked:
+		return true
+	}
+	return false
+}
+
+func scanMachineCertificate(row interface{ Scan(...any) error }) (MachineCertificate, error) {
+	certificate := MachineCertificate{}
+	var pemCertificate, fingerprint, notAfter sql.NullString
+	err := row.Scan(
+		&certificate.Id,
+		&certificate.SystemId,
+		&certificate.Status,
+		&certificate.CertificateRequest,
+		&pemCertificate,
+		&fingerprint,
+		&notAfter,
+		&certificate.ClientAddress,
+		&certificate.CreationDate,
+		&certificate.UpdatedDate,
+	)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+	certificate.Certificate = pemCertificate.String
+	certificate.Fingerprint = fingerprint.String
+	if notAfter.Valid {
+		certificate.NotAfter = ConvertSqliteTimestamp(notAfter.String)
+	}
+	certificate.CreationDate = ConvertSqliteTimestamp(certificate.CreationDate)
+	certificate.UpdatedDate = ConvertSqliteTimestamp(certificate.UpdatedDate)
+
+	return certificate, nil
+}
+
+// systemSerialNumber returns the serial number client certificates of a
+// system are issued to
+func systemSerialNumber(t *Tx, systemId int) (string, error) {
+	var serialNumber string
+	err := t.QueryRow("SELECT SerialNumber FROM Systems WHERE Id = ?", systemId).Scan(&serialNumber)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", &InvalidMachineCertificateRequest{Err: errors.New("no system with Id " + strconv.Itoa(systemId))}
+		}
+		log.Println("ERROR: Cannot retrieve system from DB: " + string(err.Error()))
+		return "", err
+	}
+	return serialNumber, nil
+}
+
+// RequestMachineCertificate files the certificate request a system sent while
+// enrolling, to wait for an operator to approve or reject it. Sending a
+// request again returns the one on file, whatever has become of it, so
+// systems check on their enrollment by repeating it. A system can only have
+// one request pending at a time
+func (repo *sqlRepository) RequestMachineCertificate(systemId int, p ProposedMachineCertificate) (MachineCertificate, error) {
+	log.Println("INFO: Machine certificate requested for system: " + strconv.Itoa(systemId))
+	_, err := machineca.ParseRequest(p.CertificateRequest)
+	if err != nil {
+		return MachineCertificate{}, &InvalidMachineCertificateRequest{Err: err}
+	}
+	p.CertificateRequest = strings.TrimSpace(p.CertificateRequest)
+
+	filed, err := repo.getMachineCertificates("SystemId = ? AND CertificateRequest = ?", systemId, p.CertificateRequest)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+	if len(filed) > 0 {
+		return filed[len(filed)-1], nil
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var pending bool
+	err = t.QueryRow("SELECT EXISTS(SELECT 1 FROM MachineCertificates WHERE SystemId = ? AND Status = ?)",
+		systemId, MachineCertificatePending).Scan(&pending)
+	if err != nil {
+		log.Println("ERROR: Cannot check for pending machine certificate requests: " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+	if pending {
+		err = &MachineCertificateRequestPending{Err: errors.New("certificate request pending for system " + strconv.Itoa(systemId))}
+		return MachineCertificate{}, err
+	}
+
+	var certificateId int
+	err = t.QueryRow("INSERT INTO MachineCertificates (SystemId, Status, CertificateRequest, ClientAddress) VALUES (?, ?, ?, ?) RETURNING Id",
+		systemId, MachineCertificatePending, p.CertificateRequest, p.ClientAddress).Scan(&certificateId)
+	if err != nil {
+		log.Println("ERROR: Cannot store machine certificate request for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	err = repo.auditById(t, "MachineCertificates", certificateId, rowSnapshot{})
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	log.Println("INFO: Machine certificate request Id " + strconv.Itoa(certificateId) + " pending for system '" + strconv.Itoa(systemId) + "'")
+	return repo.GetMachineCertificateById(certificateId)
+}
+
+// IssueMachineCertificate issues a client certificate to a system straight
+// away, without a request waiting for approval first. Certificates the
+// system already holds stay valid until they expire or are revoked
+func (repo *sqlRepository) IssueMachineCertificate(systemId int, p ProposedMachineCertificate) (MachineCertificate, error) {
+	log.Println("INFO: Machine certificate issue requested for system: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	serialNumber, err := systemSerialNumber(t, systemId)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+	issued, err := machineca.Issue(p.CertificateRequest, serialNumber)
+	if err != nil {
+		err = &InvalidMachineCertificateRequest{Err: err}
+		return MachineCertificate{}, err
+	}
+
+	var certificateId int
+	err = t.QueryRow("INSERT INTO MachineCertificates (SystemId, Status, CertificateRequest, Certificate, Fingerprint, NotAfter, ClientAddress) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+		systemId, MachineCertificateIssued, p.CertificateRequest, issued.PEM, issued.Fingerprint,
+		issued.NotAfter.UTC().Format(sqliteTimeLayout), p.ClientAddress).Scan(&certificateId)
+	if err != nil {
+		log.Println("ERROR: Cannot store machine certificate for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	err = repo.auditById(t, "MachineCertificates", certificateId, rowSnapshot{})
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	log.Println("INFO: Machine certificate Id " + strconv.Itoa(certificateId) + " issued for system '" + strconv.Itoa(systemId) + "'")
+	return repo.GetMachineCertificateById(certificateId)
+}
+
+// ApproveMachineCertificate issues the certificate a pending request asked
+// for, to the serial number the system has at the time of approval
+func (repo *sqlRepository) ApproveMachineCertificate(certificateId int) (MachineCertificate, error) {
+	log.Println("INFO: Approval requested for machine certificate request Id: " + strconv.Itoa(certificateId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	request, err := scanMachineCertificate(t.QueryRow("SELECT * FROM MachineCertificates WHERE Id = ?", certificateId))
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot retrieve machine certificate request from DB: " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+	if err == sql.ErrNoRows || request.Status != MachineCertificatePending {
+		err = &NoPendingMachineCertificate{Err: errors.New("no pending request with Id " + strconv.Itoa(certificateId))}
+		return MachineCertificate{}, err
+	}
+
+	serialNumber, err := systemSerialNumber(t, request.SystemId)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+	issued, err := machineca.Issue(request.CertificateRequest, serialNumber)
+	if err != nil {
+		err = &InvalidMachineCertificateRequest{Err: err}
+		return MachineCertificate{}, err
+	}
+
+	before, err := snapshotById(t, "MachineCertificates", certificateId)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+
+	_, err = t.Exec("UPDATE MachineCertificates SET Status = ?, Certificate = ?, Fingerprint = ?, NotAfter = ?, UpdatedDate = CURRENT_TIMESTAMP WHERE Id = ?",
+		MachineCertificateIssued, issued.PEM, issued.Fingerprint, issued.NotAfter.UTC().Format(sqliteTimeLayout), certificateId)
+	if err != nil {
+		log.Println("ERROR: Cannot store machine certificate Id " + strconv.Itoa(certificateId) + ": " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	err = repo.auditById(t, "MachineCertificates", certificateId, before)
+	if err != nil {
+		return MachineCertificate{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	log.Println("INFO: Machine certificate Id " + strconv.Itoa(certificateId) + " issued for system '" + strconv.Itoa(request.SystemId) + "'")
+	return repo.GetMachineCertificateById(certificateId)
+}
+
+// setMachineCertificateStatus moves a certificate or request from one status
+// to another, reporting whether it had the status it was expected to have
+func (repo *sqlRepository) setMachineCertificateStatus(certificateId int, from string, to string) (bool, error) {
+	log.Println("INFO: Machine certificate Id " + strconv.Itoa(certificateId) + " to be " + to)
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "MachineCertificates", certificateId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("UPDATE MachineCertificates SET Status = ?, UpdatedDate = CURRENT_TIMESTAMP WHERE Id = ? AND Status = ?",
+		to, certificateId, from)
+	if err != nil {
+		log.Println("ERROR: Cannot update machine certificate Id " + strconv.Itoa(certificateId) + ": " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "MachineCertificates", certificateId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+// RejectMachineCertificate turns down a pending certificate request,
+// reporting whether there was one with that Id
+func (repo *sqlRepository) RejectMachineCertificate(certificateId int) (bool, error) {
+	return repo.setMachineCertificateStatus(certificateId, MachineCertificatePending, MachineCertificateRejected)
+}
+
+// RevokeMachineCertificate stops an issued certificate from being accepted,
+// reporting whether there was one with that Id
+func (repo *sqlRepository) RevokeMachineCertificate(certificateId int) (bool, error) {
+	return repo.setMachineCertificateStatus(certificateId, MachineCertificateIssued, MachineCertificateRevoked)
+}
+
+func (repo *sqlRepository) GetMachineCertificateById(certificateId int) (MachineCertificate, error) {
+	certificate, err := scanMachineCertificate(repo.db.QueryRow("SELECT * FROM MachineCertificates WHERE Id = ?", certificateId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return MachineCertificate{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve machine certificate from DB: " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	return certificate, nil
+}
+
+// GetMachineCertificateByFingerprint returns the certificate with a
+// fingerprint, or an empty one if none was issued with it
+func (repo *sqlRepository) GetMachineCertificateByFingerprint(fingerprint string) (MachineCertificate, error) {
+	certificate, err := scanMachineCertificate(repo.db.QueryRow("SELECT * FROM MachineCertificates WHERE Fingerprint = ?", fingerprint))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("WARN: Unknown machine certificate presented")
+			return MachineCertificate{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve machine certificate from DB: " + string(err.Error()))
+		return MachineCertificate{}, err
+	}
+
+	return certificate, nil
+}
+
+func (repo *sqlRepository) getMachineCertificates(where string, args ...any) ([]MachineCertificate, error) {
+	rows, err := repo.db.Query("SELECT * FROM MachineCertificates WHERE "+where+" ORDER BY Id", args...)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve machine certificates from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	certificates := make([]MachineCertificate, 0)
+	for rows.Next() {
+		certificate, err := scanMachineCertificate(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot scan the machine certificate object!" + string(err.Error()))
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+func (repo *sqlRepository) GetMachineCertificatesBySystemId(systemId int) ([]MachineCertificate, error) {
+	return repo.getMachineCertificates("SystemId = ?", systemId)
+}
+
+func (repo *sqlRepository) GetMachineCertificatesByStatus(status string) ([]MachineCertificate, error) {
+	return repo.getMachineCertificates("Status = ?", status)
+}
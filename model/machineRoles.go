@@ -25,9 +25,9 @@ import (
 	"strconv"
 )
 
-func CreateMachineRole(m MachineRole, id int) (bool, error) {
+func (repo *sqlRepository) CreateMachineRole(m MachineRole, id int) (bool, error) {
 	log.Println("INFO: Machine Role creation requested: " + m.MachineRoleName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -43,18 +43,24 @@ func CreateMachineRole(m MachineRole, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO MachineRoles (MachineRoleName, Description, CreatorId) VALUES (?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO MachineRoles (MachineRoleName, Description, CreatorId) VALUES (?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(m.MachineRoleName, id)
+	var newId int
+	err = q.QueryRow(m.MachineRoleName, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create machine role '" + m.MachineRoleName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "MachineRoles", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -65,9 +71,9 @@ func CreateMachineRole(m MachineRole, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteMachineRole(machineRoleId int) (bool, error) {
+func (repo *sqlRepository) DeleteMachineRole(machineRoleId int) (bool, error) {
 	log.Println("INFO: Machine Role deletion requested: " + strconv.Itoa(machineRoleId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -83,7 +89,12 @@ func DeleteMachineRole(machineRoleId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM MachineRoles WHERE Id IS ?")
+	before, err := snapshotById(t, "MachineRoles", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM MachineRoles WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -95,6 +106,11 @@ func DeleteMachineRole(machineRoleId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "MachineRoles", machineRoleId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -105,9 +121,9 @@ func DeleteMachineRole(machineRoleId int) (bool, error) {
 	return true, nil
 }
 
-func GetMachineRoles() ([]MachineRole, error) {
+func (repo *sqlRepository) GetMachineRoles() ([]MachineRole, error) {
 	log.Println("INFO: List of machine role objects requested")
-	rows, err := DB.Query("SELECT * FROM MachineRoles")
+	rows, err := repo.db.Query("SELECT * FROM MachineRoles")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -138,9 +154,9 @@ func GetMachineRoles() ([]MachineRole, error) {
 	return machineRoles, nil
 }
 
-func GetMachineRoleById(id int) (MachineRole, error) {
+func (repo *sqlRepository) GetMachineRoleById(id int) (MachineRole, error) {
 	log.Println("INFO: Machine role by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM MachineRoles WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM MachineRoles WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return MachineRole{}, err
@@ -149,18 +165,7 @@ func GetMachineRoleById(id int) (MachineRole, error) {
 
 	machineRole := MachineRole{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such machine role found in DB: " + string(err.Error()))
-			return MachineRole{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve machine role from DB: " + string(err.Error()))
-		return MachineRole{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&machineRole.Id,
 		&machineRole.MachineRoleName,
 		&machineRole.Description,
@@ -168,7 +173,11 @@ func GetMachineRoleById(id int) (MachineRole, error) {
 		&machineRole.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the machine role objects!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such machine role found in DB: " + string(err.Error()))
+			return MachineRole{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve machine role from DB: " + string(err.Error()))
 		return MachineRole{}, err
 	}
 
@@ -178,9 +187,9 @@ func GetMachineRoleById(id int) (MachineRole, error) {
 	return machineRole, nil
 }
 
-func GetMachineRoleByName(machineRoleName string) (MachineRole, error) {
+func (repo *sqlRepository) GetMachineRoleByName(machineRoleName string) (MachineRole, error) {
 	log.Println("INFO: Machine Role by Name requested: " + machineRoleName)
-	rec, err := DB.Prepare("SELECT * FROM MachineRoles WHERE MachineRoleName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM MachineRoles WHERE MachineRoleName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return MachineRole{}, err
@@ -218,9 +227,9 @@ func GetMachineRoleByName(machineRoleName string) (MachineRole, error) {
 	return machineRole, nil
 }
 
-func UpdateMachineRoleById(machineRoleId int, m MachineRole) (bool, error) {
+func (repo *sqlRepository) UpdateMachineRoleById(machineRoleId int, m MachineRole) (bool, error) {
 	log.Println("INFO: Update machine role by Id requested: " + strconv.Itoa(machineRoleId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -236,6 +245,11 @@ func UpdateMachineRoleById(machineRoleId int, m MachineRole) (bool, error) {
 		}
 	}()
 
+	before, err := snapshotById(t, "MachineRoles", machineRoleId)
+	if err != nil {
+		return false, err
+	}
+
 	q, err := t.Prepare("UPDATE MachineRoles SET MachineRoleName = ?, Description =? WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
@@ -254,6 +268,11 @@ func UpdateMachineRoleById(machineRoleId int, m MachineRole) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "MachineRoles", machineRoleId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
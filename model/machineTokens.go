@@ -0,0 +1,266 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+)
+
+// machine and API tokens are random, so a plain SHA-256 is enough to keep the
+// stored value from being usable if the DB leaks
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// IssueMachineToken creates the token a system uses to authenticate against
+// the machine facing API. Only the hash is stored, so the returned token
+// cannot be retrieved again later
+func (repo *sqlRepository) IssueMachineToken(systemId int, id int) (string, error) {
+	log.Println("INFO: Machine token requested for system: " + strconv.Itoa(systemId))
+	token, err := generateToken()
+	if err != nil {
+		log.Println("ERROR: Could not generate machine token!" + string(err.Error()))
+		return "", err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return "", err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "MachineTokens", "SystemId = ?", systemId)
+	if err != nil {
+		return "", err
+	}
+
+	var exists bool
+	err = t.QueryRow("SELECT EXISTS(SELECT 1 FROM MachineTokens WHERE SystemId = ?)", systemId).Scan(&exists)
+	if err != nil {
+		log.Println("ERROR: Cannot check for existing machine token: " + string(err.Error()))
+		return "", err
+	}
+	if exists {
+		err = &MachineTokenExists{Err: errors.New("token exists for system " + strconv.Itoa(systemId))}
+		return "", err
+	}
+
+	q, err := t.Prepare("INSERT INTO MachineTokens (SystemId, TokenHash, CreatorId) VALUES (?, ?, ?)")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return "", err
+	}
+
+	_, err = q.Exec(systemId, hashToken(token), id)
+	if err != nil {
+		log.Println("ERROR: Cannot store machine token for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return "", err
+	}
+
+	err = repo.auditRow(t, "MachineTokens", before, "SystemId = ?", systemId)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return "", err
+	}
+
+	log.Println("INFO: Machine token issued for system '" + strconv.Itoa(systemId) + "'")
+	return token, nil
+}
+
+// RotateMachineToken replaces a system's existing token with a new one. The
+// old token stops working as soon as this returns
+func (repo *sqlRepository) RotateMachineToken(systemId int, id int) (string, error) {
+	log.Println("INFO: Machine token rotation requested for system: " + strconv.Itoa(systemId))
+	token, err := generateToken()
+	if err != nil {
+		log.Println("ERROR: Could not generate machine token!" + string(err.Error()))
+		return "", err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return "", err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "MachineTokens", "SystemId = ?", systemId)
+	if err != nil {
+		return "", err
+	}
+
+	q, err := t.Prepare("UPDATE MachineTokens SET TokenHash = ?, CreatorId = ?, CreationDate = CURRENT_TIMESTAMP WHERE SystemId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return "", err
+	}
+
+	result, err := q.Exec(hashToken(token), id, systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot rotate machine token for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return "", err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return "", err
+	}
+	if numberOfRows == 0 {
+		err = &NoSuchMachineToken{Err: errors.New("no token for system " + strconv.Itoa(systemId))}
+		return "", err
+	}
+
+	err = repo.auditRow(t, "MachineTokens", before, "SystemId = ?", systemId)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return "", err
+	}
+
+	log.Println("INFO: Machine token rotated for system '" + strconv.Itoa(systemId) + "'")
+	return token, nil
+}
+
+func (repo *sqlRepository) RevokeMachineToken(systemId int) (bool, error) {
+	log.Println("INFO: Machine token revocation requested for system: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "MachineTokens", "SystemId = ?", systemId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM MachineTokens WHERE SystemId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	result, err := q.Exec(systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke machine token for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+	if numberOfRows == 0 {
+		err = &NoSuchMachineToken{Err: errors.New("no token for system " + strconv.Itoa(systemId))}
+		return false, err
+	}
+
+	err = repo.auditRow(t, "MachineTokens", before, "SystemId = ?", systemId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Machine token revoked for system '" + strconv.Itoa(systemId) + "'")
+	return true, nil
+}
+
+// GetSystemIdByMachineToken returns the Id of the system owning a token, or 0
+// if the token is unknown or has been revoked
+func (repo *sqlRepository) GetSystemIdByMachineToken(token string) (int, error) {
+	rec, err := repo.db.Prepare("SELECT SystemId FROM MachineTokens WHERE TokenHash = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return 0, err
+	}
+	defer rec.Close()
+
+	systemId := 0
+	err = rec.QueryRow(hashToken(token)).Scan(&systemId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("WARN: Unknown machine token presented")
+			return 0, nil
+		}
+		log.Println("ERROR: Cannot retrieve machine token from DB: " + string(err.Error()))
+		return 0, err
+	}
+
+	return systemId, nil
+}
@@ -0,0 +1,219 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+)
+
+const minutesPerWeek = 7 * 24 * 60
+
+// maintenance windows recur weekly. Weekday follows time.Weekday (0 is
+// Sunday) and StartTime is HH:MM, both in UTC
+func validateMaintenanceWindow(w MaintenanceWindow) error {
+	if w.Weekday < 0 || w.Weekday > 6 {
+		return &InvalidMaintenanceWindow{Err: errors.New("weekday out of range")}
+	}
+	if _, err := time.Parse("15:04", w.StartTime); err != nil {
+		return &InvalidMaintenanceWindow{Err: err}
+	}
+	if w.DurationMinutes <= 0 || w.DurationMinutes > minutesPerWeek {
+		return &InvalidMaintenanceWindow{Err: errors.New("duration out of range")}
+	}
+
+	return nil
+}
+
+// IsMaintenanceWindowOpen reports whether the given time falls inside any of
+// the windows. Windows may run past midnight or the end of the week
+func IsMaintenanceWindowOpen(windows []MaintenanceWindow, now time.Time) bool {
+	now = now.UTC()
+	nowMinute := int(now.Weekday())*24*60 + now.Hour()*60 + now.Minute()
+	for _, w := range windows {
+		start, err := time.Parse("15:04", w.StartTime)
+		if err != nil {
+			continue
+		}
+		startMinute := w.Weekday*24*60 + start.Hour()*60 + start.Minute()
+		if (nowMinute-startMinute+minutesPerWeek)%minutesPerWeek < w.DurationMinutes {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (repo *sqlRepository) CreateMaintenanceWindow(w MaintenanceWindow, id int) (bool, error) {
+	log.Println("INFO: Maintenance window creation requested for building: " + strconv.Itoa(w.BuildingId))
+	err := validateMaintenanceWindow(w)
+	if err != nil {
+		return false, err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	q, err := t.Prepare("INSERT INTO MaintenanceWindows (BuildingId, Weekday, StartTime, DurationMinutes, CreatorId) VALUES (?, ?, ?, ?, ?) RETURNING Id")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	var newId int
+	err = q.QueryRow(w.BuildingId, w.Weekday, w.StartTime, w.DurationMinutes, id).Scan(&newId)
+	if err != nil {
+		log.Println("ERROR: Cannot create maintenance window for building '" + strconv.Itoa(w.BuildingId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "MaintenanceWindows", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Maintenance window for building '" + strconv.Itoa(w.BuildingId) + "' created")
+	return true, nil
+}
+
+func (repo *sqlRepository) DeleteMaintenanceWindow(windowId int) (bool, error) {
+	log.Println("INFO: Maintenance window deletion requested: " + strconv.Itoa(windowId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "MaintenanceWindows", windowId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM MaintenanceWindows WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(windowId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete maintenance window with Id '" + strconv.Itoa(windowId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "MaintenanceWindows", windowId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Maintenance window with Id '" + strconv.Itoa(windowId) + "' has been deleted")
+	return true, nil
+}
+
+func (repo *sqlRepository) queryMaintenanceWindows(query string, args ...any) ([]MaintenanceWindow, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make([]MaintenanceWindow, 0)
+	for rows.Next() {
+		window := MaintenanceWindow{}
+		err = rows.Scan(
+			&window.Id,
+			&window.BuildingId,
+			&window.Weekday,
+			&window.StartTime,
+			&window.DurationMinutes,
+			&window.CreatorId,
+			&window.CreationDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the maintenance window objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		window.CreationDate = ConvertSqliteTimestamp(window.CreationDate)
+
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+func (repo *sqlRepository) GetMaintenanceWindows() ([]MaintenanceWindow, error) {
+	log.Println("INFO: List of maintenance window objects requested")
+	windows, err := repo.queryMaintenanceWindows("SELECT * FROM MaintenanceWindows")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of all maintenance windows retrieved")
+	return windows, nil
+}
+
+func (repo *sqlRepository) GetMaintenanceWindowsByBuildingId(buildingId int) ([]MaintenanceWindow, error) {
+	log.Println("INFO: Maintenance windows by building Id requested: " + strconv.Itoa(buildingId))
+	windows, err := repo.queryMaintenanceWindows("SELECT * FROM MaintenanceWindows WHERE BuildingId = ?", buildingId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of maintenance windows with building Id '" + strconv.Itoa(buildingId) + "' retrieved")
+	return windows, nil
+}
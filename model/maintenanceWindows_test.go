@@ -0,0 +1,97 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsMaintenanceWindowOpen(t *testing.T) {
+	// 2024-03-03 is a Sunday, weekday 0
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	tuesdayEvening := MaintenanceWindow{Weekday: 2, StartTime: "22:00", DurationMinutes: 240}
+	saturdayNight := MaintenanceWindow{Weekday: 6, StartTime: "23:30", DurationMinutes: 90}
+	sundayMorning := MaintenanceWindow{Weekday: 0, StartTime: "06:00", DurationMinutes: 60}
+
+	tests := []struct {
+		name    string
+		windows []MaintenanceWindow
+		now     time.Time
+		want    bool
+	}{
+		{"no windows", nil, at(5, 22, 30), false},
+		{"before start", []MaintenanceWindow{tuesdayEvening}, at(5, 21, 59), false},
+		{"at start", []MaintenanceWindow{tuesdayEvening}, at(5, 22, 0), true},
+		{"past midnight", []MaintenanceWindow{tuesdayEvening}, at(6, 1, 59), true},
+		{"at end", []MaintenanceWindow{tuesdayEvening}, at(6, 2, 0), false},
+		{"other weekday", []MaintenanceWindow{tuesdayEvening}, at(4, 23, 0), false},
+		{"same weekday next week", []MaintenanceWindow{tuesdayEvening}, at(12, 22, 15), true},
+		{"end of week before", []MaintenanceWindow{saturdayNight}, at(9, 23, 29), false},
+		{"end of week on Saturday", []MaintenanceWindow{saturdayNight}, at(9, 23, 45), true},
+		{"end of week wrapped to Sunday", []MaintenanceWindow{saturdayNight}, at(10, 0, 59), true},
+		{"end of week after", []MaintenanceWindow{saturdayNight}, at(10, 1, 0), false},
+		{"any of several", []MaintenanceWindow{tuesdayEvening, sundayMorning}, at(3, 6, 30), true},
+		{"none of several", []MaintenanceWindow{tuesdayEvening, sundayMorning}, at(3, 7, 0), false},
+		{"whole week", []MaintenanceWindow{{Weekday: 3, StartTime: "12:00", DurationMinutes: minutesPerWeek}}, at(7, 0, 0), true},
+		{"unparsable start skipped", []MaintenanceWindow{{Weekday: 2, StartTime: "late", DurationMinutes: 60}}, at(5, 22, 0), false},
+		// 21:30 in New York on a Tuesday is already 02:30 on Wednesday in UTC
+		{"compared in UTC", []MaintenanceWindow{{Weekday: 3, StartTime: "02:00", DurationMinutes: 60}},
+			time.Date(2024, 3, 5, 21, 30, 0, 0, time.FixedZone("EST", -5*60*60)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMaintenanceWindowOpen(tt.windows, tt.now); got != tt.want {
+				t.Errorf("IsMaintenanceWindowOpen(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		ok     bool
+	}{
+		{"valid", MaintenanceWindow{Weekday: 6, StartTime: "23:30", DurationMinutes: 90}, true},
+		{"whole week", MaintenanceWindow{Weekday: 0, StartTime: "00:00", DurationMinutes: minutesPerWeek}, true},
+		{"weekday too low", MaintenanceWindow{Weekday: -1, StartTime: "00:00", DurationMinutes: 60}, false},
+		{"weekday too high", MaintenanceWindow{Weekday: 7, StartTime: "00:00", DurationMinutes: 60}, false},
+		{"hour out of range", MaintenanceWindow{Weekday: 1, StartTime: "24:00", DurationMinutes: 60}, false},
+		{"not a time", MaintenanceWindow{Weekday: 1, StartTime: "noon", DurationMinutes: 60}, false},
+		{"no duration", MaintenanceWindow{Weekday: 1, StartTime: "12:00", DurationMinutes: 0}, false},
+		{"longer than a week", MaintenanceWindow{Weekday: 1, StartTime: "12:00", DurationMinutes: minutesPerWeek + 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMaintenanceWindow(tt.window)
+			if tt.ok && err != nil {
+				t.Errorf("validateMaintenanceWindow: %v", err)
+			}
+			var invalid *InvalidMaintenanceWindow
+			if !tt.ok && !errors.As(err, &invalid) {
+				t.Errorf("got %v, want an InvalidMaintenanceWindow", err)
+			}
+		})
+	}
+}
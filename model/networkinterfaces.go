@@ -20,14 +20,34 @@ package model
 
 import (
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"strconv"
+	"strings"
 )
 
-func CreateNetworkInterface(n NetworkInterface, id int) (bool, error) {
+// NormalizeMACAddress returns a MAC address in the one form every MAC address
+// is stored and looked up in, lower case and separated by colons, whichever
+// form it was written in
+func NormalizeMACAddress(macAddress string) (string, error) {
+	hw, err := net.ParseMAC(strings.TrimSpace(macAddress))
+	if err != nil {
+		return "", &InvalidMACAddress{Err: errors.New("'" + macAddress + "' is not a MAC address")}
+	}
+	return hw.String(), nil
+}
+
+func (repo *sqlRepository) CreateNetworkInterface(n NetworkInterface, id int) (bool, error) {
 	log.Println("INFO: Network Interface creation requested: " + n.DeviceModel)
-	t, err := DB.Begin()
+	macAddress, err := NormalizeMACAddress(n.MACAddress)
+	if err != nil {
+		log.Println("ERROR: Cannot create network interface '" + n.DeviceModel + "': " + string(err.Error()))
+		return false, err
+	}
+	n.MACAddress = macAddress
+
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -43,18 +63,24 @@ func CreateNetworkInterface(n NetworkInterface, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO NetworkInterfaces (DeviceModel, DeviceId, MACAddress, SystemId, IpAddress, Bitmask, Gateway, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO NetworkInterfaces (DeviceModel, DeviceId, MACAddress, SystemId, IpAddress, Bitmask, Gateway, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(n.DeviceModel, n.DeviceId, n.MACAddress, n.SystemId, n.IpAddress, n.Bitmask, n.Gateway, id)
+	var newId int
+	err = q.QueryRow(n.DeviceModel, n.DeviceId, n.MACAddress, n.SystemId, n.IpAddress, n.Bitmask, n.Gateway, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create network interface '" + n.DeviceModel + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "NetworkInterfaces", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -65,9 +91,9 @@ func CreateNetworkInterface(n NetworkInterface, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteNetworkInterface(networkInterfaceId int) (bool, error) {
+func (repo *sqlRepository) DeleteNetworkInterface(networkInterfaceId int) (bool, error) {
 	log.Println("INFO: Network Interface deletion requested: " + strconv.Itoa(networkInterfaceId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -83,7 +109,12 @@ func DeleteNetworkInterface(networkInterfaceId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM NetworkInterfaces WHERE Id IS ?")
+	before, err := snapshotById(t, "NetworkInterfaces", networkInterfaceId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM NetworkInterfaces WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -95,6 +126,11 @@ func DeleteNetworkInterface(networkInterfaceId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "NetworkInterfaces", networkInterfaceId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -105,9 +141,9 @@ func DeleteNetworkInterface(networkInterfaceId int) (bool, error) {
 	return true, nil
 }
 
-func GetNetworkInterfaces() ([]NetworkInterface, error) {
+func (repo *sqlRepository) GetNetworkInterfaces() ([]NetworkInterface, error) {
 	log.Println("INFO: List of network interface objects requested")
-	rows, err := DB.Query("SELECT * FROM NetworkInterfaces")
+	rows, err := repo.db.Query("SELECT * FROM NetworkInterfaces")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -143,9 +179,9 @@ func GetNetworkInterfaces() ([]NetworkInterface, error) {
 	return networkInterfaces, nil
 }
 
-func GetNetworkInterfaceById(id int) (NetworkInterface, error) {
+func (repo *sqlRepository) GetNetworkInterfaceById(id int) (NetworkInterface, error) {
 	log.Println("INFO: Network Interface by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM NetworkInterfaces WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM NetworkInterfaces WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return NetworkInterface{}, err
@@ -154,18 +190,7 @@ func GetNetworkInterfaceById(id int) (NetworkInterface, error) {
 
 	networkInterface := NetworkInterface{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
-			return NetworkInterface{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
-		return NetworkInterface{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&networkInterface.Id,
 		&networkInterface.DeviceModel,
 		&networkInterface.DeviceId,
@@ -178,7 +203,11 @@ func GetNetworkInterfaceById(id int) (NetworkInterface, error) {
 		&networkInterface.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the network interface object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
+			return NetworkInterface{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
 		return NetworkInterface{}, err
 	}
 
@@ -188,9 +217,9 @@ func GetNetworkInterfaceById(id int) (NetworkInterface, error) {
 	return networkInterface, nil
 }
 
-func GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error) {
+func (repo *sqlRepository) GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error) {
 	log.Println("INFO: Network Interface by IP address requested: " + ipAddr)
-	rec, err := DB.Prepare("SELECT * FROM NetworkInterfaces WHERE IpAddress = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM NetworkInterfaces WHERE IpAddress = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return NetworkInterface{}, err
@@ -199,18 +228,7 @@ func GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error) {
 
 	networkInterface := NetworkInterface{}
 
-	r, err := rec.Query(ipAddr)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
-			return NetworkInterface{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
-		return NetworkInterface{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(ipAddr).Scan(
 		&networkInterface.Id,
 		&networkInterface.DeviceModel,
 		&networkInterface.DeviceId,
@@ -223,7 +241,11 @@ func GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error) {
 		&networkInterface.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the network interface object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
+			return NetworkInterface{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
 		return NetworkInterface{}, err
 	}
 
@@ -233,9 +255,15 @@ func GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error) {
 	return networkInterface, nil
 }
 
-func GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error) {
+func (repo *sqlRepository) GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error) {
 	log.Println("INFO: Network Interface by MAC address requested: " + macAddress)
-	rec, err := DB.Prepare("SELECT * FROM NetworkInterfaces WHERE MACAddress = ?")
+	// what cannot be a MAC address cannot belong to any interface either
+	normalized, err := NormalizeMACAddress(macAddress)
+	if err != nil {
+		log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
+		return NetworkInterface{}, nil
+	}
+	rec, err := repo.db.Prepare("SELECT * FROM NetworkInterfaces WHERE MACAddress = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return NetworkInterface{}, err
@@ -243,19 +271,7 @@ func GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error
 	defer rec.Close()
 
 	networkInterface := NetworkInterface{}
-
-	r, err := rec.Query(macAddress)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
-			return NetworkInterface{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
-		return NetworkInterface{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(normalized).Scan(
 		&networkInterface.Id,
 		&networkInterface.DeviceModel,
 		&networkInterface.DeviceId,
@@ -268,7 +284,11 @@ func GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error
 		&networkInterface.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the network interface object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such network interface found in DB: " + string(err.Error()))
+			return NetworkInterface{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve network interface from DB: " + string(err.Error()))
 		return NetworkInterface{}, err
 	}
 
@@ -278,9 +298,9 @@ func GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error
 	return networkInterface, nil
 }
 
-func GetNetworkInterfacesBySystemId(systemId int) ([]NetworkInterface, error) {
+func (repo *sqlRepository) GetNetworkInterfacesBySystemId(systemId int) ([]NetworkInterface, error) {
 	log.Println("INFO: Network Interfaces by System Id requested: " + strconv.Itoa(systemId))
-	rec, err := DB.Prepare("SELECT * FROM NetworkInterfaces WHERE SystemId = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM NetworkInterfaces WHERE SystemId = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return nil, err
@@ -328,9 +348,16 @@ func GetNetworkInterfacesBySystemId(systemId int) ([]NetworkInterface, error) {
 	return networkInterfaces, nil
 }
 
-func UpdateNetworkInterface(networkInterfaceId int, n NetworkInterface) (bool, error) {
+func (repo *sqlRepository) UpdateNetworkInterface(networkInterfaceId int, n NetworkInterface) (bool, error) {
 	log.Println("INFO: Update network interface by Id requested: " + strconv.Itoa(networkInterfaceId))
-	t, err := DB.Begin()
+	macAddress, err := NormalizeMACAddress(n.MACAddress)
+	if err != nil {
+		log.Println("ERROR: Cannot update network interface '" + n.DeviceModel + "': " + string(err.Error()))
+		return false, err
+	}
+	n.MACAddress = macAddress
+
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -346,21 +373,25 @@ func UpdateNetworkInterface(networkInterfaceId int, n NetworkInterface) (bool, e
 		}
 	}()
 
+	before, err := snapshotById(t, "NetworkInterfaces", networkInterfaceId)
+	if err != nil {
+		return false, err
+	}
+
 	q, err := t.Prepare("UPDATE NetworkInterfaces SET DeviceModel = ?, DeviceId = ?, MACAddress = ?, SystemId = ?, IpAddress = ?, Bitmask = ?, Gateway = ? WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	networkInterface, err := json.Marshal(n)
+	_, err = q.Exec(n.DeviceModel, n.DeviceId, n.MACAddress, n.SystemId, n.IpAddress, n.Bitmask, n.Gateway, networkInterfaceId)
 	if err != nil {
-		log.Println("ERROR: Cannot marshal the network interface object!" + string(err.Error()))
+		log.Println("ERROR: Cannot update network interface '" + n.DeviceModel + "': " + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(networkInterface, networkInterfaceId)
+	err = repo.auditById(t, "NetworkInterfaces", networkInterfaceId, before)
 	if err != nil {
-		log.Println("ERROR: Cannot update network interface '" + n.DeviceModel + "': " + string(err.Error()))
 		return false, err
 	}
 
@@ -20,14 +20,13 @@ package model
 
 import (
 	"database/sql"
-	"encoding/json"
 	"log"
 	"strconv"
 )
 
-func CreateOperatingSystem(os OperatingSystem, id int) (bool, error) {
+func (repo *sqlRepository) CreateOperatingSystem(os OperatingSystem, id int) (bool, error) {
 	log.Println("INFO: Operating System creation requested: " + os.OSName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -43,18 +42,24 @@ func CreateOperatingSystem(os OperatingSystem, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO OperatingSystems (OSName, OSFamilyId, OSImageUrl, ImageUriProtocol, CreatorId) VALUES (?, ?, ?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO OperatingSystems (OSName, OSFamilyId, VendorId, OSImageUrl, ImageUriProtocol, CreatorId) VALUES (?, ?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(os.OSName, os.OSFamilyId, os.OSImageUrl, os.ImageUriProtocol, id)
+	var newId int
+	err = q.QueryRow(os.OSName, os.OSFamilyId, nullableId(os.VendorId), os.OSImageUrl, os.ImageUriProtocol, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create Operating System record for '" + os.OSName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystems", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -65,9 +70,9 @@ func CreateOperatingSystem(os OperatingSystem, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteOperatingSystem(osId int) (bool, error) {
+func (repo *sqlRepository) DeleteOperatingSystem(osId int) (bool, error) {
 	log.Println("INFO: Operating System deletion requested: " + strconv.Itoa(osId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -83,7 +88,12 @@ func DeleteOperatingSystem(osId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM OperatingSystems WHERE Id IS ?")
+	before, err := snapshotById(t, "OperatingSystems", osId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM OperatingSystems WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -95,6 +105,11 @@ func DeleteOperatingSystem(osId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystems", osId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -105,9 +120,9 @@ func DeleteOperatingSystem(osId int) (bool, error) {
 	return true, nil
 }
 
-func GetOperatingSystems() ([]OperatingSystem, error) {
+func (repo *sqlRepository) GetOperatingSystems() ([]OperatingSystem, error) {
 	log.Println("INFO: List of Operating System objects requested")
-	rows, err := DB.Query("SELECT * FROM OperatingSystems")
+	rows, err := repo.db.Query("SELECT * FROM OperatingSystems")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -117,11 +132,12 @@ func GetOperatingSystems() ([]OperatingSystem, error) {
 	operatingSystems := make([]OperatingSystem, 0)
 	for rows.Next() {
 		operatingSystem := OperatingSystem{}
+		var vendorId sql.NullInt64
 		err = rows.Scan(
 			&operatingSystem.Id,
 			&operatingSystem.OSName,
 			&operatingSystem.OSFamilyId,
-			&operatingSystem.VendorId,
+			&vendorId,
 			&operatingSystem.OSImageUrl,
 			&operatingSystem.ImageUriProtocol,
 			&operatingSystem.CreatorId,
@@ -132,6 +148,7 @@ func GetOperatingSystems() ([]OperatingSystem, error) {
 			return nil, err
 		}
 
+		operatingSystem.VendorId = int(vendorId.Int64)
 		operatingSystem.CreationDate = ConvertSqliteTimestamp(operatingSystem.CreationDate)
 
 		operatingSystems = append(operatingSystems, operatingSystem)
@@ -141,9 +158,9 @@ func GetOperatingSystems() ([]OperatingSystem, error) {
 	return operatingSystems, nil
 }
 
-func GetOperatingSystemById(id int) (OperatingSystem, error) {
+func (repo *sqlRepository) GetOperatingSystemById(id int) (OperatingSystem, error) {
 	log.Println("INFO: Operating System by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystems WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystems WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return OperatingSystem{}, err
@@ -151,42 +168,36 @@ func GetOperatingSystemById(id int) (OperatingSystem, error) {
 	defer rec.Close()
 
 	os := OperatingSystem{}
-
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such Operating System record found in DB: " + string(err.Error()))
-			return OperatingSystem{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve Operating System record from DB: " + string(err.Error()))
-		return OperatingSystem{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	var vendorId sql.NullInt64
+	err = rec.QueryRow(id).Scan(
 		&os.Id,
 		&os.OSName,
 		&os.OSFamilyId,
-		&os.VendorId,
+		&vendorId,
 		&os.OSImageUrl,
 		&os.ImageUriProtocol,
 		&os.CreatorId,
 		&os.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the Operating System record!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such Operating System record found in DB: " + string(err.Error()))
+			return OperatingSystem{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve Operating System record from DB: " + string(err.Error()))
 		return OperatingSystem{}, err
 	}
 
+	os.VendorId = int(vendorId.Int64)
 	os.CreationDate = ConvertSqliteTimestamp(os.CreationDate)
 
 	log.Println("INFO: Operating System with Id '" + strconv.Itoa(id) + "' has been retrieved")
 	return os, nil
 }
 
-func GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error) {
+func (repo *sqlRepository) GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error) {
 	log.Println("INFO: Operating Systems by Name requested: " + strconv.Itoa(osFamilyId))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystems WHERE OSFamilyId = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystems WHERE OSFamilyId = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return nil, err
@@ -207,11 +218,12 @@ func GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error) {
 	operatingSystems := make([]OperatingSystem, 0)
 	for rows.Next() {
 		os := OperatingSystem{}
+		var vendorId sql.NullInt64
 		err = rows.Scan(
 			&os.Id,
 			&os.OSName,
 			&os.OSFamilyId,
-			&os.VendorId,
+			&vendorId,
 			&os.OSImageUrl,
 			&os.ImageUriProtocol,
 			&os.CreatorId,
@@ -222,6 +234,7 @@ func GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error) {
 			return nil, err
 		}
 
+		os.VendorId = int(vendorId.Int64)
 		os.CreationDate = ConvertSqliteTimestamp(os.CreationDate)
 
 		operatingSystems = append(operatingSystems, os)
@@ -231,9 +244,9 @@ func GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error) {
 	return operatingSystems, nil
 }
 
-func GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error) {
+func (repo *sqlRepository) GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error) {
 	log.Println("INFO: Operating Systems by Vendor Id requested: " + strconv.Itoa(osVendorId))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystems WHERE VendorId = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystems WHERE VendorId = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return nil, err
@@ -254,11 +267,12 @@ func GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error) {
 	operatingSystems := make([]OperatingSystem, 0)
 	for rows.Next() {
 		os := OperatingSystem{}
+		var vendorId sql.NullInt64
 		err = rows.Scan(
 			&os.Id,
 			&os.OSName,
 			&os.OSFamilyId,
-			&os.VendorId,
+			&vendorId,
 			&os.OSImageUrl,
 			&os.ImageUriProtocol,
 			&os.CreatorId,
@@ -269,6 +283,7 @@ func GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error) {
 			return nil, err
 		}
 
+		os.VendorId = int(vendorId.Int64)
 		os.CreationDate = ConvertSqliteTimestamp(os.CreationDate)
 
 		operatingSystems = append(operatingSystems, os)
@@ -278,9 +293,9 @@ func GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error) {
 	return operatingSystems, nil
 }
 
-func UpdateOperatingSystemById(osId int, os OperatingSystem) (bool, error) {
+func (repo *sqlRepository) UpdateOperatingSystemById(osId int, os OperatingSystem) (bool, error) {
 	log.Println("INFO: Update Operating System by Id requested: " + strconv.Itoa(osId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		return false, err
 	}
@@ -295,16 +310,22 @@ func UpdateOperatingSystemById(osId int, os OperatingSystem) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("UPDATE OperatingSystems SET OSName = ?, OSFamilyId = ?, OSImageUrl = ?, ImageUriProtocol = ? WHERE Id = ?")
+	before, err := snapshotById(t, "OperatingSystems", osId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE OperatingSystems SET OSName = ?, OSFamilyId = ?, VendorId = ?, OSImageUrl = ?, ImageUriProtocol = ? WHERE Id = ?")
 	if err != nil {
 		return false, err
 	}
 
-	operatingSystem, err := json.Marshal(os)
+	_, err = q.Exec(os.OSName, os.OSFamilyId, nullableId(os.VendorId), os.OSImageUrl, os.ImageUriProtocol, osId)
 	if err != nil {
 		return false, err
 	}
-	_, err = q.Exec(operatingSystem, osId)
+
+	err = repo.auditById(t, "OperatingSystems", osId, before)
 	if err != nil {
 		return false, err
 	}
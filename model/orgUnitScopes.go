@@ -0,0 +1,318 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/greeneg/allocatord/dhcp"
+)
+
+// Contains reports whether records belonging to the organizational unit are
+// visible through the scope
+func (s OrgUnitScope) Contains(ouId int) bool {
+	if s.Global {
+		return true
+	}
+	for _, id := range s.OrgUnitIds {
+		if id == ouId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OrgUnitScope) FilterSystems(systems []System) []System {
+	if s.Global {
+		return systems
+	}
+	visible := make([]System, 0)
+	for _, system := range systems {
+		if s.Contains(system.BilledToOrgUnitId) {
+			visible = append(visible, system)
+		}
+	}
+	return visible
+}
+
+// systemIds returns the Ids of the systems visible through the scope
+func (s OrgUnitScope) systemIds(systems []System) map[int]bool {
+	visible := make(map[int]bool)
+	for _, system := range s.FilterSystems(systems) {
+		visible[system.Id] = true
+	}
+	return visible
+}
+
+// FilterNetworkInterfaces keeps the interfaces of the systems visible
+// through the scope, out of the given list of all systems
+func (s OrgUnitScope) FilterNetworkInterfaces(interfaces []NetworkInterface, systems []System) []NetworkInterface {
+	if s.Global {
+		return interfaces
+	}
+	systemIds := s.systemIds(systems)
+	visible := make([]NetworkInterface, 0)
+	for _, networkInterface := range interfaces {
+		if systemIds[networkInterface.SystemId] {
+			visible = append(visible, networkInterface)
+		}
+	}
+	return visible
+}
+
+// FilterStorageVolumes keeps the volumes of the systems visible through the
+// scope, out of the given list of all systems
+func (s OrgUnitScope) FilterStorageVolumes(volumes []StorageVolume, systems []System) []StorageVolume {
+	if s.Global {
+		return volumes
+	}
+	systemIds := s.systemIds(systems)
+	visible := make([]StorageVolume, 0)
+	for _, volume := range volumes {
+		if systemIds[volume.SystemId] {
+			visible = append(visible, volume)
+		}
+	}
+	return visible
+}
+
+// FilterDhcpHosts keeps the reservations of the systems visible through the
+// scope, out of the given list of all systems
+func (s OrgUnitScope) FilterDhcpHosts(hosts []dhcp.Host, systems []System) []dhcp.Host {
+	if s.Global {
+		return hosts
+	}
+	serialNumbers := make(map[string]bool)
+	for _, system := range s.FilterSystems(systems) {
+		serialNumbers[system.SerialNumber] = true
+	}
+	visible := make([]dhcp.Host, 0)
+	for _, host := range hosts {
+		if serialNumbers[host.SerialNumber] {
+			visible = append(visible, host)
+		}
+	}
+	return visible
+}
+
+func (s OrgUnitScope) FilterUsers(users []User) []User {
+	if s.Global {
+		return users
+	}
+	visible := make([]User, 0)
+	for _, user := range users {
+		if s.Contains(user.OrgUnitId) {
+			visible = append(visible, user)
+		}
+	}
+	return visible
+}
+
+// GetOrgUnitScope works out which organizational units a user can see and
+// manage: everything when their role holds the global permission, otherwise
+// their own organizational unit and the ones delegated to it, each along
+// with every organizational unit below it
+func (repo *sqlRepository) GetOrgUnitScope(user User) (OrgUnitScope, error) {
+	global, err := repo.UserHasPermission(user.UserName, PermissionOrgUnitsGlobal)
+	if err != nil {
+		return OrgUnitScope{}, err
+	}
+	if global {
+		return OrgUnitScope{Global: true, OrgUnitIds: []int{}}, nil
+	}
+
+	roots := []int{user.OrgUnitId}
+	delegations, err := repo.GetOrgUnitDelegations(user.OrgUnitId)
+	if err != nil {
+		return OrgUnitScope{}, err
+	}
+	for _, delegation := range delegations {
+		roots = append(roots, delegation.DelegatedOrgUnitId)
+	}
+
+	scope := OrgUnitScope{OrgUnitIds: []int{}}
+	for _, root := range roots {
+		if scope.Contains(root) {
+			continue
+		}
+		subtree, err := repo.GetOUSubtree(root)
+		if err != nil {
+			return OrgUnitScope{}, err
+		}
+		for _, unit := range subtree {
+			if !scope.Contains(unit.Id) {
+				scope.OrgUnitIds = append(scope.OrgUnitIds, unit.Id)
+			}
+		}
+	}
+
+	return scope, nil
+}
+
+func (repo *sqlRepository) CreateOrgUnitDelegation(d OrgUnitDelegation, id int) (bool, error) {
+	ouIdStr := strconv.Itoa(d.OrgUnitId)
+	delegatedIdStr := strconv.Itoa(d.DelegatedOrgUnitId)
+	log.Println("INFO: Organizational unit delegation requested: " + delegatedIdStr + " to " + ouIdStr)
+	if d.OrgUnitId == d.DelegatedOrgUnitId {
+		return false, &InvalidOrgUnitDelegation{Err: errors.New("an organizational unit cannot be delegated to itself")}
+	}
+	for _, ouId := range []int{d.OrgUnitId, d.DelegatedOrgUnitId} {
+		ou, err := repo.GetOUById(ouId)
+		if err != nil {
+			return false, err
+		}
+		if ou.OUName == "" {
+			return false, &InvalidOrgUnitDelegation{Err: errors.New("no organizational unit found with Id " + strconv.Itoa(ouId))}
+		}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "OrgUnitDelegations", "OrgUnitId = ? AND DelegatedOrgUnitId = ?", d.OrgUnitId, d.DelegatedOrgUnitId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("INSERT INTO OrgUnitDelegations (OrgUnitId, DelegatedOrgUnitId, CreatorId) VALUES (?, ?, ?) ON CONFLICT (OrgUnitId, DelegatedOrgUnitId) DO NOTHING")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(d.OrgUnitId, d.DelegatedOrgUnitId, id)
+	if err != nil {
+		log.Println("ERROR: Cannot delegate organizational unit Id '" + delegatedIdStr + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "OrgUnitDelegations", before, "OrgUnitId = ? AND DelegatedOrgUnitId = ?", d.OrgUnitId, d.DelegatedOrgUnitId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Organizational unit Id '" + delegatedIdStr + "' delegated to organizational unit Id '" + ouIdStr + "'")
+	return true, nil
+}
+
+func (repo *sqlRepository) DeleteOrgUnitDelegation(ouId int, delegatedOuId int) (bool, error) {
+	ouIdStr := strconv.Itoa(ouId)
+	delegatedIdStr := strconv.Itoa(delegatedOuId)
+	log.Println("INFO: Organizational unit delegation removal requested: " + delegatedIdStr + " from " + ouIdStr)
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "OrgUnitDelegations", "OrgUnitId = ? AND DelegatedOrgUnitId = ?", ouId, delegatedOuId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM OrgUnitDelegations WHERE OrgUnitId = ? AND DelegatedOrgUnitId = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(ouId, delegatedOuId)
+	if err != nil {
+		log.Println("ERROR: Cannot remove delegation of organizational unit Id '" + delegatedIdStr + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "OrgUnitDelegations", before, "OrgUnitId = ? AND DelegatedOrgUnitId = ?", ouId, delegatedOuId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Organizational unit Id '" + delegatedIdStr + "' is no longer delegated to organizational unit Id '" + ouIdStr + "'")
+	return true, nil
+}
+
+func (repo *sqlRepository) GetOrgUnitDelegations(ouId int) ([]OrgUnitDelegation, error) {
+	log.Println("INFO: List of delegations requested for organizational unit Id " + strconv.Itoa(ouId))
+	rows, err := repo.db.Query("SELECT * FROM OrgUnitDelegations WHERE OrgUnitId = ? ORDER BY DelegatedOrgUnitId", ouId)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := make([]OrgUnitDelegation, 0)
+	for rows.Next() {
+		delegation := OrgUnitDelegation{}
+		err = rows.Scan(
+			&delegation.Id,
+			&delegation.OrgUnitId,
+			&delegation.DelegatedOrgUnitId,
+			&delegation.CreatorId,
+			&delegation.CreationDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the organizational unit delegation objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		delegation.CreationDate = ConvertSqliteTimestamp(delegation.CreationDate)
+
+		delegations = append(delegations, delegation)
+	}
+
+	log.Println("INFO: List of delegations for organizational unit Id " + strconv.Itoa(ouId) + " retrieved")
+	return delegations, nil
+}
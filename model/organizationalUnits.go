@@ -20,13 +20,32 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"strconv"
 )
 
-func CreateOU(o OrgUnit, id int) (bool, error) {
+// orgUnitSubtree is a common table expression naming Subtree the Ids of an
+// organizational unit and of every organizational unit below it
+const orgUnitSubtree = `WITH RECURSIVE Subtree (Id) AS (
+	SELECT Id FROM OrganizationalUnits WHERE Id = ?
+	UNION ALL
+	SELECT o.Id FROM OrganizationalUnits o JOIN Subtree s ON o.ParentId = s.Id
+) `
+
+func (repo *sqlRepository) CreateOU(o OrgUnit, id int) (bool, error) {
 	log.Println("INFO: Organizational Unit creation requested: " + o.OUName)
-	t, err := DB.Begin()
+	if o.ParentId != 0 {
+		parent, err := repo.GetOUById(o.ParentId)
+		if err != nil {
+			return false, err
+		}
+		if parent.OUName == "" {
+			return false, &InvalidOrgUnitHierarchy{Err: errors.New("no parent organizational unit found with Id " + strconv.Itoa(o.ParentId))}
+		}
+	}
+
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +61,24 @@ func CreateOU(o OrgUnit, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Roles (OUName, Description, CreatorId) VALUES (?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO OrganizationalUnits (OUName, Description, CreatorId, ParentId) VALUES (?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(o.OUName, o.Description, id)
+	var newId int
+	err = q.QueryRow(o.OUName, o.Description, id, nullableId(o.ParentId)).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create organizational unit '" + o.OUName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "OrganizationalUnits", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +89,17 @@ func CreateOU(o OrgUnit, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteOU(ouId int) (bool, error) {
+func (repo *sqlRepository) DeleteOU(ouId int) (bool, error) {
 	log.Println("INFO: Organizational Unit deletion requested: " + strconv.Itoa(ouId))
-	t, err := DB.Begin()
+	children, err := repo.GetOUChildren(ouId)
+	if err != nil {
+		return false, err
+	}
+	if len(children) > 0 {
+		return false, &InvalidOrgUnitHierarchy{Err: errors.New("organizational unit Id " + strconv.Itoa(ouId) + " still has child organizational units")}
+	}
+
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +115,12 @@ func DeleteOU(ouId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM OrganizationalUnits WHERE Id IS ?")
+	before, err := snapshotById(t, "OrganizationalUnits", ouId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM OrganizationalUnits WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +132,11 @@ func DeleteOU(ouId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "OrganizationalUnits", ouId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +147,9 @@ func DeleteOU(ouId int) (bool, error) {
 	return true, nil
 }
 
-func GetOUs() ([]OrgUnit, error) {
-	log.Println("INFO: List of organizational unit object requested")
-	rows, err := DB.Query("SELECT * FROM OrganizationalUnits")
+// queryOUs runs a SELECT against the OrganizationalUnits table
+func (repo *sqlRepository) queryOUs(query string, args ...any) ([]OrgUnit, error) {
+	rows, err := repo.db.Query(query, args...)
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -116,30 +159,43 @@ func GetOUs() ([]OrgUnit, error) {
 	units := make([]OrgUnit, 0)
 	for rows.Next() {
 		unit := OrgUnit{}
+		var parentId sql.NullInt64
 		err = rows.Scan(
 			&unit.Id,
 			&unit.OUName,
 			&unit.Description,
 			&unit.CreatorId,
 			&unit.CreationDate,
+			&parentId,
 		)
 		if err != nil {
 			log.Println("ERROR: Cannot marshal the organizational unit objects!" + string(err.Error()))
 			return nil, err
 		}
 
+		unit.ParentId = int(parentId.Int64)
 		unit.CreationDate = ConvertSqliteTimestamp(unit.CreationDate)
 
 		units = append(units, unit)
 	}
 
+	return units, nil
+}
+
+func (repo *sqlRepository) GetOUs() ([]OrgUnit, error) {
+	log.Println("INFO: List of organizational unit object requested")
+	units, err := repo.queryOUs("SELECT * FROM OrganizationalUnits")
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("INFO: List of all organizational units retrieved")
 	return units, nil
 }
 
-func GetOUById(id int) (OrgUnit, error) {
+func (repo *sqlRepository) GetOUById(id int) (OrgUnit, error) {
 	log.Println("INFO: Organizational Unit by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM OrganizationalUnits WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OrganizationalUnits WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return OrgUnit{}, err
@@ -147,32 +203,129 @@ func GetOUById(id int) (OrgUnit, error) {
 	defer rec.Close()
 
 	ou := OrgUnit{}
+	var parentId sql.NullInt64
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
-			return OrgUnit{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
-		return OrgUnit{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&ou.Id,
 		&ou.OUName,
 		&ou.Description,
 		&ou.CreatorId,
 		&ou.CreationDate,
+		&parentId,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot marshal the organizational unit objects!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
+			return OrgUnit{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
 		return OrgUnit{}, err
 	}
 
+	ou.ParentId = int(parentId.Int64)
 	ou.CreationDate = ConvertSqliteTimestamp(ou.CreationDate)
 
 	log.Println("INFO: Organizational Unit with Id '" + strconv.Itoa(ou.Id) + "' retrieved")
 	return ou, nil
 }
+
+func (repo *sqlRepository) GetOUChildren(ouId int) ([]OrgUnit, error) {
+	log.Println("INFO: Children of organizational unit Id requested: " + strconv.Itoa(ouId))
+	units, err := repo.queryOUs("SELECT * FROM OrganizationalUnits WHERE ParentId = ? ORDER BY OUName", ouId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: Children of organizational unit Id '" + strconv.Itoa(ouId) + "' retrieved")
+	return units, nil
+}
+
+// GetOUSubtree returns an organizational unit along with every organizational
+// unit below it
+func (repo *sqlRepository) GetOUSubtree(ouId int) ([]OrgUnit, error) {
+	log.Println("INFO: Subtree of organizational unit Id requested: " + strconv.Itoa(ouId))
+	units, err := repo.queryOUs(orgUnitSubtree+"SELECT * FROM OrganizationalUnits WHERE Id IN (SELECT Id FROM Subtree) ORDER BY Id", ouId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: Subtree of organizational unit Id '" + strconv.Itoa(ouId) + "' retrieved")
+	return units, nil
+}
+
+// MoveOU puts an organizational unit, along with everything below it, under
+// a new parent. A parent Id of 0 makes it a top level organizational unit
+func (repo *sqlRepository) MoveOU(ouId int, parentId int) (bool, error) {
+	ouIdStr := strconv.Itoa(ouId)
+	parentIdStr := strconv.Itoa(parentId)
+	log.Println("INFO: Move of organizational unit Id " + ouIdStr + " under " + parentIdStr + " requested")
+	subtree, err := repo.GetOUSubtree(ouId)
+	if err != nil {
+		return false, err
+	}
+	if len(subtree) == 0 {
+		return false, &InvalidOrgUnitHierarchy{Err: errors.New("no organizational unit found with Id " + ouIdStr)}
+	}
+	if parentId != 0 {
+		parent, err := repo.GetOUById(parentId)
+		if err != nil {
+			return false, err
+		}
+		if parent.OUName == "" {
+			return false, &InvalidOrgUnitHierarchy{Err: errors.New("no parent organizational unit found with Id " + parentIdStr)}
+		}
+		// an organizational unit cannot end up below itself
+		for _, unit := range subtree {
+			if unit.Id == parentId {
+				return false, &InvalidOrgUnitHierarchy{Err: errors.New("organizational unit Id " + parentIdStr + " is organizational unit Id " + ouIdStr + " or below it")}
+			}
+		}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "OrganizationalUnits", ouId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE OrganizationalUnits SET ParentId = ? WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(nullableId(parentId), ouId)
+	if err != nil {
+		log.Println("ERROR: Cannot move organizational unit Id '" + ouIdStr + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "OrganizationalUnits", ouId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Organizational Unit with Id '" + ouIdStr + "' moved under '" + parentIdStr + "'")
+	return true, nil
+}
@@ -24,9 +24,9 @@ import (
 	"strconv"
 )
 
-func CreateOSFamily(osFamily OperatingSystemFamily, id int) (bool, error) {
+func (repo *sqlRepository) CreateOSFamily(osFamily OperatingSystemFamily, id int) (bool, error) {
 	log.Println("INFO: Operating System Family creation requested: " + osFamily.OSFamilyName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +42,24 @@ func CreateOSFamily(osFamily OperatingSystemFamily, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO OperatingSystemFamilies (OSFamilyName, CreatorId) VALUES (?, ?)")
+	q, err := t.Prepare("INSERT INTO OperatingSystemFamilies (OSFamilyName, CreatorId) VALUES (?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(osFamily.OSFamilyName, id)
+	var newId int
+	err = q.QueryRow(osFamily.OSFamilyName, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create operating system family '" + osFamily.OSFamilyName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystemFamilies", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +70,9 @@ func CreateOSFamily(osFamily OperatingSystemFamily, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteOSFamily(osFamilyIdId int) (bool, error) {
+func (repo *sqlRepository) DeleteOSFamily(osFamilyIdId int) (bool, error) {
 	log.Println("INFO: Operating System Family deletion requested: " + strconv.Itoa(osFamilyIdId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +88,12 @@ func DeleteOSFamily(osFamilyIdId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM OperatingSystemFamilies WHERE Id IS ?")
+	before, err := snapshotById(t, "OperatingSystemFamilies", osFamilyIdId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM OperatingSystemFamilies WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +105,11 @@ func DeleteOSFamily(osFamilyIdId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystemFamilies", osFamilyIdId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +120,9 @@ func DeleteOSFamily(osFamilyIdId int) (bool, error) {
 	return true, nil
 }
 
-func GetOSFamilies() ([]OperatingSystemFamily, error) {
+func (repo *sqlRepository) GetOSFamilies() ([]OperatingSystemFamily, error) {
 	log.Println("INFO: List of Operating System Family objects requested")
-	rows, err := DB.Query("SELECT * FROM OperatingSystemFamilies")
+	rows, err := repo.db.Query("SELECT * FROM OperatingSystemFamilies")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -136,9 +152,9 @@ func GetOSFamilies() ([]OperatingSystemFamily, error) {
 	return osFamilies, nil
 }
 
-func GetOSFamilyById(id int) (OperatingSystemFamily, error) {
+func (repo *sqlRepository) GetOSFamilyById(id int) (OperatingSystemFamily, error) {
 	log.Println("INFO: Operating System Family by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystemFamilies WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystemFamilies WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return OperatingSystemFamily{}, err
@@ -147,25 +163,18 @@ func GetOSFamilyById(id int) (OperatingSystemFamily, error) {
 
 	osFamily := OperatingSystemFamily{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such Operating System Family found in DB: " + string(err.Error()))
-			return OperatingSystemFamily{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve Operating System Family from DB: " + string(err.Error()))
-		return OperatingSystemFamily{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&osFamily.Id,
 		&osFamily.OSFamilyName,
 		&osFamily.CreatorId,
 		&osFamily.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the Operating System Family object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such Operating System Family found in DB: " + string(err.Error()))
+			return OperatingSystemFamily{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve Operating System Family from DB: " + string(err.Error()))
 		return OperatingSystemFamily{}, err
 	}
 
@@ -175,9 +184,9 @@ func GetOSFamilyById(id int) (OperatingSystemFamily, error) {
 	return osFamily, nil
 }
 
-func GetOSFamilyByName(name string) (OperatingSystemFamily, error) {
+func (repo *sqlRepository) GetOSFamilyByName(name string) (OperatingSystemFamily, error) {
 	log.Println("INFO: Operating System Family by name requested: " + name)
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystemFamilies WHERE OSFamilyName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystemFamilies WHERE OSFamilyName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return OperatingSystemFamily{}, err
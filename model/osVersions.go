@@ -24,9 +24,9 @@ import (
 	"strconv"
 )
 
-func CreateOSVersion(osVersion OperatingSystemVersion, id int) (bool, error) {
+func (repo *sqlRepository) CreateOSVersion(osVersion OperatingSystemVersion, id int) (bool, error) {
 	log.Println("INFO: Operating System Version record creation requested: " + osVersion.VersionNumber)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +42,24 @@ func CreateOSVersion(osVersion OperatingSystemVersion, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO OperatingSystemVersions (OperatingSystemId, VersionNumber, CreatorId) VALUES (?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO OperatingSystemVersions (OperatingSystemId, VersionNumber, CreatorId) VALUES (?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(osVersion.OperatingSystemId, osVersion.VersionNumber, id)
+	var newId int
+	err = q.QueryRow(osVersion.OperatingSystemId, osVersion.VersionNumber, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create Operating System Version record for version number '" + osVersion.VersionNumber + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystemVersions", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +70,9 @@ func CreateOSVersion(osVersion OperatingSystemVersion, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteOSVersion(osVersionId int) (bool, error) {
+func (repo *sqlRepository) DeleteOSVersion(osVersionId int) (bool, error) {
 	log.Println("INFO: Operating System Version deletion requested: " + strconv.Itoa(osVersionId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +88,12 @@ func DeleteOSVersion(osVersionId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM OperatingSystemVersions WHERE Id IS ?")
+	before, err := snapshotById(t, "OperatingSystemVersions", osVersionId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM OperatingSystemVersions WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +105,11 @@ func DeleteOSVersion(osVersionId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "OperatingSystemVersions", osVersionId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +120,9 @@ func DeleteOSVersion(osVersionId int) (bool, error) {
 	return true, nil
 }
 
-func GetOSVersions() ([]OperatingSystemVersion, error) {
-	log.Println("INFO: List of Operating System objects requested")
-	rows, err := DB.Query("SELECT * FROM OperatingSystems")
+func (repo *sqlRepository) GetOSVersions() ([]OperatingSystemVersion, error) {
+	log.Println("INFO: List of Operating System Version objects requested")
+	rows, err := repo.db.Query("SELECT * FROM OperatingSystemVersions")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -137,9 +153,9 @@ func GetOSVersions() ([]OperatingSystemVersion, error) {
 	return osVersions, nil
 }
 
-func GetOSVersionById(id int) (OperatingSystemVersion, error) {
+func (repo *sqlRepository) GetOSVersionById(id int) (OperatingSystemVersion, error) {
 	log.Println("INFO: Operating System Version by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystemVersions WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystemVersions WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return OperatingSystemVersion{}, err
@@ -177,9 +193,9 @@ func GetOSVersionById(id int) (OperatingSystemVersion, error) {
 	return osVersion, nil
 }
 
-func GetOSVersionsByOSId(osId int) ([]OperatingSystemVersion, error) {
+func (repo *sqlRepository) GetOSVersionsByOSId(osId int) ([]OperatingSystemVersion, error) {
 	log.Println("INFO: Operating System Versions by OS Id requested: " + strconv.Itoa(osId))
-	rec, err := DB.Prepare("SELECT * FROM OperatingSystemVersions WHERE OperatingSystemId = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystemVersions WHERE OperatingSystemId = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return nil, err
@@ -220,3 +236,35 @@ func GetOSVersionsByOSId(osId int) ([]OperatingSystemVersion, error) {
 	log.Println("INFO: List of Operating System Versions with OS Id '" + strconv.Itoa(osId) + "' retrieved")
 	return versions, nil
 }
+
+func (repo *sqlRepository) GetLatestOSVersionByOSId(osId int) (OperatingSystemVersion, error) {
+	log.Println("INFO: Latest Operating System Version by OS Id requested: " + strconv.Itoa(osId))
+	rec, err := repo.db.Prepare("SELECT * FROM OperatingSystemVersions WHERE OperatingSystemId = ? ORDER BY Id DESC LIMIT 1")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return OperatingSystemVersion{}, err
+	}
+	defer rec.Close()
+
+	osVersion := OperatingSystemVersion{}
+	err = rec.QueryRow(osId).Scan(
+		&osVersion.Id,
+		&osVersion.OperatingSystemId,
+		&osVersion.VersionNumber,
+		&osVersion.CreatorId,
+		&osVersion.CreationDate,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No Operating System Version registered for OS Id '" + strconv.Itoa(osId) + "': " + string(err.Error()))
+			return OperatingSystemVersion{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve Operating System Version record from DB: " + string(err.Error()))
+		return OperatingSystemVersion{}, err
+	}
+
+	osVersion.CreationDate = ConvertSqliteTimestamp(osVersion.CreationDate)
+
+	log.Println("INFO: Latest Operating System Version for OS Id '" + strconv.Itoa(osId) + "' has been retrieved")
+	return osVersion, nil
+}
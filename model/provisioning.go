@@ -0,0 +1,90 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"log"
+	"strconv"
+)
+
+// GetProvisioningDocument assembles everything an imaging client needs to
+// know about a system: whether to reimage, which image to use and how the
+// disks and network interfaces are to be laid out. Systems do not carry an
+// OS version of their own, so the most recently registered version of the
+// system's operating system is reported. HostVars holds the system's merged
+// variable layers. Systems waiting for approval have nothing to provision
+func (repo *sqlRepository) GetProvisioningDocument(s System) (ProvisioningDocument, error) {
+	log.Println("INFO: Provisioning document requested for system: " + strconv.Itoa(s.Id))
+	if s.Status == SystemStatusPending {
+		return ProvisioningDocument{}, &SystemNotApproved{Err: errors.New("system " + strconv.Itoa(s.Id) + " is pending")}
+	}
+	operatingSystem, err := repo.GetOperatingSystemById(s.OperatingSystemId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve operating system for system '" + s.SerialNumber + "': " + string(err.Error()))
+		return ProvisioningDocument{}, err
+	}
+
+	osVersion, err := repo.GetLatestOSVersionByOSId(s.OperatingSystemId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve operating system version for system '" + s.SerialNumber + "': " + string(err.Error()))
+		return ProvisioningDocument{}, err
+	}
+
+	volumes, err := repo.GetStorageVolumesBySystemId(s.Id)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve storage volumes for system '" + s.SerialNumber + "': " + string(err.Error()))
+		return ProvisioningDocument{}, err
+	}
+
+	interfaces, err := repo.GetNetworkInterfacesBySystemId(s.Id)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve network interfaces for system '" + s.SerialNumber + "': " + string(err.Error()))
+		return ProvisioningDocument{}, err
+	}
+
+	// systems registered before HostVars were validated may still carry
+	// something other than a JSON object, which is passed on untouched
+	hostVars, err := repo.resolvedHostVarsJSON(s)
+	if err != nil {
+		var invalid *InvalidHostVars
+		if !errors.As(err, &invalid) {
+			log.Println("ERROR: Cannot resolve host vars for system '" + s.SerialNumber + "': " + string(err.Error()))
+			return ProvisioningDocument{}, err
+		}
+		log.Println("WARN: Host vars of system '" + s.SerialNumber + "' are not a JSON object, passing them on unresolved")
+		hostVars = s.HostVars
+	}
+
+	document := ProvisioningDocument{
+		SystemId:          s.Id,
+		SerialNumber:      s.SerialNumber,
+		Reimage:           s.Reimage,
+		OSName:            operatingSystem.OSName,
+		OSVersion:         osVersion.VersionNumber,
+		OSImageUrl:        operatingSystem.OSImageUrl,
+		ImageUriProtocol:  operatingSystem.ImageUriProtocol,
+		StorageVolumes:    volumes,
+		NetworkInterfaces: interfaces,
+		HostVars:          hostVars,
+	}
+
+	log.Println("INFO: Provisioning document for system '" + s.SerialNumber + "' has been assembled")
+	return document, nil
+}
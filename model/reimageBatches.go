@@ -0,0 +1,624 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	ReimageBatchStateScheduled = "scheduled"
+	ReimageBatchStateRunning   = "running"
+	ReimageBatchStateComplete  = "complete"
+	ReimageBatchStateCancelled = "cancelled"
+
+	ReimageBatchSystemPending = "pending"
+	ReimageBatchSystemStarted = "started"
+	ReimageBatchSystemSkipped = "skipped"
+)
+
+// the layout SQLite uses for CURRENT_TIMESTAMP, so stored dates compare as text
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// selectBatchSystems resolves a batch's selectors into systems. The first
+// selector set picks the candidates and every other one narrows them down
+func (repo *sqlRepository) selectBatchSystems(p ProposedReimageBatch) ([]System, error) {
+	var candidates []System
+	var err error
+	switch {
+	case len(p.SystemIds) > 0:
+		for _, systemId := range p.SystemIds {
+			system, err := repo.GetSystemById(systemId)
+			if err != nil {
+				return nil, err
+			}
+			if system.SerialNumber == "" {
+				return nil, &InvalidReimageBatch{Err: errors.New("no system with Id " + strconv.Itoa(systemId))}
+			}
+			candidates = append(candidates, system)
+		}
+	case p.MachineRoleId != 0:
+		candidates, err = repo.GetSystemsByMachineRoleId(p.MachineRoleId)
+	case p.OrgUnitId != 0:
+		candidates, err = repo.GetSystemsByOuId(p.OrgUnitId)
+	case p.BuildingId != 0:
+		candidates, err = repo.GetSystemsByBuildingId(p.BuildingId)
+	default:
+		return nil, &InvalidReimageBatch{Err: errors.New("no machine role, organizational unit, building or system Ids given")}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	systems := make([]System, 0)
+	for _, system := range candidates {
+		if p.MachineRoleId != 0 && system.MachineRoleId != p.MachineRoleId {
+			continue
+		}
+		if p.OrgUnitId != 0 && system.BilledToOrgUnitId != p.OrgUnitId {
+			continue
+		}
+		if p.BuildingId != 0 && system.BuildingId != p.BuildingId {
+			continue
+		}
+		systems = append(systems, system)
+	}
+
+	return systems, nil
+}
+
+// CreateReimageBatch schedules a reimage of every system matching the batch's
+// selectors. The systems are resolved now, so systems added later are not
+// picked up by an existing batch
+func (repo *sqlRepository) CreateReimageBatch(p ProposedReimageBatch, id int) (ReimageBatch, error) {
+	log.Println("INFO: Reimage batch creation requested: " + p.BatchName)
+	if p.BatchName == "" {
+		return ReimageBatch{}, &InvalidReimageBatch{Err: errors.New("batch name is required")}
+	}
+	if p.MaxPerBuilding < 0 {
+		return ReimageBatch{}, &InvalidReimageBatch{Err: errors.New("maxPerBuilding cannot be negative")}
+	}
+
+	scheduledDate := time.Now().UTC()
+	if p.ScheduledDate != "" {
+		parsed, err := time.Parse(time.RFC3339, p.ScheduledDate)
+		if err != nil {
+			return ReimageBatch{}, &InvalidReimageBatch{Err: errors.New("scheduledDate must be an RFC 3339 timestamp")}
+		}
+		scheduledDate = parsed.UTC()
+	}
+
+	systems, err := repo.selectBatchSystems(p)
+	if err != nil {
+		return ReimageBatch{}, err
+	}
+	if len(systems) == 0 {
+		return ReimageBatch{}, &InvalidReimageBatch{Err: errors.New("no systems match the given selectors")}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return ReimageBatch{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var batchId int
+	err = t.QueryRow("INSERT INTO ReimageBatches (BatchName, ScheduledDate, MaxPerBuilding, State, CreatorId) VALUES (?, ?, ?, ?, ?) RETURNING Id",
+		p.BatchName, scheduledDate.Format(sqliteTimeLayout), p.MaxPerBuilding, ReimageBatchStateScheduled, id).Scan(&batchId)
+	if err != nil {
+		log.Println("ERROR: Cannot create reimage batch '" + p.BatchName + "': " + string(err.Error()))
+		return ReimageBatch{}, err
+	}
+
+	err = repo.auditById(t, "ReimageBatches", batchId, rowSnapshot{})
+	if err != nil {
+		return ReimageBatch{}, err
+	}
+
+	q, err := t.Prepare("INSERT INTO ReimageBatchSystems (BatchId, SystemId, State) VALUES (?, ?, ?) RETURNING Id")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return ReimageBatch{}, err
+	}
+	for _, system := range systems {
+		var memberId int
+		err = q.QueryRow(batchId, system.Id, ReimageBatchSystemPending).Scan(&memberId)
+		if err != nil {
+			log.Println("ERROR: Cannot add system '" + strconv.Itoa(system.Id) + "' to reimage batch: " + string(err.Error()))
+			return ReimageBatch{}, err
+		}
+		err = repo.auditById(t, "ReimageBatchSystems", memberId, rowSnapshot{})
+		if err != nil {
+			return ReimageBatch{}, err
+		}
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return ReimageBatch{}, err
+	}
+
+	log.Println("INFO: Reimage batch '" + p.BatchName + "' created with " + strconv.Itoa(len(systems)) + " systems")
+	return repo.GetReimageBatchById(batchId)
+}
+
+// CancelReimageBatch stops a batch from starting any more reimages. Reimages
+// the batch already started are left to finish
+func (repo *sqlRepository) CancelReimageBatch(batchId int) (bool, error) {
+	log.Println("INFO: Reimage batch cancellation requested: " + strconv.Itoa(batchId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "ReimageBatches", batchId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("UPDATE ReimageBatches SET State = ? WHERE Id = ? AND State IN (?, ?)",
+		ReimageBatchStateCancelled, batchId, ReimageBatchStateScheduled, ReimageBatchStateRunning)
+	if err != nil {
+		log.Println("ERROR: Cannot cancel reimage batch with Id '" + strconv.Itoa(batchId) + "': " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+	if numberOfRows == 0 {
+		err = &ReimageBatchNotCancellable{Err: errors.New("batch " + strconv.Itoa(batchId) + " not cancellable")}
+		return false, err
+	}
+
+	err = repo.auditById(t, "ReimageBatches", batchId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Reimage batch with Id '" + strconv.Itoa(batchId) + "' has been cancelled")
+	return true, nil
+}
+
+func scanReimageBatch(row interface{ Scan(...any) error }) (ReimageBatch, error) {
+	batch := ReimageBatch{}
+	err := row.Scan(
+		&batch.Id,
+		&batch.BatchName,
+		&batch.ScheduledDate,
+		&batch.MaxPerBuilding,
+		&batch.State,
+		&batch.CreatorId,
+		&batch.CreationDate,
+	)
+	if err != nil {
+		return ReimageBatch{}, err
+	}
+	batch.ScheduledDate = ConvertSqliteTimestamp(batch.ScheduledDate)
+	batch.CreationDate = ConvertSqliteTimestamp(batch.CreationDate)
+
+	return batch, nil
+}
+
+func (repo *sqlRepository) getReimageBatchSystems(batchId int) ([]ReimageBatchSystem, error) {
+	rows, err := repo.db.Query("SELECT * FROM ReimageBatchSystems WHERE BatchId = ? ORDER BY Id", batchId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve reimage batch systems from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]ReimageBatchSystem, 0)
+	for rows.Next() {
+		member := ReimageBatchSystem{}
+		var requestId sql.NullInt64
+		var startedDate sql.NullString
+		err = rows.Scan(
+			&member.Id,
+			&member.BatchId,
+			&member.SystemId,
+			&member.State,
+			&requestId,
+			&startedDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the reimage batch system objects!" + string(err.Error()))
+			return nil, err
+		}
+		member.ReimageRequestId = int(requestId.Int64)
+		if startedDate.Valid {
+			member.StartedDate = ConvertSqliteTimestamp(startedDate.String)
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (repo *sqlRepository) GetReimageBatches() ([]ReimageBatch, error) {
+	log.Println("INFO: List of reimage batch objects requested")
+	rows, err := repo.db.Query("SELECT * FROM ReimageBatches ORDER BY Id DESC")
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]ReimageBatch, 0)
+	for rows.Next() {
+		batch, err := scanReimageBatch(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the reimage batch objects!" + string(err.Error()))
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	// close before fetching the members so the connection is free for the next query
+	rows.Close()
+
+	for i := range batches {
+		batches[i].Systems, err = repo.getReimageBatchSystems(batches[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Println("INFO: List of all reimage batches retrieved")
+	return batches, nil
+}
+
+func (repo *sqlRepository) GetReimageBatchById(batchId int) (ReimageBatch, error) {
+	log.Println("INFO: Reimage batch by Id requested: " + strconv.Itoa(batchId))
+	batch, err := scanReimageBatch(repo.db.QueryRow("SELECT * FROM ReimageBatches WHERE Id = ?", batchId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ReimageBatch{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve reimage batch from DB: " + string(err.Error()))
+		return ReimageBatch{}, err
+	}
+
+	batch.Systems, err = repo.getReimageBatchSystems(batch.Id)
+	if err != nil {
+		return ReimageBatch{}, err
+	}
+
+	return batch, nil
+}
+
+type dueReimageBatch struct {
+	id             int
+	maxPerBuilding int
+	state          string
+	creatorId      int
+}
+
+type reimageBatchMember struct {
+	id           int
+	systemId     int
+	state        string
+	buildingId   int
+	reimageState string
+}
+
+// the key of the PostgreSQL advisory lock daemons sharing a database take
+// while processing reimage batches
+const reimageSchedulerLockKey = 0x616c6c6f63
+
+// lockReimageScheduler keeps the daemons sharing a PostgreSQL database from
+// processing reimage batches at the same time, which would let them read the
+// same members as pending and start more reimages per building than a batch
+// allows. It reports false when another daemon holds the lock. SQLite
+// databases are not shared, so there is nothing to take
+func (repo *sqlRepository) lockReimageScheduler() (func(), bool, error) {
+	if repo.db.Driver() != PostgreSQL {
+		return func() {}, true, nil
+	}
+
+	// advisory locks belong to a session, so hold on to the connection
+	// taking it until it is released
+	ctx := context.Background()
+	conn, err := repo.db.SQL().Conn(ctx)
+	if err != nil {
+		log.Println("ERROR: Cannot get a connection for the reimage scheduler lock: " + string(err.Error()))
+		return nil, false, err
+	}
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", reimageSchedulerLockKey).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		log.Println("ERROR: Cannot take the reimage scheduler lock: " + string(err.Error()))
+		return nil, false, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", reimageSchedulerLockKey)
+		if err != nil {
+			log.Println("WARN: Cannot release the reimage scheduler lock: " + string(err.Error()))
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// ProcessReimageBatches starts the reimages of every batch that is due,
+// honouring each building's maintenance windows and the batch's per building
+// concurrency limit. It is safe to call repeatedly, and from several daemons
+// sharing a database; the scheduler calls it on every tick
+func (repo *sqlRepository) ProcessReimageBatches(now time.Time) error {
+	unlock, locked, err := repo.lockReimageScheduler()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		log.Println("INFO: Another daemon is processing reimage batches, skipping this run")
+		return nil
+	}
+	defer unlock()
+
+	rows, err := repo.db.Query("SELECT Id, MaxPerBuilding, State, CreatorId FROM ReimageBatches WHERE State IN (?, ?) AND ScheduledDate <= ?",
+		ReimageBatchStateScheduled, ReimageBatchStateRunning, now.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve due reimage batches: " + string(err.Error()))
+		return err
+	}
+	defer rows.Close()
+
+	batches := make([]dueReimageBatch, 0)
+	for rows.Next() {
+		batch := dueReimageBatch{}
+		err = rows.Scan(&batch.id, &batch.maxPerBuilding, &batch.state, &batch.creatorId)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the reimage batch objects!" + string(err.Error()))
+			return err
+		}
+		batches = append(batches, batch)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, batch := range batches {
+		err = repo.processReimageBatch(batch, now)
+		if err != nil {
+			log.Println("ERROR: Cannot process reimage batch '" + strconv.Itoa(batch.id) + "': " + string(err.Error()))
+		}
+	}
+
+	return nil
+}
+
+func (repo *sqlRepository) getReimageBatchMembers(batchId int) ([]reimageBatchMember, error) {
+	rows, err := repo.db.Query(`SELECT rbs.Id, rbs.SystemId, rbs.State, s.BuildingId, COALESCE(rr.State, '')
+		FROM ReimageBatchSystems rbs
+		JOIN Systems s ON s.Id = rbs.SystemId
+		LEFT JOIN ReimageRequests rr ON rr.Id = rbs.ReimageRequestId
+		WHERE rbs.BatchId = ? ORDER BY rbs.Id`, batchId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]reimageBatchMember, 0)
+	for rows.Next() {
+		member := reimageBatchMember{}
+		err = rows.Scan(&member.id, &member.systemId, &member.state, &member.buildingId, &member.reimageState)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// startReimageBatchMember starts the reimage of a pending member of a batch,
+// or skips it when its system is already being reimaged. The member is only
+// claimed while it is still pending, so it is started once however many
+// daemons get to it. It returns the state the member was left in, or an
+// empty state when it had been claimed already
+func (repo *sqlRepository) startReimageBatchMember(batch dueReimageBatch, member reimageBatchMember) (string, error) {
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return "", err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "ReimageBatchSystems", member.id)
+	if err != nil {
+		return "", err
+	}
+
+	var result sql.Result
+	state := ReimageBatchSystemStarted
+	requestId, startErr := repo.startReimage(t, member.systemId, batch.creatorId)
+	var inProgress *ReimageInProgress
+	switch {
+	case errors.As(startErr, &inProgress):
+		log.Println("WARN: System '" + strconv.Itoa(member.systemId) + "' is already being reimaged, skipping it in batch '" + strconv.Itoa(batch.id) + "'")
+		state = ReimageBatchSystemSkipped
+		result, err = t.Exec("UPDATE ReimageBatchSystems SET State = ? WHERE Id = ? AND State = ?",
+			state, member.id, ReimageBatchSystemPending)
+	case startErr != nil:
+		err = startErr
+		return "", err
+	default:
+		result, err = t.Exec("UPDATE ReimageBatchSystems SET State = ?, ReimageRequestId = ?, StartedDate = CURRENT_TIMESTAMP WHERE Id = ? AND State = ?",
+			state, requestId, member.id, ReimageBatchSystemPending)
+	}
+	if err != nil {
+		log.Println("ERROR: Cannot claim member '" + strconv.Itoa(member.id) + "' of reimage batch '" + strconv.Itoa(batch.id) + "': " + string(err.Error()))
+		return "", err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return "", err
+	}
+	if numberOfRows == 0 {
+		// another daemon got to it first, and anything started here goes
+		// with the rollback
+		t.Rollback()
+		log.Println("INFO: System '" + strconv.Itoa(member.systemId) + "' of reimage batch '" + strconv.Itoa(batch.id) + "' was already claimed")
+		return "", nil
+	}
+
+	err = repo.auditById(t, "ReimageBatchSystems", member.id, before)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return "", err
+	}
+
+	return state, nil
+}
+
+func (repo *sqlRepository) processReimageBatch(batch dueReimageBatch, now time.Time) error {
+	members, err := repo.getReimageBatchMembers(batch.id)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[int]int)
+	pending := 0
+	for _, member := range members {
+		switch member.state {
+		case ReimageBatchSystemStarted:
+			if !IsTerminalReimageState(member.reimageState) {
+				active[member.buildingId]++
+			}
+		case ReimageBatchSystemPending:
+			pending++
+		}
+	}
+
+	// buildings without any maintenance windows are always open
+	windowOpen := make(map[int]bool)
+	for _, member := range members {
+		if member.state != ReimageBatchSystemPending {
+			continue
+		}
+		open, known := windowOpen[member.buildingId]
+		if !known {
+			windows, err := repo.GetMaintenanceWindowsByBuildingId(member.buildingId)
+			if err != nil {
+				return err
+			}
+			open = len(windows) == 0 || IsMaintenanceWindowOpen(windows, now)
+			windowOpen[member.buildingId] = open
+		}
+		if !open {
+			continue
+		}
+		if batch.maxPerBuilding > 0 && active[member.buildingId] >= batch.maxPerBuilding {
+			continue
+		}
+
+		state, err := repo.startReimageBatchMember(batch, member)
+		if err != nil {
+			return err
+		}
+		// members claimed elsewhere are counted as started, so the batch is
+		// neither finished early nor let past its limit
+		if state != ReimageBatchSystemSkipped {
+			active[member.buildingId]++
+		}
+		pending--
+	}
+
+	running := 0
+	for _, count := range active {
+		running += count
+	}
+
+	state := batch.state
+	if pending == 0 && running == 0 {
+		state = ReimageBatchStateComplete
+	} else if state == ReimageBatchStateScheduled {
+		state = ReimageBatchStateRunning
+	}
+	if state != batch.state {
+		// a batch cancelled while it was being processed stays cancelled
+		err = repo.execAudited("ReimageBatches", batch.id, "UPDATE ReimageBatches SET State = ? WHERE Id = ? AND State = ?", state, batch.id, batch.state)
+		if err != nil {
+			return err
+		}
+		log.Println("INFO: Reimage batch '" + strconv.Itoa(batch.id) + "' is now " + state)
+	}
+
+	return nil
+}
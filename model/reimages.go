@@ -0,0 +1,390 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+)
+
+const (
+	ReimageStateRequested    = "requested"
+	ReimageStateBooting      = "booting"
+	ReimageStatePartitioning = "partitioning"
+	ReimageStateImaging      = "imaging"
+	ReimageStateConfiguring  = "configuring"
+	ReimageStateComplete     = "complete"
+	ReimageStateFailed       = "failed"
+)
+
+// the state each non-terminal state is expected to move on to next. Any
+// non-terminal state may also move to failed
+var nextReimageState = map[string]string{
+	ReimageStateRequested:    ReimageStateBooting,
+	ReimageStateBooting:      ReimageStatePartitioning,
+	ReimageStatePartitioning: ReimageStateImaging,
+	ReimageStateImaging:      ReimageStateConfiguring,
+	ReimageStateConfiguring:  ReimageStateComplete,
+}
+
+func IsTerminalReimageState(state string) bool {
+	return state == ReimageStateComplete || state == ReimageStateFailed
+}
+
+// IsValidReimageTransition reports whether a reimage may move from one state
+// to another. Repeating the current state is allowed so clients can report
+// progress messages without advancing
+func IsValidReimageTransition(from string, to string) bool {
+	if IsTerminalReimageState(from) {
+		return false
+	}
+	if to == ReimageStateFailed || to == from {
+		return true
+	}
+
+	return nextReimageState[from] == to
+}
+
+func scanReimageRequest(row interface{ Scan(...any) error }) (ReimageRequest, error) {
+	request := ReimageRequest{}
+	var completedDate sql.NullString
+	err := row.Scan(
+		&request.Id,
+		&request.SystemId,
+		&request.State,
+		&request.RequestedById,
+		&request.RequestedDate,
+		&request.UpdatedDate,
+		&completedDate,
+	)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+	request.RequestedDate = ConvertSqliteTimestamp(request.RequestedDate)
+	request.UpdatedDate = ConvertSqliteTimestamp(request.UpdatedDate)
+	if completedDate.Valid {
+		request.CompletedDate = ConvertSqliteTimestamp(completedDate.String)
+	}
+
+	return request, nil
+}
+
+func (repo *sqlRepository) getReimageEvents(requestId int) ([]ReimageEvent, error) {
+	rows, err := repo.db.Query("SELECT * FROM ReimageEvents WHERE ReimageRequestId = ? ORDER BY Id", requestId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve reimage events from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]ReimageEvent, 0)
+	for rows.Next() {
+		event := ReimageEvent{}
+		err = rows.Scan(
+			&event.Id,
+			&event.ReimageRequestId,
+			&event.State,
+			&event.Message,
+			&event.EventDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the reimage event objects!" + string(err.Error()))
+			return nil, err
+		}
+		event.EventDate = ConvertSqliteTimestamp(event.EventDate)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// startReimage opens a reimage for a system and flags it for reimaging
+// within a transaction, returning the Id of the new request
+func (repo *sqlRepository) startReimage(t *Tx, systemId int, id int) (int, error) {
+	var active bool
+	err := t.QueryRow("SELECT EXISTS(SELECT 1 FROM ReimageRequests WHERE SystemId = ? AND State NOT IN (?, ?))",
+		systemId, ReimageStateComplete, ReimageStateFailed).Scan(&active)
+	if err != nil {
+		log.Println("ERROR: Cannot check for active reimage: " + string(err.Error()))
+		return 0, err
+	}
+	if active {
+		return 0, &ReimageInProgress{Err: errors.New("reimage in progress for system " + strconv.Itoa(systemId))}
+	}
+
+	systemBefore, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return 0, err
+	}
+
+	// the check above only saves work, a request racing this one past it is
+	// stopped by the unique index on the open reimages of a system
+	var requestId int
+	err = t.QueryRow("INSERT INTO ReimageRequests (SystemId, State, RequestedById) VALUES (?, ?, ?) RETURNING Id",
+		systemId, ReimageStateRequested, id).Scan(&requestId)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, &ReimageInProgress{Err: errors.New("reimage in progress for system " + strconv.Itoa(systemId))}
+		}
+		log.Println("ERROR: Cannot create reimage request for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return 0, err
+	}
+
+	_, err = t.Exec("INSERT INTO ReimageEvents (ReimageRequestId, State, Message) VALUES (?, ?, ?)",
+		requestId, ReimageStateRequested, "Reimage requested")
+	if err != nil {
+		log.Println("ERROR: Cannot record reimage event: " + string(err.Error()))
+		return 0, err
+	}
+
+	_, err = t.Exec("UPDATE Systems SET Reimage = TRUE WHERE Id = ?", systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot flag system '" + strconv.Itoa(systemId) + "' for reimage: " + string(err.Error()))
+		return 0, err
+	}
+
+	err = repo.auditById(t, "ReimageRequests", requestId, rowSnapshot{})
+	if err != nil {
+		return 0, err
+	}
+	err = repo.auditById(t, "Systems", systemId, systemBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return requestId, nil
+}
+
+// CreateReimageRequest opens a new reimage for a system and flags the system
+// for reimaging. Only one reimage may be in progress per system
+func (repo *sqlRepository) CreateReimageRequest(systemId int, id int) (ReimageRequest, error) {
+	log.Println("INFO: Reimage requested for system: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	requestId, err := repo.startReimage(t, systemId, id)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	log.Println("INFO: Reimage request created for system '" + strconv.Itoa(systemId) + "'")
+	return repo.GetReimageRequestById(requestId)
+}
+
+// ReportReimageStatus records a progress event against a system's active
+// reimage. When the reimage reaches a terminal state the system's reimage flag
+// is cleared
+func (repo *sqlRepository) ReportReimageStatus(systemId int, report ReimageStatusReport) (ReimageRequest, error) {
+	log.Println("INFO: Reimage status '" + report.State + "' reported for system: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var requestId int
+	var state string
+	err = t.QueryRow("SELECT Id, State FROM ReimageRequests WHERE SystemId = ? AND State NOT IN (?, ?) ORDER BY Id DESC LIMIT 1",
+		systemId, ReimageStateComplete, ReimageStateFailed).Scan(&requestId, &state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = &NoActiveReimage{Err: errors.New("no active reimage for system " + strconv.Itoa(systemId))}
+			return ReimageRequest{}, err
+		}
+		log.Println("ERROR: Cannot retrieve active reimage: " + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	if !IsValidReimageTransition(state, report.State) {
+		err = &InvalidReimageTransition{From: state, To: report.State}
+		return ReimageRequest{}, err
+	}
+
+	requestBefore, err := snapshotById(t, "ReimageRequests", requestId)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+	systemBefore, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+
+	_, err = t.Exec("INSERT INTO ReimageEvents (ReimageRequestId, State, Message) VALUES (?, ?, ?)",
+		requestId, report.State, report.Message)
+	if err != nil {
+		log.Println("ERROR: Cannot record reimage event: " + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	if IsTerminalReimageState(report.State) {
+		_, err = t.Exec("UPDATE ReimageRequests SET State = ?, UpdatedDate = CURRENT_TIMESTAMP, CompletedDate = CURRENT_TIMESTAMP WHERE Id = ?",
+			report.State, requestId)
+		if err != nil {
+			log.Println("ERROR: Cannot update reimage request '" + strconv.Itoa(requestId) + "': " + string(err.Error()))
+			return ReimageRequest{}, err
+		}
+		_, err = t.Exec("UPDATE Systems SET Reimage = FALSE WHERE Id = ?", systemId)
+		if err != nil {
+			log.Println("ERROR: Cannot clear reimage flag for system '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+			return ReimageRequest{}, err
+		}
+	} else {
+		_, err = t.Exec("UPDATE ReimageRequests SET State = ?, UpdatedDate = CURRENT_TIMESTAMP WHERE Id = ?",
+			report.State, requestId)
+		if err != nil {
+			log.Println("ERROR: Cannot update reimage request '" + strconv.Itoa(requestId) + "': " + string(err.Error()))
+			return ReimageRequest{}, err
+		}
+	}
+
+	err = repo.auditById(t, "ReimageRequests", requestId, requestBefore)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+	err = repo.auditById(t, "Systems", systemId, systemBefore)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	log.Println("INFO: Reimage request '" + strconv.Itoa(requestId) + "' is now in state '" + report.State + "'")
+	return repo.GetReimageRequestById(requestId)
+}
+
+// CancelReimageRequest fails a system's active reimage on behalf of an
+// operator, for reimages stuck in a state the machine will never report its
+// way out of. The system's reimage flag is cleared
+func (repo *sqlRepository) CancelReimageRequest(systemId int, username string) (ReimageRequest, error) {
+	log.Println("INFO: Reimage cancellation requested for system: " + strconv.Itoa(systemId))
+	return repo.ReportReimageStatus(systemId, ReimageStatusReport{
+		State:   ReimageStateFailed,
+		Message: "Reimage cancelled by " + username,
+	})
+}
+
+func (repo *sqlRepository) GetReimageRequestById(requestId int) (ReimageRequest, error) {
+	log.Println("INFO: Reimage request by Id requested: " + strconv.Itoa(requestId))
+	request, err := scanReimageRequest(repo.db.QueryRow("SELECT * FROM ReimageRequests WHERE Id = ?", requestId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ReimageRequest{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve reimage request from DB: " + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	request.Events, err = repo.getReimageEvents(request.Id)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+
+	return request, nil
+}
+
+// GetCurrentReimageRequest returns the most recent reimage for a system,
+// whether or not it has finished
+func (repo *sqlRepository) GetCurrentReimageRequest(systemId int) (ReimageRequest, error) {
+	log.Println("INFO: Current reimage requested for system: " + strconv.Itoa(systemId))
+	request, err := scanReimageRequest(repo.db.QueryRow("SELECT * FROM ReimageRequests WHERE SystemId = ? ORDER BY Id DESC LIMIT 1", systemId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ReimageRequest{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve reimage request from DB: " + string(err.Error()))
+		return ReimageRequest{}, err
+	}
+
+	request.Events, err = repo.getReimageEvents(request.Id)
+	if err != nil {
+		return ReimageRequest{}, err
+	}
+
+	return request, nil
+}
+
+func (repo *sqlRepository) GetReimageRequestsBySystemId(systemId int) ([]ReimageRequest, error) {
+	log.Println("INFO: Reimage history requested for system: " + strconv.Itoa(systemId))
+	rows, err := repo.db.Query("SELECT * FROM ReimageRequests WHERE SystemId = ? ORDER BY Id DESC", systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve reimage requests from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]ReimageRequest, 0)
+	for rows.Next() {
+		request, err := scanReimageRequest(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the reimage request objects!" + string(err.Error()))
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	// close before fetching events so the connection is free for the next query
+	rows.Close()
+
+	for i := range requests {
+		requests[i].Events, err = repo.getReimageEvents(requests[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,376 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"time"
+
+	"github.com/greeneg/allocatord/dhcp"
+)
+
+// Each entity is reached through its repository interface. The SQL
+// implementation below backs all of them and runs against either SQLite or
+// PostgreSQL, depending on the Database it is handed
+
+type AnswerFileTemplateRepository interface {
+	CreateAnswerFileTemplate(a AnswerFileTemplate, id int) (AnswerFileTemplate, error)
+	DeleteAnswerFileTemplate(templateId int) (bool, error)
+	GetAnswerFileTemplates() ([]AnswerFileTemplate, error)
+	GetAnswerFileTemplatesByOSFamilyId(osFamilyId int) ([]AnswerFileTemplate, error)
+	GetAnswerFileTemplateById(templateId int) (AnswerFileTemplate, error)
+	GetLatestAnswerFileTemplate(osFamilyId int, templateType string) (AnswerFileTemplate, error)
+}
+
+type ApiTokenRepository interface {
+	CreateApiToken(userId int, p ProposedApiToken) (ApiToken, string, error)
+	RevokeApiToken(userId int, tokenId int) (bool, error)
+	GetApiTokensByUserId(userId int) ([]ApiToken, error)
+	GetApiTokenByToken(token string) (ApiToken, error)
+	TouchApiToken(tokenId int) error
+}
+
+type ArchitectureRepository interface {
+	CreateArchitecture(a Architecture, id int) (bool, error)
+	DeleteArchitecture(architectureId int) (bool, error)
+	GetArchitectures() ([]Architecture, error)
+	GetArchitectureById(id int) (Architecture, error)
+	GetArchitectureByName(architectureName string) (Architecture, error)
+}
+
+type AuditRepository interface {
+	GetAuditEntries(f AuditFilter) ([]AuditEntry, error)
+	GetAuditEntryById(entryId int) (AuditEntry, error)
+}
+
+type BuildingRepository interface {
+	CreateBuilding(b Building, id int) (bool, error)
+	DeleteBuilding(buildingId int) (bool, error)
+	GetBuildings() ([]Building, error)
+	GetBuildingById(id int) (Building, error)
+	GetBuildingByShortName(buildingShortName string) (Building, error)
+	UpdateBuildingById(buildingId int, b Building) (bool, error)
+}
+
+type DiscoveryRepository interface {
+	DiscoverSystem(r DiscoveryReport) (System, error)
+	ApproveSystem(systemId int, a SystemApproval) (bool, error)
+	RejectSystem(systemId int) (bool, error)
+	GetPendingSystems() ([]DiscoveredSystem, error)
+	GetDiscoveredSystem(systemId int) (DiscoveredSystem, error)
+}
+
+type HostVarRepository interface {
+	SetHostVarLayer(l HostVarLayer, id int) (bool, error)
+	DeleteHostVarLayer(scope string, scopeId int) (bool, error)
+	GetHostVarLayers() ([]HostVarLayer, error)
+	GetHostVarLayer(scope string, scopeId int) (HostVarLayer, error)
+	SetMachineRoleHostVarsSchema(machineRoleId int, schema string, id int) (bool, error)
+	DeleteMachineRoleHostVarsSchema(machineRoleId int) (bool, error)
+	GetMachineRoleHostVarsSchema(machineRoleId int) (MachineRoleHostVarsSchema, error)
+	ResolveHostVars(s System) (ResolvedHostVars, error)
+}
+
+type MachineCertificateRepository interface {
+	RequestMachineCertificate(systemId int, p ProposedMachineCertificate) (MachineCertificate, error)
+	IssueMachineCertificate(systemId int, p ProposedMachineCertificate) (MachineCertificate, error)
+	ApproveMachineCertificate(certificateId int) (MachineCertificate, error)
+	RejectMachineCertificate(certificateId int) (bool, error)
+	RevokeMachineCertificate(certificateId int) (bool, error)
+	GetMachineCertificateById(certificateId int) (MachineCertificate, error)
+	GetMachineCertificateByFingerprint(fingerprint string) (MachineCertificate, error)
+	GetMachineCertificatesBySystemId(systemId int) ([]MachineCertificate, error)
+	GetMachineCertificatesByStatus(status string) ([]MachineCertificate, error)
+}
+
+type MachineRoleRepository interface {
+	CreateMachineRole(m MachineRole, id int) (bool, error)
+	DeleteMachineRole(machineRoleId int) (bool, error)
+	GetMachineRoles() ([]MachineRole, error)
+	GetMachineRoleById(id int) (MachineRole, error)
+	GetMachineRoleByName(machineRoleName string) (MachineRole, error)
+	UpdateMachineRoleById(machineRoleId int, m MachineRole) (bool, error)
+}
+
+type MachineTokenRepository interface {
+	IssueMachineToken(systemId int, id int) (string, error)
+	RotateMachineToken(systemId int, id int) (string, error)
+	RevokeMachineToken(systemId int) (bool, error)
+	GetSystemIdByMachineToken(token string) (int, error)
+}
+
+type MaintenanceWindowRepository interface {
+	CreateMaintenanceWindow(w MaintenanceWindow, id int) (bool, error)
+	DeleteMaintenanceWindow(windowId int) (bool, error)
+	GetMaintenanceWindows() ([]MaintenanceWindow, error)
+	GetMaintenanceWindowsByBuildingId(buildingId int) ([]MaintenanceWindow, error)
+}
+
+type NetworkInterfaceRepository interface {
+	CreateNetworkInterface(n NetworkInterface, id int) (bool, error)
+	DeleteNetworkInterface(networkInterfaceId int) (bool, error)
+	GetNetworkInterfaces() ([]NetworkInterface, error)
+	GetNetworkInterfaceById(id int) (NetworkInterface, error)
+	GetNetworkInterfaceByIpAddress(ipAddr string) (NetworkInterface, error)
+	GetNetworkInterfaceByMACAddress(macAddress string) (NetworkInterface, error)
+	GetNetworkInterfacesBySystemId(systemId int) ([]NetworkInterface, error)
+	UpdateNetworkInterface(networkInterfaceId int, n NetworkInterface) (bool, error)
+}
+
+type OSFamilyRepository interface {
+	CreateOSFamily(osFamily OperatingSystemFamily, id int) (bool, error)
+	DeleteOSFamily(osFamilyIdId int) (bool, error)
+	GetOSFamilies() ([]OperatingSystemFamily, error)
+	GetOSFamilyById(id int) (OperatingSystemFamily, error)
+	GetOSFamilyByName(name string) (OperatingSystemFamily, error)
+}
+
+type OSVersionRepository interface {
+	CreateOSVersion(osVersion OperatingSystemVersion, id int) (bool, error)
+	DeleteOSVersion(osVersionId int) (bool, error)
+	GetOSVersions() ([]OperatingSystemVersion, error)
+	GetOSVersionById(id int) (OperatingSystemVersion, error)
+	GetOSVersionsByOSId(osId int) ([]OperatingSystemVersion, error)
+	GetLatestOSVersionByOSId(osId int) (OperatingSystemVersion, error)
+}
+
+type OperatingSystemRepository interface {
+	CreateOperatingSystem(os OperatingSystem, id int) (bool, error)
+	DeleteOperatingSystem(osId int) (bool, error)
+	GetOperatingSystems() ([]OperatingSystem, error)
+	GetOperatingSystemById(id int) (OperatingSystem, error)
+	GetOperatingSystemsByFamilyId(osFamilyId int) ([]OperatingSystem, error)
+	GetOperatingSystemsByVendorId(osVendorId int) ([]OperatingSystem, error)
+	UpdateOperatingSystemById(osId int, os OperatingSystem) (bool, error)
+}
+
+type OrgUnitRepository interface {
+	CreateOU(o OrgUnit, id int) (bool, error)
+	DeleteOU(ouId int) (bool, error)
+	GetOUs() ([]OrgUnit, error)
+	GetOUById(id int) (OrgUnit, error)
+	GetOUChildren(ouId int) ([]OrgUnit, error)
+	GetOUSubtree(ouId int) ([]OrgUnit, error)
+	MoveOU(ouId int, parentId int) (bool, error)
+	CreateOrgUnitDelegation(d OrgUnitDelegation, id int) (bool, error)
+	DeleteOrgUnitDelegation(ouId int, delegatedOuId int) (bool, error)
+	GetOrgUnitDelegations(ouId int) ([]OrgUnitDelegation, error)
+	GetOrgUnitScope(user User) (OrgUnitScope, error)
+}
+
+type ProvisioningRepository interface {
+	GetBootTargetByMACAddress(macAddress string) (BootTarget, error)
+	GetDhcpHosts() ([]dhcp.Host, error)
+	GetProvisioningDocument(s System) (ProvisioningDocument, error)
+}
+
+type ReimageBatchRepository interface {
+	CreateReimageBatch(p ProposedReimageBatch, id int) (ReimageBatch, error)
+	CancelReimageBatch(batchId int) (bool, error)
+	GetReimageBatches() ([]ReimageBatch, error)
+	GetReimageBatchById(batchId int) (ReimageBatch, error)
+	ProcessReimageBatches(now time.Time) error
+}
+
+type ReimageRepository interface {
+	CreateReimageRequest(systemId int, id int) (ReimageRequest, error)
+	ReportReimageStatus(systemId int, report ReimageStatusReport) (ReimageRequest, error)
+	CancelReimageRequest(systemId int, username string) (ReimageRequest, error)
+	GetReimageRequestById(requestId int) (ReimageRequest, error)
+	GetCurrentReimageRequest(systemId int) (ReimageRequest, error)
+	GetReimageRequestsBySystemId(systemId int) ([]ReimageRequest, error)
+}
+
+type RolePermissionRepository interface {
+	GrantRolePermission(roleId int, permission string, id int) (bool, error)
+	RevokeRolePermission(roleId int, permission string) (bool, error)
+	GetRolePermissions(roleId int) ([]RolePermission, error)
+	UserHasPermission(username string, permission string) (bool, error)
+}
+
+type RoleRepository interface {
+	CreateRole(r Role) (bool, error)
+	DeleteRole(roleId int) (bool, error)
+	GetRoles() ([]Role, error)
+	GetRoleById(id int) (Role, error)
+	GetRoleByName(roleName string) (Role, error)
+	SetRoleRequireTwoFactor(roleId int, j RoleRequireTwoFactor) (bool, error)
+}
+
+type SessionRepository interface {
+	CreateSession(userId int, p ProposedSession) (Session, string, error)
+	GetSessionByToken(token string) (Session, error)
+	GetSessionsByUserId(userId int) ([]Session, error)
+	TouchSession(sessionId int) error
+	RevokeSession(userId int, sessionId int) (bool, error)
+	RevokeUserSessions(userId int) (int, error)
+}
+
+type StorageVolumeRepository interface {
+	CreateStorageVolume(s StorageVolume, id int) (bool, error)
+	DeleteStorageVolume(storageVolumeId int) (bool, error)
+	GetStorageVolumes() ([]StorageVolume, error)
+	GetStorageVolumeById(id int) (StorageVolume, error)
+	GetStorageVolumeByLabel(label string, id int) (StorageVolume, error)
+	GetStorageVolumesBySystemId(systemId int) ([]StorageVolume, error)
+	UpdateStorageVolume(id int, s StorageVolume) (bool, error)
+}
+
+type SystemRepository interface {
+	CreateSystem(s System, id int) (bool, error)
+	DeleteSystem(systemId int) (bool, error)
+	GetSystems() ([]System, error)
+	GetSystemById(id int) (System, error)
+	GetSystemBySerialNumber(serialNumber string) (System, error)
+	GetSystemsByVendorId(vendorId int) ([]System, error)
+	GetSystemsByCpuCores(coreCount int) ([]System, error)
+	GetSystemsByRAM(memoryCount int) ([]System, error)
+	GetSystemsByMachineRoleId(machineRoleId int) ([]System, error)
+	GetSystemsByOuId(ouId int) ([]System, error)
+	GetSystemsByOuSubtree(ouId int) ([]System, error)
+	GetSystemsByBuildingId(buildingId int) ([]System, error)
+	UpdateSystemById(systemId int, s System) (bool, error)
+}
+
+type TwoFactorRepository interface {
+	StartTwoFactorEnrollment(userId int, secret string) (bool, error)
+	EnableTwoFactor(userId int, step int64) ([]string, error)
+	DisableTwoFactor(userId int) (bool, error)
+	GetTwoFactorByUserId(userId int) (TwoFactor, error)
+	AcceptTwoFactorStep(userId int, step int64) (bool, error)
+	UseRecoveryCode(userId int, code string) (bool, error)
+	RegenerateRecoveryCodes(userId int) ([]string, error)
+}
+
+type UserRepository interface {
+	ChangeAccountPassword(username string, oldPassword string, newPassword string) (bool, error)
+	ResetAccountPassword(username string, newPassword string) (bool, error)
+	UpgradePasswordHash(username string, hashedPassword string) (bool, error)
+	RecordFailedLogin(username string) (bool, error)
+	RecordSuccessfulLogin(username string) (bool, error)
+	UnlockUser(username string) (bool, error)
+	SetUserMustChangePassword(username string, j UserMustChangePassword) (bool, error)
+	ProvisionExternalUser(provider string, subject string, p ProposedUser) (bool, error)
+	ClaimExternalUser(username string, provider string, subject string) (bool, error)
+	SyncExternalUser(provider string, subject string, p ProposedUser) (bool, error)
+	GetUserByExternalIdentity(provider string, subject string) (User, error)
+	GetUserById(id int) (User, error)
+	GetUserByUserName(username string) (User, error)
+	CreateUser(p ProposedUser) (bool, error)
+	DeleteUser(username string) (bool, error)
+	GetUsers() ([]User, error)
+	GetUsersByOuId(ouId int) ([]User, error)
+	GetUsersByOuSubtree(ouId int) ([]User, error)
+	GetUsersByRoleId(roleId int) ([]User, error)
+	GetUserByTypeId(typeId int) ([]User, error)
+	GetUsersByTypeId(typeId int) ([]User, error)
+	GetUserStatus(username string) (string, error)
+	SetUserStatus(username string, j UserStatus) (bool, error)
+	SetUserOuId(username string, j UserOrgUnitId) (bool, error)
+	SetUserRoleId(username string, j UserRoleId) (bool, error)
+	SetUserTypeId(username string, j UserType) (bool, error)
+	CanDeleteUser(username string) (bool, error)
+	CanChangeUserStatus(username string) (bool, error)
+}
+
+type VendorRepository interface {
+	CreateVendor(v Vendor, id int) (bool, error)
+	DeleteVendor(vendorId int) (bool, error)
+	GetVendors() ([]Vendor, error)
+	GetVendorById(id int) (Vendor, error)
+	GetVendorByName(vendorName string) (Vendor, error)
+}
+
+// Repositories holds the repository of every entity
+type Repositories struct {
+	AnswerFileTemplates AnswerFileTemplateRepository
+	ApiTokens           ApiTokenRepository
+	Architectures       ArchitectureRepository
+	Audit               AuditRepository
+	Buildings           BuildingRepository
+	Discovery           DiscoveryRepository
+	HostVars            HostVarRepository
+	MachineCertificates MachineCertificateRepository
+	MachineRoles        MachineRoleRepository
+	MachineTokens       MachineTokenRepository
+	MaintenanceWindows  MaintenanceWindowRepository
+	NetworkInterfaces   NetworkInterfaceRepository
+	OSFamilies          OSFamilyRepository
+	OSVersions          OSVersionRepository
+	OperatingSystems    OperatingSystemRepository
+	OrgUnits            OrgUnitRepository
+	Provisioning        ProvisioningRepository
+	ReimageBatches      ReimageBatchRepository
+	Reimages            ReimageRepository
+	RolePermissions     RolePermissionRepository
+	Roles               RoleRepository
+	Sessions            SessionRepository
+	StorageVolumes      StorageVolumeRepository
+	Systems             SystemRepository
+	TwoFactor           TwoFactorRepository
+	Users               UserRepository
+	Vendors             VendorRepository
+}
+
+// Repos is what the daemon reads and writes through, set up by
+// ConnectDatabase. Changes made through it are recorded against the SYSTEM
+// account, use ReposFor to record them against a user
+var Repos Repositories
+
+type sqlRepository struct {
+	db    *Database
+	actor AuditActor
+}
+
+// NewRepositories returns repositories backed by the given database
+func NewRepositories(db *Database) Repositories {
+	return newRepositories(db, systemActor)
+}
+
+func newRepositories(db *Database, actor AuditActor) Repositories {
+	repo := &sqlRepository{db: db, actor: actor}
+	return Repositories{
+		AnswerFileTemplates: repo,
+		ApiTokens:           repo,
+		Architectures:       repo,
+		Audit:               repo,
+		Buildings:           repo,
+		Discovery:           repo,
+		HostVars:            repo,
+		MachineCertificates: repo,
+		MachineRoles:        repo,
+		MachineTokens:       repo,
+		MaintenanceWindows:  repo,
+		NetworkInterfaces:   repo,
+		OSFamilies:          repo,
+		OSVersions:          repo,
+		OperatingSystems:    repo,
+		OrgUnits:            repo,
+		Provisioning:        repo,
+		ReimageBatches:      repo,
+		Reimages:            repo,
+		RolePermissions:     repo,
+		Roles:               repo,
+		Sessions:            repo,
+		StorageVolumes:      repo,
+		Systems:             repo,
+		TwoFactor:           repo,
+		Users:               repo,
+		Vendors:             repo,
+	}
+}
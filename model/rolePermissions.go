@@ -0,0 +1,241 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"strconv"
+)
+
+// permissions that can be granted to roles. Reading is open to every
+// authenticated user, these cover changing things
+const (
+	PermissionAll                = "*"
+	PermissionAnswerFilesWrite   = "answerFiles:write"
+	PermissionArchitecturesWrite = "architectures:write"
+	PermissionAuditRead          = "audit:read"
+	PermissionBuildingsWrite     = "buildings:write"
+	PermissionHostVarsWrite      = "hostVars:write"
+	PermissionMachineCertsAdmin  = "machineCertificates:admin"
+	PermissionMachineRolesWrite  = "machineRoles:write"
+	PermissionMachineTokensAdmin = "machineTokens:admin"
+	PermissionMaintenanceWrite   = "maintenanceWindows:write"
+	PermissionNetworkWrite       = "networkInterfaces:write"
+	PermissionOSWrite            = "operatingSystems:write"
+	PermissionOrgUnitsGlobal     = "orgUnits:global"
+	PermissionOrgUnitsWrite      = "orgUnits:write"
+	PermissionReimageTrigger     = "reimage:trigger"
+	PermissionRolesAdmin         = "roles:admin"
+	PermissionStorageWrite       = "storageVolumes:write"
+	PermissionSystemsApprove     = "systems:approve"
+	PermissionSystemsWrite       = "systems:write"
+	PermissionUsersAdmin         = "users:admin"
+	PermissionVendorsWrite       = "vendors:write"
+)
+
+var permissionCatalog = []Permission{
+	{Name: PermissionAll, Description: "Every permission, including ones added later"},
+	{Name: PermissionAnswerFilesWrite, Description: "Create, delete and dry-run render answer file templates"},
+	{Name: PermissionArchitecturesWrite, Description: "Create and delete architectures"},
+	{Name: PermissionAuditRead, Description: "Read the audit trail of every change"},
+	{Name: PermissionBuildingsWrite, Description: "Create, update and delete buildings"},
+	{Name: PermissionHostVarsWrite, Description: "Set and delete host var layers and machine role host vars schemas"},
+	{Name: PermissionMachineCertsAdmin, Description: "Approve, reject, issue and revoke the client certificates of systems"},
+	{Name: PermissionMachineRolesWrite, Description: "Create, update and delete machine roles"},
+	{Name: PermissionMachineTokensAdmin, Description: "Issue, rotate and revoke machine tokens"},
+	{Name: PermissionMaintenanceWrite, Description: "Create and delete maintenance windows"},
+	{Name: PermissionNetworkWrite, Description: "Create, update and delete network interfaces"},
+	{Name: PermissionOSWrite, Description: "Create, update and delete operating systems, their families and versions"},
+	{Name: PermissionOrgUnitsGlobal, Description: "See and manage the systems and users of every organizational unit"},
+	{Name: PermissionOrgUnitsWrite, Description: "Create and delete organizational units and their delegations"},
+	{Name: PermissionReimageTrigger, Description: "Request or cancel reimages and schedule or cancel reimage batches"},
+	{Name: PermissionRolesAdmin, Description: "Create and delete roles and manage their permissions"},
+	{Name: PermissionStorageWrite, Description: "Create, update and delete storage volumes"},
+	{Name: PermissionSystemsApprove, Description: "Review the systems imaging clients discovered, and approve or reject them"},
+	{Name: PermissionSystemsWrite, Description: "Create, update and delete systems"},
+	{Name: PermissionUsersAdmin, Description: "Create, delete, lock and reassign user accounts"},
+	{Name: PermissionVendorsWrite, Description: "Create and delete vendors"},
+}
+
+func GetPermissions() []Permission {
+	return permissionCatalog
+}
+
+func IsValidPermission(permission string) bool {
+	for _, p := range permissionCatalog {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *sqlRepository) GrantRolePermission(roleId int, permission string, id int) (bool, error) {
+	log.Println("INFO: Permission grant requested: " + permission + " to role Id " + strconv.Itoa(roleId))
+	if !IsValidPermission(permission) {
+		return false, &UnknownPermission{Permission: permission}
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "RolePermissions", "RoleId = ? AND Permission = ?", roleId, permission)
+	if err != nil {
+		return false, err
+	}
+
+	// granting a permission the role already holds is not an error
+	q, err := t.Prepare("INSERT INTO RolePermissions (RoleId, Permission, CreatorId) VALUES (?, ?, ?) ON CONFLICT (RoleId, Permission) DO NOTHING")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(roleId, permission, id)
+	if err != nil {
+		log.Println("ERROR: Cannot grant permission '" + permission + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "RolePermissions", before, "RoleId = ? AND Permission = ?", roleId, permission)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Permission '" + permission + "' granted to role Id " + strconv.Itoa(roleId))
+	return true, nil
+}
+
+func (repo *sqlRepository) RevokeRolePermission(roleId int, permission string) (bool, error) {
+	log.Println("INFO: Permission revocation requested: " + permission + " from role Id " + strconv.Itoa(roleId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "RolePermissions", "RoleId = ? AND Permission = ?", roleId, permission)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM RolePermissions WHERE RoleId = ? AND Permission = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(roleId, permission)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke permission '" + permission + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "RolePermissions", before, "RoleId = ? AND Permission = ?", roleId, permission)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Permission '" + permission + "' revoked from role Id " + strconv.Itoa(roleId))
+	return true, nil
+}
+
+func (repo *sqlRepository) GetRolePermissions(roleId int) ([]RolePermission, error) {
+	log.Println("INFO: List of permissions requested for role Id " + strconv.Itoa(roleId))
+	rows, err := repo.db.Query("SELECT * FROM RolePermissions WHERE RoleId = ? ORDER BY Permission", roleId)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]RolePermission, 0)
+	for rows.Next() {
+		permission := RolePermission{}
+		err = rows.Scan(
+			&permission.Id,
+			&permission.RoleId,
+			&permission.Permission,
+			&permission.CreatorId,
+			&permission.CreationDate,
+		)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the role permission objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		permission.CreationDate = ConvertSqliteTimestamp(permission.CreationDate)
+
+		permissions = append(permissions, permission)
+	}
+
+	log.Println("INFO: List of permissions for role Id " + strconv.Itoa(roleId) + " retrieved")
+	return permissions, nil
+}
+
+// UserHasPermission reports whether the role of the user holds the permission,
+// either by name or through the wildcard
+func (repo *sqlRepository) UserHasPermission(username string, permission string) (bool, error) {
+	var granted bool
+	err := repo.db.QueryRow(`SELECT EXISTS (
+		SELECT 1 FROM Users u
+		JOIN RolePermissions p ON p.RoleId = u.RoleId
+		WHERE u.UserName = ? AND p.Permission IN (?, '*'))`, username, permission).Scan(&granted)
+	if err != nil {
+		log.Println("ERROR: Cannot check permission '" + permission + "' of user '" + username + "': " + string(err.Error()))
+		return false, err
+	}
+
+	return granted, nil
+}
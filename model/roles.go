@@ -24,9 +24,9 @@ import (
 	"strconv"
 )
 
-func CreateRole(r Role) (bool, error) {
+func (repo *sqlRepository) CreateRole(r Role) (bool, error) {
 	log.Println("INFO: User creation requested: " + r.RoleName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +42,24 @@ func CreateRole(r Role) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Roles (RoleName, Description) VALUES (?, ?)")
+	q, err := t.Prepare("INSERT INTO Roles (RoleName, Description) VALUES (?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(r.RoleName, r.Description)
+	var newId int
+	err = q.QueryRow(r.RoleName, r.Description).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create user '" + r.RoleName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "Roles", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +70,9 @@ func CreateRole(r Role) (bool, error) {
 	return true, nil
 }
 
-func DeleteRole(roleId int) (bool, error) {
+func (repo *sqlRepository) DeleteRole(roleId int) (bool, error) {
 	log.Println("INFO: Role deletion requested: " + strconv.Itoa(roleId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +88,12 @@ func DeleteRole(roleId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM Roles WHERE Id IS ?")
+	before, err := snapshotById(t, "Roles", roleId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Roles WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +105,11 @@ func DeleteRole(roleId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "Roles", roleId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +120,9 @@ func DeleteRole(roleId int) (bool, error) {
 	return true, nil
 }
 
-func GetRoles() ([]Role, error) {
+func (repo *sqlRepository) GetRoles() ([]Role, error) {
 	log.Println("INFO: List of role object requested")
-	rows, err := DB.Query("SELECT * FROM Roles")
+	rows, err := repo.db.Query("SELECT * FROM Roles")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -121,6 +137,7 @@ func GetRoles() ([]Role, error) {
 			&role.RoleName,
 			&role.Description,
 			&role.CreationDate,
+			&role.RequireTwoFactor,
 		)
 		if err != nil {
 			log.Println("ERROR: Cannot marshal the user objects!" + string(err.Error()))
@@ -136,9 +153,9 @@ func GetRoles() ([]Role, error) {
 	return roles, nil
 }
 
-func GetRoleById(id int) (Role, error) {
+func (repo *sqlRepository) GetRoleById(id int) (Role, error) {
 	log.Println("INFO: Role by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM Roles WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Roles WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Role{}, err
@@ -147,25 +164,19 @@ func GetRoleById(id int) (Role, error) {
 
 	role := Role{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
-			return Role{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
-		return Role{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&role.Id,
 		&role.RoleName,
 		&role.Description,
 		&role.CreationDate,
+		&role.RequireTwoFactor,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
+			return Role{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
 		return Role{}, err
 	}
 
@@ -175,9 +186,9 @@ func GetRoleById(id int) (Role, error) {
 	return role, nil
 }
 
-func GetRoleByName(roleName string) (Role, error) {
+func (repo *sqlRepository) GetRoleByName(roleName string) (Role, error) {
 	log.Println("INFO: Role by Id requested: " + roleName)
-	rec, err := DB.Prepare("SELECT * FROM Roles WHERE RoleName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Roles WHERE RoleName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Role{}, err
@@ -186,25 +197,19 @@ func GetRoleByName(roleName string) (Role, error) {
 
 	role := Role{}
 
-	r, err := rec.Query(roleName)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
-			return Role{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
-		return Role{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(roleName).Scan(
 		&role.Id,
 		&role.RoleName,
 		&role.Description,
 		&role.CreationDate,
+		&role.RequireTwoFactor,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
+			return Role{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve user from DB: " + string(err.Error()))
 		return Role{}, err
 	}
 
@@ -213,3 +218,49 @@ func GetRoleByName(roleName string) (Role, error) {
 	log.Println("INFO: Role with Id '" + strconv.Itoa(role.Id) + "' has been retrieved")
 	return role, nil
 }
+
+// SetRoleRequireTwoFactor sets whether users holding the role have to log in
+// with a second factor
+func (repo *sqlRepository) SetRoleRequireTwoFactor(roleId int, j RoleRequireTwoFactor) (bool, error) {
+	log.Println("INFO: Set two-factor requirement for role Id: " + strconv.Itoa(roleId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "Roles", roleId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("UPDATE Roles SET RequireTwoFactor = ? WHERE Id = ?", j.RequireTwoFactor, roleId)
+	if err != nil {
+		log.Println("ERROR: Cannot update role Id " + strconv.Itoa(roleId) + ": " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "Roles", roleId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
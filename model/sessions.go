@@ -0,0 +1,299 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/greeneg/allocatord/websession"
+)
+
+// how a session was started
+const (
+	SessionAuthPassword = "password"
+	SessionAuthOidc     = "oidc"
+)
+
+// Expired reports whether the session has run past its expiry date or gone
+// unused for longer than the idle timeout
+func (s Session) Expired(now time.Time) bool {
+	expiry, err := ParseTimestamp(s.ExpiryDate)
+	if err != nil || !now.Before(expiry) {
+		return true
+	}
+	lastSeen, err := ParseTimestamp(s.LastSeenDate)
+	if err != nil {
+		return true
+	}
+	return !now.Before(lastSeen.Add(websession.IdleTimeout()))
+}
+
+func scanSession(row interface{ Scan(...any) error }) (Session, error) {
+	session := Session{}
+	err := row.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.TokenHash,
+		&session.AuthMethod,
+		&session.ClientAddress,
+		&session.UserAgent,
+		&session.CreationDate,
+		&session.LastSeenDate,
+		&session.ExpiryDate,
+	)
+	if err != nil {
+		return Session{}, err
+	}
+	session.CreationDate = ConvertSqliteTimestamp(session.CreationDate)
+	session.LastSeenDate = ConvertSqliteTimestamp(session.LastSeenDate)
+	session.ExpiryDate = ConvertSqliteTimestamp(session.ExpiryDate)
+
+	return session, nil
+}
+
+// sessionCutoffs returns the expiry date at or before which a session has run
+// out, and the last seen date at or before which it has been idle too long
+func sessionCutoffs(now time.Time) (string, string) {
+	now = now.UTC()
+	return now.Format(sqliteTimeLayout), now.Add(-websession.IdleTimeout()).Format(sqliteTimeLayout)
+}
+
+// CreateSession starts a session for a user who has just logged in, clearing
+// out any of theirs that have ended. Only the hash of the token is stored,
+// so the returned token cannot be retrieved again later. Logging in is not a
+// change to anything, so it is left out of the audit trail
+func (repo *sqlRepository) CreateSession(userId int, p ProposedSession) (Session, string, error) {
+	log.Println("INFO: Session requested for user Id: " + strconv.Itoa(userId))
+	token, err := generateToken()
+	if err != nil {
+		log.Println("ERROR: Could not generate session token!" + string(err.Error()))
+		return Session{}, "", err
+	}
+
+	now := time.Now().UTC()
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return Session{}, "", err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	expired, idle := sessionCutoffs(now)
+	_, err = t.Exec("DELETE FROM Sessions WHERE UserId = ? AND (ExpiryDate <= ? OR LastSeenDate <= ?)", userId, expired, idle)
+	if err != nil {
+		log.Println("ERROR: Cannot remove ended sessions: " + string(err.Error()))
+		return Session{}, "", err
+	}
+
+	var sessionId int
+	err = t.QueryRow("INSERT INTO Sessions (UserId, TokenHash, AuthMethod, ClientAddress, UserAgent, CreationDate, LastSeenDate, ExpiryDate) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+		userId, hashToken(token), p.AuthMethod, p.ClientAddress, p.UserAgent,
+		now.Format(sqliteTimeLayout), now.Format(sqliteTimeLayout), now.Add(websession.MaxAge()).Format(sqliteTimeLayout)).Scan(&sessionId)
+	if err != nil {
+		log.Println("ERROR: Cannot store session: " + string(err.Error()))
+		return Session{}, "", err
+	}
+
+	session, err := scanSession(t.QueryRow("SELECT * FROM Sessions WHERE Id = ?", sessionId))
+	if err != nil {
+		log.Println("ERROR: Cannot read back session Id " + strconv.Itoa(sessionId) + ": " + string(err.Error()))
+		return Session{}, "", err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return Session{}, "", err
+	}
+
+	log.Println("INFO: Session Id " + strconv.Itoa(sessionId) + " started for user Id: " + strconv.Itoa(userId))
+	return session, token, nil
+}
+
+// GetSessionByToken returns the session a token belongs to, or an empty
+// session if it is unknown or has been revoked. Whether it has ended is left
+// to the caller
+func (repo *sqlRepository) GetSessionByToken(token string) (Session, error) {
+	session, err := scanSession(repo.db.QueryRow("SELECT * FROM Sessions WHERE TokenHash = ?", hashToken(token)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("WARN: Unknown session token presented")
+			return Session{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve session from DB: " + string(err.Error()))
+		return Session{}, err
+	}
+
+	return session, nil
+}
+
+// GetSessionsByUserId returns the sessions of a user that have not ended
+func (repo *sqlRepository) GetSessionsByUserId(userId int) ([]Session, error) {
+	expired, idle := sessionCutoffs(time.Now())
+	rows, err := repo.db.Query("SELECT * FROM Sessions WHERE UserId = ? AND ExpiryDate > ? AND LastSeenDate > ? ORDER BY Id", userId, expired, idle)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve sessions from DB: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot scan the session object!" + string(err.Error()))
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records that a session has just been used, which keeps it
+// from going idle. This is bookkeeping rather than a change anybody made, so
+// it is left out of the audit trail
+func (repo *sqlRepository) TouchSession(sessionId int) error {
+	_, err := repo.db.Exec("UPDATE Sessions SET LastSeenDate = ? WHERE Id = ?", time.Now().UTC().Format(sqliteTimeLayout), sessionId)
+	if err != nil {
+		log.Println("ERROR: Cannot record use of session Id " + strconv.Itoa(sessionId) + ": " + string(err.Error()))
+	}
+	return err
+}
+
+// RevokeSession ends one of a user's sessions, reporting whether they had a
+// session with that Id
+func (repo *sqlRepository) RevokeSession(userId int, sessionId int) (bool, error) {
+	log.Println("INFO: Revocation of session Id " + strconv.Itoa(sessionId) + " requested for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "Sessions", "Id = ? AND UserId = ?", sessionId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec("DELETE FROM Sessions WHERE Id = ? AND UserId = ?", sessionId, userId)
+	if err != nil {
+		log.Println("ERROR: Cannot revoke session Id " + strconv.Itoa(sessionId) + ": " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "Sessions", before, "Id = ? AND UserId = ?", sessionId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+// RevokeUserSessions ends every session of a user, returning how many there
+// were
+func (repo *sqlRepository) RevokeUserSessions(userId int) (int, error) {
+	log.Println("INFO: Revocation of all sessions requested for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	rows, err := t.Query("SELECT Id FROM Sessions WHERE UserId = ?", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve sessions from DB: " + string(err.Error()))
+		return 0, err
+	}
+	sessionIds := make([]int, 0)
+	for rows.Next() {
+		var sessionId int
+		err = rows.Scan(&sessionId)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sessionIds = append(sessionIds, sessionId)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sessionId := range sessionIds {
+		var before rowSnapshot
+		before, err = snapshotById(t, "Sessions", sessionId)
+		if err != nil {
+			return 0, err
+		}
+		_, err = t.Exec("DELETE FROM Sessions WHERE Id = ?", sessionId)
+		if err != nil {
+			log.Println("ERROR: Cannot revoke session Id " + strconv.Itoa(sessionId) + ": " + string(err.Error()))
+			return 0, err
+		}
+		err = repo.auditById(t, "Sessions", sessionId, before)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return 0, err
+	}
+
+	return len(sessionIds), nil
+}
@@ -25,9 +25,9 @@ import (
 	"strconv"
 )
 
-func CreateStorageVolume(s StorageVolume, id int) (bool, error) {
+func (repo *sqlRepository) CreateStorageVolume(s StorageVolume, id int) (bool, error) {
 	log.Println("INFO: Storage Volume creation requested: " + s.VolumeName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -43,18 +43,24 @@ func CreateStorageVolume(s StorageVolume, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO StorageVolumes (VolumeName, StorageType, DeviceModel, DeviceId, MountPoint, VolumeSize, VolumeFormat, VolumeLabel, SystemId, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	q, err := t.Prepare("INSERT INTO StorageVolumes (VolumeName, StorageType, DeviceModel, DeviceId, MountPoint, VolumeSize, VolumeFormat, VolumeLabel, SystemId, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(s.VolumeName, s.StorageType, s.DeviceModel, s.DeviceId, s.MountPoint, s.VolumeSize, s.VolumeFormat, s.VolumeLabel, s.SystemId, id)
+	var newId int
+	err = q.QueryRow(s.VolumeName, s.StorageType, s.DeviceModel, s.DeviceId, s.MountPoint, s.VolumeSize, s.VolumeFormat, s.VolumeLabel, s.SystemId, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create storage volume '" + s.VolumeName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "StorageVolumes", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -65,9 +71,9 @@ func CreateStorageVolume(s StorageVolume, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteStorageVolume(storageVolumeId int) (bool, error) {
+func (repo *sqlRepository) DeleteStorageVolume(storageVolumeId int) (bool, error) {
 	log.Println("INFO: Storage Volume deletion requested: " + strconv.Itoa(storageVolumeId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -83,7 +89,12 @@ func DeleteStorageVolume(storageVolumeId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM StorageVolumes WHERE Id IS ?")
+	before, err := snapshotById(t, "StorageVolumes", storageVolumeId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM StorageVolumes WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -95,6 +106,11 @@ func DeleteStorageVolume(storageVolumeId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "StorageVolumes", storageVolumeId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -105,9 +121,9 @@ func DeleteStorageVolume(storageVolumeId int) (bool, error) {
 	return true, nil
 }
 
-func GetStorageVolumes() ([]StorageVolume, error) {
+func (repo *sqlRepository) GetStorageVolumes() ([]StorageVolume, error) {
 	log.Println("INFO: List of storage volume object requested")
-	rows, err := DB.Query("SELECT * FROM StorageVolumes")
+	rows, err := repo.db.Query("SELECT * FROM StorageVolumes")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -145,9 +161,9 @@ func GetStorageVolumes() ([]StorageVolume, error) {
 	return volumes, nil
 }
 
-func GetStorageVolumeById(id int) (StorageVolume, error) {
+func (repo *sqlRepository) GetStorageVolumeById(id int) (StorageVolume, error) {
 	log.Println("INFO: Storage Volume by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM StorageVolumes WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM StorageVolumes WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return StorageVolume{}, err
@@ -156,18 +172,7 @@ func GetStorageVolumeById(id int) (StorageVolume, error) {
 
 	volume := StorageVolume{}
 
-	r, err := rec.Query(id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such storage volume found in DB: " + string(err.Error()))
-			return StorageVolume{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve storage volume from DB: " + string(err.Error()))
-		return StorageVolume{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id).Scan(
 		&volume.Id,
 		&volume.VolumeName,
 		&volume.StorageType,
@@ -182,7 +187,11 @@ func GetStorageVolumeById(id int) (StorageVolume, error) {
 		&volume.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the storage volume object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such storage volume found in DB: " + string(err.Error()))
+			return StorageVolume{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve storage volume from DB: " + string(err.Error()))
 		return StorageVolume{}, err
 	}
 
@@ -192,9 +201,9 @@ func GetStorageVolumeById(id int) (StorageVolume, error) {
 	return volume, nil
 }
 
-func GetStorageVolumeByLabel(label string, id int) (StorageVolume, error) {
+func (repo *sqlRepository) GetStorageVolumeByLabel(label string, id int) (StorageVolume, error) {
 	log.Println("INFO: Storage Volume by label requested: " + label)
-	rec, err := DB.Prepare("SELECT * FROM StorageVolumes WHERE Id = ? AND VolumeLabel = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM StorageVolumes WHERE SystemId = ? AND VolumeLabel = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return StorageVolume{}, err
@@ -203,18 +212,7 @@ func GetStorageVolumeByLabel(label string, id int) (StorageVolume, error) {
 
 	volume := StorageVolume{}
 
-	r, err := rec.Query(id, label)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Println("ERROR: No such storage volume found in DB: " + string(err.Error()))
-			return StorageVolume{}, nil
-		}
-		log.Println("ERROR: Cannot retrieve storage volume from DB: " + string(err.Error()))
-		return StorageVolume{}, err
-	}
-	defer r.Close()
-
-	err = r.Scan(
+	err = rec.QueryRow(id, label).Scan(
 		&volume.Id,
 		&volume.VolumeName,
 		&volume.StorageType,
@@ -229,7 +227,11 @@ func GetStorageVolumeByLabel(label string, id int) (StorageVolume, error) {
 		&volume.CreationDate,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the storage volume object!" + string(err.Error()))
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such storage volume found in DB: " + string(err.Error()))
+			return StorageVolume{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve storage volume from DB: " + string(err.Error()))
 		return StorageVolume{}, err
 	}
 
@@ -239,9 +241,9 @@ func GetStorageVolumeByLabel(label string, id int) (StorageVolume, error) {
 	return volume, nil
 }
 
-func GetStorageVolumesBySystemId(systemId int) ([]StorageVolume, error) {
+func (repo *sqlRepository) GetStorageVolumesBySystemId(systemId int) ([]StorageVolume, error) {
 	log.Println("INFO: Storage Volumes by System Id requested: " + strconv.Itoa(systemId))
-	rec, err := DB.Prepare("SELECT * FROM StorageVolumes WHERE SystemId = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM StorageVolumes WHERE SystemId = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return nil, err
@@ -268,6 +270,7 @@ func GetStorageVolumesBySystemId(systemId int) ([]StorageVolume, error) {
 			&storageVolume.MountPoint,
 			&storageVolume.VolumeSize,
 			&storageVolume.VolumeFormat,
+			&storageVolume.VolumeLabel,
 			&storageVolume.SystemId,
 			&storageVolume.CreatorId,
 			&storageVolume.CreationDate,
@@ -290,9 +293,9 @@ func GetStorageVolumesBySystemId(systemId int) ([]StorageVolume, error) {
 	return storageVolumes, nil
 }
 
-func UpdateStorageVolume(id int, s StorageVolume) (bool, error) {
+func (repo *sqlRepository) UpdateStorageVolume(id int, s StorageVolume) (bool, error) {
 	log.Println("INFO: Update storage volume by Id requested: " + strconv.Itoa(id))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -308,6 +311,11 @@ func UpdateStorageVolume(id int, s StorageVolume) (bool, error) {
 		}
 	}()
 
+	before, err := snapshotById(t, "StorageVolumes", id)
+	if err != nil {
+		return false, err
+	}
+
 	q, err := t.Prepare("UPDATE StorageVolumes SET VolumeName = ?, StorageType = ?, DeviceModel = ?, DeviceId = ?, MountPoint = ?, VolumeSize = ?, VolumeFormat = ?, VolumeLabel = ?, SystemId = ? WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
@@ -326,6 +334,11 @@ func UpdateStorageVolume(id int, s StorageVolume) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "StorageVolumes", id, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
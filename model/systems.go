@@ -0,0 +1,388 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+)
+
+func (repo *sqlRepository) CreateSystem(s System, id int) (bool, error) {
+	log.Println("INFO: System creation requested: " + s.SerialNumber)
+	if strings.TrimSpace(s.HostVars) == "" {
+		s.HostVars = "{}"
+	}
+	err := repo.validateSystemHostVars(s)
+	if err != nil {
+		return false, err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	q, err := t.Prepare("INSERT INTO Systems (SerialNumber, ModelId, OperatingSystemId, Reimage, HostVars, BilledToOrgUnitId, MachineRoleId, BuildingId, VendorId, ArchitectureId, RAM, CPUCores, CreatorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	var newId int
+	err = q.QueryRow(s.SerialNumber, s.ModelId, s.OperatingSystemId, s.Reimage, s.HostVars, s.BilledToOrgUnitId,
+		s.MachineRoleId, s.BuildingId, s.VendorId, s.ArchitectureId, s.RAM, s.CpuCores, id).Scan(&newId)
+	if err != nil {
+		log.Println("ERROR: Cannot create system '" + s.SerialNumber + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "Systems", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: System '" + s.SerialNumber + "' created")
+	return true, nil
+}
+
+func (repo *sqlRepository) DeleteSystem(systemId int) (bool, error) {
+	log.Println("INFO: System deletion requested: " + strconv.Itoa(systemId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Systems WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot delete system with Id '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "Systems", systemId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: System with Id '" + strconv.Itoa(systemId) + "' has been deleted")
+	return true, nil
+}
+
+const (
+	SystemStatusActive  = "active"
+	SystemStatusPending = "pending"
+)
+
+// scanSystem reads a row of the Systems table. Systems waiting for approval
+// leave the columns an operator assigns empty, which read as 0
+func scanSystem(row interface{ Scan(...any) error }) (System, error) {
+	system := System{}
+	var modelId, osId, orgUnitId, machineRoleId, buildingId, vendorId, architectureId sql.NullInt64
+	err := row.Scan(
+		&system.Id,
+		&system.SerialNumber,
+		&modelId,
+		&osId,
+		&system.Reimage,
+		&system.HostVars,
+		&orgUnitId,
+		&machineRoleId,
+		&buildingId,
+		&vendorId,
+		&architectureId,
+		&system.RAM,
+		&system.CpuCores,
+		&system.CreatorId,
+		&system.CreationDate,
+		&system.Status,
+	)
+	if err != nil {
+		return System{}, err
+	}
+	system.ModelId = int(modelId.Int64)
+	system.OperatingSystemId = int(osId.Int64)
+	system.BilledToOrgUnitId = int(orgUnitId.Int64)
+	system.MachineRoleId = int(machineRoleId.Int64)
+	system.BuildingId = int(buildingId.Int64)
+	system.VendorId = int(vendorId.Int64)
+	system.ArchitectureId = int(architectureId.Int64)
+	system.CreationDate = ConvertSqliteTimestamp(system.CreationDate)
+
+	return system, nil
+}
+
+// querySystems runs a SELECT against the Systems table and marshals every
+// returned row. It backs the various list lookups below
+func (repo *sqlRepository) querySystems(query string, args ...any) ([]System, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	systems := make([]System, 0)
+	for rows.Next() {
+		system, err := scanSystem(rows)
+		if err != nil {
+			log.Println("ERROR: Cannot marshal the system objects!" + string(err.Error()))
+			return nil, err
+		}
+
+		systems = append(systems, system)
+	}
+
+	return systems, nil
+}
+
+// querySystem runs a SELECT against the Systems table that is expected to
+// return a single row. An empty System is returned if nothing matched
+func (repo *sqlRepository) querySystem(query string, args ...any) (System, error) {
+	system, err := scanSystem(repo.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Println("ERROR: No such system found in DB: " + string(err.Error()))
+			return System{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve system from DB: " + string(err.Error()))
+		return System{}, err
+	}
+
+	return system, nil
+}
+
+func (repo *sqlRepository) GetSystems() ([]System, error) {
+	log.Println("INFO: List of system objects requested")
+	systems, err := repo.querySystems("SELECT * FROM Systems")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of all systems retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemById(id int) (System, error) {
+	log.Println("INFO: System by Id requested: " + strconv.Itoa(id))
+	system, err := repo.querySystem("SELECT * FROM Systems WHERE Id = ?", id)
+	if err != nil {
+		return System{}, err
+	}
+
+	log.Println("INFO: System with Id '" + strconv.Itoa(id) + "' has been retrieved")
+	return system, nil
+}
+
+func (repo *sqlRepository) GetSystemBySerialNumber(serialNumber string) (System, error) {
+	log.Println("INFO: System by serial number requested: " + serialNumber)
+	system, err := repo.querySystem("SELECT * FROM Systems WHERE SerialNumber = ?", serialNumber)
+	if err != nil {
+		return System{}, err
+	}
+
+	log.Println("INFO: System with serial number '" + serialNumber + "' has been retrieved")
+	return system, nil
+}
+
+func (repo *sqlRepository) GetSystemsByVendorId(vendorId int) ([]System, error) {
+	log.Println("INFO: Systems by vendor Id requested: " + strconv.Itoa(vendorId))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE VendorId = ?", vendorId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with vendor Id '" + strconv.Itoa(vendorId) + "' retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemsByCpuCores(coreCount int) ([]System, error) {
+	log.Println("INFO: Systems by CPU core count requested: " + strconv.Itoa(coreCount))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE CPUCores = ?", coreCount)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with '" + strconv.Itoa(coreCount) + "' CPU cores retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemsByRAM(memoryCount int) ([]System, error) {
+	log.Println("INFO: Systems by installed RAM requested: " + strconv.Itoa(memoryCount))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE RAM = ?", memoryCount)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with '" + strconv.Itoa(memoryCount) + "' RAM retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemsByMachineRoleId(machineRoleId int) ([]System, error) {
+	log.Println("INFO: Systems by machine role Id requested: " + strconv.Itoa(machineRoleId))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE MachineRoleId = ?", machineRoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with machine role Id '" + strconv.Itoa(machineRoleId) + "' retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemsByOuId(ouId int) ([]System, error) {
+	log.Println("INFO: Systems by organizational unit Id requested: " + strconv.Itoa(ouId))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE BilledToOrgUnitId = ?", ouId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with organizational unit Id '" + strconv.Itoa(ouId) + "' retrieved")
+	return systems, nil
+}
+
+// GetSystemsByOuSubtree returns the systems billed to an organizational unit or
+// to any organizational unit below it
+func (repo *sqlRepository) GetSystemsByOuSubtree(ouId int) ([]System, error) {
+	log.Println("INFO: Systems by organizational unit subtree requested: " + strconv.Itoa(ouId))
+	systems, err := repo.querySystems(orgUnitSubtree+"SELECT * FROM Systems WHERE BilledToOrgUnitId IN (SELECT Id FROM Subtree)", ouId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems below organizational unit Id '" + strconv.Itoa(ouId) + "' retrieved")
+	return systems, nil
+}
+
+func (repo *sqlRepository) GetSystemsByBuildingId(buildingId int) ([]System, error) {
+	log.Println("INFO: Systems by building Id requested: " + strconv.Itoa(buildingId))
+	systems, err := repo.querySystems("SELECT * FROM Systems WHERE BuildingId = ?", buildingId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("INFO: List of systems with building Id '" + strconv.Itoa(buildingId) + "' retrieved")
+	return systems, nil
+}
+
+// UpdateSystemById changes a system. Its Reimage flag is left alone, that is
+// only ever set and cleared through its reimage requests
+func (repo *sqlRepository) UpdateSystemById(systemId int, s System) (bool, error) {
+	log.Println("INFO: Update system by Id requested: " + strconv.Itoa(systemId))
+	if strings.TrimSpace(s.HostVars) == "" {
+		s.HostVars = "{}"
+	}
+	s.Id = systemId
+	err := repo.validateSystemHostVars(s)
+	if err != nil {
+		return false, err
+	}
+
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotById(t, "Systems", systemId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE Systems SET SerialNumber = ?, ModelId = ?, OperatingSystemId = ?, HostVars = ?, BilledToOrgUnitId = ?, MachineRoleId = ?, BuildingId = ?, VendorId = ?, ArchitectureId = ?, RAM = ?, CPUCores = ? WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(s.SerialNumber, s.ModelId, s.OperatingSystemId, s.HostVars, s.BilledToOrgUnitId,
+		s.MachineRoleId, s.BuildingId, s.VendorId, s.ArchitectureId, s.RAM, s.CpuCores, systemId)
+	if err != nil {
+		log.Println("ERROR: Cannot update system with Id '" + strconv.Itoa(systemId) + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "Systems", systemId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: System with Id '" + strconv.Itoa(systemId) + "' has been updated")
+	return true, nil
+}
@@ -0,0 +1,399 @@
+package model
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many recovery codes a user is handed at a time
+const recoveryCodeCount = 10
+
+// recovery codes are 16 base32 characters, shown in groups of four
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeRecoveryCode lets codes be typed without their dashes or in upper
+// case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func scanTwoFactor(row interface{ Scan(...any) error }) (TwoFactor, error) {
+	tf := TwoFactor{}
+	err := row.Scan(
+		&tf.Id,
+		&tf.UserId,
+		&tf.Secret,
+		&tf.Enabled,
+		&tf.LastUsedStep,
+		&tf.CreationDate,
+	)
+	if err != nil {
+		return TwoFactor{}, err
+	}
+	tf.CreationDate = ConvertSqliteTimestamp(tf.CreationDate)
+
+	return tf, nil
+}
+
+// replaceRecoveryCodes hands the user a fresh set of recovery codes, voiding
+// any they had before. Only the hashes are stored
+func replaceRecoveryCodes(t *Tx, userId int) ([]string, error) {
+	_, err := t.Exec("DELETE FROM RecoveryCodes WHERE UserId = ?", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot remove old recovery codes: " + string(err.Error()))
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			log.Println("ERROR: Could not generate recovery code!" + string(err.Error()))
+			return nil, err
+		}
+		_, err = t.Exec("INSERT INTO RecoveryCodes (UserId, CodeHash) VALUES (?, ?)", userId, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			log.Println("ERROR: Cannot store recovery code: " + string(err.Error()))
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// StartTwoFactorEnrollment stores a new TOTP secret for the user, which only
+// takes effect once confirmed with EnableTwoFactor. A user who already has
+// two-factor authentication enabled keeps their secret, and false is
+// returned
+func (repo *sqlRepository) StartTwoFactorEnrollment(userId int, secret string) (bool, error) {
+	log.Println("INFO: Two-factor enrollment requested for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var enabled bool
+	err = t.QueryRow("SELECT Enabled FROM TwoFactorSecrets WHERE UserId = ?", userId).Scan(&enabled)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot check for an existing TOTP secret: " + string(err.Error()))
+		return false, err
+	}
+	if enabled {
+		err = t.Rollback()
+		return false, err
+	}
+
+	// an enrollment that was never confirmed is simply started over
+	before, err := snapshotRow(t, "TwoFactorSecrets", "UserId = ?", userId)
+	if err != nil {
+		return false, err
+	}
+	_, err = t.Exec("DELETE FROM TwoFactorSecrets WHERE UserId = ?", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot remove unconfirmed TOTP secret: " + string(err.Error()))
+		return false, err
+	}
+	_, err = t.Exec("INSERT INTO TwoFactorSecrets (UserId, Secret) VALUES (?, ?)", userId, secret)
+	if err != nil {
+		log.Println("ERROR: Cannot store TOTP secret: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "TwoFactorSecrets", before, "UserId = ?", userId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// EnableTwoFactor turns on two-factor authentication for a user whose
+// enrollment has been confirmed with the code of the given time step,
+// returning their recovery codes. No codes are returned when the user has
+// no enrollment waiting to be confirmed
+func (repo *sqlRepository) EnableTwoFactor(userId int, step int64) ([]string, error) {
+	log.Println("INFO: Enabling two-factor authentication for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "TwoFactorSecrets", "UserId = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.Exec("UPDATE TwoFactorSecrets SET Enabled = TRUE, LastUsedStep = ? WHERE UserId = ? AND Enabled = FALSE", step, userId)
+	if err != nil {
+		log.Println("ERROR: Cannot enable two-factor authentication: " + string(err.Error()))
+		return nil, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return nil, err
+	}
+	if numberOfRows == 0 {
+		err = t.Rollback()
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(t, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.auditRow(t, "TwoFactorSecrets", before, "UserId = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return nil, err
+	}
+
+	log.Println("INFO: Two-factor authentication enabled for user Id: " + strconv.Itoa(userId))
+	return codes, nil
+}
+
+// DisableTwoFactor removes a user's TOTP secret and recovery codes,
+// reporting whether they had a secret
+func (repo *sqlRepository) DisableTwoFactor(userId int) (bool, error) {
+	log.Println("INFO: Disabling two-factor authentication for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "TwoFactorSecrets", "UserId = ?", userId)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = t.Exec("DELETE FROM RecoveryCodes WHERE UserId = ?", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot remove recovery codes: " + string(err.Error()))
+		return false, err
+	}
+	result, err := t.Exec("DELETE FROM TwoFactorSecrets WHERE UserId = ?", userId)
+	if err != nil {
+		log.Println("ERROR: Cannot remove TOTP secret: " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "TwoFactorSecrets", before, "UserId = ?", userId)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+// GetTwoFactorByUserId returns the TOTP enrollment of a user, or an empty one
+// if they have none
+func (repo *sqlRepository) GetTwoFactorByUserId(userId int) (TwoFactor, error) {
+	tf, err := scanTwoFactor(repo.db.QueryRow("SELECT * FROM TwoFactorSecrets WHERE UserId = ?", userId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TwoFactor{}, nil
+		}
+		log.Println("ERROR: Cannot retrieve TOTP secret from DB: " + string(err.Error()))
+		return TwoFactor{}, err
+	}
+
+	err = repo.db.QueryRow("SELECT COUNT(*) FROM RecoveryCodes WHERE UserId = ? AND UsedDate IS NULL", userId).Scan(&tf.RecoveryCodesLeft)
+	if err != nil {
+		log.Println("ERROR: Cannot count recovery codes: " + string(err.Error()))
+		return TwoFactor{}, err
+	}
+
+	return tf, nil
+}
+
+// AcceptTwoFactorStep records the time step of a code a user has just logged
+// in with, reporting false if a code from that step or a later one was
+// already used. This is bookkeeping rather than a change anybody made, so it
+// is left out of the audit trail
+func (repo *sqlRepository) AcceptTwoFactorStep(userId int, step int64) (bool, error) {
+	result, err := repo.db.Exec("UPDATE TwoFactorSecrets SET LastUsedStep = ? WHERE UserId = ? AND Enabled = TRUE AND LastUsedStep < ?", step, userId, step)
+	if err != nil {
+		log.Println("ERROR: Cannot record TOTP code use: " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+// UseRecoveryCode spends one of a user's recovery codes, reporting whether
+// it was one they had not used yet
+func (repo *sqlRepository) UseRecoveryCode(userId int, code string) (bool, error) {
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var codeId int
+	err = t.QueryRow("SELECT Id FROM RecoveryCodes WHERE UserId = ? AND CodeHash = ? AND UsedDate IS NULL",
+		userId, hashToken(normalizeRecoveryCode(code))).Scan(&codeId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = t.Rollback()
+			return false, err
+		}
+		log.Println("ERROR: Cannot look up recovery code: " + string(err.Error()))
+		return false, err
+	}
+
+	before, err := snapshotById(t, "RecoveryCodes", codeId)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = t.Exec("UPDATE RecoveryCodes SET UsedDate = ? WHERE Id = ?", time.Now().UTC().Format(sqliteTimeLayout), codeId)
+	if err != nil {
+		log.Println("ERROR: Cannot record use of recovery code: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "RecoveryCodes", codeId, before)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("WARN: Recovery code used by user Id: " + strconv.Itoa(userId))
+	return true, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user with two-factor
+// authentication enabled. No codes are returned for other users
+func (repo *sqlRepository) RegenerateRecoveryCodes(userId int) ([]string, error) {
+	log.Println("INFO: New recovery codes requested for user Id: " + strconv.Itoa(userId))
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var enabled bool
+	err = t.QueryRow("SELECT Enabled FROM TwoFactorSecrets WHERE UserId = ?", userId).Scan(&enabled)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("ERROR: Cannot retrieve TOTP secret from DB: " + string(err.Error()))
+		return nil, err
+	}
+	if !enabled {
+		err = t.Rollback()
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(t, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -18,11 +18,39 @@ package model
 
 */
 
+import "encoding/json"
+
 // primary object structs
+type AnswerFileTemplate struct {
+	Id           int    `json:"Id"`
+	OSFamilyId   int    `json:"osFamilyId"`
+	TemplateType string `json:"templateType" enum:"kickstart,autoyast,preseed,cloud-init"`
+	Version      int    `json:"version"`
+	Content      string `json:"content"`
+	CreatorId    int    `json:"creatorId"`
+	CreationDate string `json:"creationDate"`
+}
+
+type AnswerFileTemplateList struct {
+	Data []AnswerFileTemplate `json:"data"`
+}
+
+// Note that this is not stored in the DB. Content is rendered as given when
+// set, otherwise the stored template with TemplateId is used
+type AnswerFileRenderRequest struct {
+	SystemId   int    `json:"systemId"`
+	TemplateId int    `json:"templateId"`
+	Content    string `json:"content"`
+}
+
 type Architecture struct {
 	Id           int    `json:"Id"`
 	ISEName      string `json:"iseName"`
 	RegisterSize int    `json:"registerSize"`
+	KernelPath   string `json:"kernelPath"`
+	InitrdPath   string `json:"initrdPath"`
+	KernelArgs   string `json:"kernelArgs"`
+	BootFilename string `json:"bootFilename"`
 	CreatorId    int    `json:"creatorId"`
 	CreationDate string `json:"creationDate"`
 }
@@ -31,6 +59,45 @@ type ArchitectureList struct {
 	Data []Architecture `json:"data"`
 }
 
+// Note that the row snapshots are the columns of the changed row as they
+// were before and after the change, with secrets such as password hashes left
+// out. Creations have no before and deletions no after
+type AuditEntry struct {
+	Id            int             `json:"Id"`
+	ChangedById   int             `json:"changedById"`
+	ChangedByName string          `json:"changedByName"`
+	TableChanged  string          `json:"tableChanged"`
+	ChangeClass   string          `json:"changeClass" enum:"create,update,delete"`
+	ChangeDate    string          `json:"changeDate"`
+	EntityId      int             `json:"entityId"`
+	RequestId     string          `json:"requestId"`
+	RowBefore     json.RawMessage `json:"rowBefore" swaggertype:"object"`
+	RowAfter      json.RawMessage `json:"rowAfter" swaggertype:"object"`
+}
+
+type AuditEntryList struct {
+	Data []AuditEntry `json:"data"`
+}
+
+// Note that this is not stored in the DB, zero values leave a field out of
+// the search
+type AuditFilter struct {
+	TableChanged string
+	ChangedById  int
+	EntityId     int
+	From         string
+	To           string
+}
+
+// Note that this is not stored in the DB. Changes are recorded against the
+// user behind a request, or against the SYSTEM account for those the daemon
+// or a machine makes on its own
+type AuditActor struct {
+	UserId    int
+	UserName  string
+	RequestId string
+}
+
 type Building struct {
 	Id           int    `json:"Id"`
 	BuildingName string `json:"buildingName"`
@@ -45,6 +112,19 @@ type BuildingList struct {
 	Data []Building `json:"data"`
 }
 
+type HostVarLayer struct {
+	Id           int    `json:"Id"`
+	Scope        string `json:"scope" enums:"global,orgUnit,building,machineRole"`
+	ScopeId      int    `json:"scopeId"`
+	HostVars     string `json:"hostVars"`
+	CreatorId    int    `json:"creatorId"`
+	CreationDate string `json:"creationDate"`
+}
+
+type HostVarLayerList struct {
+	Data []HostVarLayer `json:"data"`
+}
+
 type MachineRole struct {
 	Id              int    `json:"Id"`
 	MachineRoleName string `json:"machineRoleName"`
@@ -57,6 +137,107 @@ type MachineRoleList struct {
 	Data []MachineRole `json:"data"`
 }
 
+type MachineRoleHostVarsSchema struct {
+	Id             int    `json:"Id"`
+	MachineRoleId  int    `json:"machineRoleId"`
+	HostVarsSchema string `json:"hostVarsSchema"`
+	CreatorId      int    `json:"creatorId"`
+	CreationDate   string `json:"creationDate"`
+}
+
+type ApiToken struct {
+	Id           int      `json:"Id"`
+	UserId       int      `json:"userId"`
+	TokenName    string   `json:"tokenName"`
+	TokenHash    string   `json:"-"`
+	Scopes       []string `json:"scopes"`
+	ExpiryDate   string   `json:"expiryDate"`
+	LastUsedDate string   `json:"lastUsedDate"`
+	CreationDate string   `json:"creationDate"`
+}
+
+type ApiTokenList struct {
+	Data []ApiToken `json:"data"`
+}
+
+type ApiTokenMsg struct {
+	Message  string   `json:"message"`
+	Token    string   `json:"token"`
+	ApiToken ApiToken `json:"apiToken"`
+}
+
+type ProposedApiToken struct {
+	TokenName  string   `json:"tokenName"`
+	Scopes     []string `json:"scopes"`
+	ExpiryDate string   `json:"expiryDate"`
+}
+
+type MachineCertificate struct {
+	Id                 int    `json:"Id"`
+	SystemId           int    `json:"systemId"`
+	Status             string `json:"status"`
+	CertificateRequest string `json:"certificateRequest"`
+	Certificate        string `json:"certificate"`
+	Fingerprint        string `json:"fingerprint"`
+	NotAfter           string `json:"notAfter"`
+	ClientAddress      string `json:"clientAddress"`
+	CreationDate       string `json:"creationDate"`
+	UpdatedDate        string `json:"updatedDate"`
+}
+
+type MachineCertificateList struct {
+	Data []MachineCertificate `json:"data"`
+}
+
+type MachineCertificateMsg struct {
+	Message            string             `json:"message"`
+	MachineCertificate MachineCertificate `json:"machineCertificate"`
+}
+
+type ProposedMachineCertificate struct {
+	CertificateRequest string `json:"certificateRequest"`
+	ClientAddress      string `json:"-"`
+}
+
+type MachineEnrollment struct {
+	SerialNumber       string `json:"serialNumber"`
+	CertificateRequest string `json:"certificateRequest"`
+}
+
+type MachineEnrollmentMsg struct {
+	Message       string `json:"message"`
+	Status        string `json:"status"`
+	Certificate   string `json:"certificate,omitempty"`
+	CACertificate string `json:"caCertificate,omitempty"`
+}
+
+type MachineToken struct {
+	Id           int    `json:"Id"`
+	SystemId     int    `json:"systemId"`
+	TokenHash    string `json:"-"`
+	CreatorId    int    `json:"creatorId"`
+	CreationDate string `json:"creationDate"`
+}
+
+type MachineTokenMsg struct {
+	Message string `json:"message"`
+	Token   string `json:"token"`
+}
+
+type MaintenanceWindow struct {
+	Id              int    `json:"Id"`
+	BuildingId      int    `json:"buildingId"`
+	Weekday         int    `json:"weekday"`
+	StartTime       string `json:"startTime"`
+	DurationMinutes int    `json:"durationMinutes"`
+	CreatorId       int    `json:"creatorId"`
+	CreationDate    string `json:"creationDate"`
+}
+
+type MaintenanceWindowList struct {
+	Data []MaintenanceWindow `json:"data"`
+}
+
 type NetworkInterface struct {
 	Id           int    `json:"Id"`
 	DeviceModel  string `json:"deviceModel"`
@@ -119,23 +300,191 @@ type OrgUnit struct {
 	Description  string `json:"description"`
 	CreatorId    int    `json:"creatorId"`
 	CreationDate string `json:"creationDate"`
+	ParentId     int    `json:"parentId"`
 }
 
 type OrgUnitList struct {
 	Data []OrgUnit `json:"data"`
 }
 
-type Role struct {
+type OrgUnitParent struct {
+	ParentId int `json:"parentId"`
+}
+
+type OrgUnitDelegation struct {
+	Id                 int    `json:"Id"`
+	OrgUnitId          int    `json:"orgUnitId"`
+	DelegatedOrgUnitId int    `json:"delegatedOrgUnitId"`
+	CreatorId          int    `json:"creatorId"`
+	CreationDate       string `json:"creationDate"`
+}
+
+type OrgUnitDelegationList struct {
+	Data []OrgUnitDelegation `json:"data"`
+}
+
+// Note that this is not stored in the DB, it's worked out from the role and
+// organizational unit of a user whenever they list or change systems or users
+type OrgUnitScope struct {
+	Global     bool  `json:"global"`
+	OrgUnitIds []int `json:"orgUnitIds"`
+}
+
+type ReimageBatchSystem struct {
+	Id               int    `json:"Id"`
+	BatchId          int    `json:"batchId"`
+	SystemId         int    `json:"systemId"`
+	State            string `json:"state" enum:"pending,started,skipped"`
+	ReimageRequestId int    `json:"reimageRequestId"`
+	StartedDate      string `json:"startedDate"`
+}
+
+type ReimageBatch struct {
+	Id             int                  `json:"Id"`
+	BatchName      string               `json:"batchName"`
+	ScheduledDate  string               `json:"scheduledDate"`
+	MaxPerBuilding int                  `json:"maxPerBuilding"`
+	State          string               `json:"state" enum:"scheduled,running,complete,cancelled"`
+	CreatorId      int                  `json:"creatorId"`
+	CreationDate   string               `json:"creationDate"`
+	Systems        []ReimageBatchSystem `json:"systems"`
+}
+
+type ReimageBatchList struct {
+	Data []ReimageBatch `json:"data"`
+}
+
+// Note that this is not stored in the DB, the selectors are resolved into the
+// batch's list of systems when the batch is created
+type ProposedReimageBatch struct {
+	BatchName      string `json:"batchName"`
+	ScheduledDate  string `json:"scheduledDate"`
+	MaxPerBuilding int    `json:"maxPerBuilding"`
+	MachineRoleId  int    `json:"machineRoleId"`
+	OrgUnitId      int    `json:"orgUnitId"`
+	BuildingId     int    `json:"buildingId"`
+	SystemIds      []int  `json:"systemIds"`
+}
+
+type ReimageEvent struct {
+	Id               int    `json:"Id"`
+	ReimageRequestId int    `json:"reimageRequestId"`
+	State            string `json:"state"`
+	Message          string `json:"message"`
+	EventDate        string `json:"eventDate"`
+}
+
+type ReimageRequest struct {
+	Id            int            `json:"Id"`
+	SystemId      int            `json:"systemId"`
+	State         string         `json:"state" enum:"requested,booting,partitioning,imaging,configuring,complete,failed"`
+	RequestedById int            `json:"requestedById"`
+	RequestedDate string         `json:"requestedDate"`
+	UpdatedDate   string         `json:"updatedDate"`
+	CompletedDate string         `json:"completedDate"`
+	Events        []ReimageEvent `json:"events"`
+}
+
+type ReimageRequestList struct {
+	Data []ReimageRequest `json:"data"`
+}
+
+type ReimageStatusReport struct {
+	State   string `json:"state" enum:"booting,partitioning,imaging,configuring,complete,failed"`
+	Message string `json:"message"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type PermissionList struct {
+	Data []Permission `json:"data"`
+}
+
+type RolePermission struct {
 	Id           int    `json:"Id"`
-	RoleName     string `json:"roleName"`
-	Description  string `json:"description"`
+	RoleId       int    `json:"roleId"`
+	Permission   string `json:"permission"`
+	CreatorId    int    `json:"creatorId"`
 	CreationDate string `json:"creationDate"`
 }
 
+type RolePermissionList struct {
+	Data []RolePermission `json:"data"`
+}
+
+type RolePermissionGrant struct {
+	Permission string `json:"permission"`
+}
+
+type Role struct {
+	Id               int    `json:"Id"`
+	RoleName         string `json:"roleName"`
+	Description      string `json:"description"`
+	CreationDate     string `json:"creationDate"`
+	RequireTwoFactor bool   `json:"requireTwoFactor"`
+}
+
+type RoleRequireTwoFactor struct {
+	RequireTwoFactor bool `json:"requireTwoFactor"`
+}
+
 type RolesList struct {
 	Data []Role `json:"data"`
 }
 
+type Session struct {
+	Id            int    `json:"Id"`
+	UserId        int    `json:"userId"`
+	TokenHash     string `json:"-"`
+	AuthMethod    string `json:"authMethod"`
+	ClientAddress string `json:"clientAddress"`
+	UserAgent     string `json:"userAgent"`
+	CreationDate  string `json:"creationDate"`
+	LastSeenDate  string `json:"lastSeenDate"`
+	ExpiryDate    string `json:"expiryDate"`
+	Current       bool   `json:"current"`
+}
+
+type SessionList struct {
+	Data []Session `json:"data"`
+}
+
+type ProposedSession struct {
+	AuthMethod    string `json:"authMethod"`
+	ClientAddress string `json:"clientAddress"`
+	UserAgent     string `json:"userAgent"`
+}
+
+type LoginCredentials struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+	Otp      string `json:"otp"`
+}
+
+type LoginMsg struct {
+	Message                     string  `json:"message"`
+	Session                     Session `json:"session"`
+	MustChangePassword          bool    `json:"mustChangePassword"`
+	TwoFactorEnrollmentRequired bool    `json:"twoFactorEnrollmentRequired"`
+}
+
+type WhoAmI struct {
+	Id                          int      `json:"Id"`
+	UserName                    string   `json:"userName"`
+	FullName                    string   `json:"fullName"`
+	OrgUnitId                   int      `json:"orgUnitId"`
+	RoleId                      int      `json:"roleId"`
+	TypeId                      int      `json:"typeId"`
+	AuthMethod                  string   `json:"authMethod"`
+	Session                     *Session `json:"session,omitempty"`
+	TwoFactorEnabled            bool     `json:"twoFactorEnabled"`
+	MustChangePassword          bool     `json:"mustChangePassword"`
+	TwoFactorEnrollmentRequired bool     `json:"twoFactorEnrollmentRequired"`
+}
+
 type StorageVolume struct {
 	Id           int    `json:"Id"`
 	VolumeName   string `json:"volumeName"`
@@ -156,6 +505,63 @@ type StorageVolumes struct {
 	Volumes []StorageVolume `json:"volumes"`
 }
 
+type DiscoveryReport struct {
+	SerialNumber      string                `json:"serialNumber"`
+	Vendor            string                `json:"vendor"`
+	Model             string                `json:"model"`
+	Architecture      string                `json:"architecture"`
+	CpuCores          int                   `json:"cpuCores"`
+	RAM               int                   `json:"ram"`
+	NetworkInterfaces []DiscoveredInterface `json:"networkInterfaces"`
+	Disks             []DiscoveredDisk      `json:"disks"`
+	ClientAddress     string                `json:"-"`
+}
+
+type DiscoveredInterface struct {
+	DeviceModel string `json:"deviceModel"`
+	DeviceId    string `json:"deviceId"`
+	MACAddress  string `json:"macAddress"`
+}
+
+type DiscoveredDisk struct {
+	DeviceModel string `json:"deviceModel"`
+	DeviceId    string `json:"deviceId"`
+	StorageType string `json:"storageType"`
+	Size        int    `json:"size"`
+}
+
+type DiscoveryMsg struct {
+	Message  string `json:"message"`
+	SystemId int    `json:"systemId"`
+	Status   string `json:"status"`
+}
+
+type DiscoveredSystem struct {
+	System               System             `json:"system"`
+	ReportedVendor       string             `json:"reportedVendor"`
+	ReportedModel        string             `json:"reportedModel"`
+	ReportedArchitecture string             `json:"reportedArchitecture"`
+	ClientAddress        string             `json:"clientAddress"`
+	DiscoveryDate        string             `json:"discoveryDate"`
+	NetworkInterfaces    []NetworkInterface `json:"networkInterfaces"`
+	StorageVolumes       []StorageVolume    `json:"storageVolumes"`
+}
+
+type DiscoveredSystemList struct {
+	Data []DiscoveredSystem `json:"data"`
+}
+
+type SystemApproval struct {
+	BilledToOrgUnitId int    `json:"billedToOrgUnitId"`
+	BuildingId        int    `json:"buildingId"`
+	MachineRoleId     int    `json:"machineRoleId"`
+	OperatingSystemId int    `json:"osId"`
+	ModelId           int    `json:"modelId"`
+	VendorId          int    `json:"vendorId"`
+	ArchitectureId    int    `json:"architectureId"`
+	HostVars          string `json:"hostVars"`
+}
+
 type System struct {
 	Id                int    `json:"Id"`
 	SerialNumber      string `json:"serialNumber"`
@@ -164,23 +570,96 @@ type System struct {
 	Reimage           bool   `json:"reimage"`
 	HostVars          string `json:"HostVars"`
 	BilledToOrgUnitId int    `json:"billedToOrgUnitId"`
+	MachineRoleId     int    `json:"machineRoleId"`
+	BuildingId        int    `json:"buildingId"`
 	VendorId          int    `json:"vendorId"`
 	ArchitectureId    int    `json:"architectureId"`
 	RAM               int    `json:"ram"`
 	CpuCores          int    `json:"cpuCores"`
 	CreatorId         int    `json:"creatorId"`
 	CreationDate      string `json:"creationDate"`
+	Status            string `json:"status"`
 }
 
 type SystemList struct {
 	Data []System `json:"data"`
 }
 
+// Note that this is not stored in the DB, it's assembled from the system and its
+// related records when an imaging client checks in
+type ProvisioningDocument struct {
+	SystemId          int                `json:"systemId"`
+	SerialNumber      string             `json:"serialNumber"`
+	Reimage           bool               `json:"reimage"`
+	OSName            string             `json:"osName"`
+	OSVersion         string             `json:"osVersion"`
+	OSImageUrl        string             `json:"osImageUrl"`
+	ImageUriProtocol  string             `json:"imageUriProtocol"`
+	StorageVolumes    []StorageVolume    `json:"storageVolumes"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces"`
+	HostVars          string             `json:"hostVars"`
+}
+
+// Note that this is not stored in the DB. Each variable carries the layer it
+// was taken from, and the merged variables are checked against the machine
+// role's schema when it has one
+type ResolvedHostVar struct {
+	Value   any    `json:"value"`
+	Scope   string `json:"scope"`
+	ScopeId int    `json:"scopeId"`
+}
+
+type ResolvedHostVars struct {
+	SystemId      int                        `json:"systemId"`
+	MachineRoleId int                        `json:"machineRoleId"`
+	Vars          map[string]ResolvedHostVar `json:"vars"`
+	Valid         bool                       `json:"valid"`
+	Violations    []string                   `json:"violations"`
+}
+
+// Note that this is not stored in the DB, it's assembled from the records
+// needed to decide how a machine network booting from a given interface boots
+type BootTarget struct {
+	System          System
+	Interface       NetworkInterface
+	OperatingSystem OperatingSystem
+	Architecture    Architecture
+}
+
+type TwoFactor struct {
+	Id                int    `json:"Id"`
+	UserId            int    `json:"userId"`
+	Secret            string `json:"-"`
+	Enabled           bool   `json:"enabled"`
+	LastUsedStep      int64  `json:"-"`
+	CreationDate      string `json:"creationDate"`
+	RecoveryCodesLeft int    `json:"recoveryCodesLeft"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioningUri"`
+	QrCode          string `json:"qrCode"`
+}
+
+type RecoveryCodesMsg struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
 type PasswordChange struct {
 	OldPassword string `json:"oldPassword"`
 	NewPassword string `json:"newPassword"`
 }
 
+type PasswordReset struct {
+	NewPassword string `json:"newPassword"`
+}
+
 type ProposedUser struct {
 	Id        int    `json:"Id"`
 	UserName  string `json:"userName"`
@@ -203,6 +682,11 @@ type User struct {
 	PasswordHash            string `json:"passwordHash"`
 	CreationDate            string `json:"creationDate"`
 	LastPasswordChangedDate string `json:"lastPasswordChangedDate"`
+	FailedLoginCount        int    `json:"failedLoginCount"`
+	LockedUntil             string `json:"lockedUntil"`
+	MustChangePassword      bool   `json:"mustChangePassword"`
+	ExternalProvider        string `json:"externalProvider"`
+	ExternalSubject         string `json:"externalSubject"`
 }
 
 type UsersList struct {
@@ -213,6 +697,10 @@ type UserStatus struct {
 	Status string `json:"status" enum:"enabled,disabled"`
 }
 
+type UserMustChangePassword struct {
+	MustChangePassword bool `json:"mustChangePassword"`
+}
+
 type UserStatusMsg struct {
 	Message    string `json:"message"`
 	UserStatus string `json:"userStatus" enum:"enabled,disabled"`
@@ -260,6 +748,11 @@ type FailureMsg struct {
 	Error string `json:"error"`
 }
 
+type PermissionDeniedMsg struct {
+	Error      string `json:"error"`
+	Permission string `json:"permission"`
+}
+
 type SuccessMsg struct {
 	Message string `json:"message"`
 }
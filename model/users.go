@@ -19,18 +19,25 @@ package model
 */
 
 import (
-	"crypto/sha512"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"log"
 	"strconv"
 	"time"
+
+	"github.com/greeneg/allocatord/passwords"
+)
+
+// the user types every database is created with
+const (
+	UserTypeBuiltin  = 1
+	UserTypeLocal    = 2
+	UserTypeExternal = 3
 )
 
-func getStoredPasswordHash(username string) (string, error) {
+func (repo *sqlRepository) getStoredPasswordHash(username string) (string, error) {
 	log.Println("INFO: Retrieve stored password hash for user '" + username + "'")
-	q, err := DB.Prepare("SELECT PasswordHash FROM Users WHERE UserName = ?")
+	q, err := repo.db.Prepare("SELECT PasswordHash FROM Users WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return "", err
@@ -38,17 +45,7 @@ func getStoredPasswordHash(username string) (string, error) {
 	defer q.Close()
 
 	passwordHash := ""
-
-	r, err := q.Query(username)
-	if err != nil {
-		log.Println("ERROR: Could not query password hash for user '" + username + "': " + string(err.Error()))
-		return "", err
-	}
-	defer r.Close()
-
-	err = r.Scan(
-		&passwordHash,
-	)
+	err = q.QueryRow(username).Scan(&passwordHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Println("ERROR: No such user found in DB: " + string(err.Error()))
@@ -62,9 +59,9 @@ func getStoredPasswordHash(username string) (string, error) {
 	return passwordHash, nil
 }
 
-func storeNewPassword(hashedPassword string, username string) (bool, error) {
+func (repo *sqlRepository) storeNewPassword(hashedPassword string, username string, mustChangePassword bool) (bool, error) {
 	log.Println("INFO: Store new password hash for user '" + username + "'")
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -80,8 +77,13 @@ func storeNewPassword(hashedPassword string, username string) (bool, error) {
 		}
 	}()
 
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	// now we need to create a new transaction to SET the password hash into the DB
-	q, err := DB.Prepare("UPDATE Users SET PasswordHash = ?, LastPasswordChangedDate = ? WHERE UserName = ?")
+	q, err := t.Prepare("UPDATE Users SET PasswordHash = ?, LastPasswordChangedDate = ?, MustChangePassword = ? WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -91,12 +93,17 @@ func storeNewPassword(hashedPassword string, username string) (bool, error) {
 	// get time stamp
 	tStamp := time.Now().Format("2006-01-02 15:04:05") // force into SQL DateTime format
 
-	_, err = q.Exec(hashedPassword, tStamp, username)
+	_, err = q.Exec(hashedPassword, tStamp, mustChangePassword, username)
 	if err != nil {
 		log.Println("ERROR: Cannot store updated password hash in DB: " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -107,29 +114,57 @@ func storeNewPassword(hashedPassword string, username string) (bool, error) {
 	return true, nil
 }
 
-func ChangeAccountPassword(username string, oldPassword string, newPassword string) (bool, error) {
+// ChangeAccountPassword replaces a user's password once the old one has been
+// given. A wrong old password counts as a failed login, and no attempts are
+// taken while the user is locked out, so the lockout cannot be bypassed by
+// guessing passwords here instead
+func (repo *sqlRepository) ChangeAccountPassword(username string, oldPassword string, newPassword string) (bool, error) {
 	log.Println("INFO: Password change requested")
-	hashedOldPassword := sha512.Sum512([]byte(oldPassword))
-	encodedHashedOldPassword := hex.EncodeToString(hashedOldPassword[:])
+	user, err := repo.GetUserByUserName(username)
+	if err != nil {
+		log.Println("ERROR: Cannot retrieve user '" + username + "' from DB: " + string(err.Error()))
+		return false, err
+	}
+	if user.LockedUntil != "" {
+		lockedUntil, err := ParseTimestamp(user.LockedUntil)
+		if err == nil && time.Now().UTC().Before(lockedUntil) {
+			log.Println("WARN: User '" + username + "' is locked out until " + user.LockedUntil + " UTC")
+			return false, &AccountLockedOut{Err: errors.New("locked out until " + user.LockedUntil + " UTC")}
+		}
+	}
 
-	storedHash, err := getStoredPasswordHash(username)
+	storedHash, err := repo.getStoredPasswordHash(username)
 	if err != nil {
 		log.Println("ERROR: Cannot retrieve stored password hash from DB: " + string(err.Error()))
 		return false, err
 	}
 	log.Println("INFO: Retrieved stored hash for comparison")
 
-	// now get password hash from the db
-	if storedHash != encodedHashedOldPassword {
+	// check the old password against the stored hash, whatever its format
+	match, _ := passwords.Verify(oldPassword, storedHash)
+	if !match {
 		log.Println("ERROR: Hashed value of old password does not match stored hashed value")
+		_, err = repo.RecordFailedLogin(username)
+		if err != nil {
+			log.Println("WARN: Could not record the failed password change of user '" + username + "': " + string(err.Error()))
+		}
 		p := new(PasswordHashMismatch)
 		return false, p
 	}
 
+	err = passwords.CheckPolicy(newPassword)
+	if err != nil {
+		log.Println("ERROR: New password does not meet the password policy: " + string(err.Error()))
+		return false, &PasswordPolicyViolation{Err: err}
+	}
+
 	// matches, so hash new password
-	hashedNewPassword := sha512.Sum512([]byte(newPassword))
-	encodedHashedNewPassword := hex.EncodeToString(hashedNewPassword[:])
-	_, err = storeNewPassword(encodedHashedNewPassword, username)
+	hashedNewPassword, err := passwords.Hash(newPassword)
+	if err != nil {
+		log.Println("ERROR: Cannot hash the new password: " + string(err.Error()))
+		return false, err
+	}
+	_, err = repo.storeNewPassword(hashedNewPassword, username, false)
 	if err != nil {
 		log.Println("ERROR: Cannot store updated password hash in DB: " + string(err.Error()))
 		return false, err
@@ -139,18 +174,273 @@ func ChangeAccountPassword(username string, oldPassword string, newPassword stri
 	return true, nil
 }
 
-func GetUserById(id int) (User, error) {
-	log.Println("INFO: User by Id requested: " + strconv.Itoa(id))
-	stmt, err := DB.Prepare("SELECT * FROM Users WHERE Id = ?")
+// ResetAccountPassword sets a new password for a user who cannot give their
+// old one, such as one who has forgotten it. The user has to change it on
+// their next login, so only they know the password they end up with. Their
+// failed logins are left as they are, since nobody tried to log in
+func (repo *sqlRepository) ResetAccountPassword(username string, newPassword string) (bool, error) {
+	log.Println("INFO: Password reset requested for user '" + username + "'")
+	user, err := repo.GetUserByUserName(username)
 	if err != nil {
-		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
-		return User{}, err
+		log.Println("ERROR: Cannot retrieve user '" + username + "' from DB: " + string(err.Error()))
+		return false, err
 	}
-	defer stmt.Close()
+	if user.TypeId != UserTypeLocal {
+		log.Println("ERROR: User '" + username + "' is not a LOCAL user")
+		return false, &PasswordNotResettable{Err: errors.New("only LOCAL users have passwords allocatord can set")}
+	}
+
+	err = passwords.CheckPolicy(newPassword)
+	if err != nil {
+		log.Println("ERROR: New password does not meet the password policy: " + string(err.Error()))
+		return false, &PasswordPolicyViolation{Err: err}
+	}
+
+	hashedNewPassword, err := passwords.Hash(newPassword)
+	if err != nil {
+		log.Println("ERROR: Cannot hash the new password: " + string(err.Error()))
+		return false, err
+	}
+	return repo.storeNewPassword(hashedNewPassword, username, true)
+}
+
+// UpgradePasswordHash replaces a stored password hash with a new hash of the
+// same password, leaving the password change date alone
+func (repo *sqlRepository) UpgradePasswordHash(username string, hashedPassword string) (bool, error) {
+	log.Println("INFO: Password hash upgrade requested for user '" + username + "'")
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = t.Exec("UPDATE Users SET PasswordHash = ? WHERE UserName = ?", hashedPassword, username)
+	if err != nil {
+		log.Println("ERROR: Cannot store upgraded password hash in DB: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: Upgraded password hash for user '" + username + "'")
+	return true, nil
+}
+
+// updateUser runs an update of a single user's row and records it, reporting
+// whether the user exists
+func (repo *sqlRepository) updateUser(username string, query string, args ...any) (bool, error) {
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := t.Exec(query, args...)
+	if err != nil {
+		log.Println("ERROR: Could not execute query for user '" + username + "': " + string(err.Error()))
+		return false, err
+	}
+	numberOfRows, err := result.RowsAffected()
+	if err != nil {
+		log.Println("ERROR: Could not get number of rows affected: " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	return numberOfRows > 0, nil
+}
+
+// RecordFailedLogin counts a failed login against a user and locks them out
+// for a while once the policy's limit is reached, reporting whether it was
+func (repo *sqlRepository) RecordFailedLogin(username string) (bool, error) {
+	log.Println("INFO: Failed login recorded for user '" + username + "'")
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	// the count is raised in the same statement that reads it, so failed
+	// logins made in parallel cannot overwrite each other's. A lockout starts
+	// the count again, so the user gets the full number of attempts once it
+	// ends, and a count of zero afterwards means this login started one
+	limit := passwords.CurrentPolicy().MaxFailedLogins
+	lockedUntil, _ := passwords.LockoutEnds(limit, time.Now().UTC())
+	var failedLogins int
+	err = t.QueryRow(`UPDATE Users SET
+			FailedLoginCount = CASE WHEN ? > 0 AND FailedLoginCount + 1 >= ? THEN 0 ELSE FailedLoginCount + 1 END,
+			LockedUntil = CASE WHEN ? > 0 AND FailedLoginCount + 1 >= ? THEN ? ELSE LockedUntil END
+		WHERE UserName = ? RETURNING FailedLoginCount`,
+		limit, limit, limit, limit, lockedUntil.Format(sqliteTimeLayout), username).Scan(&failedLogins)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+			return false, nil
+		}
+		log.Println("ERROR: Cannot record failed login in DB: " + string(err.Error()))
+		return false, err
+	}
+	locked := failedLogins == 0
+
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	if locked {
+		log.Println("WARN: User '" + username + "' is locked out until " + lockedUntil.Format(sqliteTimeLayout) + " UTC")
+	}
+	return locked, nil
+}
+
+// RecordSuccessfulLogin clears a user's failed logins and any lockout that
+// has run out
+func (repo *sqlRepository) RecordSuccessfulLogin(username string) (bool, error) {
+	return repo.updateUser(username, "UPDATE Users SET FailedLoginCount = 0, LockedUntil = NULL WHERE UserName = ?", username)
+}
+
+// UnlockUser lifts a lockout after failed logins before it runs out
+func (repo *sqlRepository) UnlockUser(username string) (bool, error) {
+	log.Println("INFO: Unlock requested for user '" + username + "'")
+	return repo.updateUser(username, "UPDATE Users SET FailedLoginCount = 0, LockedUntil = NULL WHERE UserName = ?", username)
+}
+
+// SetUserMustChangePassword sets whether a user has to change their password
+// before they can do anything else
+func (repo *sqlRepository) SetUserMustChangePassword(username string, j UserMustChangePassword) (bool, error) {
+	log.Println("INFO: Set forced password change for user '" + username + "'")
+	return repo.updateUser(username, "UPDATE Users SET MustChangePassword = ? WHERE UserName = ?", j.MustChangePassword, username)
+}
 
+// ProvisionExternalUser creates the account of a user authenticated by an
+// external identity provider the first time they log in, owned by that
+// provider and the subject it knows them by. Their password stays with the
+// provider, so the account has no password hash that could match
+func (repo *sqlRepository) ProvisionExternalUser(provider string, subject string, p ProposedUser) (bool, error) {
+	log.Println("INFO: Provisioning of external user requested: " + p.UserName)
+	t, err := repo.db.Begin()
+	if err != nil {
+		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
+		return false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to panic: " + string(r.(error).Error()))
+		}
+		if err != nil {
+			t.Rollback()
+			log.Println("ERROR: Transaction rolled back due to error: " + string(err.Error()))
+		}
+	}()
+
+	var newId int
+	err = t.QueryRow("INSERT INTO Users (UserName, FullName, Status, OrgUnitId, RoleId, TypeId, PasswordHash, ExternalProvider, ExternalSubject) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING Id",
+		p.UserName, p.FullName, "enabled", p.OrgUnitId, p.RoleId, UserTypeExternal, "", provider, subject).Scan(&newId)
+	if err != nil {
+		log.Println("ERROR: Cannot create user '" + p.UserName + "': " + string(err.Error()))
+		return false, err
+	}
+
+	err = repo.auditById(t, "Users", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
+		return false, err
+	}
+
+	log.Println("INFO: External user '" + p.UserName + "' provisioned")
+	return true, nil
+}
+
+// ClaimExternalUser hands an EXTERNAL account no provider owns yet, such as
+// one created by hand or before owners were recorded, to the provider
+// logging its user in. Accounts some provider already owns are left alone
+func (repo *sqlRepository) ClaimExternalUser(username string, provider string, subject string) (bool, error) {
+	log.Println("INFO: External user '" + username + "' claimed by identity provider '" + provider + "'")
+	return repo.updateUser(username, "UPDATE Users SET ExternalProvider = ?, ExternalSubject = ? WHERE UserName = ? AND TypeId = ? AND ExternalProvider IS NULL",
+		provider, subject, username, UserTypeExternal)
+}
+
+// SyncExternalUser brings the name, role and organizational unit of an
+// external user in line with what their identity provider says on each
+// login. Only the provider owning the account can change it
+func (repo *sqlRepository) SyncExternalUser(provider string, subject string, p ProposedUser) (bool, error) {
+	log.Println("INFO: Sync of external user requested: " + p.UserName)
+	return repo.updateUser(p.UserName, "UPDATE Users SET FullName = ?, OrgUnitId = ?, RoleId = ? WHERE UserName = ? AND TypeId = ? AND ExternalProvider = ? AND ExternalSubject = ?",
+		p.FullName, p.OrgUnitId, p.RoleId, p.UserName, UserTypeExternal, provider, subject)
+}
+
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
 	user := User{}
-	record := stmt.QueryRow(id)
-	err = record.Scan(
+	var lockedUntil, externalProvider, externalSubject sql.NullString
+	err := row.Scan(
 		&user.Id,
 		&user.UserName,
 		&user.FullName,
@@ -161,57 +451,94 @@ func GetUserById(id int) (User, error) {
 		&user.PasswordHash,
 		&user.CreationDate,
 		&user.LastPasswordChangedDate,
+		&user.FailedLoginCount,
+		&lockedUntil,
+		&user.MustChangePassword,
+		&externalProvider,
+		&externalSubject,
 	)
 	if err != nil {
-		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
 		return User{}, err
 	}
-
 	user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
 	user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
+	if lockedUntil.Valid {
+		user.LockedUntil = ConvertSqliteTimestamp(lockedUntil.String)
+	}
+	user.ExternalProvider = externalProvider.String
+	user.ExternalSubject = externalSubject.String
+
+	return user, nil
+}
+
+func (repo *sqlRepository) GetUserById(id int) (User, error) {
+	log.Println("INFO: User by Id requested: " + strconv.Itoa(id))
+	stmt, err := repo.db.Prepare("SELECT * FROM Users WHERE Id = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return User{}, err
+	}
+	defer stmt.Close()
+
+	user, err := scanUser(stmt.QueryRow(id))
+	if err != nil {
+		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
+		return User{}, err
+	}
 
 	log.Println("INFO: User with Id '" + strconv.Itoa(id) + "' has been retrieved")
 	return user, nil
 }
 
-func GetUserByUserName(username string) (User, error) {
+func (repo *sqlRepository) GetUserByUserName(username string) (User, error) {
 	log.Println("INFO: User by username requested: " + username)
-	stmt, err := DB.Prepare("SELECT * FROM Users WHERE UserName = ?")
+	stmt, err := repo.db.Prepare("SELECT * FROM Users WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return User{}, err
 	}
 	defer stmt.Close()
 
-	user := User{}
-	record := stmt.QueryRow(username)
-	err = record.Scan(
-		&user.Id,
-		&user.UserName,
-		&user.FullName,
-		&user.Status,
-		&user.OrgUnitId,
-		&user.RoleId,
-		&user.TypeId,
-		&user.PasswordHash,
-		&user.CreationDate,
-		&user.LastPasswordChangedDate,
-	)
+	user, err := scanUser(stmt.QueryRow(username))
 	if err != nil {
 		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
 		return User{}, err
 	}
 
-	user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-	user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
-
 	log.Println("INFO: User with username '" + username + "' has been retrieved")
 	return user, nil
 }
 
-func CreateUser(p ProposedUser) (bool, error) {
+// GetUserByExternalIdentity returns the account an identity provider created
+// for the subject it knows a user by
+func (repo *sqlRepository) GetUserByExternalIdentity(provider string, subject string) (User, error) {
+	log.Println("INFO: User by external identity requested: " + provider + " " + subject)
+	stmt, err := repo.db.Prepare("SELECT * FROM Users WHERE ExternalProvider = ? AND ExternalSubject = ?")
+	if err != nil {
+		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
+		return User{}, err
+	}
+	defer stmt.Close()
+
+	user, err := scanUser(stmt.QueryRow(provider, subject))
+	if err != nil {
+		log.Println("ERROR: Cannot scan the user object!" + string(err.Error()))
+		return User{}, err
+	}
+
+	log.Println("INFO: User with username '" + user.UserName + "' has been retrieved")
+	return user, nil
+}
+
+func (repo *sqlRepository) CreateUser(p ProposedUser) (bool, error) {
 	log.Println("INFO: User creation requested: " + p.UserName)
-	t, err := DB.Begin()
+	err := passwords.CheckPolicy(p.Password)
+	if err != nil {
+		log.Println("ERROR: Password of user '" + p.UserName + "' does not meet the password policy: " + string(err.Error()))
+		return false, &PasswordPolicyViolation{Err: err}
+	}
+
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -227,22 +554,40 @@ func CreateUser(p ProposedUser) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Users (UserName, PasswordHash) VALUES (?, ?)")
+	q, err := t.Prepare("INSERT INTO Users (UserName, FullName, Status, OrgUnitId, RoleId, TypeId, PasswordHash) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
 	// take password and hash it
-	hash := sha512.Sum512([]byte(p.Password))
-	passwdHash := hex.EncodeToString(hash[:])
+	passwdHash, err := passwords.Hash(p.Password)
+	if err != nil {
+		log.Println("ERROR: Cannot hash the password of user '" + p.UserName + "': " + string(err.Error()))
+		return false, err
+	}
 
-	_, err = q.Exec(p.UserName, passwdHash)
+	// accounts made through the API are enabled local accounts unless told
+	// otherwise
+	if p.Status == "" {
+		p.Status = "enabled"
+	}
+	if p.TypeId == 0 {
+		p.TypeId = UserTypeLocal
+	}
+
+	var newId int
+	err = q.QueryRow(p.UserName, p.FullName, p.Status, p.OrgUnitId, p.RoleId, p.TypeId, passwdHash).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create user '" + p.UserName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "Users", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -253,9 +598,9 @@ func CreateUser(p ProposedUser) (bool, error) {
 	return true, nil
 }
 
-func DeleteUser(username string) (bool, error) {
+func (repo *sqlRepository) DeleteUser(username string) (bool, error) {
 	log.Println("INFO: User deletion requested: " + username)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -271,7 +616,12 @@ func DeleteUser(username string) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM Users WHERE UserName IS ?")
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Users WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -283,6 +633,11 @@ func DeleteUser(username string) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -293,9 +648,9 @@ func DeleteUser(username string) (bool, error) {
 	return true, nil
 }
 
-func GetUsers() ([]User, error) {
+func (repo *sqlRepository) GetUsers() ([]User, error) {
 	log.Println("INFO: List of user object requested")
-	rows, err := DB.Query("SELECT * FROM Users")
+	rows, err := repo.db.Query("SELECT * FROM Users")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -304,27 +659,12 @@ func GetUsers() ([]User, error) {
 
 	users := make([]User, 0)
 	for rows.Next() {
-		user := User{}
-		err = rows.Scan(
-			&user.Id,
-			&user.UserName,
-			&user.FullName,
-			&user.Status,
-			&user.OrgUnitId,
-			&user.RoleId,
-			&user.TypeId,
-			&user.PasswordHash,
-			&user.CreationDate,
-			&user.LastPasswordChangedDate,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			log.Println("ERROR: Cannot marshal the user objects!" + string(err.Error()))
 			return nil, err
 		}
 
-		user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-		user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
-
 		users = append(users, user)
 	}
 
@@ -332,9 +672,9 @@ func GetUsers() ([]User, error) {
 	return users, nil
 }
 
-func GetUsersByOuId(ouId int) ([]User, error) {
+func (repo *sqlRepository) GetUsersByOuId(ouId int) ([]User, error) {
 	log.Println("INFO: List user objects based on organizational unit Id")
-	rows, err := DB.Query("SELECT * FROM Users WHERE OrgUnitId IS ?", ouId)
+	rows, err := repo.db.Query("SELECT * FROM Users WHERE OrgUnitId = ?", ouId)
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return []User{}, err
@@ -343,25 +683,35 @@ func GetUsersByOuId(ouId int) ([]User, error) {
 
 	users := make([]User, 0)
 	for rows.Next() {
-		user := User{}
-		err := rows.Scan(
-			&user.Id,
-			&user.UserName,
-			&user.FullName,
-			&user.Status,
-			&user.OrgUnitId,
-			&user.RoleId,
-			&user.TypeId,
-			&user.PasswordHash,
-			&user.CreationDate,
-			&user.LastPasswordChangedDate,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-		user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
+		users = append(users, user)
+	}
+
+	log.Println("INFO: List of selected users retrieved")
+	return users, nil
+}
+
+// GetUsersByOuSubtree returns the users of an organizational unit and of every
+// organizational unit below it
+func (repo *sqlRepository) GetUsersByOuSubtree(ouId int) ([]User, error) {
+	log.Println("INFO: List user objects based on organizational unit subtree")
+	rows, err := repo.db.Query(orgUnitSubtree+"SELECT * FROM Users WHERE OrgUnitId IN (SELECT Id FROM Subtree)", ouId)
+	if err != nil {
+		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
+		return []User{}, err
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
 
 		users = append(users, user)
 	}
@@ -370,9 +720,9 @@ func GetUsersByOuId(ouId int) ([]User, error) {
 	return users, nil
 }
 
-func GetUsersByRoleId(roleId int) ([]User, error) {
+func (repo *sqlRepository) GetUsersByRoleId(roleId int) ([]User, error) {
 	log.Println("INFO: List user objects based on role Id")
-	rows, err := DB.Query("SELECT * FROM Users WHERE RoleId IS ?", roleId)
+	rows, err := repo.db.Query("SELECT * FROM Users WHERE RoleId = ?", roleId)
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return []User{}, err
@@ -381,26 +731,11 @@ func GetUsersByRoleId(roleId int) ([]User, error) {
 
 	users := make([]User, 0)
 	for rows.Next() {
-		user := User{}
-		err := rows.Scan(
-			&user.Id,
-			&user.UserName,
-			&user.FullName,
-			&user.Status,
-			&user.OrgUnitId,
-			&user.RoleId,
-			&user.TypeId,
-			&user.PasswordHash,
-			&user.CreationDate,
-			&user.LastPasswordChangedDate,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-		user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
-
 		users = append(users, user)
 	}
 
@@ -408,9 +743,9 @@ func GetUsersByRoleId(roleId int) ([]User, error) {
 	return users, nil
 }
 
-func GetUserByTypeId(typeId int) ([]User, error) {
+func (repo *sqlRepository) GetUserByTypeId(typeId int) ([]User, error) {
 	log.Println("INFO: List user objects based on type Id")
-	rows, err := DB.Query("SELECT * FROM Users WHERE TypeId IS ?", typeId)
+	rows, err := repo.db.Query("SELECT * FROM Users WHERE TypeId = ?", typeId)
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return []User{}, err
@@ -419,26 +754,11 @@ func GetUserByTypeId(typeId int) ([]User, error) {
 
 	users := make([]User, 0)
 	for rows.Next() {
-		user := User{}
-		err := rows.Scan(
-			&user.Id,
-			&user.UserName,
-			&user.FullName,
-			&user.Status,
-			&user.OrgUnitId,
-			&user.RoleId,
-			&user.TypeId,
-			&user.PasswordHash,
-			&user.CreationDate,
-			&user.LastPasswordChangedDate,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-		user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
-
 		users = append(users, user)
 	}
 
@@ -446,9 +766,9 @@ func GetUserByTypeId(typeId int) ([]User, error) {
 	return users, nil
 }
 
-func GetUsersByTypeId(typeId int) ([]User, error) {
+func (repo *sqlRepository) GetUsersByTypeId(typeId int) ([]User, error) {
 	log.Println("INFO: List user objects based on type Id")
-	rows, err := DB.Query("SELECT * FROM Users WHERE UserTypeId IS ?", typeId)
+	rows, err := repo.db.Query("SELECT * FROM Users WHERE UserTypeId = ?", typeId)
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return []User{}, err
@@ -457,26 +777,11 @@ func GetUsersByTypeId(typeId int) ([]User, error) {
 
 	users := make([]User, 0)
 	for rows.Next() {
-		user := User{}
-		err := rows.Scan(
-			&user.Id,
-			&user.UserName,
-			&user.FullName,
-			&user.Status,
-			&user.OrgUnitId,
-			&user.RoleId,
-			&user.TypeId,
-			&user.PasswordHash,
-			&user.CreationDate,
-			&user.LastPasswordChangedDate,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		user.CreationDate = ConvertSqliteTimestamp(user.CreationDate)
-		user.LastPasswordChangedDate = ConvertSqliteTimestamp(user.LastPasswordChangedDate)
-
 		users = append(users, user)
 	}
 
@@ -484,9 +789,9 @@ func GetUsersByTypeId(typeId int) ([]User, error) {
 	return users, nil
 }
 
-func GetUserStatus(username string) (string, error) {
+func (repo *sqlRepository) GetUserStatus(username string) (string, error) {
 	log.Println("INFO: User status requested for user '" + username + "'")
-	q, err := DB.Prepare("SELECT Status FROM Users WHERE UserName IS ?")
+	q, err := repo.db.Prepare("SELECT Status FROM Users WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return "", err
@@ -517,9 +822,9 @@ func GetUserStatus(username string) (string, error) {
 	return status, nil
 }
 
-func SetUserStatus(username string, j UserStatus) (bool, error) {
+func (repo *sqlRepository) SetUserStatus(username string, j UserStatus) (bool, error) {
 	log.Println("INFO: Set user status for user '" + username + "'")
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction: " + string(err.Error()))
 		return false, err
@@ -535,7 +840,12 @@ func SetUserStatus(username string, j UserStatus) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("UPDATE Users SET Status = ? WHERE UserName = ?")
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE Users SET Status = ? WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -558,6 +868,11 @@ func SetUserStatus(username string, j UserStatus) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -568,9 +883,9 @@ func SetUserStatus(username string, j UserStatus) (bool, error) {
 	return true, nil
 }
 
-func SetUserOuId(username string, j UserOrgUnitId) (bool, error) {
+func (repo *sqlRepository) SetUserOuId(username string, j UserOrgUnitId) (bool, error) {
 	log.Println("INFO: Set user's organizational unit Id for user '" + username + "'")
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction: " + string(err.Error()))
 		return false, err
@@ -586,7 +901,12 @@ func SetUserOuId(username string, j UserOrgUnitId) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("UPDATE Users SET OrgUnitId = ? WHERE UserName = ?")
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE Users SET OrgUnitId = ? WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -602,6 +922,11 @@ func SetUserOuId(username string, j UserOrgUnitId) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -612,9 +937,9 @@ func SetUserOuId(username string, j UserOrgUnitId) (bool, error) {
 	return true, nil
 }
 
-func SetUserRoleId(username string, j UserRoleId) (bool, error) {
+func (repo *sqlRepository) SetUserRoleId(username string, j UserRoleId) (bool, error) {
 	log.Println("INFO: Set user's role Id for user '" + username + "'")
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction: " + string(err.Error()))
 		return false, err
@@ -630,7 +955,12 @@ func SetUserRoleId(username string, j UserRoleId) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("UPDATE Users SET RoleId = ? WHERE UserName = ?")
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE Users SET RoleId = ? WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -648,6 +978,11 @@ func SetUserRoleId(username string, j UserRoleId) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -658,9 +993,9 @@ func SetUserRoleId(username string, j UserRoleId) (bool, error) {
 	return true, nil
 }
 
-func SetUserTypeId(username string, j UserType) (bool, error) {
+func (repo *sqlRepository) SetUserTypeId(username string, j UserType) (bool, error) {
 	log.Println("INFO: Set user's type Id for user '" + username + "'")
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction: " + string(err.Error()))
 		return false, err
@@ -676,7 +1011,12 @@ func SetUserTypeId(username string, j UserType) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("UPDATE Users SET TypeId = ? WHERE UserName = ?")
+	before, err := snapshotRow(t, "Users", "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("UPDATE Users SET TypeId = ? WHERE UserName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -694,6 +1034,11 @@ func SetUserTypeId(username string, j UserType) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditRow(t, "Users", before, "UserName = ?", username)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -704,9 +1049,9 @@ func SetUserTypeId(username string, j UserType) (bool, error) {
 	return true, nil
 }
 
-func CanDeleteUser(username string) (bool, error) {
+func (repo *sqlRepository) CanDeleteUser(username string) (bool, error) {
 	log.Println("INFO: Check if user '" + username + "' can be deleted")
-	user, err := GetUserByUserName(username)
+	user, err := repo.GetUserByUserName(username)
 	if err != nil {
 		log.Println("ERROR: Cannot retrieve user by username: " + string(err.Error()))
 		return false, err
@@ -721,9 +1066,9 @@ func CanDeleteUser(username string) (bool, error) {
 	return true, nil
 }
 
-func CanChangeUserStatus(username string) (bool, error) {
+func (repo *sqlRepository) CanChangeUserStatus(username string) (bool, error) {
 	log.Println("INFO: Check if user '" + username + "' status can be changed")
-	user, err := GetUserByUserName(username)
+	user, err := repo.GetUserByUserName(username)
 	if err != nil {
 		log.Println("ERROR: Cannot retrieve user by username: " + string(err.Error()))
 		return false, err
@@ -24,9 +24,9 @@ import (
 	"strconv"
 )
 
-func CreateVendor(v Vendor, id int) (bool, error) {
+func (repo *sqlRepository) CreateVendor(v Vendor, id int) (bool, error) {
 	log.Println("INFO: Vendor creation requested: " + v.VendorName)
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -42,18 +42,24 @@ func CreateVendor(v Vendor, id int) (bool, error) {
 		}
 	}()
 
-	q, err := t.Prepare("INSERT INTO Vendors (VendorName, CreatorId) VALUES (?, ?)")
+	q, err := t.Prepare("INSERT INTO Vendors (VendorName, CreatorId) VALUES (?, ?) RETURNING Id")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
 	}
 
-	_, err = q.Exec(v.VendorName, id)
+	var newId int
+	err = q.QueryRow(v.VendorName, id).Scan(&newId)
 	if err != nil {
 		log.Println("ERROR: Cannot create vendor '" + v.VendorName + "': " + string(err.Error()))
 		return false, err
 	}
 
+	err = repo.auditById(t, "Vendors", newId, rowSnapshot{})
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -64,9 +70,9 @@ func CreateVendor(v Vendor, id int) (bool, error) {
 	return true, nil
 }
 
-func DeleteVendor(vendorId int) (bool, error) {
+func (repo *sqlRepository) DeleteVendor(vendorId int) (bool, error) {
 	log.Println("INFO: Vendor deletion requested: " + strconv.Itoa(vendorId))
-	t, err := DB.Begin()
+	t, err := repo.db.Begin()
 	if err != nil {
 		log.Println("ERROR: Could not start DB transaction!" + string(err.Error()))
 		return false, err
@@ -82,7 +88,12 @@ func DeleteVendor(vendorId int) (bool, error) {
 		}
 	}()
 
-	q, err := DB.Prepare("DELETE FROM Vendors WHERE Id IS ?")
+	before, err := snapshotById(t, "Vendors", vendorId)
+	if err != nil {
+		return false, err
+	}
+
+	q, err := t.Prepare("DELETE FROM Vendors WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return false, err
@@ -94,6 +105,11 @@ func DeleteVendor(vendorId int) (bool, error) {
 		return false, err
 	}
 
+	err = repo.auditById(t, "Vendors", vendorId, before)
+	if err != nil {
+		return false, err
+	}
+
 	err = t.Commit()
 	if err != nil {
 		log.Println("ERROR: Could not commit the DB transaction!" + string(err.Error()))
@@ -104,9 +120,9 @@ func DeleteVendor(vendorId int) (bool, error) {
 	return true, nil
 }
 
-func GetVendors() ([]Vendor, error) {
+func (repo *sqlRepository) GetVendors() ([]Vendor, error) {
 	log.Println("INFO: List of vendor object requested")
-	rows, err := DB.Query("SELECT * FROM Vendors")
+	rows, err := repo.db.Query("SELECT * FROM Vendors")
 	if err != nil {
 		log.Println("ERROR: Could not run the DB query!" + string(err.Error()))
 		return nil, err
@@ -136,9 +152,9 @@ func GetVendors() ([]Vendor, error) {
 	return vendors, nil
 }
 
-func GetVendorById(id int) (Vendor, error) {
+func (repo *sqlRepository) GetVendorById(id int) (Vendor, error) {
 	log.Println("INFO: Vendor by Id requested: " + strconv.Itoa(id))
-	rec, err := DB.Prepare("SELECT * FROM Vendors WHERE Id = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Vendors WHERE Id = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Vendor{}, err
@@ -175,9 +191,9 @@ func GetVendorById(id int) (Vendor, error) {
 	return vendor, nil
 }
 
-func GetVendorByName(vendorName string) (Vendor, error) {
+func (repo *sqlRepository) GetVendorByName(vendorName string) (Vendor, error) {
 	log.Println("INFO: Vendor by Name requested: " + vendorName)
-	rec, err := DB.Prepare("SELECT * FROM Vendors WHERE VendorName = ?")
+	rec, err := repo.db.Prepare("SELECT * FROM Vendors WHERE VendorName = ?")
 	if err != nil {
 		log.Println("ERROR: Could not prepare the DB query!" + string(err.Error()))
 		return Vendor{}, err
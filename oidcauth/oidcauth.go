@@ -0,0 +1,290 @@
+package oidcauth
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrNoMappedGroup is returned when a user is in none of the groups mapped to
+// a role or an organizational unit, and there is no default to fall back to
+var ErrNoMappedGroup = errors.New("user is not a member of any mapped OIDC group")
+
+// ErrMissingUserName is returned when a token lacks the claim users are
+// named after
+var ErrMissingUserName = errors.New("token does not carry the user name claim")
+
+// GroupMapping maps the members of a group named in the groups claim to the
+// Id of a role or an organizational unit
+type GroupMapping struct {
+	Group string
+	Id    int
+}
+
+// Config is the OpenID provider users log in with and how the claims of its
+// tokens map to allocatord users. Access tokens presented as bearer
+// credentials must be JWTs issued for Audience. Accounts are known by the
+// issuer and the subject of their tokens, and UserNameClaim only names the
+// account made on a user's first login
+type Config struct {
+	Issuer           string
+	ClientId         string
+	ClientSecret     string
+	RedirectUrl      string
+	Scopes           []string
+	Audience         string
+	UserNameClaim    string
+	FullNameClaim    string
+	GroupsClaim      string
+	RoleGroups       []GroupMapping
+	OrgUnitGroups    []GroupMapping
+	DefaultRoleId    int
+	DefaultOrgUnitId int
+}
+
+// Identity is who a token says its user is, with the role and organizational
+// unit their groups map to
+type Identity struct {
+	Subject   string
+	UserName  string
+	FullName  string
+	Groups    []string
+	RoleId    int
+	OrgUnitId int
+}
+
+var config Config
+
+// the provider is discovered the first time it is needed, so the daemon
+// starts even while the provider cannot be reached
+var (
+	discovery sync.Mutex
+	provider  *oidc.Provider
+)
+
+// Configure sets the OpenID provider. Leaving the issuer empty turns OIDC
+// login off
+func Configure(c Config) error {
+	discovery.Lock()
+	defer discovery.Unlock()
+	provider = nil
+
+	if c.Issuer == "" {
+		config = Config{}
+		return nil
+	}
+	if c.ClientId == "" || c.RedirectUrl == "" {
+		return errors.New("an OIDC client Id and redirect URL are needed to log users in")
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if c.Audience == "" {
+		c.Audience = c.ClientId
+	}
+	if c.UserNameClaim == "" {
+		c.UserNameClaim = "preferred_username"
+	}
+	if c.FullNameClaim == "" {
+		c.FullNameClaim = "name"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+
+	config = c
+	return nil
+}
+
+// Enabled reports whether OIDC login has been configured
+func Enabled() bool {
+	return config.Issuer != ""
+}
+
+// Provider is the identity provider the accounts of OIDC users are recorded
+// as belonging to. Subjects are only unique within an issuer, so it is named
+// after the issuer
+func Provider() string {
+	return "oidc:" + config.Issuer
+}
+
+func discover(ctx context.Context) (*oidc.Provider, error) {
+	discovery.Lock()
+	defer discovery.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	p, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot discover the OIDC provider: %w", err)
+	}
+	provider = p
+	return provider, nil
+}
+
+func oauth2Config(p *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.ClientId,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectUrl,
+		Endpoint:     p.Endpoint(),
+		Scopes:       config.Scopes,
+	}
+}
+
+// NewChallenge returns the random state, nonce and PKCE code verifier of a
+// login, which have to be kept until its callback
+func NewChallenge() (state string, nonce string, verifier string, err error) {
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", "", "", err
+	}
+	state = hex.EncodeToString(buf[:16])
+	nonce = hex.EncodeToString(buf[16:])
+
+	return state, nonce, oauth2.GenerateVerifier(), nil
+}
+
+// AuthCodeURL returns where to send the user to log in with the provider
+func AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oauth2Config(p).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange trades the code the provider sent the user back with for their
+// tokens, and returns who the ID token says they are
+func Exchange(ctx context.Context, code string, nonce string, verifier string) (Identity, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := oauth2Config(p).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("cannot exchange the authorization code: %w", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("the OIDC provider did not return an ID token")
+	}
+
+	idToken, err := p.Verifier(&oidc.Config{ClientID: config.ClientId}).Verify(ctx, rawIdToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("invalid ID token: nonce does not match")
+	}
+
+	return identityFromToken(idToken)
+}
+
+// VerifyAccessToken checks an access token presented as a bearer credential
+// and returns who it says its user is
+func VerifyAccessToken(ctx context.Context, rawToken string) (Identity, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := p.Verifier(&oidc.Config{ClientID: config.Audience}).Verify(ctx, rawToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	return identityFromToken(token)
+}
+
+func identityFromToken(token *oidc.IDToken) (Identity, error) {
+	var claims map[string]any
+	err := token.Claims(&claims)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		Subject:  token.Subject,
+		UserName: stringClaim(claims, config.UserNameClaim),
+		FullName: stringClaim(claims, config.FullNameClaim),
+		Groups:   listClaim(claims, config.GroupsClaim),
+	}
+	if identity.UserName == "" {
+		return Identity{}, ErrMissingUserName
+	}
+	if identity.FullName == "" {
+		identity.FullName = identity.UserName
+	}
+	identity.RoleId = mapGroups(identity.Groups, config.RoleGroups, config.DefaultRoleId)
+	identity.OrgUnitId = mapGroups(identity.Groups, config.OrgUnitGroups, config.DefaultOrgUnitId)
+	if identity.RoleId == 0 || identity.OrgUnitId == 0 {
+		return identity, ErrNoMappedGroup
+	}
+
+	return identity, nil
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// listClaim reads a claim holding either a list of strings or, as some
+// providers send a single group, one string
+func listClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// mapGroups returns the Id of the first mapping the user's groups match
+func mapGroups(groups []string, mappings []GroupMapping, fallback int) int {
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if strings.EqualFold(group, mapping.Group) {
+				return mapping.Id
+			}
+		}
+	}
+	return fallback
+}
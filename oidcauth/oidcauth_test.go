@@ -0,0 +1,299 @@
+package oidcauth
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/oidcauth/oidctest"
+)
+
+const (
+	clientId       = "allocatord"
+	redirectUrl    = "https://allocatord.example.com/api/v1/oidc/callback"
+	adminRoleId    = 2
+	operatorRoleId = 3
+	salesOrgUnitId = 4
+)
+
+// startProvider starts an OpenID provider and points the package at it
+func startProvider(t *testing.T) *oidctest.Provider {
+	t.Helper()
+	p := oidctest.NewProvider()
+	t.Cleanup(p.Close)
+
+	err := Configure(Config{
+		Issuer:      p.URL,
+		ClientId:    clientId,
+		RedirectUrl: redirectUrl,
+		Audience:    "allocatord-api",
+		RoleGroups: []GroupMapping{
+			{Group: "allocatord-admins", Id: adminRoleId},
+			{Group: "allocatord-operators", Id: operatorRoleId},
+		},
+		OrgUnitGroups:    []GroupMapping{{Group: "sales", Id: salesOrgUnitId}},
+		DefaultOrgUnitId: 1,
+	})
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { Configure(Config{}) })
+	return p
+}
+
+func userClaims(username string, groups ...string) map[string]any {
+	return map[string]any{
+		"sub":                "sub-" + username,
+		"preferred_username": username,
+		"name":               "Test " + username,
+		"groups":             groups,
+	}
+}
+
+// login starts a login and has the provider send the user back, returning
+// the code and state of the callback
+func login(t *testing.T, p *oidctest.Provider, nonce string, verifier string, claims map[string]any) (string, string) {
+	t.Helper()
+	authUrl, err := AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	callback, err := p.Login(authUrl, claims)
+	if err != nil {
+		t.Fatalf("provider login: %v", err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("callback URL: %v", err)
+	}
+	return u.Query().Get("code"), u.Query().Get("state")
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Config{})
+
+	err := Configure(Config{Issuer: "https://idp.example.com"})
+	if err == nil {
+		t.Errorf("Configure without a client Id and redirect URL succeeded")
+	}
+	err = Configure(Config{Issuer: "https://idp.example.com", ClientId: clientId, RedirectUrl: redirectUrl})
+	if err != nil || !Enabled() {
+		t.Fatalf("Configure = %v, enabled %v; want OIDC on", err, Enabled())
+	}
+	if config.Audience != clientId || config.UserNameClaim != "preferred_username" || config.GroupsClaim != "groups" {
+		t.Errorf("defaults not applied: %+v", config)
+	}
+	err = Configure(Config{})
+	if err != nil || Enabled() {
+		t.Errorf("Configure without an issuer = %v, enabled %v; want OIDC off", err, Enabled())
+	}
+}
+
+func TestNewChallenge(t *testing.T) {
+	state, nonce, verifier, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	if state == "" || nonce == "" || state == nonce || len(verifier) < 43 {
+		t.Errorf("NewChallenge = %q, %q, %q; want distinct random values", state, nonce, verifier)
+	}
+	again, _, _, _ := NewChallenge()
+	if again == state {
+		t.Errorf("NewChallenge returned the same state twice")
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p := startProvider(t)
+
+	authUrl, err := AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-verifier-the-verifier-the-verifier-1234")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authUrl, p.URL+"/authorize?") {
+		t.Errorf("AuthCodeURL = %s, want the provider's authorization endpoint", authUrl)
+	}
+	u, _ := url.Parse(authUrl)
+	q := u.Query()
+	sum := sha256.Sum256([]byte("the-verifier-the-verifier-the-verifier-1234"))
+	want := map[string]string{
+		"client_id":             clientId,
+		"redirect_uri":          redirectUrl,
+		"response_type":         "code",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge_method": "S256",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	for name, value := range want {
+		if q.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, q.Get(name), value)
+		}
+	}
+	if !strings.Contains(q.Get("scope"), "openid") {
+		t.Errorf("scope = %q, want openid among them", q.Get("scope"))
+	}
+}
+
+func TestExchange(t *testing.T) {
+	p := startProvider(t)
+	_, nonce, verifier, _ := NewChallenge()
+
+	code, state := login(t, p, nonce, verifier, userClaims("alice", "allocatord-admins", "sales"))
+	if state != "state-1" {
+		t.Errorf("provider sent back state %q, want state-1", state)
+	}
+	identity, err := Exchange(context.Background(), code, nonce, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != "sub-alice" || identity.UserName != "alice" || identity.FullName != "Test alice" {
+		t.Errorf("identity = %+v, want alice", identity)
+	}
+	if identity.RoleId != adminRoleId || identity.OrgUnitId != salesOrgUnitId {
+		t.Errorf("role %d and OU %d, want role %d and OU %d", identity.RoleId, identity.OrgUnitId, adminRoleId, salesOrgUnitId)
+	}
+
+	// codes are good for one exchange only
+	_, err = Exchange(context.Background(), code, nonce, verifier)
+	if err == nil {
+		t.Errorf("exchanging a code twice succeeded")
+	}
+}
+
+func TestExchangeRejected(t *testing.T) {
+	p := startProvider(t)
+	_, nonce, verifier, _ := NewChallenge()
+	_, _, otherVerifier, _ := NewChallenge()
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _ := login(t, p, nonce, verifier, userClaims("alice", "allocatord-admins"))
+		_, err := Exchange(context.Background(), code, nonce, otherVerifier)
+		if err == nil {
+			t.Errorf("Exchange with the wrong PKCE verifier succeeded")
+		}
+	})
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, _ := login(t, p, nonce, verifier, userClaims("alice", "allocatord-admins"))
+		_, err := Exchange(context.Background(), code, "another-nonce", verifier)
+		if err == nil || !strings.Contains(err.Error(), "nonce") {
+			t.Errorf("Exchange with the wrong nonce = %v, want a nonce error", err)
+		}
+	})
+	t.Run("replayed ID token", func(t *testing.T) {
+		claims := userClaims("alice", "allocatord-admins")
+		claims["nonce"] = "nonce-of-another-login"
+		code, _ := login(t, p, nonce, verifier, claims)
+		_, err := Exchange(context.Background(), code, nonce, verifier)
+		if err == nil || !strings.Contains(err.Error(), "nonce") {
+			t.Errorf("Exchange of an ID token with another login's nonce = %v, want a nonce error", err)
+		}
+	})
+	t.Run("ID token for another client", func(t *testing.T) {
+		claims := userClaims("alice", "allocatord-admins")
+		claims["aud"] = "another-client"
+		code, _ := login(t, p, nonce, verifier, claims)
+		_, err := Exchange(context.Background(), code, nonce, verifier)
+		if err == nil {
+			t.Errorf("Exchange of an ID token for another client succeeded")
+		}
+	})
+	t.Run("unknown code", func(t *testing.T) {
+		_, err := Exchange(context.Background(), "made-up", nonce, verifier)
+		if err == nil {
+			t.Errorf("Exchange of a made up code succeeded")
+		}
+	})
+}
+
+func TestClaimMapping(t *testing.T) {
+	p := startProvider(t)
+
+	tests := []struct {
+		name      string
+		claims    map[string]any
+		roleId    int
+		orgUnitId int
+		fullName  string
+		err       error
+	}{
+		{"groups list", userClaims("alice", "allocatord-admins", "sales"), adminRoleId, salesOrgUnitId, "Test alice", nil},
+		{"first mapping wins", userClaims("bob", "allocatord-operators", "allocatord-admins"), adminRoleId, 1, "Test bob", nil},
+		{"case insensitive", userClaims("carol", "Allocatord-Operators", "SALES"), operatorRoleId, salesOrgUnitId, "Test carol", nil},
+		{"single group string", map[string]any{"sub": "dave", "preferred_username": "dave", "groups": "allocatord-operators"}, operatorRoleId, 1, "dave", nil},
+		{"no mapped group", userClaims("erin", "other"), 0, 1, "Test erin", ErrNoMappedGroup},
+		{"no groups claim", map[string]any{"sub": "frank", "preferred_username": "frank"}, 0, 1, "frank", ErrNoMappedGroup},
+		{"no user name", map[string]any{"sub": "grace", "groups": []string{"allocatord-admins"}}, 0, 0, "", ErrMissingUserName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := VerifyAccessToken(context.Background(), p.Token("allocatord-api", tt.claims))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("VerifyAccessToken = %v, want %v", err, tt.err)
+			}
+			if identity.RoleId != tt.roleId || identity.OrgUnitId != tt.orgUnitId || identity.FullName != tt.fullName {
+				t.Errorf("identity %+v, want role %d, OU %d and full name %q", identity, tt.roleId, tt.orgUnitId, tt.fullName)
+			}
+		})
+	}
+}
+
+func TestVerifyAccessTokenRejected(t *testing.T) {
+	p := startProvider(t)
+	other := oidctest.NewProvider()
+	defer other.Close()
+
+	claims := userClaims("alice", "allocatord-admins")
+	expired := userClaims("alice", "allocatord-admins")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	foreignIssuer := userClaims("alice", "allocatord-admins")
+	foreignIssuer["iss"] = other.URL
+
+	for name, token := range map[string]string{
+		"wrong audience":   p.Token(clientId, claims),
+		"expired":          p.Token("allocatord-api", expired),
+		"another issuer":   p.Token("allocatord-api", foreignIssuer),
+		"another key":      other.Token("allocatord-api", map[string]any{"iss": p.URL, "sub": "alice", "preferred_username": "alice"}),
+		"not a JWT":        "not-a-token",
+		"tampered payload": tamper(p.Token("allocatord-api", claims)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := VerifyAccessToken(context.Background(), token)
+			if err == nil {
+				t.Errorf("VerifyAccessToken accepted a token it should not have")
+			}
+		})
+	}
+}
+
+// tamper swaps the payload of a token for one granting more, keeping the
+// original signature
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	payload := `{"sub":"mallory","preferred_username":"mallory","groups":["allocatord-admins"]}`
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,250 @@
+package oidctest
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+// Package oidctest provides an OpenID provider for tests, in the manner of
+// net/http/httptest. It serves discovery, its signing keys and a token
+// endpoint checking PKCE, and stands in for the user at the authorization
+// endpoint so tests need no browser
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const keyId = "oidctest"
+
+// Provider is an OpenID provider on a port of the loopback interface
+type Provider struct {
+	// URL is the issuer of the provider
+	URL string
+
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	mutex   sync.Mutex
+	grants  map[string]grant
+	counter int
+}
+
+// grant is an authorization code waiting to be exchanged
+type grant struct {
+	clientId    string
+	redirectUri string
+	challenge   string
+	nonce       string
+	claims      map[string]any
+}
+
+// NewProvider starts a provider with a fresh signing key. It is stopped with
+// Close
+func NewProvider() *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: cannot generate a signing key: " + err.Error())
+	}
+
+	p := &Provider{key: key, grants: make(map[string]grant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	p.URL = p.server.URL
+	return p
+}
+
+// Close stops the provider
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// Login plays the part of a user logging in at the authorization endpoint.
+// It takes the URL a client sent the user to, and returns the URL the user
+// is sent back to with an authorization code. The ID token the code is
+// exchanged for carries the given claims, over any the provider sets itself
+func (p *Provider) Login(authCodeUrl string, claims map[string]any) (string, error) {
+	u, err := url.Parse(authCodeUrl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Get("response_type") != "code" {
+		return "", errors.New("oidctest: only the authorization code flow is supported")
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", errors.New("oidctest: a PKCE S256 code challenge is required")
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" {
+		return "", errors.New("oidctest: a redirect URI is required")
+	}
+
+	p.mutex.Lock()
+	p.counter++
+	code := "code-" + strconv.Itoa(p.counter)
+	p.grants[code] = grant{
+		clientId:    q.Get("client_id"),
+		redirectUri: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		claims:      claims,
+	}
+	p.mutex.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", q.Get("state"))
+	redirect.RawQuery = values.Encode()
+	return redirect.String(), nil
+}
+
+// Token returns a token signed by the provider for the given audience. The
+// issuer, issue time and an expiry an hour away are set unless the claims
+// carry their own
+func (p *Provider) Token(audience string, claims map[string]any) string {
+	return p.sign(p.withDefaults(audience, claims))
+}
+
+func (p *Provider) withDefaults(audience string, claims map[string]any) map[string]any {
+	now := time.Now()
+	all := map[string]any{
+		"iss": p.URL,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		all[name] = value
+	}
+	return all
+}
+
+func (p *Provider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyId})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic("oidctest: cannot encode claims: " + err.Error())
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		panic("oidctest: cannot sign a token: " + err.Error())
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	public := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyId,
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+// token exchanges an authorization code, once, for an ID token and an access
+// token, provided the code verifier matches the challenge it was issued for
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		tokenError(w, "invalid_request", "a form POST is required")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mutex.Lock()
+	g, found := p.grants[code]
+	delete(p.grants, code)
+	p.mutex.Unlock()
+	if !found {
+		tokenError(w, "invalid_grant", "unknown or used authorization code")
+		return
+	}
+
+	clientId, _, hasBasicAuth := r.BasicAuth()
+	if !hasBasicAuth {
+		clientId = r.PostForm.Get("client_id")
+	}
+	if clientId != g.clientId || r.PostForm.Get("redirect_uri") != g.redirectUri {
+		tokenError(w, "invalid_grant", "the code was issued to another client or redirect URI")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, "invalid_grant", "the code verifier does not match the challenge")
+		return
+	}
+
+	idClaims := map[string]any{}
+	if g.nonce != "" {
+		idClaims["nonce"] = g.nonce
+	}
+	for name, value := range g.claims {
+		idClaims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"access_token": p.Token(g.clientId, g.claims),
+		"id_token":     p.Token(g.clientId, idClaims),
+	})
+}
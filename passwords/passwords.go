@@ -0,0 +1,184 @@
+package passwords
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// the hashing algorithms new passwords can be stored with
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+const (
+	saltLength    = 16
+	argon2KeySize = 32
+	// unsalted hex encoded SHA-512, which accounts created before the move to
+	// a proper KDF still carry until their owner next logs in
+	legacyHashLength = sha512.Size * 2
+)
+
+// Params is how new password hashes are made. Hashes made with other
+// parameters still verify, but are reported as needing a rehash
+type Params struct {
+	Algorithm      string
+	Argon2Time     uint32
+	Argon2MemoryKB uint32
+	Argon2Threads  uint8
+	BcryptCost     int
+}
+
+// DefaultParams follows the OWASP recommendation for argon2id
+func DefaultParams() Params {
+	return Params{
+		Algorithm:      Argon2id,
+		Argon2Time:     3,
+		Argon2MemoryKB: 64 * 1024,
+		Argon2Threads:  2,
+		BcryptCost:     bcrypt.DefaultCost,
+	}
+}
+
+var params = DefaultParams()
+
+// Configure sets the parameters new hashes are made with. Unset fields keep
+// their defaults
+func Configure(p Params) error {
+	defaults := DefaultParams()
+	if p.Algorithm == "" {
+		p.Algorithm = defaults.Algorithm
+	}
+	if p.Argon2Time == 0 {
+		p.Argon2Time = defaults.Argon2Time
+	}
+	if p.Argon2MemoryKB == 0 {
+		p.Argon2MemoryKB = defaults.Argon2MemoryKB
+	}
+	if p.Argon2Threads == 0 {
+		p.Argon2Threads = defaults.Argon2Threads
+	}
+	if p.BcryptCost == 0 {
+		p.BcryptCost = defaults.BcryptCost
+	}
+
+	switch p.Algorithm {
+	case Argon2id, Bcrypt:
+	default:
+		return errors.New("unsupported password hash algorithm '" + p.Algorithm + "', expected " + Argon2id + " or " + Bcrypt)
+	}
+	if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	params = p
+	return nil
+}
+
+// Hash returns the PHC string of a password, salted and hashed with the
+// configured algorithm
+func Hash(password string) (string, error) {
+	if params.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2MemoryKB, params.Argon2Threads, argon2KeySize)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		params.Argon2MemoryKB, params.Argon2Time, params.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether the password matches the stored hash and, when it
+// does, whether the hash should be replaced with one made with the current
+// algorithm and parameters. Hashes in a format it does not know never match
+func Verify(password string, encoded string) (match bool, needsRehash bool) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return true, err != nil || params.Algorithm != Bcrypt || cost != params.BcryptCost
+	case len(encoded) == legacyHashLength:
+		sum := sha512.Sum512([]byte(password))
+		legacy := hex.EncodeToString(sum[:])
+		match := subtle.ConstantTimeCompare([]byte(legacy), []byte(strings.ToLower(encoded))) == 1
+		return match, match
+	}
+
+	return false, false
+}
+
+func verifyArgon2id(password string, encoded string) (bool, bool) {
+	// $argon2id$v=19$m=65536,t=3,p=2$salt$key
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 {
+		return false, false
+	}
+
+	var version int
+	_, err := fmt.Sscanf(fields[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return false, false
+	}
+	var memory, time uint32
+	var threads uint8
+	_, err = fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
+	if err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, false
+	}
+
+	return true, params.Algorithm != Argon2id || memory != params.Argon2MemoryKB || time != params.Argon2Time || threads != params.Argon2Threads
+}
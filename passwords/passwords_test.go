@@ -0,0 +1,223 @@
+package passwords
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, so the tests do not spend their time hashing
+var (
+	fastArgon2id = Params{Algorithm: Argon2id, Argon2Time: 1, Argon2MemoryKB: 1024, Argon2Threads: 1}
+	fastBcrypt   = Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}
+)
+
+func configure(t *testing.T, p Params) {
+	t.Helper()
+	err := Configure(p)
+	if err != nil {
+		t.Fatalf("Configure(%+v): %v", p, err)
+	}
+	t.Cleanup(func() { params = DefaultParams() })
+}
+
+func hash(t *testing.T, p Params, password string) string {
+	t.Helper()
+	configure(t, p)
+	encoded, err := Hash(password)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	return encoded
+}
+
+func legacyHash(password string) string {
+	sum := sha512.Sum512([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		prefix string
+	}{
+		{"argon2id", fastArgon2id, "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{"bcrypt", fastBcrypt, "$2a$04$"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := hash(t, test.params, "Correct-horse-1")
+			if !strings.HasPrefix(encoded, test.prefix) {
+				t.Errorf("Hash = %s, want it to start with %s", encoded, test.prefix)
+			}
+			if again := hash(t, test.params, "Correct-horse-1"); again == encoded {
+				t.Errorf("hashing the same password twice gave %s both times, want different salts", encoded)
+			}
+
+			match, needsRehash := Verify("Correct-horse-1", encoded)
+			if !match || needsRehash {
+				t.Errorf("Verify of the right password = %v, %v, want a match that needs no rehash", match, needsRehash)
+			}
+			match, _ = Verify("Correct-horse-2", encoded)
+			if match {
+				t.Errorf("Verify of the wrong password matched")
+			}
+		})
+	}
+}
+
+// TestRehash checks that a hash made with other parameters than the current
+// ones still verifies, but is reported as needing to be replaced
+func TestRehash(t *testing.T) {
+	strongerArgon2id := fastArgon2id
+	strongerArgon2id.Argon2MemoryKB = 2048
+	strongerBcrypt := fastBcrypt
+	strongerBcrypt.BcryptCost = bcrypt.MinCost + 1
+
+	tests := []struct {
+		name        string
+		hashedWith  Params
+		verifiedBy  Params
+		needsRehash bool
+	}{
+		{"argon2id unchanged", fastArgon2id, fastArgon2id, false},
+		{"argon2id memory raised", fastArgon2id, strongerArgon2id, true},
+		{"argon2id time raised", fastArgon2id, Params{Algorithm: Argon2id, Argon2Time: 2, Argon2MemoryKB: 1024, Argon2Threads: 1}, true},
+		{"argon2id threads raised", fastArgon2id, Params{Algorithm: Argon2id, Argon2Time: 1, Argon2MemoryKB: 1024, Argon2Threads: 2}, true},
+		{"argon2id to bcrypt", fastArgon2id, fastBcrypt, true},
+		{"bcrypt unchanged", fastBcrypt, fastBcrypt, false},
+		{"bcrypt cost raised", fastBcrypt, strongerBcrypt, true},
+		{"bcrypt to argon2id", fastBcrypt, fastArgon2id, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := hash(t, test.hashedWith, "Correct-horse-1")
+			configure(t, test.verifiedBy)
+
+			match, needsRehash := Verify("Correct-horse-1", encoded)
+			if !match || needsRehash != test.needsRehash {
+				t.Errorf("Verify = %v, %v, want a match with needsRehash %v", match, needsRehash, test.needsRehash)
+			}
+			match, needsRehash = Verify("Correct-horse-2", encoded)
+			if match || needsRehash {
+				t.Errorf("Verify of the wrong password = %v, %v, want no match and no rehash", match, needsRehash)
+			}
+		})
+	}
+}
+
+// TestLegacyUpgrade checks that the unsalted SHA-512 hashes of old accounts
+// verify once more, always asking to be replaced by one of the current kind
+func TestLegacyUpgrade(t *testing.T) {
+	for _, p := range []Params{fastArgon2id, fastBcrypt} {
+		t.Run(p.Algorithm, func(t *testing.T) {
+			configure(t, p)
+			for _, legacy := range []string{legacyHash("Correct-horse-1"), strings.ToUpper(legacyHash("Correct-horse-1"))} {
+				match, needsRehash := Verify("Correct-horse-1", legacy)
+				if !match || !needsRehash {
+					t.Errorf("Verify of a legacy hash = %v, %v, want a match that needs a rehash", match, needsRehash)
+				}
+				match, needsRehash = Verify("Correct-horse-2", legacy)
+				if match || needsRehash {
+					t.Errorf("Verify of the wrong password against a legacy hash = %v, %v, want no match and no rehash", match, needsRehash)
+				}
+			}
+
+			// as the login does once the password matched
+			upgraded, err := Hash("Correct-horse-1")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			match, needsRehash := Verify("Correct-horse-1", upgraded)
+			if !match || needsRehash {
+				t.Errorf("Verify of the upgraded hash = %v, %v, want a match that needs no rehash", match, needsRehash)
+			}
+		})
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	configure(t, fastArgon2id)
+	valid := hash(t, fastArgon2id, "Correct-horse-1")
+	fields := strings.Split(valid, "$")
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"plain text", "Correct-horse-1"},
+		{"locked account marker", "!"},
+		{"legacy length but not hex", strings.Repeat("z", legacyHashLength)},
+		{"argon2id missing key", strings.Join(fields[:5], "$")},
+		{"argon2id other version", strings.Join([]string{"", "argon2id", "v=16", fields[3], fields[4], fields[5]}, "$")},
+		{"argon2id garbled parameters", strings.Join([]string{"", "argon2id", fields[2], "m=lots", fields[4], fields[5]}, "$")},
+		{"argon2id salt not base64", strings.Join([]string{"", "argon2id", fields[2], fields[3], "!!", fields[5]}, "$")},
+		{"argon2id empty key", strings.Join([]string{"", "argon2id", fields[2], fields[3], fields[4], ""}, "$")},
+		{"bcrypt truncated", "$2a$04$short"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, needsRehash := Verify("Correct-horse-1", test.encoded)
+			if match || needsRehash {
+				t.Errorf("Verify(%q) = %v, %v, want no match and no rehash", test.encoded, match, needsRehash)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { params = DefaultParams() })
+
+	tests := []struct {
+		name  string
+		p     Params
+		valid bool
+	}{
+		{"defaults", Params{}, true},
+		{"bcrypt", Params{Algorithm: Bcrypt}, true},
+		{"unknown algorithm", Params{Algorithm: "md5"}, false},
+		{"bcrypt cost too low", Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost - 1}, false},
+		{"bcrypt cost too high", Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MaxCost + 1}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Configure(test.p)
+			if (err == nil) != test.valid {
+				t.Errorf("Configure(%+v) = %v, want valid %v", test.p, err, test.valid)
+			}
+		})
+	}
+
+	err := Configure(Params{Argon2Time: 5})
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	want := DefaultParams()
+	want.Argon2Time = 5
+	if params != want {
+		t.Errorf("after configuring only the time, params = %+v, want the defaults otherwise: %+v", params, want)
+	}
+}
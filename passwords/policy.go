@@ -0,0 +1,117 @@
+package passwords
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Policy is what a new password must look like and how long it, and a
+// lockout after failed logins, lasts. Zero values switch a rule off
+type Policy struct {
+	MinLength       int
+	RequireUpper    bool
+	RequireLower    bool
+	RequireDigit    bool
+	RequireSymbol   bool
+	MaxAgeDays      int
+	MaxFailedLogins int
+	LockoutMinutes  int
+}
+
+var policy Policy
+
+// ConfigurePolicy sets the password policy
+func ConfigurePolicy(p Policy) error {
+	if p.MinLength < 0 || p.MaxAgeDays < 0 || p.MaxFailedLogins < 0 || p.LockoutMinutes < 0 {
+		return errors.New("password policy values cannot be negative")
+	}
+	if p.MaxFailedLogins > 0 && p.LockoutMinutes == 0 {
+		return errors.New("a lockout duration is needed when failed logins are limited")
+	}
+
+	policy = p
+	return nil
+}
+
+// CurrentPolicy returns the password policy in force
+func CurrentPolicy() Policy {
+	return policy
+}
+
+// CheckPolicy returns an error naming every rule the password breaks
+func CheckPolicy(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var broken []string
+	if len([]rune(password)) < policy.MinLength {
+		broken = append(broken, "be at least "+strconv.Itoa(policy.MinLength)+" characters long")
+	}
+	if policy.RequireUpper && !upper {
+		broken = append(broken, "contain an upper case letter")
+	}
+	if policy.RequireLower && !lower {
+		broken = append(broken, "contain a lower case letter")
+	}
+	if policy.RequireDigit && !digit {
+		broken = append(broken, "contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		broken = append(broken, "contain a symbol")
+	}
+	if len(broken) > 0 {
+		return errors.New("password must " + strings.Join(broken, ", "))
+	}
+
+	return nil
+}
+
+// Expired reports whether a password last changed at the given time is
+// older than the maximum password age
+func Expired(lastChanged time.Time, now time.Time) bool {
+	if policy.MaxAgeDays == 0 {
+		return false
+	}
+	return now.After(lastChanged.AddDate(0, 0, policy.MaxAgeDays))
+}
+
+// LockoutEnds returns when an account that has failed to log in the given
+// number of times is unlocked again, or false while it is below the limit
+func LockoutEnds(failedLogins int, now time.Time) (time.Time, bool) {
+	if policy.MaxFailedLogins == 0 || failedLogins < policy.MaxFailedLogins {
+		return time.Time{}, false
+	}
+	return now.Add(time.Duration(policy.LockoutMinutes) * time.Minute), true
+}
@@ -0,0 +1,154 @@
+package passwords
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func configurePolicy(t *testing.T, p Policy) {
+	t.Helper()
+	err := ConfigurePolicy(p)
+	if err != nil {
+		t.Fatalf("ConfigurePolicy(%+v): %v", p, err)
+	}
+	t.Cleanup(func() { policy = Policy{} })
+}
+
+func TestCheckPolicy(t *testing.T) {
+	strict := Policy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		broken   []string
+	}{
+		{"no policy", Policy{}, "", nil},
+		{"meets everything", strict, "Correct-horse-1", nil},
+		{"too short", strict, "Co-horse1", []string{"at least 10 characters"}},
+		{"length counts characters, not bytes", Policy{MinLength: 4}, "ääää", nil},
+		{"no upper case", strict, "correct-horse-1", []string{"upper case"}},
+		{"no lower case", strict, "CORRECT-HORSE-1", []string{"lower case"}},
+		{"no digit", strict, "Correct-horse-one", []string{"digit"}},
+		{"no symbol", strict, "CorrectHorse1", []string{"symbol"}},
+		{"space is a symbol", strict, "Correct horse 1", nil},
+		{"every rule broken", strict, "", []string{"at least 10 characters", "upper case", "lower case", "digit", "symbol"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configurePolicy(t, test.policy)
+			err := CheckPolicy(test.password)
+			if len(test.broken) == 0 {
+				if err != nil {
+					t.Errorf("CheckPolicy(%q) = %v, want no error", test.password, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("CheckPolicy(%q) = nil, want an error naming %v", test.password, test.broken)
+			}
+			for _, rule := range test.broken {
+				if !strings.Contains(err.Error(), rule) {
+					t.Errorf("CheckPolicy(%q) = %v, want it to name %q", test.password, err, rule)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigurePolicy(t *testing.T) {
+	t.Cleanup(func() { policy = Policy{} })
+
+	tests := []struct {
+		name  string
+		p     Policy
+		valid bool
+	}{
+		{"off", Policy{}, true},
+		{"negative length", Policy{MinLength: -1}, false},
+		{"negative age", Policy{MaxAgeDays: -1}, false},
+		{"negative failed logins", Policy{MaxFailedLogins: -1}, false},
+		{"negative lockout", Policy{LockoutMinutes: -1}, false},
+		{"failed logins without a lockout", Policy{MaxFailedLogins: 5}, false},
+		{"failed logins with a lockout", Policy{MaxFailedLogins: 5, LockoutMinutes: 15}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ConfigurePolicy(test.p)
+			if (err == nil) != test.valid {
+				t.Errorf("ConfigurePolicy(%+v) = %v, want valid %v", test.p, err, test.valid)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	changed := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		maxAgeDays int
+		now        time.Time
+		expired    bool
+	}{
+		{"no maximum age", 0, changed.AddDate(10, 0, 0), false},
+		{"within the age", 90, changed.AddDate(0, 0, 89), false},
+		{"exactly at the age", 90, changed.AddDate(0, 0, 90), false},
+		{"past the age", 90, changed.AddDate(0, 0, 90).Add(time.Second), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configurePolicy(t, Policy{MaxAgeDays: test.maxAgeDays})
+			if expired := Expired(changed, test.now); expired != test.expired {
+				t.Errorf("Expired = %v, want %v", expired, test.expired)
+			}
+		})
+	}
+}
+
+func TestLockoutEnds(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		failedLogins int
+		locked       bool
+	}{
+		{"no limit", Policy{}, 1000, false},
+		{"below the limit", Policy{MaxFailedLogins: 5, LockoutMinutes: 15}, 4, false},
+		{"at the limit", Policy{MaxFailedLogins: 5, LockoutMinutes: 15}, 5, true},
+		{"past the limit", Policy{MaxFailedLogins: 5, LockoutMinutes: 15}, 6, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configurePolicy(t, test.policy)
+			ends, locked := LockoutEnds(test.failedLogins, now)
+			if locked != test.locked {
+				t.Fatalf("LockoutEnds(%d) locked = %v, want %v", test.failedLogins, locked, test.locked)
+			}
+			if locked && !ends.Equal(now.Add(15*time.Minute)) {
+				t.Errorf("LockoutEnds(%d) = %v, want 15 minutes from now", test.failedLogins, ends)
+			}
+		})
+	}
+}
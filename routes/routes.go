@@ -22,112 +22,235 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/greeneg/allocatord/controllers"
+	"github.com/greeneg/allocatord/middleware"
+	"github.com/greeneg/allocatord/model"
 )
 
 func PrivateRoutes(g *gin.RouterGroup, a *controllers.Allocator) {
+	// Answer File Templates
+	g.GET("/answerFileTemplates", a.GetAnswerFileTemplates)                                                                                 // get all answer file templates
+	g.GET("/answerFileTemplates/byOSFamilyId/:osFamilyId", a.GetAnswerFileTemplatesByOSFamilyId)                                            // get answer file templates by OS family Id
+	g.GET("/answerFileTemplate/byId/:templateId", a.GetAnswerFileTemplateById)                                                              // get an answer file template by Id
+	g.POST("/answerFileTemplate", middleware.RequirePermission(model.PermissionAnswerFilesWrite), a.CreateAnswerFileTemplate)               // create a new answer file template version
+	g.POST("/answerFileTemplate/render", middleware.RequirePermission(model.PermissionAnswerFilesWrite), a.RenderAnswerFileTemplate)        // dry-run render a template for a system
+	g.DELETE("/answerFileTemplate/:templateId", middleware.RequirePermission(model.PermissionAnswerFilesWrite), a.DeleteAnswerFileTemplate) // delete an answer file template version
 	// Architectures
-	g.GET("/architectures", a.GetArchitectures)                              // get all architectures
-	g.GET("/architecture/byId/:architectureId", a.GetArchitectureById)       // get architecture by Id
-	g.GET("/architecture/byName/:architectureName", a.GetArchitectureByName) // get architectures by name
-	g.POST("/architecture", a.CreateArchitecture)                            // create a new architecture record
-	g.DELETE("/architecture/:architectureId", a.DeleteArchitecture)          // delete an architecture by Id
+	g.GET("/architectures", a.GetArchitectures)                                                                                       // get all architectures
+	g.GET("/architecture/byId/:architectureId", a.GetArchitectureById)                                                                // get architecture by Id
+	g.GET("/architecture/byName/:architectureName", a.GetArchitectureByName)                                                          // get architectures by name
+	g.POST("/architecture", middleware.RequirePermission(model.PermissionArchitecturesWrite), a.CreateArchitecture)                   // create a new architecture record
+	g.DELETE("/architecture/:architectureId", middleware.RequirePermission(model.PermissionArchitecturesWrite), a.DeleteArchitecture) // delete an architecture by Id
+	// Audit trail
+	g.GET("/audit", middleware.RequirePermission(model.PermissionAuditRead), a.GetAuditEntries)                 // get the audit trail, optionally filtered
+	g.GET("/audit/byId/:entryId", middleware.RequirePermission(model.PermissionAuditRead), a.GetAuditEntryById) // get an audit trail entry by Id
 	// Buildings
-	g.GET("/buildings", a.GetBuildings)                              // get all buildings
-	g.GET("/building/byId/:id", a.GetBuildingById)                   // get building by Id
-	g.GET("/building/byShortName/:abbrev", a.GetBuildingByShortName) // get building by abbreviation
-	g.POST("/building", a.CreateBuilding)                            // create a new building
-	g.PATCH("/building/:buildingId", a.UpdateBuildingById)           // update a building by its Id
-	g.DELETE("/building/:buildingId", a.DeleteBuilding)              // delete a building by its Id
+	g.GET("/buildings", a.GetBuildings)                                                                                  // get all buildings
+	g.GET("/building/byId/:id", a.GetBuildingById)                                                                       // get building by Id
+	g.GET("/building/byShortName/:abbrev", a.GetBuildingByShortName)                                                     // get building by abbreviation
+	g.POST("/building", middleware.RequirePermission(model.PermissionBuildingsWrite), a.CreateBuilding)                  // create a new building
+	g.PATCH("/building/:buildingId", middleware.RequirePermission(model.PermissionBuildingsWrite), a.UpdateBuildingById) // update a building by its Id
+	g.DELETE("/building/:buildingId", middleware.RequirePermission(model.PermissionBuildingsWrite), a.DeleteBuilding)    // delete a building by its Id
+	// DHCP
+	g.GET("/dhcp/config/:format", a.GetDhcpConfig) // export DHCP host reservations
+	// Host Vars
+	g.GET("/hostVarLayers", a.GetHostVarLayers)                                                                                  // get all host var layers
+	g.GET("/hostVarLayer/:scope/:scopeId", a.GetHostVarLayer)                                                                    // get the host var layer of a scope
+	g.POST("/hostVarLayer", middleware.RequirePermission(model.PermissionHostVarsWrite), a.SetHostVarLayer)                      // create or replace a host var layer
+	g.DELETE("/hostVarLayer/:scope/:scopeId", middleware.RequirePermission(model.PermissionHostVarsWrite), a.DeleteHostVarLayer) // delete a host var layer
 	// Machine Roles
-	g.GET("/machineRoles", a.GetMachineRoles)                       // get all machine roles
-	g.GET("/machineRole/byId/:id", a.GetMachineRoleById)            // get a machine role by Id
-	g.POST("/machineRole", a.CreateMachineRole)                     // create a new machine role
-	g.PATCH("/machineRole/:machineRoleId", a.UpdateMachineRoleById) // update a machine role by Id
-	g.DELETE("/machineRole/:machineRoleId", a.DeleteMachineRole)    // delete a machine role by Id
+	g.GET("/machineRoles", a.GetMachineRoles)                                                                                                                  // get all machine roles
+	g.GET("/machineRole/byId/:id", a.GetMachineRoleById)                                                                                                       // get a machine role by Id
+	g.POST("/machineRole", middleware.RequirePermission(model.PermissionMachineRolesWrite), a.CreateMachineRole)                                               // create a new machine role
+	g.PATCH("/machineRole/:machineRoleId", middleware.RequirePermission(model.PermissionMachineRolesWrite), a.UpdateMachineRoleById)                           // update a machine role by Id
+	g.DELETE("/machineRole/:machineRoleId", middleware.RequirePermission(model.PermissionMachineRolesWrite), a.DeleteMachineRole)                              // delete a machine role by Id
+	g.GET("/machineRole/:machineRoleId/hostVarsSchema", a.GetMachineRoleHostVarsSchema)                                                                        // get a machine role's host vars schema
+	g.POST("/machineRole/:machineRoleId/hostVarsSchema", middleware.RequirePermission(model.PermissionMachineRolesWrite), a.SetMachineRoleHostVarsSchema)      // set a machine role's host vars schema
+	g.DELETE("/machineRole/:machineRoleId/hostVarsSchema", middleware.RequirePermission(model.PermissionMachineRolesWrite), a.DeleteMachineRoleHostVarsSchema) // remove a machine role's host vars schema
+	// Maintenance Windows
+	g.GET("/maintenanceWindows", a.GetMaintenanceWindows)                                                                               // get all maintenance windows
+	g.GET("/maintenanceWindows/byBuildingId/:buildingId", a.GetMaintenanceWindowsByBuildingId)                                          // get maintenance windows by building Id
+	g.POST("/maintenanceWindow", middleware.RequirePermission(model.PermissionMaintenanceWrite), a.CreateMaintenanceWindow)             // create a maintenance window
+	g.DELETE("/maintenanceWindow/:windowId", middleware.RequirePermission(model.PermissionMaintenanceWrite), a.DeleteMaintenanceWindow) // delete a maintenance window by Id
 	// Network Interfaces
-	g.GET("/networkInterfaces", a.GetNetworkInterfaces)                                    // get all network interfaces
-	g.GET("/networkInterfaces/:systemId", a.GetNetworkInterfacesBySystemId)                // get all network interfaces by system
-	g.GET("/networkInterface/byId/:networkInterfaceId", a.GetNetworkInterfaceById)         // get network interface by Id
-	g.GET("/networkInterface/byIpAddress/:ipAddress", a.GetNetworkInterfaceByIpAddress)    // get network interface by IP address
-	g.GET("/networkInterface/byMACAddress/:macAddress", a.GetNetworkInterfaceByMACAddress) // get network interface by MAC address
-	g.POST("/networkInterface", a.CreateNetworkInterface)                                  // create a new network interface
-	g.PATCH("/networkInterface/:networkInterfaceId", a.UpdateNetworkInterface)             // update a network interface
-	g.DELETE("/networkInterface/:networkInterfaceId", a.DeleteNetworkInterface)            // delete a network interface
+	g.GET("/networkInterfaces", a.GetNetworkInterfaces)                                                                                     // get all network interfaces
+	g.GET("/networkInterfaces/:systemId", a.GetNetworkInterfacesBySystemId)                                                                 // get all network interfaces by system
+	g.GET("/networkInterface/byId/:networkInterfaceId", a.GetNetworkInterfaceById)                                                          // get network interface by Id
+	g.GET("/networkInterface/byIpAddress/:ipAddress", a.GetNetworkInterfaceByIpAddress)                                                     // get network interface by IP address
+	g.GET("/networkInterface/byMACAddress/:macAddress", a.GetNetworkInterfaceByMACAddress)                                                  // get network interface by MAC address
+	g.POST("/networkInterface", middleware.RequirePermission(model.PermissionNetworkWrite), a.CreateNetworkInterface)                       // create a new network interface
+	g.PATCH("/networkInterface/:networkInterfaceId", middleware.RequirePermission(model.PermissionNetworkWrite), a.UpdateNetworkInterface)  // update a network interface
+	g.DELETE("/networkInterface/:networkInterfaceId", middleware.RequirePermission(model.PermissionNetworkWrite), a.DeleteNetworkInterface) // delete a network interface
 	// Operating System Families
-	g.GET("/osFamilies", a.GetOSFamilies)                        // get all operating system families
-	g.GET("/osFamily/byId/:osFamilyId", a.GetOSFamilyById)       // get operating system family by Id
-	g.GET("/osFamily/byName/:osFamilyName", a.GetOSFamilyByName) // get operating system family by name
-	g.POST("/osFamily", a.CreateOSFamily)                        // create a new operating system family
-	g.DELETE("/osFamily/:osFamilyId", a.DeleteOSFamily)          // delete an operating system by Id
+	g.GET("/osFamilies", a.GetOSFamilies)                                                                      // get all operating system families
+	g.GET("/osFamily/byId/:osFamilyId", a.GetOSFamilyById)                                                     // get operating system family by Id
+	g.GET("/osFamily/byName/:osFamilyName", a.GetOSFamilyByName)                                               // get operating system family by name
+	g.POST("/osFamily", middleware.RequirePermission(model.PermissionOSWrite), a.CreateOSFamily)               // create a new operating system family
+	g.DELETE("/osFamily/:osFamilyId", middleware.RequirePermission(model.PermissionOSWrite), a.DeleteOSFamily) // delete an operating system by Id
 	// Operating Systems
-	g.GET("/operatingSystems", a.GetOperatingSystems)                                  // get all operating systems
-	g.GET("/operatingSystems/byFamilyId/:osFamilyId", a.GetOperatingSystemsByFamilyId) // get operating sytems by OS Family Id
-	g.GET("/operatingSystems/byVendorId/:vendorId", a.GetOperatingSystemsByVendorId)   // get operating system by its vendor Id
-	g.GET("/operatingSystem/byId/:osId", a.GetOperatingSystemById)                     // get operating systems by Id
-	g.POST("/operatingSystem", a.CreateOperatingSystem)                                // create operating system
-	g.PATCH("/operatingSystem/:osId", a.UpdateOperatingSystemById)                     // update an operating system by Id
-	g.DELETE("/operatingSystem/:osId", a.DeleteOperatingSystem)                        // delete an operating system
+	g.GET("/operatingSystems", a.GetOperatingSystems)                                                                     // get all operating systems
+	g.GET("/operatingSystems/byFamilyId/:osFamilyId", a.GetOperatingSystemsByFamilyId)                                    // get operating sytems by OS Family Id
+	g.GET("/operatingSystems/byVendorId/:vendorId", a.GetOperatingSystemsByVendorId)                                      // get operating system by its vendor Id
+	g.GET("/operatingSystem/byId/:osId", a.GetOperatingSystemById)                                                        // get operating systems by Id
+	g.POST("/operatingSystem", middleware.RequirePermission(model.PermissionOSWrite), a.CreateOperatingSystem)            // create operating system
+	g.PATCH("/operatingSystem/:osId", middleware.RequirePermission(model.PermissionOSWrite), a.UpdateOperatingSystemById) // update an operating system by Id
+	g.DELETE("/operatingSystem/:osId", middleware.RequirePermission(model.PermissionOSWrite), a.DeleteOperatingSystem)    // delete an operating system
 	// Operating System Versions
-	g.GET("/osVersions", a.GetOSVersions)                     // get operating system versions
-	g.GET("/osVersion/byId/:osVersionId", a.GetOSVersionById) // get operating system version by Id
-	g.GET("/osVersion/byOSId/:osId", a.GetOSVersionsByOSId)   // get operating system version by Operating System Id
-	g.POST("/osVersion", a.CreateOSVersion)                   // create operating system versions
-	g.DELETE("/osVersion/:osVersionId", a.DeleteOSVersion)    // delete operating system versions
+	g.GET("/osVersions", a.GetOSVersions)                                                                         // get operating system versions
+	g.GET("/osVersion/byId/:osVersionId", a.GetOSVersionById)                                                     // get operating system version by Id
+	g.GET("/osVersion/byOSId/:osId", a.GetOSVersionsByOSId)                                                       // get operating system version by Operating System Id
+	g.POST("/osVersion", middleware.RequirePermission(model.PermissionOSWrite), a.CreateOSVersion)                // create operating system versions
+	g.DELETE("/osVersion/:osVersionId", middleware.RequirePermission(model.PermissionOSWrite), a.DeleteOSVersion) // delete operating system versions
 	// Organizational Units
-	g.GET("/organizationalUnits", a.GetOUs)              // get all organizational units
-	g.GET("/organizationalUnit/byId/:ouId", a.GetOUById) // get organizational unit by Id
-	g.POST("/organizationalUnit", a.CreateOU)            // create a new organizational unit
-	g.DELETE("/organizationalUnit/:ouId", a.DeleteOU)    // delete an organizational unit by Id
+	g.GET("/organizationalUnits", a.GetOUs)                                                                                                                 // get all organizational units
+	g.GET("/organizationalUnit/byId/:ouId", a.GetOUById)                                                                                                    // get organizational unit by Id
+	g.POST("/organizationalUnit", middleware.RequirePermission(model.PermissionOrgUnitsWrite), a.CreateOU)                                                  // create a new organizational unit
+	g.DELETE("/organizationalUnit/:ouId", middleware.RequirePermission(model.PermissionOrgUnitsWrite), a.DeleteOU)                                          // delete an organizational unit by Id
+	g.GET("/organizationalUnit/:ouId/children", a.GetOUChildren)                                                                                            // get the organizational units directly below an organizational unit
+	g.GET("/organizationalUnit/:ouId/subtree", a.GetOUSubtree)                                                                                              // get an organizational unit and everything below it
+	g.PATCH("/organizationalUnit/:ouId/parent", middleware.RequirePermission(model.PermissionOrgUnitsWrite), a.MoveOU)                                      // move an organizational unit under a new parent
+	g.GET("/organizationalUnit/:ouId/delegations", a.GetOrgUnitDelegations)                                                                                 // get the organizational units delegated to an organizational unit
+	g.POST("/organizationalUnit/:ouId/delegations", middleware.RequirePermission(model.PermissionOrgUnitsWrite), a.CreateOrgUnitDelegation)                 // delegate an organizational unit to another
+	g.DELETE("/organizationalUnit/:ouId/delegation/:delegatedOuId", middleware.RequirePermission(model.PermissionOrgUnitsWrite), a.DeleteOrgUnitDelegation) // remove a delegation
+	// Reimage Batches
+	g.GET("/reimageBatches", a.GetReimageBatches)                                                                               // get all reimage batches
+	g.GET("/reimageBatch/byId/:batchId", a.GetReimageBatchById)                                                                 // get a reimage batch by Id
+	g.POST("/reimageBatch", middleware.RequirePermission(model.PermissionReimageTrigger), a.CreateReimageBatch)                 // schedule a reimage batch
+	g.POST("/reimageBatch/:batchId/cancel", middleware.RequirePermission(model.PermissionReimageTrigger), a.CancelReimageBatch) // cancel a reimage batch
 	// Roles
-	g.GET("/roles", a.GetRoles)                      // get all roles
-	g.GET("/role/byId/:roleId", a.GetRoleById)       // get role by Id
-	g.GET("/role/byName/:roleName", a.GetRoleByName) // get role by name
-	g.POST("/role", a.CreateRole)                    // create new role
-	g.DELETE("/role/:roleId", a.DeleteRole)          // delete a role by Id
+	g.GET("/roles", a.GetRoles)                                                                                                        // get all roles
+	g.GET("/role/byId/:roleId", a.GetRoleById)                                                                                         // get role by Id
+	g.GET("/role/byName/:roleName", a.GetRoleByName)                                                                                   // get role by name
+	g.POST("/role", middleware.RequirePermission(model.PermissionRolesAdmin), a.CreateRole)                                            // create new role
+	g.DELETE("/role/:roleId", middleware.RequirePermission(model.PermissionRolesAdmin), a.DeleteRole)                                  // delete a role by Id
+	g.GET("/role/:roleId/permissions", a.GetRolePermissions)                                                                           // get the permissions granted to a role
+	g.POST("/role/:roleId/permissions", middleware.RequirePermission(model.PermissionRolesAdmin), a.GrantRolePermission)               // grant a permission to a role
+	g.DELETE("/role/:roleId/permission/:permission", middleware.RequirePermission(model.PermissionRolesAdmin), a.RevokeRolePermission) // revoke a permission from a role
+	g.PATCH("/role/:roleId/requireTwoFactor", middleware.RequirePermission(model.PermissionRolesAdmin), a.SetRoleRequireTwoFactor)     // set whether a role's users need two-factor authentication
+	g.GET("/permissions", a.GetPermissions)                                                                                            // get the permissions that can be granted
 	// Storage Volumes
-	g.GET("/storageVolumes", a.GetStorageVolumes)                                            // get all storage volumes
-	g.GET("/storageVolumes/:systemId", a.GetStorageVolumesBySystemId)                        // get storage volumes by system Id
-	g.GET("/storageVolume/byId/:storageVolumeId", a.GetStorageVolumeById)                    // get a storage volume by Id
-	g.GET("/storageVolume/:systemId/byLabel/:storageVolumeLabel", a.GetStorageVolumeByLabel) // get a storage volume by label
-	g.POST("/storageVolume", a.CreateStorageVolume)                                          // create a new storage volume
-	g.PATCH("/storageVolume/:storageVolumeId", a.UpdateStorageVolume)                        // update a storage volume
-	g.DELETE("/storageVolume/:storageVolumeId", a.DeleteStorageVolume)                       // delete a storage volume
+	g.GET("/storageVolumes", a.GetStorageVolumes)                                                                                  // get all storage volumes
+	g.GET("/storageVolumes/:systemId", a.GetStorageVolumesBySystemId)                                                              // get storage volumes by system Id
+	g.GET("/storageVolume/byId/:storageVolumeId", a.GetStorageVolumeById)                                                          // get a storage volume by Id
+	g.GET("/storageVolume/:systemId/byLabel/:storageVolumeLabel", a.GetStorageVolumeByLabel)                                       // get a storage volume by label
+	g.POST("/storageVolume", middleware.RequirePermission(model.PermissionStorageWrite), a.CreateStorageVolume)                    // create a new storage volume
+	g.PATCH("/storageVolume/:storageVolumeId", middleware.RequirePermission(model.PermissionStorageWrite), a.UpdateStorageVolume)  // update a storage volume
+	g.DELETE("/storageVolume/:storageVolumeId", middleware.RequirePermission(model.PermissionStorageWrite), a.DeleteStorageVolume) // delete a storage volume
 	// System Models
 	// g.GET("/systemModels", a.GetSystemModels)                                // get all system models
 	// g.GET("/systemModels/byVendorId/:vendorId", a.GetSystemModelsByVendorId) // get system models by vendor Id
 	//g.POST("/systemModel", a.CreateSystemModel)                              // create new system models
 	//g.DELETE("/systemModel/:modelId", a.DeleteSystemModel)                   // delete a system model
 	// Systems
-	g.GET("/systems")                                // get all systems
-	g.GET("/systems/byVendorId/:vendorId")           // get systems by vendor Id
-	g.GET("/systems/byCpuCores/:coreCount")          // get systems by number of CPU Cores
-	g.GET("/systems/byRAM/:memoryCount")             // get systems by amount of installed RAM
-	g.GET("/systems/byMachineRoleId/:machineRoleId") // get systems by the machine's role Id
-	g.GET("/systems/byOuId/:ouId")                   // get systems by organizational unit Id
-	g.GET("/system/byId/:id")                        // get system by Id
+	g.GET("/systems", a.GetSystems)                                                                              // get all systems
+	g.GET("/systems/byVendorId/:vendorId", a.GetSystemsByVendorId)                                               // get systems by vendor Id
+	g.GET("/systems/byCpuCores/:coreCount", a.GetSystemsByCpuCores)                                              // get systems by number of CPU Cores
+	g.GET("/systems/byRAM/:memoryCount", a.GetSystemsByRAM)                                                      // get systems by amount of installed RAM
+	g.GET("/systems/byMachineRoleId/:machineRoleId", a.GetSystemsByMachineRoleId)                                // get systems by the machine's role Id
+	g.GET("/systems/byOuId/:ouId", a.GetSystemsByOuId)                                                           // get systems by organizational unit Id
+	g.GET("/systems/byBuildingId/:buildingId", a.GetSystemsByBuildingId)                                         // get systems by building Id
+	g.GET("/system/byId/:id", a.GetSystemById)                                                                   // get system by Id
+	g.POST("/system", middleware.RequirePermission(model.PermissionSystemsWrite), a.CreateSystem)                // create a new system
+	g.PATCH("/system/:systemId", middleware.RequirePermission(model.PermissionSystemsWrite), a.UpdateSystemById) // update a system by Id
+	g.DELETE("/system/:systemId", middleware.RequirePermission(model.PermissionSystemsWrite), a.DeleteSystem)    // delete a system by Id
+	// Discovered Systems
+	g.GET("/systems/pending", middleware.RequirePermission(model.PermissionSystemsApprove), a.GetPendingSystems)              // get the systems waiting for approval
+	g.GET("/system/:systemId/discovery", middleware.RequirePermission(model.PermissionSystemsApprove), a.GetDiscoveredSystem) // get what a system's imaging client reported when discovered
+	g.POST("/system/:systemId/approve", middleware.RequirePermission(model.PermissionSystemsApprove), a.ApproveSystem)        // approve a discovered system
+	g.POST("/system/:systemId/reject", middleware.RequirePermission(model.PermissionSystemsApprove), a.RejectSystem)          // reject a discovered system
+	// Machine Certificates
+	g.GET("/machineCertificates/byStatus/:status", a.GetMachineCertificatesByStatus)                                                                   // get machine certificates and requests by status
+	g.GET("/machineCertificate/byId/:certificateId", a.GetMachineCertificateById)                                                                      // get a machine certificate or request by Id
+	g.POST("/machineCertificate/:certificateId/approve", middleware.RequirePermission(model.PermissionMachineCertsAdmin), a.ApproveMachineCertificate) // approve a pending certificate request
+	g.POST("/machineCertificate/:certificateId/reject", middleware.RequirePermission(model.PermissionMachineCertsAdmin), a.RejectMachineCertificate)   // reject a pending certificate request
+	g.DELETE("/machineCertificate/:certificateId", middleware.RequirePermission(model.PermissionMachineCertsAdmin), a.RevokeMachineCertificate)        // revoke a machine certificate
+	g.GET("/system/:systemId/certificates", a.GetSystemMachineCertificates)                                                                            // get a system's machine certificates and requests
+	g.POST("/system/:systemId/certificate", middleware.RequirePermission(model.PermissionMachineCertsAdmin), a.IssueMachineCertificate)                // issue a machine certificate to a system
+	// Machine Tokens
+	g.POST("/system/:systemId/machineToken", middleware.RequirePermission(model.PermissionMachineTokensAdmin), a.IssueMachineToken)    // issue a machine token for a system
+	g.PATCH("/system/:systemId/machineToken", middleware.RequirePermission(model.PermissionMachineTokensAdmin), a.RotateMachineToken)  // rotate a system's machine token
+	g.DELETE("/system/:systemId/machineToken", middleware.RequirePermission(model.PermissionMachineTokensAdmin), a.RevokeMachineToken) // revoke a system's machine token
+	// Reimages
+	g.GET("/system/:systemId/reimage", a.GetCurrentReimage)                                                                   // get the state of a system's latest reimage
+	g.GET("/system/:systemId/reimages", a.GetReimageHistory)                                                                  // get all reimages of a system
+	g.POST("/system/:systemId/reimage", middleware.RequirePermission(model.PermissionReimageTrigger), a.RequestReimage)       // request a reimage of a system
+	g.POST("/system/:systemId/reimage/cancel", middleware.RequirePermission(model.PermissionReimageTrigger), a.CancelReimage) // fail a system's stuck reimage
+	// Answer Files
+	g.GET("/system/:systemId/answerFile/:templateType", a.GetSystemAnswerFile) // render a system's answer file
+	// Host Vars
+	g.GET("/system/:systemId/hostVars", a.GetResolvedHostVars) // get a system's merged host vars and their sources
 	// user related routes
-	g.GET("/users", a.GetUsers)                        // get all users
-	g.GET("/users/ouid/:ouId", a.GetUsersByOuId)       // get all users by organizational unit Id
-	g.GET("/users/roleid/:roleId", a.GetUsersByRoleId) // get all users by role Id
-	g.GET("/users/typeid/:typeId", a.GetUsersByTypeId) // get all users by type Id
-	g.GET("/user/name/:name", a.GetUserByUserName)     // get a user by username
-	g.GET("/user/name/:name/status", a.GetUserStatus)  // get whether a user is locked or not
-	g.GET("/user/id/:id", a.GetUserById)               // get a user by Id
-	g.POST("/user", a.CreateUser)                      // create new user
-	g.PATCH("/user/:name", a.ChangeAccountPassword)    // update a user password
-	g.PATCH("/user/:name/status", a.SetUserStatus)     // lock a user
-	g.PATCH("/user/:name/ouid", a.SetUserOuId)         // set a user's organizational unit Id
-	g.PATCH("/user/:name/roleid", a.SetUserRoleId)     // set a user's role Id
-	g.PATCH("/user/:name/typeid", a.SetUserTypeId)     // set a user's type Id
-	g.DELETE("/user/:name", a.DeleteUser)              // trash a user
+	g.GET("/users", a.GetUsers)                                                                                                      // get all users
+	g.GET("/users/ouid/:ouId", a.GetUsersByOuId)                                                                                     // get all users by organizational unit Id
+	g.GET("/users/roleid/:roleId", a.GetUsersByRoleId)                                                                               // get all users by role Id
+	g.GET("/users/typeid/:typeId", a.GetUsersByTypeId)                                                                               // get all users by type Id
+	g.GET("/user/name/:name", a.GetUserByUserName)                                                                                   // get a user by username
+	g.GET("/user/name/:name/status", a.GetUserStatus)                                                                                // get whether a user is locked or not
+	g.GET("/user/id/:id", a.GetUserById)                                                                                             // get a user by Id
+	g.POST("/user", middleware.RequirePermission(model.PermissionUsersAdmin), a.CreateUser)                                          // create new user
+	g.PATCH("/user/:name", middleware.RejectScopedTokens, a.ChangeAccountPassword)                                                   // update the session user's password
+	g.POST("/user/:name/resetPassword", middleware.RequirePermission(model.PermissionUsersAdmin), a.ResetUserPassword)               // set a new password for a user who cannot give their old one
+	g.PATCH("/user/:name/status", middleware.RequirePermission(model.PermissionUsersAdmin), a.SetUserStatus)                         // lock a user
+	g.POST("/user/:name/unlock", middleware.RequirePermission(model.PermissionUsersAdmin), a.UnlockUser)                             // lift a lockout after failed logins
+	g.PATCH("/user/:name/mustChangePassword", middleware.RequirePermission(model.PermissionUsersAdmin), a.SetUserMustChangePassword) // force a password change on next login
+	g.PATCH("/user/:name/ouid", middleware.RequirePermission(model.PermissionUsersAdmin), a.SetUserOuId)                             // set a user's organizational unit Id
+	g.PATCH("/user/:name/roleid", middleware.RequirePermission(model.PermissionUsersAdmin), a.SetUserRoleId)                         // set a user's role Id
+	g.PATCH("/user/:name/typeid", middleware.RequirePermission(model.PermissionUsersAdmin), a.SetUserTypeId)                         // set a user's type Id
+	g.DELETE("/user/:name", middleware.RequirePermission(model.PermissionUsersAdmin), a.DeleteUser)                                  // trash a user
+	// API tokens
+	g.GET("/tokens", middleware.RejectScopedTokens, a.GetApiTokens)                                                        // get the session user's API tokens
+	g.POST("/token", a.CreateApiToken)                                                                                     // create an API token for the session user
+	g.DELETE("/token/:tokenId", middleware.RejectScopedTokens, a.RevokeApiToken)                                           // revoke one of the session user's API tokens
+	g.GET("/user/:name/tokens", middleware.RequirePermission(model.PermissionUsersAdmin), a.GetUserApiTokens)              // get a user's API tokens
+	g.DELETE("/user/:name/token/:tokenId", middleware.RequirePermission(model.PermissionUsersAdmin), a.RevokeUserApiToken) // revoke one of a user's API tokens
+	// Two-factor authentication
+	g.GET("/twoFactor", a.GetTwoFactorStatus)                                                                         // get the session user's two-factor status
+	g.POST("/twoFactor/enroll", a.EnrollTwoFactor)                                                                    // start two-factor enrollment for the session user
+	g.POST("/twoFactor/confirm", a.ConfirmTwoFactor)                                                                  // confirm the session user's two-factor enrollment
+	g.POST("/twoFactor/disable", a.DisableTwoFactor)                                                                  // turn off the session user's two-factor authentication
+	g.POST("/twoFactor/recoveryCodes", a.RegenerateRecoveryCodes)                                                     // replace the session user's recovery codes
+	g.DELETE("/user/:name/twoFactor", middleware.RequirePermission(model.PermissionUsersAdmin), a.ResetUserTwoFactor) // reset a user's two-factor authentication
+	// Sessions
+	g.POST("/logout", a.Logout)                                                                                               // end the current session
+	g.GET("/whoami", a.WhoAmI)                                                                                                // get the authenticated user
+	g.GET("/sessions", middleware.RejectScopedTokens, a.GetSessions)                                                          // get the session user's active sessions
+	g.DELETE("/session/:sessionId", middleware.RejectScopedTokens, a.RevokeSession)                                           // revoke one of the session user's sessions
+	g.GET("/user/:name/sessions", middleware.RequirePermission(model.PermissionUsersAdmin), a.GetUserSessions)                // get a user's active sessions
+	g.DELETE("/user/:name/sessions", middleware.RequirePermission(model.PermissionUsersAdmin), a.RevokeUserSessions)          // revoke all of a user's sessions
+	g.DELETE("/user/:name/session/:sessionId", middleware.RequirePermission(model.PermissionUsersAdmin), a.RevokeUserSession) // revoke one of a user's sessions
 	// Vendors
-	g.GET("/vendors", a.GetVendors)               // get all vendors
-	g.GET("/vendor/byId/:id", a.GetVendorById)    // get a vendor by Id
-	g.POST("/vendor", a.CreateVendor)             // create new vendor record
-	g.DELETE("/vendor/:vendorId", a.DeleteVendor) // delete a vendor record by Id
+	g.GET("/vendors", a.GetVendors)                                                                           // get all vendors
+	g.GET("/vendor/byId/:id", a.GetVendorById)                                                                // get a vendor by Id
+	g.POST("/vendor", middleware.RequirePermission(model.PermissionVendorsWrite), a.CreateVendor)             // create new vendor record
+	g.DELETE("/vendor/:vendorId", middleware.RequirePermission(model.PermissionVendorsWrite), a.DeleteVendor) // delete a vendor record by Id
+}
+
+func MachineRoutes(g *gin.RouterGroup, a *controllers.Allocator) {
+	// Machine check-in
+	g.GET("/checkIn/bySerialNumber/:serialNumber", a.CheckInBySerialNumber) // get provisioning document by serial number
+	g.GET("/checkIn/byMACAddress/:macAddress", a.CheckInByMACAddress)       // get provisioning document by MAC address
+	// Reimage progress
+	g.GET("/reimage/:systemId", a.GetCurrentReimage)           // get the state of the system's latest reimage
+	g.POST("/reimage/:systemId/status", a.ReportReimageStatus) // report reimage progress
+	// Answer files
+	g.GET("/answerFile/:systemId/:templateType", a.GetSystemAnswerFile) // render the system's answer file
+	// Host vars
+	g.GET("/hostVars/:systemId", a.GetResolvedHostVars) // get the system's merged host vars
+	// Machine certificates
+	g.POST("/certificate", a.RenewMachineCertificate) // issue the system a new client certificate
 }
 
 func PublicRoutes(g *gin.RouterGroup, a *controllers.Allocator) {
 	// service related routes
 	g.GET("/health") // service health API
+	// network boot, called by machine firmware which cannot authenticate
+	g.GET("/boot/ipxe/:macAddress", a.GetIPXEScript) // get the iPXE boot script for a MAC address
+	// logging in, which starts a session
+	g.POST("/login", a.Login) // log in with a password and start a session
+	// OpenID Connect login, which starts a session
+	g.GET("/oidc/login", a.OidcLogin)       // redirect to the OpenID provider
+	g.GET("/oidc/callback", a.OidcCallback) // finish logging in with the code the provider sent back
+	// enrolling imaging clients, which have no credentials until approved
+	g.POST("/enroll", middleware.Throttle(a.EnrollmentRequestsPerMinute()), a.EnrollMachine) // send a system's certificate request for approval, or check on it
+	// discovery of unknown hardware by imaging clients
+	g.POST("/discover", middleware.Throttle(a.DiscoveryRequestsPerMinute()), a.DiscoverSystem) // report unknown hardware, to wait for approval
 }
@@ -0,0 +1,62 @@
+package scheduler
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"log"
+	"time"
+
+	"github.com/greeneg/allocatord/model"
+)
+
+const defaultInterval = 60 * time.Second
+
+// Start runs the reimage batch scheduler in the background, checking for due
+// batches and open maintenance windows every interval seconds. An interval of
+// zero or less uses the default of one minute
+func Start(interval int) {
+	tick := defaultInterval
+	if interval > 0 {
+		tick = time.Duration(interval) * time.Second
+	}
+
+	log.Println("INFO: Starting reimage scheduler with an interval of " + tick.String())
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			run()
+			<-ticker.C
+		}
+	}()
+}
+
+func run() {
+	// never let a bad batch take the whole daemon down
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("ERROR: Reimage scheduler recovered from panic: %v", r)
+		}
+	}()
+
+	err := model.Repos.ReimageBatches.ProcessReimageBatches(time.Now())
+	if err != nil {
+		log.Println("ERROR: Reimage scheduler run failed: " + string(err.Error()))
+	}
+}
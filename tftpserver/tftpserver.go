@@ -0,0 +1,202 @@
+package tftpserver
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/greeneg/allocatord/boot"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/model"
+	"github.com/pin/tftp"
+)
+
+const defaultAddress = ":69"
+
+// per-MAC configs are generated on request rather than read from disk. iPXE
+// asks for <mac>.ipxe and GRUB for grub.cfg-01-<mac with dashes>
+var (
+	ipxeConfigPattern = regexp.MustCompile(`^([0-9a-fA-F]{2}(?:[:-][0-9a-fA-F]{2}){5})\.ipxe$`)
+	grubConfigPattern = regexp.MustCompile(`^grub\.cfg-01-([0-9a-fA-F]{2}(?:-[0-9a-fA-F]{2}){5})$`)
+)
+
+type Server struct {
+	root      string
+	serverUrl string
+	tftp      *tftp.Server
+	conn      *net.UDPConn
+}
+
+// the URL handed to imaging clients. Without an externalUrl in the config the
+// best guess is this host's name and the API port
+func serverUrl(config globals.Config) string {
+	if config.ExternalUrl != "" {
+		return config.ExternalUrl + "/api/v1"
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	if config.UseTLS {
+		return "https://" + hostname + ":" + strconv.Itoa(config.TLSTcpPort) + "/api/v1"
+	}
+
+	return "http://" + hostname + ":" + strconv.Itoa(config.TcpPort) + "/api/v1"
+}
+
+// Start binds the TFTP listener and serves requests in the background. Bind
+// errors are returned so a misconfigured listener stops the daemon at startup
+func Start(config globals.Config) (*Server, error) {
+	address := config.TFTPAddress
+	if address == "" {
+		address = defaultAddress
+	}
+	if config.ExternalUrl == "" {
+		log.Println("WARN: No externalUrl configured, imaging clients booted over TFTP will be pointed at " + serverUrl(config))
+	}
+
+	udpAddress, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		root:      config.TFTPRoot,
+		serverUrl: serverUrl(config),
+		conn:      conn,
+	}
+	s.tftp = tftp.NewServer(s.readHandler, nil)
+
+	log.Println("INFO: TFTP server listening on " + conn.LocalAddr().String() + ", serving files from '" + s.root + "'")
+	go s.tftp.Serve(conn)
+	return s, nil
+}
+
+// Addr returns the address the listener is bound to, which is useful when
+// the configured port was 0
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+func (s *Server) Shutdown() {
+	s.tftp.Shutdown()
+}
+
+func (s *Server) readHandler(filename string, rf io.ReaderFrom) error {
+	remote := "unknown"
+	if transfer, ok := rf.(tftp.OutgoingTransfer); ok {
+		address := transfer.RemoteAddr()
+		remote = address.String()
+	}
+	log.Println("INFO: TFTP read request for '" + filename + "' from " + remote)
+
+	content, err := s.generatedConfig(path.Base(filename))
+	if err != nil {
+		log.Println("ERROR: Cannot generate '" + filename + "': " + string(err.Error()))
+		return err
+	}
+	if content != nil {
+		return send(rf, strings.NewReader(string(content)), int64(len(content)))
+	}
+
+	return s.sendFile(filename, rf)
+}
+
+// generatedConfig returns nil if the file name is not one of the per-MAC
+// configs, so the request falls through to the files on disk
+func (s *Server) generatedConfig(name string) ([]byte, error) {
+	render := boot.RenderIPXE
+	match := ipxeConfigPattern.FindStringSubmatch(name)
+	if match == nil {
+		render = boot.RenderGrub
+		match = grubConfigPattern.FindStringSubmatch(name)
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	macAddress := strings.ReplaceAll(match[1], "-", ":")
+	target, err := model.Repos.Provisioning.GetBootTargetByMACAddress(macAddress)
+	if err != nil {
+		return nil, err
+	}
+	if target.System.SerialNumber == "" {
+		return nil, errors.New("no system found with MAC address " + macAddress)
+	}
+
+	params, err := boot.NewParams(target, s.serverUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return render(params)
+}
+
+func (s *Server) sendFile(filename string, rf io.ReaderFrom) error {
+	if s.root == "" {
+		return errors.New("file not found")
+	}
+
+	// cleaning against / keeps requests from climbing out of the root
+	fullPath := filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+filename)))
+	file, err := os.Open(fullPath)
+	if err != nil {
+		log.Println("WARN: Cannot open '" + fullPath + "': " + string(err.Error()))
+		return errors.New("file not found")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("file not found")
+	}
+
+	return send(rf, file, info.Size())
+}
+
+func send(rf io.ReaderFrom, r io.Reader, size int64) error {
+	// lets clients that asked for the tsize option know the size up front
+	if transfer, ok := rf.(tftp.OutgoingTransfer); ok {
+		transfer.SetSize(size)
+	}
+
+	_, err := rf.ReadFrom(r)
+	if err != nil {
+		log.Println("ERROR: TFTP transfer failed: " + string(err.Error()))
+	}
+
+	return err
+}
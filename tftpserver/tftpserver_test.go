@@ -0,0 +1,187 @@
+package tftpserver
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/dhcp"
+	"github.com/greeneg/allocatord/globals"
+	"github.com/greeneg/allocatord/model"
+	"github.com/pin/tftp"
+)
+
+// fakeProvisioning knows of a single system, flagged for reimage
+type fakeProvisioning struct {
+	target model.BootTarget
+}
+
+func (f fakeProvisioning) GetBootTargetByMACAddress(macAddress string) (model.BootTarget, error) {
+	if !strings.EqualFold(macAddress, f.target.Interface.MACAddress) {
+		return model.BootTarget{}, nil
+	}
+	return f.target, nil
+}
+
+func (f fakeProvisioning) GetDhcpHosts() ([]dhcp.Host, error) {
+	return nil, nil
+}
+
+func (f fakeProvisioning) GetProvisioningDocument(s model.System) (model.ProvisioningDocument, error) {
+	return model.ProvisioningDocument{}, nil
+}
+
+const bootloader = "not really a bootloader"
+
+// startServer serves a TFTP root holding a bootloader, next to a file that
+// must never be reachable from it
+func startServer(t *testing.T) *Server {
+	t.Helper()
+
+	saved := model.Repos
+	t.Cleanup(func() { model.Repos = saved })
+	model.Repos.Provisioning = fakeProvisioning{target: model.BootTarget{
+		System:          model.System{SerialNumber: "SN-TFTP-1", Reimage: true},
+		Interface:       model.NetworkInterface{MACAddress: "aa:bb:cc:dd:ee:ff"},
+		OperatingSystem: model.OperatingSystem{OSName: "TestOS", OSImageUrl: "http://images.example.com/testos/", ImageUriProtocol: "http"},
+	}}
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "tftproot")
+	err := os.MkdirAll(filepath.Join(root, "boot"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "boot", "undionly.kpxe"), []byte(bootloader), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "outside.txt"), []byte("secret"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Start(globals.Config{
+		TFTPAddress: "127.0.0.1:0",
+		TFTPRoot:    root,
+		ExternalUrl: "http://allocatord.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func fetch(t *testing.T, s *Server, filename string) (string, error) {
+	t.Helper()
+	client, err := tftp.NewClient(s.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.SetTimeout(time.Second)
+	client.SetRetries(1)
+
+	transfer, err := client.Receive(filename, "octet")
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	_, err = transfer.WriteTo(&out)
+	return out.String(), err
+}
+
+func TestBootloader(t *testing.T) {
+	s := startServer(t)
+
+	content, err := fetch(t, s, "boot/undionly.kpxe")
+	if err != nil {
+		t.Fatalf("fetching the bootloader: %v", err)
+	}
+	if content != bootloader {
+		t.Errorf("bootloader = %q, want %q", content, bootloader)
+	}
+
+	_, err = fetch(t, s, "boot/missing.efi")
+	if err == nil {
+		t.Errorf("fetching a missing file succeeded")
+	}
+}
+
+func TestGeneratedConfigs(t *testing.T) {
+	s := startServer(t)
+
+	tests := []struct {
+		filename string
+		want     []string
+	}{
+		{"aa:bb:cc:dd:ee:ff.ipxe", []string{
+			"#!ipxe",
+			"kernel http://images.example.com/testos/",
+			"allocatord.url=http://allocatord.example.com/api/v1",
+			"allocatord.serial=SN-TFTP-1",
+		}},
+		{"AA-BB-CC-DD-EE-FF.ipxe", []string{"allocatord.serial=SN-TFTP-1"}},
+		{"grub.cfg-01-aa-bb-cc-dd-ee-ff", []string{
+			"Reimage SN-TFTP-1 with TestOS",
+			"linux (http,images.example.com)/testos/",
+			"allocatord.serial=SN-TFTP-1",
+		}},
+		{"boot/grub/grub.cfg-01-aa-bb-cc-dd-ee-ff", []string{"Reimage SN-TFTP-1 with TestOS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			content, err := fetch(t, s, tt.filename)
+			if err != nil {
+				t.Fatalf("fetching %s: %v", tt.filename, err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("%s does not contain %q:\n%s", tt.filename, want, content)
+				}
+			}
+		})
+	}
+
+	_, err := fetch(t, s, "11:22:33:44:55:66.ipxe")
+	if err == nil {
+		t.Errorf("fetching the config of an unknown MAC address succeeded")
+	}
+}
+
+func TestRootEscape(t *testing.T) {
+	s := startServer(t)
+
+	for _, filename := range []string{
+		"../outside.txt",
+		"/../outside.txt",
+		"boot/../../outside.txt",
+		"boot/../../../" + strings.TrimPrefix(filepath.ToSlash(s.root), "/") + "/../outside.txt",
+	} {
+		content, err := fetch(t, s, filename)
+		if err == nil {
+			t.Errorf("fetching %s escaped the TFTP root and returned %q", filename, content)
+		}
+	}
+}
@@ -19,15 +19,15 @@ package main
 */
 
 import (
-	"database/sql"
-
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/greeneg/allocatord/model"
 )
 
-var DB *sql.DB
+// DB goes through the daemon's database layer, which rewrites the queries
+// here for PostgreSQL when needed
+var DB *model.Database
 
-func ConnectDatabase(dbPath string) error {
-	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_foreign_keys=on")
+func ConnectDatabase(driver string, dataSource string) error {
+	db, err := model.OpenDatabase(driver, dataSource)
 	if err != nil {
 		return err
 	}
@@ -1,11 +1,11 @@
 package main
 
 import (
-	"crypto/sha512"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"time"
+
+	"github.com/greeneg/allocatord/passwords"
 )
 
 type OrgUnit struct {
@@ -60,7 +60,7 @@ func createOU(ouName string, ouDescription string, creatorId int) (bool, error)
 	}
 
 	t.Commit()
-	return false, nil
+	return true, nil
 }
 
 func createInformationTechnologyOu(creator User) (OrgUnit, error) {
@@ -73,12 +73,12 @@ func createInformationTechnologyOu(creator User) (OrgUnit, error) {
 	}
 	if !biOrgUnitState {
 		infoPrintln("Creating org unit 'InformationTechnology'")
-		status, err := createOU("InformationTechnolgy", "The organizational unit that manages Information Technology services", creator.Id)
+		status, err := createOU("InformationTechnology", "The organizational unit that manages Information Technology services", creator.Id)
 		if err != nil {
 			errPrintln("Encountered error when creating org unit: " + string(err.Error()))
 		}
 		if status {
-			ouRecord, err := getOrgUnitByName("InformationTechnology")
+			ouRecord, err = getOrgUnitByName("InformationTechnology")
 			if err != nil {
 				errPrintln("Encountered error when retrieving org unit 'InformationTechnology'")
 				return OrgUnit{}, err
@@ -106,7 +106,7 @@ func getOrgUnitStatus(ouName string) (bool, error) {
 		return false, err
 	}
 
-	q, err := DB.Prepare("SELECT * FROM OrganizationalUnits WHERE OUName IS ?")
+	q, err := DB.Prepare("SELECT Id, OUName, Description, CreatorId, CreationDate FROM OrganizationalUnits WHERE OUName = ?")
 	if err != nil {
 		errPrintln("Could not prepare DB query: " + string(err.Error()))
 		return false, err
@@ -134,7 +134,7 @@ func getOrgUnitStatus(ouName string) (bool, error) {
 }
 
 func getOrgUnitByName(ouName string) (OrgUnit, error) {
-	rec, err := DB.Prepare("SELECT * FROM OrganizationalUnits WHERE OUName = ?")
+	rec, err := DB.Prepare("SELECT Id, OUName, Description, CreatorId, CreationDate FROM OrganizationalUnits WHERE OUName = ?")
 	if err != nil {
 		errPrintln("Could not prepare the DB query: " + string(err.Error()))
 		return OrgUnit{}, err
@@ -170,7 +170,7 @@ func getRoleStatus(role string) (bool, error) {
 		return false, err
 	}
 
-	q, err := DB.Prepare("SELECT * FROM Roles WHERE RoleName IS ?")
+	q, err := DB.Prepare("SELECT Id, RoleName, Description, CreationDate FROM Roles WHERE RoleName = ?")
 	if err != nil {
 		errPrintln("Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -214,7 +214,7 @@ func createAdministratorsRole(creator User) (Role, error) {
 			return Role{}, err
 		}
 		if status {
-			roleRecord, err := getRoleByName("administrators")
+			roleRecord, err = getRoleByName("administrators")
 			if err != nil {
 				errPrintln("Encountered error when retrieving role 'administrators'")
 				return Role{}, err
@@ -231,6 +231,13 @@ func createAdministratorsRole(creator User) (Role, error) {
 		roleRecord, _ = getRoleByName("administrators")
 	}
 
+	// administrators hold every permission, including ones added later
+	_, err = grantRolePermission(roleRecord.Id, "*", creator.Id)
+	if err != nil {
+		errPrintln("Encountered error when granting permissions to role 'administrators'")
+		return Role{}, err
+	}
+
 	return roleRecord, nil
 }
 
@@ -258,8 +265,32 @@ func createRole(roleName string, roleDescription string) (bool, error) {
 	return true, nil
 }
 
+func grantRolePermission(roleId int, permission string, creatorId int) (bool, error) {
+	t, err := DB.Begin()
+	if err != nil {
+		errPrintln("Could not start DB transaction: " + string(err.Error()))
+		return false, err
+	}
+
+	q, err := t.Prepare("INSERT INTO RolePermissions (RoleId, Permission, CreatorId) VALUES (?, ?, ?) ON CONFLICT (RoleId, Permission) DO NOTHING")
+	if err != nil {
+		errPrintln("Could not prepare the DB query: " + string(err.Error()))
+		return false, err
+	}
+
+	_, err = q.Exec(roleId, permission, creatorId)
+	if err != nil {
+		errPrintln("Cannot grant permission '" + permission + "': " + string(err.Error()))
+		return false, err
+	}
+
+	t.Commit()
+
+	return true, nil
+}
+
 func getRoleByName(roleName string) (Role, error) {
-	rec, err := DB.Prepare("SELECT * FROM Roles WHERE RoleName = ?")
+	rec, err := DB.Prepare("SELECT Id, RoleName, Description, CreationDate FROM Roles WHERE RoleName = ?")
 	if err != nil {
 		errPrintln("Could not prepare the DB query!" + string(err.Error()))
 		return Role{}, err
@@ -294,7 +325,7 @@ func getAccountStatus(account string) (bool, error) {
 		return false, err
 	}
 
-	q, err := DB.Prepare("SELECT EXISTS(SELECT 1 FROM Users WHERE UserName IS ?)")
+	q, err := DB.Prepare("SELECT EXISTS(SELECT 1 FROM Users WHERE UserName = ?)")
 	if err != nil {
 		errPrintln("Could not prepare DB query! " + string(err.Error()))
 		return false, err
@@ -312,7 +343,7 @@ func getAccountStatus(account string) (bool, error) {
 
 	t.Commit()
 
-	return true, nil
+	return exists, nil
 }
 
 func createAdminAccount(creator User, ouRecord OrgUnit, roleRecord Role) (User, error) {
@@ -350,6 +381,13 @@ func createAdminAccount(creator User, ouRecord OrgUnit, roleRecord Role) (User,
 }
 
 func createAccount(accountName string, accountFullName string, orgUnitId int, roleId int, passwd string) (User, bool, error) {
+	// take password and hash it
+	passwdHash, err := passwords.Hash(passwd)
+	if err != nil {
+		errPrintln("Cannot hash the password of user '" + accountName + "': " + string(err.Error()))
+		return User{}, false, err
+	}
+
 	t, err := DB.Begin()
 	if err != nil {
 		errPrintln("Could not start DB transaction!" + string(err.Error()))
@@ -362,10 +400,6 @@ func createAccount(accountName string, accountFullName string, orgUnitId int, ro
 		return User{}, false, err
 	}
 
-	// take password and hash it
-	hash := sha512.Sum512([]byte(passwd))
-	passwdHash := hex.EncodeToString(hash[:])
-
 	// get the org Id
 
 	_, err = q.Exec(accountName, accountFullName, orgUnitId, roleId, passwdHash)
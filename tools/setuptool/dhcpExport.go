@@ -0,0 +1,86 @@
+package main
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"os"
+
+	"github.com/greeneg/allocatord/dhcp"
+)
+
+// same query the daemon uses for its DHCP export endpoint
+func getDhcpHosts() ([]dhcp.Host, error) {
+	rows, err := DB.Query(`SELECT s.SerialNumber, n.DeviceId, n.MACAddress, n.IpAddress, n.Bitmask, n.Gateway, COALESCE(a.BootFilename, '')
+		FROM NetworkInterfaces n
+		JOIN Systems s ON s.Id = n.SystemId
+		LEFT JOIN Architectures a ON a.Id = s.ArchitectureId
+		WHERE s.Status = 'active'`)
+	if err != nil {
+		errPrintln("Could not run the DB query: " + string(err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	hosts := make([]dhcp.Host, 0)
+	for rows.Next() {
+		host := dhcp.Host{}
+		err = rows.Scan(
+			&host.SerialNumber,
+			&host.DeviceId,
+			&host.MACAddress,
+			&host.IpAddress,
+			&host.Bitmask,
+			&host.Gateway,
+			&host.BootFilename,
+		)
+		if err != nil {
+			errPrintln("Cannot read DHCP host record: " + string(err.Error()))
+			return nil, err
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// exportDhcpConfig writes the reservations to outputFile, or to stdout when no
+// file was given so the output can be piped into a DHCP server's config
+func exportDhcpConfig(format string, nextServer string, outputFile string) error {
+	hosts, err := getDhcpHosts()
+	if err != nil {
+		return err
+	}
+
+	config, err := dhcp.Render(format, hosts, dhcp.Options{NextServer: nextServer})
+	if err != nil {
+		return err
+	}
+
+	if outputFile == "" {
+		_, err = os.Stdout.Write(config)
+		return err
+	}
+
+	err = os.WriteFile(outputFile, config, 0644)
+	if err != nil {
+		return err
+	}
+	infoPrintln("DHCP config written to " + outputFile)
+	return nil
+}
@@ -1,8 +1,19 @@
 module github.com/greeneg/allocatord/tools/setuptool
 
-go 1.22.4
+go 1.23.0
 
 require (
-	github.com/mattn/go-sqlite3 v2.0.3+incompatible
+	github.com/greeneg/allocatord v0.0.0
 	github.com/pborman/getopt/v2 v2.1.0
 )
+
+require (
+	github.com/lib/pq v1.10.9 // indirect
+	github.com/mattn/go-sqlite3 v2.0.3+incompatible // indirect
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
+
+// the DHCP renderer and schema migrations are shared with the daemon
+replace github.com/greeneg/allocatord => ../../
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/pborman/getopt/v2"
+
+	"github.com/greeneg/allocatord/model"
 )
 
 var VERSION string = "0.1.0"
@@ -17,12 +19,17 @@ var app = path.Base(appFullPath)
 
 var (
 	dbFile             string
+	pgConnection       string
 	account            string
 	fullName           string
 	orgUnitName        string
 	orgUnitDescription string
 	role               string
 	roleDescription    string
+	dhcpFormat         string = "isc"
+	nextServer         string
+	outputFile         string
+	migrateTo          = -1
 	optHelp            = getopt.BoolLong("help", 'h', "This help message")
 	optVersion         = getopt.BoolLong("version", 'v', "Show the version")
 )
@@ -32,9 +39,19 @@ func showHelp() {
 	dividerLine := strings.Repeat("=", 43)
 	println(dividerLine)
 	println("Add and configure roles or accounts for the Allocator Daemon\n")
+	println("USAGE:")
+	println("   " + app + " -d FILENAME_PATH [OPTIONS]")
+	println("   " + app + " -d FILENAME_PATH [DHCP OPTIONS] dhcp-export")
+	println("   " + app + " -d FILENAME_PATH [MIGRATION OPTIONS] migrate")
+	println("   " + app + " -d FILENAME_PATH migrate-status")
+	println("   " + app + " -p CONNECTION_STRING [OPTIONS]\n")
 	println("OPTIONS:")
 	println("   -d|--database-file FILENAME_PATH       REQUIRED: The full or relative path")
-	println("                                          to the database file")
+	println("                                          to the database file, unless -p is")
+	println("                                          given")
+	println("   -p|--postgres CONNECTION_STRING        OPTIONAL: Use the PostgreSQL database")
+	println("                                          at this URL or key/value connection")
+	println("                                          string instead of a database file")
 	println("   -a|--account ACCOUNT_NAME              OPTIONAL: The account to create")
 	println("   -r|--role ROLE_NAME                    OPTIONAL: The role to create")
 	println("   -o|--org-unit ORGANIZATIONAL_UNIT_NAME OPTIONAL: The organizational unit to")
@@ -55,6 +72,21 @@ func showHelp() {
 	println("                                          for the org-unit to be registered with")
 	println("                                          the system.")
 	println("")
+	println("DHCP OPTIONS:")
+	println("   -F|--dhcp-format FORMAT                OPTIONAL: The config format to export,")
+	println("                                          one of isc, kea or dnsmasq. Defaults")
+	println("                                          to isc")
+	println("   -n|--next-server ADDRESS               OPTIONAL: The TFTP server address to")
+	println("                                          hand to PXE clients")
+	println("   -w|--output FILENAME_PATH              OPTIONAL: Write the config to this")
+	println("                                          file instead of stdout")
+	println("")
+	println("MIGRATION OPTIONS:")
+	println("   -m|--migrate-to VERSION                OPTIONAL: The schema version to")
+	println("                                          migrate to. Versions below the")
+	println("                                          current one revert migrations.")
+	println("                                          Defaults to the latest version")
+	println("")
 	println("Author: Gary L. Greene, Jr. <greeneg@tolharadys.net>")
 	println("License: Apache Public License, v2")
 	showVersion()
@@ -66,12 +98,17 @@ func showVersion() {
 
 func init() {
 	getopt.FlagLong(&dbFile, "database-file", 'd', "The full path to the database file")
+	getopt.FlagLong(&pgConnection, "postgres", 'p', "The connection string of a PostgreSQL database")
 	getopt.FlagLong(&account, "account", 'a', "The account to add to the system")
 	getopt.FlagLong(&fullName, "fullname", 'f', "The full name to associate with the account")
 	getopt.FlagLong(&orgUnitName, "org-unit", 'o', "The name of the organizational unit")
 	getopt.FlagLong(&orgUnitDescription, "org-unit-description", 'O', "The description of the organizational unit to process")
 	getopt.FlagLong(&role, "role", 'r', "The role to add to the system")
 	getopt.FlagLong(&roleDescription, "role-description", 'D', "The description of the role to process")
+	getopt.FlagLong(&dhcpFormat, "dhcp-format", 'F', "The DHCP config format to export")
+	getopt.FlagLong(&nextServer, "next-server", 'n', "The IPv4 address of the TFTP server to hand to PXE clients")
+	getopt.FlagLong(&outputFile, "output", 'w', "The file to write the DHCP config to")
+	getopt.FlagLong(&migrateTo, "migrate-to", 'm', "The schema version to migrate to")
 }
 
 func processFlags() {
@@ -88,15 +125,23 @@ func processFlags() {
 	println("Starting setuptool... \n")
 
 	// first, setup our DB connection
-	if dbFile != "" {
+	var err error
+	if pgConnection != "" {
+		println("PostgreSQL database")
+		err = ConnectDatabase(model.PostgreSQL, pgConnection)
+	} else if dbFile != "" {
 		println("Database file: " + dbFile)
-		ConnectDatabase(dbFile)
-		infoPrintln("Database connection completed")
+		err = ConnectDatabase(model.SQLite, dbFile)
 	} else {
-		errPrintln("Database file must be defined.")
+		errPrintln("Database file or PostgreSQL connection must be defined.")
 		showHelp()
 		os.Exit(1)
 	}
+	if err != nil {
+		errPrintln("Could not connect to the database: " + string(err.Error()))
+		os.Exit(1)
+	}
+	infoPrintln("Database connection completed")
 
 	// do we need to process an account the user passed in?
 	if account != "" {
@@ -139,6 +184,45 @@ func main() {
 	getopt.Parse()
 	processFlags()
 
+	// subcommands run on their own rather than as part of the initial setup
+	if getopt.NArgs() > 0 {
+		switch getopt.Arg(0) {
+		case "dhcp-export":
+			err := exportDhcpConfig(dhcpFormat, nextServer, outputFile)
+			if err != nil {
+				errPrintln("Encountered error when exporting the DHCP config: " + string(err.Error()))
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "migrate":
+			err := migrateDatabase(migrateTo)
+			if err != nil {
+				errPrintln("Encountered error when migrating the database: " + string(err.Error()))
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "migrate-status":
+			err := showMigrationStatus()
+			if err != nil {
+				errPrintln("Encountered error when reading the schema version: " + string(err.Error()))
+				os.Exit(1)
+			}
+			os.Exit(0)
+		default:
+			errPrintln("Unknown command '" + getopt.Arg(0) + "'")
+			showHelp()
+			os.Exit(1)
+		}
+	}
+
+	// the initial setup needs the built-in records, so a fresh database is
+	// brought up to the latest schema first
+	err := migrateDatabase(-1)
+	if err != nil {
+		errPrintln("Encountered error when migrating the database: " + string(err.Error()))
+		os.Exit(1)
+	}
+
 	creator, err := getAccountByName("SYSTEM")
 	if err != nil {
 		errPrintln("Encountered error when looking up the 'SYSTEM' account")
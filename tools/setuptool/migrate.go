@@ -0,0 +1,77 @@
+package main
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/greeneg/allocatord/migrations"
+)
+
+// migrateDatabase moves the schema to the given version, or to the latest
+// one when the version is negative. Targets below the current version revert
+// migrations
+func migrateDatabase(target int) error {
+	if target < 0 {
+		target = migrations.Latest()
+	}
+
+	current, err := migrations.CurrentVersion(DB)
+	if err != nil {
+		return err
+	}
+	if current == target {
+		infoPrintln("Database schema is already at version " + strconv.Itoa(current))
+		return nil
+	}
+
+	err = migrations.MigrateTo(DB, target)
+	if err != nil {
+		return err
+	}
+
+	infoPrintln("Database schema migrated from version " + strconv.Itoa(current) + " to " + strconv.Itoa(target))
+	return nil
+}
+
+// showMigrationStatus prints every known migration and whether it has been
+// applied, to stdout so it can be piped
+func showMigrationStatus() error {
+	applied, err := migrations.Applied(DB)
+	if err != nil {
+		return err
+	}
+
+	appliedDates := make(map[int]string, len(applied))
+	for _, migration := range applied {
+		appliedDates[migration.Version] = migration.AppliedDate
+	}
+
+	for _, migration := range migrations.Migrations() {
+		state := "pending"
+		if date, ok := appliedDates[migration.Version]; ok {
+			state = "applied " + convertSqliteTimestamp(date)
+		}
+		fmt.Fprintf(os.Stdout, "%4d  %-24s %s\n", migration.Version, migration.Name, state)
+	}
+
+	return nil
+}
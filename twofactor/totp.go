@@ -0,0 +1,113 @@
+package twofactor
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Period is how long, in seconds, each code is valid for
+const Period = 30
+
+// codes from this many periods either side of the current one are accepted,
+// allowing for clocks that have drifted a little
+const skew = 1
+
+// Enrollment is what a user needs to add their secret to an authenticator
+// app
+type Enrollment struct {
+	Secret          string
+	ProvisioningUri string
+	QrCode          string
+}
+
+var issuer = "allocatord"
+
+// Configure sets the issuer authenticator apps show the accounts under
+func Configure(name string) {
+	if name != "" {
+		issuer = name
+	}
+}
+
+// Generate creates a new secret for the account, along with its otpauth://
+// provisioning URI and a PNG QR code of it as a data URI
+func Generate(accountName string) (Enrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+		Period:      Period,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	return Enrollment{
+		Secret:          key.Secret(),
+		ProvisioningUri: key.URL(),
+		QrCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Validate checks a code against the secret, returning the time step it
+// belongs to so callers can refuse a code that has been used before
+func Validate(secret string, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != int(otp.DigitsSix) {
+		return 0, false
+	}
+
+	step := now.Unix() / Period
+	for offset := int64(-skew); offset <= skew; offset++ {
+		candidate := step + offset
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(candidate*Period, 0), totp.ValidateOpts{
+			Period:    Period,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return candidate, true
+		}
+	}
+
+	return 0, false
+}
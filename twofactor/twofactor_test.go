@@ -0,0 +1,256 @@
+package twofactor_test
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeneg/allocatord/helpers"
+	"github.com/greeneg/allocatord/migrations"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/twofactor"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func code(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	c, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{Period: twofactor.Period, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1})
+	if err != nil {
+		t.Fatalf("GenerateCodeCustom: %v", err)
+	}
+	return c
+}
+
+func secret(t *testing.T) string {
+	t.Helper()
+	enrollment, err := twofactor.Generate("alice")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return enrollment.Secret
+}
+
+func TestGenerate(t *testing.T) {
+	twofactor.Configure("Example Allocator")
+	t.Cleanup(func() { twofactor.Configure("allocatord") })
+
+	enrollment, err := twofactor.Generate("alice")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if enrollment.Secret == "" {
+		t.Errorf("enrollment has no secret")
+	}
+	if !strings.HasPrefix(enrollment.ProvisioningUri, "otpauth://totp/Example%20Allocator:alice?") || !strings.Contains(enrollment.ProvisioningUri, "secret="+enrollment.Secret) {
+		t.Errorf("provisioning URI = %s, want one for alice at Example Allocator carrying the secret", enrollment.ProvisioningUri)
+	}
+	if !strings.HasPrefix(enrollment.QrCode, "data:image/png;base64,") {
+		t.Errorf("QR code = %.40s..., want a PNG data URI", enrollment.QrCode)
+	}
+	if other := secret(t); other == enrollment.Secret {
+		t.Errorf("two enrollments got the same secret %s", other)
+	}
+}
+
+// TestValidateSkew checks that codes of the periods next to the current one
+// are accepted, for clocks that drifted a little, and no further
+func TestValidateSkew(t *testing.T) {
+	s := secret(t)
+	// the middle of a period, so only the skew decides
+	now := time.Unix(1_700_000_000/twofactor.Period*twofactor.Period+twofactor.Period/2, 0)
+	step := now.Unix() / twofactor.Period
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"two periods early", -2, false},
+		{"one period early", -1, true},
+		{"current period", 0, true},
+		{"one period late", 1, true},
+		{"two periods late", 2, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := code(t, s, now.Add(time.Duration(test.offset*twofactor.Period)*time.Second))
+			got, valid := twofactor.Validate(s, c, now)
+			if valid != test.valid {
+				t.Fatalf("Validate of a code %d periods off = %v, want %v", test.offset, valid, test.valid)
+			}
+			if valid && got != step+test.offset {
+				t.Errorf("Validate returned step %d, want %d", got, step+test.offset)
+			}
+		})
+	}
+
+	// the edges of a period belong to it
+	start := time.Unix(step*twofactor.Period, 0)
+	for _, at := range []time.Time{start, start.Add(twofactor.Period*time.Second - time.Second)} {
+		if got, valid := twofactor.Validate(s, code(t, s, at), at); !valid || got != step {
+			t.Errorf("Validate at %v = %d, %v, want step %d", at, got, valid, step)
+		}
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	s := secret(t)
+	now := time.Now()
+	valid := code(t, s, now)
+	wrong := "000000"
+	if wrong == valid {
+		wrong = "111111"
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"the code", valid, true},
+		{"surrounded by spaces", " " + valid + "\n", true},
+		{"empty", "", false},
+		{"too short", valid[:5], false},
+		{"too long", valid + "0", false},
+		{"wrong", wrong, false},
+		{"letters", "abcdef", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, ok := twofactor.Validate(s, test.code, now); ok != test.valid {
+				t.Errorf("Validate(%q) = %v, want %v", test.code, ok, test.valid)
+			}
+		})
+	}
+}
+
+// enrolled is a user with two-factor authentication enabled, along with
+// their secret and recovery codes
+func enrolled(t *testing.T) (model.User, string, []string) {
+	t.Helper()
+	err := model.ConnectDatabase(model.SQLite, filepath.Join(t.TempDir(), "allocatord.db"))
+	if err != nil {
+		t.Fatalf("ConnectDatabase: %v", err)
+	}
+	t.Cleanup(func() { model.DB.Close() })
+	err = migrations.Up(model.DB)
+	if err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	_, err = model.Repos.Users.CreateUser(model.ProposedUser{UserName: "alice", FullName: "Alice", Status: "enabled", OrgUnitId: 1, RoleId: 1, TypeId: model.UserTypeLocal, Password: "Alice-password-1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := model.Repos.Users.GetUserByUserName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByUserName: %v", err)
+	}
+
+	s := secret(t)
+	_, err = model.Repos.TwoFactor.StartTwoFactorEnrollment(user.Id, s)
+	if err != nil {
+		t.Fatalf("StartTwoFactorEnrollment: %v", err)
+	}
+	// confirmed long ago, so no code of the present has been used yet
+	codes, err := model.Repos.TwoFactor.EnableTwoFactor(user.Id, 0)
+	if err != nil {
+		t.Fatalf("EnableTwoFactor: %v", err)
+	}
+	if len(codes) == 0 {
+		t.Fatalf("EnableTwoFactor returned no recovery codes")
+	}
+	return user, s, codes
+}
+
+// TestStepReuse checks that a code cannot be used twice, nor a code older
+// than one already used
+func TestStepReuse(t *testing.T) {
+	user, s, _ := enrolled(t)
+
+	c := code(t, s, time.Now())
+	if !helpers.CheckSecondFactor(user, c) {
+		t.Fatalf("CheckSecondFactor with a fresh code failed")
+	}
+	if helpers.CheckSecondFactor(user, c) {
+		t.Errorf("CheckSecondFactor accepted the same code twice")
+	}
+
+	step := time.Now().Unix()/twofactor.Period + 10
+	tests := []struct {
+		name     string
+		step     int64
+		accepted bool
+	}{
+		{"a later step", step, true},
+		{"the same step", step, false},
+		{"an earlier step", step - 1, false},
+		{"the next step", step + 1, true},
+	}
+	for _, test := range tests {
+		accepted, err := model.Repos.TwoFactor.AcceptTwoFactorStep(user.Id, test.step)
+		if err != nil {
+			t.Fatalf("AcceptTwoFactorStep: %v", err)
+		}
+		if accepted != test.accepted {
+			t.Errorf("AcceptTwoFactorStep of %s = %v, want %v", test.name, accepted, test.accepted)
+		}
+	}
+}
+
+// TestRecoveryCodesSingleUse checks that each recovery code works once,
+// however it is typed, and that replacing them voids the old ones
+func TestRecoveryCodesSingleUse(t *testing.T) {
+	user, _, codes := enrolled(t)
+
+	if !helpers.CheckSecondFactor(user, codes[0]) {
+		t.Fatalf("CheckSecondFactor with an unused recovery code failed")
+	}
+	if helpers.CheckSecondFactor(user, codes[0]) {
+		t.Errorf("CheckSecondFactor accepted a recovery code twice")
+	}
+
+	retyped := strings.ToUpper(strings.ReplaceAll(codes[1], "-", " "))
+	used, err := model.Repos.TwoFactor.UseRecoveryCode(user.Id, retyped)
+	if err != nil || !used {
+		t.Errorf("UseRecoveryCode(%q) = %v, %v, want the code typed in upper case with spaces accepted", retyped, used, err)
+	}
+	used, err = model.Repos.TwoFactor.UseRecoveryCode(user.Id, codes[1])
+	if err != nil || used {
+		t.Errorf("UseRecoveryCode of a code used in another spelling = %v, %v, want it refused", used, err)
+	}
+	used, err = model.Repos.TwoFactor.UseRecoveryCode(user.Id, "aaaa-bbbb-cccc-dddd")
+	if err != nil || used {
+		t.Errorf("UseRecoveryCode of a made up code = %v, %v, want it refused", used, err)
+	}
+
+	replaced, err := model.Repos.TwoFactor.RegenerateRecoveryCodes(user.Id)
+	if err != nil || len(replaced) != len(codes) {
+		t.Fatalf("RegenerateRecoveryCodes = %v, %v, want %d new codes", replaced, err, len(codes))
+	}
+	if helpers.CheckSecondFactor(user, codes[2]) {
+		t.Errorf("CheckSecondFactor accepted a recovery code that was replaced")
+	}
+	if !helpers.CheckSecondFactor(user, replaced[0]) {
+		t.Errorf("CheckSecondFactor refused a new recovery code")
+	}
+}
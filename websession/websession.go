@@ -0,0 +1,157 @@
+package websession
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config is how session cookies are protected and how long sessions last.
+// Every key can read a cookie but only the first signs new ones, so a key is
+// rotated by putting its replacement in front of it, and dropped once the
+// sessions it signed have run out. Keys come from the configuration or from
+// a file holding one per line, not both
+type Config struct {
+	Keys        []string
+	KeyFile     string
+	IdleTimeout time.Duration
+	MaxAge      time.Duration
+}
+
+// sessions end after this long without a request, and this long after they
+// started, unless configured otherwise
+const (
+	DefaultIdleTimeout = 30 * time.Minute
+	DefaultMaxAge      = 12 * time.Hour
+)
+
+// keys shorter than this are too easily guessed to sign cookies with
+const minKeyLength = 32
+
+var config Config
+var keyPairs [][]byte
+
+// Configure loads the session keys and sets the timeouts. Without any keys a
+// random one is made up, which signs out every user when the daemon restarts
+func Configure(c Config) error {
+	if c.IdleTimeout < 0 || c.MaxAge < 0 {
+		return errors.New("session timeouts cannot be negative")
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = DefaultIdleTimeout
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = DefaultMaxAge
+	}
+	if len(c.Keys) > 0 && c.KeyFile != "" {
+		return errors.New("session keys can be set in the configuration or a key file, not both")
+	}
+
+	keys := c.Keys
+	if c.KeyFile != "" {
+		var err error
+		keys, err = readKeyFile(c.KeyFile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(keys) == 0 {
+		log.Println("WARN: No session key configured, sessions will not survive a restart")
+		key, err := randomKey()
+		if err != nil {
+			return err
+		}
+		keys = []string{key}
+	}
+
+	pairs := make([][]byte, 0, 2*len(keys))
+	for _, key := range keys {
+		if len(key) < minKeyLength {
+			return errors.New("session keys must be at least 32 characters long")
+		}
+		pairs = append(pairs, deriveKey(key, "authentication"), deriveKey(key, "encryption"))
+	}
+
+	config = c
+	keyPairs = pairs
+	return nil
+}
+
+// readKeyFile reads the keys of a file, one per line. Blank lines and lines
+// starting with # are skipped
+func readKeyFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("session key file " + path + " holds no keys")
+	}
+	return keys, nil
+}
+
+func randomKey() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// deriveKey turns a configured key into a 32 byte key for one purpose, so
+// cookies are both signed and encrypted (with AES-256) from a single key
+func deriveKey(key string, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("allocatord session " + purpose))
+	return mac.Sum(nil)
+}
+
+// KeyPairs returns the authentication and encryption key of every session
+// key, the one new cookies are signed with first
+func KeyPairs() [][]byte {
+	return keyPairs
+}
+
+// IdleTimeout returns how long a session lasts without a request
+func IdleTimeout() time.Duration {
+	return config.IdleTimeout
+}
+
+// MaxAge returns how long a session lasts at most
+func MaxAge() time.Duration {
+	return config.MaxAge
+}
@@ -0,0 +1,197 @@
+package websession_test
+
+/*
+
+  Copyright 2024, YggdrasilSoft, LLC.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/greeneg/allocatord/model"
+	"github.com/greeneg/allocatord/websession"
+)
+
+var (
+	oldKey = strings.Repeat("o", 32)
+	newKey = strings.Repeat("n", 32)
+)
+
+// sessionRouter stores a value in the session cookie on /set and reads it
+// back on /get, with the keys Configure was last given
+func sessionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(sessions.Sessions("session", cookie.NewStore(websession.KeyPairs()...)))
+	r.GET("/set", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("user", "alice")
+		session.Save()
+	})
+	r.GET("/get", func(c *gin.Context) {
+		user, _ := sessions.Default(c).Get("user").(string)
+		c.String(http.StatusOK, user)
+	})
+	return r
+}
+
+func setCookie(t *testing.T) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	sessionRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func getUser(cookie *http.Cookie) string {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cookie)
+	sessionRouter().ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func configure(t *testing.T, c websession.Config) {
+	t.Helper()
+	err := websession.Configure(c)
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	configure(t, websession.Config{Keys: []string{oldKey}})
+	signedWithOld := setCookie(t)
+	if got := getUser(signedWithOld); got != "alice" {
+		t.Fatalf("cookie read back as %q, want alice", got)
+	}
+
+	// the replacement goes in front, and the old key still reads its cookies
+	configure(t, websession.Config{Keys: []string{newKey, oldKey}})
+	if got := getUser(signedWithOld); got != "alice" {
+		t.Errorf("cookie signed with the old key read back as %q after rotation, want alice", got)
+	}
+	signedWithNew := setCookie(t)
+
+	// once the old key is dropped only cookies signed with the new one work
+	configure(t, websession.Config{Keys: []string{newKey}})
+	if got := getUser(signedWithOld); got != "" {
+		t.Errorf("cookie signed with a dropped key read back as %q, want nothing", got)
+	}
+	if got := getUser(signedWithNew); got != "alice" {
+		t.Errorf("cookie signed with the new key read back as %q, want alice", got)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.keys")
+	err := os.WriteFile(path, []byte("# newest first\n"+newKey+"\n\n"+oldKey+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configure(t, websession.Config{KeyFile: path})
+	if got := len(websession.KeyPairs()); got != 4 {
+		t.Errorf("got %d keys from the key file, want 4", got)
+	}
+
+	err = websession.Configure(websession.Config{Keys: []string{newKey}, KeyFile: path})
+	if err == nil {
+		t.Error("keys were accepted from both the configuration and a key file")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.keys")
+	os.WriteFile(empty, []byte("# nothing here\n"), 0600)
+	if err := websession.Configure(websession.Config{KeyFile: empty}); err == nil {
+		t.Error("a key file without keys was accepted")
+	}
+}
+
+func TestKeyLength(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		ok   bool
+	}{
+		{"minimum length", []string{strings.Repeat("k", 32)}, true},
+		{"one short", []string{strings.Repeat("k", 31)}, false},
+		{"short old key", []string{newKey, "tooshort"}, false},
+		{"none", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := websession.Configure(websession.Config{Keys: tt.keys})
+			if tt.ok && err != nil {
+				t.Errorf("Configure: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("a key shorter than 32 characters was accepted")
+			}
+		})
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	configure(t, websession.Config{Keys: []string{newKey}})
+	if websession.IdleTimeout() != websession.DefaultIdleTimeout || websession.MaxAge() != websession.DefaultMaxAge {
+		t.Errorf("got timeouts %v and %v, want the defaults", websession.IdleTimeout(), websession.MaxAge())
+	}
+	err := websession.Configure(websession.Config{Keys: []string{newKey}, IdleTimeout: -time.Minute})
+	if err == nil {
+		t.Error("a negative idle timeout was accepted")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	configure(t, websession.Config{Keys: []string{newKey}, IdleTimeout: 30 * time.Minute, MaxAge: 12 * time.Hour})
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	format := func(t time.Time) string { return t.Format("2006-01-02 15:04:05") }
+
+	tests := []struct {
+		name     string
+		lastSeen time.Time
+		now      time.Time
+		expired  bool
+	}{
+		{"fresh", start, start.Add(time.Minute), false},
+		{"just before idle", start, start.Add(30*time.Minute - time.Second), false},
+		{"idle", start, start.Add(30 * time.Minute), true},
+		{"kept alive", start.Add(11*time.Hour + 50*time.Minute), start.Add(12*time.Hour - time.Second), false},
+		{"absolute even when active", start.Add(11*time.Hour + 59*time.Minute), start.Add(12 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := model.Session{
+				CreationDate: format(start),
+				LastSeenDate: format(tt.lastSeen),
+				ExpiryDate:   format(start.Add(websession.MaxAge())),
+			}
+			if got := session.Expired(tt.now); got != tt.expired {
+				t.Errorf("Expired() = %v, want %v", got, tt.expired)
+			}
+		})
+	}
+}